@@ -0,0 +1,36 @@
+package main
+
+import "fmt"
+
+type Ints []int
+
+type T struct{ s []string }
+
+func main() {
+	var a []int
+	var b Ints
+	var c []byte
+	var d []float64
+	for i := 0; i < 5; i++ {
+		a = append(a, i)
+		b = append(b, i*i)
+		c = append(c, 'a'+byte(i))
+		d = append(d, 0.5)
+	}
+	t := T{}
+	t.s = append(t.s, "x")
+	e := append([]bool(nil), true)
+
+	n := copy(a, b[3:])
+	m := map[string]int{"a": 1, "b": 2}
+	k := "b"
+	delete(m, k)
+	delete(m, "z")
+	x, y := m["a"], m[k]
+	var i interface{} = append(a, 9)
+
+	fmt.Println(a, b, string(c), d, t.s, e, n, m, x, y, i)
+}
+
+// Output:
+// [9 16 2 3 4] [0 1 4 9 16] abcde [0.5 0.5 0.5 0.5 0.5] [x] [true] 2 map[a:1] 1 0 [9 16 2 3 4 9]
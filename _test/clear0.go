@@ -0,0 +1,22 @@
+package main
+
+import "fmt"
+
+func Reset[M ~map[K]V, K comparable, V any](m M) { clear(m) }
+
+func main() {
+	m := map[string]int{"a": 1, "b": 2}
+	clear(m)
+	fmt.Println(len(m))
+	sl := []int{1, 2, 3}
+	clear(sl[1:])
+	fmt.Println(sl)
+	m2 := map[int]bool{1: true}
+	Reset(m2)
+	fmt.Println(len(m2))
+}
+
+// Output:
+// 0
+// [1 0 0]
+// 0
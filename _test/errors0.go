@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+type MyErr struct{ Code int }
+
+func (e *MyErr) Error() string { return fmt.Sprint("code ", e.Code) }
+
+type Wrap struct{ err error }
+
+func (w Wrap) Error() string { return "wrap: " + w.err.Error() }
+func (w Wrap) Unwrap() error { return w.err }
+
+type Multi struct{ errs []error }
+
+func (m Multi) Error() string   { return "multi" }
+func (m Multi) Unwrap() []error { return m.errs }
+
+type Sentinel struct{}
+
+func (Sentinel) Error() string        { return "sentinel" }
+func (Sentinel) Is(target error) bool { return target == io.EOF }
+
+var ErrX = errors.New("x")
+
+func main() {
+	e := &MyErr{3}
+	j := errors.Join(e, io.EOF)
+	fmt.Println(j)
+	fmt.Println(errors.Is(j, io.EOF), errors.Is(j, e))
+
+	var me *MyErr
+	fmt.Println(errors.As(j, &me), me.Code)
+
+	w := fmt.Errorf("a: %w, b: %w", e, ErrX)
+	me = nil
+	fmt.Println(w, errors.Is(w, ErrX), errors.Is(w, e), errors.As(w, &me), me.Code)
+
+	fmt.Println(errors.Is(Wrap{io.EOF}, io.EOF))
+	fmt.Println(errors.Is(Multi{[]error{ErrX}}, ErrX))
+	fmt.Println(errors.Is(fmt.Errorf("z: %w", Sentinel{}), io.EOF))
+	me = nil
+	fmt.Println(errors.As(fmt.Errorf("z: %w", Wrap{e}), &me), me.Code)
+	fmt.Println(errors.Is(&MyErr{3}, e))
+}
+
+// Output:
+// code 3
+// EOF
+// true true
+// true 3
+// a: code 3, b: x true true true 3
+// true
+// true
+// true
+// true 3
+// false
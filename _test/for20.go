@@ -0,0 +1,36 @@
+package main
+
+import "fmt"
+
+func main() {
+	var s []int
+	for i := 0; i < 10; i++ {
+		if i == 2 {
+			i = 7
+		}
+		s = append(s, i)
+	}
+	fmt.Println(s)
+
+	var fs []func() int
+	for i := 0; i < 3; i++ {
+		fs = append(fs, func() int { return i })
+		i++
+	}
+	c := make(chan int, 2)
+	c <- 1
+	c <- 2
+	close(c)
+	for v := range c {
+		fs = append(fs, func() int { return v })
+	}
+	s = s[:0]
+	for _, f := range fs {
+		s = append(s, f())
+	}
+	fmt.Println(s)
+}
+
+// Output:
+// [0 1 7 8 9]
+// [1 3 1 2]
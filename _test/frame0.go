@@ -0,0 +1,40 @@
+package main
+
+import "fmt"
+
+func fib(n int) int {
+	if n < 2 {
+		return n
+	}
+	a := fib(n - 1)
+	b := fib(n - 2)
+	return a + b
+}
+
+func sum(n int) (s float64) {
+	var i int
+	for i = 0; i < n; i++ {
+		x := float64(i) / 2
+		s += x
+	}
+	return s
+}
+
+func label(n int) string {
+	var s string
+	if n > 0 {
+		s = label(n-1) + fmt.Sprint(n)
+	}
+	return s
+}
+
+func main() {
+	fmt.Println(fib(15), fib(10))
+	fmt.Println(sum(10), sum(4))
+	fmt.Println(label(5), label(3))
+}
+
+// Output:
+// 610 55
+// 22.5 3
+// 12345 123
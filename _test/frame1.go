@@ -0,0 +1,42 @@
+package main
+
+import "fmt"
+
+type pair struct{ a, b int }
+
+func build(n int) []int {
+	var s []int
+	for i := 0; i < n; i++ {
+		s = append(s, i)
+	}
+	return s
+}
+
+func swap(p pair) pair {
+	var q pair
+	q.a, q.b = p.b, p.a
+	return q
+}
+
+func depth(n int, acc []int) []int {
+	var l []int
+	if n > 0 {
+		l = depth(n-1, append(acc, n))
+	} else {
+		l = acc
+	}
+	return l
+}
+
+func main() {
+	a := build(3)
+	b := build(2)
+	fmt.Println(a, b)
+	fmt.Println(swap(pair{1, 2}), swap(pair{3, 4}))
+	fmt.Println(depth(3, nil), depth(1, nil))
+}
+
+// Output:
+// [0 1 2] [0 1]
+// {2 1} {4 3}
+// [3 2 1] [1]
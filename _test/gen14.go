@@ -0,0 +1,47 @@
+package main
+
+import "fmt"
+
+type Stack[T any] struct {
+	items []T
+}
+
+func (s *Stack[T]) Push(v T) { s.items = append(s.items, v) }
+
+func (s *Stack[T]) Pop() T {
+	v := s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	return v
+}
+
+func (s *Stack[T]) Len() int { return len(s.items) }
+
+type Lener interface{ Len() int }
+
+type Set[T comparable] map[T]struct{}
+
+func (s Set[T]) Add(v T) { s[v] = struct{}{} }
+
+func (s Set[T]) String() string { return fmt.Sprint("set of ", len(s)) }
+
+func main() {
+	s := &Stack[int]{}
+	s.Push(1)
+	s.Push(2)
+	println(s.Pop(), s.Len())
+
+	var x interface{} = s
+	l, ok := x.(Lener)
+	println(ok, l.Len())
+
+	set := make(Set[string])
+	set.Add("a")
+	set.Add("b")
+	var st fmt.Stringer = set
+	fmt.Println(st)
+}
+
+// Output:
+// 2 1
+// true 1
+// set of 2
@@ -0,0 +1,32 @@
+package main
+
+type Map[K comparable, V any] map[K]V
+
+func (m Map[K, V]) Keys() []K {
+	var keys []K
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (m *Map[K, V]) Reset() { *m = Map[K, V]{} }
+
+func main() {
+	m := make(Map[string, int])
+	m["a"] = 1
+	println(len(m.Keys()))
+
+	c := Map[string, int](map[string]int{"b": 2, "c": 3})
+	println(len(c.Keys()))
+
+	p := new(Map[string, int])
+	p.Reset()
+	(*p)["d"] = 4
+	println(len(p.Keys()))
+}
+
+// Output:
+// 1
+// 2
+// 1
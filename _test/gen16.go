@@ -0,0 +1,53 @@
+package main
+
+import "fmt"
+
+type Signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+type Unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+type Integer interface{ Signed | Unsigned }
+
+type Float interface{ ~float32 | ~float64 }
+
+type Ordered interface {
+	Integer | Float | ~string
+}
+
+type MyInt int
+
+func Max[T Ordered](a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func Abs[T Signed | Float](v T) T {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func Sum[T Integer | Float](s ...T) (r T) {
+	for _, v := range s {
+		r += v
+	}
+	return r
+}
+
+func main() {
+	fmt.Println(Max(1, 2), Max(1.5, 2), Max("a", "b"), Max(MyInt(3), 2))
+	fmt.Println(Abs(-3), Abs(-2.5), Abs(MyInt(-1)))
+	fmt.Println(Sum(1, 2, 3), Sum(1.5, 2), Sum([]uint8{4, 5}...))
+}
+
+// Output:
+// 2 2 b 3
+// 3 2.5 1
+// 6 3.5 9
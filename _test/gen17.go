@@ -0,0 +1,51 @@
+package main
+
+import "fmt"
+
+type Stack[T any] struct {
+	items []T
+}
+
+func Top[T any](s *Stack[T]) T { return s.items[len(s.items)-1] }
+
+func Map[T, U any](s []T, f func(T) U) []U {
+	r := []U{}
+	for _, v := range s {
+		r = append(r, f(v))
+	}
+	return r
+}
+
+func First[S ~[]E, E any](s S) E { return s[0] }
+
+func Keys[M ~map[K]V, K comparable, V any](m M) []K {
+	r := []K{}
+	for k := range m {
+		r = append(r, k)
+	}
+	return r
+}
+
+func Of[S []E, E any](e E) S {
+	var s S
+	return append(s, e)
+}
+
+func New[P *Q, Q any](q Q) P { return &q }
+
+type Names []string
+
+func main() {
+	fmt.Println(Top(&Stack[string]{[]string{"a", "b"}}))
+	fmt.Println(Map([]int{1, 2}, func(i int) string { return fmt.Sprint(i * 2) }))
+	fmt.Println(First(Names{"x", "y"}))
+	fmt.Println(Keys(map[string]bool{"k": true}))
+	fmt.Println(Of(3), *New("q"))
+}
+
+// Output:
+// b
+// [2 4]
+// x
+// [k]
+// [3] q
@@ -7,5 +7,4 @@ func main() {
 }
 
 // Error:
-// import cycle not allowed
-//	imports github.com/breadchris/yaegi/_test/c1
+// import cycle not allowed: github.com/breadchris/yaegi/_test/c1 → github.com/breadchris/yaegi/_test/c2 → github.com/breadchris/yaegi/_test/c1
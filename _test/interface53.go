@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Shape interface{ Area() int }
+
+type Square struct{ side int }
+
+func (s Square) Area() int { return s.side * s.side }
+
+type Rect struct{ w, h int }
+
+func (r *Rect) Area() int { return r.w * r.h }
+
+type Tagged struct {
+	Square
+	tag string
+}
+
+func main() {
+	// The same call site dispatches alternately on several dynamic types.
+	shapes := []Shape{Square{2}, &Rect{2, 3}, Square{3}, Tagged{Square{4}, "t"}, &Rect{1, 1}, Square{5}}
+	var areas []string
+	for _, s := range shapes {
+		areas = append(areas, fmt.Sprint(s.Area()))
+	}
+	var sb fmt.Stringer = &strings.Builder{}
+	fmt.Println(strings.Join(areas, " "), sb.String() == "")
+}
+
+// Output:
+// 4 6 9 16 1 25 true
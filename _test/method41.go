@@ -0,0 +1,36 @@
+package main
+
+import "fmt"
+
+type Counter struct{ n int }
+
+func (c *Counter) Add(v ...int) int {
+	for _, x := range v {
+		c.n += x
+	}
+	return c.n
+}
+
+func (c Counter) Value() int { return c.n }
+
+type Named struct {
+	*Counter
+	name string
+}
+
+type Adder interface{ Add(v ...int) int }
+
+func main() {
+	c := Counter{}
+	nc := Named{&c, "c"}
+	var a Adder = nc
+	for i := 0; i < 3; i++ {
+		c.Add(i)
+		nc.Add(1, 2)
+		a.Add([]int{10}...)
+	}
+	fmt.Println(c.Value(), nc.Value(), nc.name)
+}
+
+// Output:
+// 42 42 c
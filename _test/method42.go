@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type T int
+
+func (t T) Get() int { return int(t) }
+
+func (t *T) Inc() { *t++ }
+
+type Getter interface{ Get() int }
+
+func apply(f func(T) int, t T) int { return f(t) }
+
+func main() {
+	get, inc := T.Get, (*T).Inc
+	t := T(1)
+	inc(&t)
+	fmt.Println(get(t), apply(T.Get, 3), Getter.Get(t))
+
+	str := time.Duration.String
+	write := (*bytes.Buffer).WriteString
+	var b bytes.Buffer
+	write(&b, str(90*time.Second))
+	fmt.Println(b.String(), fmt.Stringer.String(&b))
+
+	m := map[string]func(*strings.Builder, string) (int, error){"write": (*strings.Builder).WriteString}
+	var sb strings.Builder
+	m["write"](&sb, "ok")
+	fmt.Println(sb.String())
+}
+
+// Output:
+// 2 3 2
+// 1m30s 1m30s
+// ok
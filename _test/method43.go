@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Box[T any] struct{ v T }
+
+func (b Box[T]) Get() T { return b.v }
+
+func (b *Box[T]) Set(v T) { b.v = v }
+
+type Counter struct{ n int }
+
+func (c *Counter) Inc() { c.n++ }
+
+type Named struct {
+	Counter
+	*strings.Reader
+}
+
+type Upper struct{}
+
+func (Upper) Rune(r rune) rune { return r - 'a' + 'A' }
+
+type Ints []int
+
+func (s Ints) Less(i, j int) bool { return s[i] < s[j] }
+
+func main() {
+	var b Box[string]
+	(*Box[string]).Set(&b, "box")
+	get := Box[string].Get
+	fmt.Println(get(b))
+
+	n := &Named{Reader: strings.NewReader("abc")}
+	inc, size := (*Named).Inc, Named.Len
+	inc(n)
+	inc(n)
+	fmt.Println(n.n, size(*n))
+
+	s := Ints{3, 1, 2}
+	sort.Slice(s, s.Less)
+	fmt.Println(s, strings.Map(Upper{}.Rune, "abc"))
+}
+
+// Output:
+// box
+// 2 3
+// [1 2 3] ABC
@@ -0,0 +1,60 @@
+package main
+
+import "fmt"
+
+type T struct{ n int }
+
+func (t T) Get() int { return t.n }
+
+func (t T) Show() { fmt.Println("show", t.n) }
+
+func (t *T) PGet() int { return t.n }
+
+type E struct{ T }
+
+type G[X any] struct{ v X }
+
+func (g G[X]) Get() X { return g.v }
+
+func main() {
+	t := T{1}
+	f := t.Get
+	pf := t.PGet
+	t.n = 9
+	fmt.Println(f(), pf())
+
+	p := &T{1}
+	g := p.Get
+	p.n = 9
+	fmt.Println(g())
+
+	e := E{T{1}}
+	h := e.Get
+	e.n = 9
+	fmt.Println(h())
+
+	gt := G[int]{1}
+	k := gt.Get
+	gt.v = 9
+	fmt.Println(k())
+
+	var i interface{ Get() int } = T{1}
+	m := i.Get
+	fmt.Println(m())
+
+	d := T{1}
+	defer d.Show()
+	defer fmt.Println("deferred", d.Get())
+	defer func(f func() int) { fmt.Println("bound", f()) }(d.Get)
+	d.n = 9
+}
+
+// Output:
+// 1 9
+// 1
+// 1
+// 1
+// 1
+// bound 1
+// deferred 1
+// show 1
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+type Celsius float64
+
+const c = min(3, 2.5, 4)
+const s = max("foo", "bar")
+
+func Smallest[T int | float64 | string](a T, rest ...T) T {
+	r := a
+	for _, v := range rest {
+		r = min(r, v)
+	}
+	return r
+}
+
+func main() {
+	x, y := 3, 7
+	fmt.Println(min(x, y), max(x, y), min(x, 1), max(2, x, y, 5))
+	var f float64 = 1.5
+	fmt.Println(min(f, 2), max(f, 2), min(1, 2.5), c, s)
+	fmt.Println(min(math.NaN(), 1), max(1, math.NaN()), math.Signbit(min(0.0, math.Copysign(0, -1))))
+	var u uint8 = 200
+	fmt.Println(max(u, 100), min("b", "a", "c"))
+	t := Celsius(20)
+	fmt.Println(max(t, 25.5))
+	var i interface{} = min(x, y)
+	fmt.Println(i)
+	fmt.Println(Smallest(4, 2, 8), Smallest("z", "b"), Smallest(1.5))
+
+}
+
+// Output:
+// 3 7 1 7
+// 1.5 2 1 2.5 foo
+// NaN NaN true
+// 200 a
+// 25.5
+// 3
+// 2 b 1.5
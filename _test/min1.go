@@ -0,0 +1,9 @@
+package main
+
+func main() {
+	x, f := 1, 2.5
+	println(min(x, f))
+}
+
+// Error:
+// _test/min1.go:5:17: invalid argument: mismatched types int (previous argument) and float64
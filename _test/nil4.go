@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func main() {
+	defer func() {
+		r := recover()
+		_, ok := r.(runtime.Error)
+		fmt.Println(r, ok)
+	}()
+	var p *int
+	fmt.Println(*p)
+}
+
+// Output:
+// runtime error: invalid memory address or nil pointer dereference true
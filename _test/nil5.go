@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type E struct{ m int }
+
+type T struct {
+	n int
+	*E
+}
+
+func try(name string, f func()) {
+	defer func() {
+		err, ok := recover().(runtime.Error)
+		fmt.Println(name, ok, err)
+	}()
+	f()
+}
+
+func main() {
+	var p *T
+	try("read", func() { fmt.Println(p.n) })
+	try("write", func() { p.n = 2 })
+	var t T
+	try("embedded read", func() { fmt.Println(t.E.m) })
+	try("promoted read", func() { fmt.Println(t.m) })
+}
+
+// Output:
+// read true runtime error: invalid memory address or nil pointer dereference
+// write true runtime error: invalid memory address or nil pointer dereference
+// embedded read true runtime error: invalid memory address or nil pointer dereference
+// promoted read true runtime error: invalid memory address or nil pointer dereference
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+type Celsius float64
+
+type Small int8
+
+func main() {
+	s, x := 0, 0.0
+	var t Celsius = 1.5
+	var b Small = 120
+	for i := 0; i < 10; i++ {
+		s += i * 3
+		s -= 10 / (i + 1)
+		s = 7 - s%100
+		x = x*0.5 + 2
+		t *= 2
+		b += 3
+		if 5 <= i && x > 3.9 {
+			s ^= i &^ 1
+		}
+	}
+	f := func() int { return s + 1 }
+	nan := math.NaN()
+	fmt.Println(s, x, t, b, f(), nan == nan, nan != nan, 2 < x)
+}
+
+// Output:
+// -42 3.99609375 1536 -106 -41 false true true
@@ -0,0 +1,47 @@
+package main
+
+import "fmt"
+
+type N int
+
+func (n N) Double() N { return 2 * n }
+
+func main() {
+	var s []int
+	for i := range 3 {
+		s = append(s, i)
+	}
+	n := 0
+	for range 2 {
+		n++
+	}
+	fmt.Println(s, n)
+
+	var i8 int8 = 3
+	for i := range i8 {
+		fmt.Print(i+i8, " ")
+	}
+	var u uint8 = 255
+	c := 0
+	for i := range u {
+		c += int(i)
+	}
+	fmt.Println(c)
+
+	for i := range N(3) {
+		fmt.Print(i.Double(), " ")
+	}
+	for i := range -1 {
+		fmt.Println("unexpected", i)
+	}
+	const k = 2
+	for i := range k {
+		fmt.Print(i)
+	}
+	fmt.Println()
+}
+
+// Output:
+// [0 1 2] 2
+// 3 4 5 32385
+// 0 2 4 01
@@ -0,0 +1,10 @@
+package main
+
+func main() {
+	for i, j := range 10 {
+		println(i, j)
+	}
+}
+
+// Error:
+// _test/range11.go:4:20: range over untyped int permits only one iteration variable
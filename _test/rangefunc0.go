@@ -0,0 +1,108 @@
+package main
+
+import "fmt"
+
+type Seq[V any] func(yield func(V) bool)
+
+func count(n int) func(func(int) bool) {
+	return func(yield func(int) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func pairs(s []string) func(func(int, string) bool) {
+	return func(yield func(int, string) bool) {
+		for i, v := range s {
+			if !yield(i, v) {
+				return
+			}
+		}
+	}
+}
+
+func values[V any](s []V) Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range s {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+func find(n int) int {
+	for i := range count(10) {
+		if i*i > n {
+			return i
+		}
+	}
+	return -1
+}
+
+func main() {
+	for i := range count(5) {
+		if i == 1 {
+			continue
+		}
+		if i == 4 {
+			break
+		}
+		fmt.Println("i", i)
+	}
+
+	for i, s := range pairs([]string{"a", "b", "c"}) {
+		fmt.Println(i, s)
+	}
+
+	var fs []func() int
+	for i := range count(3) {
+		fs = append(fs, func() int { return i })
+	}
+	fmt.Println(fs[0](), fs[1](), fs[2]())
+
+	fmt.Println(find(20))
+
+outer:
+	for i := range count(3) {
+		for j := range count(3) {
+			if j == 2 {
+				continue outer
+			}
+			if i == 2 {
+				break outer
+			}
+			fmt.Println(i, j)
+		}
+	}
+
+	n := 0
+	for range count(4) {
+		n++
+	}
+	fmt.Println("n", n)
+
+	for v := range values([]float64{1.5, 2.5}) {
+		fmt.Println(v)
+	}
+}
+
+// Output:
+// i 0
+// i 2
+// i 3
+// 0 a
+// 1 b
+// 2 c
+// 0 1 2
+// 5
+// 0 0
+// 0 1
+// 1 0
+// 1 1
+// n 4
+// 1.5
+// 2.5
@@ -0,0 +1,92 @@
+package main
+
+import "fmt"
+
+func count(n int) func(func(int) bool) {
+	return func(yield func(int) bool) {
+		defer fmt.Println("done", n)
+		for i := 0; i < n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func search(x int) (int, int) {
+	for i := range count(3) {
+		for j := range count(4) {
+			if i*j == x {
+				return i, j
+			}
+		}
+	}
+	return -1, -1
+}
+
+func deferred() {
+	for i := range count(2) {
+		defer fmt.Println("deferred", i)
+	}
+	fmt.Println("end of deferred")
+}
+
+func main() {
+	fmt.Println(search(6))
+
+outer:
+	for i := range count(3) {
+		for j := range count(3) {
+			if i+j == 3 {
+				break outer
+			}
+			if j > i {
+				continue outer
+			}
+			fmt.Println(i, j)
+		}
+	}
+
+	for i := range count(5) {
+		if i == 2 {
+			goto end
+		}
+		fmt.Println("goto", i)
+	}
+end:
+	deferred()
+
+	defer func() {
+		fmt.Println("recovered:", recover())
+	}()
+	bad := func(yield func(int) bool) {
+		yield(1)
+		yield(2)
+	}
+	for i := range bad {
+		fmt.Println("bad", i)
+		break
+	}
+}
+
+// Output:
+// done 4
+// done 4
+// done 4
+// done 3
+// 2 3
+// 0 0
+// done 3
+// 1 0
+// 1 1
+// done 3
+// done 3
+// goto 0
+// goto 1
+// done 5
+// done 2
+// end of deferred
+// deferred 1
+// deferred 0
+// bad 1
+// recovered: runtime error: range function continued iteration after function for loop body returned false
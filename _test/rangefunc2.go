@@ -0,0 +1,11 @@
+package main
+
+func main() {
+	seq := func(yield func(int) bool) {}
+	for i, j := range seq {
+		println(i, j)
+	}
+}
+
+// Error:
+// _test/rangefunc2.go:5:20: range over seq permits only one iteration variable
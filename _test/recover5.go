@@ -0,0 +1,27 @@
+package main
+
+import "fmt"
+
+type MyErr struct{ Code int }
+
+func (e *MyErr) Error() string { return fmt.Sprint("code ", e.Code) }
+
+func check(r interface{}) {
+	if e, ok := r.(*MyErr); ok {
+		fmt.Println("MyErr", e.Code)
+	}
+	if err, ok := r.(error); ok {
+		fmt.Println("error", err.Error())
+	}
+}
+
+func main() {
+	defer func() {
+		check(recover())
+	}()
+	panic(&MyErr{3})
+}
+
+// Output:
+// MyErr 3
+// error code 3
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+type P struct{ n int }
+
+func (p P) String() string { return fmt.Sprint("P", p.n) }
+
+type E struct{ s string }
+
+func (e *E) Error() string { return e.s }
+
+func callback() (s fmt.Stringer) {
+	defer func() { s = recover().(fmt.Stringer) }()
+	strings.Map(func(r rune) rune {
+		sort.Slice([]int{1, 2}, func(i, j int) bool { panic(P{5}) })
+		return r
+	}, "a")
+	return nil
+}
+
+func closure() (r interface{}) {
+	g := func() { r = recover() }
+	defer g()
+	panic("closure")
+}
+
+func deferred() (r interface{}) {
+	defer func() { r = recover() }()
+	defer fmt.Println("still run")
+	defer func() { panic("second") }()
+	panic("first")
+}
+
+func host() {
+	funcs := template.FuncMap{"f": func() string { panic(&E{"custom"}) }}
+	t := template.Must(template.New("").Funcs(funcs).Parse("{{f}}"))
+	fmt.Println(t.Execute(os.Stdout, nil))
+}
+
+func main() {
+	fmt.Println(callback().String())
+	fmt.Println(closure())
+	fmt.Println(deferred())
+	host()
+}
+
+// Output:
+// P5
+// closure
+// still run
+// second
+// template: :1:2: executing "" at <f>: error calling f: custom
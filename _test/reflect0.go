@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+type Base struct{ ID int }
+
+type T struct {
+	Base
+	Name string `json:"name"`
+}
+
+func (t T) String() string { return "T:" + t.Name }
+
+func typeOf(x interface{}) reflect.Type { return reflect.TypeOf(x) }
+
+func main() {
+	t := T{Base{1}, "a"}
+	rt := typeOf(t)
+	fmt.Println(rt.Kind(), rt.NumField(), rt.Field(0).Anonymous, rt.Field(1).Tag.Get("json"))
+	fmt.Println(reflect.ValueOf(interface{}(t)).Field(1))
+
+	s := []interface{}{t, 2}
+	fmt.Println(s)
+	b, err := json.Marshal(s)
+	fmt.Println(string(b), err)
+}
+
+// Output:
+// struct 2 true name
+// a
+// [T:a 2]
+// [{"ID":1,"name":"a"},2] <nil>
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func main() {
+	s := ""
+	var prefixes []string
+	for i := 0; i < 100; i++ {
+		s += "ab"
+		prefixes = append(prefixes, s)
+	}
+
+	// Appending to earlier results must not alter later ones, and conversely.
+	t := prefixes[49]
+	t += "X"
+	u := prefixes[99]
+	u += "Y"
+	s += "Z"
+
+	fmt.Println(len(s), strings.Count(s, "ab"), s[len(s)-3:])
+	fmt.Println(len(t), t[len(t)-3:], prefixes[49] == strings.Repeat("ab", 50))
+	fmt.Println(len(u), u[len(u)-3:], prefixes[99] == strings.Repeat("ab", 100))
+}
+
+// Output:
+// 201 100 abZ
+// 101 abX true
+// 201 abY true
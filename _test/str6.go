@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func main() {
+	s := ""
+	var prefixes []string
+	for i := 0; i < 100; i++ {
+		s = s + "a"
+		s = s + fmt.Sprint(i%10)
+		prefixes = append(prefixes, s)
+	}
+
+	// Appending to earlier results must not alter later ones, and conversely.
+	t := prefixes[49]
+	t = t + "X"
+	s = s + "Z"
+
+	fmt.Println(len(s), strings.Count(s, "a"), s[len(s)-3:])
+	fmt.Println(len(t), t[len(t)-3:], prefixes[49][len(prefixes[49])-2:], prefixes[99][len(prefixes[99])-2:])
+}
+
+// Output:
+// 201 100 a9Z
+// 101 a9X a9 a9
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/breadchris/yaegi/interp"
+	"github.com/breadchris/yaegi/stdlib"
+)
+
+func exportCmd(arg []string) error {
+	var name string
+	var output string
+	var tags string
+
+	eflag := flag.NewFlagSet("export", flag.ContinueOnError)
+	eflag.StringVar(&name, "name", "", "the name of the generated package")
+	eflag.StringVar(&output, "o", "", "write the generated package to the named file instead of stdout")
+	eflag.StringVar(&tags, "tags", "", "set a list of build tags")
+	eflag.Usage = func() {
+		fmt.Println("Usage: yaegi export [options] package")
+		fmt.Println("Generate a compiled package of typed wrappers calling the functions of an interpreted package.")
+		fmt.Println("Options:")
+		eflag.PrintDefaults()
+	}
+
+	if err := eflag.Parse(arg); err != nil {
+		return err
+	}
+
+	args := eflag.Args()
+	if len(args) != 1 {
+		return errors.New("missing package")
+	}
+	pkg := args[0]
+
+	if name == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		name = filepath.Base(wd)
+	}
+
+	i := interp.New(interp.Options{GoPath: build.Default.GOPATH, BuildTags: strings.Split(tags, ",")})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		return err
+	}
+	if _, err := i.CompilePath(pkg); err != nil {
+		return err
+	}
+
+	src, err := i.GenerateWrappers(pkg, name)
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(output, src, 0o644)
+}
@@ -20,6 +20,7 @@ func extractCmd(arg []string) error {
 	var exclude string
 	var include string
 	var tag string
+	var generics bool
 
 	eflag := flag.NewFlagSet("run", flag.ContinueOnError)
 	eflag.StringVar(&licensePath, "license", "", "path to a LICENSE file")
@@ -27,6 +28,7 @@ func extractCmd(arg []string) error {
 	eflag.StringVar(&exclude, "exclude", "", "comma separated list of regexp matching symbols to exclude")
 	eflag.StringVar(&include, "include", "", "comma separated list of regexp matching symbols to include")
 	eflag.StringVar(&tag, "tag", "", "comma separated list of build tags to be added to the created package")
+	eflag.BoolVar(&generics, "generics", false, "extract all the generic functions and types, not only the ones marked with //yaegi:add")
 	eflag.Usage = func() {
 		fmt.Println("Usage: yaegi extract [options] packages...")
 		fmt.Println("Options:")
@@ -56,8 +58,9 @@ func extractCmd(arg []string) error {
 		name = filepath.Base(wd)
 	}
 	ext := extract.Extractor{
-		Dest:    name,
-		License: license,
+		Dest:     name,
+		License:  license,
+		Generics: generics,
 	}
 	if tag != "" {
 		ext.Tag = strings.Split(tag, ",")
@@ -70,8 +73,6 @@ func extractCmd(arg []string) error {
 		ext.Include = strings.Split(include, ",")
 	}
 
-	r := strings.NewReplacer("/", "-", ".", "_", "~", "_")
-
 	for _, pkgIdent := range args {
 		var buf bytes.Buffer
 		importPath, err := ext.Extract(pkgIdent, name, &buf)
@@ -80,7 +81,7 @@ func extractCmd(arg []string) error {
 			continue
 		}
 
-		oFile := r.Replace(importPath) + ".go"
+		oFile := extract.FileName(importPath)
 		f, err := os.Create(oFile)
 		if err != nil {
 			return err
@@ -10,6 +10,7 @@ Usage:
 
 The commands are:
 
+    export      generate a compiled wrapper package from an interpreted package
     extract     generate a wrapper file from a source package
     help        print usage information
     run         execute a Go program from source
@@ -29,6 +30,8 @@ func help(arg []string) error {
 	}
 
 	switch cmd {
+	case Export:
+		return exportCmd([]string{"-h"})
 	case Extract:
 		return extractCmd([]string{"-h"})
 	case Help, "", "-h", "--help":
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"go/build"
 	"os"
+	"os/signal"
 	"reflect"
 	"strconv"
 	"strings"
+	gosyscall "syscall"
 
 	"github.com/breadchris/yaegi/interp"
 	"github.com/breadchris/yaegi/stdlib"
@@ -19,6 +23,9 @@ import (
 func run(arg []string) error {
 	var interactive bool
 	var noAutoImport bool
+	var watch bool
+	var cpuProfile string
+	var record, replay string
 	var tags string
 	var cmd string
 	var err error
@@ -35,6 +42,10 @@ func run(arg []string) error {
 	rflag.StringVar(&tags, "tags", "", "set a list of build tags")
 	rflag.BoolVar(&useUnsafe, "unsafe", useUnsafe, "include unsafe symbols")
 	rflag.BoolVar(&noAutoImport, "noautoimport", false, "do not auto import pre-compiled packages. Import names that would result in collisions (e.g. rand from crypto/rand and rand from math/rand) are automatically renamed (crypto_rand and math_rand)")
+	rflag.BoolVar(&watch, "watch", false, "watch source files and re-evaluate them on change")
+	rflag.StringVar(&cpuProfile, "cpuprofile", "", "write a wall-clock profile of interpreted code to the specified file")
+	rflag.StringVar(&record, "record", "", "record the nondeterministic inputs of the run to the specified file")
+	rflag.StringVar(&replay, "replay", "", "replay the run with the inputs recorded in the specified file")
 	rflag.StringVar(&cmd, "e", "", "set the command to be executed (instead of script or/and shell)")
 	rflag.Usage = func() {
 		fmt.Println("Usage: yaegi run [options] [path] [args]")
@@ -46,11 +57,29 @@ func run(arg []string) error {
 	}
 	args := rflag.Args()
 
+	var rec, rep *interp.Recording
+	if replay != "" {
+		if rep, err = loadRecording(replay); err != nil {
+			return err
+		}
+	}
+	if record != "" {
+		rec = &interp.Recording{}
+		defer func() {
+			if err := saveRecording(record, rec); err != nil {
+				showError(err)
+			}
+		}()
+	}
+
 	i := interp.New(interp.Options{
 		GoPath:       build.Default.GOPATH,
 		BuildTags:    strings.Split(tags, ","),
-		Env:          os.Environ(),
+		Env:          environ(useUnrestricted),
 		Unrestricted: useUnrestricted,
+		Unsafe:       unsafePolicy(useUnsafe),
+		Record:       rec,
+		Replay:       rep,
 	})
 	if err := i.Use(stdlib.Symbols); err != nil {
 		return err
@@ -85,6 +114,22 @@ func run(arg []string) error {
 		}
 	}
 
+	if cpuProfile != "" {
+		f, err := os.Create(cpuProfile)
+		if err != nil {
+			return err
+		}
+		if err := i.StartProfile(f); err != nil {
+			return err
+		}
+		defer func() {
+			if err := i.StopProfile(); err != nil {
+				showError(err)
+			}
+			f.Close()
+		}()
+	}
+
 	if cmd != "" {
 		if !noAutoImport {
 			i.ImportUsed()
@@ -112,11 +157,23 @@ func run(arg []string) error {
 	os.Args = arg
 	flag.CommandLine = flag.NewFlagSet(path, flag.ExitOnError)
 
+	stopSignals := func() {}
+	if !useUnrestricted {
+		// Signal handlers of interpreted code are virtualized in restricted mode.
+		stopSignals = relaySignals(i)
+	}
+
+	if watch {
+		defer stopSignals()
+		return i.Watch(context.Background(), path, showError)
+	}
+
 	if isFile(path) {
 		err = runFile(i, path, noAutoImport)
 	} else {
 		_, err = i.EvalPath(path)
 	}
+	stopSignals()
 
 	if err != nil {
 		return err
@@ -128,6 +185,77 @@ func run(arg []string) error {
 	return err
 }
 
+// loadRecording reads the recording of a run from the JSON file path.
+func loadRecording(path string) (*interp.Recording, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rec := &interp.Recording{}
+	if err := json.Unmarshal(b, rec); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rec, nil
+}
+
+// saveRecording writes the recording of a run to the JSON file path.
+func saveRecording(path string, rec *interp.Recording) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// relaySignals relays the interrupt and termination signals of the process to
+// the signal handlers of interpreted code, until the returned function is
+// called. A signal not handled by interpreted code terminates the process, as
+// by default.
+func relaySignals(i *interp.Interpreter) (stop func()) {
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, os.Interrupt, gosyscall.SIGTERM)
+	go func() {
+		for {
+			select {
+			case sig := <-c:
+				if i.Notify(sig) {
+					continue
+				}
+				signal.Reset(sig)
+				if p, err := os.FindProcess(os.Getpid()); err == nil && p.Signal(sig) == nil {
+					continue
+				}
+				os.Exit(1)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(c)
+		close(done)
+	}
+}
+
+// environ returns the environment of the interpreter: a copy of the process
+// one, or nil in unrestricted mode to give access to the process one.
+func environ(unrestricted bool) []string {
+	if unrestricted {
+		return nil
+	}
+	return os.Environ()
+}
+
+// unsafePolicy returns the policy of use of the unsafe package, given the
+// unsafe flag.
+func unsafePolicy(useUnsafe bool) interp.UnsafePolicy {
+	if useUnsafe {
+		return interp.UnsafeUnrestricted
+	}
+	return interp.UnsafeForbidden
+}
+
 func isFile(path string) bool {
 	fi, err := os.Stat(path)
 	return err == nil && fi.Mode().IsRegular()
@@ -119,8 +119,9 @@ func test(arg []string) (err error) {
 	i := interp.New(interp.Options{
 		GoPath:       build.Default.GOPATH,
 		BuildTags:    strings.Split(tags, ","),
-		Env:          os.Environ(),
+		Env:          environ(useUnrestricted),
 		Unrestricted: useUnrestricted,
+		Unsafe:       unsafePolicy(useUnsafe),
 	})
 	if err := i.Use(stdlib.Symbols); err != nil {
 		return err
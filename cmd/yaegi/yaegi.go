@@ -45,6 +45,10 @@ an explicit import is still necessary).
 Note that the source packages are always interpreted in file mode,
 even if imported from REPL.
 
+The documentation of a symbol can be printed in the REPL with the ":doc"
+command, followed by the package and the symbol names, as in
+":doc strings.ToUpper".
+
 The following extract is a valid executable script:
 
 	#!/usr/bin/env yaegi
@@ -106,6 +110,7 @@ import (
 )
 
 const (
+	Export  = "export"
 	Extract = "extract"
 	Help    = "help"
 	Run     = "run"
@@ -127,6 +132,8 @@ func main() {
 	}
 
 	switch cmd {
+	case Export:
+		err = exportCmd(os.Args[2:])
 	case Extract:
 		err = extractCmd(os.Args[2:])
 	case Help, "-h", "--help":
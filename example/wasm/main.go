@@ -0,0 +1,65 @@
+//go:build js && wasm
+
+// Command wasm runs the interpreter in a browser, or any JavaScript host
+// of Go WebAssembly programs. It exports to JavaScript the function
+// yaegiEval(src), which evaluates src in a persistent interpreter, and
+// returns a promise of an object with the output, result and error of the
+// evaluation.
+//
+// Build it, and serve it with index.html and the wasm_exec.js support file
+// of the Go distribution:
+//
+//	GOOS=js GOARCH=wasm go build -o example/wasm/main.wasm ./example/wasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" example/wasm
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"syscall/js"
+
+	"github.com/breadchris/yaegi/interp"
+	"github.com/breadchris/yaegi/stdlib"
+)
+
+func main() {
+	var out bytes.Buffer
+	i := interp.New(interp.Options{Stdin: strings.NewReader(""), Stdout: &out, Stderr: &out})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		panic(err)
+	}
+
+	var mutex sync.Mutex // serializes the evaluations
+	eval := func(src string) map[string]interface{} {
+		mutex.Lock()
+		defer mutex.Unlock()
+		out.Reset()
+		res := map[string]interface{}{}
+		v, err := i.Eval(src)
+		if err != nil {
+			res["error"] = err.Error()
+		} else if v.IsValid() && v.CanInterface() {
+			res["result"] = fmt.Sprintf("%v", v)
+		}
+		res["output"] = out.String()
+		return res
+	}
+
+	js.Global().Set("yaegiEval", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) != 1 || args[0].Type() != js.TypeString {
+			return js.Global().Get("Promise").Call("reject", "yaegiEval: expected a source string")
+		}
+		src := args[0].String()
+		// The evaluation may block, e.g. on a channel or a timer, which is
+		// not allowed in a callback: it runs in its own goroutine.
+		return js.Global().Get("Promise").New(js.FuncOf(func(this js.Value, p []js.Value) interface{} {
+			resolve := p[0]
+			go func() { resolve.Invoke(eval(src)) }()
+			return nil
+		}))
+	}))
+
+	select {}
+}
@@ -8,9 +8,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"go/ast"
 	"go/constant"
 	"go/format"
 	"go/importer"
+	"go/parser"
 	"go/token"
 	"go/types"
 	"io"
@@ -82,6 +84,8 @@ func init() {
 		{{range $m := $value.Method -}}
 		W{{$m.Name}} func{{$m.Param}} {{$m.Result}}
 		{{end}}
+		{{- if $value.Fallback}}{{$value.Fallback}}
+		{{end}}
 	}
 	{{range $m := $value.Method -}}
 		func (W {{$value.Name}}) {{$m.Name}}{{$m.Param}} {{$m.Result}} {
@@ -111,6 +115,11 @@ type Method struct {
 type Wrap struct {
 	Name   string
 	Method []Method
+
+	// Fallback is the interface type embedded in the wrapper if it has
+	// unexported methods, which the interpreter sets to a binary value
+	// implementing them, embedded in the interpreted one.
+	Fallback string
 }
 
 // restricted map defines symbols for which a special implementation is provided.
@@ -141,9 +150,91 @@ type Extractor struct {
 	Exclude []string // Comma separated list of regexp matching symbols to exclude.
 	Include []string // Comma separated list of regexp matching symbols to include.
 	Tag     []string // Comma separated of build tags to be added to the created package.
+
+	// Generics extracts the source of all the exported generic functions and
+	// types, instead of only the ones marked with a //yaegi:add directive.
+	Generics bool
+}
+
+// Exports describes the symbols extracted from a package.
+type Exports struct {
+	ImportPath string            // import path of the package
+	PkgName    string            // key of the package in the generated Symbols map
+	Val        map[string]Val    // functions, constants and variables, indexed by symbol name
+	Typ        map[string]string // types, indexed by symbol name
+	Wrap       map[string]Wrap   // interface wrappers, indexed by interface name
+}
+
+// GeneratedFile is a Go source file generated by Extract.
+type GeneratedFile struct {
+	Name    string // file name, derived from the package import path
+	Content []byte // formatted Go source
 }
 
-func (e *Extractor) genContent(importPath string, p *types.Package, fset *token.FileSet) ([]byte, error) {
+// Options are the options of Extract.
+type Options struct {
+	Dest    string   // The name of the created package.
+	License string   // License text to be included in the created package, optional.
+	Exclude []string // List of regexp matching symbols to exclude.
+	Include []string // List of regexp matching symbols to include.
+	Tag     []string // List of build tags to be added to the created package.
+
+	// ImportPath is the import path of the package when pkgPath is a local
+	// directory. If empty, it is obtained from the go.mod file of the directory.
+	ImportPath string
+
+	// Generics extracts the source of all the exported generic functions and
+	// types, to be passed to interp.GenericFunc and interp.GenericType, instead
+	// of only the ones marked with a //yaegi:add directive.
+	Generics bool
+}
+
+// Extract generates the wrappers of the symbols of the package located at
+// pkgPath, which can be an import path or a local directory path relative to
+// the current working directory. The working directory is not changed.
+// It returns the generated source file, and a description of the extracted
+// symbols. Extract is the programmatic equivalent of the yaegi extract command.
+func Extract(pkgPath string, opts Options) (GeneratedFile, Exports, error) {
+	e := Extractor{
+		Dest:    opts.Dest,
+		License: opts.License,
+		Exclude: opts.Exclude,
+		Include: opts.Include,
+		Tag:     opts.Tag,
+
+		Generics: opts.Generics,
+	}
+	content, exports, err := e.extract(pkgPath, opts.ImportPath)
+	if err != nil {
+		return GeneratedFile{}, Exports{}, err
+	}
+	return GeneratedFile{Name: FileName(exports.ImportPath), Content: content}, exports, nil
+}
+
+// Generate returns the source of the wrappers of the symbols of the package
+// located at pkgPath, as Extract, e.g. to generate from a build pipeline the
+// Exports of private packages to be passed to interp.Use:
+//
+//	src, err := extract.Generate("./internal/billing", extract.Options{
+//		Dest:       "symbols",
+//		ImportPath: "example.com/app/internal/billing",
+//		Generics:   true,
+//	})
+func Generate(pkgPath string, opts Options) ([]byte, error) {
+	file, _, err := Extract(pkgPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	return file.Content, nil
+}
+
+// FileName returns the name of the file generated for the package of the
+// given import path.
+func FileName(importPath string) string {
+	return strings.NewReplacer("/", "-", ".", "_", "~", "_").Replace(importPath) + ".go"
+}
+
+func (e *Extractor) genContent(importPath string, p *types.Package, fset *token.FileSet) ([]byte, Exports, error) {
 	prefix := "_" + importPath + "_"
 	prefix = strings.NewReplacer("/", "_", "-", "_", ".", "_", "~", "_").Replace(prefix)
 
@@ -163,6 +254,68 @@ func (e *Extractor) genContent(importPath string, p *types.Package, fset *token.
 		return pkg.Name()
 	}
 
+	// ifaceMethods returns the exported methods of interface t, for its wrapper.
+	ifaceMethods := func(t *types.Interface) []Method {
+		var methods []Method
+		for i := 0; i < t.NumMethods(); i++ {
+			f := t.Method(i)
+			if !f.Exported() {
+				continue
+			}
+
+			sign := f.Type().(*types.Signature)
+			args := make([]string, sign.Params().Len())
+			params := make([]string, len(args))
+			for j := range args {
+				v := sign.Params().At(j)
+				if args[j] = v.Name(); args[j] == "" {
+					args[j] = fmt.Sprintf("a%d", j)
+				}
+				// process interface method variadic parameter
+				if sign.Variadic() && j == len(args)-1 { // check is last arg
+					// only replace the first "[]" to "..."
+					at := types.TypeString(v.Type(), qualify)[2:]
+					params[j] = args[j] + " ..." + at
+					args[j] += "..."
+				} else {
+					params[j] = args[j] + " " + types.TypeString(v.Type(), qualify)
+				}
+			}
+			arg := "(" + strings.Join(args, ", ") + ")"
+			param := "(" + strings.Join(params, ", ") + ")"
+
+			results := make([]string, sign.Results().Len())
+			for j := range results {
+				v := sign.Results().At(j)
+				results[j] = v.Name() + " " + types.TypeString(v.Type(), qualify)
+			}
+			result := "(" + strings.Join(results, ", ") + ")"
+
+			ret := ""
+			if sign.Results().Len() > 0 {
+				ret = "return"
+			}
+
+			methods = append(methods, Method{f.Name(), param, result, arg, ret})
+		}
+		return methods
+	}
+
+	// ifaceFallback returns the interface type name to embed in the wrapper of
+	// interface t if it has unexported methods, or an empty string.
+	ifaceFallback := func(t *types.Interface, name string) string {
+		for i := 0; i < t.NumMethods(); i++ {
+			if !t.Method(i).Exported() {
+				return name
+			}
+		}
+		return ""
+	}
+
+	// Instances of generic interfaces of the package, used in its API.
+	instances := map[string]*types.Named{}
+	seen := map[types.Type]bool{}
+
 	for _, name := range sc.Names() {
 		o := sc.Lookup(name)
 		if !o.Exported() {
@@ -172,7 +325,7 @@ func (e *Extractor) genContent(importPath string, p *types.Package, fset *token.
 		if len(e.Include) > 0 {
 			match, err := matchList(name, e.Include)
 			if err != nil {
-				return nil, err
+				return nil, Exports{}, err
 			}
 			if !match {
 				// Explicitly defined include expressions force non matching symbols to be skipped.
@@ -182,7 +335,7 @@ func (e *Extractor) genContent(importPath string, p *types.Package, fset *token.
 
 		match, err := matchList(name, e.Exclude)
 		if err != nil {
-			return nil, err
+			return nil, Exports{}, err
 		}
 		if match {
 			continue
@@ -215,15 +368,15 @@ func (e *Extractor) genContent(importPath string, p *types.Package, fset *token.
 
 				f, err := os.Open(ff.Name())
 				if err != nil {
-					return nil, err
+					return nil, Exports{}, err
 				}
 				b := make([]byte, end-start)
 				_, err = f.ReadAt(b, int64(start))
 				if err != nil {
-					return nil, err
+					return nil, Exports{}, err
 				}
 				// only add if we have a //yaegi:add directive
-				if !bytes.Contains(b, []byte(`//yaegi:add`)) {
+				if !e.Generics && !bytes.Contains(b, []byte(`//yaegi:add`)) {
 					continue
 				}
 				val[name] = Val{fmt.Sprintf("interp.GenericFunc(%q)", b), false}
@@ -231,13 +384,26 @@ func (e *Extractor) genContent(importPath string, p *types.Package, fset *token.
 				continue
 			}
 			val[name] = Val{pname, false}
+			genericInstances(o.Type(), p, seen, instances)
 		case *types.Var:
 			val[name] = Val{pname, true}
+			genericInstances(o.Type(), p, seen, instances)
 		case *types.TypeName:
-			// Skip type if it is generic.
+			// Generic types are extracted as code, along with their methods.
 			if t, ok := o.Type().(*types.Named); ok && t.TypeParams().Len() > 0 {
+				b, err := genericTypeSource(fset, o, t)
+				if err != nil {
+					return nil, Exports{}, err
+				}
+				// only add if we have a //yaegi:add directive
+				if !e.Generics && !bytes.Contains(b, []byte(`//yaegi:add`)) {
+					continue
+				}
+				val[name] = Val{fmt.Sprintf("interp.GenericType(%q)", b), false}
+				imports["github.com/breadchris/yaegi/interp"] = true
 				continue
 			}
+			genericInstances(o.Type(), p, seen, instances)
 			typ[name] = pname
 			if t, ok := o.Type().Underlying().(*types.Interface); ok {
 				if t.NumMethods() == 0 && t.NumEmbeddeds() != 0 {
@@ -245,53 +411,21 @@ func (e *Extractor) genContent(importPath string, p *types.Package, fset *token.
 					delete(typ, name)
 					continue
 				}
-				var methods []Method
-				for i := 0; i < t.NumMethods(); i++ {
-					f := t.Method(i)
-					if !f.Exported() {
-						continue
-					}
-
-					sign := f.Type().(*types.Signature)
-					args := make([]string, sign.Params().Len())
-					params := make([]string, len(args))
-					for j := range args {
-						v := sign.Params().At(j)
-						if args[j] = v.Name(); args[j] == "" {
-							args[j] = fmt.Sprintf("a%d", j)
-						}
-						// process interface method variadic parameter
-						if sign.Variadic() && j == len(args)-1 { // check is last arg
-							// only replace the first "[]" to "..."
-							at := types.TypeString(v.Type(), qualify)[2:]
-							params[j] = args[j] + " ..." + at
-							args[j] += "..."
-						} else {
-							params[j] = args[j] + " " + types.TypeString(v.Type(), qualify)
-						}
-					}
-					arg := "(" + strings.Join(args, ", ") + ")"
-					param := "(" + strings.Join(params, ", ") + ")"
-
-					results := make([]string, sign.Results().Len())
-					for j := range results {
-						v := sign.Results().At(j)
-						results[j] = v.Name() + " " + types.TypeString(v.Type(), qualify)
-					}
-					result := "(" + strings.Join(results, ", ") + ")"
-
-					ret := ""
-					if sign.Results().Len() > 0 {
-						ret = "return"
-					}
-
-					methods = append(methods, Method{f.Name(), param, result, arg, ret})
-				}
-				wrap[name] = Wrap{prefix + name, methods}
+				wrap[name] = Wrap{prefix + name, ifaceMethods(t), ifaceFallback(t, pname)}
 			}
 		}
 	}
 
+	// Generic interfaces can not be extracted, but their instances can, in
+	// order to let interpreted values implement them when passed to binary
+	// functions. Their names are those of reflect, which the interpreter
+	// uses to find their wrappers.
+	for name, t := range instances {
+		typ[name] = types.TypeString(t, qualify)
+		it := t.Underlying().(*types.Interface)
+		wrap[name] = Wrap{prefix + instanceReplacer.Replace(name), ifaceMethods(it), ifaceFallback(it, typ[name])}
+	}
+
 	// Generate buildTags with Go version only for stdlib packages.
 	// Third party packages do not depend on Go compiler version by default.
 	var buildTags string
@@ -299,14 +433,14 @@ func (e *Extractor) genContent(importPath string, p *types.Package, fset *token.
 		var err error
 		buildTags, err = genBuildTags()
 		if err != nil {
-			return nil, err
+			return nil, Exports{}, err
 		}
 	}
 
 	base := template.New("extract")
 	parse, err := base.Parse(model)
 	if err != nil {
-		return nil, fmt.Errorf("template parsing error: %w", err)
+		return nil, Exports{}, fmt.Errorf("template parsing error: %w", err)
 	}
 
 	if importPath == "log/syslog" {
@@ -349,15 +483,143 @@ func (e *Extractor) genContent(importPath string, p *types.Package, fset *token.
 	}
 	err = parse.Execute(b, data)
 	if err != nil {
-		return nil, fmt.Errorf("template error: %w", err)
+		return nil, Exports{}, fmt.Errorf("template error: %w", err)
 	}
 
 	// gofmt
 	source, err := format.Source(b.Bytes())
 	if err != nil {
-		return nil, fmt.Errorf("failed to format source: %w: %s", err, b.Bytes())
+		return nil, Exports{}, fmt.Errorf("failed to format source: %w: %s", err, b.Bytes())
 	}
-	return source, nil
+	exports := Exports{
+		ImportPath: importPath,
+		PkgName:    path.Join(importPath, p.Name()),
+		Val:        val,
+		Typ:        typ,
+		Wrap:       wrap,
+	}
+	return source, exports, nil
+}
+
+// instanceReplacer converts the name of a generic type instance to an identifier.
+var instanceReplacer = strings.NewReplacer("[", "_", "]", "_", ",", "_", ".", "_", "/", "_", "*", "_", "-", "_", " ", "")
+
+// genericInstances records in found the instances of generic interfaces of
+// package pkg used in type t, indexed by their reflect name, e.g.
+// "Getter[int]". Types in seen are already visited.
+func genericInstances(t types.Type, pkg *types.Package, seen map[types.Type]bool, found map[string]*types.Named) {
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+
+	switch t := t.(type) {
+	case *types.Named:
+		if args := t.TypeArgs(); args.Len() > 0 {
+			names := make([]string, args.Len())
+			for i := range names {
+				a := args.At(i)
+				genericInstances(a, pkg, seen, found)
+				names[i] = types.TypeString(a, (*types.Package).Path)
+			}
+			_, isIface := t.Underlying().(*types.Interface)
+			if o := t.Origin().Obj(); isIface && o.Pkg() == pkg && o.Exported() {
+				found[o.Name()+"["+strings.Join(names, ",")+"]"] = t
+			}
+		}
+		if t.TypeParams().Len() > 0 && t.TypeArgs().Len() == 0 {
+			return // generic type declaration
+		}
+		for i := 0; i < t.NumMethods(); i++ {
+			if m := t.Method(i); m.Exported() {
+				genericInstances(m.Type(), pkg, seen, found)
+			}
+		}
+		if t.Obj().Pkg() == pkg {
+			genericInstances(t.Underlying(), pkg, seen, found)
+		}
+	case *types.Signature:
+		genericInstances(t.Params(), pkg, seen, found)
+		genericInstances(t.Results(), pkg, seen, found)
+	case *types.Tuple:
+		for i := 0; i < t.Len(); i++ {
+			genericInstances(t.At(i).Type(), pkg, seen, found)
+		}
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if f := t.Field(i); f.Exported() || f.Embedded() {
+				genericInstances(f.Type(), pkg, seen, found)
+			}
+		}
+	case *types.Interface:
+		for i := 0; i < t.NumMethods(); i++ {
+			genericInstances(t.Method(i).Type(), pkg, seen, found)
+		}
+	case *types.Pointer:
+		genericInstances(t.Elem(), pkg, seen, found)
+	case *types.Slice:
+		genericInstances(t.Elem(), pkg, seen, found)
+	case *types.Array:
+		genericInstances(t.Elem(), pkg, seen, found)
+	case *types.Map:
+		genericInstances(t.Key(), pkg, seen, found)
+		genericInstances(t.Elem(), pkg, seen, found)
+	case *types.Chan:
+		genericInstances(t.Elem(), pkg, seen, found)
+	}
+}
+
+// genericTypeSource returns the declaration of the generic type o, including
+// its line comment, followed by the declarations of its methods.
+func genericTypeSource(fset *token.FileSet, o *types.TypeName, t *types.Named) ([]byte, error) {
+	ff := fset.File(o.Pos())
+	src, err := os.ReadFile(ff.Name())
+	if err != nil {
+		return nil, err
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), ff.Name(), src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	offset := ff.Offset(o.Pos())
+	for _, d := range f.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, sp := range gd.Specs {
+			ts := sp.(*ast.TypeSpec)
+			if int(ts.Name.Pos()-f.FileStart) != offset {
+				continue
+			}
+			end := ts.End()
+			if ts.Comment != nil {
+				end = ts.Comment.End()
+			}
+			b.WriteString("type ")
+			b.Write(src[ts.Pos()-f.FileStart : end-f.FileStart])
+			b.WriteString("\n")
+		}
+	}
+	if b.Len() == 0 {
+		return nil, fmt.Errorf("declaration of %s not found", o.Name())
+	}
+
+	for i := 0; i < t.NumMethods(); i++ {
+		scope := t.Method(i).Scope()
+		mf := fset.File(scope.Pos())
+		msrc := src
+		if mf != ff {
+			if msrc, err = os.ReadFile(mf.Name()); err != nil {
+				return nil, err
+			}
+		}
+		b.Write(msrc[mf.Offset(scope.Pos()):mf.Offset(scope.End())])
+		b.WriteString("\n")
+	}
+	return b.Bytes(), nil
 }
 
 // fixConst checks untyped constant value, converting it if necessary to avoid overflow.
@@ -467,10 +729,23 @@ func (e *Extractor) importPath(pkgIdent, importPath string) (string, error) {
 // If pkgIdent is an import path, it is looked up in GOPATH. Vendoring is not
 // supported yet, and the behavior is only defined for GO111MODULE=off.
 func (e *Extractor) Extract(pkgIdent, importPath string, rw io.Writer) (string, error) {
-	ipp, err := e.importPath(pkgIdent, importPath)
+	content, exports, err := e.extract(pkgIdent, importPath)
 	if err != nil {
 		return "", err
 	}
+	if _, err := rw.Write(content); err != nil {
+		return "", err
+	}
+	return exports.ImportPath, nil
+}
+
+// extract returns the generated wrappers source and the extracted symbols
+// of the package found at pkgIdent.
+func (e *Extractor) extract(pkgIdent, importPath string) ([]byte, Exports, error) {
+	ipp, err := e.importPath(pkgIdent, importPath)
+	if err != nil {
+		return nil, Exports{}, err
+	}
 
 	var pkg *types.Package
 	isRelative := strings.HasPrefix(pkgIdent, ".")
@@ -480,44 +755,39 @@ func (e *Extractor) Extract(pkgIdent, importPath string, rw io.Writer) (string,
 	if isRelative && importPath != "" {
 		pkg, err = importer.ForCompiler(fset, "source", nil).Import(pkgIdent)
 		if err != nil {
-			return "", err
+			return nil, Exports{}, err
 		}
 	} else {
 		// Otherwise, we can use the much faster x/tools/go/packages loader.
+		// NeedsSyntax is needed for getting the scopes of generic functions.
+		cfg := &packages.Config{Mode: packages.NeedTypes | packages.NeedSyntax}
 		if isRelative {
-			// We must be in the location of the module for the loader to work correctly.
-			err := os.Chdir(pkgIdent)
+			// The loader must run in the location of the module to work
+			// correctly. It is set in the config rather than by changing the
+			// working directory of the process, shared by its goroutines.
+			dir, err := filepath.Abs(pkgIdent)
 			if err != nil {
-				return "", err
+				return nil, Exports{}, err
 			}
-			// Our path must point back to ourself here.
-			pkgIdent = filepath.Join("..", filepath.Base(pkgIdent))
+			cfg.Dir = dir
+			pkgIdent = "."
 		}
-		// NeedsSyntax is needed for getting the scopes of generic functions.
-		pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedTypes | packages.NeedSyntax}, pkgIdent)
+		pkgs, err := packages.Load(cfg, pkgIdent)
 		if err != nil {
-			return "", err
+			return nil, Exports{}, err
 		}
 		if len(pkgs) != 1 {
-			return "", fmt.Errorf("expected one package, got %d", len(pkgs))
+			return nil, Exports{}, fmt.Errorf("expected one package, got %d", len(pkgs))
 		}
 		ppkg := pkgs[0]
 		if len(ppkg.Errors) > 0 {
-			return "", ppkg.Errors[0]
+			return nil, Exports{}, ppkg.Errors[0]
 		}
 		pkg = ppkg.Types
 		fset = ppkg.Fset
 	}
 
-	content, err := e.genContent(ipp, pkg, fset)
-	if err != nil {
-		return "", err
-	}
-	if _, err := rw.Write(content); err != nil {
-		return "", err
-	}
-
-	return ipp, nil
+	return e.genContent(ipp, pkg, fset)
 }
 
 // GetMinor returns the minor part of the version number.
@@ -0,0 +1,3 @@
+module guthib.com/set
+
+go 1.21
@@ -0,0 +1,5 @@
+package set
+
+type Set[T comparable] map[T]struct{} //yaegi:add
+
+func (s Set[T]) Add(v T) { s[v] = struct{}{} }
@@ -0,0 +1,3 @@
+module guthib.com/private
+
+go 1.21
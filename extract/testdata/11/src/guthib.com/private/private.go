@@ -0,0 +1,11 @@
+package private
+
+func Hello() string { return "hello" }
+
+func Map[T, U any](s []T, f func(T) U) []U {
+	r := make([]U, len(s))
+	for i, v := range s {
+		r[i] = f(v)
+	}
+	return r
+}
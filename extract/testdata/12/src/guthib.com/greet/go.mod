@@ -0,0 +1,3 @@
+module guthib.com/greet
+
+go 1.21
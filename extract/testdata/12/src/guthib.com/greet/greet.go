@@ -0,0 +1,11 @@
+package greet
+
+type Greeter interface {
+	Greet() string
+	greeter()
+}
+
+type Base struct{}
+
+func (Base) Greet() string { return "hello" }
+func (Base) greeter()      {}
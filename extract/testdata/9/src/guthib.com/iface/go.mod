@@ -0,0 +1,3 @@
+module guthib.com/iface
+
+go 1.21
@@ -0,0 +1,11 @@
+package iface
+
+type Getter[T any] interface {
+	Get() T
+}
+
+type Item struct{ Name string }
+
+func Sum(a, b Getter[int]) int { return a.Get() + b.Get() }
+
+func Name(g Getter[Item]) string { return g.Get().Name }
@@ -22,6 +22,11 @@ import (
 // Arithmetic operators
 {{range $name, $op := .Arithmetic}}
 func {{$name}}(n *node) {
+	{{- if not $op.Shift}}
+	if {{$name}}Fast(n) {
+		return
+	}
+	{{- end}}
 	next := getExec(n.tnext)
 	typ := n.typ.concrete().TypeOf()
 	isInterface := n.typ.TypeOf().Kind() == reflect.Interface
@@ -49,6 +54,14 @@ func {{$name}}(n *node) {
 		case c1.rval.IsValid():
 			v0 := genValue(c0)
 			s1 :=  vString(c1.rval)
+			if isStrAccumulation(n) {
+				cat := &strCat{}
+				n.exec = func(f *frame) bltn {
+					dest(f).SetString(cat.add(v0(f).String(), s1))
+					return next
+				}
+				break
+			}
 			n.exec = func(f *frame) bltn {
 				dest(f).SetString(v0(f).String() {{$op.Name}} s1)
 				return next
@@ -56,6 +69,14 @@ func {{$name}}(n *node) {
 		default:
 			v0 := genValue(c0)
 			v1 := genValue(c1)
+			if isStrAccumulation(n) {
+				cat := &strCat{}
+				n.exec = func(f *frame) bltn {
+					dest(f).SetString(cat.add(v0(f).String(), v1(f).String()))
+					return next
+				}
+				break
+			}
 			n.exec = func(f *frame) bltn {
 				dest(f).SetString(v0(f).String() {{$op.Name}} v1(f).String())
 				return next
@@ -286,6 +307,11 @@ func {{$name}}Const(n *node) {
 // Assign operators
 {{range $name, $op := .Arithmetic}}
 func {{$name}}Assign(n *node) {
+	{{- if not $op.Shift}}
+	if {{$name}}AssignFast(n) {
+		return
+	}
+	{{- end}}
 	next := getExec(n.tnext)
 	typ := n.typ.TypeOf()
 	c0, c1 := n.child[0], n.child[1]
@@ -303,9 +329,10 @@ func {{$name}}Assign(n *node) {
 		case reflect.String:
 			v0 := genValueString(c0)
 			v1 := vString(c1.rval)
+			cat := &strCat{}
 			n.exec = func(f *frame) bltn {
 				v, s := v0(f)
-				v.SetString(s {{$op.Name}} v1)
+				v.SetString(cat.add(s, v1))
 				if setMap {
 					mapValue(f).SetMapIndex(indexValue(f), v)
 				}
@@ -369,9 +396,10 @@ func {{$name}}Assign(n *node) {
 		case reflect.String:
 			v0 := genValueString(c0)
 			v1 := genValue(c1)
+			cat := &strCat{}
 			n.exec = func(f *frame) bltn {
 				v, s := v0(f)
-				v.SetString(s {{$op.Name}} v1(f).String())
+				v.SetString(cat.add(s, v1(f).String()))
 				if setMap {
 					mapValue(f).SetMapIndex(indexValue(f), v)
 				}
@@ -437,6 +465,9 @@ func {{$name}}Assign(n *node) {
 {{end}}
 {{range $name, $op := .IncDec}}
 func {{$name}}(n *node) {
+	if {{$name}}Fast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.TypeOf()
 	c0 := n.child[0]
@@ -530,6 +561,9 @@ func {{$name}}Const(n *node) {
 {{end}}
 {{range $name, $op := .Comparison}}
 func {{$name}}(n *node) {
+	if {{$name}}Fast(n) {
+		return
+	}
 	tnext := getExec(n.tnext)
 	dest := genValueOutput(n, reflect.TypeOf(true))
 	typ := n.typ.concrete().TypeOf()
@@ -632,8 +666,8 @@ func {{$name}}(n *node) {
 		if n.fnext != nil {
 			fnext := getExec(n.fnext)
 			n.exec = func(f *frame) bltn {
-				i0 := v0(f).Interface()
-				i1 := v1(f).Interface()
+				i0 := ifaceValue(v0(f))
+				i1 := ifaceValue(v1(f))
 				if i0 {{$op.Name}} i1 {
 					dest(f).SetBool(true)
 					return tnext
@@ -644,8 +678,8 @@ func {{$name}}(n *node) {
 		} else {
 			dest := genValue(n)
 			n.exec = func(f *frame) bltn {
-				i0 := v0(f).Interface()
-				i1 := v1(f).Interface()
+				i0 := ifaceValue(v0(f))
+				i1 := ifaceValue(v1(f))
 				dest(f).SetBool(i0 {{$op.Name}} i1)
 				return tnext
 			}
@@ -1134,7 +1168,178 @@ func {{$name}}(n *node) {
 	}
 }
 {{end}}
-`
+// Fast operators on signed integer and float64 values of the local frame
+{{range $name, $op := .Arithmetic}}{{if not $op.Shift}}
+func {{$name}}Fast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	next := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+
+	switch k {
+	case reflect.Int:
+		switch {
+		case i0 < 0:
+			j0 := vInt(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(j0 {{$op.Name}} f.data[i1].Int())
+				return next
+			}
+		case i1 < 0:
+			j1 := vInt(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() {{$op.Name}} j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() {{$op.Name}} f.data[i1].Int())
+				return next
+			}
+		}
+	{{- if $op.Float}}
+	case reflect.Float64:
+		switch {
+		case i0 < 0:
+			j0 := vFloat(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(j0 {{$op.Name}} f.data[i1].Float())
+				return next
+			}
+		case i1 < 0:
+			j1 := vFloat(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(f.data[i0].Float() {{$op.Name}} j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(f.data[i0].Float() {{$op.Name}} f.data[i1].Float())
+				return next
+			}
+		}
+	{{- end}}
+	default:
+		return false
+	}
+	return true
+}
+
+func {{$name}}AssignFast(n *node) bool {
+	k, d, i1 := fastAssign(n)
+	next := getExec(n.tnext)
+
+	switch k {
+	case reflect.Int:
+		if i1 < 0 {
+			j1 := vInt(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetInt(v.Int() {{$op.Name}} j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetInt(v.Int() {{$op.Name}} f.data[i1].Int())
+			return next
+		}
+	{{- if $op.Float}}
+	case reflect.Float64:
+		if i1 < 0 {
+			j1 := vFloat(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetFloat(v.Float() {{$op.Name}} j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetFloat(v.Float() {{$op.Name}} f.data[i1].Float())
+			return next
+		}
+	{{- end}}
+	default:
+		return false
+	}
+	return true
+}
+{{end}}{{end}}
+{{- range $name, $op := .IncDec}}
+func {{$name}}Fast(n *node) bool {
+	k, d, _ := fastAssign(n)
+	next := getExec(n.tnext)
+
+	switch k {
+	case reflect.Int:
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetInt(v.Int() {{$op.Name}} 1)
+			return next
+		}
+	case reflect.Float64:
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetFloat(v.Float() {{$op.Name}} 1)
+			return next
+		}
+	default:
+		return false
+	}
+	return true
+}
+{{end}}
+{{- range $name, $op := .Comparison}}
+func {{$name}}Fast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	tnext := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+	if k == reflect.Invalid {
+		return false
+	}
+
+	// The comparison functions of both operands.
+	var cmp func(f *frame) bool
+	switch {
+	case k == reflect.Int && i0 < 0:
+		j0 := vInt(c0.rval)
+		cmp = func(f *frame) bool { return j0 {{$op.Name}} f.data[i1].Int() }
+	case k == reflect.Int && i1 < 0:
+		j1 := vInt(c1.rval)
+		cmp = func(f *frame) bool { return f.data[i0].Int() {{$op.Name}} j1 }
+	case k == reflect.Int:
+		cmp = func(f *frame) bool { return f.data[i0].Int() {{$op.Name}} f.data[i1].Int() }
+	case i0 < 0:
+		j0 := vFloat(c0.rval)
+		cmp = func(f *frame) bool { return j0 {{$op.Name}} f.data[i1].Float() }
+	case i1 < 0:
+		j1 := vFloat(c1.rval)
+		cmp = func(f *frame) bool { return f.data[i0].Float() {{$op.Name}} j1 }
+	default:
+		cmp = func(f *frame) bool { return f.data[i0].Float() {{$op.Name}} f.data[i1].Float() }
+	}
+
+	if n.fnext != nil {
+		fnext := getExec(n.fnext)
+		n.exec = func(f *frame) bltn {
+			if cmp(f) {
+				f.data[d].SetBool(true)
+				return tnext
+			}
+			f.data[d].SetBool(false)
+			return fnext
+		}
+		return true
+	}
+	n.exec = func(f *frame) bltn {
+		f.data[d].SetBool(cmp(f))
+		return tnext
+	}
+	return true
+}
+{{end}}`
 
 // Op define operator name and properties.
 type Op struct {
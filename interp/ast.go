@@ -366,7 +366,12 @@ func wrapInMain(src string) string {
 }
 
 func (interp *Interpreter) parse(src, name string, inc bool) (node ast.Node, err error) {
-	mode := parser.DeclarationErrors
+	if err := interp.limits.checkSize(name, len(src)); err != nil {
+		return nil, err
+	}
+
+	// Comments are parsed to retain doc comments of declarations.
+	mode := parser.DeclarationErrors | parser.ParseComments
 
 	// Allow incremental parsing of declarations or statements, by inserting
 	// them in a pseudo file package or function. Those statements or
@@ -384,8 +389,6 @@ func (interp *Interpreter) parse(src, name string, inc bool) (node ast.Node, err
 			inFunc = true
 			src = wrapInMain(src)
 		}
-		// Parse comments in REPL mode, to allow tag setting.
-		mode |= parser.ParseComments
 	}
 
 	if ok, err := interp.buildOk(&interp.context, name, src); !ok || err != nil {
@@ -412,12 +415,21 @@ func (interp *Interpreter) parse(src, name string, inc bool) (node ast.Node, err
 		}
 	}
 
+	if !inFunc {
+		if err := interp.embedVars(f, name, inc); err != nil {
+			return nil, err
+		}
+	}
+
 	if inFunc {
 		// return the body of the wrapper main function
 		return f.Decls[0].(*ast.FuncDecl).Body, nil
 	}
 
-	setYaegiTags(&interp.context, f.Comments)
+	if inc {
+		// Allow tag setting in REPL mode.
+		setYaegiTags(&interp.context, f.Comments)
+	}
 	return f, nil
 }
 
@@ -434,9 +446,15 @@ func (interp *Interpreter) ast(f ast.Node) (string, *node, error) {
 	var anc astNode
 	var st nodestack
 	pkgName := "main"
+	var count compileCount
+	var name string
+	if file := interp.fset.File(f.Pos()); file != nil {
+		name = file.Name()
+	}
 
 	addChild := func(root **node, anc astNode, pos token.Pos, kind nkind, act action) *node {
 		var i interface{}
+		count.add(kind)
 		nindex := atomic.AddInt64(&interp.nindex, 1)
 		n := &node{anc: anc.node, interp: interp, index: nindex, pos: pos, kind: kind, action: act, val: &i, gen: builtin[act]}
 		n.start = n
@@ -476,6 +494,10 @@ func (interp *Interpreter) ast(f ast.Node) (string, *node, error) {
 	// Populate our own private AST from Go parser AST.
 	// A stack of ancestor nodes is used to keep track of current ancestor for each depth level
 	ast.Inspect(f, func(nod ast.Node) bool {
+		if lerr := interp.limits.checkCount(name, count); lerr != nil {
+			err = lerr
+			return false
+		}
 		anc = st.top()
 		var pos token.Pos
 		if nod != nil {
@@ -710,6 +732,7 @@ func (interp *Interpreter) ast(f ast.Node) (string, *node, error) {
 		case *ast.FuncDecl:
 			n := addChild(&root, anc, pos, funcDecl, aNop)
 			n.val = n
+			interp.setDecl(n, a, anc)
 			if a.Recv == nil {
 				// Function is not a method, create an empty receiver list.
 				addChild(&root, astNode{n, nod}, pos, fieldList, aNop)
@@ -864,11 +887,13 @@ func (interp *Interpreter) ast(f ast.Node) (string, *node, error) {
 			st.push(addChild(&root, anc, pos, typeAssertExpr, aTypeAssert), nod)
 
 		case *ast.TypeSpec:
+			kind := typeSpec
 			if a.Assign.IsValid() {
-				st.push(addChild(&root, anc, pos, typeSpecAssign, aNop), nod)
-				break
+				kind = typeSpecAssign
 			}
-			st.push(addChild(&root, anc, pos, typeSpec, aNop), nod)
+			n := addChild(&root, anc, pos, kind, aNop)
+			st.push(n, nod)
+			interp.setDecl(n, a, anc)
 
 		case *ast.TypeSwitchStmt:
 			n := addChild(&root, anc, pos, typeSwitch, aNop)
@@ -927,6 +952,7 @@ func (interp *Interpreter) ast(f ast.Node) (string, *node, error) {
 			n.nleft = len(a.Names)
 			n.nright = len(a.Values)
 			st.push(n, nod)
+			interp.setDecl(n, a, anc)
 
 		default:
 			err = astError(fmt.Errorf("ast: %T not implemented, line %s", a, interp.fset.Position(pos)))
@@ -934,6 +960,9 @@ func (interp *Interpreter) ast(f ast.Node) (string, *node, error) {
 		}
 		return true
 	})
+	if lerr := interp.limits.checkCount(name, count); lerr != nil {
+		return pkgName, nil, lerr
+	}
 
 	interp.roots = append(interp.roots, root)
 	return pkgName, root, err
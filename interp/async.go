@@ -0,0 +1,137 @@
+package interp
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// A Run is an evaluation started by EvalAsync, running in its own goroutine.
+type Run struct {
+	cancel context.CancelFunc
+	state  *runState
+	done   chan struct{}
+	res    reflect.Value
+	err    error
+	stdout *runStream
+	stderr *runStream
+}
+
+// EvalAsync compiles src as Eval does, then starts its execution in a new
+// goroutine and returns its handle without waiting for its completion.
+// The returned error is the compilation error, if any, the execution error
+// being returned by Result.
+//
+// The standard output and error of the run are captured, as with WithOutput,
+// and can be read while it executes from the readers returned by Stdout and
+// Stderr. A panic of the run is recovered and returned as a *Panic error.
+func (interp *Interpreter) EvalAsync(src string) (*Run, error) {
+	prog, err := interp.compileEval(src, "", true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Run{cancel: cancel, done: make(chan struct{}), stdout: newRunStream(), stderr: newRunStream()}
+	ctx = WithOutput(ctx, r.stdout, r.stderr)
+	r.state = interp.newRun(ctx)
+	go func() {
+		defer close(r.done)
+		defer r.stderr.close()
+		defer r.stdout.close()
+		defer cancel()
+		if interp.noRun {
+			interp.endRun(r.state)
+			return
+		}
+		r.res, r.err = interp.runWithContext(ctx, r.state, func() (reflect.Value, error) {
+			return interp.execute(prog, r.state)
+		})
+	}()
+	return r, nil
+}
+
+// Done returns a channel closed when the run is complete.
+func (r *Run) Done() <-chan struct{} { return r.done }
+
+// Result waits for the completion of the run, and returns the result of the
+// evaluation, as Eval does. The error of a cancelled run is context.Canceled.
+func (r *Run) Result() (reflect.Value, error) {
+	<-r.done
+	return r.res, r.err
+}
+
+// Stats waits for the completion of the run, and returns its resource usage.
+// It is zero if the interpreter was not created with the CollectStats option.
+func (r *Run) Stats() RunStats {
+	<-r.done
+	return r.state.usage().result()
+}
+
+// Cancel stops the run, as the cancellation of the context of
+// EvalWithContext does. It has no effect on a complete run.
+func (r *Run) Cancel() { r.cancel() }
+
+// Stdout returns a reader of the standard output of the run, from its start.
+// Reads block until more output is available, and return io.EOF once the run
+// is complete and all its output is read. Each call returns a new reader.
+func (r *Run) Stdout() io.Reader { return &runReader{stream: r.stdout} }
+
+// Stderr returns a reader of the standard error of the run, from its start,
+// as Stdout does.
+func (r *Run) Stderr() io.Reader { return &runReader{stream: r.stderr} }
+
+// runStream holds the output written to a stream of a Run.
+type runStream struct {
+	mutex  sync.Mutex
+	buf    []byte
+	closed bool
+	more   chan struct{} // closed and replaced when output is written or the stream closed
+}
+
+func newRunStream() *runStream { return &runStream{more: make(chan struct{})} }
+
+func (s *runStream) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		// Output of goroutines outliving the run is discarded.
+		return len(p), nil
+	}
+	s.buf = append(s.buf, p...)
+	close(s.more)
+	s.more = make(chan struct{})
+	return len(p), nil
+}
+
+func (s *runStream) close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+	close(s.more)
+}
+
+// runReader reads a runStream from an offset.
+type runReader struct {
+	stream *runStream
+	off    int
+}
+
+func (r *runReader) Read(p []byte) (int, error) {
+	s := r.stream
+	for {
+		s.mutex.Lock()
+		n := copy(p, s.buf[r.off:])
+		closed, more := s.closed, s.more
+		s.mutex.Unlock()
+		r.off += n
+		switch {
+		case n > 0 || len(p) == 0:
+			return n, nil
+		case closed:
+			return 0, io.EOF
+		}
+		<-more
+	}
+}
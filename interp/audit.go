@@ -0,0 +1,181 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// A StdlibAudit reports the gaps between the standard library packages
+// available to interpreted code, as loaded by Use, and the exported API of
+// the standard library of the Go version running the interpreter, e.g. to
+// check before production that the stdlib calls made by scripts will not
+// fail to compile.
+type StdlibAudit struct {
+	GoVersion       string              // version of the audited standard library
+	Packages        []string            // audited import paths, sorted
+	MissingPackages []string            // audited packages not available, sorted
+	MissingSymbols  map[string][]string // missing exported symbols of available packages, by import path, sorted
+}
+
+// Ok returns true if no gap was found.
+func (a *StdlibAudit) Ok() bool {
+	return len(a.MissingPackages) == 0 && len(a.MissingSymbols) == 0
+}
+
+// String returns a report of the gaps, one line per missing package or
+// symbol.
+func (a *StdlibAudit) String() string {
+	var sb strings.Builder
+	for _, p := range a.MissingPackages {
+		fmt.Fprintf(&sb, "%s: missing package\n", p)
+	}
+	paths := make([]string, 0, len(a.MissingSymbols))
+	for p := range a.MissingSymbols {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		for _, s := range a.MissingSymbols[p] {
+			fmt.Fprintf(&sb, "%s.%s: missing symbol\n", p, s)
+		}
+	}
+	return sb.String()
+}
+
+// AuditStdlib compares the standard library packages available to the
+// interpreter with the sources of the standard library in GOROOT, for the
+// build constraints of the interpreter. If no import path is given, all the
+// importable packages of the standard library are audited, except the
+// internal and vendored ones. Exported methods and struct fields are not
+// audited, as they are provided by the types themselves.
+func (interp *Interpreter) AuditStdlib(importPaths ...string) (*StdlibAudit, error) {
+	ctx := interp.context
+	if len(importPaths) == 0 {
+		var err error
+		if importPaths, err = stdPackages(&ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	a := &StdlibAudit{GoVersion: runtime.Version(), MissingSymbols: map[string][]string{}}
+	for _, p := range importPaths {
+		bp, err := ctx.Import(p, "", 0)
+		if err != nil {
+			var noGo *build.NoGoError
+			if errors.As(err, &noGo) {
+				continue // no sources for the target
+			}
+			return nil, err
+		}
+		if !bp.Goroot {
+			return nil, fmt.Errorf("%s is not a standard library package", p)
+		}
+		if bp.Name == "main" {
+			continue // command
+		}
+		a.Packages = append(a.Packages, p)
+
+		interp.mutex.RLock()
+		bin, src := interp.binPkg[p], interp.srcPkg[p]
+		interp.mutex.RUnlock()
+		if bin == nil && src == nil {
+			a.MissingPackages = append(a.MissingPackages, p)
+			continue
+		}
+		names, err := exportedNames(bp)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if _, ok := bin[name]; ok {
+				continue
+			}
+			if _, ok := src[name]; ok {
+				continue
+			}
+			a.MissingSymbols[p] = append(a.MissingSymbols[p], name)
+		}
+	}
+	sort.Strings(a.Packages)
+	sort.Strings(a.MissingPackages)
+	return a, nil
+}
+
+// stdPackages returns the import paths of the importable packages of the
+// standard library in the GOROOT of ctx.
+func stdPackages(ctx *build.Context) ([]string, error) {
+	root := filepath.Join(ctx.GOROOT, "src")
+	var paths []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if p != root && (name == "internal" || name == "vendor" || name == "testdata" || name == "builtin" ||
+			strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		if rel = filepath.ToSlash(rel); rel == "cmd" {
+			return filepath.SkipDir
+		}
+		paths = append(paths, rel)
+		return nil
+	})
+	return paths, err
+}
+
+// exportedNames returns the sorted names of the exported package level
+// symbols declared in the Go files of bp.
+func exportedNames(bp *build.Package) ([]string, error) {
+	fset := token.NewFileSet()
+	seen := map[string]bool{}
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(bp.Dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					seen[d.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						seen[s.Name.Name] = true
+					case *ast.ValueSpec:
+						for _, n := range s.Names {
+							seen[n.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		if token.IsExported(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
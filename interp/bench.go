@@ -0,0 +1,111 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// BenchOptions are the options of RunBench.
+type BenchOptions struct {
+	// Count is the number of runs of each benchmark, as the -count flag of
+	// "go test". Default is 1.
+	Count int
+}
+
+// BenchResult is the result of a run of an interpreted benchmark.
+type BenchResult struct {
+	Name        string // name of the benchmark function
+	Package     string // import path of the package, as given to EvalTest
+	N           int    // number of iterations
+	NsPerOp     int64  // nanoseconds per iteration
+	AllocsPerOp int64  // memory allocations per iteration
+	BytesPerOp  int64  // bytes allocated per iteration
+}
+
+func (r BenchResult) String() string {
+	return fmt.Sprintf("%s\t%d\t%d ns/op\t%d B/op\t%d allocs/op", r.Name, r.N, r.NsPerOp, r.BytesPerOp, r.AllocsPerOp)
+}
+
+// RunBench runs the benchmark functions of the packages evaluated by
+// EvalTest whose names match the regular expression pattern, as the -bench
+// flag of "go test", and returns their results, sorted by package and name.
+// Benchmarks are driven by testing.Benchmark, and run for the default
+// duration of one second.
+//
+// RunBench stops at the first benchmark which fails or panics, and returns
+// the results so far with an error.
+func (interp *Interpreter) RunBench(pattern string, opts BenchOptions) ([]BenchResult, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid benchmark pattern: %w", err)
+	}
+	count := opts.Count
+	if count <= 0 {
+		count = 1
+	}
+
+	interp.mutex.RLock()
+	paths := append([]string(nil), interp.tested...)
+	interp.mutex.RUnlock()
+	if len(paths) == 0 {
+		return nil, errors.New("no test package evaluated")
+	}
+	sort.Strings(paths)
+
+	var results []BenchResult
+	for _, path := range paths {
+		syms := interp.Symbols(path)[path]
+		names := make([]string, 0, len(syms))
+		for name, sym := range syms {
+			if _, ok := sym.Interface().(func(*testing.B)); ok && strings.HasPrefix(name, "Benchmark") && re.MatchString(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fn := syms[name].Interface().(func(*testing.B))
+			for k := 0; k < count; k++ {
+				r, err := runBench(fn)
+				if err != nil {
+					return results, fmt.Errorf("%s.%s: %w", path, name, err)
+				}
+				results = append(results, BenchResult{
+					Name:        name,
+					Package:     path,
+					N:           r.N,
+					NsPerOp:     r.NsPerOp(),
+					AllocsPerOp: r.AllocsPerOp(),
+					BytesPerOp:  r.AllocedBytesPerOp(),
+				})
+			}
+		}
+	}
+	return results, nil
+}
+
+// runBench runs the benchmark function fn, returning an error if it fails
+// or panics.
+func runBench(fn func(*testing.B)) (testing.BenchmarkResult, error) {
+	var panicked interface{}
+	r := testing.Benchmark(func(b *testing.B) {
+		defer func() {
+			if p := recover(); p != nil {
+				panicked = panicValue(p)
+				b.FailNow()
+			}
+		}()
+		fn(b)
+	})
+	switch {
+	case panicked != nil:
+		return r, fmt.Errorf("panic: %v", panicked)
+	case r.N == 0:
+		return r, errors.New("benchmark failed")
+	}
+	return r, nil
+}
@@ -0,0 +1,119 @@
+package interp
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A BridgePanic is the value of the panic raised in the calling interpreter
+// when a function exported by Bridge panics or is cancelled in its own
+// interpreter.
+type BridgePanic struct {
+	Func  string      // qualified name of the bridged function
+	Value interface{} // panic value in the callee interpreter, or nil if cancelled
+	Stack []byte      // stack trace of the callee interpreter panic, if any
+}
+
+func (p BridgePanic) Error() string {
+	if p.Value == nil {
+		return p.Func + ": call cancelled"
+	}
+	return fmt.Sprintf("%s: panic: %v", p.Func, p.Value)
+}
+
+// Bridge returns the exported functions of the source package importPath,
+// which must have been previously imported or evaluated in interp, in the
+// form of Exports which can be passed to the Use method of other interpreters.
+// It allows an interpreter, e.g. a trusted library, to serve functions to
+// other interpreters.
+//
+// Bridged functions run in interp, on the goroutine of the caller. Their
+// results are translated to plain Go values of the function signature types.
+// A panic in a bridged function does not affect interp: it is recovered and
+// raised again in the caller as a BridgePanic. Likewise, a cancellation of
+// the caller does not interrupt a bridged call in progress, and a
+// cancellation of interp during a bridged call raises a BridgePanic in the
+// caller.
+func (interp *Interpreter) Bridge(importPath string) (Exports, error) {
+	interp.mutex.RLock()
+	syms, ok := interp.srcPkg[importPath]
+	pkgName := interp.pkgNames[importPath]
+	interp.mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("package not found: %s", importPath)
+	}
+
+	exports := map[string]reflect.Value{}
+	for name, s := range syms {
+		if !canExport(name) || s.kind != funcSym {
+			continue
+		}
+		exports[name] = interp.bridgeFunc(importPath+"."+name, s.node)
+	}
+	return Exports{importPath + "/" + pkgName: exports}, nil
+}
+
+// bridgeFunc returns a wrapper of the function defined at node n, isolating
+// the caller from panics and cancellations of interp.
+func (interp *Interpreter) bridgeFunc(name string, n *node) reflect.Value {
+	wrap := genFunctionWrapper(n)
+	typ := n.typ.TypeOf()
+
+	return reflect.MakeFunc(typ, func(in []reflect.Value) (out []reflect.Value) {
+		// Resynchronize the global frame, in case a previous run was cancelled.
+		id := interp.runid()
+		interp.frame.Load().setrunid(id)
+
+		defer func() {
+			r := interp.restorePanic(recover())
+			if r == nil {
+				if interp.runid() != id {
+					panic(BridgePanic{Func: name})
+				}
+				return
+			}
+			bp := BridgePanic{Func: name, Value: interp.exportPanic(r)}
+			if p := interp.GetOldestPanicForErr(r); p != nil {
+				bp.Stack = p.Stack
+			}
+			panic(bp)
+		}()
+
+		f := wrap(interp.frame.Load())
+		if typ.IsVariadic() {
+			out = f.CallSlice(in)
+		} else {
+			out = f.Call(in)
+		}
+		for i, v := range out {
+			out[i] = translateValue(v, typ.Out(i))
+		}
+		return out
+	})
+}
+
+// translateValue converts v to type t if necessary and possible, unwrapping
+// the interpreter internal representation of interface values, so v can be
+// used outside of the interpreter which created it.
+func translateValue(v reflect.Value, t reflect.Type) reflect.Value {
+	if !v.IsValid() {
+		return reflect.New(t).Elem()
+	}
+	if v.CanInterface() {
+		if vi, ok := v.Interface().(valueInterface); ok {
+			return translateValue(vi.value, t)
+		}
+	}
+	if v.Type() == t {
+		return v
+	}
+	if v.Type().AssignableTo(t) {
+		r := reflect.New(t).Elem()
+		r.Set(v)
+		return r
+	}
+	if v.Type().ConvertibleTo(t) {
+		return v.Convert(t)
+	}
+	return v
+}
@@ -1,8 +1,10 @@
 package interp
 
 import (
+	"fmt"
 	"go/ast"
 	"go/build"
+	"go/build/constraint"
 	"go/parser"
 	"path"
 	"strconv"
@@ -18,6 +20,26 @@ func (interp *Interpreter) buildOk(ctx *build.Context, name, src string) (bool,
 	if err != nil {
 		return false, err
 	}
+	// A //go:build line takes precedence over // +build lines.
+	for _, g := range f.Comments {
+		if g.End() > f.Package {
+			break
+		}
+		for _, c := range g.List {
+			if !constraint.IsGoBuild(c.Text) {
+				continue
+			}
+			expr, err := constraint.Parse(c.Text)
+			if err != nil {
+				return false, fmt.Errorf("%s: %w", interp.fset.Position(c.Pos()), err)
+			}
+			ok := expr.Eval(func(tag string) bool { return buildTagOk(ctx, tag) })
+			if ok {
+				setYaegiTags(ctx, f.Comments)
+			}
+			return ok, nil
+		}
+	}
 	for _, g := range f.Comments {
 		// in file, evaluate the AND of multiple line build constraints
 		for _, line := range strings.Split(strings.TrimSpace(g.Text()), "\n") {
@@ -67,10 +89,16 @@ func buildTagOk(ctx *build.Context, s string) (r bool) {
 	switch {
 	case contains(ctx.BuildTags, s):
 		r = true
-	case s == ctx.GOOS:
+	case matchOS(ctx, s):
 		r = true
 	case s == ctx.GOARCH:
 		r = true
+	case s == "unix":
+		r = unixOS[ctx.GOOS]
+	case s == "cgo":
+		r = ctx.CgoEnabled
+	case s == ctx.Compiler:
+		r = true
 	case len(s) > 4 && s[:4] == "go1.":
 		if n, err := strconv.Atoi(s[4:]); err != nil {
 			r = false
@@ -140,29 +168,40 @@ func skipFile(ctx *build.Context, p string, skipTest bool) bool {
 	if skipTest && strings.HasSuffix(p, "_test") {
 		return true
 	}
+	p = strings.TrimSuffix(p, "_test")
 	i := strings.Index(p, "_")
 	if i < 0 {
 		return false
 	}
+	// Only the last one or two elements of the name are considered, e.g.
+	// "name_GOOS_GOARCH", "name_GOOS" or "name_GOARCH".
 	a := strings.Split(p[i+1:], "_")
 	last := len(a) - 1
-	if last-1 >= 0 {
-		switch x, y := a[last-1], a[last]; {
-		case x == ctx.GOOS:
-			if knownArch[y] {
-				return y != ctx.GOARCH
-			}
-			return false
-		case knownOs[x] && knownArch[y]:
-			return true
-		case knownArch[y] && y != ctx.GOARCH:
-			return true
-		default:
-			return false
-		}
+	if last >= 1 && knownOs[a[last-1]] && knownArch[a[last]] {
+		return !matchOS(ctx, a[last-1]) || a[last] != ctx.GOARCH
+	}
+	switch x := a[last]; {
+	case knownOs[x]:
+		return !matchOS(ctx, x)
+	case knownArch[x]:
+		return x != ctx.GOARCH
 	}
-	if x := a[last]; knownOs[x] && x != ctx.GOOS || knownArch[x] && x != ctx.GOARCH {
+	return false
+}
+
+// matchOS returns true if the operating system name matches the target of
+// ctx: files and constraints for linux also apply to android, for solaris
+// to illumos, and for darwin to ios.
+func matchOS(ctx *build.Context, name string) bool {
+	switch {
+	case name == ctx.GOOS:
 		return true
+	case name == "linux":
+		return ctx.GOOS == "android"
+	case name == "solaris":
+		return ctx.GOOS == "illumos"
+	case name == "darwin":
+		return ctx.GOOS == "ios"
 	}
 	return false
 }
@@ -173,6 +212,7 @@ var knownOs = map[string]bool{
 	"darwin":    true,
 	"dragonfly": true,
 	"freebsd":   true,
+	"hurd":      true,
 	"illumos":   true,
 	"ios":       true,
 	"js":        true,
@@ -183,20 +223,48 @@ var knownOs = map[string]bool{
 	"solaris":   true,
 	"wasip1":    true,
 	"windows":   true,
+	"zos":       true,
+}
+
+// unixOS are the operating systems matching the "unix" build constraint.
+var unixOS = map[string]bool{
+	"aix":       true,
+	"android":   true,
+	"darwin":    true,
+	"dragonfly": true,
+	"freebsd":   true,
+	"hurd":      true,
+	"illumos":   true,
+	"ios":       true,
+	"linux":     true,
+	"netbsd":    true,
+	"openbsd":   true,
+	"solaris":   true,
 }
 
 var knownArch = map[string]bool{
-	"386":      true,
-	"amd64":    true,
-	"arm":      true,
-	"arm64":    true,
-	"loong64":  true,
-	"mips":     true,
-	"mips64":   true,
-	"mips64le": true,
-	"mipsle":   true,
-	"ppc64":    true,
-	"ppc64le":  true,
-	"s390x":    true,
-	"wasm":     true,
+	"386":         true,
+	"amd64":       true,
+	"amd64p32":    true,
+	"arm":         true,
+	"armbe":       true,
+	"arm64":       true,
+	"arm64be":     true,
+	"loong64":     true,
+	"mips":        true,
+	"mipsle":      true,
+	"mips64":      true,
+	"mips64le":    true,
+	"mips64p32":   true,
+	"mips64p32le": true,
+	"ppc":         true,
+	"ppc64":       true,
+	"ppc64le":     true,
+	"riscv":       true,
+	"riscv64":     true,
+	"s390":        true,
+	"s390x":       true,
+	"sparc":       true,
+	"sparc64":     true,
+	"wasm":        true,
 }
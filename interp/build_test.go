@@ -15,6 +15,7 @@ func TestBuildTag(t *testing.T) {
 	ctx := build.Context{
 		GOARCH:      "amd64",
 		GOOS:        "linux",
+		Compiler:    "gc",
 		BuildTags:   []string{"foo"},
 		ReleaseTags: []string{"go1.11"},
 	}
@@ -37,6 +38,15 @@ func TestBuildTag(t *testing.T) {
 		{"// +build foo", true},
 		{"// +build !foo", false},
 		{"// +build bar", false},
+		{"//go:build linux && amd64", true},
+		{"//go:build linux && !amd64", false},
+		{"//go:build (windows || linux) && go1.11", true},
+		{"//go:build go1.12 || foo", true},
+		{"//go:build unix", true},
+		{"//go:build cgo", false},
+		{"//go:build gc", true},
+		{"//go:build windows\n// +build linux", false},
+		{"//go:build linux\n// +build windows", true},
 	}
 
 	i := New(Options{})
@@ -77,6 +87,53 @@ func TestSkipFile(t *testing.T) {
 
 		{"bar_amd64.go", false},
 		{"bar_arm.go", true},
+		{"bar_riscv64.go", true},
+		{"bar_test.go", true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.src, func(t *testing.T) {
+			if r := skipFile(&ctx, test.src, NoTest); r != test.res {
+				t.Errorf("got %v, want %v", r, test.res)
+			}
+		})
+	}
+}
+
+func TestSkipTestFile(t *testing.T) {
+	ctx := build.Context{
+		GOARCH: "amd64",
+		GOOS:   "linux",
+	}
+
+	tests := []testBuild{
+		{"bar_test.go", false},
+		{"bar_linux_test.go", false},
+		{"bar_windows_test.go", true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.src, func(t *testing.T) {
+			if r := skipFile(&ctx, test.src, Test); r != test.res {
+				t.Errorf("got %v, want %v", r, test.res)
+			}
+		})
+	}
+}
+
+func TestSkipFileAndroid(t *testing.T) {
+	ctx := build.Context{
+		GOARCH: "arm64",
+		GOOS:   "android",
+	}
+
+	tests := []testBuild{
+		{"bar_android.go", false},
+		{"bar_linux.go", false},
+		{"bar_linux_arm64.go", false},
+		{"bar_darwin.go", true},
 	}
 
 	for _, test := range tests {
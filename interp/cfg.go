@@ -15,6 +15,7 @@ import (
 type cfgError struct {
 	*node
 	error
+	msg string // error message, without position
 }
 
 func (c *cfgError) Error() string { return c.error.Error() }
@@ -43,11 +44,20 @@ var constBltn = map[string]func(*node){
 	bltnReal:    realConst,
 }
 
+// bltnVersion holds the Go versions introducing builtins.
+var bltnVersion = map[string]string{
+	bltnClear: "go1.21",
+	bltnMax:   "go1.21",
+	bltnMin:   "go1.21",
+}
+
 const nilIdent = "nil"
 
 func init() {
 	// Use init() to avoid initialization cycles for the following constant builtins.
 	constBltn[bltnAlignof] = alignof
+	constBltn[bltnMax] = maxConst
+	constBltn[bltnMin] = minConst
 	constBltn[bltnOffsetof] = offsetof
 	constBltn[bltnSizeof] = sizeof
 }
@@ -63,6 +73,8 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 	check := typecheck{scope: sc}
 	var initNodes []*node
 	var err error
+	var errs CompileError
+	declScope := sc // scope of top level declarations
 
 	baseName := path.Base(interp.fset.Position(root.pos).Filename)
 
@@ -143,7 +155,8 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 					sc.sym[e.ident] = &symbol{index: index, kind: varSym, typ: t.val}
 					e.typ = t.val
 					e.findex = index
-					n.anc.gen = rangeChan
+					n.anc.gen = interp.chanGen(n.anc, rangeChan, rangeChanFast)
+					rangek = e
 				} else {
 					// range over array or map
 					var ktyp, vtyp *itype
@@ -157,6 +170,23 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 						k, o = n.anc.child[0], n.anc.child[1]
 					}
 
+					if isInt(o.typ.TypeOf()) {
+						// range over integer
+						if err = interp.checkGoVersion(o, "range over int", "go1.22"); err != nil {
+							return false
+						}
+						if len(n.anc.child) == 4 {
+							err = o.cfgErrorf("range over %s permits only one iteration variable", o.typ.id())
+							return false
+						}
+						n.anc.gen = rangeInt
+						ktyp = o.typ
+						if ktyp.untyped {
+							ktyp = sc.getType("int")
+						}
+						sc.add(ktyp) // Add a slot to store the range limit
+					}
+
 					switch o.typ.cat {
 					case valueT, linkedT:
 						typ := o.typ.rtype
@@ -179,6 +209,14 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 							sc.add(sc.getType("int")) // Add a dummy type to store array shallow copy for range
 							ktyp = sc.getType("int")
 							vtyp = valueTOf(typ.Elem())
+						case reflect.Func:
+							if err = interp.checkGoVersion(o, "range over func", "go1.23"); err != nil {
+								return false
+							}
+							if ktyp, vtyp, err = sc.rangeFuncTypes(n.anc); err != nil {
+								return false
+							}
+							n.anc.gen = rangeFunc
 						}
 					case mapT:
 						n.anc.gen = rangeMap
@@ -203,12 +241,20 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 						sc.add(sc.getType("int")) // Add a dummy type to store array shallow copy for range
 						ktyp = sc.getType("int")
 						vtyp = o.typ.val
-					case intT:
-						n.anc.gen = rangeInt
-						sc.add(sc.getType("int"))
-						ktyp = sc.getType("int")
+					case funcT:
+						if err = interp.checkGoVersion(o, "range over func", "go1.23"); err != nil {
+							return false
+						}
+						if ktyp, vtyp, err = sc.rangeFuncTypes(n.anc); err != nil {
+							return false
+						}
+						n.anc.gen = rangeFunc
 					}
 
+					if ktyp == nil {
+						err = o.cfgErrorf("cannot range over value of type %s", o.typ.id())
+						return false
+					}
 					kindex := sc.add(ktyp)
 					sc.sym[k.ident] = &symbol{index: kindex, kind: varSym, typ: ktyp}
 					k.typ = ktyp
@@ -229,7 +275,8 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 			n.val = nil
 			sc = sc.pushBloc()
 
-			if n.anc != nil && n.anc.kind == rangeStmt {
+			if n.anc != nil && n.anc.kind == rangeStmt && interp.goVersionAtLeast("go1.22") {
+				// Declare per-iteration copies of the iteration variables.
 				lk := n.child[0]
 				if rangek != nil {
 					lk.ident = rangek.ident
@@ -249,7 +296,9 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 					lv.gen = loopVarVal
 				}
 			}
-			if n.anc != nil && n.anc.kind == forStmt7 {
+			if n.anc != nil && n.anc.kind == forStmt7 && interp.goVersionAtLeast("go1.22") {
+				// Declare a per-iteration copy of the loop variable, copied back
+				// at the end of the body for the post statement.
 				lv := n.child[0]
 				init := n.anc.child[0]
 				if init.kind == defineStmt && len(init.child) >= 2 && init.child[0].kind == identExpr {
@@ -260,6 +309,7 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 					sc.sym[lv.ident] = &symbol{index: vindex, kind: varSym, typ: lv.typ}
 					lv.findex = vindex
 					lv.gen = loopVarFor
+					n.gen = loopVarForNext
 				}
 			}
 
@@ -285,6 +335,9 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 			}
 
 		case breakStmt, continueStmt, gotoStmt:
+			if isInRangeFunc(n) {
+				n.gen = rangeFuncBranch
+			}
 			if len(n.child) == 0 {
 				break
 			}
@@ -391,44 +444,11 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 						err = n.cfgErrorf("undefined type")
 						return false
 					}
-					// We have a composite literal of generic type, instantiate it.
-					lt := []*itype{}
-					for _, n1 := range n.child[0].child[1:] {
-						t1, err1 := nodeType(interp, sc, n1)
-						if err1 != nil {
-							return false
-						}
-						lt = append(lt, t1)
-					}
-					var g *node
-					g, _, err = genAST(sc, t0.node.anc, lt)
-					if err != nil {
+					// We have a composite literal of generic type, instantiate it,
+					// or reuse an existing instance, along with its methods.
+					if n.typ, err = nodeType(interp, sc, n.child[0]); err != nil {
 						return false
 					}
-					n.child[0] = g.lastChild()
-					n.typ, err = nodeType(interp, sc, n.child[0])
-					if err != nil {
-						return false
-					}
-					// Generate methods if any.
-					for _, nod := range t0.method {
-						gm, _, err2 := genAST(nod.scope, nod, lt)
-						if err2 != nil {
-							err = err2
-							return false
-						}
-						gm.typ, err = nodeType(interp, nod.scope, gm.child[2])
-						if err != nil {
-							return false
-						}
-						if _, err = interp.cfg(gm, sc, sc.pkgID, sc.pkgName); err != nil {
-							return false
-						}
-						if err = genRun(gm); err != nil {
-							return false
-						}
-						n.typ.addMethod(gm)
-					}
 					n.nleft = 1 // Indictate the type of composite literal.
 				}
 			}
@@ -646,6 +666,13 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 	}, func(n *node) {
 		// Post-order processing
 		if err != nil {
+			if n.kind == funcDecl && n.anc != nil && n.anc.kind == fileStmt && len(errs)+1 < interp.maxErrors {
+				// Recover from the error in the function body, to report
+				// the independent errors in the next declarations.
+				errs = append(errs, err)
+				err = nil
+				sc = declScope
+			}
 			return
 		}
 
@@ -752,6 +779,9 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 						}
 						// Do not overload existing symbols (defined in GTA) in global scope.
 						sym, _, _ = sc.lookup(dest.ident)
+						if sym != nil && sym == interp.universe.sym[dest.ident] {
+							sym = nil // Shadowing a predeclared identifier.
+						}
 					}
 					if sym == nil {
 						sym = &symbol{index: sc.add(dest.typ), kind: varSym, typ: dest.typ}
@@ -815,7 +845,7 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 					src.findex = dest.findex // Set recv address to LHS.
 					dest.typ = src.typ
 				case src.action == aCompositeLit:
-					if dest.typ.cat == valueT && dest.typ.rtype.Kind() == reflect.Interface {
+					if (dest.typ.cat == valueT || dest.typ.cat == errorT) && dest.typ.TypeOf().Kind() == reflect.Interface {
 						// Skip optimisation for assigned interface.
 						break
 					}
@@ -904,7 +934,7 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 				n.gen = nop
 			case unaryExpr:
 				if lc.action == aRecv {
-					lc.gen = recv2
+					lc.gen = interp.chanGen(lc, recv2, recv2Fast)
 					n.gen = nop
 				}
 			}
@@ -988,6 +1018,41 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 				fixUntyped(n, sc)
 			}
 
+		case indexListExpr:
+			// A generic type instantiated with several type parameters, used as
+			// a value, e.g. in make(Map[string, int]). Generic function calls are
+			// handled at the callExpr.
+			if n.anc.kind == callExpr && childPos(n) == 0 && !n.isType(sc) {
+				break
+			}
+			if t := n.child[0].typ; t != nil && t.cat == funcT && isGeneric(t) {
+				// An instantiated generic function used as a value.
+				lt := []*itype{}
+				for _, c := range n.child[1:] {
+					lt = append(lt, c.typ)
+				}
+				var g *node
+				var found bool
+				if g, found, err = genAST(sc, t.node.anc, lt); err != nil {
+					break
+				}
+				if !found {
+					if _, err = interp.cfg(g, t.node.anc.scope, importPath, pkgName); err != nil {
+						break
+					}
+					if err = genRun(g.child[3]); err != nil {
+						break
+					}
+				}
+				n.anc.child[childPos(n)] = g
+				n.typ = g.typ
+				break
+			}
+			if n.typ, err = nodeType(interp, sc, n); err != nil {
+				break
+			}
+			n.gen = nop
+
 		case indexExpr:
 			if isBlank(n.child[0]) {
 				err = n.cfgErrorf("cannot use _ as value")
@@ -1021,8 +1086,9 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 					n.typ = t
 					return
 				}
-				g, found, err := genAST(sc, t.node.anc, []*itype{c1.typ})
-				if err != nil {
+				var g *node
+				var found bool
+				if g, found, err = genAST(sc, t.node.anc, []*itype{c1.typ}); err != nil {
 					return
 				}
 				if !found {
@@ -1040,13 +1106,16 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 				return
 			case genericT:
 				name := t.id() + "[" + n.child[1].typ.id() + "]"
-				sym, _, ok := sc.lookup(name)
-				if !ok {
-					err = n.cfgErrorf("type not found: %s", name)
+				if sym, _, ok := sc.lookup(name); ok {
+					n.gen = nop
+					n.typ = sym.typ
+					return
+				}
+				// The type is not instantiated yet, e.g. in make(Set[int]).
+				if n.typ, err = nodeType(interp, sc, n); err != nil {
 					return
 				}
 				n.gen = nop
-				n.typ = sym.typ
 				return
 			case structT:
 				// A struct indexed by a Type means an instantiated generic struct.
@@ -1119,6 +1188,7 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 				err = n.cfgErrorf("invalid operation: cannot send to non-channel %s", n.child[0].typ.id())
 				break
 			}
+			n.gen = interp.chanGen(n, send, sendFast)
 			fallthrough
 
 		case declStmt, exprStmt:
@@ -1151,7 +1221,11 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 				err = n.cfgErrorf("invalid continue label %s", n.child[0].ident)
 				break
 			}
-			n.tnext = n.sym.node.child[1].lastChild().start
+			if l := n.sym.node.child[1].lastChild(); isRangeFunc(l) {
+				n.tnext = l // Do not evaluate the iterator function again.
+			} else {
+				n.tnext = l.start
+			}
 
 		case gotoStmt:
 			if n.sym.node == nil {
@@ -1179,7 +1253,7 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 			}
 			wireChild(n)
 			switch c0 := n.child[0]; {
-			case c0.kind == indexListExpr:
+			case c0.kind == indexListExpr && !c0.isType(sc):
 				// Instantiate a generic function then call it.
 				fun := c0.child[0].sym.node
 				lt := []*itype{}
@@ -1221,6 +1295,11 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 
 			case isBuiltinCall(n, sc):
 				bname := c0.ident
+				if v, ok := bltnVersion[bname]; ok {
+					if err = interp.checkGoVersion(c0, bname, v); err != nil {
+						break
+					}
+				}
 				err = check.builtin(bname, n, n.child[1:], n.action == aCallSlice)
 				if err != nil {
 					break
@@ -1236,7 +1315,7 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 					n.findex = notInFrame
 					n.val = nil
 					switch bname {
-					case "unsafe.alignOf", "unsafe.Offsetof", "unsafe.Sizeof":
+					case "unsafe.Alignof", "unsafe.Offsetof", "unsafe.Sizeof":
 						n.gen = nop
 					}
 				case n.anc.kind == returnStmt:
@@ -1283,7 +1362,9 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 					err = n.cfgErrorf("missing argument in conversion to %s", c0.typ.id())
 				case 2:
 					c1 = n.child[1]
-					err = check.conversion(c1, c0.typ)
+					if err = check.conversion(c1, c0.typ); err == nil {
+						err = interp.checkUnsafeConversion(n, c1.typ, c0.typ)
+					}
 				default:
 					err = n.cfgErrorf("too many arguments in conversion to %s", c0.typ.id())
 				}
@@ -1614,6 +1695,7 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 		case funcDecl:
 			n.start = n.child[3].start
 			n.types, n.scope = sc.types, sc
+			n.frames = newFramePool(n)
 			sc = sc.pop()
 			funcName := n.child[1].ident
 			if sym := sc.sym[funcName]; !isMethod(n) && sym != nil && !isGeneric(sym.typ) {
@@ -1783,6 +1865,9 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 			if n.start.action == aNop {
 				n.start.gen = branch
 			}
+			if interp.optimize {
+				foldLogical(n)
+			}
 
 		case lorExpr:
 			if isBlank(n.child[0]) || isBlank(n.child[1]) {
@@ -1798,6 +1883,9 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 			if n.start.action == aNop {
 				n.start.gen = branch
 			}
+			if interp.optimize {
+				foldLogical(n)
+			}
 
 		case parenExpr:
 			wireChild(n)
@@ -1809,10 +1897,12 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 
 		case rangeStmt:
 			if sc.rangeChanType(n) != nil {
+				body := n.child[2]
 				n.start = n.child[1].start // Get chan
 				n.child[1].tnext = n       // then go to range function
-				n.tnext = n.child[2].start // then go to range body
-				n.child[2].tnext = n       // then body go to range function (loop)
+				body.start = body.child[0] // loopvar
+				n.tnext = body.start       // then go to range body
+				body.tnext = n             // then body go to range function (loop)
 				n.child[0].gen = empty
 			} else {
 				var k, o, body *node
@@ -1886,13 +1976,23 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 				// Resolve binary package symbol: a type or a value
 				name := n.child[1].ident
 				pkg := n.child[0].sym.typ.path
-				if s, ok := interp.binPkg[pkg][name]; ok {
+				if pkg == "unsafe" {
+					if err = interp.checkUnsafeSymbol(n, name); err != nil {
+						break
+					}
+				}
+				if isSyscallPath(pkg) {
+					if err = interp.checkSyscall(n, pkg, name); err != nil {
+						break
+					}
+				}
+				if s, ok := interp.binSymbol(pkg, name); ok {
 					if isBinType(s) {
 						n.typ = valueTOf(s.Type().Elem())
 					} else {
 						n.typ = valueTOf(fixPossibleConstType(s.Type()), withUntyped(isValueUntyped(s)))
 						n.rval = s
-						if pkg == "unsafe" && (name == "AlignOf" || name == "Offsetof" || name == "Sizeof") {
+						if pkg == "unsafe" && (name == "Alignof" || name == "Offsetof" || name == "Sizeof") {
 							n.sym = &symbol{kind: bltnSym, node: n, rval: s}
 							n.ident = pkg + "." + name
 						}
@@ -1906,6 +2006,7 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 				pkg, name := n.child[0].sym.typ.path, n.child[1].ident
 				// Resolve source package symbol
 				if sym, ok := interp.srcPkg[pkg][name]; ok {
+					interp.useSrcPkg(pkg)
 					n.findex = sym.index
 					if sym.global {
 						n.level = globalFrame
@@ -1920,6 +2021,9 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 				} else {
 					err = n.cfgErrorf("undefined selector: %s.%s", pkg, name)
 				}
+			case isInterfaceSrc(n.typ) && n.child[0].isType(sc):
+				// Method expression of an interface, whose methods are fields.
+				err = matchMethodExpr(sc, n)
 			case isStruct(n.typ) || isInterfaceSrc(n.typ):
 				// Find a matching field.
 				if ti := n.typ.lookupField(n.child[1].ident); len(ti) > 0 {
@@ -2142,6 +2246,11 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 				n.child[0].tnext = init.start
 			} else {
 				n.child[0].tnext = sbn.start
+				if interp.optimize {
+					if t := switchTarget(n); t != nil {
+						n.child[0].tnext = t
+					}
+				}
 			}
 
 		case switchIfStmt: // like an if-else chain
@@ -2185,6 +2294,13 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 			sbn.start = clauses[0].start
 			n.start = n.child[0].start
 			n.child[0].tnext = sbn.start
+			if interp.optimize {
+				if t := switchTarget(n); t != nil && len(n.child) == 1 {
+					n.start = t
+				} else if t != nil {
+					n.child[0].tnext = t
+				}
+			}
 
 		case typeAssertExpr:
 			if len(n.child) == 1 {
@@ -2243,6 +2359,7 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 
 			n.typ = n.child[0].typ
 			if n.action == aRecv {
+				n.gen = interp.chanGen(n, recv, recvFast)
 				// Channel receive operation: set type to the channel data type
 				if n.typ.cat == valueT {
 					n.typ = valueTOf(n.typ.rtype.Elem())
@@ -2306,6 +2423,15 @@ func (interp *Interpreter) cfg(root *node, sc *scope, importPath, pkgName string
 	if sc != interp.universe {
 		sc.pop()
 	}
+	if len(errs) > 0 {
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if len(errs) == 1 {
+			return initNodes, errs[0]
+		}
+		return initNodes, errs
+	}
 	return initNodes, err
 }
 
@@ -2378,7 +2504,7 @@ func compDefineX(sc *scope, n *node) error {
 	case unaryExpr:
 		if n.child[l].action == aRecv {
 			types = append(types, src.typ, sc.getType("bool"))
-			n.child[l].gen = recv2
+			n.child[l].gen = n.interp.chanGen(n.child[l], recv2, recv2Fast)
 			n.gen = nop
 		}
 
@@ -2452,8 +2578,8 @@ func (n *node) cfgErrorf(format string, a ...interface{}) *cfgError {
 	if pos.Filename == DefaultSourceName {
 		posString = strings.TrimPrefix(posString, DefaultSourceName+":")
 	}
-	a = append([]interface{}{posString}, a...)
-	return &cfgError{n, fmt.Errorf("%s: "+format, a...)}
+	msg := fmt.Sprintf(format, a...)
+	return &cfgError{n, fmt.Errorf("%s: %s", posString, msg), msg}
 }
 
 func genRun(nod *node) error {
@@ -2614,6 +2740,8 @@ func (n *node) isType(sc *scope) bool {
 		if len(n.child) == 1 {
 			return n.child[0].isType(sc)
 		}
+	case indexListExpr:
+		return n.child[0].isType(sc)
 	case selectorExpr:
 		pkg, name := n.child[0].ident, n.child[1].ident
 		baseName := path.Base(n.interp.fset.Position(n.pos).Filename)
@@ -2656,6 +2784,19 @@ func (n *node) isType(sc *scope) bool {
 	return false
 }
 
+// chanGen returns the builtin of the channel operation of node n: fast, i.e.
+// blocking without being cancellable, if the operation can never be
+// cancelled, or else cancellable. An operation can never be cancelled if it
+// is in the top-level code compiled for a run which is not cancellable, e.g.
+// by Eval. The functions may be called by any run, and keep the cancellable
+// builtins.
+func (interp *Interpreter) chanGen(n *node, cancellable, fast bltnGenerator) bltnGenerator {
+	if interp.noCancel && funcDef(n) == nil {
+		return fast
+	}
+	return cancellable
+}
+
 // wireChild wires AST nodes for CFG in subtree.
 func wireChild(n *node, exclude ...nkind) {
 	child := excludeNodeKind(n.child, exclude)
@@ -2823,10 +2964,12 @@ func isField(n *node) bool {
 	return n.kind == selectorExpr && len(n.child) > 0 && n.child[0].typ != nil && isStruct(n.child[0].typ)
 }
 
+// isInInterfaceType returns true if n is part of an interface type or of a
+// type parameter constraint, e.g. ~int | ~float64 in [T ~int | ~float64].
 func isInInterfaceType(n *node) bool {
 	anc := n.anc
 	for anc != nil {
-		if anc.kind == interfaceType {
+		if anc.kind == interfaceType || isTypeParamList(anc) {
 			return true
 		}
 		anc = anc.anc
@@ -2834,6 +2977,21 @@ func isInInterfaceType(n *node) bool {
 	return false
 }
 
+// isTypeParamList returns true if n is the type parameter list of a generic
+// function or type.
+func isTypeParamList(n *node) bool {
+	if n.kind != fieldList || n.anc == nil {
+		return false
+	}
+	switch n.anc.kind {
+	case funcType:
+		return childPos(n) == 0
+	case typeSpec:
+		return len(n.anc.child) == 3 && childPos(n) == 1
+	}
+	return false
+}
+
 func isInConstOrTypeDecl(n *node) bool {
 	anc := n.anc
 	for anc != nil {
@@ -2868,6 +3026,12 @@ func isNewDefine(n *node, sc *scope) bool {
 	return false
 }
 
+// isMethodExpr returns true if n is the method expression of an interpreter
+// method declared on the receiver type, whose value is the method definition.
+func isMethodExpr(n *node) bool {
+	return n.kind == selectorExpr && n.action == aGetMethod && n.findex == notInFrame
+}
+
 func isMethod(n *node) bool {
 	return len(n.child[0].child) > 0 // receiver defined
 }
@@ -2880,6 +3044,26 @@ func isMapEntry(n *node) bool {
 	return n.action == aGetIndex && isMap(n.child[0].typ)
 }
 
+// isRangeFunc returns true if n is a range statement over a function iterator.
+func isRangeFunc(n *node) bool {
+	if n.kind != rangeStmt {
+		return false
+	}
+	o := n.child[len(n.child)-2]
+	return o.typ != nil && isFunc(o.typ)
+}
+
+// isInRangeFunc returns true if n is within the body of a range over function
+// loop in the current function.
+func isInRangeFunc(n *node) bool {
+	for a := n.anc; a != nil && a.kind != funcDecl && a.kind != funcLit; a = a.anc {
+		if isRangeFunc(a) {
+			return true
+		}
+	}
+	return false
+}
+
 func isCall(n *node) bool {
 	return n.action == aCall || n.action == aCallSlice
 }
@@ -2977,6 +3161,15 @@ func setExec(n *node) {
 			n.gen = nop
 		}
 		n.gen(n)
+		if n.interp != nil && n.interp.cover != nil {
+			n.exec = n.interp.cover.wrap(n, n.exec)
+		}
+		if n.interp != nil && n.interp.stats {
+			n.exec = wrapStats(n, n.exec)
+		}
+		if n.interp != nil && n.interp.tracer.Load() != nil {
+			n.exec = n.interp.traceLine(n, n.exec)
+		}
 	}
 
 	set(n)
@@ -3041,6 +3234,9 @@ func compositeGenerator(n *node, typ *itype, rtyp reflect.Type) (gen bltnGenerat
 // to find the corresponding method, and populates n accordingly.
 func matchSelectorMethod(sc *scope, n *node) (err error) {
 	name := n.child[1].ident
+	if n.child[0].isType(sc) {
+		return matchMethodExpr(sc, n)
+	}
 	if n.typ.cat == valueT || n.typ.cat == errorT {
 		switch method, ok := n.typ.rtype.MethodByName(name); {
 		case ok:
@@ -3049,9 +3245,12 @@ func matchSelectorMethod(sc *scope, n *node) (err error) {
 			n.gen = getIndexBinMethod
 			n.action = aGetMethod
 			n.recv = &receiver{node: n.child[0]}
-			n.typ = valueTOf(method.Type, isBinMethod())
 			if hasRecvType {
+				n.typ = valueTOf(method.Type, isBinMethod())
 				n.typ.recv = n.typ
+			} else {
+				// The method type of an interface has no receiver.
+				n.typ = valueTOf(method.Type)
 			}
 		case n.typ.TypeOf().Kind() == reflect.Ptr:
 			if field, ok := n.typ.rtype.Elem().FieldByName(name); ok {
@@ -3096,7 +3295,7 @@ func matchSelectorMethod(sc *scope, n *node) (err error) {
 		} else if method, ok := reflect.PtrTo(n.typ.val.rtype).MethodByName(name); ok {
 			n.val = method.Index
 			n.gen = getIndexBinMethod
-			n.typ = valueTOf(method.Type, withRecv(valueTOf(reflect.PtrTo(n.typ.val.rtype), isBinMethod())))
+			n.typ = valueTOf(method.Type, isBinMethod(), withRecv(valueTOf(reflect.PtrTo(n.typ.val.rtype))))
 			n.recv = &receiver{node: n.child[0]}
 			n.action = aGetMethod
 		} else if field, ok := n.typ.val.rtype.FieldByName(name); ok {
@@ -3110,22 +3309,12 @@ func matchSelectorMethod(sc *scope, n *node) (err error) {
 	}
 
 	if m, lind := n.typ.lookupMethod(name); m != nil {
+		// Handle method with receiver.
 		n.action = aGetMethod
-		if n.child[0].isType(sc) {
-			// Handle method as a function with receiver in 1st argument.
-			n.val = m
-			n.findex = notInFrame
-			n.gen = nop
-			n.typ = &itype{}
-			*n.typ = *m.typ
-			n.typ.arg = append([]*itype{n.child[0].typ}, m.typ.arg...)
-		} else {
-			// Handle method with receiver.
-			n.gen = getMethod
-			n.val = m
-			n.typ = m.typ
-			n.recv = &receiver{node: n.child[0], index: lind}
-		}
+		n.gen = getMethod
+		n.val = m
+		n.typ = m.typ
+		n.recv = &receiver{node: n.child[0], index: lind}
 		return nil
 	}
 
@@ -3155,6 +3344,129 @@ func matchSelectorMethod(sc *scope, n *node) (err error) {
 	return n.cfgErrorf("undefined selector: %s", name)
 }
 
+// matchMethodExpr resolves the method expression n, such as T.M or (*T).M,
+// to a function of the method arguments preceded by the receiver.
+func matchMethodExpr(sc *scope, n *node) (err error) {
+	name := n.child[1].ident
+	t := n.child[0].typ
+	if n.typ, err = methodExprType(n, t, name); err != nil {
+		return err
+	}
+
+	if m, lind := t.lookupMethod(name); m != nil {
+		n.action = aGetMethod
+		n.val = m
+		if len(lind) == 0 {
+			// The method definition is called with the receiver as first argument.
+			n.findex = notInFrame
+			n.gen = nop
+			return nil
+		}
+		// The method is promoted from an embedded field.
+		n.recv = &receiver{index: lind}
+		n.gen = methodExpr
+		return nil
+	}
+
+	if recv := t; isBin(recv) {
+		if recv.cat == ptrT {
+			recv = recv.val
+		}
+		if recv.cat == valueT || recv.cat == errorT {
+			rt := t.TypeOf()
+			if m, _ := rt.MethodByName(name); rt.Kind() == reflect.Interface {
+				n.rval = interfaceMethodExpr(n.typ.rtype, m)
+			} else {
+				n.rval = m.Func
+			}
+			n.action = aGetSym
+			n.gen = nop
+			return nil
+		}
+	}
+
+	if m, lind, isPtr, ok := t.lookupBinMethod(name); ok {
+		// The binary method is promoted from an embedded field.
+		n.rval = promotedMethodExpr(n.typ.rtype, m.Name, lind, isPtr)
+		n.action = aGetSym
+		n.gen = nop
+		return nil
+	}
+
+	// Method of an interpreter interface.
+	n.action = aGetMethod
+	n.gen = methodExpr
+	return nil
+}
+
+// methodExprType returns the type of the method expression n of the method
+// name of type t, which is a function of the method arguments preceded by
+// the receiver.
+func methodExprType(n *node, t *itype, name string) (*itype, error) {
+	if m, lind := t.lookupMethod(name); m != nil {
+		if rtn := m.child[0].child[0].lastChild(); rtn != nil && rtn.kind == starExpr && t.cat != ptrT && len(lind) == 0 {
+			return nil, n.cfgErrorf("invalid method expression %s.%s (needs pointer receiver (*%s).%s)", t.id(), name, t.id(), name)
+		}
+		if m.typ == nil {
+			return nil, n.cfgErrorf("undefined method type: %s", name)
+		}
+		fn := n.child[1] // the value is a function wrapper
+		if len(lind) == 0 {
+			fn = m.typ.node // the value is the method definition
+		}
+		return funcOf(append([]*itype{t}, m.typ.arg...), m.typ.ret, withNode(fn), withScope(m.typ.scope)), nil
+	}
+
+	if recv := t; isBin(recv) {
+		if recv.cat == ptrT {
+			recv = recv.val
+		}
+		if recv.cat == valueT || recv.cat == errorT {
+			rt := t.TypeOf()
+			m, ok := rt.MethodByName(name)
+			switch {
+			case ok && rt.Kind() == reflect.Interface:
+				return valueTOf(funcWithRecv(rt, m.Type, 0)), nil
+			case ok:
+				return valueTOf(m.Type), nil
+			case rt.Kind() != reflect.Ptr:
+				if _, ok := reflect.PtrTo(rt).MethodByName(name); ok {
+					return nil, n.cfgErrorf("invalid method expression %s.%s (needs pointer receiver (*%s).%s)", t.id(), name, t.id(), name)
+				}
+			}
+			return nil, n.cfgErrorf("%s.%s undefined (type %s has no method %s)", t.id(), name, t.id(), name)
+		}
+	}
+
+	if m, _, _, ok := t.lookupBinMethod(name); ok {
+		if m.Func.IsValid() {
+			// Replace the receiver of the concrete type method.
+			return valueTOf(funcWithRecv(t.TypeOf(), m.Type, 1)), nil
+		}
+		return valueTOf(funcWithRecv(t.TypeOf(), m.Type, 0)), nil
+	}
+
+	if typ := t.interfaceMethod(name); typ != nil && isInterfaceSrc(t) {
+		return funcOf(append([]*itype{t}, typ.arg...), typ.ret, withNode(n.child[1]), withScope(n.scope)), nil
+	}
+
+	return nil, n.cfgErrorf("%s.%s undefined (type %s has no method %s)", t.id(), name, t.id(), name)
+}
+
+// funcWithRecv returns the function type ft, without its first skip
+// parameters, and with the receiver type rt as first parameter.
+func funcWithRecv(rt, ft reflect.Type, skip int) reflect.Type {
+	in := []reflect.Type{rt}
+	for i := skip; i < ft.NumIn(); i++ {
+		in = append(in, ft.In(i))
+	}
+	out := make([]reflect.Type, ft.NumOut())
+	for i := range out {
+		out[i] = ft.Out(i)
+	}
+	return reflect.FuncOf(in, out, ft.IsVariadic())
+}
+
 // arrayTypeLen returns the node's array length. If the expression is an
 // array variable it is determined from the value's type, otherwise it is
 // computed from the source definition.
@@ -0,0 +1,119 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// UseCgoStubs registers binary packages, given as for Use, to be used in
+// place of the source packages of the same import paths which require cgo,
+// not supported by the interpreter, e.g. with pure Go implementations or
+// stubs of their symbols. A stub is loaded when its package is imported by
+// interpreted code and its source files import "C", or can not be found.
+// A source package which does not require cgo is used instead of its stub.
+func (interp *Interpreter) UseCgoStubs(values Exports) {
+	interp.compile.Lock()
+	defer interp.compile.Unlock()
+
+	if interp.cgoStubs == nil {
+		interp.cgoStubs = map[string]cgoStub{}
+	}
+	for k, v := range values {
+		interp.cgoStubs[path.Dir(k)] = cgoStub{key: k, symbols: v}
+	}
+}
+
+// cgoStub is a binary package registered by UseCgoStubs.
+type cgoStub struct {
+	key     string // package path, as in Exports
+	symbols map[string]reflect.Value
+}
+
+// loadCgoStub loads the stub of the package importPath, imported from the
+// package at rPath, if the source package requires cgo or is not found.
+// It must be called with interp.compile locked.
+func (interp *Interpreter) loadCgoStub(rPath, importPath string) error {
+	stub, ok := interp.cgoStubs[importPath]
+	if !ok || interp.srcPkg[importPath] != nil {
+		return nil
+	}
+	if cgo, err := interp.requiresCgo(rPath, importPath); err == nil && !cgo {
+		return nil
+	}
+	delete(interp.cgoStubs, importPath)
+	defer interp.hooks.clearSymbols() // binary symbols are changed
+
+	interp.addBinPkg(stub.key, stub.symbols)
+	return interp.compileGenerics(stub.key, stub.symbols, func(src string) error {
+		_, err := interp.compileSrc(src, "", true)
+		return err
+	})
+}
+
+// requiresCgo returns true if a source file of the package importPath,
+// selected by build constraints, imports "C".
+func (interp *Interpreter) requiresCgo(rPath, importPath string) (bool, error) {
+	dir, _, err := interp.srcDir(rPath, importPath)
+	if err != nil {
+		return false, err
+	}
+	files, err := fs.ReadDir(interp.opt.filesystem, dir)
+	if err != nil {
+		return false, err
+	}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		name := file.Name()
+		if skipFile(&interp.context, name, NoTest) {
+			continue
+		}
+		name = path.Join(dir, name)
+		buf, err := fs.ReadFile(interp.opt.filesystem, name)
+		if err != nil {
+			return false, err
+		}
+		ctx := interp.context
+		if ok, err := interp.buildOk(&ctx, name, string(buf)); !ok || err != nil {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, buf, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, imp := range f.Imports {
+			if p, _ := strconv.Unquote(imp.Path.Value); p == "C" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// cgoError returns the error of the import of "C" at pos by the package
+// importPath, naming the chain of imports leading to it.
+func (interp *Interpreter) cgoError(importPath string, pos token.Pos) error {
+	paths := []string{importPath}
+	if len(interp.importing) > 0 {
+		paths = []string{interp.importing[0].from}
+		for _, s := range interp.importing {
+			paths = append(paths, s.to)
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "package %s requires cgo, which is not supported: %s", importPath, strings.Join(append(paths, "C"), " → "))
+	for _, s := range interp.importing {
+		fmt.Fprintf(&sb, "\n\t%s: %s imports %s", interp.fset.Position(s.pos), s.from, s.to)
+	}
+	fmt.Fprintf(&sb, "\n\t%s: %s imports C", interp.fset.Position(pos), importPath)
+	if importPath != mainID {
+		fmt.Fprintf(&sb, "\nuse UseCgoStubs to provide a replacement of %s", importPath)
+	}
+	return errors.New(sb.String())
+}
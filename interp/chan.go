@@ -0,0 +1,135 @@
+package interp
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// ChanOut returns a channel delivering the values sent by interpreted code on
+// the channel obtained by the evaluation in interp of name, e.g.
+// "main.events". The values are translated to plain Go values of type T,
+// values which can not be translated are dropped. The returned channel has the
+// capacity of the interpreted channel, and is closed when the interpreted
+// channel is closed or when ctx is done.
+//
+// An error is returned if name does not evaluate to a receivable channel of
+// elements convertible to T.
+func ChanOut[T any](ctx context.Context, interp *Interpreter, name string) (<-chan T, error) {
+	want := reflect.TypeOf((*T)(nil)).Elem()
+	ch, err := evalChan(interp, name, reflect.RecvDir)
+	if err != nil {
+		return nil, err
+	}
+	if et := ch.Type().Elem(); et != valueInterfaceType && !isTranslatable(et, want) {
+		return nil, fmt.Errorf("channel %s: invalid element type %v, want %v", name, et, want)
+	}
+
+	out := make(chan T, ch.Cap())
+	go func() {
+		defer close(out)
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: ch},
+		}
+		for {
+			chosen, v, ok := reflect.Select(cases)
+			if chosen == 0 || !ok {
+				return
+			}
+			x, ok := translateValue(v, want).Interface().(T)
+			if !ok {
+				continue
+			}
+			select {
+			case out <- x:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// ChanIn returns a channel whose values are sent to the interpreted channel
+// obtained by the evaluation in interp of name, e.g. "main.requests", so that
+// interpreted code can receive them, or select on them. The values are
+// translated to the element type of the interpreted channel. Closing the
+// returned channel closes the interpreted channel. Forwarding stops when ctx
+// is done.
+//
+// An error is returned if name does not evaluate to a channel accepting sends
+// of elements to which T is convertible.
+func ChanIn[T any](ctx context.Context, interp *Interpreter, name string) (chan<- T, error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	ch, err := evalChan(interp, name, reflect.SendDir)
+	if err != nil {
+		return nil, err
+	}
+	et := ch.Type().Elem()
+	if et != valueInterfaceType && !isTranslatable(typ, et) {
+		return nil, fmt.Errorf("channel %s: invalid element type %v for %v", name, et, typ)
+	}
+
+	in := make(chan T, ch.Cap())
+	go func() {
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectSend, Chan: ch},
+		}
+		for {
+			var x T
+			var ok bool
+			select {
+			case x, ok = <-in:
+			case <-ctx.Done():
+				return
+			}
+			if !ok {
+				ch.Close()
+				return
+			}
+			v := reflect.ValueOf(&x).Elem()
+			if et == valueInterfaceType {
+				if v.Kind() == reflect.Interface {
+					v = v.Elem()
+				}
+				cases[1].Send = reflect.ValueOf(valueInterface{value: v})
+			} else {
+				cases[1].Send = translateValue(v, et)
+			}
+			if chosen, _, _ := reflect.Select(cases); chosen == 0 {
+				return
+			}
+		}
+	}()
+	return in, nil
+}
+
+// evalChan returns the channel obtained by the evaluation of name in interp,
+// checking that it allows operations in direction dir.
+func evalChan(interp *Interpreter, name string, dir reflect.ChanDir) (reflect.Value, error) {
+	v, err := interp.Eval(name)
+	if err != nil {
+		return v, fmt.Errorf("channel %s: %w", name, err)
+	}
+	if !v.IsValid() || v.Kind() != reflect.Chan || v.Type().ChanDir()&dir == 0 {
+		return v, fmt.Errorf("channel %s: invalid type %v", name, typeOfValue(v))
+	}
+	if v.IsNil() {
+		return v, fmt.Errorf("channel %s: nil channel", name)
+	}
+	return v, nil
+}
+
+// isTranslatable returns true if values of type from can be translated by
+// translateValue to type to. Interface values are checked at run time.
+// Conversions between kinds, e.g. from int to string, are not translations.
+func isTranslatable(from, to reflect.Type) bool {
+	switch {
+	case from.Kind() == reflect.Interface, from.AssignableTo(to):
+		return true
+	default:
+		return from.Kind() == to.Kind() && from.ConvertibleTo(to)
+	}
+}
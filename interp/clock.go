@@ -0,0 +1,343 @@
+package interp
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// A Clock provides the current time and timers to interpreted code, in place
+// of the system clock. It is set using Options.Clock.
+//
+// The functions time.Now, time.Since, time.Until, time.Sleep, time.After,
+// time.Tick, time.NewTimer, time.NewTicker and time.AfterFunc of interpreted
+// code are backed by the clock. The timers and tickers of interpreted code
+// are then of types provided by the interpreter, with the fields and methods
+// of time.Timer and time.Ticker, which can not be passed to binary code
+// expecting a *time.Timer or a *time.Ticker.
+//
+// A time.Sleep of interpreted code returns when its execution is cancelled,
+// e.g. by the context given to EvalWithContext, without waiting for the
+// clock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// Sleep pauses the current goroutine for at least the duration d.
+	Sleep(d time.Duration)
+
+	// After returns a channel which receives the current time once the
+	// duration d has elapsed.
+	After(d time.Duration) <-chan time.Time
+
+	// Tick returns a channel which receives the current time every period d.
+	Tick(d time.Duration) <-chan time.Time
+
+	// AfterFunc calls f in its own goroutine once the duration d has
+	// elapsed. The returned function cancels the call, and returns false if
+	// the call was already made or cancelled.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+// A ManualClock is a Clock where time only passes when explicitly advanced.
+// It allows deterministic execution and fast-forwarding of interpreted code
+// depending on time. It is safe for concurrent use.
+type ManualClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []*clockWaiter
+}
+
+// clockWaiter is a pending timer of a ManualClock.
+type clockWaiter struct {
+	when   time.Time      // next expiration time
+	period time.Duration  // period for tickers, 0 for one-shot timers
+	c      chan time.Time // delivery channel
+	f      func()         // function called instead of a delivery, if not nil
+}
+
+// NewManualClock returns a ManualClock set at time t.
+func NewManualClock(t time.Time) *ManualClock {
+	return &ManualClock{now: t}
+}
+
+// Now returns the current time of the clock.
+func (c *ManualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// After returns a channel which receives the clock time once the clock has been
+// advanced by at least d.
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	return c.wait(d, 0)
+}
+
+// Tick returns a channel which receives the clock time each time the clock
+// is advanced past a multiple of period d. As for time.Tick, ticks are dropped
+// for slow receivers. Tick returns nil if d <= 0.
+func (c *ManualClock) Tick(d time.Duration) <-chan time.Time {
+	if d <= 0 {
+		return nil
+	}
+	return c.wait(d, d)
+}
+
+// Sleep blocks until the clock has been advanced by at least d.
+func (c *ManualClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// AfterFunc calls f in its own goroutine once the clock has been advanced
+// by at least d. The returned function cancels the call, and returns false
+// if the call was already made or cancelled.
+func (c *ManualClock) AfterFunc(d time.Duration, f func()) (stop func() bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if d <= 0 {
+		go f()
+		return func() bool { return false }
+	}
+	w := &clockWaiter{when: c.now.Add(d), f: f}
+	c.waiters = append(c.waiters, w)
+	return func() bool {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		for k, v := range c.waiters {
+			if v == w {
+				c.waiters = append(c.waiters[:k], c.waiters[k+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Waiting returns the number of pending sleeps, timers and tickers.
+func (c *ManualClock) Waiting() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.waiters)
+}
+
+// Advance moves the clock forward by d, and fires the expired timers.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.when.After(c.now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		if w.f != nil {
+			go w.f()
+			continue
+		}
+		select {
+		case w.c <- c.now:
+		default:
+		}
+		if w.period > 0 {
+			for !w.when.After(c.now) {
+				w.when = w.when.Add(w.period)
+			}
+			waiters = append(waiters, w)
+		}
+	}
+	c.waiters = waiters
+}
+
+func (c *ManualClock) wait(d, period time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, &clockWaiter{when: c.now.Add(d), period: period, c: ch})
+	return ch
+}
+
+// systemClock is the Clock of the system.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) Tick(d time.Duration) <-chan time.Time  { return time.Tick(d) }
+
+func (systemClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+// clockTimer is the time.Timer of interpreted code using a Clock.
+type clockTimer struct {
+	C <-chan time.Time
+
+	clock Clock
+	c     chan time.Time
+	f     func() // function of time.AfterFunc, or nil
+	mutex sync.Mutex
+	stop  func() bool
+}
+
+func newClockTimer(clock Clock, d time.Duration, f func()) *clockTimer {
+	t := &clockTimer{clock: clock, f: f}
+	if f == nil {
+		t.c = make(chan time.Time, 1)
+		t.C = t.c
+	}
+	t.stop = clock.AfterFunc(d, t.fire)
+	return t
+}
+
+func (t *clockTimer) fire() {
+	if t.f != nil {
+		t.f()
+		return
+	}
+	select {
+	case t.c <- t.clock.Now():
+	default:
+	}
+}
+
+// Stop prevents the timer from firing. It returns false if the timer has
+// already expired or been stopped.
+func (t *clockTimer) Stop() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.stop()
+}
+
+// Reset changes the timer to expire after duration d. It returns true if the
+// timer had been active.
+func (t *clockTimer) Reset(d time.Duration) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	active := t.stop()
+	t.stop = t.clock.AfterFunc(d, t.fire)
+	return active
+}
+
+// clockTicker is the time.Ticker of interpreted code using a Clock. As for
+// time.Ticker, ticks are dropped for slow receivers.
+type clockTicker struct {
+	C <-chan time.Time
+
+	clock   Clock
+	c       chan time.Time
+	mutex   sync.Mutex
+	period  time.Duration
+	next    time.Time // time of the next tick
+	stopped bool
+	stop    func() bool
+}
+
+func newClockTicker(clock Clock, d time.Duration) *clockTicker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	t := &clockTicker{clock: clock, c: make(chan time.Time, 1)}
+	t.C = t.c
+	t.Reset(d)
+	return t
+}
+
+// schedule arms the next tick. It must be called with t.mutex locked.
+func (t *clockTicker) schedule() {
+	t.stop = t.clock.AfterFunc(t.next.Sub(t.clock.Now()), t.tick)
+}
+
+func (t *clockTicker) tick() {
+	now := t.clock.Now()
+	t.mutex.Lock()
+	if t.stopped {
+		t.mutex.Unlock()
+		return
+	}
+	// The next tick is armed before the delivery of this one, which may
+	// trigger an advance of the clock.
+	for !t.next.After(now) {
+		t.next = t.next.Add(t.period)
+	}
+	t.schedule()
+	t.mutex.Unlock()
+
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+// Stop turns off the ticker.
+func (t *clockTicker) Stop() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.stopped = true
+	t.stop()
+}
+
+// Reset stops the ticker and resets its period to d.
+func (t *clockTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for Ticker.Reset")
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.stop != nil {
+		t.stop()
+	}
+	t.stopped = false
+	t.period = d
+	t.next = t.clock.Now().Add(d)
+	t.schedule()
+}
+
+// fixTime redefines the time functions of the interpreter stdlib symbols to
+// use the interpreter clock.
+func fixTime(interp *Interpreter) {
+	p := interp.binPkg["time"]
+	if p == nil || interp.clock == nil {
+		return
+	}
+	clock := interp.clock
+
+	p["Now"] = reflect.ValueOf(clock.Now)
+	p["Since"] = reflect.ValueOf(func(t time.Time) time.Duration { return clock.Now().Sub(t) })
+	p["Until"] = reflect.ValueOf(func(t time.Time) time.Duration { return t.Sub(clock.Now()) })
+	p["After"] = reflect.ValueOf(clock.After)
+	p["Tick"] = reflect.ValueOf(clock.Tick)
+
+	p["Timer"] = reflect.ValueOf((*clockTimer)(nil))
+	p["Ticker"] = reflect.ValueOf((*clockTicker)(nil))
+	p["NewTimer"] = reflect.ValueOf(func(d time.Duration) *clockTimer { return newClockTimer(clock, d, nil) })
+	p["AfterFunc"] = reflect.ValueOf(func(d time.Duration, f func()) *clockTimer { return newClockTimer(clock, d, f) })
+	p["NewTicker"] = reflect.ValueOf(func(d time.Duration) *clockTicker { return newClockTicker(clock, d) })
+
+	// The sleeps of a cancellable run return once it is cancelled.
+	sleep := reflect.ValueOf(clock.Sleep)
+	p["Sleep"] = sleep
+	interp.runSymbol(sleep, func(r *runState) reflect.Value {
+		if r.cancel == nil {
+			return sleep
+		}
+		done := r.cancel.done
+		return reflect.ValueOf(func(d time.Duration) {
+			wake := make(chan struct{})
+			stop := clock.AfterFunc(d, func() { close(wake) })
+			select {
+			case <-wake:
+			case <-done:
+				stop()
+			}
+		})
+	})
+}
@@ -82,3 +82,55 @@ func TestCompileAST(t *testing.T) {
 		})
 	}
 }
+
+func TestFrameLayouts(t *testing.T) {
+	i := New(Options{})
+	prog, err := i.Compile(`
+func counter(step int) func() int {
+	n := 0
+	return func() int {
+		n += step
+		return n
+	}
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layouts := prog.FrameLayouts()
+	if len(layouts) != 2 {
+		t.Fatalf("got %d layouts, want 2", len(layouts))
+	}
+
+	outer, inner := layouts[0], layouts[1]
+	if outer.Func != "main.counter" || outer.Pos.Line != 2 {
+		t.Errorf("got function %s at line %d", outer.Func, outer.Pos.Line)
+	}
+	slots := map[string]FrameSlot{}
+	var size uintptr
+	for _, s := range outer.Slots {
+		slots[s.Name] = s
+		size += s.Type.Size()
+	}
+	for _, name := range []string{"step", "n"} {
+		if _, ok := slots[name]; !ok {
+			t.Errorf("missing slot %s in %v", name, outer.Slots)
+		}
+	}
+	if outer.Size != size || outer.Captures != nil {
+		t.Errorf("got size %d and captures %v", outer.Size, outer.Captures)
+	}
+
+	want := []FrameCapture{
+		{Name: "n", Level: 1, Index: slots["n"].Index},
+		{Name: "step", Level: 1, Index: slots["step"].Index},
+	}
+	if len(inner.Captures) != len(want) {
+		t.Fatalf("got captures %v, want %v", inner.Captures, want)
+	}
+	for k, c := range inner.Captures {
+		if c != want[k] {
+			t.Errorf("got capture %v, want %v", c, want[k])
+		}
+	}
+}
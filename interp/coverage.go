@@ -0,0 +1,98 @@
+package interp
+
+import (
+	"bufio"
+	"fmt"
+	"go/token"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Coverage is the code coverage of interpreted code, in the form of hit
+// counts of source lines, indexed by file name, then by line number. Only
+// lines holding executable code are present, with a zero count if they were
+// not executed.
+type Coverage map[string]map[int]int64
+
+// coverage records the execution counts of CFG nodes.
+type coverage struct {
+	mutex    sync.Mutex
+	counters []*coverCounter
+}
+
+// coverCounter is the execution counter of a CFG node.
+type coverCounter struct {
+	pos  token.Pos
+	hits atomic.Int64
+}
+
+// wrap returns exec, the builtin of node n, instrumented to count its
+// executions.
+func (c *coverage) wrap(n *node, exec bltn) bltn {
+	if exec == nil || n.pos == token.NoPos {
+		return exec
+	}
+	cc := &coverCounter{pos: n.pos}
+	c.mutex.Lock()
+	c.counters = append(c.counters, cc)
+	c.mutex.Unlock()
+
+	return func(f *frame) bltn {
+		cc.hits.Add(1)
+		return exec(f)
+	}
+}
+
+// Coverage returns the code coverage of the code executed by the interpreter
+// so far. It returns nil if the interpreter was not created with the
+// Coverage option.
+func (interp *Interpreter) Coverage() Coverage {
+	c := interp.cover
+	if c == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cov := Coverage{}
+	for _, cc := range c.counters {
+		pos := interp.fset.Position(cc.pos)
+		lines := cov[pos.Filename]
+		if lines == nil {
+			lines = map[int]int64{}
+			cov[pos.Filename] = lines
+		}
+		// A line is covered as much as its most executed code.
+		if hits := cc.hits.Load(); hits >= lines[pos.Line] {
+			lines[pos.Line] = hits
+		}
+	}
+	return cov
+}
+
+// WriteProfile writes the coverage to w in the format of Go cover profiles,
+// as produced by "go test -coverprofile", in count mode. Each line of code is
+// reported as a block.
+func (c Coverage) WriteProfile(w io.Writer) error {
+	files := make([]string, 0, len(c))
+	for file := range c {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "mode: count")
+	for _, file := range files {
+		lines := make([]int, 0, len(c[file]))
+		for line := range c[file] {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+		for _, line := range lines {
+			fmt.Fprintf(bw, "%s:%d.1,%d.1 1 %d\n", file, line, line+1, c[file][line])
+		}
+	}
+	return bw.Flush()
+}
@@ -1,3 +1,5 @@
+//go:build !yaegi_small
+
 package interp
 
 import (
@@ -195,7 +197,7 @@ func (interp *Interpreter) Debug(ctx context.Context, prog *Program, events func
 	mainG.mode = DebugEntry
 
 	interp.debugger = dbg
-	interp.frame.debug = &frameDebugData{kind: frameRoot, g: mainG}
+	interp.frame.Load().debug = &frameDebugData{kind: frameRoot, g: mainG}
 
 	prog.root.Walk(func(n *node) bool {
 		n.setProgram(prog)
@@ -208,10 +210,10 @@ func (interp *Interpreter) Debug(ctx context.Context, prog *Program, events func
 		defer dbg.cancel()
 
 		<-mainG.resume
-		dbg.events(&DebugEvent{dbg, DebugEnterGoRoutine, interp.frame})
+		dbg.events(&DebugEvent{dbg, DebugEnterGoRoutine, interp.frame.Load()})
 		dbg.result, dbg.err = interp.ExecuteWithContext(ctx, prog)
 		dbg.exitGoRoutine(mainG)
-		dbg.events(&DebugEvent{dbg, DebugExitGoRoutine, interp.frame})
+		dbg.events(&DebugEvent{dbg, DebugExitGoRoutine, interp.frame.Load()})
 		dbg.gWait.Wait()
 	}()
 
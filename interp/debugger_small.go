@@ -0,0 +1,21 @@
+//go:build yaegi_small
+
+package interp
+
+// Debugger is the debugger of an interpreter. It is not available in the
+// small build profile, where an interpreter has no debugger: the debug state
+// of nodes and frames is never set, and the hooks of the debugger in the
+// execution are not called.
+type Debugger struct{}
+
+type nodeDebugData struct {
+	program     *Program
+	breakOnLine bool
+	breakOnCall bool
+}
+
+type frameDebugData struct{}
+
+func (dbg *Debugger) enterCall(nFunc, nCall *node, f *frame) {}
+func (dbg *Debugger) exitCall(nFunc, nCall *node, f *frame)  {}
+func (dbg *Debugger) exec(n *node, f *frame) (stop bool)     { return false }
@@ -0,0 +1,376 @@
+package interp
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/doc"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SymbolInfo describes an exported symbol of a package.
+type SymbolInfo struct {
+	Name      string       // name of the symbol
+	Kind      string       // "const", "func", "type" or "var"
+	Type      reflect.Type // type of the symbol, or nil for generic functions and types
+	Signature string       // declaration of the symbol in Go syntax, e.g. "func Add(a, b int) int"
+	Doc       string       // doc comment of the symbol, if any
+}
+
+// Describe returns the description of the exported symbols of the source or
+// binary package importPath, sorted by name, or nil if the package is not
+// known. It allows hosts to generate user interfaces or RPC bindings for the
+// functions of loaded scripts. Doc comments are only available for source
+// packages.
+func (interp *Interpreter) Describe(importPath string) []SymbolInfo {
+	interp.mutex.RLock()
+	defer interp.mutex.RUnlock()
+
+	var infos []SymbolInfo
+	if pkg, ok := interp.srcPkg[importPath]; ok {
+		for name, sym := range pkg {
+			if !canExport(name) {
+				continue
+			}
+			if info, ok := interp.describeSym(name, sym); ok {
+				infos = append(infos, info)
+			}
+		}
+	} else if pkg, ok := interp.binPkg[importPath]; ok {
+		for name, v := range pkg {
+			if !canExport(name) {
+				continue // skip interface wrappers
+			}
+			infos = append(infos, describeBin(name, v))
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// describeSym returns the description of the source symbol sym named name.
+func (interp *Interpreter) describeSym(name string, sym *symbol) (SymbolInfo, bool) {
+	info := SymbolInfo{Name: name}
+	switch sym.kind {
+	case constSym:
+		info.Kind = "const"
+	case funcSym:
+		info.Kind = "func"
+	case typeSym:
+		info.Kind = "type"
+	case varSym:
+		info.Kind = "var"
+	default:
+		return info, false
+	}
+	if d := interp.symDecl(sym); d != nil {
+		info.Signature, info.Doc = d.sig, d.doc
+	}
+	if t := sym.typ; t != nil && t.cat != genericT && !isGeneric(t) {
+		info.Type = t.TypeOf()
+	}
+	return info, true
+}
+
+// describeBin returns the description of the binary symbol v named name.
+func describeBin(name string, v reflect.Value) SymbolInfo {
+	info := SymbolInfo{Name: name}
+	if gf, ok := v.Interface().(GenericFunc); ok {
+		info.Kind = "func"
+		info.Signature, _, _ = strings.Cut(string(gf), " {")
+		return info
+	}
+	if gt, ok := v.Interface().(GenericType); ok {
+		info.Kind = "type"
+		info.Signature, _, _ = strings.Cut(string(gt), "\n")
+		info.Signature, _, _ = strings.Cut(info.Signature, " {")
+		return info
+	}
+	switch {
+	case isBinType(v):
+		info.Kind = "type"
+		info.Type = v.Type().Elem()
+		info.Signature = "type " + name + " " + info.Type.Kind().String()
+	case v.Kind() == reflect.Func:
+		info.Kind = "func"
+		info.Type = v.Type()
+		info.Signature = "func " + name + strings.TrimPrefix(info.Type.String(), "func")
+	case v.Kind() == reflect.Ptr:
+		info.Kind = "var"
+		info.Type = v.Type().Elem()
+		info.Signature = "var " + name + " " + info.Type.String()
+	default:
+		info.Kind = "const"
+		info.Type = v.Type()
+		if c, ok := v.Interface().(constant.Value); ok {
+			info.Type = nil // untyped constant
+			info.Signature = "const " + name + " = " + c.ExactString()
+		} else {
+			info.Signature = "const " + name + " " + info.Type.String()
+		}
+	}
+	return info
+}
+
+// declInfo is the signature and doc comment of a package level declaration.
+type declInfo struct {
+	sig string
+	doc string
+}
+
+// setDecl records the signature and the doc comment of the exported package
+// level declaration node n, from its source d, which is a function
+// declaration or a spec of declaration anc.
+func (interp *Interpreter) setDecl(n *node, d ast.Node, anc astNode) {
+	var sig string
+	var doc *ast.CommentGroup
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		if !d.Name.IsExported() {
+			return
+		}
+		decl := *d
+		decl.Doc, decl.Body = nil, nil
+		sig, doc = printNode(interp.fset, &decl), d.Doc
+	case *ast.TypeSpec:
+		if !d.Name.IsExported() || anc.node.anc == nil || anc.node.anc.kind != fileStmt {
+			return
+		}
+		spec := *d
+		spec.Doc, spec.Comment = nil, nil
+		sig = "type " + printNode(interp.fset, &spec)
+		doc = specDoc(d.Doc, anc)
+	case *ast.ValueSpec:
+		if !ast.IsExported(d.Names[0].Name) || anc.node.anc == nil || anc.node.anc.kind != fileStmt {
+			return
+		}
+		spec := *d
+		spec.Doc, spec.Comment = nil, nil
+		sig = anc.ast.(*ast.GenDecl).Tok.String() + " " + printNode(interp.fset, &spec)
+		doc = specDoc(d.Doc, anc)
+	}
+	interp.decls[n] = &declInfo{sig: sig, doc: doc.Text()}
+}
+
+// specDoc returns the doc comment of a type or value spec, which defaults to
+// the doc of its declaration anc if not grouped, as in go/doc.
+func specDoc(doc *ast.CommentGroup, anc astNode) *ast.CommentGroup {
+	if doc != nil {
+		return doc
+	}
+	if g, ok := anc.ast.(*ast.GenDecl); ok && !g.Lparen.IsValid() {
+		return g.Doc
+	}
+	return nil
+}
+
+// Doc returns the documentation of the symbol designated by symbolPath, in
+// the format of "go doc": the declaration of the symbol followed by its
+// indented doc comment. symbolPath is made of a package import path, or the
+// name of a package imported by the main package, a dot and the name of a
+// symbol, optionally followed by a dot and the name of a method, as in
+// "strings.ToUpper", "net/http.Client.Do" or "http.Get". Symbols without
+// package are looked up in the main package.
+//
+// The documentation of binary packages is read from their sources, found
+// with the build context of the interpreter. If they are not available,
+// only the declaration of the symbol is returned. An empty string is
+// returned if the symbol is not found.
+func (interp *Interpreter) Doc(symbolPath string) string {
+	interp.mutex.RLock()
+	defer interp.mutex.RUnlock()
+
+	i := strings.LastIndex(symbolPath, "/")
+	names := strings.Split(symbolPath[i+1:], ".")
+	pkgPath := symbolPath[:i+1] + names[0]
+	names = names[1:]
+	if _, ok := interp.srcPkg[pkgPath]; !ok && interp.binPkg[pkgPath] == nil {
+		// Imports are keyed by package name and source file name.
+		found := false
+		for k, sym := range interp.srcPkg[mainID] {
+			if sym.kind == pkgSym && strings.HasPrefix(k, pkgPath+"/") {
+				pkgPath, found = sym.typ.path, true
+				break
+			}
+		}
+		if !found && i < 0 {
+			pkgPath, names = mainID, strings.Split(symbolPath, ".")
+		}
+	}
+	if len(names) == 0 || len(names) > 2 {
+		return ""
+	}
+
+	if pkg, ok := interp.srcPkg[pkgPath]; ok {
+		sym := pkg[names[0]]
+		if sym == nil {
+			return ""
+		}
+		var d *declInfo
+		switch {
+		case len(names) == 1:
+			d = interp.symDecl(sym)
+		case sym.kind == typeSym && sym.typ != nil:
+			for _, m := range sym.typ.method {
+				if m.kind == funcDecl && m.child[1].ident == names[1] {
+					d = interp.decls[m]
+				}
+			}
+		}
+		if d == nil {
+			return ""
+		}
+		return formatDoc(d.sig, d.doc)
+	}
+
+	pkg, ok := interp.binPkg[pkgPath]
+	if !ok {
+		return ""
+	}
+	if sig, doc, ok := interp.binDoc(pkgPath, names); ok {
+		return formatDoc(sig, doc)
+	}
+	if v, ok := pkg[names[0]]; ok && len(names) == 1 {
+		return formatDoc(describeBin(names[0], v).Signature, "")
+	}
+	return ""
+}
+
+// symDecl returns the declaration of source symbol sym, or nil.
+func (interp *Interpreter) symDecl(sym *symbol) *declInfo {
+	if d := interp.decls[sym.node]; d != nil || sym.kind != typeSym {
+		return d
+	}
+	// The symbol of a type may refer to a child of its declaration, or be
+	// redefined without node by cfg, while its type refers to the name of the
+	// declaration.
+	nodes := []*node{sym.node}
+	if sym.typ != nil {
+		nodes = append(nodes, sym.typ.node)
+	}
+	for _, n := range nodes {
+		if n != nil && n.anc != nil && (n.anc.kind == typeSpec || n.anc.kind == typeSpecAssign) {
+			if d := interp.decls[n.anc]; d != nil {
+				return d
+			}
+		}
+	}
+	return nil
+}
+
+// binDoc returns the declaration and doc comment of the symbol or method
+// designated by names in the sources of the binary package importPath.
+func (interp *Interpreter) binDoc(importPath string, names []string) (sig, text string, ok bool) {
+	bp, err := interp.context.Import(importPath, "", 0)
+	if err != nil {
+		return "", "", false
+	}
+	fset := token.NewFileSet()
+	files := make([]*ast.File, 0, len(bp.GoFiles))
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(bp.Dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return "", "", false
+		}
+		files = append(files, f)
+	}
+	p, err := doc.NewFromFiles(fset, files, importPath)
+	if err != nil {
+		return "", "", false
+	}
+
+	funcDoc := func(f *doc.Func) (string, string, bool) {
+		decl := *f.Decl
+		decl.Doc, decl.Body = nil, nil
+		return printNode(fset, &decl), f.Doc, true
+	}
+	valueDoc := func(values []*doc.Value) (string, string, bool) {
+		for _, v := range values {
+			for _, n := range v.Names {
+				if n == names[0] {
+					decl := *v.Decl
+					decl.Doc = nil
+					return printNode(fset, &decl), v.Doc, true
+				}
+			}
+		}
+		return "", "", false
+	}
+
+	if len(names) == 1 {
+		for _, f := range p.Funcs {
+			if f.Name == names[0] {
+				return funcDoc(f)
+			}
+		}
+		if sig, text, ok = valueDoc(p.Consts); ok {
+			return sig, text, ok
+		}
+		if sig, text, ok = valueDoc(p.Vars); ok {
+			return sig, text, ok
+		}
+	}
+	for _, t := range p.Types {
+		if len(names) == 2 {
+			if t.Name != names[0] {
+				continue
+			}
+			for _, m := range t.Methods {
+				if m.Name == names[1] {
+					return funcDoc(m)
+				}
+			}
+			return "", "", false
+		}
+		if t.Name == names[0] {
+			decl := *t.Decl
+			decl.Doc = nil
+			return printNode(fset, &decl), t.Doc, true
+		}
+		// Constructors, constants and variables are grouped with their type.
+		for _, f := range t.Funcs {
+			if f.Name == names[0] {
+				return funcDoc(f)
+			}
+		}
+		if sig, text, ok = valueDoc(t.Consts); ok {
+			return sig, text, ok
+		}
+		if sig, text, ok = valueDoc(t.Vars); ok {
+			return sig, text, ok
+		}
+	}
+	return "", "", false
+}
+
+// printNode returns the source of n, formatted as gofmt does.
+func printNode(fset *token.FileSet, n ast.Node) string {
+	var b strings.Builder
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	_ = cfg.Fprint(&b, fset, n)
+	return b.String()
+}
+
+// formatDoc returns the declaration sig followed by the doc comment text,
+// indented, as printed by "go doc".
+func formatDoc(sig, text string) string {
+	var b strings.Builder
+	b.WriteString(sig)
+	b.WriteByte('\n')
+	if text == "" {
+		return b.String()
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if line != "" {
+			b.WriteString("    " + line)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package interp
+
+import (
+	"errors"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// Severity is the severity level of a Diagnostic.
+type Severity int
+
+// Severity levels.
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "unknown"
+}
+
+// Diagnostic codes, identifying the compilation stage which reported a problem.
+const (
+	CodeSyntax  = "syntax"  // parse error
+	CodeCompile = "compile" // type checking or code generation error
+)
+
+// A Diagnostic describes a problem found in source code at compile time,
+// located by its position.
+type Diagnostic struct {
+	File     string   // source file name
+	Line     int      // line number, starting at 1, or 0 if unknown
+	Column   int      // column number, starting at 1 (byte count), or 0 if unknown
+	Severity Severity // severity level
+	Message  string   // description of the problem, without position
+	Code     string   // kind of problem, such as CodeSyntax or CodeCompile
+}
+
+// Diagnostics returns the diagnostics held by an error returned by the
+// compilation of source code, as by Eval or Compile, or nil if err does not
+// describe problems located in source code.
+func Diagnostics(err error) []Diagnostic {
+	var d interface{ Diagnostics() []Diagnostic }
+	if errors.As(err, &d) {
+		return d.Diagnostics()
+	}
+	var el scanner.ErrorList
+	if errors.As(err, &el) {
+		diags := make([]Diagnostic, 0, len(el))
+		for _, e := range el {
+			diags = append(diags, newDiagnostic(e.Pos, e.Msg, CodeSyntax))
+		}
+		return diags
+	}
+	var e *scanner.Error
+	if errors.As(err, &e) {
+		return []Diagnostic{newDiagnostic(e.Pos, e.Msg, CodeSyntax)}
+	}
+	return nil
+}
+
+// A CompileError is the list of errors reported by a compilation, in source
+// order, when Options.MaxErrors allows more than one.
+type CompileError []error
+
+func (e CompileError) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+// Unwrap returns the list of errors.
+func (e CompileError) Unwrap() []error { return e }
+
+// Diagnostics returns the diagnostics of all the errors.
+func (e CompileError) Diagnostics() []Diagnostic {
+	var diags []Diagnostic
+	for _, err := range e {
+		diags = append(diags, Diagnostics(err)...)
+	}
+	return diags
+}
+
+// Diagnostics returns the diagnostic describing the CFG error.
+func (c *cfgError) Diagnostics() []Diagnostic {
+	return []Diagnostic{newDiagnostic(c.interp.fset.Position(c.pos), c.msg, CodeCompile)}
+}
+
+func newDiagnostic(pos token.Position, msg, code string) Diagnostic {
+	return Diagnostic{
+		File:     pos.Filename,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Severity: SeverityError,
+		Message:  msg,
+		Code:     code,
+	}
+}
@@ -41,6 +41,42 @@ Will ensure that an import of a package will exclude files containing
 And include files containing
 
 	// +build noasm
+
+# Concurrency
+
+An Interpreter can be used by multiple goroutines simultaneously: Eval,
+EvalPath, Compile and Execute may be called concurrently on the same
+Interpreter, for example by the handlers of a server, without the need of
+an interpreter per request.
+
+Compilations, including the code generation performed at the start of
+Execute, are serialized. Executions are not: compiled programs run
+concurrently, sharing the global variables of the interpreter, while
+the temporary values of their top level statements are private to each
+program. Access to shared global variables from concurrent executions
+must be synchronized by the interpreted code, as in compiled Go.
+
+A same Program must not be executed by several goroutines at the same
+time, as its temporary values would be shared. Cancelling the context of
+EvalWithContext or ExecuteWithContext stops only the execution started
+with it, including the goroutines it started, which then returns the
+context error. Shutdown stops all the running executions of the
+interpreter.
+
+# Small build profile
+
+The yaegi_small build tag selects a reduced interpreter, to limit the size
+of the binaries embedding it:
+
+	go build -tags yaegi_small
+
+It leaves out the debugger (the Debug method and its types), the display
+of the AST and CFG graphs, and the filtering of stack traces: the
+FilteredStack and FilteredCallers methods, and the stacks of Panic errors,
+return the runtime stack traces unchanged. The profile only reduces the
+size: the interpreter core still relies on the reflect.MakeFunc,
+reflect.StructOf and reflect.FuncOf functions, so it does not target
+TinyGo, which does not implement them.
 */
 package interp
 
@@ -1,3 +1,5 @@
+//go:build !yaegi_small
+
 package interp
 
 import (
@@ -0,0 +1,16 @@
+//go:build yaegi_small
+
+package interp
+
+import "io"
+
+// The display of the AST and CFG graphs is not available in the small
+// build profile: the YAEGI_AST_DOT and YAEGI_CFG_DOT environment variables
+// have no effect.
+
+func (n *node) astDot(out io.Writer, name string) {}
+func (n *node) cfgDot(out io.Writer)              {}
+
+func dotWriter(dotCmd string) io.WriteCloser { return nil }
+
+func defaultDotCmd(filePath, prefix string) string { return "" }
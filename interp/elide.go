@@ -0,0 +1,193 @@
+package interp
+
+import "reflect"
+
+// Call elision is performed at compilation when the Optimize option is set.
+//
+// A call to a forwarding function, whose body only calls another function
+// with its own parameters, in the same order, and returns its results, is
+// replaced by a direct call to the final function, binary or interpreted,
+// skipping the frames of the forwarding functions.
+//
+// A call of a function to itself, whose results are directly returned, is a
+// tail call: the frame of the current call is reused for the new one,
+// instead of allocating a new frame, so the depth of recursion is not
+// limited by the available memory.
+
+// maxForwards is the maximum length of a chain of forwarding functions
+// which is elided.
+const maxForwards = 8
+
+// forwardValue returns a function returning the value of the function
+// called by n, or of the final function it forwards to, if its call can be
+// elided. It returns nil otherwise.
+func forwardValue(n *node, value func(*frame) reflect.Value) func(*frame) reflect.Value {
+	interp := n.interp
+	if !interp.optimize || interp.cover != nil || n.anc.kind == deferStmt || n.anc.kind == goStmt {
+		return nil
+	}
+	def := staticFunc(n.child[0])
+	if def == nil {
+		return nil
+	}
+
+	// The forwarding functions may be modified by ReloadPath, in which case
+	// the call is not elided anymore.
+	var defs []*node
+	var starts []*node
+	var target reflect.Value
+	for len(defs) < maxForwards {
+		fc := forwardCall(def)
+		if fc == nil {
+			break
+		}
+		defs, starts = append(defs, def), append(starts, def.start)
+		c0 := fc.child[0]
+		if isBinCall(fc, fc.scope) {
+			if isBinForward(fc, def) {
+				target = c0.rval
+			}
+			break
+		}
+		next := staticFunc(c0)
+		if next == nil || next.typ.id() != def.typ.id() {
+			break
+		}
+		def = next
+		target = reflect.ValueOf(def)
+	}
+	if !target.IsValid() {
+		return nil
+	}
+
+	return func(f *frame) reflect.Value {
+		if interp.debugger != nil {
+			return value(f)
+		}
+		for i, d := range defs {
+			if d.start != starts[i] {
+				return value(f)
+			}
+		}
+		return target
+	}
+}
+
+// staticFunc returns the declaration of the function, not a method, which
+// is designated by n, or nil.
+func staticFunc(n *node) *node {
+	if n.kind != identExpr || n.sym == nil || n.sym.kind != funcSym || n.recv != nil {
+		return nil
+	}
+	def := n.sym.node
+	if def == nil || def.kind != funcDecl || isMethod(def) || len(def.child) < 4 || def.typ == nil || def.typ.cat != funcT {
+		return nil
+	}
+	return def
+}
+
+// forwardCall returns the call node of the body of function def, if def is
+// a forwarding function. It returns nil otherwise.
+func forwardCall(def *node) *node {
+	body := def.child[3]
+	if len(body.child) != 1 {
+		return nil
+	}
+	fc := body.child[0]
+	if fc.kind == returnStmt {
+		if len(fc.child) != 1 {
+			return nil
+		}
+		fc = fc.child[0]
+	} else if len(def.typ.ret) > 0 {
+		return nil
+	}
+	if fc.kind != callExpr || fc.action != aCall && fc.action != aCallSlice || fc.resources != nil {
+		return nil
+	}
+	if (fc.action == aCallSlice) != (variadicPos(fc) >= 0 && def.typ.isVariadic()) {
+		return nil
+	}
+
+	// The arguments must be the parameters, in order.
+	args := fc.child[1:]
+	numRet := len(def.typ.ret)
+	if len(args) != len(def.typ.arg) {
+		return nil
+	}
+	for i, a := range args {
+		if a.kind != identExpr || a.sym == nil || a.sym.kind != varSym || a.sym.global || a.level != 0 || a.sym.index != numRet+i {
+			return nil
+		}
+	}
+	return fc
+}
+
+// isBinForward returns true if the binary function called by fc, in the
+// forwarding function def, can be called directly with the arguments of def
+// and return its results, without conversion.
+func isBinForward(fc, def *node) bool {
+	c0 := fc.child[0]
+	if !c0.rval.IsValid() || c0.rval.Kind() != reflect.Func || c0.recv != nil {
+		return false
+	}
+	ft := c0.rval.Type()
+	if ft.NumIn() != len(def.typ.arg) || ft.NumOut() != len(def.typ.ret) || ft.IsVariadic() != def.typ.isVariadic() {
+		return false
+	}
+	plain := func(t *itype, rt reflect.Type) bool {
+		if isInterfaceSrc(t) || isFuncSrc(t) || t.TypeOf() != rt {
+			return false
+		}
+		switch rt.Kind() {
+		case reflect.Interface, reflect.Func:
+			return false
+		case reflect.Slice:
+			if k := rt.Elem().Kind(); k == reflect.Interface || k == reflect.Func {
+				return false
+			}
+		}
+		return true
+	}
+	for i, a := range def.typ.arg {
+		if !plain(a, ft.In(i)) {
+			return false
+		}
+	}
+	for i, r := range def.typ.ret {
+		if !plain(r, ft.Out(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isTailCall returns true if the call n, in the function def, is a call of
+// def to itself whose results are directly returned, and the frame of def
+// can be reused for the new call.
+func isTailCall(n, def *node) bool {
+	if !n.interp.optimize || n.interp.cover != nil || def == nil || n.anc.kind != returnStmt || len(n.anc.child) != 1 {
+		return false
+	}
+	if staticFunc(n.child[0]) != def || def.typ.isVariadic() || n.action != aCall || !noDefer(def) {
+		return false
+	}
+	// The results must be stored in the return values of the frame.
+	return n.findex == 0 && n.level == 0
+}
+
+// noDefer returns true if the body of function def contains no defer
+// statement.
+func noDefer(def *node) bool {
+	ok := true
+	def.child[3].Walk(func(n *node) bool {
+		switch n.kind {
+		case deferStmt:
+			ok = false
+		case funcLit:
+			return false
+		}
+		return ok
+	}, nil)
+	return ok
+}
@@ -0,0 +1,346 @@
+package interp
+
+import (
+	"crypto/sha256"
+	"embed"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io/fs"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// embedIdent is the name of the universe function building the embed.FS
+// values of variables declared with a go:embed directive. It is not a valid
+// identifier, so it can not be referred to by interpreted code.
+const embedIdent = "go:embed"
+
+// embedFile mirrors the file entries of embed.FS, filled by the compiler.
+type embedFile struct {
+	name string
+	data string
+	hash [16]byte
+}
+
+// embedFS mirrors the layout of embed.FS.
+type embedFS struct {
+	files *[]embedFile
+}
+
+// embedLayoutOk is true if embedFS matches the layout of embed.FS.
+var embedLayoutOk = func() bool {
+	ft := reflect.TypeOf(embed.FS{})
+	if ft.NumField() != 1 || ft.Size() != unsafe.Sizeof(embedFS{}) {
+		return false
+	}
+	t := ft.Field(0).Type
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Slice {
+		return false
+	}
+	et, mt := t.Elem().Elem(), reflect.TypeOf(embedFile{})
+	if et.Kind() != reflect.Struct || et.NumField() != mt.NumField() || et.Size() != mt.Size() {
+		return false
+	}
+	for i := 0; i < et.NumField(); i++ {
+		if et.Field(i).Type.String() != mt.Field(i).Type.String() || et.Field(i).Offset != mt.Field(i).Offset {
+			return false
+		}
+	}
+	return true
+}()
+
+// newEmbedFS returns an embed.FS holding the files given by pairs of names
+// and contents. Directories are given with an empty content and a trailing
+// slash, as produced by embedVars.
+func newEmbedFS(files ...string) embed.FS {
+	list := make([]embedFile, 0, len(files)/2)
+	for i := 0; i+1 < len(files); i += 2 {
+		f := embedFile{name: files[i], data: files[i+1]}
+		if !strings.HasSuffix(f.name, "/") {
+			h := sha256.Sum256([]byte(f.data))
+			copy(f.hash[:], h[:])
+		}
+		list = append(list, f)
+	}
+	// Sort files by directory, then by name, as expected by embed.FS.
+	sort.Slice(list, func(i, j int) bool {
+		di, ei := embedSplit(list[i].name)
+		dj, ej := embedSplit(list[j].name)
+		return di < dj || di == dj && ei < ej
+	})
+	return *(*embed.FS)(unsafe.Pointer(&embedFS{files: &list}))
+}
+
+// embedSplit returns the directory and element of an embed.FS file name.
+func embedSplit(name string) (dir, elem string) {
+	name = strings.TrimSuffix(name, "/")
+	i := strings.LastIndexByte(name, '/')
+	if i < 0 {
+		return ".", name
+	}
+	return name[:i], name[i+1:]
+}
+
+// embedVars resolves the go:embed directives of the package level variable
+// declarations of the file f, of the given name, against the source
+// filesystem of the interpreter, and sets the initial values of variables
+// to the embedded contents: string or []byte conversions of a literal, or a
+// call of the embedIdent function for embed.FS variables.
+func (interp *Interpreter) embedVars(f *ast.File, name string, inc bool) error {
+	embedName := ""
+	for _, imp := range f.Imports {
+		if p, _ := strconv.Unquote(imp.Path.Value); p == "embed" {
+			embedName = "embed"
+			if imp.Name != nil {
+				embedName = imp.Name.Name
+			}
+		}
+	}
+
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range d.Specs {
+			vs := spec.(*ast.ValueSpec)
+			doc := vs.Doc
+			if doc == nil && !d.Lparen.IsValid() {
+				doc = d.Doc
+			}
+			patterns, pos, err := embedPatterns(doc)
+			if err != nil {
+				return interp.embedError(pos, err.Error())
+			}
+			if patterns == nil {
+				continue
+			}
+			switch {
+			case embedName == "" && !inc:
+				return interp.embedError(pos, `go:embed only allowed in Go files that import "embed"`)
+			case len(vs.Names) > 1:
+				return interp.embedError(pos, "go:embed cannot apply to multiple vars")
+			case len(vs.Values) > 0:
+				return interp.embedError(pos, "go:embed cannot apply to var with initializer")
+			case vs.Type == nil:
+				return interp.embedError(pos, "go:embed cannot apply to var without type")
+			}
+			files, err := interp.embedMatch(path.Dir(name), patterns)
+			if err != nil {
+				return interp.embedError(pos, err.Error())
+			}
+			vpos := vs.Names[0].Pos()
+
+			if isEmbedFS(vs.Type, embedName) {
+				if !embedLayoutOk {
+					return interp.embedError(pos, "go:embed: embed.FS is not supported with this version of Go")
+				}
+				call := &ast.CallExpr{Fun: &ast.Ident{NamePos: vpos, Name: embedIdent}, Lparen: vpos, Rparen: vpos}
+				for _, file := range files {
+					call.Args = append(call.Args,
+						&ast.BasicLit{ValuePos: vpos, Kind: token.STRING, Value: strconv.Quote(file[0])},
+						&ast.BasicLit{ValuePos: vpos, Kind: token.STRING, Value: strconv.Quote(file[1])})
+				}
+				vs.Values = []ast.Expr{call}
+				continue
+			}
+			if len(files) != 1 || strings.HasSuffix(files[0][0], "/") {
+				return interp.embedError(pos, fmt.Sprintf("invalid go:embed: multiple files for type %s", typeExprString(vs.Type)))
+			}
+			lit := &ast.BasicLit{ValuePos: vpos, Kind: token.STRING, Value: strconv.Quote(files[0][1])}
+			vs.Values = []ast.Expr{&ast.CallExpr{Fun: vs.Type, Lparen: vpos, Args: []ast.Expr{lit}, Rparen: vpos}}
+		}
+	}
+	return nil
+}
+
+// embedError returns a compilation error of a go:embed directive at pos.
+func (interp *Interpreter) embedError(pos token.Pos, msg string) error {
+	return fmt.Errorf("%s: %s", interp.fset.Position(pos), msg)
+}
+
+// isEmbedFS returns true if the type expression t is embed.FS, with the
+// embed package imported under the given name.
+func isEmbedFS(t ast.Expr, embedName string) bool {
+	s, ok := t.(*ast.SelectorExpr)
+	if !ok || s.Sel.Name != "FS" {
+		return false
+	}
+	x, ok := s.X.(*ast.Ident)
+	return ok && x.Name == embedName
+}
+
+// typeExprString returns the source of a simple type expression.
+func typeExprString(t ast.Expr) string {
+	switch t := t.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "[]" + typeExprString(t.Elt)
+		}
+	case *ast.SelectorExpr:
+		return typeExprString(t.X) + "." + t.Sel.Name
+	}
+	return "type"
+}
+
+// embedPatterns returns the patterns of the go:embed directives of the doc
+// comment of a variable, and the position of the first directive.
+func embedPatterns(doc *ast.CommentGroup) (patterns []string, pos token.Pos, err error) {
+	if doc == nil {
+		return nil, token.NoPos, nil
+	}
+	for _, c := range doc.List {
+		args, ok := strings.CutPrefix(c.Text, "//go:embed")
+		if !ok || args != "" && args[0] != ' ' && args[0] != '\t' {
+			continue
+		}
+		if !pos.IsValid() {
+			pos = c.Pos()
+		}
+		p, err := parseEmbedArgs(args)
+		if err != nil {
+			return nil, c.Pos(), err
+		}
+		if len(p) == 0 {
+			return nil, c.Pos(), errors.New("usage: //go:embed pattern...")
+		}
+		patterns = append(patterns, p...)
+	}
+	return patterns, pos, nil
+}
+
+// parseEmbedArgs splits the arguments of a go:embed directive, which are
+// separated by spaces and may be quoted as Go strings.
+func parseEmbedArgs(args string) ([]string, error) {
+	var res []string
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		var arg string
+		switch args[0] {
+		case '"', '`':
+			q, err := strconv.QuotedPrefix(args)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+			arg, _ = strconv.Unquote(q)
+			args = args[len(q):]
+			if args != "" && args[0] != ' ' && args[0] != '\t' {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", q+args)
+			}
+		default:
+			i := strings.IndexAny(args, " \t")
+			if i < 0 {
+				i = len(args)
+			}
+			arg, args = args[:i], args[i:]
+		}
+		res = append(res, arg)
+	}
+	return res, nil
+}
+
+// embedMatch returns the files matching the go:embed patterns in the source
+// directory dir, as pairs of names relative to dir and contents, sorted by
+// name. Directories are returned with a trailing slash and an empty content.
+func (interp *Interpreter) embedMatch(dir string, patterns []string) ([][2]string, error) {
+	fsys := interp.opt.filesystem
+	files := map[string]string{}
+	addDirs := func(name string) {
+		for d := path.Dir(name); d != "."; d = path.Dir(d) {
+			files[d+"/"] = ""
+		}
+	}
+	addFile := func(name string) error {
+		b, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		files[name] = string(b)
+		addDirs(name)
+		return nil
+	}
+
+	for _, pattern := range patterns {
+		all := false
+		if p, ok := strings.CutPrefix(pattern, "all:"); ok {
+			pattern, all = p, true
+		}
+		if _, err := path.Match(pattern, ""); err != nil || !validEmbedPattern(pattern) {
+			return nil, fmt.Errorf("pattern %s: invalid pattern syntax", pattern)
+		}
+		matches, err := fs.Glob(fsys, path.Join(dir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("pattern %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("pattern %s: no matching files found", pattern)
+		}
+		for _, m := range matches {
+			name := m
+			if dir != "." {
+				name = strings.TrimPrefix(m, dir+"/")
+			}
+			info, err := fs.Stat(fsys, m)
+			if err != nil {
+				return nil, fmt.Errorf("pattern %s: %w", pattern, err)
+			}
+			if !info.IsDir() {
+				if err := addFile(name); err != nil {
+					return nil, fmt.Errorf("pattern %s: %w", pattern, err)
+				}
+				continue
+			}
+			n := len(files)
+			err = fs.WalkDir(fsys, m, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				base := path.Base(p)
+				if p != m && !all && (strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_")) {
+					if d.IsDir() {
+						return fs.SkipDir
+					}
+					return nil
+				}
+				if d.IsDir() || !d.Type().IsRegular() {
+					return nil
+				}
+				return addFile(name + strings.TrimPrefix(p, m))
+			})
+			if err != nil {
+				return nil, fmt.Errorf("pattern %s: %w", pattern, err)
+			}
+			if len(files) == n {
+				return nil, fmt.Errorf("pattern %s: cannot embed directory %s: contains no embeddable files", pattern, name)
+			}
+		}
+	}
+
+	res := make([][2]string, 0, len(files))
+	for name, data := range files {
+		res = append(res, [2]string{name, data})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i][0] < res[j][0] })
+	return res, nil
+}
+
+// validEmbedPattern returns true if pattern is a valid go:embed pattern: a
+// relative slash separated path without "." or ".." elements.
+func validEmbedPattern(pattern string) bool {
+	if pattern == "" || strings.HasPrefix(pattern, "/") || strings.HasSuffix(pattern, "/") {
+		return false
+	}
+	for _, elem := range strings.Split(pattern, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package interp
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// environ is the virtual environment of an interpreter, which backs the
+// environment functions of the os package for interpreted code. It is safe
+// for concurrent use.
+type environ struct {
+	mutex sync.RWMutex
+	vars  map[string]string
+}
+
+// newEnviron returns an environment initialized from entries in the form
+// "key=value", as returned by os.Environ.
+func newEnviron(entries []string) *environ {
+	e := &environ{vars: map[string]string{}}
+	for _, s := range entries {
+		k, v, _ := strings.Cut(s, "=")
+		e.vars[k] = v
+	}
+	return e
+}
+
+func (e *environ) lookup(key string) (string, bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	v, ok := e.vars[key]
+	return v, ok
+}
+
+func (e *environ) get(key string) string {
+	v, _ := e.lookup(key)
+	return v
+}
+
+// set sets the value of key, with the same validation of key as os.Setenv.
+func (e *environ) set(key, value string) error {
+	if key == "" || strings.ContainsAny(key, "=\x00") || strings.ContainsRune(value, 0) {
+		return os.NewSyscallError("setenv", syscall.EINVAL)
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.vars[key] = value
+	return nil
+}
+
+func (e *environ) unset(key string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	delete(e.vars, key)
+}
+
+func (e *environ) clear() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.vars = map[string]string{}
+}
+
+// list returns the sorted entries of the environment, in the form
+// "key=value".
+func (e *environ) list() []string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	a := make([]string, 0, len(e.vars))
+	for k, v := range e.vars {
+		a = append(a, k+"="+v)
+	}
+	sort.Strings(a)
+	return a
+}
@@ -0,0 +1,247 @@
+package interp
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Wrappers of error, for the interpreted error types which also implement
+// the optional methods used by the errors package: Unwrap, returning one or
+// several errors, Is and As. They are selected by getWrapper, from mapTypes.
+
+type _errorUnwrap struct {
+	IValue  interface{}
+	WError  func() string
+	WUnwrap func() error
+}
+
+func (w _errorUnwrap) Error() string { return w.WError() }
+func (w _errorUnwrap) Unwrap() error { return w.WUnwrap() }
+
+type _errorUnwrapIsAs struct {
+	IValue  interface{}
+	WAs     func(target interface{}) bool
+	WError  func() string
+	WIs     func(target error) bool
+	WUnwrap func() error
+}
+
+func (w _errorUnwrapIsAs) As(target interface{}) bool { return w.WAs(target) }
+func (w _errorUnwrapIsAs) Error() string              { return w.WError() }
+func (w _errorUnwrapIsAs) Is(target error) bool       { return w.WIs(target) }
+func (w _errorUnwrapIsAs) Unwrap() error              { return w.WUnwrap() }
+
+type _errorUnwrapIs struct {
+	IValue  interface{}
+	WError  func() string
+	WIs     func(target error) bool
+	WUnwrap func() error
+}
+
+func (w _errorUnwrapIs) Error() string        { return w.WError() }
+func (w _errorUnwrapIs) Is(target error) bool { return w.WIs(target) }
+func (w _errorUnwrapIs) Unwrap() error        { return w.WUnwrap() }
+
+type _errorUnwrapAs struct {
+	IValue  interface{}
+	WAs     func(target interface{}) bool
+	WError  func() string
+	WUnwrap func() error
+}
+
+func (w _errorUnwrapAs) As(target interface{}) bool { return w.WAs(target) }
+func (w _errorUnwrapAs) Error() string              { return w.WError() }
+func (w _errorUnwrapAs) Unwrap() error              { return w.WUnwrap() }
+
+type _errorUnwrapAll struct {
+	IValue  interface{}
+	WError  func() string
+	WUnwrap func() []error
+}
+
+func (w _errorUnwrapAll) Error() string   { return w.WError() }
+func (w _errorUnwrapAll) Unwrap() []error { return w.WUnwrap() }
+
+type _errorUnwrapAllIsAs struct {
+	IValue  interface{}
+	WAs     func(target interface{}) bool
+	WError  func() string
+	WIs     func(target error) bool
+	WUnwrap func() []error
+}
+
+func (w _errorUnwrapAllIsAs) As(target interface{}) bool { return w.WAs(target) }
+func (w _errorUnwrapAllIsAs) Error() string              { return w.WError() }
+func (w _errorUnwrapAllIsAs) Is(target error) bool       { return w.WIs(target) }
+func (w _errorUnwrapAllIsAs) Unwrap() []error            { return w.WUnwrap() }
+
+type _errorUnwrapAllIs struct {
+	IValue  interface{}
+	WError  func() string
+	WIs     func(target error) bool
+	WUnwrap func() []error
+}
+
+func (w _errorUnwrapAllIs) Error() string        { return w.WError() }
+func (w _errorUnwrapAllIs) Is(target error) bool { return w.WIs(target) }
+func (w _errorUnwrapAllIs) Unwrap() []error      { return w.WUnwrap() }
+
+type _errorUnwrapAllAs struct {
+	IValue  interface{}
+	WAs     func(target interface{}) bool
+	WError  func() string
+	WUnwrap func() []error
+}
+
+func (w _errorUnwrapAllAs) As(target interface{}) bool { return w.WAs(target) }
+func (w _errorUnwrapAllAs) Error() string              { return w.WError() }
+func (w _errorUnwrapAllAs) Unwrap() []error            { return w.WUnwrap() }
+
+type _errorIsAs struct {
+	IValue interface{}
+	WAs    func(target interface{}) bool
+	WError func() string
+	WIs    func(target error) bool
+}
+
+func (w _errorIsAs) As(target interface{}) bool { return w.WAs(target) }
+func (w _errorIsAs) Error() string              { return w.WError() }
+func (w _errorIsAs) Is(target error) bool       { return w.WIs(target) }
+
+type _errorIs struct {
+	IValue interface{}
+	WError func() string
+	WIs    func(target error) bool
+}
+
+func (w _errorIs) Error() string        { return w.WError() }
+func (w _errorIs) Is(target error) bool { return w.WIs(target) }
+
+type _errorAs struct {
+	IValue interface{}
+	WAs    func(target interface{}) bool
+	WError func() string
+}
+
+func (w _errorAs) As(target interface{}) bool { return w.WAs(target) }
+func (w _errorAs) Error() string              { return w.WError() }
+
+// errorWrappers are the composed wrappers of error, sorted by complexity.
+var errorWrappers = []reflect.Type{
+	reflect.TypeOf((*_errorUnwrapIsAs)(nil)).Elem(),
+	reflect.TypeOf((*_errorUnwrapAllIsAs)(nil)).Elem(),
+	reflect.TypeOf((*_errorUnwrapIs)(nil)).Elem(),
+	reflect.TypeOf((*_errorUnwrapAs)(nil)).Elem(),
+	reflect.TypeOf((*_errorUnwrapAllIs)(nil)).Elem(),
+	reflect.TypeOf((*_errorUnwrapAllAs)(nil)).Elem(),
+	reflect.TypeOf((*_errorIsAs)(nil)).Elem(),
+	reflect.TypeOf((*_errorUnwrap)(nil)).Elem(),
+	reflect.TypeOf((*_errorUnwrapAll)(nil)).Elem(),
+	reflect.TypeOf((*_errorIs)(nil)).Elem(),
+	reflect.TypeOf((*_errorAs)(nil)).Elem(),
+}
+
+// wrappedValue returns the interpreted value held by the interface wrapper
+// err, or an invalid value if err is not a wrapper.
+func wrappedValue(err error) reflect.Value {
+	v := reflect.ValueOf(err)
+	if t := v.Type(); t.Kind() != reflect.Struct || t.NumField() == 0 || t.Field(0).Name != "IValue" {
+		return reflect.Value{}
+	}
+	return v.Field(0).Elem()
+}
+
+// errorsIs reports whether any error in the tree of err matches target, as
+// errors.Is, where errors wrapping the same interpreted value match.
+func errorsIs(err, target error) bool {
+	if err == nil || target == nil {
+		return err == target
+	}
+	tv := wrappedValue(target)
+	if !tv.IsValid() || !tv.Type().Comparable() {
+		return errors.Is(err, target)
+	}
+	return isValue(err, target, tv.Interface())
+}
+
+func isValue(err, target error, tv interface{}) bool {
+	for {
+		if v := wrappedValue(err); v.IsValid() && v.Type().Comparable() && v.Interface() == tv {
+			return true
+		}
+		if x, ok := err.(interface{ Is(error) bool }); ok && x.Is(target) {
+			return true
+		}
+		switch x := err.(type) {
+		case interface{ Unwrap() error }:
+			if err = x.Unwrap(); err == nil {
+				return false
+			}
+		case interface{ Unwrap() []error }:
+			for _, err := range x.Unwrap() {
+				if err != nil && isValue(err, target, tv) {
+					return true
+				}
+			}
+			return false
+		default:
+			return false
+		}
+	}
+}
+
+// errorsAs finds the first error in the tree of err that matches target, as
+// errors.As, where target may also point to a value of an interpreted type,
+// which matches the errors wrapping a value of this type.
+func errorsAs(err error, target interface{}) bool {
+	if err == nil {
+		return false
+	}
+	val := reflect.ValueOf(target)
+	if target == nil || val.Kind() != reflect.Ptr || val.IsNil() {
+		panic("errors: target must be a non-nil pointer")
+	}
+	if t := val.Type().Elem(); t.Kind() == reflect.Interface || t.Implements(errorType) {
+		return errors.As(err, target)
+	}
+	return asValue(err, target, val.Elem())
+}
+
+func asValue(err error, target interface{}, dest reflect.Value) bool {
+	for {
+		if v := wrappedValue(err); v.IsValid() && v.Type().AssignableTo(dest.Type()) {
+			dest.Set(v)
+			return true
+		}
+		if x, ok := err.(interface{ As(interface{}) bool }); ok && x.As(target) {
+			return true
+		}
+		switch x := err.(type) {
+		case interface{ Unwrap() error }:
+			if err = x.Unwrap(); err == nil {
+				return false
+			}
+		case interface{ Unwrap() []error }:
+			for _, err := range x.Unwrap() {
+				if err != nil && asValue(err, target, dest) {
+					return true
+				}
+			}
+			return false
+		default:
+			return false
+		}
+	}
+}
+
+// fixErrors redefines the Is and As functions of the errors package to
+// match the errors of interpreted types, which are wrapped into binary
+// values when stored in errors.
+func fixErrors(interp *Interpreter) {
+	p := interp.binPkg["errors"]
+	if p == nil {
+		return
+	}
+	p["Is"] = reflect.ValueOf(errorsIs)
+	p["As"] = reflect.ValueOf(errorsAs)
+}
@@ -0,0 +1,190 @@
+package interp
+
+import (
+	"reflect"
+	"sync"
+)
+
+// A framePool provides reusable frames for the calls of a function whose
+// frame can not be referenced once the call returns, saving the allocation
+// of the frame, of its data slice and of its local values of scalar types at
+// each call.
+type framePool struct {
+	index []int     // frame indexes of reusable values
+	pool  sync.Pool // of *pooledFrame
+}
+
+// A pooledFrame is a frame provided by a framePool, with the storage of its
+// reusable values, which may be replaced in the frame during the call.
+type pooledFrame struct {
+	frame  *frame
+	values []reflect.Value // values at index
+}
+
+// newFramePool returns a framePool for the function defined at node def, or
+// nil if its frame can not be reused.
+//
+// The analysis is conservative: frames are reused only for functions which
+// contain no closure, goroutine, defer, address operator, interface or
+// method, which are the ways for a frame value to be referenced after the
+// call. Among frame values, only the ones of local variables and
+// temporaries of scalar types are reused, the others are allocated again.
+func newFramePool(def *node) *framePool {
+	if !noEscape(def) {
+		return nil
+	}
+	types := def.types
+	var index []int
+	for i := len(def.typ.ret); i < len(types); i++ {
+		if isScalarKind(types[i]) {
+			index = append(index, i)
+		}
+	}
+
+	fp := &framePool{index: index}
+	fp.pool.New = func() interface{} {
+		pf := &pooledFrame{frame: &frame{data: make([]reflect.Value, len(types))}, values: make([]reflect.Value, len(index))}
+		for k, i := range index {
+			pf.values[k] = reflect.New(types[i]).Elem()
+		}
+		return pf
+	}
+	return fp
+}
+
+// get returns a frame for a call from frame anc, as newFrame does, to be
+// released by put once the call has returned. Its reusable values are set
+// to zero, the other ones are invalid.
+func (fp *framePool) get(anc *frame, id uint64) *pooledFrame {
+	pf := fp.pool.Get().(*pooledFrame)
+	f := pf.frame
+	f.anc, f.root, f.id, f.run = anc, anc.root, id, anc.run
+	for k, i := range fp.index {
+		v := pf.values[k]
+		v.SetZero()
+		f.data[i] = v
+	}
+	return pf
+}
+
+// put releases the frame obtained by get. Its values are cleared, to not
+// retain them.
+func (fp *framePool) put(pf *pooledFrame) {
+	f := pf.frame
+	clear(f.data)
+	f.anc, f.root, f.src, f.run = nil, nil, nil, nil
+	f.debug, f.trace, f.depth, f.call, f.prof = nil, nil, 0, nil, nil
+	f.deferred, f.recovered = nil, nil
+	fp.pool.Put(pf)
+}
+
+// noEscape returns true if no value of the frame of function def can be
+// referenced once the function returns.
+func noEscape(def *node) bool {
+	if def.kind != funcDecl || len(def.child) < 4 || def.typ == nil || def.typ.cat != funcT {
+		return false
+	}
+	seen := map[*itype]bool{}
+	ok := !mayEscapeType(def.typ, seen)
+	def.child[3].Walk(func(n *node) bool {
+		if !ok {
+			return false
+		}
+		switch n.kind {
+		case funcLit, goStmt, deferStmt, addressExpr:
+			ok = false
+		default:
+			ok = n.typ == nil || !mayEscapeType(n.typ, seen)
+		}
+		return ok
+	}, nil)
+	return ok
+}
+
+// mayEscapeType returns true if a value of type t, or of a type composing t,
+// may lead to reference a frame value, i.e. it is an interface or it has
+// methods.
+func mayEscapeType(t *itype, seen map[*itype]bool) bool {
+	if t == nil || seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	if len(t.method) > 0 || isInterface(t) {
+		return true
+	}
+	switch t.cat {
+	case valueT:
+		return t.rtype != nil && mayEscapeRtype(t.rtype, map[reflect.Type]bool{})
+	case funcT:
+		for _, a := range t.arg {
+			if mayEscapeType(a, seen) {
+				return true
+			}
+		}
+		for _, r := range t.ret {
+			if mayEscapeType(r, seen) {
+				return true
+			}
+		}
+	case structT:
+		for _, f := range t.field {
+			if mayEscapeType(f.typ, seen) {
+				return true
+			}
+		}
+	case mapT:
+		return mayEscapeType(t.key, seen) || mayEscapeType(t.val, seen)
+	}
+	return t.val != nil && mayEscapeType(t.val, seen)
+}
+
+// mayEscapeRtype is the equivalent of mayEscapeType for runtime types.
+func mayEscapeRtype(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	if t.NumMethod() > 0 {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Func:
+		for i := 0; i < t.NumIn(); i++ {
+			if mayEscapeRtype(t.In(i), seen) {
+				return true
+			}
+		}
+		for i := 0; i < t.NumOut(); i++ {
+			if mayEscapeRtype(t.Out(i), seen) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if mayEscapeRtype(t.Field(i).Type, seen) {
+				return true
+			}
+		}
+	case reflect.Map:
+		return mayEscapeRtype(t.Key(), seen) || mayEscapeRtype(t.Elem(), seen)
+	case reflect.Array, reflect.Chan, reflect.Ptr, reflect.Slice:
+		return mayEscapeRtype(t.Elem(), seen)
+	}
+	return false
+}
+
+// isScalarKind returns true if t is a boolean, numeric or string type.
+func isScalarKind(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
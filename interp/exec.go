@@ -0,0 +1,192 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os/exec"
+	"reflect"
+	"strings"
+)
+
+// ErrExecDenied is the error of the commands denied by an ExecPolicy.
+var ErrExecDenied = errors.New("denied by exec policy")
+
+// errExecModified is the error of commands whose path or arguments have been
+// modified after their approval by the exec policy.
+var errExecModified = errors.New("command modified after approval by exec policy")
+
+// An ExecRequest is a command created by interpreted code with the Command
+// or CommandContext functions of the os/exec package.
+type ExecRequest struct {
+	Name string   // name or path of the program
+	Args []string // arguments, not including the program name
+}
+
+func (r *ExecRequest) String() string {
+	return strings.Join(append([]string{r.Name}, r.Args...), " ")
+}
+
+// An ExecPolicy brokers the commands run by interpreted code. When it is set
+// in Options, the os/exec package is available to interpreted code, even in
+// restricted mode, and each command is submitted to the policy at its
+// creation.
+type ExecPolicy interface {
+	// Check allows the command req by returning nil, possibly after having
+	// rewritten its name and arguments, or denies it by returning an error,
+	// e.g. ErrExecDenied. The start of a denied command fails with this error.
+	Check(req *ExecRequest) error
+}
+
+// ExecPolicyFunc is a function used as ExecPolicy.
+type ExecPolicyFunc func(req *ExecRequest) error
+
+// Check returns f(req).
+func (f ExecPolicyFunc) Check(req *ExecRequest) error { return f(req) }
+
+// An ExecAllowlist is an ExecPolicy which allows the commands of the programs
+// listed in Allow, by name or path as given to exec.Command, and denies the
+// others with ErrExecDenied.
+type ExecAllowlist struct {
+	Allow []string
+
+	// Log, if not nil, receives the decision of each checked command.
+	Log *log.Logger
+}
+
+// Check allows req if its program is in the allowlist.
+func (a ExecAllowlist) Check(req *ExecRequest) error {
+	err := ErrExecDenied
+	for _, name := range a.Allow {
+		if name == req.Name {
+			err = nil
+			break
+		}
+	}
+	if a.Log != nil {
+		if err != nil {
+			a.Log.Printf("exec denied: %s", req)
+		} else {
+			a.Log.Printf("exec allowed: %s", req)
+		}
+	}
+	return err
+}
+
+// execCmd replaces the exec.Cmd type for interpreted code under an exec
+// policy. The commands are started only if created by Command or
+// CommandContext, and not modified since their approval.
+type execCmd struct {
+	*exec.Cmd
+	path string   // approved path
+	args []string // approved arguments
+}
+
+func (c *execCmd) approved() error {
+	if c.Cmd == nil {
+		return errors.New("exec: Cmd not created by Command")
+	}
+	if c.Cmd.Path != c.path || !equalStrings(c.Cmd.Args, c.args) {
+		return &exec.Error{Name: c.Cmd.Path, Err: errExecModified}
+	}
+	return nil
+}
+
+// Start starts the command, as exec.Cmd.Start, if it is approved.
+func (c *execCmd) Start() error {
+	if err := c.approved(); err != nil {
+		return err
+	}
+	return c.Cmd.Start()
+}
+
+// Run runs the command, as exec.Cmd.Run, if it is approved.
+func (c *execCmd) Run() error {
+	if err := c.approved(); err != nil {
+		return err
+	}
+	return c.Cmd.Run()
+}
+
+// Output runs the command, as exec.Cmd.Output, if it is approved.
+func (c *execCmd) Output() ([]byte, error) {
+	if err := c.approved(); err != nil {
+		return nil, err
+	}
+	return c.Cmd.Output()
+}
+
+// CombinedOutput runs the command, as exec.Cmd.CombinedOutput, if it is
+// approved.
+func (c *execCmd) CombinedOutput() ([]byte, error) {
+	if err := c.approved(); err != nil {
+		return nil, err
+	}
+	return c.Cmd.CombinedOutput()
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// command returns the command of name and args, as exec.CommandContext, or
+// exec.Command if ctx is nil, after submission to the exec policy of the
+// interpreter.
+func (interp *Interpreter) command(ctx context.Context, name string, args ...string) *execCmd {
+	req := &ExecRequest{Name: name, Args: append([]string{}, args...)}
+	if err := interp.execPolicy.Check(req); err != nil {
+		cmd := &exec.Cmd{Path: name, Args: append([]string{name}, args...), Err: &exec.Error{Name: name, Err: err}}
+		return &execCmd{Cmd: cmd, path: cmd.Path, args: cmd.Args}
+	}
+	var cmd *exec.Cmd
+	if ctx == nil {
+		cmd = exec.Command(req.Name, req.Args...)
+	} else {
+		cmd = exec.CommandContext(ctx, req.Name, req.Args...)
+	}
+	// Commands inherit the virtual environment and working directory of
+	// interpreted code.
+	if interp.env != nil {
+		cmd.Env = interp.env.list()
+	}
+	if interp.wd != nil {
+		cmd.Dir = interp.wd.get()
+	}
+	return &execCmd{Cmd: cmd, path: cmd.Path, args: append([]string{}, cmd.Args...)}
+}
+
+// fixExec redefines the os/exec symbols of the interpreter to broker the
+// commands of interpreted code through the exec policy. It replaces the
+// unrestricted symbols, whatever the order in which they are loaded.
+func fixExec(interp *Interpreter) {
+	p := interp.binPkg["os/exec"]
+	if p == nil {
+		p = map[string]reflect.Value{}
+		interp.binPkg["os/exec"] = p
+		interp.pkgNames["os/exec"] = "exec"
+	}
+	p["Command"] = reflect.ValueOf(func(name string, args ...string) *execCmd {
+		return interp.command(nil, name, args...)
+	})
+	p["CommandContext"] = reflect.ValueOf(func(ctx context.Context, name string, args ...string) *execCmd {
+		if ctx == nil {
+			panic("nil Context")
+		}
+		return interp.command(ctx, name, args...)
+	})
+	p["Cmd"] = reflect.ValueOf((*execCmd)(nil))
+	p["Error"] = reflect.ValueOf((*exec.Error)(nil))
+	p["ExitError"] = reflect.ValueOf((*exec.ExitError)(nil))
+	p["ErrDot"] = reflect.ValueOf(&exec.ErrDot).Elem()
+	p["ErrNotFound"] = reflect.ValueOf(&exec.ErrNotFound).Elem()
+	p["ErrWaitDelay"] = reflect.ValueOf(&exec.ErrWaitDelay).Elem()
+	p["LookPath"] = reflect.ValueOf(exec.LookPath)
+}
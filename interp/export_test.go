@@ -1,5 +1,7 @@
 package interp
 
+import "time"
+
 func (interp *Interpreter) Scopes() map[string]map[string]struct{} {
 	scopes := make(map[string]map[string]struct{})
 	for k, v := range interp.scopes {
@@ -15,3 +17,5 @@ func (interp *Interpreter) Scopes() map[string]map[string]struct{} {
 func (interp *Interpreter) Packages() map[string]string {
 	return interp.pkgNames
 }
+
+func SetWatchInterval(d time.Duration) { watchInterval = d }
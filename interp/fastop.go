@@ -0,0 +1,117 @@
+package interp
+
+import "reflect"
+
+// Fast operations apply to arithmetic operations and comparisons on signed
+// integer or float64 values held in the local frame. Their operands are read
+// and their result written directly in the frame, without the generic value
+// accessors. The specialized closures are produced by the *Fast functions
+// generated in op.go, which use the helpers below.
+
+// fastClass returns reflect.Int if t is a signed integer type,
+// reflect.Float64 if t is float64, or reflect.Invalid otherwise.
+func fastClass(t reflect.Type) reflect.Kind {
+	if t == nil {
+		return reflect.Invalid
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.Int
+	case reflect.Float64:
+		return reflect.Float64
+	}
+	return reflect.Invalid
+}
+
+// localIndex returns the index of the value of n in the local frame, or -1
+// if n is a constant. It returns false if the value of n is located
+// elsewhere. It follows the same resolution as genValue.
+func localIndex(n *node) (int, bool) {
+	switch {
+	case n.kind == basicLit || n.rval.IsValid():
+		return -1, n.rval.IsValid()
+	case n.kind == funcDecl:
+		return 0, false
+	case n.sym != nil:
+		i := n.sym.index
+		if i < 0 && n != n.sym.node {
+			return localIndex(n.sym.node)
+		}
+		if n.sym.global || n.level != 0 || i < 0 {
+			return 0, false
+		}
+		return i, true
+	case n.findex < 0 || n.level != 0:
+		return 0, false
+	}
+	return n.findex, true
+}
+
+// localOperand returns the index in local frame of the operand n of a fast
+// operation of class k, or -1 if n is a constant.
+func localOperand(n *node, k reflect.Kind) (int, bool) {
+	i, ok := localIndex(n)
+	if !ok || (i >= 0 && fastClass(n.typ.TypeOf()) != k) {
+		return 0, false
+	}
+	return i, true
+}
+
+// isOutputWrapped returns true if the result of operation n is wrapped in
+// an interface value by genValueOutput.
+func isOutputWrapped(n *node) bool {
+	switch {
+	case n.anc.action == aAssign && n.anc.typ.cat == interfaceT:
+		return true
+	case n.anc.kind == returnStmt:
+		def, ok := n.anc.val.(*node)
+		return !ok || def.typ.ret[0].cat == interfaceT
+	}
+	return false
+}
+
+// fastBinary returns the class of the arithmetic or comparison operation n,
+// the index of its result in the local frame, and the indexes of its
+// operands (-1 for a constant). The class is reflect.Invalid if n does not
+// qualify for a fast operation.
+func fastBinary(n *node) (k reflect.Kind, d, i0, i1 int) {
+	c0, c1 := n.child[0], n.child[1]
+	if isOutputWrapped(n) || n.typ.TypeOf().Kind() == reflect.Interface || n.level != 0 || n.findex < 0 {
+		return reflect.Invalid, 0, 0, 0
+	}
+	switch n.action {
+	case aEqual, aNotEqual, aGreater, aGreaterEqual, aLower, aLowerEqual:
+		if k = fastClass(c0.typ.TypeOf()); c0.rval.IsValid() {
+			k = fastClass(c1.typ.TypeOf())
+		}
+	default:
+		k = fastClass(n.typ.TypeOf())
+	}
+	var ok0, ok1 bool
+	i0, ok0 = localOperand(c0, k)
+	i1, ok1 = localOperand(c1, k)
+	if k == reflect.Invalid || !ok0 || !ok1 || (i0 < 0 && i1 < 0) {
+		return reflect.Invalid, 0, 0, 0
+	}
+	return k, n.findex, i0, i1
+}
+
+// fastAssign returns the class of the assign operation n, of kind incDecStmt
+// or assignStmt, the index of its destination in the local frame, and the
+// index of its source operand, if any (-1 for a constant). The class is
+// reflect.Invalid if n does not qualify for a fast operation.
+func fastAssign(n *node) (k reflect.Kind, d, i1 int) {
+	c0 := n.child[0]
+	k = fastClass(c0.typ.TypeOf())
+	d, ok := localOperand(c0, k)
+	if k == reflect.Invalid || !ok || d < 0 || isMapEntry(c0) {
+		return reflect.Invalid, 0, 0
+	}
+	if len(n.child) < 2 {
+		return k, d, 0
+	}
+	if i1, ok = localOperand(n.child[1], k); !ok {
+		return reflect.Invalid, 0, 0
+	}
+	return k, d, i1
+}
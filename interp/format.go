@@ -0,0 +1,198 @@
+package interp
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Format formats the Go source src, a file or a snippet of declarations or
+// statements as accepted by Eval, in the canonical gofmt style, and adds
+// imports for the packages it refers to without importing them, among the
+// binary and source packages known by the interpreter. A package is only
+// imported if it provides all the symbols used by src.
+//
+// As imports cannot be mixed with statements in a snippet, the import
+// declaration added to a snippet of statements precedes them and must be
+// evaluated separately, as the REPL does line by line. With the AutoFormat
+// option, Eval does it automatically.
+func (interp *Interpreter) Format(src string) (string, error) {
+	formatted, imports, err := interp.format(src)
+	if err != nil || imports == "" {
+		return formatted, err
+	}
+	return imports + "\n" + formatted, nil
+}
+
+// format formats src and adds the missing imports. The imports of a snippet
+// of statements are returned apart.
+func (interp *Interpreter) format(src string) (formatted, imports string, err error) {
+	fset := token.NewFileSet()
+	f, tok, err := parseSnippet(fset, src)
+	if err != nil {
+		return "", "", err
+	}
+
+	paths := interp.missingImports(f)
+	if len(paths) == 0 {
+		b, err := format.Source([]byte(src))
+		return string(b), "", err
+	}
+	var specs strings.Builder
+	for _, p := range paths {
+		specs.WriteString(strconv.Quote(p) + "\n")
+	}
+
+	if tok == token.PACKAGE {
+		// Add the imports to the first grouped import declaration, or to a
+		// new declaration after the package clause.
+		off, add := fset.Position(f.Name.End()).Offset, "\n\nimport (\n"+specs.String()+")\n"
+		for _, d := range f.Decls {
+			if g, ok := d.(*ast.GenDecl); ok && g.Tok == token.IMPORT && g.Lparen.IsValid() {
+				off, add = fset.Position(g.Lparen).Offset+1, "\n"+strings.ReplaceAll(specs.String(), "\n", ";")
+				break
+			}
+		}
+		b, err := format.Source([]byte(src[:off] + add + src[off:]))
+		return string(b), "", err
+	}
+
+	b, err := format.Source([]byte(src))
+	if err != nil {
+		return "", "", err
+	}
+	imports = "import (\n" + specs.String() + ")\n"
+	if i, err := format.Source([]byte(imports)); err == nil {
+		imports = string(i)
+	}
+	if tok != token.ILLEGAL {
+		return imports + "\n" + string(b), "", nil
+	}
+	return string(b), imports, nil
+}
+
+// parseSnippet parses src, a file or a snippet of declarations or
+// statements. It returns the first token of src, or token.ILLEGAL for
+// statements.
+func parseSnippet(fset *token.FileSet, src string) (*ast.File, token.Token, error) {
+	var s scanner.Scanner
+	s.Init(fset.AddFile("", -1, len(src)), []byte(src), nil, 0)
+	_, tok, _ := s.Scan()
+
+	file := src
+	switch tok {
+	case token.PACKAGE:
+	case token.CONST, token.FUNC, token.IMPORT, token.TYPE, token.VAR:
+		file = "package main;" + src
+	default:
+		tok = token.ILLEGAL // statements
+		file = wrapInMain(src)
+	}
+	f, err := parser.ParseFile(fset, "", file, parser.ParseComments)
+	if err != nil && tok == token.FUNC {
+		// Retry as a function literal statement.
+		tok, file = token.ILLEGAL, wrapInMain(src)
+		f, err = parser.ParseFile(fset, "", file, parser.ParseComments)
+	}
+	return f, tok, err
+}
+
+// autoImports returns the import declaration of the packages used by the
+// snippet src without being imported, or an empty string.
+func (interp *Interpreter) autoImports(src string) string {
+	f, tok, err := parseSnippet(token.NewFileSet(), src)
+	if err != nil || tok == token.PACKAGE {
+		return ""
+	}
+	paths := interp.missingImports(f)
+	if len(paths) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("import (\n")
+	for _, p := range paths {
+		b.WriteString(strconv.Quote(p) + "\n")
+	}
+	b.WriteString(")\n")
+	return b.String()
+}
+
+// missingImports returns the sorted import paths of the packages used in f
+// without being imported, either by f or, in the main package, by previous
+// evaluations.
+func (interp *Interpreter) missingImports(f *ast.File) []string {
+	interp.mutex.RLock()
+	defer interp.mutex.RUnlock()
+
+	imported := map[string]bool{}
+	for _, spec := range f.Imports {
+		p, _ := strconv.Unquote(spec.Path.Value)
+		if spec.Name != nil {
+			imported[spec.Name.Name] = true
+		} else if name, ok := interp.pkgNames[p]; ok {
+			imported[name] = true
+		} else {
+			imported[path.Base(p)] = true
+		}
+	}
+
+	// Selectors of unresolved identifiers, indexed by identifier.
+	used := map[string][]string{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil && !imported[id.Name] {
+				used[id.Name] = append(used[id.Name], sel.Sel.Name)
+			}
+		}
+		return true
+	})
+
+	var paths []string
+	for name, sels := range used {
+		if sc := interp.scopes[mainID]; sc != nil && f.Name.Name == mainID {
+			if _, ok := sc.sym[name]; ok {
+				continue // global of previous evaluations
+			}
+			if _, ok := sc.sym[path.Join(name, DefaultSourceName)]; ok {
+				continue // imported by previous evaluations
+			}
+		}
+		var candidates []string
+		for p, n := range interp.pkgNames {
+			if n == name && interp.provides(p, sels) {
+				candidates = append(candidates, p)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		// Prefer the shortest import path, as goimports does.
+		sort.Slice(candidates, func(i, j int) bool {
+			a, b := candidates[i], candidates[j]
+			return len(a) < len(b) || len(a) == len(b) && a < b
+		})
+		paths = append(paths, candidates[0])
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// provides returns true if the package importPath exports all symbols.
+func (interp *Interpreter) provides(importPath string, symbols []string) bool {
+	for _, s := range symbols {
+		if _, ok := interp.binPkg[importPath][s]; ok {
+			continue
+		}
+		if _, ok := interp.srcPkg[importPath][s]; ok {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package interp
+
+import "fmt"
+
+// Freeze freezes the source package importPath, e.g. "main" for the symbols
+// declared by Eval: its current package level symbols can no longer be
+// redeclared by subsequent evaluations, which fail with an error instead.
+// New symbols can still be declared in the package, but not methods on its
+// frozen types. Freeze protects long-lived interpreters, e.g. REPL services,
+// from an accidental redefinition of library functions by user input.
+// An error is returned if the package has not been compiled.
+func (interp *Interpreter) Freeze(importPath string) error {
+	interp.mutex.Lock()
+	defer interp.mutex.Unlock()
+
+	sc := interp.scopes[importPath]
+	if sc == nil {
+		return fmt.Errorf("package not found: %s", importPath)
+	}
+	interp.freeze(importPath, sc)
+	return nil
+}
+
+// freeze records the current symbols of package scope sc as frozen.
+// The interpreter mutex must be held.
+func (interp *Interpreter) freeze(importPath string, sc *scope) {
+	names := map[string]bool{}
+	for name, sym := range sc.sym {
+		if sym.kind == pkgSym {
+			continue // imports are scoped by file
+		}
+		names[name] = true
+	}
+	interp.frozen[importPath] = names
+}
+
+// freezeCompiled freezes the package importPath after its compilation, if
+// required by the FreezePackages option.
+func (interp *Interpreter) freezeCompiled(importPath string) {
+	if !interp.opt.freeze[importPath] {
+		return
+	}
+	interp.mutex.Lock()
+	defer interp.mutex.Unlock()
+
+	if sc := interp.scopes[importPath]; sc != nil && interp.frozen[importPath] == nil {
+		interp.freeze(importPath, sc)
+	}
+}
+
+// checkFrozen returns an error if root declares a frozen symbol of the
+// package importPath, or a method on one of its frozen types.
+func (interp *Interpreter) checkFrozen(root *node, importPath string) error {
+	interp.mutex.RLock()
+	frozen := interp.frozen[importPath]
+	interp.mutex.RUnlock()
+	if frozen == nil {
+		return nil
+	}
+
+	var err error
+	check := func(n *node, name string) {
+		if frozen[name] {
+			err = n.cfgErrorf("cannot redeclare %s: package %s is frozen", name, importPath)
+		}
+	}
+	root.Walk(func(n *node) bool {
+		if err != nil {
+			return false
+		}
+		switch n.kind {
+		case blockStmt:
+			return n == root // skip local scopes
+		case funcLit:
+			return false
+		case funcDecl:
+			if !isMethod(n) {
+				if name := n.child[1].ident; name != "init" {
+					check(n, name)
+				}
+				return false
+			}
+			// Find the receiver type name, through pointer and generic
+			// type expressions.
+			rtn := n.child[0].child[0].lastChild()
+			for rtn.ident == "" && len(rtn.child) > 0 {
+				rtn = rtn.child[0]
+			}
+			if frozen[rtn.ident] {
+				err = n.cfgErrorf("cannot declare method %s on type %s: package %s is frozen", n.child[1].ident, rtn.ident, importPath)
+			}
+			return false
+		case defineStmt:
+			if a := n.anc; a == root || a.kind == constDecl || a.kind == varDecl {
+				for _, c := range n.child[:n.nleft] {
+					check(c, c.ident)
+				}
+			}
+		case valueSpec:
+			for _, c := range n.child[:len(n.child)-1] {
+				check(c, c.ident)
+			}
+		case typeSpec, typeSpecAssign:
+			check(n, n.child[0].ident)
+		}
+		return true
+	}, nil)
+	return err
+}
@@ -0,0 +1,285 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FuzzOptions are the options of RunFuzz.
+type FuzzOptions struct {
+	// Execs is the maximum number of executions of each fuzz function with
+	// mutated inputs, after its seed corpus. Default is 10000.
+	Execs int
+
+	// Duration, if not zero, limits the fuzzing time of each fuzz function.
+	Duration time.Duration
+
+	// Seed is the seed of the random mutations, for reproducible runs.
+	Seed int64
+}
+
+// FuzzResult is the result of an interpreted fuzz test run by RunFuzz.
+type FuzzResult struct {
+	TestResult
+
+	Execs int           // number of executions of the fuzz function, including the seed corpus
+	Input []interface{} // arguments of the failing execution, excluding the *testing.T, or nil
+}
+
+// testF is a shim of testing.F, which replaces it in interpreted code when
+// the TestShim option is set.
+type testF struct {
+	testT
+
+	opts   FuzzOptions
+	fuzz   *FuzzResult
+	seeds  [][]reflect.Value
+	fuzzed bool
+}
+
+var testTType = reflect.TypeOf((*testT)(nil))
+
+// RunFuzz runs the fuzz tests of the packages evaluated by EvalTest whose
+// names match the regular expression pattern, as the -fuzz flag of "go
+// test", and returns their results, sorted by package and name. It requires
+// the TestShim option, which provides a shim of *testing.F to fuzz tests.
+//
+// The fuzz function given to F.Fuzz is executed first with the seed
+// corpus added by F.Add, then with inputs derived from the corpus by random
+// mutations, until an execution fails or the limits set in opts are
+// reached. Inputs of successful executions are not added to the corpus, as
+// there is no coverage guidance.
+func (interp *Interpreter) RunFuzz(pattern string, opts FuzzOptions) ([]FuzzResult, error) {
+	if !interp.opt.testShim {
+		return nil, errors.New("running fuzz tests requires the TestShim option")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fuzz pattern: %w", err)
+	}
+	if opts.Execs <= 0 {
+		opts.Execs = 10000
+	}
+
+	interp.mutex.RLock()
+	paths := append([]string(nil), interp.tested...)
+	interp.mutex.RUnlock()
+	if len(paths) == 0 {
+		return nil, errors.New("no test package evaluated")
+	}
+	sort.Strings(paths)
+
+	r := &testRunner{interp: interp}
+	var results []FuzzResult
+	for _, path := range paths {
+		syms := interp.Symbols(path)[path]
+		names := make([]string, 0, len(syms))
+		for name, sym := range syms {
+			if _, ok := sym.Interface().(func(*testF)); ok && strings.HasPrefix(name, "Fuzz") && re.MatchString(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			res := &FuzzResult{TestResult: TestResult{Name: name, Package: path}}
+			f := &testF{opts: opts, fuzz: res}
+			f.runner, f.result = r, &res.TestResult
+			f.ctx, f.cancel = context.WithCancel(context.Background())
+			fn := syms[name].Interface().(func(*testF))
+
+			start := time.Now()
+			f.exec(func() {
+				fn(f)
+				if !f.fuzzed && !f.Failed() && !f.Skipped() {
+					f.fail("missing call to F.Fuzz")
+				}
+			})
+			res.Duration = time.Since(start)
+			results = append(results, *res)
+		}
+	}
+	return results, nil
+}
+
+// Add adds the arguments to the seed corpus of the fuzz test.
+func (f *testF) Add(args ...any) {
+	seed := make([]reflect.Value, len(args))
+	for i, a := range args {
+		v := reflect.ValueOf(a)
+		if !isFuzzKind(v) {
+			f.Fatalf("unsupported type to Add: %T", a)
+		}
+		seed[i] = v
+	}
+	f.seeds = append(f.seeds, seed)
+}
+
+// Fuzz runs the fuzz function ff, of type func(*testing.T, args...), on the
+// seed corpus, then on mutated inputs.
+func (f *testF) Fuzz(ff any) {
+	if f.fuzzed {
+		f.Fatal("F.Fuzz called more than once")
+	}
+	f.fuzzed = true
+
+	fv := reflect.ValueOf(ff)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func || ft.NumIn() < 2 || ft.In(0) != testTType || ft.NumOut() != 0 {
+		f.Fatalf("invalid fuzz function type %v, want func(*testing.T, args...)", ft)
+	}
+	types := make([]reflect.Type, ft.NumIn()-1)
+	for i := range types {
+		types[i] = ft.In(i + 1)
+		if !isFuzzKind(reflect.Zero(types[i])) {
+			f.Fatalf("unsupported fuzz argument type %v", types[i])
+		}
+	}
+	for _, seed := range f.seeds {
+		if len(seed) != len(types) {
+			f.Fatalf("wrong number of values in seed corpus entry: %d, want %d", len(seed), len(types))
+		}
+		for i, v := range seed {
+			if v.Type() != types[i] {
+				f.Fatalf("mismatched types in seed corpus entry: %v, want %v", v.Type(), types[i])
+			}
+		}
+	}
+	if len(f.seeds) == 0 {
+		seed := make([]reflect.Value, len(types))
+		for i, t := range types {
+			seed[i] = reflect.Zero(t)
+		}
+		f.seeds = append(f.seeds, seed)
+	}
+
+	call := func(in []reflect.Value) bool {
+		f.fuzz.Execs++
+		t := newTestT(f.runner, &TestResult{Name: f.Name(), Package: f.result.Package}, nil)
+		t.exec(func() { fv.Call(append([]reflect.Value{reflect.ValueOf(t)}, in...)) })
+		if !t.Failed() {
+			return true
+		}
+		f.fuzz.Input = make([]interface{}, len(in))
+		for i, v := range in {
+			f.fuzz.Input[i] = v.Interface()
+		}
+		f.mutex.Lock()
+		f.result.Logs = append(f.result.Logs, t.result.Logs...)
+		f.result.Failed = true
+		f.mutex.Unlock()
+		return false
+	}
+
+	for _, seed := range f.seeds {
+		if !call(seed) {
+			return
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(f.opts.Seed))
+	var deadline time.Time
+	if f.opts.Duration > 0 {
+		deadline = time.Now().Add(f.opts.Duration)
+	}
+	for k := 0; k < f.opts.Execs; k++ {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return
+		}
+		seed := f.seeds[rnd.Intn(len(f.seeds))]
+		in := make([]reflect.Value, len(seed))
+		copy(in, seed)
+		// Mutate at least one argument.
+		for i := rnd.Intn(len(in)); i < len(in); i += 1 + rnd.Intn(len(in)) {
+			in[i] = mutate(rnd, in[i])
+		}
+		if !call(in) {
+			return
+		}
+	}
+}
+
+// isFuzzKind returns true if v has a type supported by fuzzing.
+func isFuzzKind(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice:
+		return v.Type().Elem().Kind() == reflect.Uint8
+	}
+	return false
+}
+
+// mutate returns a random mutation of v.
+func mutate(rnd *rand.Rand, v reflect.Value) reflect.Value {
+	r := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Bool:
+		r.SetBool(!v.Bool())
+	case reflect.String:
+		r.SetString(string(mutateBytes(rnd, []byte(v.String()))))
+	case reflect.Slice:
+		r.SetBytes(mutateBytes(rnd, append([]byte(nil), v.Bytes()...)))
+	case reflect.Float32, reflect.Float64:
+		x := v.Float()
+		switch rnd.Intn(4) {
+		case 0:
+			x = -x
+		case 1:
+			x += rnd.NormFloat64()
+		case 2:
+			x *= math.Pow(2, float64(rnd.Intn(64)-32))
+		default:
+			x = math.Float64frombits(math.Float64bits(x) ^ 1<<rnd.Intn(64))
+		}
+		r.SetFloat(x)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		r.SetInt(int64(mutateUint(rnd, uint64(v.Int()))))
+	default:
+		r.SetUint(mutateUint(rnd, v.Uint()))
+	}
+	return r
+}
+
+func mutateUint(rnd *rand.Rand, x uint64) uint64 {
+	switch rnd.Intn(4) {
+	case 0:
+		return x + uint64(rnd.Intn(33)) - 16
+	case 1:
+		return x ^ 1<<rnd.Intn(64)
+	case 2:
+		return -x
+	}
+	return rnd.Uint64()
+}
+
+func mutateBytes(rnd *rand.Rand, b []byte) []byte {
+	if len(b) == 0 {
+		return append(b, byte(rnd.Intn(256)))
+	}
+	i := rnd.Intn(len(b))
+	switch rnd.Intn(5) {
+	case 0: // Insert a random byte.
+		b = append(b[:i], append([]byte{byte(rnd.Intn(256))}, b[i:]...)...)
+	case 1: // Remove a byte.
+		b = append(b[:i], b[i+1:]...)
+	case 2: // Flip a bit.
+		b[i] ^= 1 << rnd.Intn(8)
+	case 3: // Duplicate a range.
+		j := i + rnd.Intn(len(b)-i)
+		b = append(b[:j], append(append([]byte(nil), b[i:j+1]...), b[j:]...)...)
+	default: // Replace a byte.
+		b[i] = byte(rnd.Intn(256))
+	}
+	return b
+}
@@ -1,6 +1,8 @@
 package interp
 
 import (
+	"fmt"
+	"reflect"
 	"strings"
 	"sync/atomic"
 )
@@ -12,6 +14,75 @@ import (
 // function when it is imported in yaegi.
 type GenericFunc string
 
+// GenericType contains the code of a generic type declaration, followed by
+// the declarations of its methods, e.g.
+//
+//	type Set[T comparable] map[T]struct{}
+//	func (s Set[T]) Add(v T) { s[v] = struct{}{} }
+//
+// As for GenericFunc, it is used to expose a generic type of a binary
+// package, which can not be compiled in. The type is interpreted when the
+// package is used, and instantiated by scripts as any generic type.
+type GenericType string
+
+// genericSource returns the code of the generic function or type v, if any.
+func genericSource(v reflect.Value) (string, bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return "", false
+	}
+	switch g := v.Interface().(type) {
+	case GenericFunc:
+		return string(g), true
+	case GenericType:
+		return string(g), true
+	}
+	return "", false
+}
+
+// Instantiate instantiates the generic function or type name, e.g.
+// "main.Max" or "stack.Stack", with the type arguments types, which are
+// type expressions in the scope of the main package, e.g. "int" or
+// "[]main.Point". Instances are cached per generic and type arguments, so
+// Instantiate can be used at startup to pre-warm instances later used by
+// scripts.
+//
+// It returns the instantiated function, or the zero value of the
+// instantiated type.
+func (interp *Interpreter) Instantiate(name string, types ...string) (reflect.Value, error) {
+	if len(types) == 0 {
+		return reflect.Value{}, fmt.Errorf("instantiate %s: missing type arguments", name)
+	}
+
+	interp.compile.Lock()
+	prog, err := interp.compileSrc(name, "", true)
+	interp.compile.Unlock()
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	gt := prog.root.typ
+	if gt == nil || gt.cat != genericT && !isGeneric(gt) {
+		return reflect.Value{}, fmt.Errorf("instantiate %s: not a generic function or type", name)
+	}
+
+	src := name + "[" + strings.Join(types, ", ") + "]"
+	interp.compile.Lock()
+	prog, err = interp.compileSrc(src, "", true)
+	interp.compile.Unlock()
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	// Execute only the instantiation, not the main function, if any.
+	prog.init = nil
+	if _, err = interp.Execute(prog); err != nil {
+		return reflect.Value{}, err
+	}
+	t := prog.root.typ
+	if gt.cat == genericT {
+		return reflect.New(t.TypeOf()).Elem(), nil
+	}
+	return genFunctionWrapper(t.node.anc)(interp.frame.Load()), nil
+}
+
 // adot produces an AST dot(1) directed acyclic graph for the given node. For debugging only.
 // func (n *node) adot() { n.astDot(dotWriter(n.interp.dotCmd), n.ident) }
 
@@ -77,7 +148,7 @@ func genAST(sc *scope, root *node, types []*itype) (*node, bool, error) {
 						if err != nil {
 							return nil, err
 						}
-						if err := checkConstraint(types[pindex], t); err != nil {
+						if err := checkConstraint(cc, types[pindex], t); err != nil {
 							return nil, err
 						}
 						typeParam[cc.ident] = copyNode(cc, cc.anc, false)
@@ -131,7 +202,7 @@ func genAST(sc *scope, root *node, types []*itype) (*node, bool, error) {
 						if err != nil {
 							return nil, err
 						}
-						if err := checkConstraint(types[pindex], t); err != nil {
+						if err := checkConstraint(cc, types[pindex], t); err != nil {
 							return nil, err
 						}
 						typeParam[cc.ident] = copyNode(cc, cc.anc, false)
@@ -156,7 +227,13 @@ func genAST(sc *scope, root *node, types []*itype) (*node, bool, error) {
 		return nod, nil
 	}
 
-	if nod, found := root.interp.generic[sname]; found {
+	// Instances are cached per package, as generic declarations of distinct
+	// packages, e.g. exposed by binary packages, can have the same name.
+	key := sname
+	if root.scope != nil {
+		key = root.scope.pkgID + "." + sname
+	}
+	if nod, found := root.interp.generic[key]; found {
 		return nod, true, nil
 	}
 
@@ -164,7 +241,7 @@ func genAST(sc *scope, root *node, types []*itype) (*node, bool, error) {
 	if err != nil {
 		return nil, false, err
 	}
-	root.interp.generic[sname] = r
+	root.interp.generic[key] = r
 	r.param = append(r.param, types...)
 	if tname != "" {
 		for _, nod := range fixNodes {
@@ -215,9 +292,13 @@ func copyNode(n, anc *node, recursive bool) *node {
 	return nod
 }
 
+// inferTypesFromCall returns the type arguments of the generic function fun,
+// in the order of its type parameters, inferred from the call arguments args
+// and from the core types of the constraints, e.g. E in [S ~[]E, E any].
 func inferTypesFromCall(sc *scope, fun *node, args []*node) ([]*itype, error) {
 	ftn := fun.typ.node
 	// Fill the map of parameter types, indexed by type param ident.
+	names := []string{}
 	paramTypes := map[string]*itype{}
 	for _, c := range ftn.child[0].child {
 		typ, err := nodeType(fun.interp, sc, c.lastChild())
@@ -225,106 +306,279 @@ func inferTypesFromCall(sc *scope, fun *node, args []*node) ([]*itype, error) {
 			return nil, err
 		}
 		for _, cc := range c.child[:len(c.child)-1] {
+			names = append(names, cc.ident)
 			paramTypes[cc.ident] = typ
 		}
 	}
 
-	var inferTypes func(*itype, *itype) ([]*itype, error)
-	inferTypes = func(param, input *itype) ([]*itype, error) {
-		switch param.cat {
-		case chanT, ptrT, sliceT:
-			return inferTypes(param.val, input.val)
+	inferred := map[string]*itype{}
+	bind := func(name string, input *itype) {
+		t, ok := inferred[name]
+		switch {
+		case !ok:
+			inferred[name] = input
+		case t.untyped && !input.untyped:
+			inferred[name] = input
+		case t.untyped && input.untyped && untypedRank(input) > untypedRank(t):
+			// Mixed untyped constants, e.g. 1 and 2.5, give the larger kind.
+			inferred[name] = input
+		}
+	}
 
-		case mapT:
-			k, err := inferTypes(param.key, input.key)
-			if err != nil {
-				return nil, err
+	var inferTypes func(*node, *itype, *itype)
+	inferTypes = func(pn *node, param, input *itype) {
+		if param == nil || input == nil {
+			return
+		}
+		switch param.cat {
+		case nilT, genericT:
+			if _, ok := paramTypes[param.name]; ok {
+				bind(param.name, input)
+				return
 			}
-			v, err := inferTypes(param.val, input.val)
-			if err != nil {
-				return nil, err
+			// A generic type, e.g. Stack[T]: infer from the type arguments of
+			// its instance.
+			if pn == nil || (pn.kind != indexExpr && pn.kind != indexListExpr) {
+				return
+			}
+			targs := instanceArgs(input)
+			for i, c := range pn.child[1:] {
+				if i >= len(targs) {
+					break
+				}
+				t, err := nodeType(fun.interp, sc, c)
+				if err != nil {
+					return
+				}
+				inferTypes(c, t, targs[i])
+			}
+			return
+		}
+		if input.cat == linkedT && param.cat != linkedT {
+			input = input.underlying()
+		}
+		if input.cat != param.cat && !(param.cat == variadicT && input.cat == sliceT) {
+			return
+		}
+		switch param.cat {
+		case arrayT, chanT, ptrT, sliceT, variadicT:
+			if pn != nil && (pn.kind == starExpr || pn.kind == arrayType || pn.kind == ellipsisExpr) {
+				pn = pn.lastChild()
+			} else {
+				pn = nil
 			}
-			return append(k, v...), nil
+			inferTypes(pn, param.val, input.val)
+
+		case mapT:
+			inferTypes(nil, param.key, input.key)
+			inferTypes(nil, param.val, input.val)
 
 		case structT:
-			lt := []*itype{}
 			for i, f := range param.field {
-				nl, err := inferTypes(f.typ, input.field[i].typ)
-				if err != nil {
-					return nil, err
+				if i < len(input.field) {
+					inferTypes(nil, f.typ, input.field[i].typ)
 				}
-				lt = append(lt, nl...)
 			}
-			return lt, nil
 
 		case funcT:
-			lt := []*itype{}
 			for i, t := range param.arg {
-				if i >= len(input.arg) {
-					break
-				}
-				nl, err := inferTypes(t, input.arg[i])
-				if err != nil {
-					return nil, err
+				if i < len(input.arg) {
+					inferTypes(nil, t, input.arg[i])
 				}
-				lt = append(lt, nl...)
 			}
 			for i, t := range param.ret {
-				if i >= len(input.ret) {
-					break
-				}
-				nl, err := inferTypes(t, input.ret[i])
-				if err != nil {
-					return nil, err
+				if i < len(input.ret) {
+					inferTypes(nil, t, input.ret[i])
 				}
-				lt = append(lt, nl...)
 			}
-			return lt, nil
-
-		case nilT:
-			if paramTypes[param.name] != nil {
-				return []*itype{input}, nil
-			}
-
-		case genericT:
-			return []*itype{input}, nil
 		}
-		return nil, nil
 	}
 
-	types := []*itype{}
-	for i, c := range ftn.child[1].child {
-		typ, err := nodeType(fun.interp, sc, c.lastChild())
+	// Infer from the arguments, assigned in order to the named or anonymous
+	// input parameters. Remaining arguments match the variadic parameter, if
+	// any, unless the call is of the form f(s...).
+	spread := len(args) > 0 && args[0].anc.action == aCallSlice
+	i := 0
+	for _, c := range ftn.child[1].child {
+		pn := c.lastChild()
+		typ, err := nodeType(fun.interp, sc, pn)
 		if err != nil {
 			return nil, err
 		}
-		if i < len(args) {
-			lt, err := inferTypes(typ, args[i].typ)
-			if err != nil {
-				return nil, err
+		n := len(c.child) - 1
+		if n == 0 {
+			n = 1
+		}
+		for ; n > 0 && i < len(args); n-- {
+			if typ.cat != variadicT || spread {
+				inferTypes(pn, typ, args[i].typ)
+				i++
+				continue
+			}
+			for ; i < len(args); i++ {
+				inferTypes(pn.lastChild(), typ.val, args[i].typ)
+			}
+		}
+	}
+
+	// Infer the remaining type parameters from the core types of constraints,
+	// until no more progress is made.
+	for progress := true; progress; {
+		progress = false
+		for _, name := range names {
+			core, tilde := coreType(paramTypes[name])
+			if core == nil {
+				continue
+			}
+			if t, ok := inferred[name]; ok {
+				l := len(inferred)
+				inferTypes(nil, core, t)
+				progress = progress || len(inferred) > l
+				continue
+			}
+			// An exact core type, e.g. *Q in [P *Q, Q any], determines the type.
+			if tilde {
+				continue
+			}
+			if t := substType(core, inferred); t != nil {
+				inferred[name] = t
+				progress = true
 			}
-			types = append(types, lt...)
-		} else {
-			types = append(types, typ)
 		}
 	}
 
+	types := []*itype{}
+	for _, name := range names {
+		t, ok := inferred[name]
+		if !ok {
+			return nil, ftn.cfgErrorf("cannot infer %s", name)
+		}
+		// Check untyped constants prior to their conversion to default type.
+		if err := checkConstraint(ftn, t, paramTypes[name]); err != nil {
+			return nil, err
+		}
+		types = append(types, t.defaultType(reflect.Value{}, sc))
+	}
 	return types, nil
 }
 
-func checkConstraint(it, ct *itype) error {
+// untypedRank returns the rank of the kind of the untyped constant type t,
+// from int to complex.
+func untypedRank(t *itype) int {
+	switch t.cat {
+	case int32T:
+		return 1
+	case float64T:
+		return 2
+	case complex128T:
+		return 3
+	}
+	return 0
+}
+
+// instanceArgs returns the type arguments of t, if t is an instance of a
+// generic type.
+func instanceArgs(t *itype) []*itype {
+	if t.node == nil || t.node.anc == nil || t.node.anc.kind != typeSpec {
+		return nil
+	}
+	return t.node.anc.param
+}
+
+// coreType returns the single type term of the constraint ct, if any, e.g.
+// []E for ~[]E, and whether the term is an underlying type one.
+func coreType(ct *itype) (*itype, bool) {
+	if ct != nil && ct.cat != constraintT && ct.cat != nilT && !isInterfaceSrc(ct) && !isInterfaceBin(ct) {
+		// The constraint is a single type, e.g. []E in [S []E, E any].
+		return ct, false
+	}
+	if ct == nil || len(ct.constraint)+len(ct.ulconstraint) != 1 {
+		return nil, false
+	}
+	if len(ct.ulconstraint) == 1 {
+		return ct.ulconstraint[0], true
+	}
+	return ct.constraint[0], false
+}
+
+// substType returns the type t where type parameters are replaced by their
+// inferred types, or nil if t can not be fully determined.
+func substType(t *itype, inferred map[string]*itype) *itype {
+	switch t.cat {
+	case nilT, genericT:
+		return inferred[t.name]
+	case sliceT:
+		if v := substType(t.val, inferred); v != nil {
+			return sliceOf(v, withScope(t.scope))
+		}
+	case ptrT:
+		if v := substType(t.val, inferred); v != nil {
+			return ptrOf(v, withScope(t.scope))
+		}
+	case mapT:
+		k, v := substType(t.key, inferred), substType(t.val, inferred)
+		if k != nil && v != nil {
+			return mapOf(k, v, withScope(t.scope))
+		}
+	default:
+		if !t.incomplete {
+			return t
+		}
+	}
+	return nil
+}
+
+// checkConstraint returns an error if the type it does not satisfy the
+// constraint ct. The error is located at it, or at n if it has no node.
+func checkConstraint(n *node, it, ct *itype) error {
 	if len(ct.constraint) == 0 && len(ct.ulconstraint) == 0 {
 		return nil
 	}
+	if satisfies(it, ct) {
+		return nil
+	}
+	if it.node != nil {
+		n = it.node
+	}
+	return n.cfgErrorf("%s does not implement %s", it.id(), ct.id())
+}
+
+// satisfies returns true if the type it is in the type set of constraint ct,
+// which can be composed of other constraints, e.g. Signed | Unsigned.
+func satisfies(it, ct *itype) bool {
 	for _, c := range ct.constraint {
-		if it.equals(c) || it.matchDefault(c) {
-			return nil
+		if len(c.constraint) > 0 || len(c.ulconstraint) > 0 {
+			if satisfies(it, c) {
+				return true
+			}
+			continue
+		}
+		if matchTerm(it, c) || it.untyped && it.cat == c.cat {
+			return true
 		}
 	}
 	for _, c := range ct.ulconstraint {
-		if it.underlying().equals(c) || it.matchDefault(c) {
-			return nil
+		if matchTerm(it.underlying(), c) || it.untyped && it.cat == c.cat {
+			return true
 		}
 	}
-	return it.node.cfgErrorf("%s does not implement %s", it.id(), ct.id())
+	return false
+}
+
+// matchTerm returns true if the type t matches the constraint term c, where
+// type parameters, e.g. E in ~[]E, match any type.
+func matchTerm(t, c *itype) bool {
+	if c.cat == genericT || c.cat == nilT && c.incomplete {
+		return true
+	}
+	if t.cat != c.cat {
+		return t.equals(c)
+	}
+	switch c.cat {
+	case arrayT, chanT, ptrT, sliceT:
+		return matchTerm(t.val, c.val)
+	case mapT:
+		return matchTerm(t.key, c.key) && matchTerm(t.val, c.val)
+	}
+	return t.equals(c)
 }
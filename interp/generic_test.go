@@ -199,3 +199,146 @@ func main() {
 		t.Error(err)
 	}
 }
+
+func TestGenericType(t *testing.T) {
+	i := New(Options{})
+	err := i.Use(Exports{
+		"guthib.com/generic/generic": map[string]reflect.Value{
+			"Set": reflect.ValueOf(GenericType("type Set[T comparable] map[T]struct{}\n" +
+				"func (s Set[T]) Add(v T) { s[v] = struct{}{} }\n" +
+				"func (s Set[T]) Has(v T) bool { _, ok := s[v]; return ok }")),
+			"Stack": reflect.ValueOf(GenericType("type Stack[T any] struct{ items []T }\n" +
+				"func (s *Stack[T]) Push(v T) { s.items = append(s.items, v) }\n" +
+				"func (s *Stack[T]) Len() int { return len(s.items) }")),
+		},
+		"guthib.com/other/other": map[string]reflect.Value{
+			"Set": reflect.ValueOf(GenericType("type Set[T comparable] []T")),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := i.Eval(`
+import (
+	"guthib.com/generic"
+	"guthib.com/other"
+)
+
+func check() bool {
+	s := generic.Set[string]{}
+	s.Add("a")
+	st := &generic.Stack[int]{}
+	st.Push(1)
+	st.Push(2)
+	o := other.Set[string]{"x", "y", "z"}
+	return s.Has("a") && !s.Has("b") && st.Len() == 2 && len(o) == 3
+}
+`)
+	if err != nil {
+		t.Fatal(res, err)
+	}
+	if res, err = i.Eval("check()"); err != nil || res.Interface() != true {
+		t.Fatalf("got %v, %v, want true", res, err)
+	}
+
+	if d := describeBin("Stack", i.binPkg["guthib.com/generic"]["Stack"]); d.Kind != "type" || d.Signature != "type Stack[T any] struct{ items []T }" {
+		t.Errorf("got %+v", d)
+	}
+}
+
+func TestGenericTypeDotImport(t *testing.T) {
+	i := New(Options{})
+	err := i.Use(Exports{
+		"guthib.com/generic/generic": map[string]reflect.Value{
+			"Pair": reflect.ValueOf(GenericType("type Pair[K comparable, V any] struct{ Key K; Value V }")),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := i.Eval(`
+import . "guthib.com/generic"
+
+var p = Pair[string, int]{"a", 1}
+`)
+	if err != nil {
+		t.Fatal(res, err)
+	}
+	if res, err = i.Eval("p.Value"); err != nil || res.Interface() != 1 {
+		t.Fatalf("got %v, %v, want 1", res, err)
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	i := New(Options{})
+	_, err := i.Eval(`
+type Set[T comparable] map[T]struct{}
+
+func (s Set[T]) Add(v T) { s[v] = struct{}{} }
+
+func Max[T int | float64](a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func Pair[K comparable, V any](k K, v V) map[K]V { return map[K]V{k: v} }
+
+func NotGeneric() {}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := i.Instantiate("main.Max", "float64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if max, ok := v.Interface().(func(float64, float64) float64); !ok || max(1.5, 2) != 2 {
+		t.Fatalf("got %v", v.Type())
+	}
+	if _, found := i.generic["main.Max[float64]"]; !found {
+		t.Fatal("instance of Max not cached")
+	}
+	if v, err = i.Instantiate("Pair", "string", "int"); err != nil {
+		t.Fatal(err)
+	}
+	if pair, ok := v.Interface().(func(string, int) map[string]int); !ok || pair("a", 1)["a"] != 1 {
+		t.Fatalf("got %v", v.Type())
+	}
+
+	if v, err = i.Instantiate("Set", "string"); err != nil {
+		t.Fatal(err)
+	}
+	if v.Type() != reflect.TypeOf(map[string]struct{}{}) {
+		t.Fatalf("got %v", v.Type())
+	}
+	n := len(i.generic)
+	_, err = i.Eval(`
+func f() float64 {
+	s := Set[string]{}
+	s.Add("a")
+	return float64(len(s)) + Max(1.5, 2.0)
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := i.Eval("f()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Interface() != 3.0 {
+		t.Fatalf("got %v, want 3", res)
+	}
+	if len(i.generic) != n {
+		t.Fatalf("got %d cached instances, want %d", len(i.generic), n)
+	}
+
+	if _, err = i.Instantiate("NotGeneric", "int"); err == nil {
+		t.Fatal("expected error for non generic function")
+	}
+	if _, err = i.Instantiate("Max", "string"); err == nil {
+		t.Fatal("expected error for unsatisfied constraint")
+	}
+}
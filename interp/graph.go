@@ -0,0 +1,102 @@
+package interp
+
+// Graph is the abstract syntax tree or the control flow graph of a program,
+// as exported by Program.AST and Program.CFG. It is meant to be encoded in
+// JSON, to let tools visualize or analyze programs, as astDot and cfgDot do
+// for graphviz.
+//
+// In JSON, a graph is an object with the following fields:
+//
+//	"graph": "ast" or "cfg"
+//	"nodes": [{"id", "kind", "action", "ident", "type", "file", "line", "column"}, ...]
+//	"edges": [{"from", "to", "kind"}, ...]
+//
+// Node ids are unique in the interpreter. Edges of the AST link a node to
+// each of its children, in order, with the kind "child". Edges of the CFG
+// link a node to the next one to execute, with the kind "next", or, for
+// conditional nodes, to the nodes executed if the condition is true or
+// false, with the kinds "true" and "false".
+type Graph struct {
+	Graph string      `json:"graph"`
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a node of a Graph.
+type GraphNode struct {
+	ID     int64  `json:"id"`               // node index
+	Kind   string `json:"kind"`             // node kind, e.g. "funcDecl" or "identExpr"
+	Action string `json:"action,omitempty"` // action at execution, e.g. "+" or "call"
+	Ident  string `json:"ident,omitempty"`  // identifier or literal
+	Type   string `json:"type,omitempty"`   // type of the value, if any
+	File   string `json:"file,omitempty"`   // position in source code
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// GraphEdge is an edge of a Graph.
+type GraphEdge struct {
+	From int64  `json:"from"`
+	To   int64  `json:"to"`
+	Kind string `json:"kind"` // "child", "next", "true" or "false"
+}
+
+// AST returns the abstract syntax tree of the program, in the order of
+// the source, with the types resolved by the compilation.
+func (p *Program) AST() *Graph {
+	g := &Graph{Graph: "ast", Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	p.root.Walk(func(n *node) bool {
+		g.Nodes = append(g.Nodes, graphNode(n))
+		for _, c := range n.child {
+			g.Edges = append(g.Edges, GraphEdge{From: n.index, To: c.index, Kind: "child"})
+		}
+		return true
+	}, nil)
+	return g
+}
+
+// CFG returns the control flow graph of the program. Only the nodes
+// executed, or the targets of an edge, are part of the graph.
+func (p *Program) CFG() *Graph {
+	g := &Graph{Graph: "cfg", Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	seen := map[*node]bool{}
+	add := func(n *node) {
+		if !seen[n] {
+			seen[n] = true
+			g.Nodes = append(g.Nodes, graphNode(n))
+		}
+	}
+	p.root.Walk(nil, func(n *node) {
+		if n.kind == basicLit || n.tnext == nil {
+			return
+		}
+		add(n)
+		if n.fnext != nil {
+			add(n.tnext)
+			add(n.fnext)
+			g.Edges = append(g.Edges,
+				GraphEdge{From: n.index, To: n.tnext.index, Kind: "true"},
+				GraphEdge{From: n.index, To: n.fnext.index, Kind: "false"})
+			return
+		}
+		add(n.tnext)
+		g.Edges = append(g.Edges, GraphEdge{From: n.index, To: n.tnext.index, Kind: "next"})
+	})
+	return g
+}
+
+// graphNode returns the description of n in a Graph.
+func graphNode(n *node) GraphNode {
+	gn := GraphNode{ID: n.index, Kind: n.kind.String(), Ident: n.ident}
+	if n.action != aNop {
+		gn.Action = n.action.String()
+	}
+	if n.typ != nil {
+		gn.Type = n.typ.id()
+	}
+	if n.pos.IsValid() && n.interp != nil {
+		pos := n.interp.fset.Position(n.pos)
+		gn.File, gn.Line, gn.Column = pos.Filename, pos.Line, pos.Column
+	}
+	return gn
+}
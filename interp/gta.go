@@ -191,6 +191,7 @@ func (interp *Interpreter) gta(root *node, rpath, importPath, pkgName string) ([
 					elementType.addMethod(n)
 				}
 				rcvrtype.addMethod(n)
+				interp.methods.Add(1)
 				rtn.typ = rcvrtype
 				if rcvrtype.cat == genericT {
 					// generate methods for already instantiated receivers
@@ -230,21 +231,43 @@ func (interp *Interpreter) gta(root *node, rpath, importPath, pkgName string) ([
 			if packageName := path.Base(ipath); path.Dir(ipath) == packageName {
 				ipath = packageName
 			}
+			if ipath == "C" {
+				err = interp.cgoError(importPath, n.pos)
+				return false
+			}
+			if err = interp.loadProvided(ipath); err != nil {
+				return false
+			}
+			if err = interp.loadCgoStub(rpath, ipath); err != nil {
+				return false
+			}
 			if pkg := interp.binPkg[ipath]; pkg != nil {
+				if ipath == "unsafe" {
+					if err = interp.checkUnsafeImport(n); err != nil {
+						return false
+					}
+				}
 				switch name {
 				case "_": // no import of symbols
 				case ".": // import symbols in current scope
-					for n, v := range pkg {
+					for n := range pkg {
+						if ipath == "unsafe" && !interp.unsafeAllowed(n) {
+							continue
+						}
+						v, ok := interp.binSymbol(ipath, n)
+						if !ok {
+							continue
+						}
 						typ := v.Type()
 						kind := binSym
 						if isBinType(v) {
 							typ = typ.Elem()
 							kind = typeSym
 						}
-						if gf, ok := v.Interface().(GenericFunc); ok {
+						if src, ok := genericSource(v); ok {
 							samePath := strings.HasSuffix(ipath, importPath)
 							if !samePath {
-								if _, cerr := interp.Compile(string(gf)); cerr != nil {
+								if _, cerr := interp.compileSrc(src, "", true); cerr != nil {
 									err = cerr
 									return false
 								}
@@ -280,10 +303,11 @@ func (interp *Interpreter) gta(root *node, rpath, importPath, pkgName string) ([
 					err = n.cfgErrorf("%s redeclared in this block", name)
 					return false
 				}
-			} else if pkgName, err = interp.importSrc(rpath, ipath, NoTest); err == nil {
+			} else if pkgName, err = interp.importSrcFrom(importPath, rpath, ipath, n.pos); err == nil {
 				sc.types = interp.universe.types
 				switch name {
-				case "_": // no import of symbols
+				case "_": // no import of symbols, but of the side effects of the package initialization
+					interp.useSrcPkg(ipath)
 				case ".": // import symbols in current namespace
 					for k, v := range interp.srcPkg[ipath] {
 						if canExport(k) {
@@ -326,6 +350,12 @@ func (interp *Interpreter) gta(root *node, rpath, importPath, pkgName string) ([
 				sc.sym[typeName].typ = n.typ
 				return false
 			}
+			if sym := sc.sym[typeName]; sym != nil && sym.kind == typeSym && sym.typ != nil &&
+				sym.typ.tversion != nil && sym.typ.tversion.eval != interp.evals {
+				// The type is redeclared by a new evaluation. Define a new type
+				// instead of updating the previous one, whose values may remain.
+				delete(sc.sym, typeName)
+			}
 			var typ *itype
 			if typ, err = nodeType(interp, sc, n.child[1]); err != nil {
 				err = nil
@@ -354,6 +384,7 @@ func (interp *Interpreter) gta(root *node, rpath, importPath, pkgName string) ([
 				n.typ.path = pkgName
 			}
 			n.typ.str = n.typ.path + "." + n.typ.name
+			interp.addTypeVersion(n.typ)
 
 			asImportName := path.Join(typeName, baseName)
 			if _, exists := sc.sym[asImportName]; exists {
@@ -399,10 +430,15 @@ func baseType(t *itype) *itype {
 
 // gtaRetry (re)applies gta until all global constants and types are defined.
 func (interp *Interpreter) gtaRetry(nodes []*node, importPath, pkgName string) error {
+	rpath := importPath
+	if namespaceName(importPath) != "" {
+		// Source imports of a namespace are relative to the main package.
+		rpath = mainID
+	}
 	revisit := []*node{}
 	for {
 		for _, n := range nodes {
-			list, err := interp.gta(n, importPath, importPath, pkgName)
+			list, err := interp.gta(n, rpath, importPath, pkgName)
 			if err != nil {
 				return err
 			}
@@ -1,13 +1,39 @@
 package interp
 
-import "reflect"
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
 
 // convertFn is the signature of a symbol converter.
 type convertFn func(from, to reflect.Type) func(src, dest reflect.Value)
 
+// A SymbolHook rewrites a binary symbol when it is resolved by interpreted
+// code which imports its package. It receives the package import path and the
+// symbol name and value, and returns the value to use instead, e.g. the value
+// itself, or a function of the same type wrapping it. Returning an invalid
+// reflect.Value makes the symbol unavailable to interpreted code.
+//
+// Symbols which are types are not passed to hooks.
+type SymbolHook func(importPath, name string, value reflect.Value) reflect.Value
+
+// namedHook is a symbol hook registered with AddSymbolHook.
+type namedHook struct {
+	name  string
+	order int
+	hook  SymbolHook
+}
+
 // hooks are external symbol bindings.
 type hooks struct {
 	convert []convertFn
+
+	mutex     sync.RWMutex
+	symbol    []namedHook              // sorted by order, then name
+	symbols   map[string]reflect.Value // resolved symbols, indexed by "path.name"
+	overrides map[string]reflect.Value // overridden symbols, indexed by "path.name"
 }
 
 func (h *hooks) Parse(m map[string]reflect.Value) {
@@ -26,3 +52,174 @@ func getConvertFn(v reflect.Value) (convertFn, bool) {
 	}
 	return fn, true
 }
+
+// AddSymbolHook registers hook under the given name, to be applied to the
+// binary symbols subsequently resolved by interpreted code. Hooks are applied
+// in increasing order, then by name for equal orders, each hook receiving the
+// value returned by the previous one. Symbols already resolved by compiled
+// code are not affected. An error is returned if a hook of the same name is
+// already registered.
+func (interp *Interpreter) AddSymbolHook(name string, order int, hook SymbolHook) error {
+	h := interp.hooks
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, nh := range h.symbol {
+		if nh.name == name {
+			return fmt.Errorf("symbol hook %q already registered", name)
+		}
+	}
+	h.symbol = append(h.symbol, namedHook{name: name, order: order, hook: hook})
+	sort.SliceStable(h.symbol, func(i, j int) bool {
+		if h.symbol[i].order != h.symbol[j].order {
+			return h.symbol[i].order < h.symbol[j].order
+		}
+		return h.symbol[i].name < h.symbol[j].name
+	})
+	h.symbols = nil
+	return nil
+}
+
+// RemoveSymbolHook unregisters the symbol hook of the given name, and
+// returns true if it was registered. As for AddSymbolHook, symbols already
+// resolved by compiled code are not affected.
+func (interp *Interpreter) RemoveSymbolHook(name string) bool {
+	h := interp.hooks
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for i, nh := range h.symbol {
+		if nh.name == name {
+			h.symbol = append(h.symbol[:i:i], h.symbol[i+1:]...)
+			h.symbols = nil
+			return true
+		}
+	}
+	return false
+}
+
+// SymbolHooks returns the names of the registered symbol hooks, in the order
+// they are applied.
+func (interp *Interpreter) SymbolHooks() []string {
+	h := interp.hooks
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	names := make([]string, len(h.symbol))
+	for i, nh := range h.symbol {
+		names[i] = nh.name
+	}
+	return names
+}
+
+// Override shadows the symbol name of the binary package at importPath,
+// previously loaded by Use, by the value v, for the code subsequently
+// compiled by the interpreter, e.g. to stub a network client in tests.
+// The value must have the same type as the symbol, i.e. be a function of the
+// same signature, or a pointer to a variable of the same type. Symbol hooks
+// apply to v as to the original symbol. Code already compiled keeps the
+// symbol it was compiled with. Types can not be overridden.
+func (interp *Interpreter) Override(importPath, name string, v reflect.Value) error {
+	orig, ok := interp.binPkg[importPath][name]
+	switch {
+	case !ok:
+		return fmt.Errorf("symbol %s.%s not found", importPath, name)
+	case isBinType(orig):
+		return fmt.Errorf("symbol %s.%s is a type and can not be overridden", importPath, name)
+	case !v.IsValid():
+		return fmt.Errorf("cannot override symbol %s.%s with an invalid value", importPath, name)
+	case v.Type() != orig.Type():
+		return fmt.Errorf("cannot override symbol %s.%s of type %s with value of type %s", importPath, name, orig.Type(), v.Type())
+	}
+
+	h := interp.hooks
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.overrides == nil {
+		h.overrides = map[string]reflect.Value{}
+	}
+	h.overrides[importPath+"."+name] = v
+	h.symbols = nil
+	return nil
+}
+
+// RemoveOverride reverts the override of the symbol name of the binary
+// package at importPath, and returns true if it was overridden. As for
+// Override, code already compiled is not affected.
+func (interp *Interpreter) RemoveOverride(importPath, name string) bool {
+	h := interp.hooks
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	key := importPath + "." + name
+	if _, ok := h.overrides[key]; !ok {
+		return false
+	}
+	delete(h.overrides, key)
+	h.symbols = nil
+	return true
+}
+
+// binSymbol returns the value of the symbol name of the binary package at
+// importPath, after application of overrides and symbol hooks.
+func (interp *Interpreter) binSymbol(importPath, name string) (reflect.Value, bool) {
+	v, ok := interp.binPkg[importPath][name]
+	if !ok || isBinType(v) {
+		return v, ok
+	}
+	v = interp.hooks.override(importPath, name, v)
+	if isSyscallPath(importPath) {
+		if v, ok = interp.syscallSymbol(importPath, name, v); !ok {
+			return v, ok
+		}
+	}
+	return interp.hooks.apply(importPath, name, v)
+}
+
+// override returns the value overriding the binary symbol of value v, or v.
+func (h *hooks) override(importPath, name string, v reflect.Value) reflect.Value {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if o, ok := h.overrides[importPath+"."+name]; ok {
+		return o
+	}
+	return v
+}
+
+// apply returns the value v of a binary symbol, after application of symbol
+// hooks. Results are cached, so a symbol is resolved to the same value until
+// hooks are changed.
+func (h *hooks) apply(importPath, name string, v reflect.Value) (reflect.Value, bool) {
+	h.mutex.RLock()
+	if len(h.symbol) == 0 {
+		h.mutex.RUnlock()
+		return v, true
+	}
+	key := importPath + "." + name
+	if r, ok := h.symbols[key]; ok {
+		h.mutex.RUnlock()
+		return r, r.IsValid()
+	}
+	h.mutex.RUnlock()
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for _, nh := range h.symbol {
+		if v = nh.hook(importPath, name, v); !v.IsValid() {
+			break
+		}
+	}
+	if h.symbols == nil {
+		h.symbols = map[string]reflect.Value{}
+	}
+	h.symbols[key] = v
+	return v, v.IsValid()
+}
+
+// clearSymbols invalidates the cache of resolved symbols, after a change of
+// binary symbols.
+func (h *hooks) clearSymbols() {
+	h.mutex.Lock()
+	h.symbols = nil
+	h.mutex.Unlock()
+}
@@ -0,0 +1,20 @@
+//go:build !wasm
+
+package interp
+
+import (
+	"os"
+	"os/signal"
+)
+
+// defaultSpecialStdio is the default of the specialStdio option: the
+// standard streams of interpreted code are the streams of the options only
+// if they are files, see fixStdlib.
+const defaultSpecialStdio = false
+
+// The signals of the process, as the functions of the os/signal package.
+
+func signalNotify(c chan<- os.Signal, sigs ...os.Signal) { signal.Notify(c, sigs...) }
+func signalStop(c chan<- os.Signal)                      { signal.Stop(c) }
+func signalIgnore(sigs ...os.Signal)                     { signal.Ignore(sigs...) }
+func signalReset(sigs ...os.Signal)                      { signal.Reset(sigs...) }
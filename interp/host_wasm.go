@@ -0,0 +1,18 @@
+package interp
+
+import "os"
+
+// defaultSpecialStdio is the default of the specialStdio option. The
+// standard streams of WebAssembly hosts, e.g. a browser, are usually not
+// files: the standard streams of interpreted code are the streams of the
+// options, whatever their type.
+const defaultSpecialStdio = true
+
+// WebAssembly hosts deliver no signals to the process: the functions on
+// the signals of the process do nothing. The synthetic signals of Notify
+// are still delivered to interpreted code.
+
+func signalNotify(c chan<- os.Signal, sigs ...os.Signal) {}
+func signalStop(c chan<- os.Signal)                      {}
+func signalIgnore(sigs ...os.Signal)                     {}
+func signalReset(sigs ...os.Signal)                      {}
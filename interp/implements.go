@@ -0,0 +1,88 @@
+package interp
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Implements returns a host value of interface type ifaceType, whose methods
+// dispatch to the interpreted value obtained by the evaluation in interp of
+// symbol, e.g. "main.Greeter{}" or "handlers.Default". The symbol can also
+// be an interpreted function, if ifaceType has a single method of the same
+// signature, in the manner of http.HandlerFunc.
+//
+// Unless the value already implements ifaceType, the interface wrapper
+// generated by the extract tool for ifaceType must have been loaded with Use.
+// Wrappers of composed interfaces registered in MapTypes are preferred if the
+// interpreted type has their methods, so that the host can detect optional
+// interfaces, e.g. io.WriterTo, with a type assertion.
+func (interp *Interpreter) Implements(symbol string, ifaceType reflect.Type) (interface{}, error) {
+	if ifaceType == nil || ifaceType.Kind() != reflect.Interface {
+		return nil, fmt.Errorf("implements %s: %v is not an interface type", symbol, ifaceType)
+	}
+
+	interp.compile.Lock()
+	prog, err := interp.compileSrc(symbol, "", true)
+	interp.compile.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	v, err := interp.Execute(prog)
+	if err != nil {
+		return nil, err
+	}
+	typ := prog.root.typ
+	if !v.IsValid() || typ == nil {
+		return nil, fmt.Errorf("implements %s: not a value", symbol)
+	}
+
+	lm := typ.methods()
+	if v.Kind() == reflect.Func && len(lm) == 0 {
+		return interp.implementsFunc(symbol, v, ifaceType)
+	}
+	if typ.cat != structT && v.Type().Implements(ifaceType) {
+		return translateValue(v, ifaceType).Interface(), nil
+	}
+
+	// Check the method set first, as the wrapper panics at run time on a
+	// missing method.
+	n := &node{interp: interp, kind: identExpr, typ: typ, rval: v, findex: notInFrame}
+	for i := 0; i < ifaceType.NumMethod(); i++ {
+		m := ifaceType.Method(i)
+		if _, ok := lm[m.Name]; ok {
+			continue
+		}
+		if _, _, _, ok := typ.lookupBinMethod(m.Name); !ok {
+			return nil, fmt.Errorf("implements %s: missing method %s of %v", symbol, m.Name, ifaceType)
+		}
+	}
+	if getWrapper(n, ifaceType) == nil {
+		return nil, fmt.Errorf("implements %s: no interface wrapper for %v", symbol, ifaceType)
+	}
+
+	w := genInterfaceWrapper(n, ifaceType)(interp.frame.Load())
+	if !w.Type().Implements(ifaceType) {
+		return nil, fmt.Errorf("implements %s: invalid interface wrapper for %v", symbol, ifaceType)
+	}
+	return w.Interface(), nil
+}
+
+// implementsFunc returns a host value of interface type ifaceType, whose
+// single method calls the function fn.
+func (interp *Interpreter) implementsFunc(symbol string, fn reflect.Value, ifaceType reflect.Type) (interface{}, error) {
+	if ifaceType.NumMethod() != 1 {
+		return nil, fmt.Errorf("implements %s: %v has %d methods, want 1", symbol, ifaceType, ifaceType.NumMethod())
+	}
+	m := ifaceType.Method(0)
+	if fn.Type() != m.Type {
+		return nil, fmt.Errorf("implements %s: invalid type %v for method %s of %v", symbol, fn.Type(), m.Name, ifaceType)
+	}
+	n := &node{interp: interp, typ: valueTOf(fn.Type())}
+	wrap := getWrapper(n, ifaceType)
+	if wrap == nil {
+		return nil, fmt.Errorf("implements %s: no interface wrapper for %v", symbol, ifaceType)
+	}
+	w := reflect.New(wrap).Elem()
+	w.Field(1).Set(fn)
+	return w.Interface(), nil
+}
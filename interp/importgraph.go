@@ -0,0 +1,130 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// ImportGraph is the dependency graph of the source packages compiled by an
+// interpreter, including the main package of evaluated code. It holds, for
+// each package import path, the imports of source packages by its files,
+// sorted by import path. Imports of binary packages are not part of the
+// graph. The graph is acyclic, as import cycles are rejected.
+type ImportGraph map[string][]Import
+
+// Import is an import of a source package.
+type Import struct {
+	Path string         // import path of the imported package
+	Pos  token.Position // position of the first import spec of the package
+}
+
+// Packages returns the import paths of the packages of g in dependency
+// order: each package comes after the packages it imports. Packages which
+// do not depend on each other are sorted by import path.
+func (g ImportGraph) Packages() []string {
+	paths := map[string]bool{}
+	for p, imports := range g {
+		paths[p] = true
+		for _, imp := range imports {
+			paths[imp.Path] = true
+		}
+	}
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	res := make([]string, 0, len(sorted))
+	visited := map[string]bool{}
+	var visit func(p string)
+	visit = func(p string) {
+		if visited[p] {
+			return
+		}
+		visited[p] = true
+		for _, imp := range g[p] {
+			visit(imp.Path)
+		}
+		res = append(res, p)
+	}
+	for _, p := range sorted {
+		visit(p)
+	}
+	return res
+}
+
+// ImportGraph returns the dependency graph of the source packages compiled
+// by the interpreter.
+func (interp *Interpreter) ImportGraph() ImportGraph {
+	interp.mutex.RLock()
+	defer interp.mutex.RUnlock()
+
+	g := ImportGraph{}
+	for from, imports := range interp.srcImports {
+		// Skip the imports of packages which failed to compile.
+		if interp.scopes[from] == nil {
+			continue
+		}
+		for path, pos := range imports {
+			g[from] = append(g[from], Import{Path: path, Pos: interp.fset.Position(pos)})
+		}
+		sort.Slice(g[from], func(i, j int) bool { return g[from][i].Path < g[from][j].Path })
+	}
+	return g
+}
+
+// importStep is the import of a source package being loaded.
+type importStep struct {
+	from, to string    // import paths of the importing and imported packages
+	pos      token.Pos // position of the import spec
+}
+
+// importSrcFrom loads the source package to, imported by the package from,
+// with an import spec at pos, and records the import in the import graph.
+func (interp *Interpreter) importSrcFrom(from, rPath, to string, pos token.Pos) (string, error) {
+	interp.importing = append(interp.importing, importStep{from: from, to: to, pos: pos})
+	defer func() { interp.importing = interp.importing[:len(interp.importing)-1] }()
+
+	name, err := interp.importSrc(rPath, to, NoTest)
+	if err != nil {
+		return "", err
+	}
+	interp.mutex.Lock()
+	defer interp.mutex.Unlock()
+	if interp.srcImports == nil {
+		interp.srcImports = map[string]map[string]token.Pos{}
+	}
+	if interp.srcImports[from] == nil {
+		interp.srcImports[from] = map[string]token.Pos{}
+	}
+	if _, ok := interp.srcImports[from][to]; !ok {
+		interp.srcImports[from][to] = pos
+	}
+	return name, nil
+}
+
+// importCycle returns an error describing the import cycle formed by loading
+// the source package of importPath, or nil if there is none.
+func (interp *Interpreter) importCycle(importPath string) error {
+	for i, s := range interp.importing {
+		if s.from != importPath {
+			continue
+		}
+		cycle := interp.importing[i:]
+		paths := []string{importPath}
+		for _, s := range cycle {
+			paths = append(paths, s.to)
+		}
+		var sb strings.Builder
+		sb.WriteString("import cycle not allowed: " + strings.Join(paths, " → "))
+		for _, s := range cycle {
+			fmt.Fprintf(&sb, "\n\t%s: %s imports %s", interp.fset.Position(s.pos), s.from, s.to)
+		}
+		return errors.New(sb.String())
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package interp
+
+import (
+	"reflect"
+	"strings"
+)
+
+// A CallInterceptor is invoked around the calls of binary functions and
+// methods by interpreted code, e.g. to audit, rate-limit, mock or deny them.
+// It receives the name of the called symbol, the arguments of the call, and
+// next, which performs the actual call with args and returns its results.
+// The interceptor returns the results of the call, which must match the
+// results of the called function, for example the ones returned by next.
+// It may also panic with an error, which is raised in interpreted code as
+// a runtime panic, to deny the call.
+//
+// The symbol name is qualified by the package import path for functions,
+// e.g. "strings.ToUpper", and by the receiver type for methods, e.g.
+// "(*strings.Builder).WriteString" or "time.Time.Format". For other function
+// values, it is the name of the variable holding the function, if any.
+// Variadic arguments are passed individually, unless the call uses the ...
+// syntax, in which case they are passed as a single slice. Elements of args
+// can be replaced before calling next, to change the arguments of the call.
+//
+// It may be called concurrently, from the goroutines of interpreted code.
+type CallInterceptor func(sym string, args []reflect.Value, next func() []reflect.Value) []reflect.Value
+
+// binCallName returns the name of the binary function or method called by n,
+// as passed to a CallInterceptor.
+func binCallName(n *node) string {
+	c0 := n.child[0]
+	switch c0.kind {
+	case identExpr:
+		return c0.ident
+	case selectorExpr:
+		recv, name := c0.child[0], c0.child[1].ident
+		if recv.typ == nil {
+			return name
+		}
+		if recv.typ.cat == binPkgT {
+			return recv.typ.path + "." + name
+		}
+		t := recv.typ.id()
+		if rt := recv.typ.rtype; recv.typ.cat == valueT && rt != nil && rt.Kind() != reflect.Interface {
+			// Methods of pointer receivers called on addressable values.
+			if _, ok := rt.MethodByName(name); !ok {
+				t = "*" + t
+			}
+		}
+		if strings.HasPrefix(t, "*") {
+			return "(" + t + ")." + name
+		} else if t != "" {
+			return t + "." + name
+		}
+		return name
+	}
+	return ""
+}
+
+// interceptCall returns callFn, the function performing the binary call n,
+// wrapped to be invoked through the call interceptor, if any.
+func interceptCall(n *node, callFn func(uintptr, reflect.Value, []reflect.Value) []reflect.Value) func(uintptr, reflect.Value, []reflect.Value) []reflect.Value {
+	intercept := n.interp.opt.intercept
+	if intercept == nil {
+		return callFn
+	}
+	sym := binCallName(n)
+	return func(callHandle uintptr, v reflect.Value, in []reflect.Value) []reflect.Value {
+		return intercept(sym, in, func() []reflect.Value { return callFn(callHandle, v, in) })
+	}
+}
+
+// interceptDeferred returns the function value v, deferred by the binary call
+// n, wrapped to be invoked through the call interceptor, if any.
+func interceptDeferred(n *node, v reflect.Value) reflect.Value {
+	intercept := n.interp.opt.intercept
+	if intercept == nil {
+		return v
+	}
+	sym := binCallName(n)
+	call, variadic := v.Call, false
+	if v.Type().IsVariadic() {
+		call, variadic = v.CallSlice, n.action != aCallSlice
+	}
+	return reflect.MakeFunc(v.Type(), func(in []reflect.Value) []reflect.Value {
+		if variadic {
+			// Pass variadic arguments individually, as for other calls.
+			last := in[len(in)-1]
+			args := append([]reflect.Value{}, in[:len(in)-1]...)
+			for i := 0; i < last.Len(); i++ {
+				args = append(args, last.Index(i))
+			}
+			return intercept(sym, args, func() []reflect.Value { return v.Call(args) })
+		}
+		return intercept(sym, in, func() []reflect.Value { return call(in) })
+	})
+}
@@ -1,17 +1,15 @@
 package interp
 
 import (
-	"bufio"
 	"context"
-	"errors"
 	"fmt"
 	"go/build"
 	"go/scanner"
 	"go/token"
+	"go/version"
 	"io"
 	"io/fs"
 	"os"
-	"os/signal"
 	"path"
 	"path/filepath"
 	"reflect"
@@ -44,6 +42,9 @@ type node struct {
 	typ        *itype         // type of value in frame, or nil
 	recv       *receiver      // method receiver node for call, or nil
 	types      []reflect.Type // frame types, used by function literals only
+	frames     *framePool     // reusable storage of frame values (func def), or nil
+	limits     *ExecLimits    // execution limits (func def), or nil
+	resources  *resources     // tracked resources (binary call), or nil
 	scope      *scope         // frame scope
 	action     action         // action
 	exec       bltn           // generated function to execute
@@ -109,15 +110,20 @@ type frame struct {
 	id uint64
 
 	debug *frameDebugData
+	trace *frameTrace // trace state, or nil if not traced
+	depth int         // depth of nested interpreted calls
+	call  *node       // call expression of the frame, for stack overflow reports
+	prof  *profCall   // profiled call of the frame, or of its caller, or nil
 
 	root *frame          // global space
 	anc  *frame          // ancestor frame (caller space)
+	src  *frame          // frame of which this one is a clone, or nil
 	data []reflect.Value // values
+	run  *runState       // execution of the frame, or nil
 
 	mutex     sync.RWMutex
-	deferred  [][]reflect.Value  // defer stack
-	recovered interface{}        // to handle panic recover
-	done      reflect.SelectCase // for cancellation of channel operations
+	deferred  [][]reflect.Value // defer stack
+	recovered interface{}       // to handle panic recover
 }
 
 func newFrame(anc *frame, length int, id uint64) *frame {
@@ -129,7 +135,7 @@ func newFrame(anc *frame, length int, id uint64) *frame {
 	if anc == nil {
 		f.root = f
 	} else {
-		f.done = anc.done
+		f.run = anc.run
 		f.root = anc.root
 	}
 	return f
@@ -146,8 +152,12 @@ func (f *frame) clone() *frame {
 		deferred:  f.deferred,
 		recovered: f.recovered,
 		id:        f.runid(),
-		done:      f.done,
 		debug:     f.debug,
+		src:       f,
+		run:       f.run,
+	}
+	if f.src != nil {
+		nf.src = f.src
 	}
 	nf.data = make([]reflect.Value, len(f.data))
 	copy(nf.data, f.data)
@@ -167,19 +177,39 @@ type opt struct {
 	// dotCmd is the command to process the dot graph produced when astDot and/or
 	// cfgDot is enabled. It defaults to 'dot -Tdot -o <filename>.dot'.
 	dotCmd       string
-	context      build.Context     // build context: GOPATH, build constraints
-	stdin        io.Reader         // standard input
-	stdout       io.Writer         // standard output
-	stderr       io.Writer         // standard error
-	args         []string          // cmdline args
-	env          map[string]string // environment of interpreter, entries in form of "key=value"
-	filesystem   fs.FS             // filesystem containing sources
-	astDot       bool              // display AST graph (debug)
-	cfgDot       bool              // display CFG graph (debug)
-	noRun        bool              // compile, but do not run
-	fastChan     bool              // disable cancellable chan operations
-	specialStdio bool              // allows os.Stdin, os.Stdout, os.Stderr to not be file descriptors
-	unrestricted bool              // allow use of non-sandboxed symbols
+	context      build.Context   // build context: GOPATH, build constraints
+	stdin        io.Reader       // standard input
+	stdout       io.Writer       // standard output
+	stderr       io.Writer       // standard error
+	args         []string        // cmdline args
+	env          *environ        // virtual environment of interpreter, or nil for the process one
+	wd           *workdir        // virtual working directory of interpreter, or nil for the process one
+	filesystem   fs.FS           // filesystem containing sources
+	astDot       bool            // display AST graph (debug)
+	cfgDot       bool            // display CFG graph (debug)
+	noRun        bool            // compile, but do not run
+	specialStdio bool            // allows os.Stdin, os.Stdout, os.Stderr to not be file descriptors
+	recStdin     bool            // standard input recorded or replayed, see setRecording
+	unrestricted bool            // allow use of non-sandboxed symbols
+	capabilities []Capability    // capabilities granted to the interpreter
+	unsafe       UnsafePolicy    // allowed uses of the unsafe package
+	syscalls     SyscallTable    // interception of syscall symbols
+	execPolicy   ExecPolicy      // broker of os/exec commands, or nil
+	clock        Clock           // clock backing the time package, or nil for system clock
+	seed         int64           // seed of the math/rand top-level functions, or 0 for the global source
+	renderer     Renderer        // renderer of print builtins and REPL values, or nil for default
+	logSink      LogSink         // receiver of print builtins and log messages, or nil
+	intercept    CallInterceptor // hook around calls of binary functions, or nil
+	maxErrors    int             // maximum number of errors reported by a compilation
+	freeze       map[string]bool // import paths of packages to freeze once compiled
+	testShim     bool            // replace testing.T by a shim, for RunTests
+	limits       CompileLimits   // limits of compiled sources
+	maxCallDepth int             // maximum depth of nested interpreted calls, or 0
+	autoFormat   bool            // format and fix imports of evaluated sources
+	autoImport   bool            // import packages used by evaluated snippets
+	optimize     bool            // enable additional compile time optimizations
+	lazyInit     bool            // defer the initialization of imported source packages
+	goVersion    string          // Go language version of interpreted code, or "" for latest
 }
 
 // Interpreter contains global resources and state.
@@ -197,28 +227,66 @@ type Interpreter struct {
 
 	name string // name of the input source file (or main)
 
-	opt                                         // user settable options
-	cancelChan bool                             // enables cancellable chan operations
-	fset       *token.FileSet                   // fileset to locate node in source code
-	binPkg     Exports                          // binary packages used in interpreter, indexed by path
-	rdir       map[string]bool                  // for src import cycle detection
-	mapTypes   map[reflect.Value][]reflect.Type // special interfaces mapping for wrappers
+	opt                                       // user settable options
+	fset     *token.FileSet                   // fileset to locate node in source code
+	binPkg   Exports                          // binary packages used in interpreter, indexed by path
+	mapTypes map[reflect.Value][]reflect.Type // special interfaces mapping for wrappers
+
+	providers map[string]provided // binary packages not loaded yet, indexed by path, see Lazy
+	cgoStubs  map[string]cgoStub  // replacements of source packages requiring cgo, see UseCgoStubs
 
 	mutex    sync.RWMutex
-	frame    *frame            // program data storage during execution
-	universe *scope            // interpreter global level scope
-	scopes   map[string]*scope // package level scopes, indexed by import path
-	srcPkg   imports           // source packages used in interpreter, indexed by path
-	pkgNames map[string]string // package names, indexed by import path
-	done     chan struct{}     // for cancellation of channel operations
+	compile  sync.Mutex              // serializes compilations and code generations
+	frame    atomic.Pointer[frame]   // global data storage during execution, see resizeFrame
+	universe *scope                  // interpreter global level scope
+	scopes   map[string]*scope       // package level scopes, indexed by import path
+	srcPkg   imports                 // source packages used in interpreter, indexed by path
+	pkgNames map[string]string       // package names, indexed by import path
+	sources  map[string]string       // source of files evaluated by EvalPath, indexed by path, for ReloadPath, guarded by compile
+	decls    map[*node]*declInfo     // signatures and docs of package level declarations, for Describe
+	runs     map[*runCancel]struct{} // runs started with a context, stopped by Shutdown
+	pkgRun   *runState               // run of the package initializations by EvalPath, or nil
+	noCancel bool                    // the top-level code compiled runs in a run which can not be cancelled, see chanGen
 	roots    []*node
 	generic  map[string]*node
 
-	hooks *hooks // symbol hooks
+	frozen map[string]map[string]bool // frozen symbol names, indexed by package import path
+	tested []string                   // import paths of packages evaluated by EvalTest, for RunBench
+
+	evals int                     // number of compilations, to identify type versions
+	types map[string]*typeVersion // last version of global defined types, by qualified name
+
+	proxies proxies // plain Go types mirroring interpreted structs, see ExportType
+
+	logFuncs   map[reflect.Value]func(token.Position) reflect.Value // log functions bound to a call position, see sinkLog
+	scopedSyms map[reflect.Value]scopedSym                          // symbols writing to the output scope of runs, see WithOutput
+
+	importing  []importStep                    // imports of source packages being loaded, for cycle detection
+	srcImports map[string]map[string]token.Pos // positions of source imports, by importing and imported path
 
-	debugger *Debugger
-	calls    map[uintptr]*node // for translating runtime stacktrace, see FilterStack()
-	panics   []*Panic          // list of panics we have had, see GetOldestPanicForErr()
+	lazy     map[string]*lazyPkg // pending initializations of source packages, see LazyInit
+	lazyUsed []string            // lazy packages used by compiled code, initialized before it runs
+
+	hooks    *hooks                   // symbol hooks
+	signals  *signals                 // signal handlers of interpreted code
+	shutdown *shutdown                // listeners and cleanups released by Shutdown
+	cover    *coverage                // execution counts of nodes, if coverage is enabled
+	stats    bool                     // collect the resource usage of runs, see CollectStats
+	profiler atomic.Pointer[profiler] // profiler, or nil
+	tracer   atomic.Pointer[tracer]   // trace event handler, or nil
+	yielder  atomic.Pointer[yielder]  // periodic hooks of the execution, or nil
+
+	scheduler *Scheduler // scheduler of the evaluations, or nil
+	yieldHook *yieldHook // hook set by SetYieldHook, or nil
+
+	traceFrames atomic.Uint64 // last traced frame identifier
+	methods     atomic.Uint64 // generation of declared methods, to invalidate method caches
+
+	debugger    *Debugger
+	calls       map[uintptr]*node // for translating runtime stacktrace, see FilterStack()
+	callsMutex  sync.RWMutex
+	panics      []*Panic // list of panics we have had, see GetOldestPanicForErr()
+	panicsMutex sync.Mutex
 }
 
 const (
@@ -283,8 +351,22 @@ type Options struct {
 	// BuildTags sets build constraints for the interpreter.
 	BuildTags []string
 
+	// GOOS and GOARCH set the target operating system and architecture
+	// emulated for interpreted code, e.g. to exercise the Windows specific
+	// logic of scripts on a Linux host. They default to the host ones.
+	// They are used to evaluate the build constraints and file name
+	// suffixes of source files, and set the runtime.GOOS and runtime.GOARCH
+	// values. The path separators, and the lexical path operations of the
+	// path/filepath package, follow the target operating system, while the
+	// functions accessing files still operate on the host.
+	GOOS   string
+	GOARCH string
+
 	// Standard input, output and error streams.
 	// They default to os.Stdin, os.Stdout and os.Stderr respectively.
+	// On WebAssembly (js and wasip1), the os.Stdin, os.Stdout and os.Stderr
+	// variables of interpreted code are these streams, even if they are not
+	// files. The output of an evaluation can be redirected with WithOutput.
 	Stdin          io.Reader
 	Stdout, Stderr io.Writer
 
@@ -292,36 +374,195 @@ type Options struct {
 	Args []string
 
 	// Environment of interpreter. Entries are in the form "key=values".
+	// The environment functions of the os package operate on a copy of it
+	// private to the interpreter, which is not visible to the process. In
+	// unrestricted mode, a nil Env gives access to the process environment
+	// instead.
 	Env []string
 
+	// Dir is the initial working directory of interpreted code. It is
+	// virtual: os.Chdir changes it for the interpreter only, and the relative
+	// paths given to the file functions of the os, io/ioutil and path/filepath
+	// packages are resolved against it, so concurrent interpreters do not
+	// interfere. Files opened on a relative path keep it as their name.
+	// It defaults to the working directory of the process at New.
+	//
+	// In restricted mode, the default, the working directory is always
+	// virtual: os.Chdir no longer changes the working directory of the
+	// process, and os.Getwd does not see its later changes. In unrestricted
+	// mode, an empty Dir gives access to the working directory of the
+	// process instead.
+	Dir string
+
 	// SourcecodeFilesystem is where the _sourcecode_ is loaded from and does
 	// NOT affect the filesystem of scripts when they run.
 	// It can be any fs.FS compliant filesystem (e.g. embed.FS, or fstest.MapFS for testing)
 	// See example/fs/fs_test.go for an example.
 	SourcecodeFilesystem fs.FS
 
-	// Unrestricted allows to run non sandboxed stdlib symbols such as os/exec and environment
+	// Unrestricted allows to run non sandboxed stdlib symbols such as os/exec and
+	// environment, unless Env is set.
 	Unrestricted bool
+
+	// Capabilities are the capabilities granted to the interpreter. They
+	// control which packages of a Registry are made available by UseRegistry.
+	Capabilities []Capability
+
+	// Unsafe is the policy of use of the unsafe package by interpreted code.
+	// By default, the import of unsafe is rejected, even if its symbols are
+	// provided with Use. See UnsafePolicy.
+	Unsafe UnsafePolicy
+
+	// Syscalls stubs or denies the symbols of the syscall and
+	// golang.org/x/sys packages used by interpreted code. See SyscallTable.
+	Syscalls SyscallTable
+
+	// ExecPolicy, if not nil, makes the os/exec package available to
+	// interpreted code, even in restricted mode, and allows, rewrites or
+	// denies the commands it creates. See ExecAllowlist for a policy
+	// allowing a list of programs.
+	ExecPolicy ExecPolicy
+
+	// Clock, if not nil, replaces the system clock for the time functions
+	// of interpreted code. See Clock for the list of affected functions.
+	Clock Clock
+
+	// Record, if not nil, captures in it the nondeterministic inputs of
+	// interpreted code, to replay its run with Replay. See Recording.
+	Record *Recording
+
+	// Replay, if not nil, feeds interpreted code with the inputs captured in
+	// a recording by Record, instead of the actual ones, e.g. to reproduce
+	// a failure. It takes precedence over Record, Args, Env and Stdin.
+	Replay *Recording
+
+	// Renderer, if not nil, formats the values displayed by the print and
+	// println builtins, and the results displayed by the REPL.
+	Renderer Renderer
+
+	// LogSink, if not nil, receives the messages of the print and println
+	// builtins and of the log package functions of interpreted code, with
+	// the position of their call, instead of the standard output and error.
+	LogSink LogSink
+
+	// CallInterceptor, if not nil, is invoked around each call of a binary
+	// function or method by interpreted code, to observe, replace or deny
+	// the call.
+	CallInterceptor CallInterceptor
+
+	// MaxErrors is the maximum number of errors reported by a single
+	// compilation. If greater than 1, the compilation of a source file
+	// continues after an error in a function body, and the independent
+	// errors of the file are reported together in a CompileError.
+	// By default, the compilation stops at the first error.
+	MaxErrors int
+
+	// Coverage enables the recording of code coverage, which can be
+	// retrieved with the Coverage method. It slows down the execution.
+	Coverage bool
+
+	// CollectStats enables the collection of the resource usage of the
+	// executions of code, which can be retrieved with EvalWithStats and
+	// Run.Stats. It slows down the execution.
+	CollectStats bool
+
+	// TestShim replaces the testing.T, testing.F and testing.TB types of the
+	// testing package with a shim, in order to run the tests of packages
+	// evaluated by EvalTest with RunTests and RunFuzz, outside of a "go test"
+	// binary. The test functions are then incompatible with testing.Main.
+	TestShim bool
+
+	// AutoFormat formats the sources given to Eval and adds their missing
+	// imports before their evaluation, as Format does, so pasted or
+	// generated code with missing imports still runs. Positions in errors
+	// refer to the formatted source.
+	AutoFormat bool
+
+	// AutoImport imports the packages used without import by the snippets
+	// given to Eval, e.g. fmt for "fmt.Println(1)", among the binary and
+	// source packages known by the interpreter, before their evaluation.
+	// Unlike ImportUsed, packages are imported on demand, including those
+	// added by Use afterwards, and a package name shared by several
+	// packages, e.g. rand, is resolved by the symbols used. Files with a
+	// package clause are not affected.
+	AutoImport bool
+
+	// MaxCallDepth is the maximum depth of nested interpreted calls, or 0
+	// for no limit. A call exceeding it panics with a StackOverflowError,
+	// which can be recovered by interpreted code, instead of overflowing
+	// the stack of the host goroutine, which crashes the process. The
+	// depth is counted as in ExecLimits.
+	MaxCallDepth int
+
+	// CompileLimits are the limits of the size and complexity of compiled
+	// sources. Compiling a source exceeding them fails with a
+	// CompileLimitError.
+	CompileLimits CompileLimits
+
+	// FreezePackages lists the import paths of source packages, e.g. "main",
+	// to freeze after their first successful compilation. See Freeze.
+	FreezePackages []string
+
+	// Optimize enables additional optimizations at compilation, to reduce
+	// the nodes executed at runtime: logical expressions decided by
+	// constants are folded, as the branches they make dead, and switch
+	// statements on constants jump directly to the selected clause.
+	// Constant arithmetic expressions and conditions are always folded.
+	//
+	// Calls to functions which only forward their parameters to another
+	// function are replaced by direct calls to the final function, and tail
+	// calls of functions to themselves reuse the frame of the caller. The
+	// elided calls do not appear in stack traces, nor count in the call depth.
+	Optimize bool
+
+	// LazyInit defers the initialization of the source packages imported by
+	// interpreted code, i.e. the evaluation of their package vars and the
+	// execution of their init functions, until code using one of their
+	// symbols is compiled by an evaluation or a main package, and is about to
+	// run, or until their symbols are retrieved by Symbols. Blank imports
+	// count as a use. The packages imported by a package are initialized
+	// before it. It reduces the startup time of large trees of packages of
+	// which only a few are used, but departs from Go, where all imported
+	// packages are initialized first.
+	LazyInit bool
+
+	// GoVersion is the Go language version of the interpreted code, e.g.
+	// "go1.21", as set by the go directive of a go.mod file, so the behavior
+	// of scripts does not change with upgrades of the interpreter. The
+	// language features introduced after this version are rejected: the
+	// min, max and clear builtins (go1.21), range over integers (go1.22) and
+	// range over functions (go1.23). Before go1.22, the variables declared
+	// by a for statement are shared by all iterations instead of being
+	// created anew at each iteration. Files with build constraints on later
+	// versions are ignored. It defaults to the latest version.
+	GoVersion string
 }
 
 // New returns a new interpreter.
 func New(options Options) *Interpreter {
 	i := Interpreter{
-		opt:      opt{context: build.Default, filesystem: &realFS{}, env: map[string]string{}},
-		frame:    newFrame(nil, 0, 0),
-		fset:     token.NewFileSet(),
-		universe: initUniverse(),
-		scopes:   map[string]*scope{},
-		binPkg:   Exports{"": map[string]reflect.Value{"_error": reflect.ValueOf((*_error)(nil))}},
-		mapTypes: map[reflect.Value][]reflect.Type{},
-		srcPkg:   imports{},
-		pkgNames: map[string]string{},
-		rdir:     map[string]bool{},
-		hooks:    &hooks{},
-		calls:    map[uintptr]*node{},
-		panics:   []*Panic{},
-		generic:  map[string]*node{},
+		opt:        opt{context: build.Default, filesystem: &realFS{}},
+		fset:       token.NewFileSet(),
+		universe:   initUniverse(),
+		scopes:     map[string]*scope{},
+		binPkg:     Exports{"": map[string]reflect.Value{"_error": reflect.ValueOf((*_error)(nil))}},
+		mapTypes:   map[reflect.Value][]reflect.Type{reflect.ValueOf((*_error)(nil)): errorWrappers},
+		logFuncs:   map[reflect.Value]func(token.Position) reflect.Value{},
+		scopedSyms: map[reflect.Value]scopedSym{},
+		runs:       map[*runCancel]struct{}{},
+		srcPkg:     imports{},
+		pkgNames:   map[string]string{},
+		sources:    map[string]string{},
+		decls:      map[*node]*declInfo{},
+		types:      map[string]*typeVersion{},
+		frozen:     map[string]map[string]bool{},
+		hooks:      &hooks{},
+		shutdown:   &shutdown{},
+		calls:      map[uintptr]*node{},
+		panics:     []*Panic{},
+		generic:    map[string]*node{},
 	}
+	i.frame.Store(newFrame(nil, 0, 0))
 
 	if i.opt.stdin = options.Stdin; i.opt.stdin == nil {
 		i.opt.stdin = os.Stdin
@@ -339,28 +580,82 @@ func New(options Options) *Interpreter {
 		i.opt.args = os.Args
 	}
 
-	// unrestricted allows to use non sandboxed stdlib symbols and env.
-	if options.Unrestricted {
-		i.opt.unrestricted = true
-	} else {
-		for _, e := range options.Env {
-			a := strings.SplitN(e, "=", 2)
-			if len(a) == 2 {
-				i.opt.env[a[0]] = a[1]
-			} else {
-				i.opt.env[a[0]] = ""
-			}
+	// unrestricted allows to use non sandboxed stdlib symbols and, if no env
+	// is given, the process env.
+	i.opt.unrestricted = options.Unrestricted
+	i.signals = newSignals(options.Unrestricted)
+	if !options.Unrestricted || options.Env != nil {
+		i.opt.env = newEnviron(options.Env)
+	}
+
+	if !options.Unrestricted || options.Dir != "" {
+		dir, err := filepath.Abs(options.Dir)
+		if err != nil {
+			dir = options.Dir
 		}
+		i.opt.wd = &workdir{dir: dir}
 	}
 
+	i.opt.capabilities = append([]Capability{}, options.Capabilities...)
+	i.opt.unsafe = options.Unsafe
+	i.opt.syscalls = options.Syscalls
+	i.opt.execPolicy = options.ExecPolicy
+	i.opt.clock = options.Clock
+	if options.Replay != nil {
+		i.setRecording(options.Replay, true)
+	} else if options.Record != nil {
+		i.setRecording(options.Record, false)
+	}
+	i.opt.renderer = options.Renderer
+	i.opt.logSink = options.LogSink
+	i.opt.intercept = options.CallInterceptor
+	i.opt.maxErrors = options.MaxErrors
+	i.opt.testShim = options.TestShim
+	i.opt.limits = options.CompileLimits
+	i.opt.maxCallDepth = options.MaxCallDepth
+	i.opt.autoFormat = options.AutoFormat
+	i.opt.autoImport = options.AutoImport
+	i.opt.optimize = options.Optimize
+	i.opt.lazyInit = options.LazyInit
+	i.opt.freeze = map[string]bool{}
+	for _, p := range options.FreezePackages {
+		i.opt.freeze[p] = true
+	}
+	if options.Coverage {
+		i.cover = &coverage{}
+	}
+	i.stats = options.CollectStats
+
 	if options.SourcecodeFilesystem != nil {
 		i.opt.filesystem = options.SourcecodeFilesystem
 	}
 
 	i.opt.context.GOPATH = options.GoPath
+	i.opt.context.CgoEnabled = false // no "C" package
+	if v := options.GoVersion; v != "" {
+		if !strings.HasPrefix(v, "go") {
+			v = "go" + v
+		}
+		if version.IsValid(v) {
+			i.opt.goVersion = v
+			var tags []string
+			for _, t := range i.opt.context.ReleaseTags {
+				if version.Compare(t, v) <= 0 {
+					tags = append(tags, t)
+				}
+			}
+			i.opt.context.ReleaseTags = tags
+		}
+	}
 	if len(options.BuildTags) > 0 {
 		i.opt.context.BuildTags = options.BuildTags
 	}
+	if options.GOOS != "" {
+		i.opt.context.GOOS = options.GOOS
+	}
+	if options.GOARCH != "" {
+		i.opt.context.GOARCH = options.GOARCH
+	}
 
 	// astDot activates AST graph display for the interpreter
 	i.opt.astDot, _ = strconv.ParseBool(os.Getenv("YAEGI_AST_DOT"))
@@ -376,12 +671,12 @@ func New(options Options) *Interpreter {
 	// noRun disables the execution (but not the compilation) in the interpreter
 	i.opt.noRun, _ = strconv.ParseBool(os.Getenv("YAEGI_NO_RUN"))
 
-	// fastChan disables the cancellable version of channel operations in evalWithContext
-	i.opt.fastChan, _ = strconv.ParseBool(os.Getenv("YAEGI_FAST_CHAN"))
-
 	// specialStdio allows to assign directly io.Writer and io.Reader to os.Stdxxx,
-	// even if they are not file descriptors.
-	i.opt.specialStdio, _ = strconv.ParseBool(os.Getenv("YAEGI_SPECIAL_STDIO"))
+	// even if they are not file descriptors. It is the default on WebAssembly.
+	i.opt.specialStdio = defaultSpecialStdio
+	if s, ok := os.LookupEnv("YAEGI_SPECIAL_STDIO"); ok {
+		i.opt.specialStdio, _ = strconv.ParseBool(s)
+	}
 
 	return &i
 }
@@ -390,6 +685,7 @@ const (
 	bltnAlignof  = "unsafe.Alignof"
 	bltnAppend   = "append"
 	bltnCap      = "cap"
+	bltnClear    = "clear"
 	bltnClose    = "close"
 	bltnComplex  = "complex"
 	bltnImag     = "imag"
@@ -397,6 +693,8 @@ const (
 	bltnDelete   = "delete"
 	bltnLen      = "len"
 	bltnMake     = "make"
+	bltnMax      = "max"
+	bltnMin      = "min"
 	bltnNew      = "new"
 	bltnOffsetof = "unsafe.Offsetof"
 	bltnPanic    = "panic"
@@ -445,6 +743,7 @@ func initUniverse() *scope {
 		// predefined Go builtins
 		bltnAppend:  {kind: bltnSym, builtin: _append},
 		bltnCap:     {kind: bltnSym, builtin: _cap},
+		bltnClear:   {kind: bltnSym, builtin: _clear},
 		bltnClose:   {kind: bltnSym, builtin: _close},
 		bltnComplex: {kind: bltnSym, builtin: _complex},
 		bltnImag:    {kind: bltnSym, builtin: _imag},
@@ -452,39 +751,99 @@ func initUniverse() *scope {
 		bltnDelete:  {kind: bltnSym, builtin: _delete},
 		bltnLen:     {kind: bltnSym, builtin: _len},
 		bltnMake:    {kind: bltnSym, builtin: _make},
+		bltnMax:     {kind: bltnSym, builtin: _max},
+		bltnMin:     {kind: bltnSym, builtin: _min},
 		bltnNew:     {kind: bltnSym, builtin: _new},
 		bltnPanic:   {kind: bltnSym, builtin: _panic},
 		bltnPrint:   {kind: bltnSym, builtin: _print},
 		bltnPrintln: {kind: bltnSym, builtin: _println},
 		bltnReal:    {kind: bltnSym, builtin: _real},
 		bltnRecover: {kind: bltnSym, builtin: _recover},
+
+		// initializer of variables declared with a go:embed directive
+		embedIdent: {kind: binSym, typ: valueTOf(reflect.TypeOf(newEmbedFS)), rval: reflect.ValueOf(newEmbedFS)},
 	}}
 	return sc
 }
 
-// resizeFrame resizes the global frame of interpreter.
+// goVersionAtLeast returns true if the language features of the Go version v
+// are enabled by the GoVersion option.
+func (interp *Interpreter) goVersionAtLeast(v string) bool {
+	return interp.opt.goVersion == "" || version.Compare(interp.opt.goVersion, v) >= 0
+}
+
+// checkGoVersion returns an error at n if the feature, introduced by the Go
+// version v, is not enabled by the GoVersion option.
+func (interp *Interpreter) checkGoVersion(n *node, feature, v string) error {
+	if interp.goVersionAtLeast(v) {
+		return nil
+	}
+	return n.cfgErrorf("%s requires %s or later (GoVersion is %s)", feature, v, interp.opt.goVersion)
+}
+
+// resizeFrame resizes the global frame of interpreter. It must be called
+// with interp.compile locked.
+//
+// The global frame may be in use by concurrent executions, so it is not
+// modified: a new global frame is created instead, for the next executions.
+// It shares the values of global variables with the previous one. The other
+// values, temporaries of top level statements, are private to each program
+// and are allocated again.
 func (interp *Interpreter) resizeFrame() {
+	f := interp.frame.Load()
 	l := len(interp.universe.types)
-	b := len(interp.frame.data)
+	b := len(f.data)
 	if l-b <= 0 {
 		return
 	}
-	data := make([]reflect.Value, l)
-	copy(data, interp.frame.data)
-	for j, t := range interp.universe.types[b:] {
-		data[b+j] = reflect.New(t).Elem()
+
+	shared := map[int]bool{}
+	for _, sc := range interp.scopes {
+		for _, sym := range sc.sym {
+			if sym.kind == varSym && sym.index >= 0 {
+				shared[sym.index] = true
+			}
+		}
 	}
-	interp.frame.data = data
+
+	f.mutex.RLock()
+	nf := &frame{id: f.runid(), debug: f.debug, trace: f.trace}
+	f.mutex.RUnlock()
+	nf.root = nf
+	nf.data = make([]reflect.Value, l)
+	for i, t := range interp.universe.types {
+		if i < b && shared[i] {
+			nf.data[i] = f.data[i]
+			continue
+		}
+		nf.data[i] = reflect.New(t).Elem()
+	}
+	interp.frame.Store(nf)
 }
 
 // Add a call with handle that we recognize and can filter from the stacktrace
 // Need to make sure this never overlaps with real PCs from runtime.Callers
 func (interp *Interpreter) addCall(n *node) uintptr {
 	handle := reflect.ValueOf(n).Pointer()
-	interp.calls[handle] = n
+	interp.callsMutex.RLock()
+	_, ok := interp.calls[handle]
+	interp.callsMutex.RUnlock()
+	if !ok {
+		interp.callsMutex.Lock()
+		interp.calls[handle] = n
+		interp.callsMutex.Unlock()
+	}
 	return handle
 }
 
+// callNode returns the node of the call identified by handle, if any.
+func (interp *Interpreter) callNode(handle uintptr) (*node, bool) {
+	interp.callsMutex.RLock()
+	defer interp.callsMutex.RUnlock()
+	n, ok := interp.calls[handle]
+	return n, ok
+}
+
 // Return func name as it appears in go stacktraces
 func funcName(n *node) string {
 	if n.scope == nil || n.scope.def == nil {
@@ -593,7 +952,7 @@ type IFunc interface {
 
 // return call if we know it, pass to runtime.FuncForPC otherwise
 func (interp *Interpreter) FuncForPC(handle uintptr) IFunc {
-	n, ok := interp.calls[handle]
+	n, ok := interp.callNode(handle)
 	if !ok {
 		return runtime.FuncForPC(handle)
 	}
@@ -605,198 +964,16 @@ func (interp *Interpreter) FuncForPC(handle uintptr) IFunc {
 	}
 }
 
-func (interp *Interpreter) FilteredStack() []byte {
-	return interp.FilterStack(debug.Stack())
-}
-
-func (interp *Interpreter) FilteredCallers() []uintptr {
-	pc := make([]uintptr, 64)
-	runtime.Callers(0, pc)
-	_, fPc := interp.FilterStackAndCallers(debug.Stack(), pc, 2)
-	return fPc
-}
-
-func (interp *Interpreter) FilterStack(stack []byte) []byte {
-	newStack, _ := interp.FilterStackAndCallers(stack, []uintptr{}, 2)
-	return newStack
-}
-
-// Given a runtime stacktrace and callers list, filter out the interpreter runtime
-// and replace it with the interpreted calls. Parses runtime stacktrace to figure
-// out which interp node by placing a magic value in parameters to runCfg and callBin
-func (interp *Interpreter) FilterStackAndCallers(stack []byte, callers []uintptr, skip int) ([]byte, []uintptr) {
-	newFrames := [][]string{}
-	newCallers := []uintptr{}
-
-	stackLines := strings.Split(string(stack), "\n")
-	lastFrame := len(stackLines)
-	skipFrame := 0
-
-	const (
-		notSyncedYet = -1
-		dontSync     = -2
-	)
-
-	// index to copy over from callers into newCallers
-	callersIndex := notSyncedYet // to indicate we haven't synced up with stack yet
-	if len(callers) == 0 {
-		callersIndex = dontSync // don't attempt to copy over from callers
-	}
-
-	// Parse stack in reverse order, because sometimes we want to skip frames
-	var lastInterpFrame int
-	for i := len(stackLines) - 1; i >= 0; i-- {
-		// Split stack trace into paragraphs (frames)
-		if len(stackLines[i]) == 0 || stackLines[i][0] == '\t' {
-			continue
-		}
-
-		if callersIndex > 0 {
-			callersIndex--
-		}
-
-		if skipFrame > 0 {
-			lastFrame = i
-			skipFrame--
-			continue
-		}
-
-		p := stackLines[i:lastFrame] // all lines in single frame
-		lastFrame = i
-
-		lastSlash := strings.LastIndex(p[0], "/")
-		funcPath := strings.Split(p[0][lastSlash+1:], ".")
-		pkgName := p[0][0:lastSlash+1] + funcPath[0]
-
-		if callersIndex >= 0 {
-			callName := runtime.FuncForPC(callers[callersIndex]).Name()
-			if callName != strings.Split(p[0], "(")[0] {
-				// for some reason runtime.gopanic shows up as panic in stacktrace
-				if callName != "runtime.gopanic" || strings.Split(p[0], "(")[0] != "panic" {
-					// since we're walking stack and callers at the same time they
-					// should be in sync. If not, we stop messing with callers
-					for ; callersIndex >= 0; callersIndex-- {
-						newCallers = append(newCallers, callers[callersIndex])
-					}
-					callersIndex = dontSync
-				}
-			}
-		}
-
-		// Don't touch any stack frames that aren't in the yaegi runtime
-		// Functions called on (*Interpreter) may provide information
-		// on how we entered yaegi, so we pass these through as well
-		if pkgName != selfPrefix+"/interp" || funcPath[1] == "(*Interpreter)" {
-			newFrames = append(newFrames, p)
-			if callersIndex >= 0 {
-				newCallers = append(newCallers, callers[callersIndex])
-			}
-			continue
-		}
-
-		// This is the first call into the interpreter, so try to sync callers
-		if callersIndex == notSyncedYet {
-			for j, call := range callers {
-				if call == 0 {
-					break
-				}
-				callName := runtime.FuncForPC(call).Name()
-				if callName == strings.Split(p[0], "(")[0] {
-					callersIndex = j
-				}
-			}
-			for j := len(callers) - 1; j > callersIndex; j-- {
-				if callers[j] != 0 {
-					newCallers = append(newCallers, callers[j])
-				}
-			}
-		}
-
-		var handle uintptr
-		originalExecNode := false
-
-		// A runCfg call refers to an interpreter level call
-		// grab callHandle from the first parameter to it
-		if strings.HasPrefix(funcPath[1], "runCfg(") {
-			fmt.Sscanf(funcPath[1], "runCfg(%v,", &handle)
-		}
-
-		// capture node that panicked
-		if strings.HasPrefix(funcPath[1], "runCfgPanic(") {
-			fmt.Sscanf(funcPath[1], "runCfgPanic(%v,", &handle)
-			originalExecNode = true
-		}
-
-		// callBin is a call to a binPkg
-		// the callHandle will be on the first or second function literal
-		if funcPath[1] == "callBin" &&
-			(strings.HasPrefix(funcPath[2], "func1(") ||
-				strings.HasPrefix(funcPath[2], "func2(")) {
-			fmt.Sscanf(strings.Split(funcPath[2], "(")[1], "%v,", &handle)
-			// after a binary call, the next two frames will be reflect.Value.Call
-			skipFrame = 2
-		}
-
-		if handle != 0 {
-			if callersIndex >= 0 {
-				newCallers = append(newCallers, handle)
-			}
-			n, ok := interp.calls[handle]
-
-			// Don't print scopes that weren't function calls
-			// (unless they're the node that caused the panic)
-			if !ok || (n.kind != callExpr && !originalExecNode) {
-				continue
-			}
-
-			pos := n.interp.fset.Position(n.pos)
-			newFrame := []string{
-				funcName(n) + "()",
-				fmt.Sprintf("\t%s", pos),
-			}
-
-			// we only find originalExecNode a few frames later
-			// so place it right after the last interpreted frame
-			if originalExecNode && len(newFrames) != lastInterpFrame {
-				newFrames = append(
-					newFrames[:lastInterpFrame+1],
-					newFrames[lastInterpFrame:]...)
-				newFrames[lastInterpFrame] = newFrame
-			} else {
-				newFrames = append(newFrames, newFrame)
-			}
-			lastInterpFrame = len(newFrames)
-		}
-	}
-
-	// reverse order because we parsed from bottom up, fix that now.
-	newStack := []string{}
-	newStack = append(newStack, newFrames[len(newFrames)-1]...) // skip after goroutine id
-	for i := len(newFrames) - 2 - skip; i >= 0; i-- {
-		newStack = append(newStack, newFrames[i]...)
-	}
-	unreversedNewCallers := []uintptr{}
-	if len(newCallers) == 0 {
-		if len(callers) >= skip {
-			unreversedNewCallers = callers[skip:] // just pass the original through
-		}
-	} else {
-		for i := len(newCallers) - 1 - skip; i >= 0; i-- {
-			unreversedNewCallers = append(unreversedNewCallers, newCallers[i])
-		}
-	}
-
-	newStackJoined := strings.Join(newStack, "\n")
-	newStackBytes := make([]byte, len(newStackJoined)-1)
-	copy(newStackBytes, newStackJoined)
-	return newStackBytes, unreversedNewCallers
-}
-
 // Panic is an error recovered from a panic call in interpreted code.
 type Panic struct {
-	// Value is the recovered value of a call to panic.
+	// Value is the recovered value of a call to panic. A value of an
+	// interpreted type implementing error is wrapped into a binary value
+	// implementing error, whose Error method calls the interpreted one.
 	Value interface{}
 
+	raw   interface{} // value as raised, to match it in GetOldestPanicForErr
+	nodes []*node     // nodes executed by the unwound interpreted frames, innermost first
+
 	// Callers is the call stack obtained from the recover call.
 	// It may be used as the parameter to runtime.CallersFrames.
 	Callers []uintptr
@@ -813,55 +990,119 @@ func (e Panic) Error() string {
 	return fmt.Sprintf("panic: %s\n%s\n", e.Value, e.FilteredStack)
 }
 
+// Unwrap returns the value of the panic if it is an error, so that errors.Is
+// and errors.As inspect the error chain of the panic value.
+func (e Panic) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// exportPanic returns the value r of a panic raised by interpreted code, as
+// exposed to the host in Panic.Value.
+func (interp *Interpreter) exportPanic(r interface{}) interface{} {
+	v, ok := r.(reflect.Value)
+	if !ok || !v.IsValid() || !v.CanInterface() {
+		return r
+	}
+	if vi, ok := v.Interface().(valueInterface); ok && vi.node != nil && vi.value.IsValid() {
+		if m, _ := vi.node.typ.lookupMethod("Error"); m != nil {
+			if w := interfaceWrapper(vi.node, errorType); w != nil {
+				return w(vi.value, interp.frame.Load()).Interface()
+			}
+		}
+	}
+	return panicValue(r)
+}
+
 // Store a panic record if this is an error we have not seen.
 // Not strictly correct: code might recover from err and never
 // call GetOldestPanicForErr(), and we later return the wrong one.
 func (interp *Interpreter) Panic(err interface{}) {
-	if len(interp.panics) > 0 && interp.panics[len(interp.panics)-1].Value == err {
+	interp.panicsMutex.Lock()
+	defer interp.panicsMutex.Unlock()
+	if len(interp.panics) > 0 && interp.panics[len(interp.panics)-1].raw == err {
 		return
 	}
 	pc := make([]uintptr, 64)
 	runtime.Callers(0, pc)
-	stack := debug.Stack()
-	fStack, fPc := interp.FilterStackAndCallers(stack, pc, 2)
 	interp.panics = append(interp.panics, &Panic{
-		Value:           err,
-		Callers:         pc,
-		Stack:           stack,
-		FilteredCallers: fPc,
-		FilteredStack:   fStack,
+		Value:   interp.exportPanic(err),
+		raw:     err,
+		Callers: pc,
+		Stack:   debug.Stack(),
 	})
 }
 
+// unwind records that the panic err unwinds an interpreted frame, in which
+// node n was executed, i.e. the node which raised the panic for the
+// innermost frame, and the call of the next frame for the others.
+func (interp *Interpreter) unwind(err interface{}, n *node) {
+	interp.panicsMutex.Lock()
+	defer interp.panicsMutex.Unlock()
+	if k := len(interp.panics); k > 0 && interp.panics[k-1].raw == err {
+		p := interp.panics[k-1]
+		p.nodes = append(p.nodes, n)
+	}
+}
+
+// filter sets the filtered stack and callers of the panic p, from the
+// interpreted frames recorded at its unwinding.
+func (interp *Interpreter) filter(p *Panic) {
+	if p.FilteredStack != nil {
+		return
+	}
+	if soe, ok := p.raw.(*StackOverflowError); ok {
+		// The interpreted calls are elided from the Go traceback.
+		_, p.FilteredCallers = interp.filterStack(p.Stack, p.Callers, 2, p.nodes)
+		p.FilteredStack = soe.Stack
+		return
+	}
+	p.FilteredStack, p.FilteredCallers = interp.filterStack(p.Stack, p.Callers, 2, p.nodes)
+}
+
 // We want to capture the full stacktrace from where the panic originated.
 // Return oldest panic that matches err. Then, clear out the list of panics.
 func (interp *Interpreter) GetOldestPanicForErr(err interface{}) *Panic {
 	if _, ok := err.(*Panic); ok {
 		return err.(*Panic)
 	}
+	interp.panicsMutex.Lock()
+	defer interp.panicsMutex.Unlock()
 	r := (*Panic)(nil)
 	for i := len(interp.panics) - 1; i >= 0; i-- {
-		if interp.panics[i].Value == err {
+		if interp.panics[i].raw == err {
 			r = interp.panics[i]
 			break
 		}
 	}
 	interp.panics = []*Panic{}
+	if r != nil {
+		interp.filter(r)
+	}
 	return r
 }
 
 // Eval evaluates Go code represented as a string. Eval returns the last result
 // computed by the interpreter, and a non nil error in case of failure.
 func (interp *Interpreter) Eval(src string) (res reflect.Value, err error) {
-	return interp.eval(src, "", true)
+	return interp.eval(src, "", true, nil)
 }
 
 // EvalPath evaluates Go code located at path and returns the last result computed
 // by the interpreter, and a non nil error in case of failure.
 // The main function of the main package is executed if present.
 func (interp *Interpreter) EvalPath(path string) (res reflect.Value, err error) {
+	return interp.evalPath(path, nil)
+}
+
+// evalPath evaluates Go code located at path as EvalPath, in run r.
+func (interp *Interpreter) evalPath(path string, r *runState) (res reflect.Value, err error) {
 	path = filepath.ToSlash(path) // Ensure path is in Unix format. Since we work with fs.FS, we need to use Unix path.
 	if !isFile(interp.opt.filesystem, path) {
+		interp.compile.Lock()
+		defer interp.compile.Unlock()
+		interp.pkgRun = r
+		defer func() { interp.pkgRun = nil }()
 		_, err := interp.importSrc(mainID, path, NoTest)
 		return res, err
 	}
@@ -870,31 +1111,20 @@ func (interp *Interpreter) EvalPath(path string) (res reflect.Value, err error)
 	if err != nil {
 		return res, err
 	}
-	return interp.eval(string(b), path, false)
+	interp.compile.Lock()
+	interp.sources[path] = string(b)
+	interp.compile.Unlock()
+	return interp.eval(string(b), path, false, r)
 }
 
 // EvalPathWithContext evaluates Go code located at path and returns the last
 // result computed by the interpreter, and a non nil error in case of failure.
 // The main function of the main package is executed if present.
 func (interp *Interpreter) EvalPathWithContext(ctx context.Context, path string) (res reflect.Value, err error) {
-	interp.mutex.Lock()
-	interp.done = make(chan struct{})
-	interp.cancelChan = !interp.opt.fastChan
-	interp.mutex.Unlock()
-
-	done := make(chan struct{})
-	go func() {
-		defer close(done)
-		res, err = interp.EvalPath(path)
-	}()
-
-	select {
-	case <-ctx.Done():
-		interp.stop()
-		return reflect.Value{}, ctx.Err()
-	case <-done:
-	}
-	return res, err
+	r := interp.newRun(ctx)
+	return interp.runWithContext(ctx, r, func() (reflect.Value, error) {
+		return interp.evalPath(path, r)
+	})
 }
 
 // EvalTest evaluates Go code located at path, including test files with "_test.go" suffix.
@@ -902,8 +1132,16 @@ func (interp *Interpreter) EvalPathWithContext(ctx context.Context, path string)
 // The main function, test functions and benchmark functions are internally compiled but not
 // executed. Test functions can be retrieved using the Symbol() method.
 func (interp *Interpreter) EvalTest(path string) error {
+	interp.compile.Lock()
 	_, err := interp.importSrc(mainID, path, Test)
-	return err
+	interp.compile.Unlock()
+	if err != nil {
+		return err
+	}
+	interp.mutex.Lock()
+	interp.tested = append(interp.tested, path)
+	interp.mutex.Unlock()
+	return nil
 }
 
 func isFile(filesystem fs.FS, path string) bool {
@@ -911,58 +1149,65 @@ func isFile(filesystem fs.FS, path string) bool {
 	return err == nil && fi.Mode().IsRegular()
 }
 
-func (interp *Interpreter) eval(src, name string, inc bool) (res reflect.Value, err error) {
-	prog, err := interp.compileSrc(src, name, inc)
-	if err != nil {
+func (interp *Interpreter) eval(src, name string, inc bool, r *runState) (res reflect.Value, err error) {
+	prog, err := interp.compileEval(src, name, inc, r != nil && r.cancel != nil)
+	if err != nil || interp.noRun {
 		return res, err
 	}
 
-	if interp.noRun {
-		return res, err
+	return interp.execute(prog, r)
+}
+
+// compileEval compiles src for an evaluation, after formatting it and
+// evaluating its missing imports if the options require it. If cancel is
+// false, the evaluation is executed in a run which can not be cancelled.
+func (interp *Interpreter) compileEval(src, name string, inc, cancel bool) (*Program, error) {
+	if inc && interp.autoFormat {
+		// Keep the source unchanged if it can not be formatted, e.g. an
+		// incomplete input of the REPL.
+		if formatted, imports, err := interp.format(src); err == nil {
+			if imports != "" {
+				// Imports can not be mixed with statements in a snippet.
+				if _, err = interp.eval(imports, name, inc, nil); err != nil {
+					return nil, err
+				}
+			}
+			src = formatted
+		}
+	}
+	if inc && interp.autoImport {
+		if imports := interp.autoImports(src); imports != "" {
+			if _, err := interp.eval(imports, name, inc, nil); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	return interp.Execute(prog)
+	interp.compile.Lock()
+	defer interp.compile.Unlock()
+	interp.noCancel = !cancel
+	defer func() { interp.noCancel = false }()
+	return interp.compileSrc(src, name, inc)
 }
 
 // EvalWithContext evaluates Go code represented as a string. It returns
 // a map on current interpreted package exported symbols.
 func (interp *Interpreter) EvalWithContext(ctx context.Context, src string) (reflect.Value, error) {
-	var v reflect.Value
-	var err error
-
-	interp.mutex.Lock()
-	interp.done = make(chan struct{})
-	interp.cancelChan = !interp.opt.fastChan
-	interp.mutex.Unlock()
-
-	done := make(chan struct{})
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				var pc [64]uintptr
-				n := runtime.Callers(1, pc[:])
-				err = Panic{Value: r, Callers: pc[:n], Stack: debug.Stack()}
-			}
-			close(done)
-		}()
-		v, err = interp.Eval(src)
-	}()
-
-	select {
-	case <-ctx.Done():
-		interp.stop()
-		return reflect.Value{}, ctx.Err()
-	case <-done:
-	}
-	return v, err
+	r := interp.newRun(ctx)
+	return interp.runWithContext(ctx, r, func() (reflect.Value, error) {
+		return interp.eval(src, "", true, r)
+	})
 }
 
-// stop sends a semaphore to all running frames and closes the chan
-// operation short circuit channel. stop may only be called once per
-// invocation of EvalWithContext.
+// stop sends a semaphore to all running frames, and cancels the channel
+// operations of the runs started with a context.
 func (interp *Interpreter) stop() {
 	atomic.AddUint64(&interp.id, 1)
-	close(interp.done)
+	interp.mutex.Lock()
+	defer interp.mutex.Unlock()
+	for c := range interp.runs {
+		c.stop()
+	}
 }
 
 func (interp *Interpreter) runid() uint64 { return atomic.LoadUint64(&interp.id) }
@@ -1024,99 +1269,7 @@ func fixKey(k string) string {
 // at creation time. Errors are printed to the similarly defined errors writer.
 // The last interpreter result value and error are returned.
 func (interp *Interpreter) REPL() (reflect.Value, error) {
-	in, out, errs := interp.stdin, interp.stdout, interp.stderr
-	ctx, cancel := context.WithCancel(context.Background())
-	end := make(chan struct{})     // channel to terminate the REPL
-	sig := make(chan os.Signal, 1) // channel to trap interrupt signal (Ctrl-C)
-	lines := make(chan string)     // channel to read REPL input lines
-	prompt := getPrompt(in, out)   // prompt activated on tty like IO stream
-	s := bufio.NewScanner(in)      // read input stream line by line
-	var v reflect.Value            // result value from eval
-	var err error                  // error from eval
-	src := ""                      // source string to evaluate
-
-	signal.Notify(sig, os.Interrupt)
-	defer signal.Stop(sig)
-	prompt(v)
-
-	go func() {
-		defer close(end)
-		for s.Scan() {
-			lines <- s.Text()
-		}
-		if e := s.Err(); e != nil {
-			fmt.Fprintln(errs, e)
-		}
-	}()
-
-	go func() {
-		for {
-			select {
-			case <-sig:
-				cancel()
-				lines <- ""
-			case <-end:
-				return
-			}
-		}
-	}()
-
-	for {
-		var line string
-
-		select {
-		case <-end:
-			cancel()
-			return v, err
-		case line = <-lines:
-			src += line + "\n"
-		}
-
-		v, err = interp.EvalWithContext(ctx, src)
-		if err != nil {
-			switch e := err.(type) {
-			case scanner.ErrorList:
-				if len(e) > 0 && ignoreScannerError(e[0], line) {
-					continue
-				}
-				fmt.Fprintln(errs, strings.TrimPrefix(e[0].Error(), DefaultSourceName+":"))
-			case Panic:
-				fmt.Fprintln(errs, e.Value)
-				fmt.Fprintln(errs, string(e.Stack))
-			default:
-				fmt.Fprintln(errs, err)
-			}
-		}
-		if errors.Is(err, context.Canceled) {
-			ctx, cancel = context.WithCancel(context.Background())
-		}
-		src = ""
-		prompt(v)
-	}
-}
-
-func doPrompt(out io.Writer) func(v reflect.Value) {
-	return func(v reflect.Value) {
-		if v.IsValid() {
-			fmt.Fprintln(out, ":", v)
-		}
-		fmt.Fprint(out, "> ")
-	}
-}
-
-// getPrompt returns a function which prints a prompt only if input is a terminal.
-func getPrompt(in io.Reader, out io.Writer) func(reflect.Value) {
-	forcePrompt, _ := strconv.ParseBool(os.Getenv("YAEGI_PROMPT"))
-	if forcePrompt {
-		return doPrompt(out)
-	}
-	s, ok := in.(interface{ Stat() (os.FileInfo, error) })
-	if !ok {
-		return func(reflect.Value) {}
-	}
-	stat, err := s.Stat()
-	if err == nil && stat.Mode()&os.ModeCharDevice != 0 {
-		return doPrompt(out)
-	}
-	return func(reflect.Value) {}
+	t := newStreamREPL(interp.stdin, interp.stdout, interp.stderr)
+	defer t.close()
+	return interp.ServeREPL(t)
 }
@@ -148,7 +148,7 @@ func TestInterpConsistencyBuild(t *testing.T) {
 			r, w, _ := os.Pipe()
 			os.Stdout = w
 
-			i := interp.New(interp.Options{GoPath: build.Default.GOPATH})
+			i := interp.New(interp.Options{GoPath: build.Default.GOPATH, Unsafe: interp.UnsafeUnrestricted})
 			if err := i.Use(stdlib.Symbols); err != nil {
 				t.Fatal(err)
 			}
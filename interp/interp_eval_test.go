@@ -3,13 +3,20 @@ package interp_test
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"go/build"
+	"go/constant"
 	"go/parser"
+	"go/token"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -18,10 +25,13 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/breadchris/yaegi/interp"
 	"github.com/breadchris/yaegi/stdlib"
+	"github.com/breadchris/yaegi/stdlib/syscall"
+	"github.com/breadchris/yaegi/stdlib/unsafe"
 )
 
 func init() { log.SetFlags(log.Lshortfile) }
@@ -616,6 +626,59 @@ func TestEvalMethod(t *testing.T) {
 	})
 }
 
+func TestEvalMethodRedeclared(t *testing.T) {
+	i := interp.New(interp.Options{})
+	eval(t, i, `
+		type T struct{}
+
+		type Hi interface {
+			Hello() string
+		}
+
+		func (T) Hello() string { return "hello" }
+
+		func greet(h Hi) string { return h.Hello() }
+	`)
+	runTests(t, i, []testCase{
+		{src: "greet(T{})", res: "hello"},
+		{pre: func() { eval(t, i, `func (T) Hello() string { return "bonjour" }`) }, src: "greet(T{})", res: "bonjour"},
+		{src: "T{}.Hello()", res: "bonjour"},
+	})
+}
+
+func TestEvalMethodExpr(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `
+		import "time"
+
+		type T int
+
+		func (t T) Get() int { return int(t) }
+
+		func (t *T) Inc() { *t++ }
+	`)
+	runTests(t, i, []testCase{
+		{src: "T.Get(2)", res: "2"},
+		{src: "x := T(3); (*T).Inc(&x); x", res: "4"},
+		{src: "time.Duration.Seconds(time.Minute)", res: "60"},
+		{src: "T.Inc", err: "1:28: invalid method expression main.T.Inc (needs pointer receiver (*main.T).Inc)"},
+		{src: "time.Time.Nope", err: "1:28: time.Time.Nope undefined (type time.Time has no method Nope)"},
+	})
+
+	// Method expressions and values are usable by the host as functions.
+	v := eval(t, i, "T.Get")
+	if res := v.Call([]reflect.Value{reflect.ValueOf(5).Convert(v.Type().In(0))}); res[0].Int() != 5 {
+		t.Fatalf("got %v, want 5", res[0])
+	}
+	v = eval(t, i, "time.Duration(90 * time.Second).String")
+	if f, ok := v.Interface().(func() string); !ok || f() != "1m30s" {
+		t.Fatalf("got %v, want 1m30s", v)
+	}
+}
+
 func TestEvalChan(t *testing.T) {
 	i := interp.New(interp.Options{})
 	runTests(t, i, []testCase{
@@ -1172,6 +1235,213 @@ func TestImportPathIsKey(t *testing.T) {
 // obvious since the calls (and hence the "compilation phases") are sequential too.
 // 2) That two concurrent goroutine runs spawned by the same interpreter do not
 // collide either.
+func TestEvalAsync(t *testing.T) {
+	var stdout bytes.Buffer
+	i := interp.New(interp.Options{Stdout: &stdout})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import "fmt"`)
+	eval(t, i, `func answer() int { fmt.Println("hello"); fmt.Println("world"); return 42 }`)
+
+	r, err := i.EvalAsync(`answer()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(r.Stdout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "hello\nworld\n" {
+		t.Fatalf("got output %q, want %q", out, "hello\nworld\n")
+	}
+	<-r.Done()
+	if v, err := r.Result(); err != nil || v.Interface() != 42 {
+		t.Fatalf("got %v, %v, want 42", v, err)
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("unexpected interpreter output %q", stdout.String())
+	}
+
+	// Cancelling a run does not affect the other runs.
+	eval(t, i, `var ready = make(chan bool)`)
+	eval(t, i, `func count() int { <-ready; n := 0; for k := 0; k < 1000; k++ { n++ }; return n }`)
+	eval(t, i, `func spin() { fmt.Println("spinning"); for {} }`)
+	r1, err := i.EvalAsync(`spin()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := i.EvalAsync(`count()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bufio.NewReader(r1.Stdout()).ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	r1.Cancel()
+	if _, err := r1.Result(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+	eval(t, i, `close(ready)`)
+	if v, err := r2.Result(); err != nil || v.Interface() != 1000 {
+		t.Fatalf("got %v, %v, want 1000", v, err)
+	}
+
+	// Output is streamed while the run executes.
+	r, err = i.EvalAsync(`func() { fmt.Println("started"); for {} }()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(r.Stdout()).ReadString('\n')
+	if err != nil || line != "started\n" {
+		t.Fatalf("got %q, %v, want %q", line, err, "started\n")
+	}
+	r.Cancel()
+	if _, err := r.Result(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+
+	r, err = i.EvalAsync(`func() { panic("boom") }()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p *interp.Panic
+	if _, err := r.Result(); !errors.As(err, &p) || p.Value != "boom" {
+		t.Fatalf("got error %v, want a panic", err)
+	}
+
+	if _, err := i.EvalAsync(`undefined()`); err == nil {
+		t.Fatal("expected a compilation error")
+	}
+
+}
+
+func TestScheduler(t *testing.T) {
+	started := make(chan bool)
+	newInterp := func() *interp.Interpreter {
+		i := interp.New(interp.Options{})
+		if err := i.Use(interp.Exports{"host/host": {"Started": reflect.ValueOf(func() { started <- true })}}); err != nil {
+			t.Fatal(err)
+		}
+		eval(t, i, `import "host"`)
+		eval(t, i, `func spin(n int) int { s := 0; for k := 0; k < n; k++ { s += k }; return s }`)
+		return i
+	}
+	i1, i2 := newInterp(), newInterp()
+	s := interp.NewScheduler(interp.SchedulerOptions{Slots: 1, Quantum: 100})
+	ctx := context.Background()
+
+	// A run of higher priority preempts the executing one.
+	done := make(chan string, 2)
+	go func() {
+		if _, err := s.Eval(ctx, i1, `host.Started(); spin(300000)`, interp.ScheduleOptions{}); err != nil {
+			t.Error(err)
+		}
+		done <- "low"
+	}()
+	<-started
+	if _, err := s.Eval(ctx, i2, `spin(1000)`, interp.ScheduleOptions{Priority: 1}); err != nil {
+		t.Fatal(err)
+	}
+	done <- "high"
+	if first := <-done; first != "high" {
+		t.Fatalf("got %s priority run first, want high", first)
+	}
+	<-done
+
+	// A run is aborted at its deadline, while executing or waiting.
+	errc := make(chan error)
+	go func() {
+		_, err := s.Eval(ctx, i1, `host.Started(); for {}`, interp.ScheduleOptions{Priority: 1, Deadline: time.Now().Add(200 * time.Millisecond)})
+		errc <- err
+	}()
+	<-started
+	_, err := s.Eval(ctx, i2, `spin(10)`, interp.ScheduleOptions{Deadline: time.Now().Add(50 * time.Millisecond)})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := <-errc; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The slot is available again.
+	if v, err := s.Eval(ctx, i2, `spin(10)`, interp.ScheduleOptions{}); err != nil || v.Interface() != 45 {
+		t.Fatalf("got %v, %v, want 45", v, err)
+	}
+
+	if _, err := interp.NewScheduler(interp.SchedulerOptions{}).Eval(ctx, i1, `1`, interp.ScheduleOptions{}); err == nil {
+		t.Fatal("expected an error for an interpreter used by another scheduler")
+	}
+
+	// The deadline of a run does not abort the other runs of its interpreter.
+	i3 := newInterp()
+	s2 := interp.NewScheduler(interp.SchedulerOptions{Slots: 2, Quantum: 100})
+	go func() {
+		_, err := s2.Eval(ctx, i3, `host.Started(); for {}`, interp.ScheduleOptions{Deadline: time.Now().Add(50 * time.Millisecond)})
+		errc <- err
+	}()
+	<-started
+	if v, err := s2.Eval(ctx, i3, `spin(2000000)`, interp.ScheduleOptions{}); err != nil || v.Interface() != 1999999000000 {
+		t.Fatalf("got %v, %v, want 1999999000000", v, err)
+	}
+	if err := <-errc; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestSetYieldHook(t *testing.T) {
+	i := interp.New(interp.Options{})
+	eval(t, i, `
+func spin(n int) int {
+	s := 0
+	for k := 0; k < n; k++ {
+		s += k
+	}
+	return s
+}
+
+func guarded() (r interface{}) {
+	defer func() { r = recover() }()
+	for {
+	}
+}
+`)
+
+	var calls int
+	var pos token.Position
+	i.SetYieldHook(100, func(p token.Position) error {
+		calls++
+		pos = p
+		return nil
+	})
+	eval(t, i, "spin(1000)")
+	if calls < 10 || pos.Filename != "_.go" || pos.Line != 2 {
+		t.Fatalf("got %d calls at %v, want at least 10 at _.go:2", calls, pos)
+	}
+
+	// A hook error aborts the execution, and can not be recovered.
+	errStop := errors.New("budget exhausted")
+	calls = 0
+	i.SetYieldHook(1000, func(token.Position) error {
+		if calls++; calls == 5 {
+			return errStop
+		}
+		return nil
+	})
+	_, err := i.Eval("guarded()")
+	var ye *interp.YieldError
+	if !errors.Is(err, errStop) || !errors.As(err, &ye) || ye.Pos.Line != 10 {
+		t.Fatalf("got error %v, want %v at line 10", err, errStop)
+	}
+
+	i.SetYieldHook(0, nil)
+	calls = 0
+	eval(t, i, "spin(1000)")
+	if calls != 0 {
+		t.Fatalf("got %d calls of a removed hook", calls)
+	}
+}
+
 func TestConcurrentEvals(t *testing.T) {
 	if testing.Short() {
 		return
@@ -1371,6 +1641,72 @@ func TestConcurrentEvals3(t *testing.T) {
 	}
 }
 
+// TestConcurrentEvals4 checks that Eval can be called by concurrent goroutines
+// on the same interpreter, while new global vars are declared.
+func TestConcurrentEvals4(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import "sync"`)
+	eval(t, i, `
+var (
+	mu    sync.Mutex
+	count int
+)
+
+func handle(n int) int {
+	mu.Lock()
+	count++
+	mu.Unlock()
+	return 2 * n
+}`)
+
+	const workers, evals = 8, 50
+	var wg sync.WaitGroup
+	errc := make(chan error, workers+1)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for k := 0; k < evals; k++ {
+				n := w*evals + k
+				res, err := i.Eval(fmt.Sprintf("handle(%d)", n))
+				if err != nil {
+					errc <- err
+					return
+				}
+				if got := res.Interface(); got != 2*n {
+					errc <- fmt.Errorf("got %v, want %d", got, 2*n)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for k := 0; k < evals; k++ {
+			if _, err := i.Eval(fmt.Sprintf("var v%d = %d", k, k)); err != nil {
+				errc <- err
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	close(errc)
+	for err := range errc {
+		t.Fatal(err)
+	}
+
+	if res := eval(t, i, "count"); res.Interface() != workers*evals {
+		t.Fatalf("got %v, want %d", res, workers*evals)
+	}
+	if res := eval(t, i, "v10 + v20"); res.Interface() != 30 {
+		t.Fatalf("got %v, want 30", res)
+	}
+}
+
 func TestConcurrentComposite1(t *testing.T) {
 	testConcurrentComposite(t, "./testdata/concurrent/composite/composite_lit.go")
 }
@@ -1851,82 +2187,3914 @@ func TestRestrictedEnv(t *testing.T) {
 	}
 }
 
-func TestIssue1388(t *testing.T) {
-	i := interp.New(interp.Options{Env: []string{"foo=bar"}})
-	err := i.Use(stdlib.Symbols)
-	if err != nil {
+func TestVirtualEnv(t *testing.T) {
+	i := interp.New(interp.Options{Env: []string{"foo=bar", "a=b=c", "empty"}, Unrestricted: true})
+	if err := i.Use(stdlib.Symbols); err != nil {
 		t.Fatal(err)
 	}
+	i.ImportUsed()
+	runTests(t, i, []testCase{
+		{src: `os.Environ()`, res: "[a=b=c empty= foo=bar]"},
+		{src: `os.Setenv("yaegi_virtual_env", "1"); os.Getenv("yaegi_virtual_env")`, res: "1"},
+		{src: `os.Setenv("", "1")`, res: "setenv: invalid argument"},
+		{src: `os.Setenv("a=b", "1")`, res: "setenv: invalid argument"},
+	})
+	if s, ok := os.LookupEnv("yaegi_virtual_env"); ok {
+		t.Fatal("expected \"\", got " + s)
+	}
 
-	_, err = i.Eval(`x := errors.New("")`)
-	if err == nil {
-		t.Fatal("Expected an error")
+	// Each interpreter has its own environment.
+	j := interp.New(interp.Options{Env: []string{"foo=bar"}})
+	if err := j.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
 	}
+	j.ImportUsed()
+	runTests(t, j, []testCase{
+		{src: `_, ok := os.LookupEnv("yaegi_virtual_env"); ok`, res: "false"},
+	})
 
-	_, err = i.Eval(`import "errors"`)
-	if err != nil {
+	// In unrestricted mode without Env, the process environment is used.
+	t.Setenv("yaegi_virtual_env", "2")
+	k := interp.New(interp.Options{Unrestricted: true})
+	if err := k.Use(stdlib.Symbols); err != nil {
 		t.Fatal(err)
 	}
+	k.ImportUsed()
+	runTests(t, k, []testCase{
+		{src: `os.Getenv("yaegi_virtual_env")`, res: "2"},
+	})
+}
 
-	_, err = i.Eval(`x := errors.New("")`)
+func TestVirtualWorkdir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
 	if err != nil {
 		t.Fatal(err)
 	}
-}
 
-func TestIssue1383(t *testing.T) {
-	const src = `
-			package main
+	i := interp.New(interp.Options{Dir: dir})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	i.ImportUsed()
+	runTests(t, i, []testCase{
+		{src: `os.WriteFile("a.txt", []byte("hello"), 0o644)`, res: "<nil>"},
+		{src: `os.Chdir("sub")`, res: "<nil>"},
+		{src: `d, _ := os.Getwd(); d`, res: filepath.Join(dir, "sub")},
+		{src: `b, _ := os.ReadFile("../a.txt"); string(b)`, res: "hello"},
+		{src: `_, err := os.Stat("a.txt"); err`, res: "stat a.txt: no such file or directory"},
+		{src: `os.Chdir("nowhere")`, res: "chdir nowhere: no such file or directory"},
+		{src: `os.Chdir("..")`, res: "<nil>"},
+		{src: `f, _ := os.Open("a.txt"); defer f.Close(); f.Name()`, res: "a.txt"},
+		{src: `f, _ := os.Create("sub/b.txt"); f.WriteString("hi"); f.Close(); f.Name()`, res: "sub/b.txt"},
+		{src: `b, _ := os.ReadFile("sub/b.txt"); string(b)`, res: "hi"},
+		{src: `f, _ := os.CreateTemp("sub", "t"); f.Close(); os.Remove(f.Name()); filepath.Dir(f.Name())`, res: "sub"},
+		{src: `filepath.Glob("*.txt")`, res: "[a.txt]"},
+		{src: `a, _ := filepath.Abs("sub"); a`, res: filepath.Join(dir, "sub")},
+		{src: `names := []string{}; filepath.WalkDir(".", func(p string, d fs.DirEntry, err error) error { names = append(names, p); return err }); names`, res: "[. a.txt sub sub/b.txt]"},
+	})
 
-			func main() {
-				fmt.Println("Hello")
-			}
-		`
+	if d, _ := os.Getwd(); d != cwd {
+		t.Errorf("process working directory changed to %s", d)
+	}
+}
 
+func TestSignalNotify(t *testing.T) {
 	i := interp.New(interp.Options{})
-	err := i.Use(stdlib.Symbols)
-	if err != nil {
+	if err := i.Use(stdlib.Symbols); err != nil {
 		t.Fatal(err)
 	}
-	_, err = i.Eval(`import "fmt"`)
-	if err != nil {
-		t.Fatal(err)
+	eval(t, i, `import ("context"; "os"; "os/signal")`)
+	eval(t, i, `var c = make(chan os.Signal, 1)`)
+
+	if i.Notify(os.Interrupt) {
+		t.Error("signal delivered without handler")
+	}
+	eval(t, i, `signal.Notify(c, os.Interrupt)`)
+	if !i.Notify(os.Interrupt) {
+		t.Error("signal not delivered")
+	}
+	if res := eval(t, i, `<-c`); res.Interface() != os.Interrupt {
+		t.Errorf("got %v, want %v", res, os.Interrupt)
 	}
 
-	ast, err := parser.ParseFile(i.FileSet(), "_.go", src, parser.DeclarationErrors)
-	if err != nil {
+	eval(t, i, `signal.Ignore(os.Interrupt)`)
+	if i.Notify(os.Interrupt) {
+		t.Error("ignored signal delivered")
+	}
+	if res := eval(t, i, `signal.Ignored(os.Interrupt)`); res.Interface() != true {
+		t.Error("signal not ignored")
+	}
+	eval(t, i, `signal.Reset()`)
+
+	eval(t, i, `ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)`)
+	if !i.Notify(os.Interrupt) {
+		t.Error("signal not delivered to context")
+	}
+	if res := eval(t, i, `<-ctx.Done(); stop(); ctx.Err()`); res.Interface() != context.Canceled {
+		t.Errorf("got %v, want %v", res, context.Canceled)
+	}
+	if i.Notify(os.Interrupt) {
+		t.Error("signal delivered after stop")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
 		t.Fatal(err)
 	}
-	prog, err := i.CompileAST(ast)
-	if err != nil {
+	if err := i.Use(interp.Symbols); err != nil {
 		t.Fatal(err)
 	}
-	_, err = i.Execute(prog)
-	if err != nil {
+	eval(t, i, `import ("net"; "os"; "os/signal"; "github.com/breadchris/yaegi/interp")`)
+	eval(t, i, `var (
+	events []string
+	l, _ = net.Listen("tcp", "127.0.0.1:0")
+	sig = make(chan os.Signal, 1)
+)`)
+	eval(t, i, `func init() {
+	signal.Notify(sig, os.Interrupt)
+	interp.Self.OnShutdown(func() { events = append(events, "cleanup1") })
+	interp.Self.OnShutdown(func() { events = append(events, "cleanup2") })
+}`)
+	l := eval(t, i, "l").Interface().(net.Listener)
+
+	if err := i.Shutdown(context.Background()); err != nil {
 		t.Fatal(err)
 	}
+	if _, err := l.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("got %v, want %v", err, net.ErrClosed)
+	}
+	if res := eval(t, i, "len(sig)"); res.Interface() != 1 {
+		t.Error("interrupt not delivered")
+	}
+	if res := eval(t, i, "events"); fmt.Sprint(res) != "[cleanup2 cleanup1]" {
+		t.Errorf("got %v, want [cleanup2 cleanup1]", res)
+	}
+
+	// Shutdown returns when its context is done.
+	i.OnShutdown(func() { select {} })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := i.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
 }
 
-func TestIssue1623(t *testing.T) {
-	var f float64
-	var j int
-	var s string = "foo"
+func TestPanicUnwrap(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import ("fmt"; "io")`)
+	eval(t, i, `type MyErr struct{ Code int }`)
+	eval(t, i, `func (e *MyErr) Error() string { return fmt.Sprint("code ", e.Code) }`)
+
+	_, err := i.Eval(`panic(fmt.Errorf("read: %w", io.EOF))`)
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("got %v, want %v in chain", err, io.EOF)
+	}
+	var p *interp.Panic
+	if !errors.As(err, &p) {
+		t.Fatalf("got %T, want *interp.Panic", err)
+	}
+
+	// A value of an interpreted error type is exposed as an error.
+	_, err = i.Eval(`panic(&MyErr{3})`)
+	if e := errors.Unwrap(err); e == nil || e.Error() != "code 3" {
+		t.Errorf("got %v, want code 3", e)
+	}
+}
 
+func TestErrorsChain(t *testing.T) {
 	i := interp.New(interp.Options{})
-	if err := i.Use(interp.Exports{
-		"pkg/pkg": map[string]reflect.Value{
-			"F": reflect.ValueOf(&f).Elem(),
-			"J": reflect.ValueOf(&j).Elem(),
-			"S": reflect.ValueOf(&s).Elem(),
-		},
-	}); err != nil {
+	if err := i.Use(stdlib.Symbols); err != nil {
 		t.Fatal(err)
 	}
-	i.ImportUsed()
+	eval(t, i, `import ("errors"; "io")`)
+	eval(t, i, `type Wrap struct{ err error }`)
+	eval(t, i, `func (w Wrap) Error() string { return "wrap: " + w.err.Error() }`)
+	eval(t, i, `func (w Wrap) Unwrap() error { return w.err }`)
+	eval(t, i, `type Multi []error`)
+	eval(t, i, `func (m Multi) Error() string { return "multi" }`)
+	eval(t, i, `func (m Multi) Unwrap() []error { return m }`)
+	eval(t, i, `type Timeout struct{}`)
+	eval(t, i, `func (Timeout) Error() string { return "timeout" }`)
+	eval(t, i, `func (Timeout) Is(target error) bool { return target == io.ErrNoProgress }`)
+
+	// Errors of interpreted types expose their Unwrap and Is methods to the
+	// errors package of the host.
+	for _, src := range []string{
+		`Wrap{io.EOF}`,
+		`Multi{errors.New("x"), io.EOF}`,
+		`errors.Join(Wrap{io.EOF}, Timeout{})`,
+	} {
+		eval(t, i, `func f() error { return `+src+` }`)
+		err := eval(t, i, `f`).Interface().(func() error)()
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("%s: got %v, want %v in chain", src, err, io.EOF)
+		}
+	}
+	eval(t, i, `func f() error { return Wrap{Timeout{}} }`)
+	err := eval(t, i, `f`).Interface().(func() error)()
+	if !errors.Is(err, io.ErrNoProgress) {
+		t.Errorf("got %v, want %v in chain", err, io.ErrNoProgress)
+	}
 
-	runTests(t, i, []testCase{
-		{desc: "pkg.F = 2.0", src: "pkg.F = 2.0; pkg.F", res: "2"},
-		{desc: "pkg.J = 3", src: "pkg.J = 3; pkg.J", res: "3"},
-		{desc: `pkg.S = "bar"`, src: `pkg.S = "bar"; pkg.S`, res: "bar"},
-	})
+	// Unwrapping an error returns the original error, also when it crossed
+	// the interpreter boundary in a wrapper.
+	eval(t, i, `import "fmt"`)
+	eval(t, i, `var orig error = Timeout{}`)
+	for _, src := range []string{
+		`errors.Unwrap(Wrap{orig})`,
+		`errors.Unwrap(fmt.Errorf("x: %w", orig))`,
+		`errors.Unwrap(errors.Unwrap(fmt.Errorf("x: %w", Wrap{orig})))`,
+	} {
+		if res := eval(t, i, src+` == orig`); !res.Bool() {
+			t.Errorf("%s: got %v, want the original error", src, res)
+		}
+	}
+	eval(t, i, `func g(err error) error { return Wrap{err} }`)
+	orig := &os.PathError{Op: "open", Path: "x", Err: io.EOF}
+	if err := eval(t, i, `g`).Interface().(func(error) error)(orig); errors.Unwrap(err) != orig {
+		t.Errorf("got %v, want %v", errors.Unwrap(err), orig)
+	}
+}
+
+func TestPanicTunnel(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `type MyErr struct{ Code int }`)
+	eval(t, i, `func (e *MyErr) Error() string { return "my error" }`)
+	eval(t, i, `func fail() { panic(&MyErr{3}) }`)
+
+	// The host code calling an interpreted function recovers its panic value
+	// as a Go value, in a CallbackPanic.
+	fail := eval(t, i, `fail`).Interface().(func())
+	func() {
+		defer func() {
+			err, ok := recover().(error)
+			if !ok || err.Error() != "my error" {
+				t.Errorf("got %v, want my error", err)
+			}
+			var p *interp.CallbackPanic
+			if !errors.As(err, &p) || strings.Contains(fmt.Sprintf("%T", p.Value), "valueInterface") {
+				t.Errorf("got %T, want a CallbackPanic of a Go value", err)
+			}
+		}()
+		fail()
+	}()
+
+	// Values which are not comparable are exported too.
+	eval(t, i, `func failMap() { panic(map[string]int{"a": 1}) }`)
+	failMap := eval(t, i, `failMap`).Interface().(func())
+	func() {
+		defer func() {
+			p, ok := recover().(*interp.CallbackPanic)
+			if !ok {
+				t.Fatalf("got %T, want a CallbackPanic", p)
+			}
+			if m, ok := p.Value.(map[string]int); !ok || m["a"] != 1 {
+				t.Errorf("got %T %v, want map[a:1]", p.Value, p.Value)
+			}
+		}()
+		failMap()
+	}()
+
+	// The value is restored when the panic reenters interpreted code.
+	eval(t, i, `import "sort"`)
+	eval(t, i, `func code() (code int) {
+	defer func() { code = recover().(*MyErr).Code }()
+	sort.Slice([]int{1, 2}, func(i, j int) bool { fail(); return false })
+	return
+}`)
+	if code := eval(t, i, `code()`).Interface(); code != 3 {
+		t.Errorf("got %v, want 3", code)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	src := `package main
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+)
+
+func main() {
+	s := bufio.NewScanner(os.Stdin)
+	s.Scan()
+	fmt.Println(s.Text(), os.Getenv("MODE"), os.Args[1:])
+	fmt.Println(time.Now().UnixNano(), time.Since(time.Unix(0, 0)) > 0)
+	fmt.Println(rand.Intn(1<<30), rand.Float64())
+}
+`
+	run := func(opts interp.Options) string {
+		t.Helper()
+		var out bytes.Buffer
+		opts.Stdout = &out
+		i := interp.New(opts)
+		if err := i.Use(stdlib.Symbols); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := i.Eval(src); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+
+	rec := &interp.Recording{}
+	recorded := run(interp.Options{
+		Stdin:  strings.NewReader("recorded input\n"),
+		Env:    []string{"MODE=recorded"},
+		Args:   []string{"script", "arg"},
+		Record: rec,
+	})
+	if !strings.HasPrefix(recorded, "recorded input recorded [arg]\n") {
+		t.Fatalf("unexpected recorded output %q", recorded)
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep := &interp.Recording{}
+	if err := json.Unmarshal(b, rep); err != nil {
+		t.Fatal(err)
+	}
+	replayed := run(interp.Options{
+		Stdin:  strings.NewReader("other input\n"),
+		Env:    []string{"MODE=other"},
+		Replay: rep,
+	})
+	if replayed != recorded {
+		t.Errorf("got %q, want %q", replayed, recorded)
+	}
+
+	// Only the input read by the script is recorded.
+	rec = &interp.Recording{}
+	i := interp.New(interp.Options{Stdin: strings.NewReader("first\nsecond\n"), Record: rec})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import "fmt"`)
+	eval(t, i, `func read() (s string) { fmt.Scanln(&s); return }`)
+	if v := eval(t, i, `read()`); v.String() != "first" {
+		t.Fatalf("got %q, want first", v)
+	}
+	if string(rec.Stdin) != "first\n" {
+		t.Errorf("got recorded input %q, want %q", rec.Stdin, "first\n")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import ("fmt"; "strings")`)
+	eval(t, i, `
+type Shape interface{ Area() float64 }
+
+type Square struct{ Side float64 }
+
+func (s Square) Area() float64 { return s.Side * s.Side }
+
+type Name string
+
+func (n Name) String() string { return string(n) }
+
+type Level int
+
+func (l Level) MarshalText() ([]byte, error) { return []byte(strings.Repeat("*", int(l))), nil }
+
+func (l *Level) UnmarshalText(b []byte) error { *l = Level(len(b)); return nil }
+
+type Base struct{ ID int }
+
+type Node struct {
+	Base
+	A      int `+"`json:\"a\"`"+`
+	secret string
+	P      *int
+	Shape  Shape
+	Shapes []Shape
+	Owner  fmt.Stringer
+	Levels map[string]Level
+	Next   *Node `+"`json:\",omitempty\"`"+`
+}
+
+var n = 3
+
+var v = Node{Base{7}, 1, "s", &n, Square{2}, []Shape{Square{1}}, Name("bob"), map[string]Level{"k": 2}, &Node{A: 9}}
+`)
+
+	b, err := i.Marshal(eval(t, i, "v"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"ID":7,"a":1,"P":3,"Shape":{"Side":2},"Shapes":[{"Side":1}],"Owner":"bob","Levels":{"k":"**"},` +
+		`"Next":{"ID":0,"a":9,"P":null,"Shape":null,"Shapes":null,"Owner":null,"Levels":null}}`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+
+	data := `{"ID":8,"a":5,"secret":"x","P":10,"Shape":{"Side":5},"Levels":{"k":"****"},"Next":{"a":11}}`
+	if err := i.Unmarshal([]byte(data), eval(t, i, "&v")); err != nil {
+		t.Fatal(err)
+	}
+	res := eval(t, i, `fmt.Sprintf("%d %d %s %d %v %d %d", v.ID, v.A, v.secret, *v.P, v.Shape.Area(), v.Levels["k"], v.Next.A)`)
+	if got, want := res.String(), "8 5 s 10 25 4 11"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	eval(t, i, `var f = struct{ F func() }{}`)
+	if _, err := i.Marshal(eval(t, i, "f")); err == nil {
+		t.Error("expected an error on a func field")
+	}
+}
+
+// replTransport is a REPL transport replaying input lines, and recording
+// the frames.
+type replTransport struct {
+	lines  []string
+	frames []string
+}
+
+func (t *replTransport) ReadLine() (string, error) {
+	if len(t.lines) == 0 {
+		return "", io.EOF
+	}
+	line := t.lines[0]
+	t.lines = t.lines[1:]
+	return line, nil
+}
+
+func (t *replTransport) WriteFrame(f interp.REPLFrame) error {
+	t.frames = append(t.frames, f.Kind.String()+":"+f.Text)
+	return nil
+}
+
+func TestServeREPL(t *testing.T) {
+	i := interp.New(interp.Options{})
+	tr := &replTransport{lines: []string{"x := 2", "if x > 1 {", "x++ }", "x", "undefined", ":doc nothing"}}
+	if _, err := i.ServeREPL(tr); err == nil {
+		t.Error("expected the error of the last evaluation")
+	}
+	want := []string{
+		"prompt:", "result:2", "prompt:", "continue:", "result:3", "prompt:", "result:3", "prompt:",
+		"error:1:28: undefined: undefined", "prompt:",
+		"error:no documentation found for nothing", "prompt:",
+	}
+	if strings.Join(tr.frames, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", tr.frames, want)
+	}
+}
+
+func TestWithOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	i := interp.New(interp.Options{Stdout: &stdout, Stderr: &stderr})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import "fmt"`)
+	eval(t, i, `import "log"`)
+	eval(t, i, `import "sync"`)
+	eval(t, i, `
+func report(id int) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for j := 0; j < 3; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mu.Lock()
+			fmt.Println("out", id)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	println("print", id)
+	log.SetFlags(0)
+	log.Println("err", id)
+}`)
+
+	const n = 8
+	outs := make([]bytes.Buffer, n)
+	errs := make([]bytes.Buffer, n)
+	var wg sync.WaitGroup
+	for k := 0; k < n; k++ {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			ctx := interp.WithOutput(context.Background(), &outs[k], &errs[k])
+			if _, err := i.EvalWithContext(ctx, fmt.Sprintf("report(%d)", k)); err != nil {
+				t.Error(err)
+			}
+		}(k)
+	}
+	wg.Wait()
+
+	for k := 0; k < n; k++ {
+		want := strings.Repeat(fmt.Sprintf("out %d\n", k), 3) + fmt.Sprintf("print %d\n", k)
+		if got := outs[k].String(); got != want {
+			t.Errorf("stdout of %d: got %q, want %q", k, got, want)
+		}
+		if got, want := errs[k].String(), fmt.Sprintf("err %d\n", k); got != want {
+			t.Errorf("stderr of %d: got %q, want %q", k, got, want)
+		}
+	}
+	if stdout.Len() != 0 || stderr.Len() != 0 {
+		t.Errorf("unexpected output to the interpreter streams: %q, %q", stdout.String(), stderr.String())
+	}
+
+	// Evaluations without an output scope write to the interpreter streams,
+	// and a nil writer keeps the stream of the interpreter.
+	eval(t, i, `report(-1)`)
+	ctx := interp.WithOutput(context.Background(), &outs[0], nil)
+	outs[0].Reset()
+	if _, err := i.EvalWithContext(ctx, `report(-2)`); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "out -1\nout -1\nout -1\nprint -1\n"; got != want {
+		t.Errorf("got stdout %q, want %q", got, want)
+	}
+	if got, want := stderr.String(), "err -1\nerr -2\n"; got != want {
+		t.Errorf("got stderr %q, want %q", got, want)
+	}
+	if got, want := outs[0].String(), "out -2\nout -2\nout -2\nprint -2\n"; got != want {
+		t.Errorf("got scoped stdout %q, want %q", got, want)
+	}
+}
+
+func TestLogSink(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	var mutex sync.Mutex
+	var logs []string
+	i := interp.New(interp.Options{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		LogSink: func(level, msg string, pos token.Position) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			logs = append(logs, fmt.Sprintf("%s %d:%d %s", level, pos.Line, pos.Column, msg))
+		},
+	})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import "log"`)
+	eval(t, i, `
+func run() {
+	println("a", 1)
+	print("b")
+	log.Println("c", 2)
+	log.Printf("%d%s", 3, "d")
+	f := log.Print
+	f("e")
+	defer func() { recover() }()
+	log.Panicf("f")
+}`)
+	eval(t, i, "run()")
+
+	want := []string{
+		"print 3:2 a 1",
+		"print 4:2 b",
+		"info 5:2 c 2",
+		"info 6:2 3d",
+		"info 0:0 e",
+		"panic 10:2 f",
+	}
+	if strings.Join(logs, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", logs, want)
+	}
+	if stdout.Len() != 0 || stderr.Len() != 0 {
+		t.Errorf("unexpected output: %q, %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestCallInterceptor(t *testing.T) {
+	var mutex sync.Mutex
+	var calls []string
+	i := interp.New(interp.Options{
+		CallInterceptor: func(sym string, args []reflect.Value, next func() []reflect.Value) []reflect.Value {
+			mutex.Lock()
+			calls = append(calls, fmt.Sprintf("%s/%d", sym, len(args)))
+			mutex.Unlock()
+			switch sym {
+			case "strings.Repeat":
+				return []reflect.Value{reflect.ValueOf("mock")}
+			case "os.Getenv":
+				panic(errors.New("os.Getenv denied"))
+			case "strings.ToUpper":
+				args[0] = reflect.ValueOf("b")
+			}
+			return next()
+		},
+	})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import ("fmt"; "os"; "strings")`)
+	eval(t, i, `
+func run() string {
+	var sb strings.Builder
+	defer sb.Reset()
+	sb.WriteString(strings.ToUpper("a"))
+	sb.WriteString(strings.Repeat("x", 3))
+	return fmt.Sprint(sb.String(), 1)
+}`)
+	res := eval(t, i, "run()")
+	if s := res.String(); s != "Bmock1" {
+		t.Errorf("got %q, want %q", s, "Bmock1")
+	}
+	want := []string{
+		"strings.ToUpper/1",
+		"(*strings.Builder).WriteString/1",
+		"strings.Repeat/2",
+		"(*strings.Builder).WriteString/1",
+		"(*strings.Builder).String/0",
+		"fmt.Sprint/2",
+		"(*strings.Builder).Reset/0",
+	}
+	if strings.Join(calls, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", calls, want)
+	}
+
+	_, err := i.Eval(`os.Getenv("HOME")`)
+	if err == nil || !strings.Contains(err.Error(), "os.Getenv denied") {
+		t.Errorf("got error %v, want denied call", err)
+	}
+}
+
+func TestPanicFilteredStack(t *testing.T) {
+	if smallProfile {
+		t.Skip("stack traces are not filtered in the small build profile")
+	}
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import "sort"`)
+	eval(t, i, `
+func index(a []int, i int) int {
+	return a[i]
+}
+
+func less(i, j int) bool { return index(nil, i) > 0 }
+
+func sorted() { sort.Slice([]int{1, 2}, less) }
+`)
+
+	for _, test := range []struct{ src, stack string }{
+		{src: "index(nil, 3)", stack: "main.index()\n\t_.go:3:9\n"},
+		{src: "sorted()", stack: "main.index()\n\t_.go:3:9\nmain.less()\n\t_.go:6:35\n"},
+	} {
+		_, err := i.Eval(test.src)
+		var p *interp.Panic
+		if !errors.As(err, &p) {
+			t.Fatalf("%s: got %v, want a Panic", test.src, err)
+		}
+		if stack := string(p.FilteredStack); !strings.Contains(stack, test.stack) {
+			t.Errorf("%s: got stack %s, want %q", test.src, stack, test.stack)
+		}
+	}
+}
+
+func TestIssue1388(t *testing.T) {
+	i := interp.New(interp.Options{Env: []string{"foo=bar"}})
+	err := i.Use(stdlib.Symbols)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = i.Eval(`x := errors.New("")`)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	_, err = i.Eval(`import "errors"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = i.Eval(`x := errors.New("")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIssue1383(t *testing.T) {
+	const src = `
+			package main
+
+			func main() {
+				fmt.Println("Hello")
+			}
+		`
+
+	i := interp.New(interp.Options{})
+	err := i.Use(stdlib.Symbols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = i.Eval(`import "fmt"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ast, err := parser.ParseFile(i.FileSet(), "_.go", src, parser.DeclarationErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := i.CompileAST(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = i.Execute(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIssue1623(t *testing.T) {
+	var f float64
+	var j int
+	var s string = "foo"
+
+	i := interp.New(interp.Options{})
+	if err := i.Use(interp.Exports{
+		"pkg/pkg": map[string]reflect.Value{
+			"F": reflect.ValueOf(&f).Elem(),
+			"J": reflect.ValueOf(&j).Elem(),
+			"S": reflect.ValueOf(&s).Elem(),
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	i.ImportUsed()
+
+	runTests(t, i, []testCase{
+		{desc: "pkg.F = 2.0", src: "pkg.F = 2.0; pkg.F", res: "2"},
+		{desc: "pkg.J = 3", src: "pkg.J = 3; pkg.J", res: "3"},
+		{desc: `pkg.S = "bar"`, src: `pkg.S = "bar"; pkg.S`, res: "bar"},
+	})
+}
+
+func TestEvalGenericMethodsPackage(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"main.go": &fstest.MapFile{Data: []byte(`package main
+
+import (
+	"fmt"
+
+	"guthib.com/stack"
+)
+
+type Item struct{ n int }
+
+type Lener interface{ Len() int }
+
+func main() {
+	s := &stack.Stack[int]{}
+	s.Push(1)
+	var l Lener = s
+	stack.Ints.Push(2)
+	is := stack.Wrap(Item{3})
+	is.Push(Item{4})
+	fmt.Println(l.Len(), stack.Count(stack.Ints), is.Len(), is)
+}
+`)},
+		"_pkg/src/guthib.com/stack/stack.go": &fstest.MapFile{Data: []byte(`package stack
+
+type Stack[T any] struct{ items []T }
+
+func (s *Stack[T]) Push(v T) { s.items = append(s.items, v) }
+
+func (s *Stack[T]) Len() int { return len(s.items) }
+
+func (s *Stack[T]) String() string { return "stack" }
+
+var Ints = &Stack[int]{}
+
+func Count(s *Stack[int]) int { return s.Len() }
+
+func Wrap[T any](v T) *Stack[T] {
+	s := &Stack[T]{}
+	s.Push(v)
+	return s
+}
+`)},
+	}
+	var stdout bytes.Buffer
+	i := interp.New(interp.Options{GoPath: "./_pkg", SourcecodeFilesystem: filesystem, Stdout: &stdout})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.EvalPath("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "1 1 2 stack\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"app/main.go": &fstest.MapFile{Data: []byte(`package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+//go:embed hello.txt
+var s string
+
+//go:embed "hello.txt"
+var b []byte
+
+//go:embed static
+var content embed.FS
+
+func main() {
+	fmt.Printf("%q %q\n", s, b)
+	fs.WalkDir(content, ".", func(p string, d fs.DirEntry, err error) error {
+		fmt.Println(p, d.IsDir())
+		return err
+	})
+	data, err := content.ReadFile("static/sub/b.txt")
+	fmt.Printf("%q %v\n", data, err)
+}
+`)},
+		"app/hello.txt":         &fstest.MapFile{Data: []byte("hello")},
+		"app/static/a.txt":      &fstest.MapFile{Data: []byte("a")},
+		"app/static/sub/b.txt":  &fstest.MapFile{Data: []byte("b")},
+		"app/static/.hidden":    &fstest.MapFile{Data: []byte("hidden")},
+		"app/static/_skip.txt":  &fstest.MapFile{Data: []byte("skip")},
+		"app/missing.go":        &fstest.MapFile{Data: []byte("package main\n\nimport _ \"embed\"\n\n//go:embed *.css\nvar s string\n")},
+		"app/noimport.go":       &fstest.MapFile{Data: []byte("package main\n\n//go:embed hello.txt\nvar s string\n")},
+		"app/multiple/main.go":  &fstest.MapFile{Data: []byte("package main\n\nimport _ \"embed\"\n\n//go:embed *.txt\nvar s string\n")},
+		"app/multiple/a.txt":    &fstest.MapFile{Data: []byte("a")},
+		"app/multiple/b.txt":    &fstest.MapFile{Data: []byte("b")},
+		"app/multiple/dir/c.go": &fstest.MapFile{Data: []byte("package dir")},
+	}
+	var stdout bytes.Buffer
+	i := interp.New(interp.Options{SourcecodeFilesystem: filesystem, Stdout: &stdout})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.EvalPath("app/main.go"); err != nil {
+		t.Fatal(err)
+	}
+	want := `"hello" "hello"
+. true
+static true
+static/a.txt false
+static/sub true
+static/sub/b.txt false
+"b" <nil>
+`
+	if got := stdout.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for path, msg := range map[string]string{
+		"app/missing.go":       "app/missing.go:5:1: pattern *.css: no matching files found",
+		"app/noimport.go":      `app/noimport.go:3:1: go:embed only allowed in Go files that import "embed"`,
+		"app/multiple/main.go": "app/multiple/main.go:5:1: invalid go:embed: multiple files for type string",
+	} {
+		i := interp.New(interp.Options{SourcecodeFilesystem: filesystem})
+		if err := i.Use(stdlib.Symbols); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := i.EvalPath(path); err == nil || err.Error() != msg {
+			t.Errorf("%s: got error %v, want %q", path, err, msg)
+		}
+	}
+}
+
+func TestBuildTarget(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"_pkg/src/guthib.com/plat/plat.go":         &fstest.MapFile{Data: []byte("package plat\n\nvar Name = name + \"/\" + arch\n")},
+		"_pkg/src/guthib.com/plat/plat_linux.go":   &fstest.MapFile{Data: []byte("package plat\n\nconst name = \"linux\"\n")},
+		"_pkg/src/guthib.com/plat/plat_windows.go": &fstest.MapFile{Data: []byte("package plat\n\nconst name = \"windows\"\n")},
+		"_pkg/src/guthib.com/plat/arch_arm64.go":   &fstest.MapFile{Data: []byte("//go:build arm64 && !purego\n\npackage plat\n\nconst arch = \"arm64\"\n")},
+		"_pkg/src/guthib.com/plat/arch_other.go":   &fstest.MapFile{Data: []byte("//go:build !arm64 || purego\n\npackage plat\n\nconst arch = \"other\"\n")},
+	}
+	for _, test := range []struct {
+		opts interp.Options
+		want string
+	}{
+		{interp.Options{GOOS: "windows", GOARCH: "arm64"}, "windows/arm64"},
+		{interp.Options{GOOS: "linux", GOARCH: "amd64"}, "linux/other"},
+		{interp.Options{GOOS: "android", GOARCH: "arm64", BuildTags: []string{"purego"}}, "linux/other"},
+	} {
+		test.opts.GoPath = "./_pkg"
+		test.opts.SourcecodeFilesystem = filesystem
+		i := interp.New(test.opts)
+		eval(t, i, `import "guthib.com/plat"`)
+		if v := eval(t, i, "plat.Name"); v.Interface() != test.want {
+			t.Errorf("%s/%s: got %v, want %s", test.opts.GOOS, test.opts.GOARCH, v, test.want)
+		}
+	}
+}
+
+func TestTargetEmulation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the host path separators are the Windows ones")
+	}
+	i := interp.New(interp.Options{GOOS: "windows", GOARCH: "arm64"})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import ("os"; "path/filepath"; "runtime")`)
+	runTests(t, i, []testCase{
+		{src: "runtime.GOOS + runtime.GOARCH", res: "windowsarm64"},
+		{src: "string(filepath.Separator) + string(os.PathListSeparator)", res: `\;`},
+		{src: `filepath.Join("a", "b/c", "..", "d")`, res: `a\b\d`},
+		{src: `filepath.IsAbs("C:\\x") && !filepath.IsAbs("/x") && filepath.IsAbs("\\\\host\\share\\x")`, res: "true"},
+		{src: `filepath.Base("C:\\a\\b.txt") + filepath.Ext("C:\\a\\b.txt")`, res: "b.txt.txt"},
+		{src: `filepath.Dir("C:\\a\\b.txt")`, res: `C:\a`},
+		{src: `filepath.VolumeName("C:\\a")`, res: "C:"},
+		{src: `filepath.SplitList("a;b")`, res: "[a b]"},
+		{src: `filepath.ToSlash("a\\b")`, res: "a/b"},
+		{src: `os.IsPathSeparator('\\')`, res: "true"},
+	})
+}
+
+func TestCgoStubs(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"main.go":                        &fstest.MapFile{Data: []byte("package main\n\nimport \"guthib.com/a\"\n\nfunc main() { println(a.Hello()) }\n")},
+		"_pkg/src/guthib.com/a/a.go":     &fstest.MapFile{Data: []byte("package a\n\nimport (\n\t\"guthib.com/b\"\n\t\"guthib.com/c\"\n)\n\nfunc Hello() string { return b.Hello() + c.Hello() }\n")},
+		"_pkg/src/guthib.com/b/b.go":     &fstest.MapFile{Data: []byte("package b\n\n// #include <stdio.h>\nimport \"C\"\n\nfunc Hello() string { return \"cgo\" }\n")},
+		"_pkg/src/guthib.com/c/c.go":     &fstest.MapFile{Data: []byte("package c\n\nfunc Hello() string { return \" source\" }\n")},
+		"_pkg/src/guthib.com/c/c_cgo.go": &fstest.MapFile{Data: []byte("//go:build cgo\n\npackage c\n\nimport \"C\"\n")},
+	}
+
+	i := interp.New(interp.Options{GoPath: "./_pkg", SourcecodeFilesystem: filesystem})
+	_, err := i.EvalPath("main.go")
+	want := `package guthib.com/b requires cgo, which is not supported: main → guthib.com/a → guthib.com/b → C
+	main.go:3:8: main imports guthib.com/a
+	_pkg/src/guthib.com/a/a.go:4:2: guthib.com/a imports guthib.com/b
+	_pkg/src/guthib.com/b/b.go:4:8: guthib.com/b imports C
+use UseCgoStubs to provide a replacement of guthib.com/b`
+	if err == nil || !strings.HasSuffix(err.Error(), want) {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+
+	var stdout, stderr bytes.Buffer
+	i = interp.New(interp.Options{GoPath: "./_pkg", SourcecodeFilesystem: filesystem, Stdout: &stdout, Stderr: &stderr})
+	i.UseCgoStubs(interp.Exports{
+		"guthib.com/b/b": {"Hello": reflect.ValueOf(func() string { return "stub" })},
+		"guthib.com/c/c": {"Hello": reflect.ValueOf(func() string { return " stub" })},
+	})
+	if _, err := i.EvalPath("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String() + stderr.String(); got != "stub source\n" {
+		t.Errorf("got %q, want %q", got, "stub source\n")
+	}
+}
+
+func TestEvalProject(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"proj/main.go": &fstest.MapFile{Data: []byte(`package main
+
+import "./greet"
+
+func main() { println(greet.Hello("main")) }
+`)},
+		"proj/greet/greet.go": &fstest.MapFile{Data: []byte(`package greet
+
+func Hello(s string) string { return "hello " + s }
+`)},
+		"proj/greet/greet_windows.go": &fstest.MapFile{Data: []byte(`package greet
+
+func Windows() {}
+`)},
+		"proj/broken/broken.go": &fstest.MapFile{Data: []byte(`package broken
+
+func Broken() int { return undefined }
+`)},
+		"proj/testdata/skip.go": &fstest.MapFile{Data: []byte(`package skip`)},
+	}
+	i := interp.New(interp.Options{SourcecodeFilesystem: filesystem})
+
+	p, err := i.EvalProject("proj")
+	if err == nil || !strings.Contains(err.Error(), "undefined: undefined") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Packages) != 3 {
+		t.Fatalf("got %d packages, want 3", len(p.Packages))
+	}
+	if errs := p.Errors(); len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1: %v", len(errs), errs)
+	}
+	if pkg := p.Package("proj"); pkg == nil || pkg.Name != "main" || pkg.Err != nil {
+		t.Fatalf("unexpected main package: %+v", pkg)
+	}
+
+	syms := p.Symbols("proj/greet")
+	if _, ok := syms["Windows"]; ok {
+		t.Fatal("file excluded by build constraints was loaded")
+	}
+	hello, ok := syms["Hello"].Interface().(func(string) string)
+	if !ok {
+		t.Fatalf("Hello not found in %v", syms)
+	}
+	if s := hello("project"); s != "hello project" {
+		t.Fatalf("got %q", s)
+	}
+
+	// EvalProject is serialized with the other compilations.
+	j := interp.New(interp.Options{SourcecodeFilesystem: filesystem})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = j.EvalProject("proj")
+	}()
+	for k := 0; k < 10; k++ {
+		if _, err := j.Eval(fmt.Sprintf("var v%d = %d", k, k)); err != nil {
+			t.Error(err)
+		}
+	}
+	wg.Wait()
+}
+
+func TestImportGraph(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"main.go": &fstest.MapFile{Data: []byte(`package main
+
+import (
+	"guthib.com/a"
+	"guthib.com/b"
+)
+
+func main() { println(a.A + b.B) }
+`)},
+		"_pkg/src/guthib.com/a/a.go": &fstest.MapFile{Data: []byte(`package a
+
+import "guthib.com/b"
+
+var A = b.B + 1
+`)},
+		"_pkg/src/guthib.com/b/b.go": &fstest.MapFile{Data: []byte(`package b
+
+import "strings"
+
+var B = len(strings.Repeat("b", 2))
+`)},
+		"_pkg/src/guthib.com/c/c.go": &fstest.MapFile{Data: []byte(`package c
+
+import "guthib.com/d"
+
+var C = d.D
+`)},
+		"_pkg/src/guthib.com/d/d.go": &fstest.MapFile{Data: []byte(`package d
+
+import "guthib.com/c"
+
+var D = c.C
+`)},
+	}
+	var stdout bytes.Buffer
+	i := interp.New(interp.Options{GoPath: "./_pkg", SourcecodeFilesystem: filesystem, Stdout: &stdout})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.EvalPath("main.go"); err != nil {
+		t.Fatal(err)
+	}
+
+	g := i.ImportGraph()
+	if got, want := fmt.Sprint(g.Packages()), "[guthib.com/b guthib.com/a main]"; got != want {
+		t.Errorf("got packages %s, want %s", got, want)
+	}
+	if imps := g["guthib.com/a"]; len(imps) != 1 || imps[0].Path != "guthib.com/b" || imps[0].Pos.Line != 3 {
+		t.Errorf("unexpected imports of a: %+v", imps)
+	}
+	if imps := g["main"]; len(imps) != 2 || imps[0].Path != "guthib.com/a" || imps[1].Path != "guthib.com/b" {
+		t.Errorf("unexpected imports of main: %+v", imps)
+	}
+
+	// The cycle is reported each time, with the position of its imports.
+	want := "import cycle not allowed: guthib.com/c → guthib.com/d → guthib.com/c\n" +
+		"\t_pkg/src/guthib.com/c/c.go:3:8: guthib.com/c imports guthib.com/d\n" +
+		"\t_pkg/src/guthib.com/d/d.go:3:8: guthib.com/d imports guthib.com/c"
+	for k := 0; k < 2; k++ {
+		_, err := i.Eval(`import "guthib.com/c"`)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("got error %v, want %q", err, want)
+		}
+	}
+	if _, ok := i.ImportGraph()["guthib.com/c"]; ok {
+		t.Error("unexpected imports of a package which failed to compile")
+	}
+}
+
+func TestLazyInit(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"_pkg/src/guthib.com/a/a.go": &fstest.MapFile{Data: []byte(`package a
+
+var A = trace("var a", 1)
+
+func init() { println("init a") }
+
+func trace(s string, v int) int { println(s); return v }
+`)},
+		"_pkg/src/guthib.com/b/b.go": &fstest.MapFile{Data: []byte(`package b
+
+import "guthib.com/a"
+
+var B = a.A + 1
+
+func init() { println("init b") }
+
+func Get() int { return B }
+`)},
+		"_pkg/src/guthib.com/c/c.go": &fstest.MapFile{Data: []byte(`package c
+
+var C = 3
+
+func init() { println("init c") }
+`)},
+		"_pkg/src/guthib.com/d/d.go": &fstest.MapFile{Data: []byte(`package d
+
+func init() { println("init d") }
+`)},
+	}
+	var stdout bytes.Buffer
+	i := interp.New(interp.Options{GoPath: "./_pkg", SourcecodeFilesystem: filesystem, Stdout: &stdout, LazyInit: true})
+	eval(t, i, `import "guthib.com/b"`)
+	eval(t, i, `import "guthib.com/c"`)
+	if stdout.Len() != 0 {
+		t.Fatalf("unexpected initialization at import: %q", stdout.String())
+	}
+
+	res := eval(t, i, "b.Get()")
+	if got, want := stdout.String(), "var a\ninit a\ninit b\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if res.Interface() != 2 {
+		t.Errorf("got %v, want 2", res)
+	}
+
+	// A package is initialized once.
+	stdout.Reset()
+	eval(t, i, "b.Get()")
+	if stdout.Len() != 0 {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+
+	// Symbols returns the values of initialized packages.
+	c := i.Symbols("guthib.com/c")["guthib.com/c"]["C"]
+	if got := stdout.String(); got != "init c\n" {
+		t.Errorf("got %q, want %q", got, "init c\n")
+	}
+	if c.Interface() != 3 {
+		t.Errorf("got %v, want 3", c)
+	}
+
+	// A blank import initializes the package, for its side effects.
+	stdout.Reset()
+	eval(t, i, `import _ "guthib.com/d"`)
+	if got := stdout.String(); got != "init d\n" {
+		t.Errorf("got %q, want %q", got, "init d\n")
+	}
+}
+
+func TestRegistryCapabilities(t *testing.T) {
+	r := interp.NewRegistry()
+	if err := r.Register(interp.VirtualPackage{
+		Path:    "host/info/info",
+		Symbols: map[string]reflect.Value{"Version": reflect.ValueOf(func() string { return "1.0" })},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(interp.VirtualPackage{
+		Path:     "host/admin/admin",
+		Symbols:  map[string]reflect.Value{"Reset": reflect.ValueOf(func() string { return "reset" })},
+		Requires: []interp.Capability{"admin"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(interp.VirtualPackage{Path: "host/info/info"}); err == nil {
+		t.Fatal("expected error on duplicate registration")
+	}
+
+	user := interp.New(interp.Options{})
+	if err := user.UseRegistry(r); err != nil {
+		t.Fatal(err)
+	}
+	runTests(t, user, []testCase{
+		{pre: func() { eval(t, user, `import "host/info"`) }, src: `info.Version()`, res: "1.0"},
+		{src: `import "host/admin"`, err: "1:21: import \"host/admin\" error: unable to find source related to: \"host/admin\". Either the GOPATH environment variable, or the Interpreter.Options.GoPath needs to be set"},
+	})
+
+	admin := interp.New(interp.Options{Capabilities: []interp.Capability{"admin"}})
+	if !admin.HasCapability("admin") {
+		t.Fatal("missing admin capability")
+	}
+	if err := admin.UseRegistry(r); err != nil {
+		t.Fatal(err)
+	}
+	runTests(t, admin, []testCase{
+		{pre: func() { eval(t, admin, `import "host/admin"`) }, src: `admin.Reset()`, res: "reset"},
+	})
+}
+
+func TestManualClock(t *testing.T) {
+	clock := interp.NewManualClock(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	i := interp.New(interp.Options{Clock: clock})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	i.ImportUsed()
+	runTests(t, i, []testCase{
+		{src: "time.Now().Year()", res: "2000"},
+		{pre: func() { eval(t, i, "var start = time.Now()") }, src: "time.Since(start)", res: "0s"},
+	})
+
+	done := make(chan string)
+	go func() {
+		v, err := i.Eval(`time.Sleep(time.Hour); <-time.After(time.Minute); time.Since(start).String()`)
+		if err != nil {
+			done <- err.Error()
+			return
+		}
+		done <- v.String()
+	}()
+
+	for _, d := range []time.Duration{time.Hour, time.Minute} {
+		for clock.Waiting() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		clock.Advance(d)
+	}
+	select {
+	case s := <-done:
+		if s != "1h1m0s" {
+			t.Fatalf("got %q, want 1h1m0s", s)
+		}
+	case <-time.After(applyCIMultiplier(5 * time.Second)):
+		t.Fatal("timeout")
+	}
+
+	// Timers, tickers and functions are scheduled on the clock.
+	eval(t, i, `var ready, fired = make(chan bool), make(chan string, 1)`)
+	eval(t, i, `func timers() string {
+	start := time.Now()
+	tk := time.NewTicker(time.Second)
+	defer tk.Stop()
+	time.AfterFunc(time.Hour, func() { fired <- time.Since(start).String() })
+	if !time.NewTimer(time.Minute).Stop() {
+		return "stop failed"
+	}
+	ready <- true
+	<-tk.C
+	ready <- true
+	<-tk.C
+	t := time.NewTimer(time.Hour)
+	t.Reset(2 * time.Minute)
+	ready <- true
+	<-t.C
+	elapsed := time.Since(start).String()
+	ready <- true
+	return elapsed + " " + <-fired
+}`)
+	ready := eval(t, i, "ready").Interface().(chan bool)
+	go func() {
+		v, err := i.Eval(`timers()`)
+		if err != nil {
+			done <- err.Error()
+			return
+		}
+		done <- v.String()
+	}()
+	for _, d := range []time.Duration{time.Second, time.Second, 2 * time.Minute, time.Hour} {
+		select {
+		case <-ready:
+		case s := <-done:
+			t.Fatal(s)
+		}
+		clock.Advance(d)
+	}
+	select {
+	case s := <-done:
+		if s != "2m2s 1h2m2s" {
+			t.Fatalf("got %q, want %q", s, "2m2s 1h2m2s")
+		}
+	case <-time.After(applyCIMultiplier(5 * time.Second)):
+		t.Fatal("timeout")
+	}
+
+	// A sleep returns when its run is cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := i.EvalWithContext(ctx, `time.Sleep(time.Hour)`); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	for start := time.Now(); clock.Waiting() > 0; {
+		if time.Since(start) > applyCIMultiplier(5*time.Second) {
+			t.Fatalf("got %d pending timers, want none", clock.Waiting())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRenderer(t *testing.T) {
+	var stdout bytes.Buffer
+	i := interp.New(interp.Options{
+		Stdout:   &stdout,
+		Renderer: &interp.ValueRenderer{MaxWidth: 6, FloatFormat: 'f', FloatPrecision: 2},
+	})
+	if _, err := i.Eval(`println(3.14159, "abcdefghij", 42)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.Eval(`print(float32(1) / 3)`); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "3.14 abcdef... 42\n0.33"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestBridge(t *testing.T) {
+	lib := interp.New(interp.Options{})
+	eval(t, lib, `package lib
+
+type Point struct{ X, Y int }
+
+func Sum(a ...int) (s int) {
+	for _, v := range a {
+		s += v
+	}
+	return s
+}
+
+func Any(i int) interface{} { return Point{i, i} }
+
+func Fail(msg string) { panic(msg) }
+`)
+	exports, err := lib.Bridge("lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := interp.New(interp.Options{})
+	if err := i.Use(exports); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import "lib"`)
+	runTests(t, i, []testCase{
+		{src: "lib.Sum(1, 2, 3)", res: "6"},
+		{src: "lib.Any(2)", res: "{2 2}"},
+		{
+			pre: func() {
+				eval(t, i, `func try() (r string) { defer func() { r = recover().(error).Error() }(); lib.Fail("boom"); return }`)
+			},
+			src: "try()", res: "lib.Fail: panic: boom",
+		},
+	})
+
+	// The library interpreter remains usable after a panic.
+	if res := eval(t, lib, "lib.Sum(4, 5)"); res.Interface() != 9 {
+		t.Fatalf("got %v, want 9", res)
+	}
+}
+
+func TestChanCancellation(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import "time"`)
+	// The functions are compiled outside of a cancellable run.
+	eval(t, i, `var exited = make(chan bool)`)
+	eval(t, i, `func wait(c chan int) int { defer close(exited); v := <-c; return v }`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := i.EvalWithContext(ctx, `wait(make(chan int))`); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	select {
+	case <-eval(t, i, "exited").Interface().(chan bool):
+	case <-time.After(applyCIMultiplier(time.Second)):
+		t.Fatal("blocking receive was not cancelled")
+	}
+
+	// Blocking operations are not affected by a previous cancellation.
+	eval(t, i, `exited = make(chan bool)`)
+	eval(t, i, `func f() int { c := make(chan int); go func() { time.Sleep(10 * time.Millisecond); c <- 3 }(); return wait(c) }`)
+	if res := eval(t, i, "f()"); res.Interface() != 3 {
+		t.Fatalf("got %v, want 3", res)
+	}
+
+	// The top-level operations compiled by Eval can not be cancelled, and
+	// use the non-cancellable builtins.
+	i = interp.New(interp.Options{})
+	eval(t, i, `var c = make(chan int)`)
+	eval(t, i, `func fill() { for k := 1; k <= 3; k++ { c <- k }; close(c) }`)
+	eval(t, i, `go fill()`)
+	if res := eval(t, i, `<-c`); res.Interface() != 1 {
+		t.Fatalf("got %v, want 1", res)
+	}
+	eval(t, i, `var s, v int`)
+	eval(t, i, `var ok bool`)
+	eval(t, i, `for v := range c { s += v }`)
+	eval(t, i, `v, ok = <-c`)
+	if res := eval(t, i, `s`); res.Interface() != 5 {
+		t.Fatalf("got %v, want 5", res)
+	}
+	if res := eval(t, i, `ok`); res.Interface() != false {
+		t.Fatalf("got %v, want false", res)
+	}
+
+	// The ones compiled by EvalWithContext remain cancellable.
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := i.EvalWithContext(ctx, `c = make(chan int); c <- 1`); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestChanBridge(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `
+type Event struct { Name string; N int }
+
+var (
+	events   = make(chan Event, 2)
+	values   = make(chan interface{})
+	requests = make(chan int)
+	quit     = make(chan bool)
+)
+
+func sum() (s int) {
+	for {
+		select {
+		case n, ok := <-requests:
+			if !ok {
+				return s
+			}
+			s += n
+		case <-quit:
+			return -1
+		}
+	}
+}
+`)
+
+	type event struct {
+		Name string
+		N    int
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out, err := interp.ChanOut[event](ctx, i, "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `events <- Event{"a", 1}; events <- Event{"b", 2}; close(events)`)
+	var got []event
+	for e := range out {
+		got = append(got, e)
+	}
+	if want := []event{{"a", 1}, {"b", 2}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	vals, err := interp.ChanOut[any](ctx, i, "values")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `go func() { values <- 3; values <- "x"; values <- Event{"c", 3} }()`)
+	for _, want := range []any{3, "x", struct {
+		Name string
+		N    int
+	}{"c", 3}} {
+		if v := <-vals; !reflect.DeepEqual(v, want) {
+			t.Fatalf("got %#v, want %#v", v, want)
+		}
+	}
+
+	in, err := interp.ChanIn[int](ctx, i, "requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for k := 1; k <= 4; k++ {
+			in <- k
+		}
+		close(in)
+	}()
+	if res := eval(t, i, "sum()"); res.Interface() != 10 {
+		t.Fatalf("got %v, want 10", res)
+	}
+
+	// A script blocked on a bridged channel is cancellable.
+	eval(t, i, `requests = make(chan int)`)
+	if _, err := interp.ChanIn[int](ctx, i, "requests"); err != nil {
+		t.Fatal(err)
+	}
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel2()
+	if _, err := i.EvalWithContext(ctx2, `sum()`); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if _, err := interp.ChanOut[string](ctx, i, "requests"); err == nil {
+		t.Fatal("expected an element type error")
+	}
+	if _, err := interp.ChanIn[int](ctx, i, "quit"); err == nil {
+		t.Fatal("expected an element type error")
+	}
+	if _, err := interp.ChanIn[int](ctx, i, "sum"); err == nil {
+		t.Fatal("expected a channel type error")
+	}
+}
+func TestImplements(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+type Greeter struct{ Name string }
+
+func (g Greeter) String() string { return "hello " + g.Name }
+
+type source struct{ r io.Reader }
+
+func (s *source) Read(p []byte) (int, error) { return s.r.Read(p) }
+func (s *source) Close() error             { return nil }
+
+func newSource() *source { return &source{strings.NewReader("data")} }
+
+func hello(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hi " + r.URL.Path)) }
+`)
+
+	v, err := i.Implements("main.Greeter{Name: \"bob\"}", reflect.TypeOf((*fmt.Stringer)(nil)).Elem())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := v.(fmt.Stringer).String(); s != "hello bob" {
+		t.Fatalf("got %q, want %q", s, "hello bob")
+	}
+
+	v, err = i.Implements("newSource()", reflect.TypeOf((*io.ReadCloser)(nil)).Elem())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(v.(io.ReadCloser))
+	if err != nil || string(b) != "data" {
+		t.Fatalf("got %q, %v, want %q", b, err, "data")
+	}
+
+	v, err = i.Implements("hello", reflect.TypeOf((*http.Handler)(nil)).Elem())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	v.(http.Handler).ServeHTTP(rec, httptest.NewRequest("GET", "/x", nil))
+	if got := rec.Body.String(); got != "hi /x" {
+		t.Fatalf("got %q, want %q", got, "hi /x")
+	}
+
+	// A binary value is returned as is.
+	v, err = i.Implements(`strings.NewReader("x")`, reflect.TypeOf((*io.Reader)(nil)).Elem())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(*strings.Reader); !ok {
+		t.Fatalf("got %T, want *strings.Reader", v)
+	}
+
+	type local interface{ String() string }
+	for _, test := range []struct {
+		symbol string
+		typ    reflect.Type
+	}{
+		{"main.Greeter{}", reflect.TypeOf((*io.Reader)(nil)).Elem()},
+		{"main.Greeter{}", reflect.TypeOf((*local)(nil)).Elem()},
+		{"main.Greeter{}", reflect.TypeOf(0)},
+		{"hello", reflect.TypeOf((*fmt.Stringer)(nil)).Elem()},
+		{"hello", reflect.TypeOf((*io.ReadCloser)(nil)).Elem()},
+	} {
+		if _, err := i.Implements(test.symbol, test.typ); err == nil {
+			t.Errorf("%s as %v: expected an error", test.symbol, test.typ)
+		}
+	}
+}
+
+func TestExportType(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	// Struct tags are quoted by ' in the source below.
+	eval(t, i, strings.ReplaceAll(`
+import "fmt"
+
+type Base struct{ ID int 'json:"id"' }
+
+type Named interface{ Name() string }
+
+type User struct {
+	Base
+	Login    string            'json:"login"'
+	Tags     []string          'json:"tags,omitempty"'
+	Attrs    map[string]string 'json:"attrs,omitempty"'
+	Manager  *User             'json:"manager,omitempty"'
+	Extra    Named             'json:"extra,omitempty"'
+	password string
+}
+
+type Team struct{ Label string }
+
+func (t Team) Name() string { return t.Label }
+
+var bob = &User{Base{2}, "bob", nil, nil, &User{Base: Base{1}, Login: "alice"}, Team{"ops"}, "secret"}
+
+func describe(u User) string { return fmt.Sprintf("%d %s %v %v", u.ID, u.Login, u.Tags, u.Manager.Login) }
+`, "'", "`"))
+
+	typ, err := i.ExportType("main.User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := typ.FieldByName("Manager"); !ok || f.Type != reflect.PtrTo(typ) {
+		t.Fatalf("got field %v, want *%v", f, typ)
+	}
+	if _, ok := typ.FieldByName("password"); ok {
+		t.Fatal("unexpected unexported field")
+	}
+
+	v, err := i.ExportValue(eval(t, i, "bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":2,"login":"bob","manager":{"id":1,"login":"alice"},"extra":{"Label":"ops"}}`
+	if string(b) != want {
+		t.Fatalf("got %s, want %s", b, want)
+	}
+
+	u := reflect.New(typ)
+	if err := json.Unmarshal([]byte(`{"id":3,"login":"carol","tags":["a"],"manager":{"login":"dave"}}`), u.Interface()); err != nil {
+		t.Fatal(err)
+	}
+	iv, err := i.ImportValue(u.Elem())
+	if err != nil {
+		t.Fatal(err)
+	}
+	describe := eval(t, i, "describe")
+	if got := describe.Call([]reflect.Value{iv})[0].String(); got != "3 carol [a] dave" {
+		t.Fatalf("got %q, want %q", got, "3 carol [a] dave")
+	}
+
+	if _, err := i.ExportType("main.Named"); err == nil {
+		t.Fatal("expected an error for an interface type")
+	}
+	if _, err := i.ExportValue(reflect.ValueOf(1)); err == nil {
+		t.Fatal("expected an error for a non exported type")
+	}
+}
+
+func TestTypeOf(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	// Struct tags are quoted by ' in the source below.
+	eval(t, i, strings.ReplaceAll(`
+type Route struct {
+	Path   string 'route:"/users" method:"GET"'
+	Limit  int    'validate:"max=100"'
+	secret string 'json:"-"'
+}
+
+type Handler interface{ Serve() }
+
+var routes = []Route{{Path: "/"}}
+
+func path(r *Route) string { return r.Path }
+`, "'", "`"))
+
+	typ, err := i.TypeOf("main.Route")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ.Kind() != reflect.Struct || typ.NumField() != 3 {
+		t.Fatalf("got %v, want a struct of 3 fields", typ)
+	}
+	if got := tagValues(typ, "route"); !reflect.DeepEqual(got, map[string]string{"Path": "/users"}) {
+		t.Fatalf("got route tags %v", got)
+	}
+	if got := tagValues(typ, "validate"); !reflect.DeepEqual(got, map[string]string{"Limit": "max=100"}) {
+		t.Fatalf("got validate tags %v", got)
+	}
+	if f, ok := typ.FieldByName("Xsecret"); !ok || f.Tag.Get("json") != "-" {
+		t.Fatalf("got field %v", f)
+	}
+
+	tags, err := i.StructTags("main.Route")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags["Path"].Get("method") != "GET" || tags["secret"].Get("json") != "-" || len(tags) != 3 {
+		t.Fatalf("got tags %v", tags)
+	}
+	if tags, err := i.StructTags("main.routes"); err == nil {
+		t.Fatalf("got tags %v, want an error for a slice", tags)
+	}
+
+	// Values of the returned type can be passed to interpreted functions.
+	v := reflect.New(typ)
+	v.Elem().Field(0).SetString("/admin")
+	if got := eval(t, i, "path").Call([]reflect.Value{v})[0].String(); got != "/admin" {
+		t.Fatalf("got %q, want %q", got, "/admin")
+	}
+
+	if rt, err := i.TypeOf("main.routes"); err != nil || rt != reflect.SliceOf(typ) {
+		t.Fatalf("got %v, %v, want %v", rt, err, reflect.SliceOf(typ))
+	}
+	if _, err := i.TypeOf("main.Handler"); err == nil {
+		t.Fatal("expected an error for an interface type")
+	}
+	if _, err := i.TypeOf("main.Unknown"); err == nil {
+		t.Fatal("expected an error for an undefined symbol")
+	}
+	if _, err := i.TypeOf("Route"); err == nil {
+		t.Fatal("expected an error for an unqualified symbol")
+	}
+	if rt, err := i.TypeOf("main.path"); err != nil || rt.Kind() != reflect.Func || rt.In(0) != reflect.PointerTo(typ) {
+		t.Fatalf("got %v, %v", rt, err)
+	}
+
+	// The lookup does not declare symbols in the interpreter.
+	eval(t, i, "var Route2 = Route{Path: `/x`}")
+	if got := eval(t, i, "Route2.Path").String(); got != "/x" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+// tagValues returns the values of the tag key of the fields of struct type
+// typ which have one, indexed by field name, as a host framework reads them.
+func tagValues(typ reflect.Type, key string) map[string]string {
+	m := map[string]string{}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if v, ok := f.Tag.Lookup(key); ok {
+			m[f.Name] = v
+		}
+	}
+	return m
+}
+
+func TestPool(t *testing.T) {
+	p, err := interp.NewPool(interp.PoolOptions{
+		Size:       2,
+		Use:        []interp.Exports{stdlib.Symbols},
+		ImportUsed: true,
+		Prelude:    `var count int; func incr() int { count++; return count }`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	ctx := context.Background()
+	for k := 0; k < 5; k++ {
+		i, err := p.Acquire(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Globals are reset between uses.
+		if res := eval(t, i, "incr()"); res.Interface() != 1 {
+			t.Fatalf("got %v, want 1", res)
+		}
+		if res := eval(t, i, `strings.ToUpper("a")`); res.Interface() != "A" {
+			t.Fatalf("got %v, want A", res)
+		}
+		if err := p.Release(i); err != nil {
+			t.Fatal(err)
+		}
+		// Duplicate or foreign releases are rejected.
+		if err := p.Release(i); err == nil {
+			t.Fatal("expected an error for a duplicate release")
+		}
+	}
+	if err := p.Release(interp.New(interp.Options{})); err == nil {
+		t.Fatal("expected an error for the release of a foreign interpreter")
+	}
+
+	p.Close()
+	if _, err := p.Acquire(ctx); !errors.Is(err, interp.ErrPoolClosed) {
+		t.Fatalf("got %v, want %v", err, interp.ErrPoolClosed)
+	}
+}
+
+func TestPoolRefillError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := interp.NewPool(interp.PoolOptions{
+		Use: []interp.Exports{stdlib.Symbols},
+		Prelude: fmt.Sprintf(`import "os"
+
+var _, err = os.Stat(%q)
+
+var checked = check()
+
+func check() int { if err != nil { panic(err) }; return 0 }`, file),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	i, err := p.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Release(i); err != nil {
+		t.Fatal(err)
+	}
+
+	// The refill error is returned by Acquire, instead of blocking.
+	if _, err := p.Acquire(ctx); err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the refill error", err)
+	}
+
+	// The pool recovers once the failure condition is gone.
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if i, err = p.Acquire(ctx); err == nil || errors.Is(err, context.DeadlineExceeded) {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Release(i); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecompileFunc(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"_pkg/src/guthib.com/calc/calc.go": &fstest.MapFile{Data: []byte(`package calc
+
+var factor = 10
+
+func Scale(x int) int { return x * factor }
+`)},
+	}
+	i := interp.New(interp.Options{GoPath: "./_pkg", SourcecodeFilesystem: filesystem})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import "guthib.com/calc"`)
+	eval(t, i, `
+var calls int
+
+func step(x int) int { calls++; return x + 1 }
+
+func run(x int) int { return calc.Scale(step(x)) }`)
+	run := eval(t, i, "run").Interface().(func(int) int)
+	if res := run(1); res != 20 {
+		t.Fatalf("got %d, want 20", res)
+	}
+
+	// Existing references use the new code, in the existing package scopes.
+	if err := i.RecompileFunc("", "step", "func step(x int) int { calls++; return x + 2 }"); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.RecompileFunc("guthib.com/calc", "Scale", "func Scale(x int) int { return x * factor * 2 }"); err != nil {
+		t.Fatal(err)
+	}
+	if res := run(1); res != 60 {
+		t.Errorf("got %d, want 60", res)
+	}
+	runTests(t, i, []testCase{{src: "calls", res: "2"}})
+
+	for _, test := range []struct{ pkg, name, src, err string }{
+		{name: "step", src: "func step(x int64) int { return 0 }", err: "new signature"},
+		{name: "step", src: "func other(x int) int { return 0 }", err: "not the declaration of function step"},
+		{name: "step", src: "func step(x int) int { return undefined }", err: "undefined: undefined"},
+		{name: "calls", src: "func calls() {}", err: "main.calls is not a function"},
+	} {
+		if err := i.RecompileFunc(test.pkg, test.name, test.src); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.src, err, test.err)
+		}
+	}
+	// Failed recompilations leave the function unchanged.
+	if res := run(1); res != 60 {
+		t.Errorf("got %d, want 60", res)
+	}
+}
+
+func TestReloadPath(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "handler.go")
+	write := func(src string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(src), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`package main
+
+var count int
+
+func Handle() string { count++; return "v1" }
+
+func main() {}
+`)
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.EvalPath(file); err != nil {
+		t.Fatal(err)
+	}
+	handle := eval(t, i, "Handle").Interface().(func() string)
+	handle()
+
+	write(`package main
+
+import "strconv"
+
+var count int
+
+func Handle() string { count++; return "v2:" + suffix() }
+
+func suffix() string { return strconv.Itoa(count) }
+
+func main() {}
+`)
+	if err := i.ReloadPath(file); err != nil {
+		t.Fatal(err)
+	}
+	// Existing references use the new code, with preserved state.
+	if res := handle(); res != "v2:2" {
+		t.Fatalf("got %q, want %q", res, "v2:2")
+	}
+	runTests(t, i, []testCase{{src: "Handle()", res: "v2:3"}})
+
+	write(`package main
+
+var count int
+
+func Handle(s string) string { return s }
+`)
+	if err := i.ReloadPath(file); err == nil || !strings.Contains(err.Error(), "new signature") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runTests(t, i, []testCase{{src: "Handle()", res: "v2:4"}})
+}
+
+func TestWatch(t *testing.T) {
+	interp.SetWatchInterval(10 * time.Millisecond)
+	defer interp.SetWatchInterval(500 * time.Millisecond)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	pkg := "app"
+	if err := os.MkdirAll(filepath.Join(dir, "src", pkg), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, src string) {
+		t.Helper()
+		if err := os.WriteFile(name, []byte(src), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		path, file string
+		srcs       []string
+	}{
+		{path: file, file: file, srcs: []string{
+			"package main\nvar Version = 0\nfunc Get() int { return Version + 1 }\n",
+			"package main\nvar Version = 0\nfunc Get() int { return Version + 2 }\n",
+		}},
+		{path: pkg, file: filepath.Join(dir, "src", pkg, "main.go"), srcs: []string{
+			"package main\nvar Version = 0\nfunc Get() int { return 1 }\nfunc main() { Version = Get() }\n",
+			"package main\nvar Version = 0\nfunc Get() int { return 2 }\nfunc main() { Version = Get() }\n",
+		}},
+	} {
+		t.Run(filepath.Base(test.path), func(t *testing.T) {
+			write(test.file, test.srcs[0])
+			i := interp.New(interp.Options{GoPath: dir})
+			reloads := make(chan error)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() { done <- i.Watch(ctx, test.path, func(err error) { reloads <- err }) }()
+
+			for n, src := range test.srcs {
+				if n > 0 {
+					write(test.file, src)
+				}
+				select {
+				case err := <-reloads:
+					if err != nil {
+						t.Fatal(err)
+					}
+				case <-time.After(applyCIMultiplier(5 * time.Second)):
+					t.Fatal("timeout")
+				}
+				get := i.Symbols(test.path)[test.path]["Get"]
+				if test.path == file {
+					get = eval(t, i, "Get")
+				}
+				if res := get.Call(nil)[0].Interface(); res != n+1 {
+					t.Fatalf("got %v, want %d", res, n+1)
+				}
+			}
+			cancel()
+			if err := <-done; !errors.Is(err, context.Canceled) {
+				t.Fatalf("got %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
+
+func TestWatchRunning(t *testing.T) {
+	interp.SetWatchInterval(10 * time.Millisecond)
+	defer interp.SetWatchInterval(500 * time.Millisecond)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	pkg := "app"
+	if err := os.MkdirAll(filepath.Join(dir, "src", pkg), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, src string) {
+		t.Helper()
+		if err := os.WriteFile(name, []byte(src), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The main function reports its version, then blocks until the program
+	// is stopped.
+	src := func(version int) string {
+		return fmt.Sprintf("package main\nimport \"host\"\nfunc Get() int { return %d }\nfunc main() { host.Started(Get()); <-make(chan int) }\n", version)
+	}
+
+	for _, test := range []struct{ path, file string }{
+		{path: file, file: file},
+		{path: pkg, file: filepath.Join(dir, "src", pkg, "main.go")},
+	} {
+		t.Run(filepath.Base(test.path), func(t *testing.T) {
+			write(test.file, src(1))
+			i := interp.New(interp.Options{GoPath: dir})
+			started := make(chan int)
+			if err := i.Use(interp.Exports{"host/host": {"Started": reflect.ValueOf(func(v int) { started <- v })}}); err != nil {
+				t.Fatal(err)
+			}
+			reloads := make(chan error, 1)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() { done <- i.Watch(ctx, test.path, func(err error) { reloads <- err }) }()
+
+			for version := 1; version <= 3; version++ {
+				if version > 1 {
+					write(test.file, src(version))
+				}
+				select {
+				case v := <-started:
+					if v != version {
+						t.Fatalf("got %d, want %d", v, version)
+					}
+				case err := <-reloads:
+					t.Fatalf("unexpected end of evaluation: %v", err)
+				case <-time.After(applyCIMultiplier(5 * time.Second)):
+					t.Fatal("timeout")
+				}
+			}
+			cancel()
+			if err := <-done; !errors.Is(err, context.Canceled) {
+				t.Fatalf("got %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		src  string
+		want interp.Diagnostic
+	}{
+		{src: "func f() int {\n\treturn x\n}", want: interp.Diagnostic{File: "_.go", Line: 2, Column: 9, Message: "undefined: x", Code: interp.CodeCompile}},
+		{src: "func f() {\n\treturn 1 +\n}", want: interp.Diagnostic{File: "_.go", Line: 3, Column: 1, Message: "expected operand, found '}'", Code: interp.CodeSyntax}},
+	}
+
+	for _, test := range tests {
+		i := interp.New(interp.Options{})
+		_, err := i.Eval(test.src)
+		if err == nil {
+			t.Fatalf("%q: expected an error", test.src)
+		}
+		diags := interp.Diagnostics(err)
+		if len(diags) == 0 {
+			t.Fatalf("%q: no diagnostics for %v", test.src, err)
+		}
+		if diags[0] != test.want {
+			t.Errorf("%q: got %+v, want %+v", test.src, diags[0], test.want)
+		}
+	}
+
+	if diags := interp.Diagnostics(errors.New("not a compile error")); diags != nil {
+		t.Errorf("got %+v, want nil", diags)
+	}
+}
+
+func TestMaxErrors(t *testing.T) {
+	src := `package main
+
+func f() int {
+	return x
+}
+
+func g() string {
+	var s string = 1
+	return s
+}
+
+func h() int { return f() }
+
+func k() {
+	y = 2
+}
+`
+	for _, test := range []struct {
+		max  int
+		want []string
+	}{
+		{max: 0, want: []string{"_.go:4:9: undefined: x"}},
+		{max: 2, want: []string{"_.go:4:9: undefined: x", "_.go:8:17: cannot convert 1 to string"}},
+		{max: 10, want: []string{"_.go:4:9: undefined: x", "_.go:8:17: cannot convert 1 to string", "_.go:15:2: undefined: y"}},
+	} {
+		i := interp.New(interp.Options{MaxErrors: test.max})
+		_, err := i.Eval(src)
+		if err == nil {
+			t.Fatalf("max %d: expected an error", test.max)
+		}
+		var got []string
+		for _, d := range interp.Diagnostics(err) {
+			got = append(got, fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("max %d: got %q, want %q", test.max, got, test.want)
+		}
+		if len(test.want) > 1 && strings.Count(err.Error(), "\n") != len(test.want)-1 {
+			t.Errorf("max %d: unexpected error %q", test.max, err)
+		}
+	}
+}
+
+func TestSymbolHooks(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	trace := func(tag string) interp.SymbolHook {
+		return func(path, name string, v reflect.Value) reflect.Value {
+			if path != "strings" || v.Kind() != reflect.Func {
+				return v
+			}
+			return reflect.MakeFunc(v.Type(), func(in []reflect.Value) []reflect.Value {
+				calls = append(calls, tag+" "+name)
+				return v.Call(in)
+			})
+		}
+	}
+	for _, h := range []struct {
+		name  string
+		order int
+	}{{"b", 1}, {"a", 1}, {"c", 0}} {
+		if err := i.AddSymbolHook(h.name, h.order, trace(h.name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := i.AddSymbolHook("a", 2, trace("a")); err == nil {
+		t.Fatal("expected an error for a duplicate hook name")
+	}
+	if got, want := i.SymbolHooks(), []string{"c", "a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if err := i.AddSymbolHook("hide", 3, func(path, name string, v reflect.Value) reflect.Value {
+		if path == "strings" && name == "Repeat" {
+			return reflect.Value{}
+		}
+		return v
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	eval(t, i, `import "strings"`)
+	v := eval(t, i, `strings.ToUpper("x")`)
+	if v.Interface() != "X" {
+		t.Fatalf("got %v, want X", v)
+	}
+	// The last applied hook wraps the others, so it is called first.
+	if want := []string{"b ToUpper", "a ToUpper", "c ToUpper"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("got %v, want %v", calls, want)
+	}
+	if _, err := i.Eval(`strings.Repeat("x", 2)`); err == nil || !strings.Contains(err.Error(), "has no symbol Repeat") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if !i.RemoveSymbolHook("hide") || i.RemoveSymbolHook("hide") {
+		t.Fatal("unexpected result of RemoveSymbolHook")
+	}
+	if v := eval(t, i, `strings.Repeat("x", 2)`); v.Interface() != "xx" {
+		t.Fatalf("got %v, want xx", v)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import "strings"`)
+	eval(t, i, `func before(s string) string { return strings.ToUpper(s) }`)
+
+	upper := reflect.ValueOf(func(s string) string { return "stub " + s })
+	if err := i.Override("strings", "ToUpper", upper); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		path, name string
+		v          reflect.Value
+	}{
+		{"strings", "Missing", upper},
+		{"strings", "Builder", reflect.ValueOf((*strings.Builder)(nil))},
+		{"strings", "ToLower", reflect.ValueOf(strings.Repeat)},
+		{"strings", "ToLower", reflect.Value{}},
+	} {
+		if err := i.Override(test.path, test.name, test.v); err == nil {
+			t.Errorf("expected an error overriding %s.%s", test.path, test.name)
+		}
+	}
+
+	if v := eval(t, i, `strings.ToUpper("x")`); v.Interface() != "stub x" {
+		t.Fatalf("got %v, want stub x", v)
+	}
+	// Code compiled before the override is not affected.
+	if v := eval(t, i, `before("x")`); v.Interface() != "X" {
+		t.Fatalf("got %v, want X", v)
+	}
+
+	if !i.RemoveOverride("strings", "ToUpper") || i.RemoveOverride("strings", "ToUpper") {
+		t.Fatal("unexpected result of RemoveOverride")
+	}
+	if v := eval(t, i, `strings.ToUpper("x")`); v.Interface() != "X" {
+		t.Fatalf("got %v, want X", v)
+	}
+}
+
+func TestLazyUse(t *testing.T) {
+	i := interp.New(interp.Options{})
+	calls := 0
+	err := i.Use(interp.Exports{
+		"example.com/greet/greet": interp.Lazy(func() map[string]reflect.Value {
+			calls++
+			return map[string]reflect.Value{
+				"Hello": reflect.ValueOf(func(s string) string { return "hello " + s }),
+			}
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `func f() int { return 1 }`)
+	if calls != 0 {
+		t.Fatalf("provider called %d times before import", calls)
+	}
+
+	eval(t, i, `import "example.com/greet"`)
+	if v := eval(t, i, `greet.Hello("world")`); v.Interface() != "hello world" {
+		t.Fatalf("got %v, want hello world", v)
+	}
+	if _, ok := i.Symbols("example.com/greet")["example.com/greet"]["Hello"]; !ok {
+		t.Error("missing symbol Hello")
+	}
+	if calls != 1 {
+		t.Errorf("provider called %d times, want 1", calls)
+	}
+}
+
+func TestAuditStdlib(t *testing.T) {
+	i := interp.New(interp.Options{})
+	err := i.Use(interp.Exports{
+		"errors/errors": {
+			"Is":  reflect.ValueOf(errors.Is),
+			"New": reflect.ValueOf(errors.New),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := i.AuditStdlib("errors", "encoding/ascii85")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Ok() {
+		t.Error("expected gaps")
+	}
+	if want := []string{"encoding/ascii85", "errors"}; !reflect.DeepEqual(a.Packages, want) {
+		t.Errorf("got packages %v, want %v", a.Packages, want)
+	}
+	if want := []string{"encoding/ascii85"}; !reflect.DeepEqual(a.MissingPackages, want) {
+		t.Errorf("got missing packages %v, want %v", a.MissingPackages, want)
+	}
+	missing := strings.Join(a.MissingSymbols["errors"], " ")
+	if !strings.Contains(missing, "Join") || !strings.Contains(missing, "Unwrap") || strings.Contains(missing, "New") {
+		t.Errorf("unexpected missing symbols of errors: %s", missing)
+	}
+	if r := a.String(); !strings.Contains(r, "encoding/ascii85: missing package\n") || !strings.Contains(r, "errors.Join: missing symbol\n") {
+		t.Errorf("unexpected report %q", r)
+	}
+
+	if _, err := i.AuditStdlib("guthib.com/foo"); err == nil {
+		t.Error("expected an error for a non standard package")
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `
+import (
+	"errors"
+	"strings"
+)
+
+func Trim(s string) (string, error) { return strings.TrimSpace(s), nil }
+
+func Upper(s string) (string, error) { return strings.ToUpper(s), nil }
+
+func Check(s string) (string, error) {
+	if s == "" {
+		return s, errors.New("empty")
+	}
+	if s == "BOOM" {
+		panic("boom")
+	}
+	return s, nil
+}
+
+func Length(s string) int { return len(s) }
+`)
+
+	if _, err := interp.NewPipeline[string](i, "Trim", "Length"); err == nil {
+		t.Fatal("expected an error for a stage of invalid type")
+	}
+	if _, err := interp.NewPipeline[string](i, "Trim", "Missing"); err == nil {
+		t.Fatal("expected an error for an undefined stage")
+	}
+
+	p, err := interp.NewPipeline[string](i, "Trim", "Upper", "Check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, err := p.Run("  hello "); err != nil || res != "HELLO" {
+		t.Fatalf("got %q, %v, want HELLO", res, err)
+	}
+	if _, err := p.Run("  "); err == nil || err.Error() != "empty" {
+		t.Fatalf("got %v, want empty", err)
+	}
+	res, err := p.Run("boom")
+	var sp interp.StagePanic
+	if !errors.As(err, &sp) || sp.Stage != "Check" || sp.Value != "boom" || res != "BOOM" {
+		t.Fatalf("got %q, %v, want a panic in Check", res, err)
+	}
+	if res, err := p.Run("ok"); err != nil || res != "OK" {
+		t.Fatalf("after panic: got %q, %v, want OK", res, err)
+	}
+
+	stats := p.Stats()
+	if len(stats) != 3 {
+		t.Fatalf("got %d stats, want 3", len(stats))
+	}
+	for k, want := range []interp.StageStats{
+		{Name: "Trim", Calls: 4},
+		{Name: "Upper", Calls: 4},
+		{Name: "Check", Calls: 4, Errors: 1, Panics: 1},
+	} {
+		got := stats[k]
+		got.Duration = 0
+		if got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	src := `package main
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func main() {
+	s := 0
+	for i := 0; i < 3; i++ {
+		s += abs(i)
+	}
+	println(s)
+}
+`
+	i := interp.New(interp.Options{Coverage: true, Stdout: io.Discard, Stderr: io.Discard})
+	if _, err := i.Eval(src); err != nil {
+		t.Fatal(err)
+	}
+	lines := i.Coverage()["_.go"]
+	for line, want := range map[int]int64{4: 3, 5: 0, 7: 3, 11: 1, 13: 3, 15: 1} {
+		if got, ok := lines[line]; !ok || got != want {
+			t.Errorf("line %d: got %d (%v), want %d", line, got, ok, want)
+		}
+	}
+	if _, ok := lines[3]; ok {
+		t.Error("line 3 should not be executable")
+	}
+
+	var b strings.Builder
+	if err := i.Coverage().WriteProfile(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "mode: count\n") || !strings.Contains(b.String(), "\n_.go:5.1,6.1 1 0\n") {
+		t.Errorf("unexpected profile:\n%s", b.String())
+	}
+
+	if interp.New(interp.Options{}).Coverage() != nil {
+		t.Error("coverage should be nil when disabled")
+	}
+}
+
+func TestRunStats(t *testing.T) {
+	i := interp.New(interp.Options{CollectStats: true})
+	eval(t, i, `
+func depth(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return depth(n-1) + 1
+}
+
+func work() int {
+	s := make([]int, 0)
+	for k := 0; k < 10; k++ {
+		s = append(s, k)
+	}
+	p := new(int)
+	done := make(chan bool)
+	go func() { done <- true }()
+	<-done
+	return len(s) + *p + depth(5)
+}
+`)
+	_, rs, err := i.EvalWithStats("work()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Allocs != 13 || rs.Goroutines != 1 || rs.MaxDepth != 7 || rs.Steps < 50 || rs.WallTime <= 0 {
+		t.Fatalf("unexpected stats: %+v", rs)
+	}
+
+	_, rs, err = i.EvalWithStats("depth(2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Allocs != 0 || rs.Goroutines != 0 || rs.MaxDepth != 3 {
+		t.Fatalf("unexpected stats: %+v", rs)
+	}
+
+	// The stats of concurrent runs are collected separately.
+	r1, err := i.EvalAsync("work()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := i.EvalAsync("depth(2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs := r1.Stats(); rs.Allocs != 13 || rs.Goroutines != 1 || rs.MaxDepth != 7 {
+		t.Fatalf("unexpected stats: %+v", rs)
+	}
+	if rs := r2.Stats(); rs.Allocs != 0 || rs.Goroutines != 0 || rs.MaxDepth != 3 {
+		t.Fatalf("unexpected stats: %+v", rs)
+	}
+
+	if _, _, err := interp.New(interp.Options{}).EvalWithStats("1"); err == nil {
+		t.Error("expected an error when stats are not collected")
+	}
+}
+
+func TestProfile(t *testing.T) {
+	i := interp.New(interp.Options{})
+	eval(t, i, `
+func spin(n int) int {
+	s := 0
+	for k := 0; k < n; k++ {
+		s += k % 7
+	}
+	return s
+}
+
+func work() int { return spin(2000000) }
+`)
+	var b bytes.Buffer
+	if err := i.StartProfile(&b); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.StartProfile(io.Discard); err == nil {
+		t.Fatal("expected an error when profiling is already enabled")
+	}
+	eval(t, i, "work()")
+	if err := i.StopProfile(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := gzip.NewReader(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"main.spin", "main.work", "wall", "nanoseconds"} {
+		if !bytes.Contains(data, []byte(name)) {
+			t.Errorf("profile does not contain %q", name)
+		}
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	i := interp.New(interp.Options{})
+	eval(t, i, `
+type Point struct{ X, Y int }
+
+func (p Point) Sum() int { return p.X + p.Y }
+
+const Version = 1
+
+var Count = 2
+
+func Check(s string) bool { return s != "" }
+`)
+	if err := i.Freeze("main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Freeze("missing"); err == nil {
+		t.Fatal("expected an error for an unknown package")
+	}
+
+	for _, src := range []string{
+		`func Check(s string) bool { return true }`,
+		`Check := 3`,
+		`var Count = 4`,
+		`const Version = 2`,
+		`type Point int`,
+		`func (p *Point) Reset() { p.X, p.Y = 0, 0 }`,
+	} {
+		_, err := i.Eval(src)
+		if err == nil || !strings.Contains(err.Error(), "package main is frozen") {
+			t.Errorf("%s: got error %v, want a frozen package error", src, err)
+		}
+	}
+
+	// Frozen symbols remain usable, new ones can be declared, and shadowed
+	// in local scopes.
+	eval(t, i, `func Double(n int) int { Count := n; return 2 * Count }`)
+	eval(t, i, `Count = 5`)
+	if v := eval(t, i, `Check("x") && Double(Count) == 10 && Point{1, 2}.Sum() == 3 && Version == 1`); !v.Bool() {
+		t.Fatal("unexpected result")
+	}
+
+	// Packages can be frozen once compiled.
+	i = interp.New(interp.Options{FreezePackages: []string{"main"}})
+	eval(t, i, `func Check(s string) bool { return s != "" }`)
+	if _, err := i.Eval(`func Check(s string) bool { return true }`); err == nil {
+		t.Fatal("expected an error for a redeclaration in a frozen package")
+	}
+}
+
+func TestTracer(t *testing.T) {
+	i := interp.New(interp.Options{})
+	var mu sync.Mutex
+	var events []string
+	i.SetTracer(func(ev interp.TraceEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		s := fmt.Sprintf("%v %s %d", ev.Kind, ev.Func, ev.Pos.Line)
+		if ev.Value != nil {
+			s += fmt.Sprintf(" %v", ev.Value)
+		}
+		events = append(events, s)
+	})
+	eval(t, i, `
+func add(a, b int) int {
+	return a + b
+}
+
+func fail() {
+	panic("boom")
+}
+
+func safe() (r string) {
+	defer func() {
+		if recover() != nil {
+			r = "recovered"
+		}
+	}()
+	fail()
+	return
+}
+
+func run() int {
+	done := make(chan bool)
+	go func() { done <- true }()
+	<-done
+	safe()
+	return add(1, 2)
+}
+`)
+	eval(t, i, "run()")
+	i.SetTracer(nil)
+
+	has := func(s string) bool {
+		for _, e := range events {
+			if e == s {
+				return true
+			}
+		}
+		return false
+	}
+	for _, want := range []string{
+		"call main.run 20",
+		"line main.run 21",
+		"go main.run 22",
+		"call main.add 2",
+		"line main.add 3",
+		"return main.add 2",
+		"panic main.fail 7 boom",
+		"panic main.safe 16 boom",
+		"return main.run 20",
+	} {
+		if !has(want) {
+			t.Errorf("missing event %q in %q", want, events)
+		}
+	}
+
+	n := len(events)
+	eval(t, i, "run()")
+	if len(events) != n {
+		t.Errorf("got %d events after disabling the tracer", len(events)-n)
+	}
+}
+
+func TestRunBench(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"bench/sum.go": &fstest.MapFile{Data: []byte(`package bench
+
+func Sum(n int) int {
+	s := 0
+	for i := 0; i < n; i++ {
+		s += i
+	}
+	return s
+}
+`)},
+		"bench/sum_test.go": &fstest.MapFile{Data: []byte(`package bench
+
+import "testing"
+
+func BenchmarkSum(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Sum(10)
+	}
+}
+
+func BenchmarkAlloc(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = make([]int, 100)
+	}
+}
+
+func BenchmarkPanic(b *testing.B) { panic("boom") }
+`)},
+	}
+	i := interp.New(interp.Options{SourcecodeFilesystem: filesystem})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.RunBench(".", interp.BenchOptions{}); err == nil {
+		t.Fatal("expected an error before EvalTest")
+	}
+	if err := i.EvalTest("./bench"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := i.RunBench("Alloc", interp.BenchOptions{Count: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0].Name != "BenchmarkAlloc" || res[1].Name != "BenchmarkAlloc" {
+		t.Fatalf("unexpected results: %v", res)
+	}
+	for _, r := range res {
+		if r.Package != "./bench" || r.N == 0 || r.NsPerOp <= 0 {
+			t.Errorf("unexpected result: %v", r)
+		}
+	}
+	if res[0].AllocsPerOp == 0 {
+		t.Errorf("expected allocations: %v", res[0])
+	}
+
+	if _, err := i.RunBench("Panic", interp.BenchOptions{}); err == nil || !strings.Contains(err.Error(), "panic: boom") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestExecLimits(t *testing.T) {
+	i := interp.New(interp.Options{})
+	prog, err := i.Compile(`
+func recurse(n int) int { return recurse(n+1) + 1 }
+
+func defers(n int) {
+	for k := 0; k < n; k++ {
+		defer func() {}()
+	}
+}
+
+func safe() (err error) {
+	defer func() { err = recover().(error) }()
+	recurse(0)
+	return nil
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog.SetLimits(interp.ExecLimits{MaxCallDepth: 100, MaxDefers: 10})
+	if _, err := i.Execute(prog); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct{ src, err string }{
+		{src: "recurse(0)", err: "main.recurse: call depth limit exceeded (100)"},
+		{src: "defers(11)", err: "main.defers: defer limit exceeded (10)"},
+	} {
+		_, err := i.Eval(test.src)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.src, err, test.err)
+		}
+	}
+	eval(t, i, "defers(10)")
+
+	res := eval(t, i, "safe()")
+	var le *interp.LimitError
+	if err, _ := res.Interface().(error); !errors.As(err, &le) || le.Limit != "call depth" {
+		t.Errorf("got %v, want a recovered call depth LimitError", res)
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	i := interp.New(interp.Options{MaxCallDepth: 1000})
+	eval(t, i, `
+func recurse(n int) int { return recurse(n+1) + 1 }
+
+func count(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return count(n-1) + 1
+}
+
+func safe() (err error) {
+	defer func() { err = recover().(error) }()
+	recurse(0)
+	return nil
+}
+`)
+
+	_, err := i.Eval("recurse(0)")
+	var soe *interp.StackOverflowError
+	if !errors.As(err, &soe) || soe.Max != 1000 {
+		t.Fatalf("got error %v, want a StackOverflowError", err)
+	}
+	if !strings.Contains(err.Error(), "interpreted stack overflow: main.recurse") {
+		t.Errorf("got error %v, want interpreted stack overflow", err)
+	}
+	if stack := string(soe.Stack); !strings.HasPrefix(stack, "main.recurse()") || !strings.Contains(stack, "calls elided") {
+		t.Errorf("got stack %q, want the elided interpreted stack", stack)
+	}
+
+	if res := eval(t, i, "count(900)"); res.Interface() != 900 {
+		t.Errorf("got %v, want 900", res)
+	}
+	res := eval(t, i, "safe()")
+	if err, _ := res.Interface().(error); !errors.As(err, &soe) {
+		t.Errorf("got %v, want a recovered StackOverflowError", res)
+	}
+}
+
+func TestGenerateWrappers(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"greet/greet.go": &fstest.MapFile{Data: []byte(`package greet
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type Person struct{ Name string }
+
+func Hello(name string) string { return "hello " + name }
+
+func Join(sep string, words ...string) (string, error) {
+	if len(words) == 0 {
+		return "", errors.New("no words")
+	}
+	return strings.Join(words, sep), nil
+}
+
+func Status(r *http.Request) int { return http.StatusOK }
+
+func Greet(p Person) string { return Hello(p.Name) }
+
+func private() {}
+`)},
+	}
+	i := interp.New(interp.Options{SourcecodeFilesystem: filesystem})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.CompilePath("./greet"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.GenerateWrappers("missing", "greet"); err == nil {
+		t.Fatal("expected an error for a missing package")
+	}
+	src, err := i.GenerateWrappers("./greet", "greet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(i.FileSet(), "greet.go", src, 0); err != nil {
+		t.Fatalf("invalid generated source: %v\n%s", err, src)
+	}
+	for _, s := range []string{
+		"package greet\n",
+		`"net/http"`,
+		"\tHello  func(string) string\n",
+		"\tJoin   func(string, ...string) (string, error)\n",
+		"\tStatus func(*http.Request) int\n",
+		"//\tGreet func(",
+		`load(syms, "Join", &p.Join)`,
+	} {
+		if !strings.Contains(string(src), s) {
+			t.Errorf("missing %q in generated source:\n%s", s, src)
+		}
+	}
+	if strings.Contains(string(src), "private") {
+		t.Errorf("unexported function in generated source:\n%s", src)
+	}
+
+	// Check that the field types match the interpreted functions.
+	syms := i.Symbols("./greet")["./greet"]
+	if _, ok := syms["Join"].Interface().(func(string, ...string) (string, error)); !ok {
+		t.Errorf("unexpected type of Join: %v", syms["Join"].Type())
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"calc/calc.go": &fstest.MapFile{Data: []byte(`package calc
+
+func Add(a, b int) int { return a + b }
+`)},
+		"calc/calc_test.go": &fstest.MapFile{Data: []byte(`package calc
+
+import "testing"
+
+func check(tb testing.TB, got, want int) {
+	tb.Helper()
+	if got != want {
+		tb.Errorf("got %d, want %d", got, want)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	t.Log("adding")
+	check(t, Add(1, 2), 3)
+}
+
+func TestTable(t *testing.T) {
+	for _, c := range []struct{ name string; a, b, want int }{
+		{"zero", 0, 0, 0},
+		{"wrong", 1, 1, 3},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			check(t, Add(c.a, c.b), c.want)
+		})
+	}
+}
+
+func TestSkip(t *testing.T) {
+	t.Skip("not now")
+	t.Fatal("not reached")
+}
+
+func TestFatal(t *testing.T) {
+	cleaned := false
+	t.Cleanup(func() { cleaned = true })
+	defer func() { t.Log("cleaned:", cleaned) }()
+	t.Fatal("stop")
+}
+
+func TestPanic(t *testing.T) { panic("boom") }
+`)},
+	}
+
+	i := interp.New(interp.Options{SourcecodeFilesystem: filesystem})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.EvalTest("./calc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.RunTests(""); err == nil {
+		t.Fatal("expected an error without the TestShim option")
+	}
+
+	i = interp.New(interp.Options{SourcecodeFilesystem: filesystem, TestShim: true})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.EvalTest("./calc"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := i.RunTests("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range res {
+		got = append(got, fmt.Sprintf("%s %v %v %q", r.Name, r.Failed, r.Skipped, r.Logs))
+	}
+	want := []string{
+		`TestAdd false false ["adding"]`,
+		`TestFatal true false ["stop" "cleaned: false"]`,
+		`TestPanic true false ["panic: boom"]`,
+		`TestSkip false true ["not now"]`,
+		`TestTable true false []`,
+		`TestTable/zero false false []`,
+		`TestTable/wrong true false ["got 2, want 3"]`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got results:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	res, err = i.RunTests("Table/zero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[1].Name != "TestTable/zero" || res[0].Failed {
+		t.Errorf("unexpected results: %v", res)
+	}
+}
+
+func TestRunFuzz(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"parse/parse.go": &fstest.MapFile{Data: []byte(`package parse
+
+func Header(b []byte) int {
+	if len(b) > 2 && b[0] == 'H' && b[1] == 'D' {
+		return int(b[2]) * 2 / int(b[2]-'!')
+	}
+	return 0
+}
+`)},
+		"parse/parse_test.go": &fstest.MapFile{Data: []byte(`package parse
+
+import "testing"
+
+func FuzzHeader(f *testing.F) {
+	f.Add([]byte("HDx"))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		Header(b)
+	})
+}
+
+func FuzzSafe(f *testing.F) {
+	f.Add("abc", 1)
+	f.Fuzz(func(t *testing.T, s string, n int) {
+		if len(s) > 1000 {
+			t.Fatal("too long")
+		}
+	})
+}
+`)},
+	}
+	i := interp.New(interp.Options{SourcecodeFilesystem: filesystem, TestShim: true})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.EvalTest("./parse"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := i.RunFuzz("Fuzz", interp.FuzzOptions{Execs: 2000, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("got %d results, want 2", len(res))
+	}
+
+	crash, safe := res[0], res[1]
+	if !crash.Failed || len(crash.Input) != 1 || len(crash.Logs) == 0 || !strings.Contains(crash.Logs[0], "divide by zero") {
+		t.Errorf("unexpected result of FuzzHeader: %+v", crash)
+	} else if b := crash.Input[0].([]byte); len(b) < 3 || b[2] != '!' {
+		t.Errorf("unexpected failing input %q", b)
+	}
+	if safe.Failed || safe.Execs != 2001 {
+		t.Errorf("unexpected result of FuzzSafe: %+v", safe)
+	}
+}
+
+// Getter is a generic interface, of which instances are extracted.
+type Getter[T any] interface{ Get() T }
+
+// _Getter_int_ is the wrapper of Getter[int], as generated by extract.
+type _Getter_int_ struct {
+	IValue interface{}
+	WGet   func() int
+}
+
+func (W _Getter_int_) Get() int { return W.WGet() }
+
+func TestGenericInterfaceWrapper(t *testing.T) {
+	i := interp.New(interp.Options{})
+	err := i.Use(interp.Exports{
+		"github.com/breadchris/yaegi/interp_test/gen": {
+			"Getter[int]":  reflect.ValueOf((*Getter[int])(nil)),
+			"_Getter[int]": reflect.ValueOf((*_Getter_int_)(nil)),
+			"Sum":          reflect.ValueOf(func(a, b Getter[int]) int { return a.Get() + b.Get() }),
+			"First":        reflect.ValueOf(func(g Getter[string]) string { return g.Get() }),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import gen "github.com/breadchris/yaegi/interp_test"`)
+	eval(t, i, `
+type num int
+
+func (n num) Get() int { return int(n) }
+`)
+	if res := eval(t, i, "gen.Sum(num(2), num(3))"); res.Interface() != 5 {
+		t.Errorf("got %v, want 5", res)
+	}
+
+	eval(t, i, `
+type word string
+
+func (w word) Get() string { return string(w) }
+`)
+	// Without wrapper for Getter[string], the conversion fails with an error.
+	if _, err := i.Eval(`gen.First(word("a"))`); err == nil {
+		t.Error("expected an error for an instance without wrapper")
+	}
+}
+
+// Greeter is an interface with an unexported method, which can only be
+// implemented by embedding a type of its package, e.g. BaseGreeter.
+type Greeter interface {
+	Greet() string
+	greeter()
+}
+
+// BaseGreeter is the default implementation of Greeter.
+type BaseGreeter struct{}
+
+func (BaseGreeter) Greet() string { return "hello" }
+func (BaseGreeter) greeter()      {}
+
+// _Greeter is the wrapper of Greeter, as generated by extract, with the
+// interface embedded as a fallback for the unexported methods.
+type _Greeter struct {
+	IValue interface{}
+	WGreet func() string
+	Greeter
+}
+
+func (W _Greeter) Greet() string { return W.WGreet() }
+
+func TestUnexportedInterfaceWrapper(t *testing.T) {
+	i := interp.New(interp.Options{})
+	err := i.Use(interp.Exports{
+		"github.com/breadchris/yaegi/interp_test/greet": {
+			"Greeter":     reflect.ValueOf((*Greeter)(nil)),
+			"_Greeter":    reflect.ValueOf((*_Greeter)(nil)),
+			"BaseGreeter": reflect.ValueOf((*BaseGreeter)(nil)),
+			"Say":         reflect.ValueOf(func(g Greeter) string { return g.Greet() }),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import greet "github.com/breadchris/yaegi/interp_test"`)
+	eval(t, i, `
+type loud struct {
+	greet.BaseGreeter
+	name string
+}
+
+func (l *loud) Greet() string { return "HELLO " + l.name }
+
+type quiet struct{ *greet.BaseGreeter }
+`)
+	if res := eval(t, i, `greet.Say(&loud{name: "bob"})`); res.Interface() != "HELLO bob" {
+		t.Errorf("got %v, want HELLO bob", res)
+	}
+	if res := eval(t, i, `greet.Say(quiet{&greet.BaseGreeter{}})`); res.Interface() != "hello" {
+		t.Errorf("got %v, want hello", res)
+	}
+
+	// Without an embedded implementation, the unexported method is missing.
+	eval(t, i, `
+type alone struct{}
+
+func (alone) Greet() string { return "alone" }
+`)
+	_, err = i.Eval(`greet.Say(alone{})`)
+	if err == nil || !strings.Contains(err.Error(), "greeter") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"calc/calc.go": &fstest.MapFile{Data: []byte(`package calc
+
+// Pi is an approximation of pi.
+const Pi = 3.14
+
+// Counter counts calls.
+var Counter int
+
+// Point is a point.
+type Point struct{ X, Y int }
+
+// Add returns the sum of a and b.
+func Add(a, b int) int { Counter++; return a + b }
+
+// Max returns the max of a and b.
+func Max[T int | float64](a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func unexported() {}
+`)},
+	}
+	i := interp.New(interp.Options{SourcecodeFilesystem: filesystem})
+	if err := i.Use(interp.Exports{"example.com/bin/bin": {
+		"Double": reflect.ValueOf(func(x int) int { return 2 * x }),
+		"Answer": reflect.ValueOf(constant.MakeInt64(42)),
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.CompilePath("./calc"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]string{}
+	for _, s := range i.Describe("./calc") {
+		got[s.Name] = s.Kind + "|" + s.Signature + "|" + s.Doc
+	}
+	want := map[string]string{
+		"Add":     "func|func Add(a, b int) int|Add returns the sum of a and b.\n",
+		"Counter": "var|var Counter int|Counter counts calls.\n",
+		"Max":     "func|func Max[T int | float64](a, b T) T|Max returns the max of a and b.\n",
+		"Pi":      "const|const Pi = 3.14|Pi is an approximation of pi.\n",
+		"Point":   "type|type Point struct{ X, Y int }|Point is a point.\n",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	infos := i.Describe("example.com/bin")
+	if len(infos) != 2 || infos[0].Name != "Answer" || infos[1].Signature != "func Double(int) int" || infos[1].Type == nil {
+		t.Errorf("unexpected binary package description %+v", infos)
+	}
+	if infos := i.Describe("unknown"); infos != nil {
+		t.Errorf("got %+v, want nil", infos)
+	}
+}
+
+func TestDoc(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import "math/rand"`)
+	eval(t, i, `
+// Point is a point.
+type Point struct{ X, Y int }
+
+// Scale multiplies the coordinates of p by k.
+func (p Point) Scale(k int) Point { return Point{p.X * k, p.Y * k} }
+`)
+	eval(t, i, `
+// Add returns the sum of a and b.
+//
+// It never fails.
+func Add(a, b int) int { return a + b }
+`)
+
+	tests := []struct {
+		path, want string
+		prefix     bool // docs of binary packages depend on the Go version, only check the declaration
+	}{
+		{path: "Add", want: "func Add(a, b int) int\n    Add returns the sum of a and b.\n\n    It never fails.\n"},
+		{path: "main.Point", want: "type Point struct{ X, Y int }\n    Point is a point.\n"},
+		{path: "Point.Scale", want: "func (p Point) Scale(k int) Point\n    Scale multiplies the coordinates of p by k.\n"},
+		{path: "strings.ToUpper", want: "func ToUpper(s string) string\n", prefix: true},
+		{path: "rand.Intn", want: "func Intn(n int) int\n", prefix: true},
+		{path: "Missing"},
+		{path: "strings.Missing"},
+	}
+	for _, test := range tests {
+		got := i.Doc(test.path)
+		if test.prefix && !strings.HasPrefix(got, test.want) || !test.prefix && got != test.want {
+			t.Errorf("%s: got %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestCloseLeaked(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import ("os"; "time")`)
+	prog, err := i.Compile(`
+var (
+	closed *os.File
+	leaked *os.File
+	ticker *time.Ticker
+)
+
+func init() {
+	closed, _ = os.CreateTemp("", "yaegi-closed-")
+	closed.Close()
+	leaked, _ = os.CreateTemp("", "yaegi-leaked-")
+	ticker = time.NewTicker(time.Hour)
+	_, _ = os.Open("/nonexistent")
+	panic("oops")
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog.TrackResources()
+	if _, err := i.Execute(prog); err == nil {
+		t.Fatal("expected a panic")
+	}
+
+	closed := eval(t, i, "closed").Interface().(*os.File)
+	leaked := eval(t, i, "leaked").Interface().(*os.File)
+	defer os.Remove(closed.Name())
+	defer os.Remove(leaked.Name())
+	if _, err := leaked.Write([]byte("x")); err != nil {
+		t.Fatalf("leaked file should still be open: %v", err)
+	}
+
+	if err := prog.CloseLeaked(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := leaked.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("got %v, want %v", err, os.ErrClosed)
+	}
+	if err := prog.CloseLeaked(); err != nil {
+		t.Errorf("second CloseLeaked: %v", err)
+	}
+}
+
+func TestCompileLimits(t *testing.T) {
+	src := `
+import ("fmt"; "strings")
+
+func f() { g := func() {}; g() }
+
+var s = fmt.Sprint(strings.Repeat("a", 3))
+`
+	for _, test := range []struct {
+		limits interp.CompileLimits
+		err    string
+	}{
+		{limits: interp.CompileLimits{}},
+		{limits: interp.CompileLimits{MaxFileSize: 1000, MaxNodes: 1000, MaxFuncs: 2, MaxImports: 2}},
+		{limits: interp.CompileLimits{MaxFileSize: 10}, err: "file size limit exceeded (10)"},
+		{limits: interp.CompileLimits{MaxNodes: 10}, err: "nodes limit exceeded (10)"},
+		{limits: interp.CompileLimits{MaxFuncs: 1}, err: "functions limit exceeded (1)"},
+		{limits: interp.CompileLimits{MaxImports: 1}, err: "imports limit exceeded (1)"},
+	} {
+		i := interp.New(interp.Options{CompileLimits: test.limits})
+		if err := i.Use(stdlib.Symbols); err != nil {
+			t.Fatal(err)
+		}
+		_, err := i.Compile(src)
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%+v: unexpected error: %v", test.limits, err)
+			}
+			continue
+		}
+		var lerr *interp.CompileLimitError
+		if !errors.As(err, &lerr) || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%+v: got error %v, want %q", test.limits, err, test.err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	i := interp.New(interp.Options{AutoFormat: true})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct{ src, want string }{
+		{
+			src:  "package main\nimport (\n\"fmt\"\n)\nfunc main() { fmt.Println(strings.ToUpper(\"a\")) }\n",
+			want: "package main\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n\nfunc main() { fmt.Println(strings.ToUpper(\"a\")) }\n",
+		},
+		{
+			src:  "package main\nfunc Upper(s string) string { return strings.ToUpper(s) }\n",
+			want: "package main\n\nimport (\n\t\"strings\"\n)\n\nfunc Upper(s string) string { return strings.ToUpper(s) }\n",
+		},
+		{
+			src:  "func Upper(s string)string{return strings.ToUpper(s)}",
+			want: "import (\n\t\"strings\"\n)\n\nfunc Upper(s string) string { return strings.ToUpper(s) }",
+		},
+		{
+			src:  "x:=rand.Intn(1)",
+			want: "import (\n\t\"math/rand\"\n)\n\nx := rand.Intn(1)",
+		},
+		{src: "x:=undefined.Foo", want: "x := undefined.Foo"},
+	} {
+		got, err := i.Format(test.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("got %q, want %q", got, test.want)
+		}
+	}
+
+	if _, err := i.Format("x :="); err == nil {
+		t.Error("expected a syntax error")
+	}
+
+	// The import of math/rand is evaluated before the statement.
+	if res := eval(t, i, "rand.Intn(1)"); res.Interface() != 0 {
+		t.Errorf("got %v, want 0", res)
+	}
+}
+
+func TestAutoImport(t *testing.T) {
+	var out bytes.Buffer
+	i := interp.New(interp.Options{AutoImport: true, Stdout: &out})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `fmt.Println(strings.ToUpper("a"))`)
+	if out.String() != "A\n" {
+		t.Errorf("got %q, want %q", out.String(), "A\n")
+	}
+
+	// crypto/rand provides Prime, math/rand does not.
+	eval(t, i, `p, _ := rand.Prime(rand.Reader, 8)`)
+	if res := eval(t, i, "p.BitLen()"); res.Interface() != 8 {
+		t.Errorf("got %v, want 8", res)
+	}
+
+	// A global shadows a package of the same name.
+	eval(t, i, `type T struct{ Name string }; var bytes = T{"b"}`)
+	if res := eval(t, i, "bytes.Name"); res.Interface() != "b" {
+		t.Errorf("got %v, want b", res)
+	}
+
+	if _, err := i.Eval("unknown.Foo()"); err == nil {
+		t.Error("expected an error for an unknown package")
+	}
+}
+
+func TestGoVersion(t *testing.T) {
+	src := `func f() (r []int) {
+	var fs []func() int
+	for i := 0; i < 3; i++ {
+		fs = append(fs, func() int { return i })
+	}
+	for _, v := range []int{4, 5} {
+		fs = append(fs, func() int { return v })
+	}
+	for _, g := range fs {
+		r = append(r, g())
+	}
+	return r
+}`
+
+	for _, test := range []struct{ version, want string }{
+		{version: "", want: "[0 1 2 4 5]"},
+		{version: "go1.22", want: "[0 1 2 4 5]"},
+		{version: "1.21", want: "[3 3 3 5 5]"},
+		{version: "go1.21.5", want: "[3 3 3 5 5]"},
+	} {
+		i := interp.New(interp.Options{GoVersion: test.version})
+		eval(t, i, src)
+		if res := fmt.Sprint(eval(t, i, "f()")); res != test.want {
+			t.Errorf("%q: got %v, want %v", test.version, res, test.want)
+		}
+	}
+
+	for _, test := range []struct{ version, src, err string }{
+		{version: "go1.20", src: "min(1, 2)", err: "1:28: min requires go1.21 or later (GoVersion is go1.20)"},
+		{version: "go1.20", src: "clear(map[int]int{})", err: "clear requires go1.21 or later"},
+		{version: "go1.21", src: "for range 3 {}", err: "1:38: range over int requires go1.22 or later (GoVersion is go1.21)"},
+		{version: "go1.22", src: "for range func(func() bool) {} {}", err: "range over func requires go1.23 or later"},
+	} {
+		i := interp.New(interp.Options{GoVersion: test.version})
+		if _, err := i.Eval(test.src); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.src, err, test.err)
+		}
+	}
+
+	// Shadowing a builtin introduced later is allowed.
+	i := interp.New(interp.Options{GoVersion: "go1.20"})
+	eval(t, i, "func min(a, b int) int { return a + b }")
+	if res := eval(t, i, "min(1, 2)"); res.Interface() != 3 {
+		t.Errorf("got %v, want 3", res)
+	}
+}
+
+func TestUnsafePolicy(t *testing.T) {
+	sizes := `import "unsafe"
+func size() uintptr { return unsafe.Sizeof(int32(0)) + unsafe.Alignof(int64(0)) }`
+	pointers := `import "unsafe"
+func ptr() int { x := 3; p := unsafe.Pointer(&x); return *(*int)(p) }`
+
+	for _, test := range []struct {
+		policy   interp.UnsafePolicy
+		src, err string
+	}{
+		{policy: interp.UnsafeForbidden, src: sizes, err: "import of package unsafe is not allowed (unsafe policy is UnsafeForbidden)"},
+		{policy: interp.UnsafeSizes, src: sizes},
+		{policy: interp.UnsafeSizes, src: pointers, err: "use of unsafe.Pointer is not allowed (unsafe policy is UnsafeSizes)"},
+		{policy: interp.UnsafeSizes, src: "import \"unsafe\"; var p *unsafe.Pointer", err: "use of unsafe.Pointer is not allowed"},
+		{policy: interp.UnsafeSizes, src: "import \"reflect\"; var x = 1; var p = (*int)(reflect.ValueOf(&x).UnsafePointer())", err: "conversion of unsafe.Pointer to *int is not allowed (unsafe policy is UnsafeSizes)"},
+		{policy: interp.UnsafeUnrestricted, src: sizes},
+		{policy: interp.UnsafeUnrestricted, src: pointers},
+	} {
+		i := interp.New(interp.Options{Unsafe: test.policy})
+		if err := i.Use(stdlib.Symbols); err != nil {
+			t.Fatal(err)
+		}
+		if err := i.Use(unsafe.Symbols); err != nil {
+			t.Fatal(err)
+		}
+		_, err := i.Eval(test.src)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%v: %q: unexpected error: %v", test.policy, test.src, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%v: %q: got error %v, want %q", test.policy, test.src, err, test.err)
+		}
+	}
+
+	i := interp.New(interp.Options{Unsafe: interp.UnsafeUnrestricted})
+	if err := i.Use(unsafe.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, sizes)
+	eval(t, i, strings.TrimPrefix(pointers, `import "unsafe"`))
+	if res := eval(t, i, "size()"); res.Interface() != uintptr(12) {
+		t.Errorf("got %v, want 12", res)
+	}
+	if res := eval(t, i, "ptr()"); res.Interface() != 3 {
+		t.Errorf("got %v, want 3", res)
+	}
+}
+
+func TestSyscallTable(t *testing.T) {
+	i := interp.New(interp.Options{Syscalls: interp.SyscallTable{
+		Stubs: map[string]interface{}{"syscall.Getpid": func() int { return 42 }},
+		Deny:  []string{"syscall.*"},
+	}})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Use(syscall.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import ("os"; "syscall")`)
+	if res := eval(t, i, "syscall.Getpid()"); res.Interface() != 42 {
+		t.Errorf("got %v, want 42", res)
+	}
+	// Constants and the stdlib wrappers remain available.
+	if res := eval(t, i, "int(syscall.SIGINT)"); res.Interface() != 2 {
+		t.Errorf("got %v, want 2", res)
+	}
+	if res := eval(t, i, "os.Getpid()"); res.Interface() != os.Getpid() {
+		t.Errorf("got %v, want %d", res, os.Getpid())
+	}
+	if _, err := i.Eval("syscall.Getuid()"); err == nil || !strings.Contains(err.Error(), "use of syscall.Getuid is denied by the syscall table") {
+		t.Errorf("got error %v", err)
+	}
+
+	i = interp.New(interp.Options{Syscalls: interp.SyscallTable{
+		Stubs: map[string]interface{}{"syscall.Getpid": func() int64 { return 42 }},
+	}})
+	if err := i.Use(syscall.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import "syscall"`)
+	if _, err := i.Eval("syscall.Getpid()"); err == nil || !strings.Contains(err.Error(), "stub of syscall.Getpid has type func() int64, want func() int") {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestExecPolicy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no echo command")
+	}
+	var logs bytes.Buffer
+	policy := interp.ExecPolicyFunc(func(req *interp.ExecRequest) error {
+		if req.Name == "greet" {
+			req.Name, req.Args = "echo", append([]string{"hello"}, req.Args...)
+		}
+		return interp.ExecAllowlist{Allow: []string{"echo"}, Log: log.New(&logs, "", 0)}.Check(req)
+	})
+	i := interp.New(interp.Options{ExecPolicy: policy})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import "os/exec"`)
+	eval(t, i, `func run(name string, args ...string) string {
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
+}
+func modified() string { c := exec.Command("echo", "hi"); c.Path = "/bin/rm"; return c.Run().Error() }
+func literal() string { var c exec.Cmd; return c.Run().Error() }`)
+
+	for _, test := range []struct{ src, want string }{
+		{src: `run("echo", "hi")`, want: "hi\n"},
+		{src: `run("greet", "you")`, want: "hello you\n"},
+		{src: `run("ls", "/")`, want: `exec: "ls": denied by exec policy`},
+		{src: `modified()`, want: `exec: "/bin/rm": command modified after approval by exec policy`},
+		{src: `literal()`, want: "exec: Cmd not created by Command"},
+	} {
+		if res := eval(t, i, test.src); res.Interface() != test.want {
+			t.Errorf("%s: got %q, want %q", test.src, res, test.want)
+		}
+	}
+	if want := "exec allowed: echo hi\nexec allowed: echo hello you\nexec denied: ls /\nexec allowed: echo hi\n"; logs.String() != want {
+		t.Errorf("got log %q, want %q", logs.String(), want)
+	}
+}
+
+func TestTypeVersions(t *testing.T) {
+	i := interp.New(interp.Options{})
+	eval(t, i, "type Config struct{ A int }")
+	eval(t, i, "var c = Config{1}")
+	eval(t, i, "p := &c")
+	eval(t, i, "type Config struct{ B string; A int }")
+	eval(t, i, "func f(x Config) int { return x.A }")
+	eval(t, i, "var d Config")
+	eval(t, i, "var q *Config")
+
+	for _, test := range []struct{ src, err string }{
+		{src: "f(c)", err: "value of main.Config (v1, declared eval#1) used where main.Config (v2, declared eval#4) expected"},
+		{src: "d = c", err: "value of main.Config (v1, declared eval#1) used where main.Config (v2, declared eval#4) expected"},
+		{src: "q = p", err: "value of *main.Config (v1, declared eval#1) used where *main.Config (v2, declared eval#4) expected"},
+	} {
+		_, err := i.Eval(test.src)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.src, err, test.err)
+		}
+	}
+
+	// Values of the new declaration are accepted.
+	if res := eval(t, i, `f(Config{"b", 2})`); res.Interface() != 2 {
+		t.Errorf("got %v, want 2", res)
+	}
+}
+
+func TestHoverDefinitionReferences(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"calc/calc.go": &fstest.MapFile{Data: []byte(`package calc
+
+// Add returns the sum of a and b.
+func Add(a, b int) int { return a + b }
+`)},
+		"main.go": &fstest.MapFile{Data: []byte(`package main
+
+import (
+	"strings"
+
+	"./calc"
+)
+
+// Point is a point.
+type Point struct{ X, Y int }
+
+func sum(p Point, n int) int {
+	s := calc.Add(p.X, p.Y)
+	for i := 0; i < n; i++ {
+		s += i
+	}
+	f := func() int { return s * n }
+	return f()
+}
+
+func main() { println(sum(Point{1, 2}, 3), strings.ToUpper("a")) }
+`)},
+	}
+	i := interp.New(interp.Options{SourcecodeFilesystem: filesystem})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.CompilePath("main.go"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line, col int
+		hover     string // name|kind|type
+		def       string
+		refs      string
+	}{
+		{10, 6, "Point|type|main.Point", "main.go:10:6", "10:6 12:12 21:27"},
+		{12, 12, "Point|type|main.Point", "main.go:10:6", "10:6 12:12 21:27"},
+		{12, 19, "n|var|int", "main.go:12:19", "12:19 14:18 17:31"},
+		{13, 2, "s|var|int", "main.go:13:2", "13:2 15:3 17:27"},
+		{13, 12, "Add|func|func(int,int) int", "calc/calc.go:4:6", "4:6 13:12"},
+		{13, 16, "p|var|main.Point", "main.go:12:10", "12:10 13:16 13:21"},
+		{15, 8, "i|var|int", "main.go:14:6", "14:6 14:14 14:21 15:8"},
+		{17, 27, "s|var|int", "main.go:13:2", "13:2 15:3 17:27"},
+		{18, 9, "f|var|func() int", "main.go:17:2", "17:2 18:9"},
+		{21, 23, "sum|func|func(main.Point,int) int", "main.go:12:6", "12:6 21:23"},
+		{21, 52, "ToUpper|func|func(string) string", "", ""},
+	}
+	for _, test := range tests {
+		h, ok := i.HoverAt("main.go", test.line, test.col)
+		if got := h.Name + "|" + h.Kind + "|" + h.Type; !ok || got != test.hover {
+			t.Errorf("%d:%d: got hover %q, want %q", test.line, test.col, got, test.hover)
+		}
+		def := ""
+		if p, ok := i.DefinitionOf("main.go", test.line, test.col); ok {
+			def = p.String()
+		}
+		if def != test.def {
+			t.Errorf("%d:%d: got definition %q, want %q", test.line, test.col, def, test.def)
+		}
+		var refs []string
+		for _, p := range i.ReferencesOf("main.go", test.line, test.col) {
+			refs = append(refs, fmt.Sprintf("%d:%d", p.Line, p.Column))
+		}
+		if got := strings.Join(refs, " "); got != test.refs {
+			t.Errorf("%d:%d: got references %q, want %q", test.line, test.col, got, test.refs)
+		}
+	}
+
+	if h, _ := i.HoverAt("main.go", 10, 6); h.Doc != "type Point struct{ X, Y int }\n    Point is a point.\n" {
+		t.Errorf("unexpected hover doc %q", h.Doc)
+	}
+	if h, _ := i.HoverAt("main.go", 21, 52); !strings.HasPrefix(h.Doc, "func ToUpper(s string) string\n") {
+		t.Errorf("unexpected hover doc %q", h.Doc)
+	}
+	if _, ok := i.HoverAt("main.go", 11, 1); ok {
+		t.Error("got hover on an empty line")
+	}
+}
+
+func TestProgramGraph(t *testing.T) {
+	i := interp.New(interp.Options{})
+	prog, err := i.Compile(`package main
+
+func main() {
+	if a := 1; a > 0 {
+		println(a)
+	}
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(prog.AST())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ast interp.Graph
+	if err := json.Unmarshal(b, &ast); err != nil {
+		t.Fatal(err)
+	}
+	if ast.Graph != "ast" || len(ast.Nodes) == 0 || ast.Nodes[0].Kind != "fileStmt" || len(ast.Edges) != len(ast.Nodes)-1 {
+		t.Fatalf("unexpected AST %s", b)
+	}
+	found := false
+	for _, n := range ast.Nodes {
+		if n.Kind == "identExpr" && n.Ident == "a" && n.Type == "int" && n.Line == 4 && n.Column == 5 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("identifier a not found in AST %s", b)
+	}
+
+	cfg := prog.CFG()
+	kinds := map[string]int{}
+	for _, e := range cfg.Edges {
+		kinds[e.Kind]++
+	}
+	if cfg.Graph != "cfg" || kinds["true"] != 1 || kinds["false"] != 1 || kinds["next"] == 0 || kinds["child"] != 0 {
+		t.Errorf("unexpected CFG edges %v", kinds)
+	}
+	ids := map[int64]bool{}
+	for _, n := range cfg.Nodes {
+		ids[n.ID] = true
+	}
+	for _, e := range cfg.Edges {
+		if !ids[e.From] || !ids[e.To] {
+			t.Errorf("edge %v refers to a missing node", e)
+		}
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatal(err)
+	}
+	eval(t, i, `import "strings"`)
+	eval(t, i, `func double(s string) string { return s + s }`)
+
+	a, b := i.NewNamespace("a"), i.NewNamespace("b")
+	for _, src := range []string{`x := 2`, `type T struct{ N int }`, `func f() int { return x * 10 }`} {
+		if _, err := a.Eval(src); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, src := range []string{`x := "b"`, `type T string`, `func f() string { return strings.ToUpper(double(x)) }`} {
+		if _, err := b.Eval(src); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := b.Eval(`import "fmt"`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each namespace sees its own declarations and the shared ones.
+	if res, err := a.Eval(`f() + T{N: 1}.N`); err != nil || res.Interface() != 21 {
+		t.Errorf("got %v, %v, want 21", res, err)
+	}
+	if res, err := b.Eval(`fmt.Sprint(f(), T("!"))`); err != nil || res.Interface() != "BB!" {
+		t.Errorf("got %v, %v, want BB!", res, err)
+	}
+	if got := i.NewNamespace("a").Globals()["x"]; !got.IsValid() || got.Interface() != 2 {
+		t.Errorf("got %v, want 2", got)
+	}
+
+	// Declarations and imports of namespaces are private.
+	for _, test := range []struct {
+		ns  *interp.Namespace
+		src string
+		err string
+	}{
+		{src: "x", err: "undefined: x"},
+		{ns: a, src: "fmt.Sprint(x)", err: "undefined: fmt"},
+		{ns: b, src: "package foo", err: "package foo can not be compiled in namespace b"},
+	} {
+		var err error
+		if test.ns == nil {
+			_, err = i.Eval(test.src)
+		} else {
+			_, err = test.ns.Eval(test.src)
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.src, err, test.err)
+		}
+	}
+}
+
+func TestEvalRangeFunc(t *testing.T) {
+	i := interp.New(interp.Options{})
+	if err := i.Use(interp.Exports{
+		"iters/iters": map[string]reflect.Value{
+			"Values": reflect.ValueOf(func(s []int) func(func(int) bool) {
+				return func(yield func(int) bool) {
+					for _, v := range s {
+						if !yield(v) {
+							return
+						}
+					}
+				}
+			}),
+			"All": reflect.ValueOf(func(yield func(string, int) bool) {
+				_ = yield("a", 1) && yield("b", 2) && yield("c", 3)
+			}),
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	i.ImportUsed()
+
+	runTests(t, i, []testCase{
+		{desc: "values", src: "t := 0; for v := range iters.Values([]int{1, 2, 3}) { t += v }; t", res: "6"},
+		{desc: "values break", src: "t := 0; for v := range iters.Values([]int{1, 2, 3}) { if v == 3 { break }; t += v }; t", res: "3"},
+		{desc: "keys", src: `s := ""; for k := range iters.All { s += k }; s`, res: "abc"},
+		{desc: "keys values", src: `s := ""; for k, v := range iters.All { if v == 2 { continue }; s += k }; s`, res: "ac"},
+		{desc: "return", src: `f := func() string { for k, v := range iters.All { if v > 1 { return k } }; return "" }; f()`, res: "b"},
+	})
+}
+
+func BenchmarkBuiltins(b *testing.B) {
+	benchmarks := []struct {
+		desc, src string
+	}{
+		{desc: "append", src: `func run(n int) { var s []int; for i := 0; i < n; i++ { if len(s) == 1024 { s = s[:0] }; s = append(s, i) } }`},
+		{desc: "copy", src: `func run(n int) { s, d := make([]float64, 8), make([]float64, 8); for i := 0; i < n; i++ { copy(d, s) } }`},
+		{desc: "map_index", src: `func run(n int) { m := map[string]int{"a": 1}; k, t := "a", 0; for i := 0; i < n; i++ { t = m[k] }; _ = t }`},
+		{desc: "delete", src: `func run(n int) { m := map[int]bool{}; for i := 0; i < n; i++ { delete(m, i) } }`},
+	}
+
+	for _, bench := range benchmarks {
+		b.Run(bench.desc, func(b *testing.B) {
+			i := interp.New(interp.Options{})
+			if _, err := i.Eval(bench.src); err != nil {
+				b.Fatal(err)
+			}
+			v, err := i.Eval("run")
+			if err != nil {
+				b.Fatal(err)
+			}
+			run := v.Interface().(func(int))
+			b.ReportAllocs()
+			b.ResetTimer()
+			run(b.N)
+		})
+	}
 }
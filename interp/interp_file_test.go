@@ -65,7 +65,7 @@ func runCheck(t *testing.T, p string) {
 		goPath = build.Default.GOPATH
 	}
 	var stdout, stderr bytes.Buffer
-	i := interp.New(interp.Options{GoPath: goPath, Stdout: &stdout, Stderr: &stderr})
+	i := interp.New(interp.Options{GoPath: goPath, Stdout: &stdout, Stderr: &stderr, Unsafe: interp.UnsafeUnrestricted})
 	if err := i.Use(interp.Symbols); err != nil {
 		t.Fatal(err)
 	}
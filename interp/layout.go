@@ -0,0 +1,105 @@
+package interp
+
+import (
+	"go/token"
+	"reflect"
+	"sort"
+)
+
+// A FrameLayout describes the frame of an interpreted function: the values
+// allocated for each call, and the variables of enclosing functions captured
+// by a function literal.
+type FrameLayout struct {
+	Func     string         // function name, as in stack traces
+	Pos      token.Position // position of the function
+	Size     uintptr        // size of the frame values in bytes, excluding referenced memory
+	Slots    []FrameSlot    // frame values, by index
+	Captures []FrameCapture // captured variables, by name, or nil
+}
+
+// A FrameSlot is a value of a function frame.
+type FrameSlot struct {
+	Index int          // index in frame
+	Name  string       // name of the parameter or variable, or empty for a temporary value
+	Type  reflect.Type // runtime type of the value
+}
+
+// A FrameCapture is a variable of an enclosing function, accessed by a
+// function literal through the frame of that function.
+type FrameCapture struct {
+	Name  string // variable name
+	Level int    // number of frames up from the function literal, starting at 1
+	Index int    // index in the enclosing function frame
+}
+
+// FrameLayouts returns the frame layouts of the functions and function
+// literals of the program, in source order. Generic functions, which are
+// compiled per instance, are not reported.
+func (p *Program) FrameLayouts() []FrameLayout {
+	var layouts []FrameLayout
+	p.root.Walk(func(n *node) bool {
+		if (n.kind == funcDecl || n.kind == funcLit) && n.scope != nil {
+			layouts = append(layouts, frameLayout(n))
+		}
+		return true
+	}, nil)
+	return layouts
+}
+
+// frameLayout returns the frame layout of function def.
+func frameLayout(def *node) FrameLayout {
+	names := map[int]string{}
+	var walk func(s *scope)
+	walk = func(s *scope) {
+		for name, sym := range s.sym {
+			if sym.index >= 0 && !sym.global && sym.kind != typeSym {
+				names[sym.index] = name
+			}
+		}
+		for _, c := range s.child {
+			if c.level == s.level {
+				walk(c)
+			}
+		}
+	}
+	walk(def.scope)
+
+	l := FrameLayout{Func: funcName(def), Slots: make([]FrameSlot, len(def.types))}
+	if def.interp != nil {
+		l.Pos = def.interp.fset.Position(def.pos)
+	}
+	for i, t := range def.types {
+		l.Slots[i] = FrameSlot{Index: i, Name: names[i], Type: t}
+		l.Size += t.Size()
+	}
+	if def.kind == funcLit {
+		l.Captures = frameCaptures(def)
+	}
+	return l
+}
+
+// frameCaptures returns the variables of enclosing functions accessed by the
+// function literal def, including from its nested function literals.
+func frameCaptures(def *node) []FrameCapture {
+	seen := map[FrameCapture]bool{}
+	var captures []FrameCapture
+	var walk func(n *node, depth int)
+	walk = func(n *node, depth int) {
+		if n.kind == identExpr && n.sym != nil && n.findex >= 0 && n.level != globalFrame && n.level > depth {
+			c := FrameCapture{Name: n.ident, Level: n.level - depth, Index: n.findex}
+			if !seen[c] {
+				seen[c] = true
+				captures = append(captures, c)
+			}
+		}
+		if n.kind == funcLit && n != def {
+			depth++
+		}
+		for _, c := range n.child {
+			walk(c, depth)
+		}
+	}
+	walk(def, 0)
+	sort.Slice(captures, func(i, j int) bool { return captures[i].Name < captures[j].Name })
+	return captures
+}
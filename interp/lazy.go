@@ -0,0 +1,91 @@
+package interp
+
+import "sort"
+
+// lazyPkg is the pending initialization of a source package imported with
+// the LazyInit option.
+type lazyPkg struct {
+	init    func() error // evaluates package vars and runs init functions
+	running bool         // set during the initialization, to break cycles
+}
+
+// lazyImport returns true if the source package of importPath, being loaded,
+// must be initialized lazily: the LazyInit option is set and the package is
+// imported by an import spec, see importSrcFrom.
+func (interp *Interpreter) lazyImport(importPath string) bool {
+	n := len(interp.importing)
+	return interp.lazyInit && n > 0 && interp.importing[n-1].to == importPath
+}
+
+// useSrcPkg records a use by compiled code of the source package of path.
+// If its initialization is pending, it runs before the compiled code, see
+// initUsed. It must be called with interp.compile locked.
+func (interp *Interpreter) useSrcPkg(path string) {
+	if interp.lazy[path] != nil {
+		interp.lazyUsed = append(interp.lazyUsed, path)
+	}
+}
+
+// initUsed initializes the lazy packages used by the code compiled since
+// the last call. It must be called with interp.compile locked.
+func (interp *Interpreter) initUsed() error {
+	for len(interp.lazyUsed) > 0 {
+		path := interp.lazyUsed[0]
+		interp.lazyUsed = interp.lazyUsed[1:]
+		if err := interp.initSrcPkg(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// initSrcPkg runs the pending initialization of the source package of path,
+// if any, after the ones of the source packages it imports. It must be
+// called with interp.compile locked.
+func (interp *Interpreter) initSrcPkg(path string) error {
+	lp := interp.lazy[path]
+	if lp == nil || lp.running {
+		return nil
+	}
+	lp.running = true
+
+	interp.mutex.RLock()
+	imports := make([]string, 0, len(interp.srcImports[path]))
+	for p := range interp.srcImports[path] {
+		imports = append(imports, p)
+	}
+	interp.mutex.RUnlock()
+	sort.Strings(imports)
+	for _, p := range imports {
+		if err := interp.initSrcPkg(p); err != nil {
+			return err
+		}
+	}
+
+	delete(interp.lazy, path)
+	return lp.init()
+}
+
+// initPending runs the pending initialization of the source package of
+// importPath, or of all source packages if importPath is empty, for their
+// symbols to be accessed by the host.
+func (interp *Interpreter) initPending(importPath string) {
+	if !interp.lazyInit {
+		return
+	}
+	interp.compile.Lock()
+	defer interp.compile.Unlock()
+
+	if importPath != "" {
+		_ = interp.initSrcPkg(importPath)
+		return
+	}
+	paths := make([]string, 0, len(interp.lazy))
+	for p := range interp.lazy {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		_ = interp.initSrcPkg(p)
+	}
+}
@@ -0,0 +1,178 @@
+package interp
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ExecLimits are limits on the execution of the functions of a program,
+// which protect the host from runaway code, e.g. an infinite recursion or
+// millions of deferred calls, before it exhausts the host memory.
+type ExecLimits struct {
+	// MaxCallDepth is the maximum depth of nested interpreted calls at which
+	// a function of the program can be called, or 0 for no limit. The depth
+	// is counted from the entry into interpreted code, e.g. by Execute or
+	// by a call of an interpreted function value from the host.
+	MaxCallDepth int
+
+	// MaxDefers is the maximum number of pending deferred calls in a single
+	// call of a function of the program, or 0 for no limit.
+	MaxDefers int
+}
+
+// A LimitError is the panic value raised by interpreted code which exceeds
+// an execution limit. It can be recovered as any other panic.
+type LimitError struct {
+	Func  string // name of the function, as in stack traces
+	Limit string // "call depth" or "defer"
+	Max   int    // value of the limit
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s limit exceeded (%d)", e.Func, e.Limit, e.Max)
+}
+
+// A StackOverflowError is the panic value raised by interpreted code which
+// exceeds the maximum call depth set in Options.MaxCallDepth. It can be
+// recovered as any other panic.
+type StackOverflowError struct {
+	Func  string // name of the called function, as in stack traces
+	Max   int    // maximum call depth
+	Stack []byte // stack of interpreted calls, as in Panic.FilteredStack
+}
+
+func (e *StackOverflowError) Error() string {
+	return fmt.Sprintf("interpreted stack overflow: %s: call depth limit exceeded (%d)", e.Func, e.Max)
+}
+
+// SetLimits sets the execution limits of the functions and function
+// literals of the program, including when they are called after Execute,
+// e.g. from the host. It must not be called during the execution of the
+// program. Zero limits remove the limits.
+func (p *Program) SetLimits(limits ExecLimits) {
+	var l *ExecLimits
+	if limits != (ExecLimits{}) {
+		l = &limits
+	}
+	p.root.Walk(func(n *node) bool {
+		if n.kind == funcDecl || n.kind == funcLit {
+			n.limits = l
+		}
+		return true
+	}, nil)
+}
+
+// checkCallDepth panics if the call of function def in frame f exceeds the
+// maximum call depth of the interpreter, or the call depth limit of def.
+func checkCallDepth(def *node, f *frame) {
+	if max := def.interp.maxCallDepth; max > 0 && f.depth > max {
+		panic(&StackOverflowError{Func: funcName(def), Max: max, Stack: callStack(def, f)})
+	}
+	if l := def.limits; l != nil && l.MaxCallDepth > 0 && f.depth > l.MaxCallDepth {
+		panic(&LimitError{Func: funcName(def), Limit: "call depth", Max: l.MaxCallDepth})
+	}
+}
+
+// maxStackCalls is the maximum number of calls reported by callStack.
+const maxStackCalls = 100
+
+// callStack returns the stack of interpreted calls ending with the call of
+// function def in frame f, innermost first. As in Go tracebacks, the calls
+// in the middle of a deep stack are elided.
+func callStack(def *node, f *frame) []byte {
+	var b bytes.Buffer
+	fset := def.interp.fset
+	fmt.Fprintf(&b, "%s()\n\t%s\n", funcName(def), fset.Position(def.pos))
+	n := 0
+	for ; f != nil && f.call != nil; f = f.anc {
+		if n++; n > maxStackCalls/2 && f.depth > maxStackCalls/2 {
+			if n == maxStackCalls/2+1 {
+				fmt.Fprintf(&b, "...%d calls elided...\n", f.depth-maxStackCalls/2)
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "%s()\n\t%s\n", funcName(f.call), fset.Position(f.call.pos))
+	}
+	return b.Bytes()
+}
+
+// checkDefer panics if a new deferred call in frame f of function def
+// exceeds the defer limit of def.
+func checkDefer(def *node, f *frame) {
+	if def == nil {
+		return
+	}
+	if l := def.limits; l != nil && l.MaxDefers > 0 && len(f.deferred) >= l.MaxDefers {
+		panic(&LimitError{Func: funcName(def), Limit: "defer", Max: l.MaxDefers})
+	}
+}
+
+// funcDef returns the function or function literal enclosing node n, or nil.
+func funcDef(n *node) *node {
+	for a := n.anc; a != nil; a = a.anc {
+		if a.kind == funcDecl || a.kind == funcLit {
+			return a
+		}
+	}
+	return nil
+}
+
+// CompileLimits are limits on the size and complexity of the sources
+// compiled by an interpreter, which allow services accepting arbitrary
+// source code to reject pathological inputs, e.g. machine generated
+// megabyte files, before spending time in their compilation. The limits
+// apply to each source file or snippet. Zero values mean no limit.
+type CompileLimits struct {
+	MaxFileSize int // maximum size of a source, in bytes
+	MaxNodes    int // maximum number of syntax tree nodes of a source
+	MaxFuncs    int // maximum number of functions and function literals of a source
+	MaxImports  int // maximum number of imports of a source
+}
+
+// A CompileLimitError is the error returned by the compilation of a source
+// which exceeds a compile limit.
+type CompileLimitError struct {
+	File  string // name of the source file
+	Limit string // "file size", "nodes", "functions" or "imports"
+	Max   int    // value of the limit
+}
+
+func (e *CompileLimitError) Error() string {
+	return fmt.Sprintf("%s: %s limit exceeded (%d)", e.File, e.Limit, e.Max)
+}
+
+// compileCount counts the nodes, functions and imports of a source.
+type compileCount struct {
+	nodes, funcs, imports int
+}
+
+func (c *compileCount) add(kind nkind) {
+	c.nodes++
+	switch kind {
+	case funcDecl, funcLit:
+		c.funcs++
+	case importSpec:
+		c.imports++
+	}
+}
+
+// checkSize returns an error if a source file name of size bytes exceeds l.
+func (l CompileLimits) checkSize(name string, size int) error {
+	if l.MaxFileSize > 0 && size > l.MaxFileSize {
+		return &CompileLimitError{File: name, Limit: "file size", Max: l.MaxFileSize}
+	}
+	return nil
+}
+
+// checkCount returns an error if the count c of source file name exceeds l.
+func (l CompileLimits) checkCount(name string, c compileCount) error {
+	switch {
+	case l.MaxNodes > 0 && c.nodes > l.MaxNodes:
+		return &CompileLimitError{File: name, Limit: "nodes", Max: l.MaxNodes}
+	case l.MaxFuncs > 0 && c.funcs > l.MaxFuncs:
+		return &CompileLimitError{File: name, Limit: "functions", Max: l.MaxFuncs}
+	case l.MaxImports > 0 && c.imports > l.MaxImports:
+		return &CompileLimitError{File: name, Limit: "imports", Max: l.MaxImports}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package interp
+
+import (
+	"fmt"
+	"go/token"
+	"reflect"
+	"strings"
+)
+
+// A LogSink receives the messages of the print and println builtins and of
+// the top level functions of the log package called by interpreted code,
+// instead of the standard output and error. The message has no trailing
+// newline, and pos is the position of the call, or the zero position if the
+// function is called indirectly, e.g. as a function value.
+// It may be called concurrently.
+type LogSink func(level, msg string, pos token.Position)
+
+// Levels of the messages passed to a LogSink.
+const (
+	LogPrint = "print" // print and println builtins
+	LogInfo  = "info"  // log.Print, log.Printf, log.Println and log.Output
+	LogPanic = "panic" // log.Panic, log.Panicf and log.Panicln
+	LogFatal = "fatal" // log.Fatal, log.Fatalf and log.Fatalln, which panic
+)
+
+// sinkLog redefines the top level functions of the log package in p to send
+// their messages to the log sink. The functions are also registered in
+// interp.logFuncs, to bind the position of their direct calls, see callBin.
+func (interp *Interpreter) sinkLog(p map[string]reflect.Value) {
+	sink := interp.logSink
+	send := func(level, s string, pos token.Position) {
+		sink(level, strings.TrimSuffix(s, "\n"), pos)
+		if level == LogPanic || level == LogFatal {
+			panic(s)
+		}
+	}
+	logger := func(level string, sprint func(...interface{}) string) func(token.Position) interface{} {
+		return func(pos token.Position) interface{} {
+			return func(v ...interface{}) { send(level, sprint(v...), pos) }
+		}
+	}
+	loggerf := func(level string) func(token.Position) interface{} {
+		return func(pos token.Position) interface{} {
+			return func(format string, v ...interface{}) { send(level, fmt.Sprintf(format, v...), pos) }
+		}
+	}
+
+	funcs := map[string]func(token.Position) interface{}{
+		"Fatal":   logger(LogFatal, fmt.Sprint),
+		"Fatalf":  loggerf(LogFatal),
+		"Fatalln": logger(LogFatal, fmt.Sprintln),
+		"Panic":   logger(LogPanic, fmt.Sprint),
+		"Panicf":  loggerf(LogPanic),
+		"Panicln": logger(LogPanic, fmt.Sprintln),
+		"Print":   logger(LogInfo, fmt.Sprint),
+		"Printf":  loggerf(LogInfo),
+		"Println": logger(LogInfo, fmt.Sprintln),
+		"Output": func(pos token.Position) interface{} {
+			return func(_ int, s string) error { send(LogInfo, s, pos); return nil }
+		},
+	}
+	for name, gen := range funcs {
+		gen := gen
+		v := reflect.ValueOf(gen(token.Position{}))
+		p[name] = v
+		interp.logFuncs[v] = func(pos token.Position) reflect.Value { return reflect.ValueOf(gen(pos)) }
+	}
+}
+
+// renderArgs returns the message of a print or println builtin call with args.
+func renderArgs(render func(reflect.Value) string, args []reflect.Value) string {
+	var sb strings.Builder
+	for i, value := range args {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(render(value))
+	}
+	return sb.String()
+}
@@ -0,0 +1,446 @@
+package interp
+
+import (
+	"go/token"
+	"reflect"
+	"sort"
+)
+
+// Hover describes the identifier at a position of the interpreted sources.
+type Hover struct {
+	Name string         // name of the identifier
+	Kind string         // "const", "func", "type", "var", "package", or empty if unknown
+	Type string         // type of the identifier, if any
+	Doc  string         // declaration and doc comment, as returned by Doc, if any
+	Pos  token.Position // position of the identifier
+}
+
+// HoverAt returns the description of the identifier at the given line and
+// column, starting at 1, of the compiled source file, as in the position of
+// the interpreter FileSet. If the file name was used by several
+// evaluations, as DefaultSourceName in the REPL, the last one is searched.
+// It returns false if there is no identifier at this position.
+//
+// HoverAt, DefinitionOf and ReferencesOf rely on the symbols resolved by
+// the compilation, so their results match the interpreter semantics. They
+// are meant for editors embedding the interpreter.
+func (interp *Interpreter) HoverAt(file string, line, col int) (Hover, bool) {
+	interp.mutex.RLock()
+	defer interp.mutex.RUnlock()
+
+	n := interp.identAt(file, line, col)
+	if n == nil {
+		return Hover{}, false
+	}
+	h := Hover{Name: n.ident, Pos: interp.fset.Position(n.pos)}
+	sym := interp.identSym(n)
+	typ := n.typ
+	if isSelected(n) {
+		typ = n.anc.typ
+	}
+	if typ == nil && sym != nil {
+		typ = sym.typ
+	}
+	if typ != nil && typ.cat != nilT && typ.cat != builtinT {
+		if h.Type = typ.String(); h.Type == typ.cat.String() {
+			if rt := typ.TypeOf(); rt != nil {
+				h.Type = rt.String()
+			}
+		}
+	}
+
+	if sym == nil {
+		if p := selectedPkg(n); p != nil && p.cat == binPkgT {
+			h.Kind = binKind(interp.binPkg[p.path], n.ident)
+			if sig, doc, ok := interp.binDoc(p.path, []string{n.ident}); ok {
+				h.Doc = formatDoc(sig, doc)
+			}
+		}
+		return h, true
+	}
+	switch sym.kind {
+	case constSym:
+		h.Kind = "const"
+	case funcSym:
+		h.Kind = "func"
+	case typeSym:
+		h.Kind = "type"
+	case varSym:
+		h.Kind = "var"
+	case pkgSym:
+		h.Kind, h.Type = "package", sym.typ.path
+	}
+	if d := interp.symDecl(sym); d != nil {
+		h.Doc = formatDoc(d.sig, d.doc)
+	}
+	return h, true
+}
+
+// DefinitionOf returns the position of the declaration of the identifier at
+// the given line and column of the compiled source file, as HoverAt. It
+// returns false if there is no identifier at this position, or if it is not
+// declared in the interpreted sources, as a builtin or a symbol of a binary
+// package.
+func (interp *Interpreter) DefinitionOf(file string, line, col int) (token.Position, bool) {
+	interp.mutex.RLock()
+	defer interp.mutex.RUnlock()
+
+	n := interp.identAt(file, line, col)
+	if n == nil {
+		return token.Position{}, false
+	}
+	d := interp.definition(n)
+	if d == nil {
+		return token.Position{}, false
+	}
+	return interp.fset.Position(d.pos), true
+}
+
+// ReferencesOf returns the positions of the declaration and of all the uses
+// of the identifier at the given line and column of the compiled source
+// file, as HoverAt, sorted by file name and position. It returns nil if the
+// identifier is not declared in the interpreted sources.
+func (interp *Interpreter) ReferencesOf(file string, line, col int) []token.Position {
+	interp.mutex.RLock()
+	defer interp.mutex.RUnlock()
+
+	n := interp.identAt(file, line, col)
+	if n == nil {
+		return nil
+	}
+	d := interp.definition(n)
+	if d == nil {
+		return nil
+	}
+	var refs []*node
+	for _, r := range interp.roots {
+		r.Walk(func(n *node) bool {
+			if isIdent(n) && (n == d || interp.definition(n) == d) {
+				refs = append(refs, n)
+			}
+			return true
+		}, nil)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].pos < refs[j].pos })
+
+	pos := make([]token.Position, len(refs))
+	for i, r := range refs {
+		pos[i] = interp.fset.Position(r.pos)
+	}
+	sort.SliceStable(pos, func(i, j int) bool { return pos[i].Filename < pos[j].Filename })
+	return pos
+}
+
+// identAt returns the identifier node at the given position of file, or nil.
+func (interp *Interpreter) identAt(file string, line, col int) *node {
+	var f *token.File
+	var res *node
+	for i := len(interp.roots) - 1; i >= 0 && res == nil; i-- {
+		root := interp.roots[i]
+		if rf := interp.fset.File(root.pos); rf == nil || rf.Name() != file || f != nil && rf != f {
+			continue
+		} else {
+			f = rf
+		}
+		root.Walk(func(n *node) bool {
+			if res != nil {
+				return false
+			}
+			if !isIdent(n) {
+				return true
+			}
+			p := interp.fset.Position(n.pos)
+			if p.Line == line && col >= p.Column && col < p.Column+len(n.ident) {
+				res = n
+			}
+			return true
+		}, nil)
+	}
+	return res
+}
+
+// isIdent returns true if n is an identifier of the source, possibly
+// replaced by its constant value.
+func isIdent(n *node) bool {
+	if n.ident == "" || n.ident == "_" || !n.pos.IsValid() || n.anc == nil || loopVarOrigin(n) != nil {
+		return false
+	}
+	return n.kind == identExpr || n.kind == basicLit && n.sym != nil
+}
+
+// isSelected returns true if n is the selected identifier of a selector
+// expression.
+func isSelected(n *node) bool {
+	return n.anc != nil && n.anc.kind == selectorExpr && n.anc.child[1] == n
+}
+
+// selectedPkg returns the type of the package of the qualified identifier
+// n, or nil.
+func selectedPkg(n *node) *itype {
+	if !isSelected(n) {
+		return nil
+	}
+	if t := n.anc.child[0].typ; t != nil && (t.cat == binPkgT || t.cat == srcPkgT) {
+		return t
+	}
+	if s := n.anc.child[0].sym; s != nil && s.kind == pkgSym {
+		return s.typ
+	}
+	return nil
+}
+
+// identSym returns the symbol designated by the identifier n, or nil.
+func (interp *Interpreter) identSym(n *node) *symbol {
+	if p := selectedPkg(n); p != nil {
+		if p.cat == srcPkgT {
+			return interp.srcPkg[p.path][n.ident]
+		}
+		return nil
+	}
+	if n.sym != nil {
+		return n.sym
+	}
+	if isDeclIdent(n) {
+		// Look for the symbol among the package globals.
+		for _, sc := range interp.scopes {
+			if sym := sc.sym[n.ident]; sym != nil && globalDecl(sym, n.ident) == n {
+				return sym
+			}
+		}
+		if n.anc.kind == funcDecl || n.anc.kind == typeSpec || n.anc.kind == typeSpecAssign {
+			return nil
+		}
+		// Look for a local symbol referring to n.
+		var res *symbol
+		if f := frameOf(n); f != nil {
+			f.Walk(func(r *node) bool {
+				if res == nil && r.sym != nil && r.ident == n.ident && !r.sym.global && interp.definition(r) == n {
+					res = r.sym
+				}
+				return res == nil
+			}, nil)
+		}
+		return res
+	}
+	// Identifiers of types are not resolved by the compilation.
+	if sc := interp.scopes[rootPkg(n)]; sc != nil {
+		if sym, _, ok := sc.lookup(n.ident); ok && sym.kind == typeSym {
+			return sym
+		}
+	}
+	return nil
+}
+
+// rootPkg returns the name of the package of the source of n.
+func rootPkg(n *node) string {
+	for n.anc != nil {
+		n = n.anc
+	}
+	if n.kind == fileStmt && len(n.child) > 0 && n.child[0].kind == identExpr {
+		return n.child[0].ident
+	}
+	return mainID
+}
+
+// definition returns the identifier node declaring the identifier n, or
+// nil.
+func (interp *Interpreter) definition(n *node) *node {
+	if p := selectedPkg(n); p != nil {
+		if sym := interp.identSym(n); sym != nil {
+			return globalDecl(sym, n.ident)
+		}
+		return nil
+	}
+	if isSelected(n) {
+		return nil // field or method
+	}
+	sym := n.sym
+	if sym == nil {
+		if isDeclIdent(n) {
+			return n
+		}
+		if sym = interp.identSym(n); sym == nil {
+			return nil
+		}
+	}
+	switch sym.kind {
+	case binSym, bltnSym, pkgSym, labelSym:
+		return nil
+	}
+	if sym.global || sym.index < 0 || sym.kind == typeSym {
+		return globalDecl(sym, n.ident)
+	}
+
+	// Local symbol: look for its declaration in its frame, where it is
+	// identified by its index.
+	f := frameOf(n)
+	for i := 0; i < n.level && f != nil; i++ {
+		f = frameOf(f)
+	}
+	if f == nil {
+		return nil
+	}
+	var res *node
+	walkFrame(f, func(d *node) {
+		if d.kind != identExpr || d.ident != n.ident {
+			return
+		}
+		o := loopVarOrigin(d)
+		if o == nil && !isDeclIdent(d) {
+			return
+		}
+		index := d.findex
+		if d.anc.kind == fieldExpr {
+			index = paramIndex(f, d)
+		}
+		if index != sym.index {
+			return
+		}
+		if o != nil {
+			d = o
+		}
+		if res == nil || d.pos < res.pos {
+			res = d
+		}
+	})
+	return res
+}
+
+// globalDecl returns the identifier node declaring the global symbol sym
+// named name, or nil.
+func globalDecl(sym *symbol, name string) *node {
+	n := sym.node
+	if n == nil && sym.typ != nil {
+		n = sym.typ.node
+	}
+	if n == nil {
+		return nil
+	}
+	switch {
+	case n.kind == funcDecl:
+		return n.child[1]
+	case n.anc != nil && (n.anc.kind == typeSpec || n.anc.kind == typeSpecAssign):
+		// The type may refer to a child of its declaration.
+		return n.anc.child[0]
+	}
+	var res *node
+	n.Walk(func(c *node) bool {
+		if res == nil && c.kind == identExpr && c.ident == name && isDeclIdent(c) {
+			res = c
+		}
+		return res == nil
+	}, nil)
+	return res
+}
+
+// isDeclIdent returns true if n is an identifier in the position of a
+// declaration.
+func isDeclIdent(n *node) bool {
+	a := n.anc
+	if a == nil {
+		return false
+	}
+	switch a.kind {
+	case defineStmt, defineXStmt, valueSpec:
+		return childPos(n) < a.nleft
+	case funcDecl:
+		return a.child[1] == n
+	case typeSpec, typeSpecAssign, labeledStmt:
+		return a.child[0] == n
+	case rangeStmt:
+		return childPos(n) < 2 && len(a.child) == 4 || a.child[0] == n
+	case fieldExpr:
+		return childPos(n) < len(a.child)-1
+	}
+	return false
+}
+
+// loopVarOrigin returns the identifier declared by a for or range
+// statement, of which n is the copy made at each iteration, or nil.
+func loopVarOrigin(n *node) *node {
+	b := n.anc
+	if b == nil || b.kind != blockStmt || b.anc == nil {
+		return nil
+	}
+	switch s := b.anc; {
+	case s.kind == rangeStmt && s.lastChild() == b:
+		if i := childPos(n); i < 2 && s.child[i].ident == n.ident {
+			return s.child[i]
+		}
+	case s.kind == forStmt7 && s.lastChild() == b && b.child[0] == n:
+		if init := s.child[0]; init.kind == defineStmt && init.child[0].ident == n.ident {
+			return init.child[0]
+		}
+	}
+	return nil
+}
+
+// frameOf returns the function node, or the root node, whose frame holds
+// the local variables of n, or nil if n is a root.
+func frameOf(n *node) *node {
+	for a := n.anc; a != nil; a = a.anc {
+		if a.kind == funcDecl || a.kind == funcLit || a.anc == nil {
+			return a
+		}
+	}
+	return nil
+}
+
+// paramIndex returns the frame index of the parameter, result or receiver
+// d of function f, as allocated by the compilation, or -1.
+func paramIndex(f, d *node) int {
+	if f.kind != funcDecl && f.kind != funcLit {
+		return -1
+	}
+	i, ft := 0, f.child[2]
+	if len(ft.child) == 3 {
+		for _, c := range ft.child[2].child {
+			if len(c.child) == 1 {
+				i++
+				continue
+			}
+			for _, cc := range c.child[:len(c.child)-1] {
+				if cc == d {
+					return i
+				}
+				i++
+			}
+		}
+	}
+	if len(f.child[0].child) > 0 {
+		if fr := f.child[0].child[0]; len(fr.child) > 1 && fr.child[0] == d {
+			return i
+		}
+		i++
+	}
+	for _, c := range ft.child[1].child {
+		for _, cc := range c.child[:len(c.child)-1] {
+			if cc == d {
+				return i
+			}
+			i++
+		}
+	}
+	return -1
+}
+
+// walkFrame calls fn on the nodes of function f, except the ones of its
+// function literals.
+func walkFrame(f *node, fn func(*node)) {
+	f.Walk(func(n *node) bool {
+		if n.kind == funcLit && n != f {
+			return false
+		}
+		fn(n)
+		return true
+	}, nil)
+}
+
+// binKind returns the kind of the symbol name of binary package pkg.
+func binKind(pkg map[string]reflect.Value, name string) string {
+	v, ok := pkg[name]
+	if !ok {
+		return ""
+	}
+	return describeBin(name, v).Kind
+}
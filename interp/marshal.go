@@ -0,0 +1,744 @@
+package interp
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	marshalJSONType   = reflect.TypeOf((func() ([]byte, error))(nil))
+	unmarshalJSONType = reflect.TypeOf((func([]byte) error)(nil))
+)
+
+// Marshal returns the JSON encoding of v, a value of interpreted code, e.g.
+// a result of Eval, as encoding/json would encode the same value of compiled
+// code: unexported fields are omitted, struct tags and the MarshalJSON and
+// MarshalText methods of interpreted types are honored, and the values of
+// interpreted interface types, or wrapped into binary interfaces, are encoded
+// as their concrete values. The encoding can be shipped, e.g. over RPC, and
+// decoded with Unmarshal, or as JSON by other programs.
+func (interp *Interpreter) Marshal(v reflect.Value) ([]byte, error) {
+	e := &encoder{interp: interp, types: interp.typeIndex(), seen: map[uintptr]bool{}}
+	if err := e.encode(v, nil); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// Unmarshal decodes the JSON encoded data into v, a pointer to a value of
+// interpreted code or a settable value, as encoding/json would decode it for
+// the same value of compiled code. A non-nil value of an interpreted
+// interface type is decoded in place, according to its concrete type.
+func (interp *Interpreter) Unmarshal(data []byte, v reflect.Value) error {
+	switch {
+	case v.Kind() == reflect.Ptr && !v.IsNil():
+		v = v.Elem()
+	case !v.CanSet():
+		return errors.New("interp: Unmarshal of a non-pointer or non-settable value")
+	}
+	if !json.Valid(data) {
+		var x interface{}
+		return json.Unmarshal(data, &x)
+	}
+	d := &decoder{interp: interp, types: interp.typeIndex()}
+	return d.decode(data, v, nil)
+}
+
+// typeIndex returns the interpreted struct types of the global symbols,
+// indexed by their reflect type, to recover the interpreted type of the values
+// whose interpreted type is not known, e.g. at the top level. The named types
+// of other kinds share their reflect type with the predeclared types, and the
+// struct types of identical structures share a reflect type: they are not
+// indexed.
+func (interp *Interpreter) typeIndex() map[reflect.Type]*itype {
+	interp.mutex.RLock()
+	defer interp.mutex.RUnlock()
+	types := map[reflect.Type]*itype{}
+	for _, kind := range []sKind{typeSym, varSym} {
+		for _, sc := range interp.scopes {
+			for _, sym := range sc.sym {
+				t := sym.typ
+				if sym.kind != kind || t == nil || t.cat == valueT || t.rtype == nil || t.rtype.Kind() != reflect.Struct {
+					continue
+				}
+				if u, ok := types[t.rtype]; !ok {
+					types[t.rtype] = t
+				} else if u != nil && u.id() != t.id() {
+					types[t.rtype] = nil
+				}
+			}
+		}
+	}
+	return types
+}
+
+// callMethod calls the interpreted method name of type t on the addressable
+// value v, if its signature is ftyp. It returns false if there is no such
+// method.
+func (interp *Interpreter) callMethod(v reflect.Value, t *itype, name string, ftyp reflect.Type, in ...reflect.Value) ([]reflect.Value, bool) {
+	if t == nil || t.cat == valueT {
+		return nil, false
+	}
+	m, index := t.lookupMethod(name)
+	if m == nil || m.typ.TypeOf() != ftyp {
+		return nil, false
+	}
+	nod := *m
+	nod.recv = &receiver{nil, v, index}
+	return genFunctionWrapper(&nod)(interp.frame.Load()).Call(in), true
+}
+
+// jsonField is a field of a struct in its JSON encoding.
+type jsonField struct {
+	name      string
+	index     []int
+	typ       *itype // interpreted type, or nil
+	omitEmpty bool
+	quoted    bool
+	tagged    bool
+	depth     int
+}
+
+// structFields returns the interpreted types of the fields of the struct
+// type rt of interpreted type t, or nil if unknown.
+func structFields(rt reflect.Type, t *itype) []structField {
+	if t == nil {
+		return nil
+	}
+	if t = t.underlying(); t.cat != structT || len(t.field) != rt.NumField() {
+		return nil
+	}
+	return t.field
+}
+
+// jsonFields returns the fields of the struct type rt, of interpreted type t
+// if not nil, as encoded in JSON, following the rules of encoding/json for
+// embedded structs.
+func jsonFields(rt reflect.Type, t *itype) []jsonField {
+	var all []jsonField
+	var walk func(rt reflect.Type, t *itype, index []int, seen map[reflect.Type]bool)
+	walk = func(rt reflect.Type, t *itype, index []int, seen map[reflect.Type]bool) {
+		if seen[rt] {
+			return
+		}
+		seen[rt] = true
+		defer delete(seen, rt)
+		fields := structFields(rt, t)
+		for i := 0; i < rt.NumField(); i++ {
+			f := rt.Field(i)
+			name, embed := f.Name, f.Anonymous
+			var ft *itype
+			if fields != nil {
+				name, embed, ft = fields[i].name, fields[i].embed, fields[i].typ
+			} else if len(name) > 1 && name[0] == 'X' && !unicode.IsUpper(rune(name[1])) {
+				// Unexported field of an unknown interpreted type.
+				name = name[1:]
+			}
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			tname, opts, _ := strings.Cut(tag, ",")
+			idx := append(append([]int{}, index...), i)
+
+			if embed && tname == "" {
+				et, eit := f.Type, ft
+				if et.Kind() == reflect.Ptr {
+					et = et.Elem()
+					if eit != nil && eit.underlying().cat == ptrT {
+						eit = eit.underlying().val
+					}
+				}
+				if et.Kind() == reflect.Struct && et.PkgPath() == "" {
+					walk(et, eit, idx, seen)
+					continue
+				}
+			}
+			if !canExport(name) {
+				continue
+			}
+			jf := jsonField{name: name, index: idx, typ: ft, depth: len(idx), tagged: tname != ""}
+			if jf.tagged {
+				jf.name = tname
+			}
+			for _, o := range strings.Split(opts, ",") {
+				switch o {
+				case "omitempty":
+					jf.omitEmpty = true
+				case "string":
+					switch f.Type.Kind() {
+					case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+						reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+						reflect.Float32, reflect.Float64, reflect.String:
+						jf.quoted = true
+					}
+				}
+			}
+			all = append(all, jf)
+		}
+	}
+	walk(rt, t, nil, map[reflect.Type]bool{})
+
+	// Among the fields of the same name, the shallowest one wins, if it is
+	// the only one at its depth, or the only tagged one.
+	var res []jsonField
+	for i, f := range all {
+		keep := true
+		for j, g := range all {
+			if j == i || g.name != f.name {
+				continue
+			}
+			if g.depth < f.depth || g.depth == f.depth && (g.tagged || !f.tagged) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// elemType returns the interpreted type of the elements of t, or nil.
+func elemType(t *itype) *itype {
+	if t == nil {
+		return nil
+	}
+	switch t = t.underlying(); t.cat {
+	case ptrT, sliceT, arrayT, mapT:
+		return t.val
+	}
+	return nil
+}
+
+// isWrapper returns true if rt is the type of an interface wrapper of a value
+// of interpreted type.
+func isWrapper(rt reflect.Type) bool {
+	return rt.Kind() == reflect.Struct && rt.NumField() > 0 && rt.Field(0).Name == "IValue"
+}
+
+// isBinaryJSON returns true if the values of type rt are encoded and decoded
+// by encoding/json directly.
+func isBinaryJSON(rt reflect.Type) bool {
+	if rt.PkgPath() != "" {
+		return true
+	}
+	pt := reflect.PtrTo(rt)
+	return rt.Implements(jsonMarshalerType) || rt.Implements(textMarshalerType) ||
+		pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType)
+}
+
+type encoder struct {
+	interp *Interpreter
+	types  map[reflect.Type]*itype
+	seen   map[uintptr]bool // pointers being encoded, to detect cycles
+	buf    bytes.Buffer
+}
+
+func (e *encoder) encode(v reflect.Value, t *itype) error {
+	if !v.IsValid() {
+		e.buf.WriteString("null")
+		return nil
+	}
+	rt := v.Type()
+	switch {
+	case rt == valueInterfaceType:
+		vi := v.Interface().(valueInterface)
+		if vi.node == nil || !vi.value.IsValid() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		return e.encode(vi.value, vi.node.typ)
+	case isWrapper(rt):
+		return e.encode(v.Field(0), nil)
+	case rt.Kind() == reflect.Interface:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		return e.encode(v.Elem(), nil)
+	}
+	if t == nil {
+		t = e.types[rt]
+	}
+
+	// Methods of interpreted types.
+	if t != nil && t.cat != valueT {
+		a := v
+		if !a.CanAddr() {
+			a = reflect.New(rt).Elem()
+			a.Set(v)
+		}
+		if out, ok := e.interp.callMethod(a, t, "MarshalJSON", marshalJSONType); ok {
+			if err, _ := out[1].Interface().(error); err != nil {
+				return fmt.Errorf("interp: error calling MarshalJSON for type %s: %w", t.id(), err)
+			}
+			b := out[0].Bytes()
+			if !json.Valid(b) {
+				return fmt.Errorf("interp: invalid JSON returned by MarshalJSON for type %s", t.id())
+			}
+			return json.Compact(&e.buf, b)
+		}
+		if out, ok := e.interp.callMethod(a, t, "MarshalText", marshalJSONType); ok {
+			if err, _ := out[1].Interface().(error); err != nil {
+				return fmt.Errorf("interp: error calling MarshalText for type %s: %w", t.id(), err)
+			}
+			return e.marshal(string(out[0].Bytes()))
+		}
+	}
+	if isBinaryJSON(rt) {
+		return e.marshal(v.Interface())
+	}
+
+	switch rt.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		p := v.Pointer()
+		if e.seen[p] {
+			return &json.UnsupportedValueError{Value: v, Str: "encountered a cycle via " + rt.String()}
+		}
+		e.seen[p] = true
+		defer delete(e.seen, p)
+		return e.encode(v.Elem(), elemType(t))
+
+	case reflect.Struct:
+		e.buf.WriteByte('{')
+		first := true
+		for _, f := range jsonFields(rt, t) {
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok || f.omitEmpty && isEmptyJSON(fv) {
+				continue
+			}
+			if !first {
+				e.buf.WriteByte(',')
+			}
+			first = false
+			if err := e.marshal(f.name); err != nil {
+				return err
+			}
+			e.buf.WriteByte(':')
+			if f.quoted {
+				b, err := json.Marshal(fv.Interface())
+				if err != nil {
+					return err
+				}
+				if fv.Kind() != reflect.String {
+					e.buf.WriteByte('"')
+					e.buf.Write(b)
+					e.buf.WriteByte('"')
+					continue
+				}
+				if err := e.marshal(string(b)); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := e.encode(fv, f.typ); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte('}')
+		return nil
+
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		if rt.Elem().Kind() == reflect.Uint8 && elemType(t) == nil {
+			return e.marshal(v.Bytes())
+		}
+		fallthrough
+
+	case reflect.Array:
+		e.buf.WriteByte('[')
+		et := elemType(t)
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			if err := e.encode(v.Index(i), et); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte(']')
+		return nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		var kt *itype
+		if t != nil && t.underlying().cat == mapT {
+			kt = t.underlying().key
+		}
+		keys := make([]string, 0, v.Len())
+		values := map[string]reflect.Value{}
+		for it := v.MapRange(); it.Next(); {
+			k, err := e.mapKey(it.Key(), kt)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, k)
+			values[k] = it.Value()
+		}
+		sort.Strings(keys)
+		e.buf.WriteByte('{')
+		et := elemType(t)
+		for i, k := range keys {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			if err := e.marshal(k); err != nil {
+				return err
+			}
+			e.buf.WriteByte(':')
+			if err := e.encode(values[k], et); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte('}')
+		return nil
+
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return &json.UnsupportedTypeError{Type: rt}
+	}
+	return e.marshal(v.Interface())
+}
+
+// mapKey returns the string of the map key k, of interpreted type t.
+func (e *encoder) mapKey(k reflect.Value, t *itype) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if t == nil {
+		t = e.types[k.Type()]
+	}
+	a := reflect.New(k.Type()).Elem()
+	a.Set(k)
+	if out, ok := e.interp.callMethod(a, t, "MarshalText", marshalJSONType); ok {
+		if err, _ := out[1].Interface().(error); err != nil {
+			return "", err
+		}
+		return string(out[0].Bytes()), nil
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	if m, ok := k.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+	return "", &json.UnsupportedTypeError{Type: k.Type()}
+}
+
+func (e *encoder) marshal(x interface{}) error {
+	b, err := json.Marshal(x)
+	if err != nil {
+		return err
+	}
+	e.buf.Write(b)
+	return nil
+}
+
+// fieldByIndex returns the nested field of v at index, or false if it is
+// reached through a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmptyJSON returns true if v is empty for the omitempty option.
+func isEmptyJSON(v reflect.Value) bool {
+	if v.Type() == valueInterfaceType {
+		vi := v.Interface().(valueInterface)
+		return vi.node == nil || !vi.value.IsValid()
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Ptr:
+		return v.IsZero()
+	}
+	return false
+}
+
+type decoder struct {
+	interp *Interpreter
+	types  map[reflect.Type]*itype
+}
+
+// decode decodes data into the settable value v, of interpreted type t.
+func (d *decoder) decode(data []byte, v reflect.Value, t *itype) error {
+	rt := v.Type()
+	null := string(bytes.TrimSpace(data)) == "null"
+	if rt == valueInterfaceType {
+		vi := v.Interface().(valueInterface)
+		if null {
+			v.Set(reflect.Zero(rt))
+			return nil
+		}
+		if vi.node == nil || !vi.value.IsValid() {
+			return fmt.Errorf("interp: cannot unmarshal into a nil value of interface type %s", typeString(t, rt))
+		}
+		c := reflect.New(vi.value.Type()).Elem()
+		c.Set(vi.value)
+		if err := d.decode(data, c, vi.node.typ); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(valueInterface{vi.node, c}))
+		return nil
+	}
+	if t == nil {
+		t = d.types[rt]
+	}
+
+	// Methods of interpreted types, with pointer or value receivers.
+	if t != nil && t.cat != valueT && !null {
+		if out, ok := d.interp.callMethod(v, t, "UnmarshalJSON", unmarshalJSONType, reflect.ValueOf(data)); ok {
+			err, _ := out[0].Interface().(error)
+			return err
+		}
+		var s string
+		if json.Unmarshal(data, &s) == nil {
+			if out, ok := d.interp.callMethod(v, t, "UnmarshalText", unmarshalJSONType, reflect.ValueOf([]byte(s))); ok {
+				err, _ := out[0].Interface().(error)
+				return err
+			}
+		}
+	}
+	if isBinaryJSON(rt) {
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+
+	switch rt.Kind() {
+	case reflect.Interface:
+		switch {
+		case null:
+			v.Set(reflect.Zero(rt))
+		case !v.IsNil() && v.Elem().Kind() == reflect.Ptr && !v.Elem().IsNil():
+			return d.decode(data, v.Elem().Elem(), nil)
+		case rt.NumMethod() == 0:
+			var x interface{}
+			if err := json.Unmarshal(data, &x); err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(&x).Elem())
+		default:
+			return &json.UnmarshalTypeError{Value: "object", Type: rt}
+		}
+		return nil
+
+	case reflect.Ptr:
+		if null {
+			v.Set(reflect.Zero(rt))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(rt.Elem()))
+		}
+		return d.decode(data, v.Elem(), elemType(t))
+
+	case reflect.Struct:
+		if null {
+			return nil
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		fields := jsonFields(rt, t)
+		for key, raw := range obj {
+			var f *jsonField
+			for i := range fields {
+				if fields[i].name == key {
+					f = &fields[i]
+					break
+				}
+				if f == nil && strings.EqualFold(fields[i].name, key) {
+					f = &fields[i]
+				}
+			}
+			if f == nil {
+				continue
+			}
+			fv := settableField(v, f.index)
+			if f.quoted && string(raw) != "null" {
+				var s string
+				if err := json.Unmarshal(raw, &s); err != nil {
+					return err
+				}
+				raw = json.RawMessage(s)
+			}
+			if err := d.decode(raw, fv, f.typ); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Slice:
+		if null {
+			v.Set(reflect.Zero(rt))
+			return nil
+		}
+		if rt.Elem().Kind() == reflect.Uint8 && elemType(t) == nil {
+			return json.Unmarshal(data, v.Addr().Interface())
+		}
+		var a []json.RawMessage
+		if err := json.Unmarshal(data, &a); err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(rt, len(a), len(a))
+		et := elemType(t)
+		for i, raw := range a {
+			if err := d.decode(raw, s.Index(i), et); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+
+	case reflect.Array:
+		if null {
+			return nil
+		}
+		var a []json.RawMessage
+		if err := json.Unmarshal(data, &a); err != nil {
+			return err
+		}
+		et := elemType(t)
+		for i := 0; i < v.Len(); i++ {
+			if i >= len(a) {
+				v.Index(i).Set(reflect.Zero(rt.Elem()))
+				continue
+			}
+			if err := d.decode(a[i], v.Index(i), et); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		if null {
+			v.Set(reflect.Zero(rt))
+			return nil
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(rt, len(obj)))
+		}
+		var kt *itype
+		if t != nil && t.underlying().cat == mapT {
+			kt = t.underlying().key
+		}
+		et := elemType(t)
+		for key, raw := range obj {
+			k := reflect.New(rt.Key()).Elem()
+			if err := d.mapKey(key, k, kt); err != nil {
+				return err
+			}
+			e := reflect.New(rt.Elem()).Elem()
+			if err := d.decode(raw, e, et); err != nil {
+				return err
+			}
+			v.SetMapIndex(k, e)
+		}
+		return nil
+	}
+	if null {
+		return nil
+	}
+	return json.Unmarshal(data, v.Addr().Interface())
+}
+
+// mapKey decodes the map key s into the settable value k, of interpreted
+// type t.
+func (d *decoder) mapKey(s string, k reflect.Value, t *itype) error {
+	if t == nil {
+		t = d.types[k.Type()]
+	}
+	if out, ok := d.interp.callMethod(k, t, "UnmarshalText", unmarshalJSONType, reflect.ValueOf([]byte(s))); ok {
+		err, _ := out[0].Interface().(error)
+		return err
+	}
+	switch k.Kind() {
+	case reflect.String:
+		k.SetString(s)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || k.OverflowInt(n) {
+			return &json.UnmarshalTypeError{Value: "number " + s, Type: k.Type()}
+		}
+		k.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || k.OverflowUint(n) {
+			return &json.UnmarshalTypeError{Value: "number " + s, Type: k.Type()}
+		}
+		k.SetUint(n)
+		return nil
+	}
+	if u, ok := k.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	return &json.UnmarshalTypeError{Value: "string", Type: k.Type()}
+}
+
+// settableField returns the nested field of v at index, allocating the nil
+// embedded pointers on the way.
+func settableField(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// typeString returns the name of the interpreted type t, or of rt if t is nil.
+func typeString(t *itype, rt reflect.Type) string {
+	if t != nil {
+		return t.id()
+	}
+	return rt.String()
+}
@@ -0,0 +1,111 @@
+package interp
+
+import (
+	"reflect"
+	"strings"
+)
+
+// A Namespace is a private global scope of an interpreter, layered over the
+// scope of its main package. The code evaluated in a namespace sees the
+// imports and the global symbols of the main package, but the symbols it
+// declares are only visible in the namespace. It allows to run the snippets
+// of independent users, or tenants, in the same interpreter, without
+// collisions of names.
+//
+// Namespaces of an interpreter share its imported packages: the packages
+// imported in a namespace are initialized only once, but their names are
+// only declared in the namespace.
+type Namespace struct {
+	interp *Interpreter
+	id     string // id of the package scope of the namespace
+}
+
+// namespaceSep separates the main package and the namespace name in the id of
+// the package scope of a namespace. It can not appear in an import path.
+const namespaceSep = "#"
+
+// namespaceName returns the name of the namespace whose package scope is
+// identified by pkgID, or an empty string if pkgID is not a namespace.
+func namespaceName(pkgID string) string {
+	name, ok := strings.CutPrefix(pkgID, mainID+namespaceSep)
+	if !ok {
+		return ""
+	}
+	return name
+}
+
+// NewNamespace returns the namespace identified by name in the interpreter,
+// which is created if it does not exist yet.
+func (interp *Interpreter) NewNamespace(name string) *Namespace {
+	interp.compile.Lock()
+	defer interp.compile.Unlock()
+
+	id := mainID + namespaceSep + name
+	ms := interp.initScopePkg(mainID, mainID)
+
+	interp.mutex.Lock()
+	if _, ok := interp.scopes[id]; !ok {
+		sc := ms.pushBloc()
+		sc.pkgID, sc.pkgName = id, mainID
+		interp.scopes[id] = sc
+	}
+	interp.mutex.Unlock()
+
+	return &Namespace{interp: interp, id: id}
+}
+
+// Name returns the name of the namespace.
+func (ns *Namespace) Name() string { return namespaceName(ns.id) }
+
+// Compile parses and compiles Go code represented as a string, in the
+// namespace. The resulting program is executed by the Execute method of the
+// interpreter.
+func (ns *Namespace) Compile(src string) (*Program, error) {
+	interp := ns.interp
+	interp.compile.Lock()
+	defer interp.compile.Unlock()
+
+	if interp.name == "" {
+		interp.name = DefaultSourceName
+	}
+	n, err := interp.parse(src, interp.name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// The frame layout of the namespace may be outdated by the compilations
+	// in other scopes.
+	interp.scopes[ns.id].types = interp.universe.types
+
+	return interp.compileAST(n, ns.id)
+}
+
+// Eval evaluates Go code represented as a string in the namespace. It returns
+// the last result computed by the interpreter, and a non nil error in case of
+// failure.
+func (ns *Namespace) Eval(src string) (res reflect.Value, err error) {
+	prog, err := ns.Compile(src)
+	if err != nil {
+		return res, err
+	}
+	return ns.interp.Execute(prog)
+}
+
+// Globals returns a map of global variables and constants declared in the
+// namespace.
+func (ns *Namespace) Globals() map[string]reflect.Value {
+	syms := map[string]reflect.Value{}
+	interp := ns.interp
+	interp.mutex.RLock()
+	defer interp.mutex.RUnlock()
+
+	for n, s := range interp.scopes[ns.id].sym {
+		switch s.kind {
+		case constSym:
+			syms[n] = s.rval
+		case varSym:
+			syms[n] = interp.frame.Load().data[s.index]
+		}
+	}
+	return syms
+}
@@ -11,6 +11,9 @@ import (
 // Arithmetic operators
 
 func add(n *node) {
+	if addFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.concrete().TypeOf()
 	isInterface := n.typ.TypeOf().Kind() == reflect.Interface
@@ -37,6 +40,14 @@ func add(n *node) {
 		case c1.rval.IsValid():
 			v0 := genValue(c0)
 			s1 := vString(c1.rval)
+			if isStrAccumulation(n) {
+				cat := &strCat{}
+				n.exec = func(f *frame) bltn {
+					dest(f).SetString(cat.add(v0(f).String(), s1))
+					return next
+				}
+				break
+			}
 			n.exec = func(f *frame) bltn {
 				dest(f).SetString(v0(f).String() + s1)
 				return next
@@ -44,6 +55,14 @@ func add(n *node) {
 		default:
 			v0 := genValue(c0)
 			v1 := genValue(c1)
+			if isStrAccumulation(n) {
+				cat := &strCat{}
+				n.exec = func(f *frame) bltn {
+					dest(f).SetString(cat.add(v0(f).String(), v1(f).String()))
+					return next
+				}
+				break
+			}
 			n.exec = func(f *frame) bltn {
 				dest(f).SetString(v0(f).String() + v1(f).String())
 				return next
@@ -220,6 +239,9 @@ func addConst(n *node) {
 }
 
 func and(n *node) {
+	if andFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.concrete().TypeOf()
 	isInterface := n.typ.TypeOf().Kind() == reflect.Interface
@@ -324,6 +346,9 @@ func andConst(n *node) {
 }
 
 func andNot(n *node) {
+	if andNotFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.concrete().TypeOf()
 	isInterface := n.typ.TypeOf().Kind() == reflect.Interface
@@ -428,6 +453,9 @@ func andNotConst(n *node) {
 }
 
 func mul(n *node) {
+	if mulFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.concrete().TypeOf()
 	isInterface := n.typ.TypeOf().Kind() == reflect.Interface
@@ -604,6 +632,9 @@ func mulConst(n *node) {
 }
 
 func or(n *node) {
+	if orFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.concrete().TypeOf()
 	isInterface := n.typ.TypeOf().Kind() == reflect.Interface
@@ -708,6 +739,9 @@ func orConst(n *node) {
 }
 
 func quo(n *node) {
+	if quoFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.concrete().TypeOf()
 	isInterface := n.typ.TypeOf().Kind() == reflect.Interface
@@ -893,6 +927,9 @@ func quoConst(n *node) {
 }
 
 func rem(n *node) {
+	if remFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.concrete().TypeOf()
 	isInterface := n.typ.TypeOf().Kind() == reflect.Interface
@@ -1205,6 +1242,9 @@ func shrConst(n *node) {
 }
 
 func sub(n *node) {
+	if subFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.concrete().TypeOf()
 	isInterface := n.typ.TypeOf().Kind() == reflect.Interface
@@ -1381,6 +1421,9 @@ func subConst(n *node) {
 }
 
 func xor(n *node) {
+	if xorFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.concrete().TypeOf()
 	isInterface := n.typ.TypeOf().Kind() == reflect.Interface
@@ -1487,6 +1530,9 @@ func xorConst(n *node) {
 // Assign operators
 
 func addAssign(n *node) {
+	if addAssignFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.TypeOf()
 	c0, c1 := n.child[0], n.child[1]
@@ -1503,9 +1549,10 @@ func addAssign(n *node) {
 		case reflect.String:
 			v0 := genValueString(c0)
 			v1 := vString(c1.rval)
+			cat := &strCat{}
 			n.exec = func(f *frame) bltn {
 				v, s := v0(f)
-				v.SetString(s + v1)
+				v.SetString(cat.add(s, v1))
 				if setMap {
 					mapValue(f).SetMapIndex(indexValue(f), v)
 				}
@@ -1561,9 +1608,10 @@ func addAssign(n *node) {
 		case reflect.String:
 			v0 := genValueString(c0)
 			v1 := genValue(c1)
+			cat := &strCat{}
 			n.exec = func(f *frame) bltn {
 				v, s := v0(f)
-				v.SetString(s + v1(f).String())
+				v.SetString(cat.add(s, v1(f).String()))
 				if setMap {
 					mapValue(f).SetMapIndex(indexValue(f), v)
 				}
@@ -1621,6 +1669,9 @@ func addAssign(n *node) {
 }
 
 func andAssign(n *node) {
+	if andAssignFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.TypeOf()
 	c0, c1 := n.child[0], n.child[1]
@@ -1688,6 +1739,9 @@ func andAssign(n *node) {
 }
 
 func andNotAssign(n *node) {
+	if andNotAssignFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.TypeOf()
 	c0, c1 := n.child[0], n.child[1]
@@ -1755,6 +1809,9 @@ func andNotAssign(n *node) {
 }
 
 func mulAssign(n *node) {
+	if mulAssignFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.TypeOf()
 	c0, c1 := n.child[0], n.child[1]
@@ -1867,6 +1924,9 @@ func mulAssign(n *node) {
 }
 
 func orAssign(n *node) {
+	if orAssignFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.TypeOf()
 	c0, c1 := n.child[0], n.child[1]
@@ -1934,6 +1994,9 @@ func orAssign(n *node) {
 }
 
 func quoAssign(n *node) {
+	if quoAssignFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.TypeOf()
 	c0, c1 := n.child[0], n.child[1]
@@ -2046,6 +2109,9 @@ func quoAssign(n *node) {
 }
 
 func remAssign(n *node) {
+	if remAssignFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.TypeOf()
 	c0, c1 := n.child[0], n.child[1]
@@ -2247,6 +2313,9 @@ func shrAssign(n *node) {
 }
 
 func subAssign(n *node) {
+	if subAssignFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.TypeOf()
 	c0, c1 := n.child[0], n.child[1]
@@ -2359,6 +2428,9 @@ func subAssign(n *node) {
 }
 
 func xorAssign(n *node) {
+	if xorAssignFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.TypeOf()
 	c0, c1 := n.child[0], n.child[1]
@@ -2426,6 +2498,9 @@ func xorAssign(n *node) {
 }
 
 func dec(n *node) {
+	if decFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.TypeOf()
 	c0 := n.child[0]
@@ -2482,6 +2557,9 @@ func dec(n *node) {
 }
 
 func inc(n *node) {
+	if incFast(n) {
+		return
+	}
 	next := getExec(n.tnext)
 	typ := n.typ.TypeOf()
 	c0 := n.child[0]
@@ -2619,6 +2697,9 @@ func posConst(n *node) {
 }
 
 func equal(n *node) {
+	if equalFast(n) {
+		return
+	}
 	tnext := getExec(n.tnext)
 	dest := genValueOutput(n, reflect.TypeOf(true))
 	typ := n.typ.concrete().TypeOf()
@@ -2719,8 +2800,8 @@ func equal(n *node) {
 		if n.fnext != nil {
 			fnext := getExec(n.fnext)
 			n.exec = func(f *frame) bltn {
-				i0 := v0(f).Interface()
-				i1 := v1(f).Interface()
+				i0 := ifaceValue(v0(f))
+				i1 := ifaceValue(v1(f))
 				if i0 == i1 {
 					dest(f).SetBool(true)
 					return tnext
@@ -2731,8 +2812,8 @@ func equal(n *node) {
 		} else {
 			dest := genValue(n)
 			n.exec = func(f *frame) bltn {
-				i0 := v0(f).Interface()
-				i1 := v1(f).Interface()
+				i0 := ifaceValue(v0(f))
+				i1 := ifaceValue(v1(f))
 				dest(f).SetBool(i0 == i1)
 				return tnext
 			}
@@ -3219,6 +3300,9 @@ func equal(n *node) {
 }
 
 func greater(n *node) {
+	if greaterFast(n) {
+		return
+	}
 	tnext := getExec(n.tnext)
 	dest := genValueOutput(n, reflect.TypeOf(true))
 	typ := n.typ.concrete().TypeOf()
@@ -3547,6 +3631,9 @@ func greater(n *node) {
 }
 
 func greaterEqual(n *node) {
+	if greaterEqualFast(n) {
+		return
+	}
 	tnext := getExec(n.tnext)
 	dest := genValueOutput(n, reflect.TypeOf(true))
 	typ := n.typ.concrete().TypeOf()
@@ -3875,6 +3962,9 @@ func greaterEqual(n *node) {
 }
 
 func lower(n *node) {
+	if lowerFast(n) {
+		return
+	}
 	tnext := getExec(n.tnext)
 	dest := genValueOutput(n, reflect.TypeOf(true))
 	typ := n.typ.concrete().TypeOf()
@@ -4203,6 +4293,9 @@ func lower(n *node) {
 }
 
 func lowerEqual(n *node) {
+	if lowerEqualFast(n) {
+		return
+	}
 	tnext := getExec(n.tnext)
 	dest := genValueOutput(n, reflect.TypeOf(true))
 	typ := n.typ.concrete().TypeOf()
@@ -4531,6 +4624,9 @@ func lowerEqual(n *node) {
 }
 
 func notEqual(n *node) {
+	if notEqualFast(n) {
+		return
+	}
 	tnext := getExec(n.tnext)
 	dest := genValueOutput(n, reflect.TypeOf(true))
 	typ := n.typ.concrete().TypeOf()
@@ -4631,8 +4727,8 @@ func notEqual(n *node) {
 		if n.fnext != nil {
 			fnext := getExec(n.fnext)
 			n.exec = func(f *frame) bltn {
-				i0 := v0(f).Interface()
-				i1 := v1(f).Interface()
+				i0 := ifaceValue(v0(f))
+				i1 := ifaceValue(v1(f))
 				if i0 != i1 {
 					dest(f).SetBool(true)
 					return tnext
@@ -4643,8 +4739,8 @@ func notEqual(n *node) {
 		} else {
 			dest := genValue(n)
 			n.exec = func(f *frame) bltn {
-				i0 := v0(f).Interface()
-				i1 := v1(f).Interface()
+				i0 := ifaceValue(v0(f))
+				i1 := ifaceValue(v1(f))
 				dest(f).SetBool(i0 != i1)
 				return tnext
 			}
@@ -5129,3 +5225,1001 @@ func notEqual(n *node) {
 		}
 	}
 }
+
+// Fast operators on signed integer and float64 values of the local frame
+
+func addFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	next := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+
+	switch k {
+	case reflect.Int:
+		switch {
+		case i0 < 0:
+			j0 := vInt(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(j0 + f.data[i1].Int())
+				return next
+			}
+		case i1 < 0:
+			j1 := vInt(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() + j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() + f.data[i1].Int())
+				return next
+			}
+		}
+	case reflect.Float64:
+		switch {
+		case i0 < 0:
+			j0 := vFloat(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(j0 + f.data[i1].Float())
+				return next
+			}
+		case i1 < 0:
+			j1 := vFloat(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(f.data[i0].Float() + j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(f.data[i0].Float() + f.data[i1].Float())
+				return next
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func addAssignFast(n *node) bool {
+	k, d, i1 := fastAssign(n)
+	next := getExec(n.tnext)
+
+	switch k {
+	case reflect.Int:
+		if i1 < 0 {
+			j1 := vInt(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetInt(v.Int() + j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetInt(v.Int() + f.data[i1].Int())
+			return next
+		}
+	case reflect.Float64:
+		if i1 < 0 {
+			j1 := vFloat(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetFloat(v.Float() + j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetFloat(v.Float() + f.data[i1].Float())
+			return next
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func andFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	next := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+
+	switch k {
+	case reflect.Int:
+		switch {
+		case i0 < 0:
+			j0 := vInt(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(j0 & f.data[i1].Int())
+				return next
+			}
+		case i1 < 0:
+			j1 := vInt(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() & j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() & f.data[i1].Int())
+				return next
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func andAssignFast(n *node) bool {
+	k, d, i1 := fastAssign(n)
+	next := getExec(n.tnext)
+
+	switch k {
+	case reflect.Int:
+		if i1 < 0 {
+			j1 := vInt(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetInt(v.Int() & j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetInt(v.Int() & f.data[i1].Int())
+			return next
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func andNotFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	next := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+
+	switch k {
+	case reflect.Int:
+		switch {
+		case i0 < 0:
+			j0 := vInt(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(j0 &^ f.data[i1].Int())
+				return next
+			}
+		case i1 < 0:
+			j1 := vInt(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() &^ j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() &^ f.data[i1].Int())
+				return next
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func andNotAssignFast(n *node) bool {
+	k, d, i1 := fastAssign(n)
+	next := getExec(n.tnext)
+
+	switch k {
+	case reflect.Int:
+		if i1 < 0 {
+			j1 := vInt(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetInt(v.Int() &^ j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetInt(v.Int() &^ f.data[i1].Int())
+			return next
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func mulFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	next := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+
+	switch k {
+	case reflect.Int:
+		switch {
+		case i0 < 0:
+			j0 := vInt(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(j0 * f.data[i1].Int())
+				return next
+			}
+		case i1 < 0:
+			j1 := vInt(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() * j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() * f.data[i1].Int())
+				return next
+			}
+		}
+	case reflect.Float64:
+		switch {
+		case i0 < 0:
+			j0 := vFloat(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(j0 * f.data[i1].Float())
+				return next
+			}
+		case i1 < 0:
+			j1 := vFloat(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(f.data[i0].Float() * j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(f.data[i0].Float() * f.data[i1].Float())
+				return next
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func mulAssignFast(n *node) bool {
+	k, d, i1 := fastAssign(n)
+	next := getExec(n.tnext)
+
+	switch k {
+	case reflect.Int:
+		if i1 < 0 {
+			j1 := vInt(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetInt(v.Int() * j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetInt(v.Int() * f.data[i1].Int())
+			return next
+		}
+	case reflect.Float64:
+		if i1 < 0 {
+			j1 := vFloat(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetFloat(v.Float() * j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetFloat(v.Float() * f.data[i1].Float())
+			return next
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func orFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	next := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+
+	switch k {
+	case reflect.Int:
+		switch {
+		case i0 < 0:
+			j0 := vInt(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(j0 | f.data[i1].Int())
+				return next
+			}
+		case i1 < 0:
+			j1 := vInt(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() | j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() | f.data[i1].Int())
+				return next
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func orAssignFast(n *node) bool {
+	k, d, i1 := fastAssign(n)
+	next := getExec(n.tnext)
+
+	switch k {
+	case reflect.Int:
+		if i1 < 0 {
+			j1 := vInt(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetInt(v.Int() | j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetInt(v.Int() | f.data[i1].Int())
+			return next
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func quoFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	next := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+
+	switch k {
+	case reflect.Int:
+		switch {
+		case i0 < 0:
+			j0 := vInt(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(j0 / f.data[i1].Int())
+				return next
+			}
+		case i1 < 0:
+			j1 := vInt(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() / j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() / f.data[i1].Int())
+				return next
+			}
+		}
+	case reflect.Float64:
+		switch {
+		case i0 < 0:
+			j0 := vFloat(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(j0 / f.data[i1].Float())
+				return next
+			}
+		case i1 < 0:
+			j1 := vFloat(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(f.data[i0].Float() / j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(f.data[i0].Float() / f.data[i1].Float())
+				return next
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func quoAssignFast(n *node) bool {
+	k, d, i1 := fastAssign(n)
+	next := getExec(n.tnext)
+
+	switch k {
+	case reflect.Int:
+		if i1 < 0 {
+			j1 := vInt(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetInt(v.Int() / j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetInt(v.Int() / f.data[i1].Int())
+			return next
+		}
+	case reflect.Float64:
+		if i1 < 0 {
+			j1 := vFloat(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetFloat(v.Float() / j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetFloat(v.Float() / f.data[i1].Float())
+			return next
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func remFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	next := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+
+	switch k {
+	case reflect.Int:
+		switch {
+		case i0 < 0:
+			j0 := vInt(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(j0 % f.data[i1].Int())
+				return next
+			}
+		case i1 < 0:
+			j1 := vInt(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() % j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() % f.data[i1].Int())
+				return next
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func remAssignFast(n *node) bool {
+	k, d, i1 := fastAssign(n)
+	next := getExec(n.tnext)
+
+	switch k {
+	case reflect.Int:
+		if i1 < 0 {
+			j1 := vInt(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetInt(v.Int() % j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetInt(v.Int() % f.data[i1].Int())
+			return next
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func subFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	next := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+
+	switch k {
+	case reflect.Int:
+		switch {
+		case i0 < 0:
+			j0 := vInt(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(j0 - f.data[i1].Int())
+				return next
+			}
+		case i1 < 0:
+			j1 := vInt(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() - j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() - f.data[i1].Int())
+				return next
+			}
+		}
+	case reflect.Float64:
+		switch {
+		case i0 < 0:
+			j0 := vFloat(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(j0 - f.data[i1].Float())
+				return next
+			}
+		case i1 < 0:
+			j1 := vFloat(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(f.data[i0].Float() - j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetFloat(f.data[i0].Float() - f.data[i1].Float())
+				return next
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func subAssignFast(n *node) bool {
+	k, d, i1 := fastAssign(n)
+	next := getExec(n.tnext)
+
+	switch k {
+	case reflect.Int:
+		if i1 < 0 {
+			j1 := vInt(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetInt(v.Int() - j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetInt(v.Int() - f.data[i1].Int())
+			return next
+		}
+	case reflect.Float64:
+		if i1 < 0 {
+			j1 := vFloat(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetFloat(v.Float() - j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetFloat(v.Float() - f.data[i1].Float())
+			return next
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func xorFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	next := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+
+	switch k {
+	case reflect.Int:
+		switch {
+		case i0 < 0:
+			j0 := vInt(c0.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(j0 ^ f.data[i1].Int())
+				return next
+			}
+		case i1 < 0:
+			j1 := vInt(c1.rval)
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() ^ j1)
+				return next
+			}
+		default:
+			n.exec = func(f *frame) bltn {
+				f.data[d].SetInt(f.data[i0].Int() ^ f.data[i1].Int())
+				return next
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func xorAssignFast(n *node) bool {
+	k, d, i1 := fastAssign(n)
+	next := getExec(n.tnext)
+
+	switch k {
+	case reflect.Int:
+		if i1 < 0 {
+			j1 := vInt(n.child[1].rval)
+			n.exec = func(f *frame) bltn {
+				v := f.data[d]
+				v.SetInt(v.Int() ^ j1)
+				return next
+			}
+			break
+		}
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetInt(v.Int() ^ f.data[i1].Int())
+			return next
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func decFast(n *node) bool {
+	k, d, _ := fastAssign(n)
+	next := getExec(n.tnext)
+
+	switch k {
+	case reflect.Int:
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetInt(v.Int() - 1)
+			return next
+		}
+	case reflect.Float64:
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetFloat(v.Float() - 1)
+			return next
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func incFast(n *node) bool {
+	k, d, _ := fastAssign(n)
+	next := getExec(n.tnext)
+
+	switch k {
+	case reflect.Int:
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetInt(v.Int() + 1)
+			return next
+		}
+	case reflect.Float64:
+		n.exec = func(f *frame) bltn {
+			v := f.data[d]
+			v.SetFloat(v.Float() + 1)
+			return next
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func equalFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	tnext := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+	if k == reflect.Invalid {
+		return false
+	}
+
+	// The comparison functions of both operands.
+	var cmp func(f *frame) bool
+	switch {
+	case k == reflect.Int && i0 < 0:
+		j0 := vInt(c0.rval)
+		cmp = func(f *frame) bool { return j0 == f.data[i1].Int() }
+	case k == reflect.Int && i1 < 0:
+		j1 := vInt(c1.rval)
+		cmp = func(f *frame) bool { return f.data[i0].Int() == j1 }
+	case k == reflect.Int:
+		cmp = func(f *frame) bool { return f.data[i0].Int() == f.data[i1].Int() }
+	case i0 < 0:
+		j0 := vFloat(c0.rval)
+		cmp = func(f *frame) bool { return j0 == f.data[i1].Float() }
+	case i1 < 0:
+		j1 := vFloat(c1.rval)
+		cmp = func(f *frame) bool { return f.data[i0].Float() == j1 }
+	default:
+		cmp = func(f *frame) bool { return f.data[i0].Float() == f.data[i1].Float() }
+	}
+
+	if n.fnext != nil {
+		fnext := getExec(n.fnext)
+		n.exec = func(f *frame) bltn {
+			if cmp(f) {
+				f.data[d].SetBool(true)
+				return tnext
+			}
+			f.data[d].SetBool(false)
+			return fnext
+		}
+		return true
+	}
+	n.exec = func(f *frame) bltn {
+		f.data[d].SetBool(cmp(f))
+		return tnext
+	}
+	return true
+}
+
+func greaterFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	tnext := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+	if k == reflect.Invalid {
+		return false
+	}
+
+	// The comparison functions of both operands.
+	var cmp func(f *frame) bool
+	switch {
+	case k == reflect.Int && i0 < 0:
+		j0 := vInt(c0.rval)
+		cmp = func(f *frame) bool { return j0 > f.data[i1].Int() }
+	case k == reflect.Int && i1 < 0:
+		j1 := vInt(c1.rval)
+		cmp = func(f *frame) bool { return f.data[i0].Int() > j1 }
+	case k == reflect.Int:
+		cmp = func(f *frame) bool { return f.data[i0].Int() > f.data[i1].Int() }
+	case i0 < 0:
+		j0 := vFloat(c0.rval)
+		cmp = func(f *frame) bool { return j0 > f.data[i1].Float() }
+	case i1 < 0:
+		j1 := vFloat(c1.rval)
+		cmp = func(f *frame) bool { return f.data[i0].Float() > j1 }
+	default:
+		cmp = func(f *frame) bool { return f.data[i0].Float() > f.data[i1].Float() }
+	}
+
+	if n.fnext != nil {
+		fnext := getExec(n.fnext)
+		n.exec = func(f *frame) bltn {
+			if cmp(f) {
+				f.data[d].SetBool(true)
+				return tnext
+			}
+			f.data[d].SetBool(false)
+			return fnext
+		}
+		return true
+	}
+	n.exec = func(f *frame) bltn {
+		f.data[d].SetBool(cmp(f))
+		return tnext
+	}
+	return true
+}
+
+func greaterEqualFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	tnext := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+	if k == reflect.Invalid {
+		return false
+	}
+
+	// The comparison functions of both operands.
+	var cmp func(f *frame) bool
+	switch {
+	case k == reflect.Int && i0 < 0:
+		j0 := vInt(c0.rval)
+		cmp = func(f *frame) bool { return j0 >= f.data[i1].Int() }
+	case k == reflect.Int && i1 < 0:
+		j1 := vInt(c1.rval)
+		cmp = func(f *frame) bool { return f.data[i0].Int() >= j1 }
+	case k == reflect.Int:
+		cmp = func(f *frame) bool { return f.data[i0].Int() >= f.data[i1].Int() }
+	case i0 < 0:
+		j0 := vFloat(c0.rval)
+		cmp = func(f *frame) bool { return j0 >= f.data[i1].Float() }
+	case i1 < 0:
+		j1 := vFloat(c1.rval)
+		cmp = func(f *frame) bool { return f.data[i0].Float() >= j1 }
+	default:
+		cmp = func(f *frame) bool { return f.data[i0].Float() >= f.data[i1].Float() }
+	}
+
+	if n.fnext != nil {
+		fnext := getExec(n.fnext)
+		n.exec = func(f *frame) bltn {
+			if cmp(f) {
+				f.data[d].SetBool(true)
+				return tnext
+			}
+			f.data[d].SetBool(false)
+			return fnext
+		}
+		return true
+	}
+	n.exec = func(f *frame) bltn {
+		f.data[d].SetBool(cmp(f))
+		return tnext
+	}
+	return true
+}
+
+func lowerFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	tnext := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+	if k == reflect.Invalid {
+		return false
+	}
+
+	// The comparison functions of both operands.
+	var cmp func(f *frame) bool
+	switch {
+	case k == reflect.Int && i0 < 0:
+		j0 := vInt(c0.rval)
+		cmp = func(f *frame) bool { return j0 < f.data[i1].Int() }
+	case k == reflect.Int && i1 < 0:
+		j1 := vInt(c1.rval)
+		cmp = func(f *frame) bool { return f.data[i0].Int() < j1 }
+	case k == reflect.Int:
+		cmp = func(f *frame) bool { return f.data[i0].Int() < f.data[i1].Int() }
+	case i0 < 0:
+		j0 := vFloat(c0.rval)
+		cmp = func(f *frame) bool { return j0 < f.data[i1].Float() }
+	case i1 < 0:
+		j1 := vFloat(c1.rval)
+		cmp = func(f *frame) bool { return f.data[i0].Float() < j1 }
+	default:
+		cmp = func(f *frame) bool { return f.data[i0].Float() < f.data[i1].Float() }
+	}
+
+	if n.fnext != nil {
+		fnext := getExec(n.fnext)
+		n.exec = func(f *frame) bltn {
+			if cmp(f) {
+				f.data[d].SetBool(true)
+				return tnext
+			}
+			f.data[d].SetBool(false)
+			return fnext
+		}
+		return true
+	}
+	n.exec = func(f *frame) bltn {
+		f.data[d].SetBool(cmp(f))
+		return tnext
+	}
+	return true
+}
+
+func lowerEqualFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	tnext := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+	if k == reflect.Invalid {
+		return false
+	}
+
+	// The comparison functions of both operands.
+	var cmp func(f *frame) bool
+	switch {
+	case k == reflect.Int && i0 < 0:
+		j0 := vInt(c0.rval)
+		cmp = func(f *frame) bool { return j0 <= f.data[i1].Int() }
+	case k == reflect.Int && i1 < 0:
+		j1 := vInt(c1.rval)
+		cmp = func(f *frame) bool { return f.data[i0].Int() <= j1 }
+	case k == reflect.Int:
+		cmp = func(f *frame) bool { return f.data[i0].Int() <= f.data[i1].Int() }
+	case i0 < 0:
+		j0 := vFloat(c0.rval)
+		cmp = func(f *frame) bool { return j0 <= f.data[i1].Float() }
+	case i1 < 0:
+		j1 := vFloat(c1.rval)
+		cmp = func(f *frame) bool { return f.data[i0].Float() <= j1 }
+	default:
+		cmp = func(f *frame) bool { return f.data[i0].Float() <= f.data[i1].Float() }
+	}
+
+	if n.fnext != nil {
+		fnext := getExec(n.fnext)
+		n.exec = func(f *frame) bltn {
+			if cmp(f) {
+				f.data[d].SetBool(true)
+				return tnext
+			}
+			f.data[d].SetBool(false)
+			return fnext
+		}
+		return true
+	}
+	n.exec = func(f *frame) bltn {
+		f.data[d].SetBool(cmp(f))
+		return tnext
+	}
+	return true
+}
+
+func notEqualFast(n *node) bool {
+	k, d, i0, i1 := fastBinary(n)
+	tnext := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+	if k == reflect.Invalid {
+		return false
+	}
+
+	// The comparison functions of both operands.
+	var cmp func(f *frame) bool
+	switch {
+	case k == reflect.Int && i0 < 0:
+		j0 := vInt(c0.rval)
+		cmp = func(f *frame) bool { return j0 != f.data[i1].Int() }
+	case k == reflect.Int && i1 < 0:
+		j1 := vInt(c1.rval)
+		cmp = func(f *frame) bool { return f.data[i0].Int() != j1 }
+	case k == reflect.Int:
+		cmp = func(f *frame) bool { return f.data[i0].Int() != f.data[i1].Int() }
+	case i0 < 0:
+		j0 := vFloat(c0.rval)
+		cmp = func(f *frame) bool { return j0 != f.data[i1].Float() }
+	case i1 < 0:
+		j1 := vFloat(c1.rval)
+		cmp = func(f *frame) bool { return f.data[i0].Float() != j1 }
+	default:
+		cmp = func(f *frame) bool { return f.data[i0].Float() != f.data[i1].Float() }
+	}
+
+	if n.fnext != nil {
+		fnext := getExec(n.fnext)
+		n.exec = func(f *frame) bltn {
+			if cmp(f) {
+				f.data[d].SetBool(true)
+				return tnext
+			}
+			f.data[d].SetBool(false)
+			return fnext
+		}
+		return true
+	}
+	n.exec = func(f *frame) bltn {
+		f.data[d].SetBool(cmp(f))
+		return tnext
+	}
+	return true
+}
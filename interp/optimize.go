@@ -0,0 +1,128 @@
+package interp
+
+import (
+	"go/constant"
+	"go/token"
+	"reflect"
+)
+
+// foldLogical computes at compilation the result of the logical expression
+// n, of kind landExpr or lorExpr, if it is decided by constants: its first
+// operand is false for landExpr or true for lorExpr, or both operands are
+// constants. The operands are then not executed.
+func foldLogical(n *node) {
+	c0, c1 := n.child[0], n.child[1]
+	b, ok := constBool(c0.rval)
+	if !ok {
+		return
+	}
+	if b != (n.kind == lorExpr) {
+		// The result is the one of the second operand.
+		if b, ok = constBool(c1.rval); !ok {
+			return
+		}
+	}
+	rval := reflect.ValueOf(b)
+	if t := n.typ.TypeOf(); t != nil && t.Kind() == reflect.Bool {
+		rval = rval.Convert(t)
+	}
+	n.rval = rval
+	n.gen = nop
+	n.findex = notInFrame
+	n.start = n
+}
+
+// switchTarget returns the node where to jump to execute the clause of
+// switch statement n, of kind switchStmt or switchIfStmt, if it is selected
+// by constants: the tag and all the case expressions are constants, or all
+// the case conditions are. The node n itself is returned if no clause is
+// selected. It returns nil if the clause is only known at runtime.
+func switchTarget(n *node) *node {
+	var tag reflect.Value
+	if n.kind == switchStmt {
+		if len(n.child) != 2 || !n.child[0].rval.IsValid() {
+			return nil
+		}
+		tag = n.child[0].rval
+	}
+
+	var selected, dflt *node
+	for _, c := range n.lastChild().child {
+		if len(c.child) < 2 {
+			dflt = c
+			continue
+		}
+		for _, e := range c.child[:len(c.child)-1] {
+			var match, ok bool
+			if n.kind == switchStmt {
+				match, ok = constEqual(tag, e.rval)
+			} else {
+				match, ok = constBool(e.rval)
+			}
+			if !ok {
+				return nil
+			}
+			if match && selected == nil {
+				selected = c
+			}
+		}
+	}
+	if selected == nil {
+		selected = dflt
+	}
+	if selected == nil || len(selected.child) == 0 {
+		return n
+	}
+	return selected.lastChild().start
+}
+
+// constBool returns the boolean value of the constant v, if any.
+func constBool(v reflect.Value) (b, ok bool) {
+	c := constValue(v)
+	if c == nil || c.Kind() != constant.Bool {
+		return false, false
+	}
+	return constant.BoolVal(c), true
+}
+
+// constEqual returns true if the constants a and b are equal. It returns
+// false for ok if they are not comparable constants.
+func constEqual(a, b reflect.Value) (equal, ok bool) {
+	ca, cb := constValue(a), constValue(b)
+	if ca == nil || cb == nil || ca.Kind() == constant.Unknown || cb.Kind() == constant.Unknown {
+		return false, false
+	}
+	if ka, kb := ca.Kind(), cb.Kind(); ka != kb && (!isNumericKind(ka) || !isNumericKind(kb)) {
+		return false, false
+	}
+	return constant.Compare(ca, token.EQL, cb), true
+}
+
+// constValue returns the constant value of v, a constant or a value of a
+// boolean, numeric or string type, or nil.
+func constValue(v reflect.Value) constant.Value {
+	if !v.IsValid() {
+		return nil
+	}
+	if c := vConstantValue(v); c != nil {
+		return c
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return constant.MakeBool(v.Bool())
+	case reflect.String:
+		return constant.MakeString(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return constant.MakeInt64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return constant.MakeUint64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return constant.MakeFloat64(v.Float())
+	}
+	return nil
+}
+
+// isNumericKind returns true if k is the kind of a numeric constant.
+func isNumericKind(k constant.Kind) bool {
+	return k == constant.Int || k == constant.Float || k == constant.Complex
+}
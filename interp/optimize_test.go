@@ -0,0 +1,150 @@
+package interp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/breadchris/yaegi/stdlib"
+)
+
+func TestOptimize(t *testing.T) {
+	src := `package main
+
+import "fmt"
+
+const (
+	debug = false
+	mode  = "fast"
+)
+
+func trace(s string) bool { fmt.Println("trace", s); return true }
+
+func main() {
+	if debug && trace("a") {
+		fmt.Println("debug")
+	}
+	if !debug || trace("b") {
+		fmt.Println("no debug")
+	}
+	switch mode {
+	case "slow":
+		fmt.Println("slow")
+	case "fast":
+		fmt.Println("fast")
+		fallthrough
+	default:
+		fmt.Println("default")
+	}
+	switch {
+	case len(mode) > 10:
+		fmt.Println("long")
+	case debug:
+		fmt.Println("debug")
+	}
+}
+`
+	var sizes []int
+	for _, optimize := range []bool{false, true} {
+		var out bytes.Buffer
+		i := New(Options{Stdout: &out, Optimize: optimize})
+		if err := i.Use(stdlib.Symbols); err != nil {
+			t.Fatal(err)
+		}
+		prog, err := i.Compile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := i.Execute(prog); err != nil {
+			t.Fatal(err)
+		}
+		if want := "no debug\nfast\ndefault\n"; out.String() != want {
+			t.Errorf("optimize %v: got %q, want %q", optimize, out.String(), want)
+		}
+
+		// Count the nodes reachable from the start of main.
+		seen := map[*node]bool{}
+		var visit func(n *node)
+		visit = func(n *node) {
+			if n == nil || seen[n] {
+				return
+			}
+			seen[n] = true
+			visit(n.tnext)
+			visit(n.fnext)
+		}
+		visit(i.scopes[mainID].sym[mainID].node.child[3].start)
+		sizes = append(sizes, len(seen))
+	}
+	if sizes[1] >= sizes[0] {
+		t.Errorf("got %d reachable nodes with optimizations, want less than %d", sizes[1], sizes[0])
+	}
+}
+
+func TestOptimizeCalls(t *testing.T) {
+	src := `package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+func itoa(i int) string { return strconv.Itoa(i) }
+
+func format(i int) string { return itoa(i) }
+
+func show(i int) string { return format(i) }
+
+func sum(n, acc int) int {
+	if n == 0 {
+		return acc
+	}
+	return sum(n-1, acc+n)
+}
+
+func count(n int) (c int) {
+	if n == 0 {
+		return
+	}
+	c = -1
+	return count(n - 1)
+}
+
+func depth(d int) int {
+	if d == 0 {
+		return 0
+	}
+	return 1 + depth(d-1)
+}
+
+func main() {
+	fmt.Println(show(42), sum(10000, 0), count(5))
+	fmt.Println(depth(1))
+}
+`
+	for _, optimize := range []bool{false, true} {
+		var out bytes.Buffer
+		i := New(Options{Stdout: &out, Optimize: optimize})
+		if err := i.Use(stdlib.Symbols); err != nil {
+			t.Fatal(err)
+		}
+		prog, err := i.Compile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prog.SetLimits(ExecLimits{MaxCallDepth: 2})
+		_, err = i.Execute(prog)
+		if !optimize {
+			// Without elision, the calls of show and sum exceed the call depth.
+			if err == nil {
+				t.Fatal("expected a call depth limit error")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "42 50005000 0\n1\n"; out.String() != want {
+			t.Errorf("got %q, want %q", out.String(), want)
+		}
+	}
+}
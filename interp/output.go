@@ -0,0 +1,108 @@
+package interp
+
+import (
+	"context"
+	"io"
+	"reflect"
+)
+
+// outputKey is the context key of the output scope set by WithOutput.
+type outputKey struct{}
+
+// outputScope holds the writers of the output of an evaluation.
+type outputScope struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// WithOutput returns a copy of ctx which redirects the standard output and
+// error of the evaluations started with it by EvalWithContext,
+// EvalPathWithContext or ExecuteWithContext to stdout and stderr, instead of
+// the streams set in Options. A nil writer keeps the stream of the
+// interpreter.
+//
+// It allows a host to attribute the output of concurrent evaluations in the
+// same interpreter to the right request. The scope covers the print
+// builtins, the print functions of the fmt and log packages and, if they are
+// not files, os.Stdout and os.Stderr, as evaluated by the code of the run. It
+// is inherited by the goroutines started by interpreted go statements. The
+// output of binary code using the streams otherwise, e.g. a logger created
+// by the run with log.New(os.Stderr, ...) and used later, goes to the
+// interpreter streams.
+func WithOutput(ctx context.Context, stdout, stderr io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey{}, &outputScope{stdout: stdout, stderr: stderr})
+}
+
+// writer returns the standard error of s if stderr is true, or else its
+// standard output, or nil if not set or if s is nil.
+func (s *outputScope) writer(stderr bool) io.Writer {
+	switch {
+	case s == nil:
+		return nil
+	case stderr:
+		return s.stderr
+	}
+	return s.stdout
+}
+
+// outputOf returns the output scope of the run of frame f, or nil.
+func outputOf(f *frame) *outputScope {
+	if r := f.run; r != nil {
+		return r.output
+	}
+	return nil
+}
+
+// scopedSym is a binary symbol of interpreted code replaced when evaluated
+// by a run: writing to the standard output or error, replaced in the runs
+// with an output scope, or depending on the state of the run.
+type scopedSym struct {
+	stderr bool
+	gen    func(w io.Writer) reflect.Value // returns the symbol writing to w
+	run    func(r *runState) reflect.Value // returns the symbol for run r, if not nil
+}
+
+// scopeSymbol registers the binary symbol v writing to the standard output,
+// or error if stderr is true, to be replaced by gen(w) in the runs whose
+// output scope sets the stream to w. See genValue.
+func (interp *Interpreter) scopeSymbol(v reflect.Value, stderr bool, gen func(w io.Writer) reflect.Value) {
+	interp.scopedSyms[v] = scopedSym{stderr: stderr, gen: gen}
+}
+
+// runSymbol registers the binary symbol v to be replaced by gen(r) when
+// evaluated by the code executed in run r. See genValue.
+func (interp *Interpreter) runSymbol(v reflect.Value, gen func(r *runState) reflect.Value) {
+	interp.scopedSyms[v] = scopedSym{run: gen}
+}
+
+// value returns the generator of the value of symbol v, honoring the output
+// scope or the state of the run of the frame.
+func (s scopedSym) value(v reflect.Value) func(*frame) reflect.Value {
+	if s.run != nil {
+		return func(f *frame) reflect.Value {
+			if r := f.run; r != nil {
+				return s.run(r)
+			}
+			return v
+		}
+	}
+	return func(f *frame) reflect.Value {
+		if w := outputOf(f).writer(s.stderr); w != nil {
+			return s.gen(w)
+		}
+		return v
+	}
+}
+
+// stdoutValue returns the generator of the standard output of interpreted
+// code, honoring the output scope of the run of the frame, as an io.Writer
+// value.
+func (interp *Interpreter) stdoutValue() func(*frame) reflect.Value {
+	return func(f *frame) reflect.Value {
+		w := outputOf(f).writer(false)
+		if w == nil {
+			w = interp.stdout
+		}
+		return reflect.ValueOf(&w).Elem()
+	}
+}
@@ -0,0 +1,152 @@
+package interp
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// A StagePanic is the error returned by Pipeline.Run when a stage panics or
+// is cancelled.
+type StagePanic struct {
+	Stage string      // name of the stage
+	Value interface{} // panic value, or nil if cancelled
+	Stack []byte      // stack trace of the interpreter panic, if any
+}
+
+func (p StagePanic) Error() string {
+	if p.Value == nil {
+		return p.Stage + ": call cancelled"
+	}
+	return fmt.Sprintf("%s: panic: %v", p.Stage, p.Value)
+}
+
+// StageStats are the execution metrics of a pipeline stage.
+type StageStats struct {
+	Name     string        // name of the stage
+	Calls    int64         // number of calls
+	Errors   int64         // number of calls which returned an error
+	Panics   int64         // number of calls which panicked or were cancelled
+	Duration time.Duration // cumulated duration of calls
+}
+
+// A Pipeline chains interpreted functions of type func(T) (T, error), each
+// stage receiving the result of the previous one. It lets users customize a
+// host processing with scripts. A Pipeline is safe for concurrent use if
+// its stages are.
+type Pipeline[T any] struct {
+	interp *Interpreter
+	stages []*pipelineStage[T]
+}
+
+type pipelineStage[T any] struct {
+	name   string
+	fn     func(T) (T, error)
+	calls  atomic.Int64
+	errors atomic.Int64
+	panics atomic.Int64
+	nanos  atomic.Int64
+}
+
+// NewPipeline returns a pipeline of the functions obtained by the evaluation
+// in interp of the given names, e.g. "main.Trim" or "filters.Normalize", in
+// order. An error is returned if a name does not evaluate to a function of
+// type func(T) (T, error).
+func NewPipeline[T any](interp *Interpreter, names ...string) (*Pipeline[T], error) {
+	want := reflect.TypeOf((func(T) (T, error))(nil))
+	p := &Pipeline[T]{interp: interp}
+	for _, name := range names {
+		v, err := interp.Eval(name)
+		if err != nil {
+			return nil, fmt.Errorf("stage %s: %w", name, err)
+		}
+		if !v.IsValid() || v.Type() != want {
+			return nil, fmt.Errorf("stage %s: invalid type %v, want %v", name, typeOfValue(v), want)
+		}
+		fn, ok := v.Interface().(func(T) (T, error))
+		if !ok || fn == nil {
+			return nil, fmt.Errorf("stage %s: not a function", name)
+		}
+		p.stages = append(p.stages, &pipelineStage[T]{name: name, fn: fn})
+	}
+	return p, nil
+}
+
+// Run calls the pipeline stages in order, starting with in, and returns the
+// result of the last stage. At the first stage which fails, Run stops and
+// returns the value received by the stage, and the stage error, or a
+// StagePanic if the stage panicked. A panic does not affect the other stages
+// nor the interpreter.
+func (p *Pipeline[T]) Run(in T) (T, error) {
+	for _, s := range p.stages {
+		out, err := s.call(p.interp, in)
+		if err != nil {
+			return in, err
+		}
+		in = out
+	}
+	return in, nil
+}
+
+func (s *pipelineStage[T]) call(interp *Interpreter, in T) (out T, err error) {
+	// Resynchronize the global frame, in case a previous run was cancelled.
+	id := interp.runid()
+	interp.frame.Load().setrunid(id)
+
+	start := time.Now()
+	s.calls.Add(1)
+	defer func() {
+		s.nanos.Add(int64(time.Since(start)))
+		r := interp.restorePanic(recover())
+		if r == nil && interp.runid() == id {
+			if err != nil {
+				s.errors.Add(1)
+			}
+			return
+		}
+		s.panics.Add(1)
+		sp := StagePanic{Stage: s.name, Value: interp.exportPanic(r)}
+		if p := interp.GetOldestPanicForErr(r); r != nil && p != nil {
+			sp.Stack = p.Stack
+		}
+		out, err = in, sp
+	}()
+	return s.fn(in)
+}
+
+// Stats returns the execution metrics of the pipeline stages, in order.
+func (p *Pipeline[T]) Stats() []StageStats {
+	stats := make([]StageStats, len(p.stages))
+	for i, s := range p.stages {
+		stats[i] = StageStats{
+			Name:     s.name,
+			Calls:    s.calls.Load(),
+			Errors:   s.errors.Load(),
+			Panics:   s.panics.Load(),
+			Duration: time.Duration(s.nanos.Load()),
+		}
+	}
+	return stats
+}
+
+// panicValue returns the value r of a panic of interpreted code as a plain Go
+// value.
+func panicValue(r interface{}) interface{} {
+	v, ok := r.(reflect.Value)
+	if !ok || !v.IsValid() || !v.CanInterface() {
+		return r
+	}
+	if vi, ok := v.Interface().(valueInterface); ok {
+		return panicValue(vi.value)
+	}
+	return v.Interface()
+}
+
+// typeOfValue returns the type of v, or nil if v is invalid.
+func typeOfValue(v reflect.Value) reflect.Type {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Type()
+}
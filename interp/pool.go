@@ -0,0 +1,181 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrPoolClosed is returned by Pool.Acquire once the pool has been closed.
+var ErrPoolClosed = errors.New("interpreter pool closed")
+
+// PoolOptions are the options of a Pool.
+type PoolOptions struct {
+	// Size is the number of interpreters kept ready in the pool.
+	// It defaults to 1.
+	Size int
+
+	// Options are the options used to create each interpreter.
+	Options Options
+
+	// Use lists the binary symbols loaded in each interpreter with Use.
+	Use []Exports
+
+	// ImportUsed, if true, calls ImportUsed on each interpreter after Use.
+	ImportUsed bool
+
+	// Prelude is source code evaluated in each interpreter before it is
+	// made available, e.g. to import packages or declare helpers.
+	Prelude string
+}
+
+// A Pool maintains a set of interpreters initialized in the same way, ready
+// to be used. It is safe for concurrent use.
+//
+// An interpreter obtained by Acquire is for the exclusive use of the caller,
+// until it is returned by Release. Released interpreters are discarded and
+// replaced in the background by freshly initialized ones, so no state, such
+// as global variables or declarations, leaks from a use to the next.
+type Pool struct {
+	opt   PoolOptions
+	ready chan poolEntry
+
+	mutex    sync.Mutex
+	closed   bool
+	acquired map[*Interpreter]bool // interpreters given by Acquire, not released yet
+	done     chan struct{}         // closed when the pool is closed
+	wg       sync.WaitGroup        // pending refills
+}
+
+// poolEntry is an interpreter ready in a pool, or the error of its
+// initialization.
+type poolEntry struct {
+	interp *Interpreter
+	err    error
+}
+
+// NewPool returns a new pool of interpreters, after having initialized them
+// with the given options. An error is returned if an interpreter could not
+// be initialized.
+func NewPool(opt PoolOptions) (*Pool, error) {
+	if opt.Size <= 0 {
+		opt.Size = 1
+	}
+	p := &Pool{
+		opt:      opt,
+		ready:    make(chan poolEntry, opt.Size),
+		acquired: map[*Interpreter]bool{},
+		done:     make(chan struct{}),
+	}
+	for k := 0; k < opt.Size; k++ {
+		i, err := p.newInterpreter()
+		if err != nil {
+			return nil, err
+		}
+		p.ready <- poolEntry{interp: i}
+	}
+	return p, nil
+}
+
+// newInterpreter returns a new interpreter, initialized according to the
+// pool options.
+func (p *Pool) newInterpreter() (*Interpreter, error) {
+	i := New(p.opt.Options)
+	for _, e := range p.opt.Use {
+		if err := i.Use(e); err != nil {
+			return nil, err
+		}
+	}
+	if p.opt.ImportUsed {
+		i.ImportUsed()
+	}
+	if p.opt.Prelude != "" {
+		if _, err := i.Eval(p.opt.Prelude); err != nil {
+			return nil, err
+		}
+	}
+	return i, nil
+}
+
+// Acquire returns an interpreter from the pool, waiting for one to be
+// available if necessary, or until ctx is done.
+//
+// If the initialization of a replacement interpreter failed, e.g. because of
+// a missing source file, its error is returned, and a new replacement is
+// created in the background, so that the pool keeps its size.
+func (p *Pool) Acquire(ctx context.Context) (*Interpreter, error) {
+	select {
+	case <-p.done:
+		return nil, ErrPoolClosed
+	default:
+	}
+	select {
+	case e, ok := <-p.ready:
+		if !ok {
+			return nil, ErrPoolClosed
+		}
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		if e.err != nil {
+			p.refill()
+			return nil, fmt.Errorf("interpreter pool: %w", e.err)
+		}
+		p.acquired[e.interp] = true
+		return e.interp, nil
+	case <-p.done:
+		return nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Release gives back to the pool an interpreter obtained by Acquire. The
+// interpreter must not be used after Release. A new interpreter is created
+// to replace it. An error is returned if i was not obtained by Acquire from
+// p, or was already released.
+func (p *Pool) Release(i *Interpreter) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.acquired[i] {
+		return errors.New("interpreter pool: release of an interpreter not acquired from the pool")
+	}
+	delete(p.acquired, i)
+	p.refill()
+	return nil
+}
+
+// refill creates in the background a new interpreter, or the error of its
+// initialization, for the pool, unless it is closed. It must be called with
+// p.mutex locked.
+func (p *Pool) refill() {
+	if p.closed {
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		// Never reuse a released interpreter, as its state is unknown.
+		ni, err := p.newInterpreter()
+		select {
+		case p.ready <- poolEntry{interp: ni, err: err}:
+		case <-p.done:
+		}
+	}()
+}
+
+// Close releases the resources of the pool. Interpreters already acquired
+// remain usable, but Acquire fails with ErrPoolClosed.
+func (p *Pool) Close() {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	p.mutex.Unlock()
+
+	p.wg.Wait()
+	close(p.ready)
+}
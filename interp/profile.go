@@ -0,0 +1,286 @@
+package interp
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// profileRate is the sampling frequency of profiles, in Hz.
+const profileRate = 100
+
+// profiler samples the interpreted functions being executed.
+type profiler struct {
+	w     io.Writer
+	start time.Time
+	stop  chan struct{} // closed to stop sampling
+	done  chan struct{} // closed when sampling is stopped
+
+	roots   sync.Map               // outermost calls in progress, as keys
+	samples map[string]*profSample // indexed by stack key, owned by the sampling goroutine
+}
+
+// profCall is an interpreted function call in progress. It is recorded in
+// the frame of the call, to be the parent of the calls made from it.
+type profCall struct {
+	p      *profiler
+	def    *node                    // function definition
+	parent *profCall                // caller, or nil
+	callee atomic.Pointer[profCall] // call in progress made from this one, or nil
+}
+
+// profSample is the number of samples of an interpreted call stack.
+type profSample struct {
+	stack []*node // function definitions, innermost first
+	count int64
+}
+
+// StartProfile enables the profiling of interpreted code, writing the
+// profile to w when StopProfile is called. The profile is in the gzipped
+// protocol buffer format of pprof, readable by "go tool pprof", and its
+// frames are interpreted functions, named as in stack traces.
+//
+// Each goroutine running interpreted code is sampled at 100 Hz, including
+// when it is blocked, e.g. on a channel operation or in a binary function
+// call, which is then accounted to the calling interpreted function. The
+// samples thus measure wall-clock time, not CPU time, and are labelled so
+// in the profile. The calls of interpreted functions by binary code, e.g.
+// callbacks, start new stacks. Calls are tracked in their frames, which
+// slows down interpreted function calls during profiling. An error is
+// returned if profiling is already enabled.
+func (interp *Interpreter) StartProfile(w io.Writer) error {
+	p := &profiler{
+		w:       w,
+		start:   time.Now(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		samples: map[string]*profSample{},
+	}
+	if !interp.profiler.CompareAndSwap(nil, p) {
+		return errors.New("profiling already enabled")
+	}
+	go p.run()
+	return nil
+}
+
+// StopProfile stops the current profile, if any, and writes it to the
+// writer given to StartProfile.
+func (interp *Interpreter) StopProfile() error {
+	p := interp.profiler.Swap(nil)
+	if p == nil {
+		return nil
+	}
+	close(p.stop)
+	<-p.done
+	return p.write(interp)
+}
+
+func (p *profiler) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(time.Second / profileRate)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.sample()
+		}
+	}
+}
+
+// sample records the innermost call of each goroutine.
+func (p *profiler) sample() {
+	p.roots.Range(func(k, _ interface{}) bool {
+		c := k.(*profCall)
+		for next := c.callee.Load(); next != nil; next = c.callee.Load() {
+			c = next
+		}
+		var stack []*node
+		var key strings.Builder
+		for ; c != nil; c = c.parent {
+			stack = append(stack, c.def)
+			fmt.Fprintf(&key, "%p;", c.def)
+		}
+		s := p.samples[key.String()]
+		if s == nil {
+			s = &profSample{stack: stack}
+			p.samples[key.String()] = s
+		}
+		s.count++
+		return true
+	})
+}
+
+// enter records the start of the execution of function def in frame f, and
+// returns the call, or nil if def is not a function, e.g. for package level
+// code. The caller is the call recorded in f, if any, by the call expression.
+func (p *profiler) enter(f *frame, def *node) *profCall {
+	if def.kind != funcDecl && def.kind != funcLit {
+		return nil
+	}
+	c := &profCall{p: p, def: def, parent: f.prof}
+	if c.parent != nil && c.parent.p != p {
+		// The caller started before this profile.
+		c.parent = nil
+	}
+	if c.parent != nil {
+		c.parent.callee.Store(c)
+	} else {
+		p.roots.Store(c, nil)
+	}
+	f.prof = c
+	return c
+}
+
+// exit records the end of call c in frame f.
+func (p *profiler) exit(f *frame, c *profCall) {
+	if c == nil {
+		return
+	}
+	f.prof = c.parent
+	if c.parent != nil {
+		c.parent.callee.CompareAndSwap(c, nil)
+	} else {
+		p.roots.Delete(c)
+	}
+}
+
+// write writes the profile in pprof format.
+func (p *profiler) write(interp *Interpreter) error {
+	period := int64(time.Second / profileRate)
+	strs := map[string]int64{"": 0}
+	strTable := []string{""}
+	str := func(s string) int64 {
+		i, ok := strs[s]
+		if !ok {
+			i = int64(len(strTable))
+			strs[s] = i
+			strTable = append(strTable, s)
+		}
+		return i
+	}
+	valueType := func(typ, unit string) func(*protoBuf) {
+		t, u := str(typ), str(unit)
+		return func(b *protoBuf) {
+			b.int64(1, t)
+			b.int64(2, u)
+		}
+	}
+
+	var b protoBuf
+	b.message(1, valueType("samples", "count"))
+	b.message(1, valueType("wall", "nanoseconds"))
+
+	ids := map[*node]uint64{}
+	var funcs []*node
+	for _, s := range p.samples {
+		locs := make([]uint64, len(s.stack))
+		for i, def := range s.stack {
+			id, ok := ids[def]
+			if !ok {
+				id = uint64(len(funcs) + 1)
+				ids[def] = id
+				funcs = append(funcs, def)
+			}
+			locs[i] = id
+		}
+		count := s.count
+		b.message(2, func(b *protoBuf) {
+			b.packedUint64(1, locs)
+			b.packedUint64(2, []uint64{uint64(count), uint64(count * period)})
+		})
+	}
+
+	// Each function has a single location, with the same id.
+	for i, def := range funcs {
+		id := uint64(i + 1)
+		pos := interp.fset.Position(def.pos)
+		name := funcName(def)
+		if name == "" {
+			name = "<unknown>"
+		}
+		b.message(4, func(b *protoBuf) {
+			b.uint64(1, id)
+			b.message(4, func(b *protoBuf) {
+				b.uint64(1, id)
+				b.int64(2, int64(pos.Line))
+			})
+		})
+		n, file := str(name), str(pos.Filename)
+		b.message(5, func(b *protoBuf) {
+			b.uint64(1, id)
+			b.int64(2, n)
+			b.int64(3, n)
+			b.int64(4, file)
+			b.int64(5, int64(pos.Line))
+		})
+	}
+
+	periodType := valueType("wall", "nanoseconds")
+	for _, s := range strTable {
+		b.string(6, s)
+	}
+	b.int64(9, p.start.UnixNano())
+	b.int64(10, int64(time.Since(p.start)))
+	b.message(11, periodType)
+	b.int64(12, period)
+
+	zw := gzip.NewWriter(p.w)
+	if _, err := zw.Write(b.buf); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// protoBuf is a minimal protocol buffer encoder, sufficient for the pprof
+// profile format.
+type protoBuf struct {
+	buf []byte
+}
+
+func (b *protoBuf) varint(x uint64) {
+	for x >= 0x80 {
+		b.buf = append(b.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	b.buf = append(b.buf, byte(x))
+}
+
+func (b *protoBuf) key(tag, wireType int) { b.varint(uint64(tag)<<3 | uint64(wireType)) }
+
+func (b *protoBuf) uint64(tag int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(tag, 0)
+	b.varint(x)
+}
+
+func (b *protoBuf) int64(tag int, x int64) { b.uint64(tag, uint64(x)) }
+
+func (b *protoBuf) string(tag int, s string) {
+	b.key(tag, 2)
+	b.varint(uint64(len(s)))
+	b.buf = append(b.buf, s...)
+}
+
+func (b *protoBuf) packedUint64(tag int, xs []uint64) {
+	var sub protoBuf
+	for _, x := range xs {
+		sub.varint(x)
+	}
+	b.string(tag, string(sub.buf))
+}
+
+func (b *protoBuf) message(tag int, f func(*protoBuf)) {
+	var sub protoBuf
+	f(&sub)
+	b.string(tag, string(sub.buf))
+}
@@ -0,0 +1,6 @@
+//go:build yaegi_small
+
+package interp_test
+
+// smallProfile is true in the small build profile.
+const smallProfile = true
@@ -2,6 +2,7 @@ package interp
 
 import (
 	"context"
+	"fmt"
 	"go/ast"
 	"go/token"
 	"io/fs"
@@ -11,9 +12,11 @@ import (
 
 // A Program is Go code that has been parsed and compiled.
 type Program struct {
-	pkgName string
-	root    *node
-	init    []*node
+	pkgName   string
+	pkgID     string // id of package scope, differs from pkgName in a namespace
+	root      *node
+	init      []*node
+	resources *resources
 }
 
 // PackageName returns name used in a package clause.
@@ -29,12 +32,16 @@ func (interp *Interpreter) FileSet() *token.FileSet {
 
 // Compile parses and compiles a Go code represented as a string.
 func (interp *Interpreter) Compile(src string) (*Program, error) {
+	interp.compile.Lock()
+	defer interp.compile.Unlock()
 	return interp.compileSrc(src, "", true)
 }
 
 // CompilePath parses and compiles a Go code located at the given path.
 func (interp *Interpreter) CompilePath(path string) (*Program, error) {
 	path = filepath.ToSlash(path) // Ensure path is in Unix format. Since we work with fs.FS, we need to use Unix path.
+	interp.compile.Lock()
+	defer interp.compile.Unlock()
 	if !isFile(interp.filesystem, path) {
 		_, err := interp.importSrc(mainID, path, NoTest)
 		return nil, err
@@ -47,6 +54,7 @@ func (interp *Interpreter) CompilePath(path string) (*Program, error) {
 	return interp.compileSrc(string(b), path, false)
 }
 
+// compileSrc compiles src. It must be called with interp.compile locked.
 func (interp *Interpreter) compileSrc(src, name string, inc bool) (*Program, error) {
 	if name != "" {
 		interp.name = name
@@ -61,7 +69,7 @@ func (interp *Interpreter) compileSrc(src, name string, inc bool) (*Program, err
 		return nil, err
 	}
 
-	return interp.CompileAST(n)
+	return interp.compileAST(n, "")
 }
 
 // CompileAST builds a Program for the given Go code AST. Files and block
@@ -71,11 +79,26 @@ func (interp *Interpreter) compileSrc(src, name string, inc bool) (*Program, err
 // WARNING: The node must have been parsed using interp.FileSet(). Results are
 // unpredictable otherwise.
 func (interp *Interpreter) CompileAST(n ast.Node) (*Program, error) {
+	interp.compile.Lock()
+	defer interp.compile.Unlock()
+	return interp.compileAST(n, "")
+}
+
+// compileAST compiles n in the package scope identified by pkgID, or by the
+// package name of n if pkgID is empty.
+func (interp *Interpreter) compileAST(n ast.Node, pkgID string) (*Program, error) {
+	interp.evals++
+
 	// Convert AST.
 	pkgName, root, err := interp.ast(n)
 	if err != nil || root == nil {
 		return nil, err
 	}
+	if pkgID == "" {
+		pkgID = pkgName
+	} else if pkgName != mainID {
+		return nil, fmt.Errorf("package %s can not be compiled in namespace %s", pkgName, namespaceName(pkgID))
+	}
 
 	if interp.astDot {
 		dotCmd := interp.dotCmd
@@ -88,13 +111,17 @@ func (interp *Interpreter) CompileAST(n ast.Node) (*Program, error) {
 		}
 	}
 
+	if err = interp.checkFrozen(root, pkgID); err != nil {
+		return nil, err
+	}
+
 	// Perform global types analysis.
-	if err = interp.gtaRetry([]*node{root}, pkgName, pkgName); err != nil {
+	if err = interp.gtaRetry([]*node{root}, pkgID, pkgName); err != nil {
 		return nil, err
 	}
 
 	// Annotate AST with CFG informations.
-	initNodes, err := interp.cfg(root, nil, pkgName, pkgName)
+	initNodes, err := interp.cfg(root, nil, pkgID, pkgName)
 	if err != nil {
 		if interp.cfgDot {
 			dotCmd := interp.dotCmd
@@ -111,8 +138,8 @@ func (interp *Interpreter) CompileAST(n ast.Node) (*Program, error) {
 		setExec(root.start)
 	}
 	interp.mutex.Lock()
-	gs := interp.scopes[pkgName]
-	if interp.universe.sym[pkgName] == nil {
+	gs := interp.scopes[pkgID]
+	if pkgID == pkgName && interp.universe.sym[pkgName] == nil {
 		// Make the package visible under a path identical to its name.
 		interp.srcPkg[pkgName] = gs.sym
 		interp.universe.sym[pkgName] = &symbol{kind: pkgSym, typ: &itype{cat: srcPkgT, path: pkgName}}
@@ -133,11 +160,30 @@ func (interp *Interpreter) CompileAST(n ast.Node) (*Program, error) {
 		root.cfgDot(dotWriter(dotCmd))
 	}
 
-	return &Program{pkgName, root, initNodes}, nil
+	interp.freezeCompiled(pkgID)
+
+	return &Program{pkgName: pkgName, pkgID: pkgID, root: root, init: initNodes}, nil
 }
 
 // Execute executes compiled Go code.
 func (interp *Interpreter) Execute(p *Program) (res reflect.Value, err error) {
+	return interp.execute(p, nil)
+}
+
+// execute executes program p in run r, or outside of any run if r is nil.
+func (interp *Interpreter) execute(p *Program, r *runState) (reflect.Value, error) {
+	return interp.executeGen(p, r, interp.genProgram)
+}
+
+// executeLocked executes program p as Execute, with interp.compile already
+// locked by the caller, e.g. to apply a reload atomically.
+func (interp *Interpreter) executeLocked(p *Program) (reflect.Value, error) {
+	return interp.executeGen(p, nil, interp.genProgramLocked)
+}
+
+// executeGen executes program p in run r, after the generation of its code
+// by gen.
+func (interp *Interpreter) executeGen(p *Program, r *runState, gen func(*Program) (*frame, *node, error)) (res reflect.Value, err error) {
 	defer func() {
 		r := recover()
 		if r != nil {
@@ -145,63 +191,87 @@ func (interp *Interpreter) Execute(p *Program) (res reflect.Value, err error) {
 			err = interp.GetOldestPanicForErr(r)
 		}
 	}()
-	interp.id = 0 // restart fresh!
+	if r == nil && interp.stats {
+		r = &runState{stats: newRunStats()}
+	}
+	defer r.usage().stop()
 
-	// Generate node exec closures.
-	if err = genRun(p.root); err != nil {
+	gf, vars, err := gen(p)
+	if err != nil {
 		return res, err
 	}
-
-	// Init interpreter execution memory frame.
-	interp.frame.setrunid(interp.runid())
-	interp.frame.mutex.Lock()
-	interp.resizeFrame()
-	interp.frame.mutex.Unlock()
+	gf = gf.forRun(r)
 
 	// Execute node closures.
-	interp.run(p.root, nil)
+	interp.runFrame(p.root, gf)
 
-	// Wire and execute global vars.
-	n, err := genGlobalVars([]*node{p.root}, interp.scopes[p.pkgName])
-	if err != nil {
-		return res, err
-	}
-	interp.run(n, nil)
+	// Execute global vars.
+	interp.runFrame(vars, gf)
 
 	for _, n := range p.init {
-		interp.run(n, interp.frame)
+		interp.run(n, gf)
 	}
 	v := genValue(p.root)
-	res = v(interp.frame)
+	res = v(gf)
 
 	// If result is an interpreter node, wrap it in a runtime callable function.
 	if res.IsValid() {
 		if n, ok := res.Interface().(*node); ok {
-			res = genFunctionWrapper(n)(interp.frame)
+			e := p.root
+			for (e.kind == blockStmt || e.kind == exprStmt) && len(e.child) > 0 {
+				e = e.lastChild()
+			}
+			if isMethodExpr(e) {
+				n = e // the receiver is the first argument
+			}
+			res = genFunctionWrapper(n)(gf)
 		}
 	}
 
 	return res, err
 }
 
-// ExecuteWithContext executes compiled Go code.
-func (interp *Interpreter) ExecuteWithContext(ctx context.Context, p *Program) (res reflect.Value, err error) {
-	interp.mutex.Lock()
-	interp.done = make(chan struct{})
-	interp.cancelChan = !interp.opt.fastChan
-	interp.mutex.Unlock()
+// genProgram generates the node exec closures of program p and wires its
+// global vars. It returns the global frame to execute p and the entry point of
+// global vars initialization.
+//
+// Code generation is serialized, but the execution of programs is not:
+// concurrent executions share the global vars, each program has its own
+// temporaries in the global frame.
+func (interp *Interpreter) genProgram(p *Program) (*frame, *node, error) {
+	interp.compile.Lock()
+	defer interp.compile.Unlock()
+	return interp.genProgramLocked(p)
+}
 
-	done := make(chan struct{})
-	go func() {
-		defer close(done)
-		res, err = interp.Execute(p)
-	}()
+// genProgramLocked is genProgram, called with interp.compile locked.
+func (interp *Interpreter) genProgramLocked(p *Program) (*frame, *node, error) {
+	if err := genRun(p.root); err != nil {
+		return nil, nil, err
+	}
 
-	select {
-	case <-ctx.Done():
-		interp.stop()
-		return reflect.Value{}, ctx.Err()
-	case <-done:
+	// Init interpreter execution memory frame.
+	interp.resizeFrame()
+	gf := interp.frame.Load()
+	gf.setrunid(interp.runid())
+
+	// Initialize the lazy packages used by the program.
+	if err := interp.initUsed(); err != nil {
+		return nil, nil, err
 	}
-	return res, err
+
+	// Wire global vars.
+	n, err := genGlobalVars([]*node{p.root}, interp.scopes[p.pkgID])
+	if err != nil {
+		return nil, nil, err
+	}
+	return gf, n, nil
+}
+
+// ExecuteWithContext executes compiled Go code.
+func (interp *Interpreter) ExecuteWithContext(ctx context.Context, p *Program) (reflect.Value, error) {
+	r := interp.newRun(ctx)
+	return interp.runWithContext(ctx, r, func() (reflect.Value, error) {
+		return interp.execute(p, r)
+	})
 }
@@ -0,0 +1,168 @@
+package interp
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// A Project is a tree of source packages loaded from a common root directory
+// by EvalProject.
+type Project struct {
+	// Root is the directory from which packages were loaded.
+	Root string
+
+	// Packages lists the packages found under Root, in lexical order of
+	// their directory.
+	Packages []*ProjectPackage
+
+	interp *Interpreter
+}
+
+// A ProjectPackage is a source package which is part of a Project.
+type ProjectPackage struct {
+	Dir        string // directory of package sources
+	ImportPath string // path used to import the package in the interpreter
+	Name       string // package name, as declared in the package clause
+	Err        error  // error encountered while loading the package, or nil
+}
+
+// Errors returns the errors of all the packages of the project which failed
+// to load.
+func (p *Project) Errors() []error {
+	var errs []error
+	for _, pkg := range p.Packages {
+		if pkg.Err != nil {
+			errs = append(errs, pkg.Err)
+		}
+	}
+	return errs
+}
+
+// Package returns the project package with the given import path or
+// directory, or nil if not found.
+func (p *Project) Package(name string) *ProjectPackage {
+	for _, pkg := range p.Packages {
+		if pkg.ImportPath == name || pkg.Dir == name {
+			return pkg
+		}
+	}
+	return nil
+}
+
+// Symbols returns the exported symbols of the project package with the given
+// import path or directory, or nil if the package is not part of the project
+// or failed to load.
+func (p *Project) Symbols(name string) map[string]reflect.Value {
+	pkg := p.Package(name)
+	if pkg == nil || pkg.Err != nil {
+		return nil
+	}
+	return p.interp.Symbols(pkg.ImportPath)[pkg.ImportPath]
+}
+
+// EvalProject loads and compiles all the source packages located under the
+// root directory. Package init functions are run, but the main function of
+// main packages is not executed.
+//
+// Directories named vendor or testdata, or starting with "." or "_", are
+// skipped, as are source files which do not match the build constraints of
+// the interpreter.
+//
+// Packages are imported using their path relative to GOPATH/src if root is
+// located in GOPATH, or a path relative to root otherwise.
+// All packages are processed even if some fail to compile. The returned error
+// aggregates the errors of all failing packages.
+func (interp *Interpreter) EvalProject(root string) (*Project, error) {
+	root = path.Clean(filepath.ToSlash(root))
+	dirs, err := interp.projectDirs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	interp.compile.Lock()
+	defer interp.compile.Unlock()
+
+	prefix, inGoPath := interp.goPathImport(root)
+	if !inGoPath {
+		// Relative imports are resolved from the location of the interpreter input.
+		name := interp.name
+		interp.name = path.Join(root, DefaultSourceName)
+		defer func() { interp.name = name }()
+	}
+
+	p := &Project{Root: root, interp: interp}
+	for _, dir := range dirs {
+		rel := strings.TrimPrefix(strings.TrimPrefix(dir, root), "/")
+		pkg := &ProjectPackage{Dir: dir}
+		if inGoPath {
+			pkg.ImportPath = path.Join(prefix, rel)
+		} else {
+			pkg.ImportPath = "./" + rel
+		}
+		pkg.Name, pkg.Err = interp.loadSrc(mainID, pkg.ImportPath, NoTest, false)
+		p.Packages = append(p.Packages, pkg)
+	}
+	return p, errors.Join(p.Errors()...)
+}
+
+// goPathImport returns the import path of dir relative to GOPATH/src, and
+// true if dir is located in GOPATH.
+func (interp *Interpreter) goPathImport(dir string) (string, bool) {
+	if interp.context.GOPATH == "" {
+		return "", false
+	}
+	if _, isRealFS := interp.opt.filesystem.(*realFS); isRealFS {
+		if abs, err := filepath.Abs(filepath.FromSlash(dir)); err == nil {
+			dir = filepath.ToSlash(abs)
+		}
+	}
+	src := path.Join(filepath.ToSlash(interp.context.GOPATH), "src") + "/"
+	if !strings.HasPrefix(dir+"/", src) || dir+"/" == src {
+		return "", false
+	}
+	return strings.TrimPrefix(dir, src), true
+}
+
+// projectDirs returns the list of directories under root which contain at
+// least one Go source file matching the build constraints.
+func (interp *Interpreter) projectDirs(root string) ([]string, error) {
+	var dirs []string
+	seen := map[string]bool{}
+	ctx := interp.context
+	ctx.BuildTags = append([]string{}, ctx.BuildTags...)
+	err := fs.WalkDir(interp.opt.filesystem, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if p != root && (name == vendor || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		dir := path.Dir(p)
+		if seen[dir] {
+			return nil
+		}
+		if skipFile(&ctx, name, NoTest) {
+			return nil
+		}
+		buf, err := fs.ReadFile(interp.opt.filesystem, p)
+		if err != nil {
+			return err
+		}
+		if ok, _ := interp.buildOk(&ctx, p, string(buf)); ok {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+		return nil
+	})
+	sort.Strings(dirs)
+	return dirs, err
+}
@@ -0,0 +1,109 @@
+package interp
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+)
+
+// A Provider returns the symbols of a binary package, as the values of
+// Exports, when the package is first imported by interpreted code.
+type Provider func() map[string]reflect.Value
+
+// providerKey is the key of the symbol holding the provider of a package
+// registered by Lazy. It is not a valid identifier, so it can not collide
+// with an actual symbol.
+const providerKey = "#provider"
+
+// Lazy returns the symbols of a binary package provided by p, to be passed
+// to Use in place of the actual symbols. The provider is called only when
+// the import path of the package is first referenced by interpreted code,
+// so the cost of creating the values of symbols is not paid for packages
+// which are not used, e.g.:
+//
+//	i.Use(interp.Exports{
+//		"example.com/big/big": interp.Lazy(func() map[string]reflect.Value {
+//			return map[string]reflect.Value{"New": reflect.ValueOf(big.New)}
+//		}),
+//	})
+//
+// The packages of the standard library, whose symbols are adapted by the
+// interpreter when loaded by Use, must not be provided lazily.
+func Lazy(p Provider) map[string]reflect.Value {
+	return map[string]reflect.Value{providerKey: reflect.ValueOf(p)}
+}
+
+// provided is a binary package registered by Use with a provider.
+type provided struct {
+	name     string // package name
+	provider Provider
+}
+
+// addProvider registers the provider of the symbols v of package k, as
+// returned by Lazy, and returns true, or returns false if v holds symbols.
+func (interp *Interpreter) addProvider(k string, v map[string]reflect.Value) bool {
+	pv, ok := v[providerKey]
+	if !ok || len(v) > 1 {
+		return false
+	}
+	p, ok := pv.Interface().(Provider)
+	if !ok {
+		return false
+	}
+	importPath := path.Dir(k)
+	if interp.providers == nil {
+		interp.providers = map[string]provided{}
+	}
+	interp.providers[importPath] = provided{name: path.Base(k), provider: p}
+	return true
+}
+
+// loadProvided loads the symbols of the binary package of importPath, if it
+// is registered with a provider not called yet. It must be called with
+// interp.compile locked.
+func (interp *Interpreter) loadProvided(importPath string) error {
+	p, ok := interp.providers[importPath]
+	if !ok {
+		return nil
+	}
+	delete(interp.providers, importPath)
+	defer interp.hooks.clearSymbols() // binary symbols are changed
+
+	k := importPath + "/" + p.name
+	syms := p.provider()
+	interp.addBinPkg(k, syms)
+	return interp.compileGenerics(k, syms, func(src string) error {
+		_, err := interp.compileSrc(src, "", true)
+		return err
+	})
+}
+
+// addBinPkg adds the symbols v of the binary package k to the interpreter.
+func (interp *Interpreter) addBinPkg(k string, v map[string]reflect.Value) {
+	importPath := path.Dir(k)
+	if interp.binPkg[importPath] == nil {
+		interp.binPkg[importPath] = make(map[string]reflect.Value)
+		interp.pkgNames[importPath] = path.Base(k)
+	}
+	for s, sym := range v {
+		interp.binPkg[importPath][s] = sym
+	}
+	if k == selfPath {
+		interp.binPkg[importPath]["Self"] = reflect.ValueOf(interp)
+	}
+}
+
+// compileGenerics compiles the source of the generic symbols v of the binary
+// package k, with compile.
+func (interp *Interpreter) compileGenerics(k string, v map[string]reflect.Value, compile func(string) error) error {
+	packageName := path.Base(k)
+	for _, sym := range v {
+		if src, ok := genericSource(sym); ok {
+			str := fmt.Sprintf("package %s\nimport . %q\n%s", packageName, path.Dir(k), src)
+			if err := compile(str); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
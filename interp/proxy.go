@@ -0,0 +1,393 @@
+package interp
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/breadchris/yaegi/internal/unsafe2"
+)
+
+// proxies stores the plain Go types mirroring interpreted struct types,
+// returned by ExportType.
+type proxies struct {
+	mutex sync.Mutex
+	types map[*itype]reflect.Type // proxy types, by interpreted type
+	src   map[reflect.Type]*itype // interpreted struct types, by runtime type
+	dst   map[reflect.Type]*itype // interpreted struct types, by proxy type
+	fixes []proxyFix              // proxy fields to complete, in recursive types
+	depth int                     // number of proxy struct types under construction
+}
+
+// seenKey identifies a pointer already converted, to preserve cycles and
+// sharing between pointers.
+type seenKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// proxyFix is a field of a proxy struct type which refers to a proxy type
+// under construction.
+type proxyFix struct {
+	rtype reflect.Type
+	index int
+	typ   *itype
+}
+
+// ExportType returns a plain Go struct type mirroring the interpreted struct
+// type name, e.g. "main.User", so that host packages relying on reflection,
+// such as encoding/json, database/sql scanners or text/template, see its
+// fields. The values of interpreted types are converted to and from the
+// returned type by ExportValue and ImportValue.
+//
+// The returned type has the exported fields of the interpreted type, with
+// their tags. Embedded structs remain embedded, interpreted structs in
+// fields are exported in turn, and interpreted interfaces are mapped to
+// interface{}. The methods of the interpreted type are not part of the
+// returned type: use Implements to obtain host interface values.
+func (interp *Interpreter) ExportType(name string) (reflect.Type, error) {
+	interp.compile.Lock()
+	prog, err := interp.compileSrc("(*"+name+")(nil)", "", true)
+	interp.compile.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	typ := prog.root.typ
+	if typ == nil || typ.cat != ptrT || !isStruct(typ.val) || typ.val.cat == valueT {
+		return nil, fmt.Errorf("export %s: not an interpreted struct type", name)
+	}
+
+	p := &interp.proxies
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.typeOf(typ.val), nil
+}
+
+// ExportValue converts v, a value or a pointer to a value of an interpreted
+// struct type, to the corresponding value of the type returned by
+// ExportType, which must have been called first.
+func (interp *Interpreter) ExportValue(v reflect.Value) (reflect.Value, error) {
+	p := &interp.proxies
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	v = concreteValue(v)
+	if !v.IsValid() {
+		return v, fmt.Errorf("export: invalid value")
+	}
+	rt := v.Type()
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	t, ok := p.src[rt]
+	if !ok {
+		return v, fmt.Errorf("export: no exported type for %v", v.Type())
+	}
+	if v.Kind() == reflect.Ptr {
+		t = ptrOf(t)
+	}
+	return p.exportValue(t, v, map[seenKey]reflect.Value{}), nil
+}
+
+// ImportValue converts v, a value or a pointer to a value of a type returned
+// by ExportType, to the corresponding value of the interpreted type, which
+// can be passed to interpreted functions.
+func (interp *Interpreter) ImportValue(v reflect.Value) (reflect.Value, error) {
+	p := &interp.proxies
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !v.IsValid() {
+		return v, fmt.Errorf("import: invalid value")
+	}
+	pt := v.Type()
+	if pt.Kind() == reflect.Ptr {
+		pt = pt.Elem()
+	}
+	t, ok := p.dst[pt]
+	if !ok {
+		return v, fmt.Errorf("import: %v is not an exported type", v.Type())
+	}
+	if v.Kind() == reflect.Ptr {
+		t = ptrOf(t)
+	}
+	return p.importValue(interp, t, v, t.TypeOf(), map[seenKey]reflect.Value{}), nil
+}
+
+// typeOf returns the proxy type of the interpreted type t.
+func (p *proxies) typeOf(t *itype) reflect.Type {
+	if pt, ok := p.types[t]; ok {
+		return pt
+	}
+	if p.types == nil {
+		p.types = map[*itype]reflect.Type{}
+		p.src = map[reflect.Type]*itype{}
+		p.dst = map[reflect.Type]*itype{}
+	}
+
+	switch t.cat {
+	case linkedT:
+		if t.val.cat != structT {
+			return p.typeOf(t.val)
+		}
+		return p.structOf(t, t.val)
+	case structT:
+		return p.structOf(t, t)
+	case ptrT:
+		return reflect.PtrTo(p.typeOf(t.val))
+	case sliceT, variadicT:
+		return reflect.SliceOf(p.typeOf(t.val))
+	case arrayT:
+		return reflect.ArrayOf(t.length, p.typeOf(t.val))
+	case mapT:
+		return reflect.MapOf(p.typeOf(t.key), p.typeOf(t.val))
+	case interfaceT:
+		return emptyInterfaceType
+	default:
+		return t.TypeOf()
+	}
+}
+
+// structOf returns the proxy type of the interpreted type t, of struct type
+// st. A recursive reference to t in its fields is represented by a stand-in
+// type, completed once the outermost proxy type is built.
+func (p *proxies) structOf(t, st *itype) reflect.Type {
+	if pt, ok := p.types[t]; ok {
+		return pt
+	}
+	p.types[t] = unsafe2.DummyType
+	p.depth++
+
+	var fields []reflect.StructField
+	var types []*itype
+	for _, f := range st.field {
+		if !canExport(f.name) {
+			continue
+		}
+		ft := p.typeOf(f.typ)
+		field := reflect.StructField{Name: f.name, Type: ft, Tag: reflect.StructTag(f.tag)}
+		if f.embed && noMethods(ft) {
+			field.Anonymous = true
+		}
+		fields = append(fields, field)
+		types = append(types, f.typ)
+	}
+	pt := reflect.StructOf(fields)
+	p.depth--
+	p.types[t] = pt
+	p.src[t.TypeOf()] = t
+	p.dst[pt] = t
+
+	for i, f := range fields {
+		if strings.Contains(f.Type.String(), "unsafe2.dummy") {
+			p.fixes = append(p.fixes, proxyFix{pt, i, types[i]})
+		}
+	}
+	if p.depth > 0 {
+		return pt
+	}
+	fixes := p.fixes
+	p.fixes = nil
+	for _, f := range fixes {
+		unsafe2.SetFieldType(f.rtype, f.index, p.typeOf(f.typ))
+	}
+	return pt
+}
+
+// exportValue returns the proxy value of v, of interpreted type t.
+func (p *proxies) exportValue(t *itype, v reflect.Value, seen map[seenKey]reflect.Value) reflect.Value {
+	pt := p.typeOf(t)
+	r := reflect.New(pt).Elem()
+	if t.cat == interfaceT {
+		// Export the interpreted structs held by interfaces.
+		if vi, ok := valueInterfaceOf(v); ok && vi.node != nil && vi.node.typ != nil && isStruct(vi.node.typ) && vi.node.typ.cat != valueT {
+			r.Set(p.exportValue(vi.node.typ, vi.value, seen))
+		} else if v = concreteValue(v); v.IsValid() {
+			r.Set(v)
+		}
+		return r
+	}
+	v = concreteValue(v)
+	if !v.IsValid() {
+		return r
+	}
+
+	switch t.cat {
+	case linkedT:
+		if t.val.cat != structT {
+			return p.exportValue(t.val, v, seen)
+		}
+		return p.exportFields(t.val, v, r, seen)
+	case structT:
+		return p.exportFields(t, v, r, seen)
+	case ptrT:
+		if v.IsNil() {
+			return r
+		}
+		k := seenKey{v.Pointer(), v.Type()}
+		if e, ok := seen[k]; ok {
+			return e
+		}
+		r = reflect.New(pt.Elem())
+		seen[k] = r
+		r.Elem().Set(p.exportValue(t.val, v.Elem(), seen))
+	case sliceT, variadicT:
+		if v.IsNil() {
+			return r
+		}
+		r = reflect.MakeSlice(pt, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			r.Index(i).Set(p.exportValue(t.val, v.Index(i), seen))
+		}
+	case arrayT:
+		for i := 0; i < v.Len(); i++ {
+			r.Index(i).Set(p.exportValue(t.val, v.Index(i), seen))
+		}
+	case mapT:
+		if v.IsNil() {
+			return r
+		}
+		r = reflect.MakeMapWithSize(pt, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			r.SetMapIndex(p.exportValue(t.key, it.Key(), seen), p.exportValue(t.val, it.Value(), seen))
+		}
+	default:
+		if x := translateValue(v, pt); x.Type().AssignableTo(pt) {
+			r.Set(x)
+		}
+	}
+	return r
+}
+
+// exportFields sets the fields of the proxy value r from the exported fields
+// of v, of interpreted struct type t.
+func (p *proxies) exportFields(t *itype, v, r reflect.Value, seen map[seenKey]reflect.Value) reflect.Value {
+	j := 0
+	for i, f := range t.field {
+		if !canExport(f.name) {
+			continue
+		}
+		r.Field(j).Set(p.exportValue(f.typ, v.Field(i), seen))
+		j++
+	}
+	return r
+}
+
+// importValue returns the value of type rt of interpreted type t, converted
+// from the proxy value v.
+func (p *proxies) importValue(interp *Interpreter, t *itype, v reflect.Value, rt reflect.Type, seen map[seenKey]reflect.Value) reflect.Value {
+	r := reflect.New(rt).Elem()
+	if v.Kind() == reflect.Interface && t.cat != interfaceT {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return r
+	}
+
+	switch t.cat {
+	case linkedT:
+		return p.importValue(interp, t.val, v, rt, seen)
+	case structT:
+		j := 0
+		for i, f := range t.field {
+			if !canExport(f.name) {
+				continue
+			}
+			r.Field(i).Set(p.importValue(interp, f.typ, v.Field(j), rt.Field(i).Type, seen))
+			j++
+		}
+	case ptrT:
+		if v.IsNil() {
+			return r
+		}
+		k := seenKey{v.Pointer(), v.Type()}
+		if e, ok := seen[k]; ok {
+			return e
+		}
+		r = reflect.New(rt.Elem())
+		seen[k] = r
+		r.Elem().Set(p.importValue(interp, t.val, v.Elem(), rt.Elem(), seen))
+	case sliceT, variadicT:
+		if v.IsNil() {
+			return r
+		}
+		r = reflect.MakeSlice(rt, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			r.Index(i).Set(p.importValue(interp, t.val, v.Index(i), rt.Elem(), seen))
+		}
+	case arrayT:
+		for i := 0; i < v.Len(); i++ {
+			r.Index(i).Set(p.importValue(interp, t.val, v.Index(i), rt.Elem(), seen))
+		}
+	case mapT:
+		if v.IsNil() {
+			return r
+		}
+		r = reflect.MakeMapWithSize(rt, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			r.SetMapIndex(p.importValue(interp, t.key, it.Key(), rt.Key(), seen), p.importValue(interp, t.val, it.Value(), rt.Elem(), seen))
+		}
+	case interfaceT:
+		x := v
+		if x.Kind() == reflect.Interface {
+			x = x.Elem()
+		}
+		if !x.IsValid() {
+			return r
+		}
+		// Import the exported structs held by interfaces.
+		typ := valueTOf(x.Type())
+		if it, ok := p.dst[x.Type()]; ok {
+			typ = it
+			x = p.importValue(interp, it, x, it.TypeOf(), seen)
+		}
+		if rt == valueInterfaceType {
+			r.Set(reflect.ValueOf(valueInterface{&node{interp: interp, typ: typ}, x}))
+			break
+		}
+		r.Set(x)
+	default:
+		if x := translateValue(v, rt); x.Type().AssignableTo(rt) {
+			r.Set(x)
+		}
+	}
+	return r
+}
+
+// concreteValue returns v, without interface and interpreter interface
+// indirections.
+func concreteValue(v reflect.Value) reflect.Value {
+	for v.IsValid() {
+		if vi, ok := valueInterfaceOf(v); ok {
+			v = vi.value
+			continue
+		}
+		if v.Kind() != reflect.Interface {
+			break
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// valueInterfaceOf returns the interpreter interface held by v, if any.
+func valueInterfaceOf(v reflect.Value) (valueInterface, bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return valueInterface{}, false
+	}
+	vi, ok := v.Interface().(valueInterface)
+	return vi, ok
+}
+
+// noMethods returns true if t has no methods, so that it can be embedded in
+// a struct built by reflect.StructOf.
+func noMethods(t reflect.Type) bool {
+	if t.NumMethod() > 0 {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		return t.Elem().Kind() == reflect.Struct && t.Elem().NumMethod() == 0
+	}
+	return t.Kind() == reflect.Struct
+}
@@ -0,0 +1,173 @@
+package interp
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	randv2 "math/rand/v2"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// A Recording holds the nondeterministic inputs of a run of interpreted code,
+// captured with Options.Record, in order to replay the run with
+// Options.Replay, e.g. to reproduce a failure which only occurred in
+// production. It can be saved and loaded in JSON.
+//
+// The recorded inputs are the command line arguments, the environment, the
+// data read from the standard input, the readings of the clock by time.Now, time.Since and
+// time.Until, and the seed of the top-level functions of the math/rand and
+// math/rand/v2 packages, which use random sources private to the
+// interpreter when recording or replaying. Other inputs, e.g. files, network
+// or goroutine scheduling, are not recorded. As it holds the environment, a
+// recording may contain secrets.
+//
+// When recording or replaying, os.Stdin in interpreted code is the recorded
+// input, an io.Reader which is not an *os.File, as if the YAEGI_SPECIAL_STDIO
+// environment variable was set for the standard input.
+type Recording struct {
+	Args  []string    `json:"args,omitempty"`  // command line arguments
+	Env   []string    `json:"env,omitempty"`   // initial environment
+	Stdin []byte      `json:"stdin,omitempty"` // standard input, as read
+	Now   []time.Time `json:"now,omitempty"`   // successive readings of the clock
+	Seed  int64       `json:"seed"`            // seed of the random sources
+
+	mutex sync.Mutex
+}
+
+// stdinRecorder appends the data written to it to the standard input of a
+// recording, as read by interpreted code from the recorded input.
+type stdinRecorder struct{ rec *Recording }
+
+func (s stdinRecorder) Write(p []byte) (int, error) {
+	s.rec.mutex.Lock()
+	defer s.rec.mutex.Unlock()
+	s.rec.Stdin = append(s.rec.Stdin, p...)
+	return len(p), nil
+}
+
+// recordClock is the clock of an interpreter recording or replaying a run.
+// The timers are provided by the embedded clock.
+type recordClock struct {
+	Clock
+	rec    *Recording
+	replay bool
+	next   int // index of the next reading to replay
+}
+
+// Now returns the current time of the clock, recorded, or the next recorded
+// one when replaying. The last recorded time is repeated once all readings
+// are replayed. Monotonic clock readings are stripped, so the times are the
+// same in the recorded and replayed runs.
+func (c *recordClock) Now() time.Time {
+	rec := c.rec
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	if !c.replay {
+		t := c.Clock.Now().Round(0)
+		rec.Now = append(rec.Now, t)
+		return t
+	}
+	if c.next < len(rec.Now) {
+		c.next++
+		return rec.Now[c.next-1]
+	}
+	if n := len(rec.Now); n > 0 {
+		return rec.Now[n-1]
+	}
+	return c.Clock.Now().Round(0)
+}
+
+// lockedSource is a random source safe for concurrent use, as the one of
+// the top-level functions of the math/rand packages.
+type lockedSource struct {
+	mutex sync.Mutex
+	src   rand.Source64
+}
+
+func newLockedSource(seed int64) *lockedSource {
+	return &lockedSource{src: rand.NewSource(seed).(rand.Source64)}
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.src.Seed(seed)
+}
+
+// setRecording sets up the interpreter to record its inputs in rec, or to
+// replay them from rec.
+func (interp *Interpreter) setRecording(rec *Recording, replay bool) {
+	rec.mutex.Lock()
+	if replay {
+		interp.args = rec.Args
+		interp.env = newEnviron(rec.Env)
+	} else {
+		rec.Args = append([]string{}, interp.args...)
+		if interp.env != nil {
+			rec.Env = interp.env.list()
+		} else {
+			rec.Env = os.Environ()
+		}
+		if rec.Seed == 0 {
+			rec.Seed = time.Now().UnixNano()
+		}
+	}
+	rec.mutex.Unlock()
+
+	clock := interp.clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+	interp.clock = &recordClock{Clock: clock, rec: rec, replay: replay}
+	interp.seed = rec.Seed
+
+	// The standard input is recorded as it is read by interpreted code.
+	if replay {
+		interp.stdin = bytes.NewReader(rec.Stdin)
+	} else {
+		interp.stdin = io.TeeReader(interp.stdin, stdinRecorder{rec})
+	}
+	interp.recStdin = true
+}
+
+// fixRand redefines the top-level functions of the math/rand and
+// math/rand/v2 packages to use random sources seeded by the recording of the
+// interpreter, if any.
+func fixRand(interp *Interpreter) {
+	if interp.seed == 0 {
+		return
+	}
+	rands := map[string]reflect.Value{
+		"math/rand":    reflect.ValueOf(rand.New(newLockedSource(interp.seed))),
+		"math/rand/v2": reflect.ValueOf(randv2.New(newLockedSource(interp.seed))),
+	}
+	for path, r := range rands {
+		p := interp.binPkg[path]
+		if p == nil {
+			continue
+		}
+		for name, v := range p {
+			if v.Kind() != reflect.Func {
+				continue
+			}
+			if m := r.MethodByName(name); m.IsValid() && m.Type() == v.Type() {
+				p[name] = m
+			}
+		}
+	}
+}
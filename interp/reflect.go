@@ -0,0 +1,173 @@
+package interp
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// fmtTypes and jsonTypes are the interfaces of the methods honored by fmt and
+// encoding/json, from the most to the least specific.
+var (
+	fmtTypes = []reflect.Type{
+		reflect.TypeOf((*fmt.Formatter)(nil)).Elem(),
+		errorType,
+		reflect.TypeOf((*fmt.Stringer)(nil)).Elem(),
+	}
+	jsonTypes = []reflect.Type{jsonMarshalerType, textMarshalerType}
+)
+
+// reflectValue returns the value of x as reflect.ValueOf would return it in
+// compiled code: the concrete value, out of the interpreter representations of
+// interface values and of the interface wrappers of interpreted values.
+func reflectValue(x interface{}) reflect.Value {
+	v := concreteValue(reflect.ValueOf(x))
+	for v.IsValid() && isWrapper(v.Type()) {
+		v = concreteValue(v.Field(0))
+	}
+	if v.CanAddr() && v.CanInterface() {
+		// The value of an interface is not addressable.
+		v = reflect.ValueOf(v.Interface())
+	}
+	return v
+}
+
+// fixReflect redefines the TypeOf and ValueOf functions of the reflect
+// package to return the type and value of the concrete values of interpreted
+// code, instead of the internal representations of interface values.
+func fixReflect(interp *Interpreter) {
+	p := interp.binPkg["reflect"]
+	if p == nil {
+		return
+	}
+	p["TypeOf"] = reflect.ValueOf(func(i interface{}) reflect.Type {
+		if v := reflectValue(i); v.IsValid() {
+			return v.Type()
+		}
+		return nil
+	})
+	p["ValueOf"] = reflect.ValueOf(reflectValue)
+}
+
+// Format implements fmt.Formatter to print the concrete value of v, e.g. as an
+// element of a []interface{} printed by fmt, honoring the Format, Error and
+// String methods of its interpreted type.
+func (v valueInterface) Format(s fmt.State, verb rune) {
+	fmt.Fprintf(s, fmt.FormatString(s, verb), v.concrete(fmtTypes))
+}
+
+// MarshalJSON implements json.Marshaler to encode the concrete value of v,
+// e.g. as an element of a []interface{} encoded by encoding/json, honoring
+// the MarshalJSON and MarshalText methods of its interpreted type.
+func (v valueInterface) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.concrete(jsonTypes))
+}
+
+// concrete returns the concrete value of v, wrapped into the first interface
+// of types implemented by its interpreted type, if any, or nil.
+func (v valueInterface) concrete(types []reflect.Type) interface{} {
+	for {
+		vi, ok := valueInterfaceOf(v.value)
+		if !ok {
+			break
+		}
+		if vi.node == nil {
+			vi.node = v.node
+		}
+		v = vi
+	}
+	if !v.value.IsValid() || !v.value.CanInterface() {
+		return nil
+	}
+	if n := v.node; n != nil && n.typ != nil {
+		for _, rt := range types {
+			if !n.typ.implements(&itype{cat: valueT, rtype: rt}) {
+				continue
+			}
+			if w := interfaceWrapper(n, rt); w != nil {
+				return w(v.value, n.interp.frame.Load()).Interface()
+			}
+			break
+		}
+	}
+	return v.value.Interface()
+}
+
+// TypeOf returns the runtime type of the interpreted type or value symbol,
+// qualified by the import path of its package, e.g. "main.Config" or
+// "main.config", which is the type of its values seen by host packages
+// through reflection, with the struct tags of the source. Host frameworks can
+// use it to discover, route or validate the struct types declared by scripts,
+// and to create values with reflect.New to be passed to interpreted
+// functions.
+//
+// The returned type is unnamed and has no methods. The unexported fields of
+// interpreted structs are exported in it, their names being prefixed by X.
+// Interpreted interface types, which have no runtime type, are rejected.
+func (interp *Interpreter) TypeOf(symbol string) (reflect.Type, error) {
+	typ, err := interp.symbolType(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if isInterfaceSrc(typ) {
+		return nil, fmt.Errorf("type of %s: interpreted interface type", symbol)
+	}
+	return typ.TypeOf(), nil
+}
+
+// StructTags returns the struct tags of the fields of the interpreted struct
+// type or value symbol, as TypeOf accepts it, indexed by their names in the
+// source. A pointer to a struct is dereferenced. The fields without tag are
+// included, with an empty tag.
+func (interp *Interpreter) StructTags(symbol string) (map[string]reflect.StructTag, error) {
+	typ, err := interp.symbolType(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if typ.cat == ptrT {
+		typ = typ.val
+	}
+	if typ, err = typ.finalize(); err != nil {
+		return nil, err
+	}
+	if typ.cat != structT {
+		return nil, fmt.Errorf("struct tags of %s: not a struct", symbol)
+	}
+	tags := make(map[string]reflect.StructTag, len(typ.field))
+	for _, f := range typ.field {
+		tags[f.name] = reflect.StructTag(f.tag)
+	}
+	return tags, nil
+}
+
+// symbolType returns the type of the package level symbol, "path.name", of a
+// source package: the type itself for a type symbol, or the type of the
+// value.
+func (interp *Interpreter) symbolType(symbol string) (*itype, error) {
+	i := strings.LastIndex(symbol, ".")
+	if i <= 0 {
+		return nil, fmt.Errorf("invalid symbol %q: not qualified by a package path", symbol)
+	}
+	path, name := symbol[:i], symbol[i+1:]
+
+	interp.compile.Lock()
+	defer interp.compile.Unlock()
+	sc := interp.scopes[path]
+	if sc == nil {
+		return nil, fmt.Errorf("package not found: %s", path)
+	}
+	sym := sc.sym[name]
+	if sym == nil {
+		return nil, fmt.Errorf("undefined: %s", symbol)
+	}
+	switch sym.kind {
+	case typeSym, varSym, constSym, funcSym:
+	default:
+		return nil, fmt.Errorf("type of %s: not a value or a type", symbol)
+	}
+	if sym.typ == nil || sym.typ.cat == nilT {
+		return nil, fmt.Errorf("type of %s: not a value or a type", symbol)
+	}
+	return sym.typ.finalize()
+}
@@ -0,0 +1,122 @@
+package interp
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// A Capability names a permission which can be granted to an interpreter,
+// using Options.Capabilities.
+type Capability string
+
+// A VirtualPackage is a binary package declared in a Registry. Its symbols are
+// exposed only to interpreters which have been granted all the required
+// capabilities.
+type VirtualPackage struct {
+	// Path is the package path, in the same form as Exports keys,
+	// i.e. the import path joined with the package name.
+	Path string
+
+	// Symbols are the package symbols, as in Exports values.
+	Symbols map[string]reflect.Value
+
+	// Requires lists the capabilities an interpreter must have to use the
+	// package. The package is available to all interpreters if empty.
+	Requires []Capability
+}
+
+// A Registry is a set of virtual packages, shared by multiple interpreters.
+// It is safe for concurrent use.
+type Registry struct {
+	mutex sync.RWMutex
+	pkgs  map[string]*VirtualPackage
+}
+
+// NewRegistry returns a new empty registry.
+func NewRegistry() *Registry {
+	return &Registry{pkgs: map[string]*VirtualPackage{}}
+}
+
+// Register adds a virtual package to the registry. It is an error to register
+// the same package path twice.
+func (r *Registry) Register(pkg VirtualPackage) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.pkgs[pkg.Path]; ok {
+		return fmt.Errorf("virtual package %s already registered", pkg.Path)
+	}
+	r.pkgs[pkg.Path] = &pkg
+	return nil
+}
+
+// Unregister removes the package of the given path from the registry.
+// Interpreters which already use the package are not affected.
+func (r *Registry) Unregister(path string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.pkgs, path)
+}
+
+// Exports returns the symbols of the packages allowed by the given set of
+// capabilities.
+func (r *Registry) Exports(caps ...Capability) Exports {
+	granted := map[Capability]bool{}
+	for _, c := range caps {
+		granted[c] = true
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	exports := Exports{}
+	for k, pkg := range r.pkgs {
+		if allowed(pkg.Requires, granted) {
+			exports[k] = pkg.Symbols
+		}
+	}
+	return exports
+}
+
+// Paths returns the sorted list of registered package paths.
+func (r *Registry) Paths() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	paths := make([]string, 0, len(r.pkgs))
+	for k := range r.pkgs {
+		paths = append(paths, k)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func allowed(requires []Capability, granted map[Capability]bool) bool {
+	for _, c := range requires {
+		if !granted[c] {
+			return false
+		}
+	}
+	return true
+}
+
+// HasCapability returns true if the interpreter has been granted the
+// capability c.
+func (interp *Interpreter) HasCapability(c Capability) bool {
+	for _, ic := range interp.capabilities {
+		if ic == c {
+			return true
+		}
+	}
+	return false
+}
+
+// UseRegistry loads in the interpreter the packages of registry r which are
+// allowed by the capabilities of the interpreter, as set in
+// Options.Capabilities. Packages requiring other capabilities remain hidden
+// from interpreted code, and their import fails as for any unknown package.
+func (interp *Interpreter) UseRegistry(r *Registry) error {
+	return interp.Use(r.Exports(interp.capabilities...))
+}
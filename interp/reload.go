@@ -0,0 +1,280 @@
+package interp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReloadPath re-evaluates the source file at path, previously evaluated by
+// EvalPath, after it has been modified. Only the changes are compiled:
+//
+//   - modified functions are recompiled in place: the new code is used by all
+//     the existing references to the function, including the running ones,
+//     from their next call,
+//   - new declarations, including imports, are added to the package,
+//   - removed declarations remain available.
+//
+// Package level variables keep their current value. The main function is not
+// executed again. Changes of function signatures, methods, and of other
+// existing declarations, are not supported and cause an error, in which case
+// the interpreter state is unchanged.
+//
+// The reload is serialized with compilations. The functions are patched
+// once the new declarations are executed, the previous code remaining in use
+// until then.
+func (interp *Interpreter) ReloadPath(path string) error {
+	path = filepath.ToSlash(path)
+	interp.compile.Lock()
+	defer interp.compile.Unlock()
+	oldSrc, ok := interp.sources[path]
+	if !ok {
+		return fmt.Errorf("%s: file not previously evaluated", path)
+	}
+	b, err := fs.ReadFile(interp.filesystem, path)
+	if err != nil {
+		return err
+	}
+	newSrc := string(b)
+
+	fset := token.NewFileSet()
+	oldFile, err := parser.ParseFile(fset, path, oldSrc, parser.SkipObjectResolution)
+	if err != nil {
+		return err
+	}
+	newFile, err := parser.ParseFile(fset, path, newSrc, parser.SkipObjectResolution)
+	if err != nil {
+		return err
+	}
+	pkgName := newFile.Name.Name
+	if oldName := oldFile.Name.Name; pkgName != oldName {
+		return fmt.Errorf("%s: package name changed from %s to %s", path, oldName, pkgName)
+	}
+
+	oldDecls := map[string]sourceDecl{}
+	for _, d := range sourceDecls(fset, oldFile, oldSrc) {
+		oldDecls[d.key] = d
+	}
+	var changed []string
+	var delta strings.Builder
+	for _, d := range sourceDecls(fset, newFile, newSrc) {
+		old, exists := oldDecls[d.key]
+		switch {
+		case exists && old.src == d.src:
+			continue
+		case exists && d.fun == "":
+			return fmt.Errorf("%s: reload of modified declaration %s is not supported", path, d.key)
+		case exists:
+			changed = append(changed, d.fun)
+		}
+		delta.WriteString(d.src + "\n")
+	}
+	if delta.Len() == 0 {
+		interp.sources[path] = newSrc
+		return nil
+	}
+
+	// Keep the symbols of changed functions: they are patched in place to
+	// preserve the existing references.
+	sc := interp.scopes[pkgName]
+	if sc == nil {
+		return fmt.Errorf("%s: package %s not found", path, pkgName)
+	}
+	oldSyms := map[string]*symbol{}
+	for _, name := range changed {
+		oldSyms[name] = sc.sym[name]
+	}
+	restore := func() {
+		for name, sym := range oldSyms {
+			sc.sym[name] = sym
+		}
+	}
+
+	prog, err := interp.compileSrc("package "+pkgName+"\n"+delta.String(), path, false)
+	if err != nil {
+		restore()
+		return err
+	}
+	newNodes := map[string]*node{}
+	for name, sym := range oldSyms {
+		if ns := sc.sym[name]; sym == nil || ns == nil || !sym.typ.equals(ns.typ) {
+			restore()
+			return fmt.Errorf("%s: reload of function %s with a new signature is not supported", path, name)
+		}
+		newNodes[name] = sc.sym[name].node
+	}
+	restore()
+
+	// Do not run main again.
+	if m := sc.sym[mainID]; m != nil {
+		init := prog.init[:0]
+		for _, n := range prog.init {
+			if n != m.node {
+				init = append(init, n)
+			}
+		}
+		prog.init = init
+	}
+	if _, err := interp.executeLocked(prog); err != nil {
+		return err
+	}
+
+	for name, sym := range oldSyms {
+		patchFunc(sym.node, newNodes[name])
+	}
+	interp.sources[path] = newSrc
+	return nil
+}
+
+// RecompileFunc recompiles the function name of the source package of
+// import path pkg, or of the main package if pkg is empty, with src, the
+// source of its new declaration, e.g. "func add(a, b int) int { return a+b }".
+// Only this declaration is compiled, in the existing package scope, which
+// makes it faster than ReloadPath for live coding.
+//
+// As with ReloadPath, the function is patched in place: the new code is used
+// by all the existing references to the function from their next call. The
+// signature of the function can not change, and methods are not supported.
+// In case of error, the function is unchanged.
+//
+// The patch is serialized with compilations, but not with the execution of
+// the function: it must not be called during RecompileFunc.
+func (interp *Interpreter) RecompileFunc(pkg, name, src string) error {
+	if pkg == "" {
+		pkg = mainID
+	}
+	interp.compile.Lock()
+	defer interp.compile.Unlock()
+	sc := interp.scopes[pkg]
+	if sc == nil {
+		return fmt.Errorf("package %s not found", pkg)
+	}
+	sym := sc.sym[name]
+	if sym == nil || sym.kind != funcSym || sym.node == nil || sym.node.kind != funcDecl {
+		return fmt.Errorf("%s.%s is not a function", pkg, name)
+	}
+	pkgName := interp.pkgNames[pkg]
+	if pkgName == "" {
+		pkgName = pkg
+	}
+	fileName := interp.fset.Position(sym.node.pos).Filename
+
+	// Check that src is the declaration of the function only.
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, "package "+pkgName+"\n"+src, parser.SkipObjectResolution)
+	if err != nil {
+		return err
+	}
+	if len(file.Decls) != 1 {
+		return fmt.Errorf("%s.%s: source must hold a single function declaration", pkg, name)
+	}
+	if d, ok := file.Decls[0].(*ast.FuncDecl); !ok || d.Recv != nil || d.Name.Name != name {
+		return fmt.Errorf("%s.%s: source is not the declaration of function %s", pkg, name, name)
+	}
+
+	restore := func() { sc.sym[name] = sym }
+	prog, err := interp.compileFunc(pkg, pkgName, fileName, "package "+pkgName+"\n"+src)
+	if err != nil {
+		restore()
+		return err
+	}
+	ns := sc.sym[name]
+	restore()
+	if ns == nil || ns.node == nil || !sym.typ.equals(ns.typ) {
+		return fmt.Errorf("%s.%s: recompilation with a new signature is not supported", pkg, name)
+	}
+	if _, err := interp.executeLocked(prog); err != nil {
+		return err
+	}
+	patchFunc(sym.node, ns.node)
+	return nil
+}
+
+// compileFunc compiles the source file src holding a function declaration,
+// in the scope of package pkg. It must be called with interp.compile locked.
+func (interp *Interpreter) compileFunc(pkg, pkgName, fileName, src string) (*Program, error) {
+	interp.evals++
+	n, err := interp.parse(src, fileName, false)
+	if err != nil {
+		return nil, err
+	}
+	_, root, err := interp.ast(n)
+	if err != nil {
+		return nil, err
+	}
+	if err = interp.checkFrozen(root, pkg); err != nil {
+		return nil, err
+	}
+	if err = interp.gtaRetry([]*node{root}, pkg, pkgName); err != nil {
+		return nil, err
+	}
+	initNodes, err := interp.cfg(root, nil, pkg, pkgName)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{pkgName: pkgName, pkgID: pkg, root: root, init: initNodes}, nil
+}
+
+// patchFunc replaces in place the code of the function declared by def with
+// the one of nd, for the existing references to def to use it.
+func patchFunc(def, nd *node) {
+	def.child[2], def.child[3] = nd.child[2], nd.child[3]
+	def.types, def.frames = nd.types, nd.frames
+	def.start = nd.start
+}
+
+// sourceDecl is a top level declaration of a source file.
+type sourceDecl struct {
+	key string // unique identifier of the declaration in the file
+	src string // source code of the declaration
+	fun string // name of function, or empty if not a plain function
+}
+
+// sourceDecls returns the top level declarations of file f, in source order.
+func sourceDecls(fset *token.FileSet, f *ast.File, src string) []sourceDecl {
+	var decls []sourceDecl
+	text := func(n ast.Node) string {
+		return src[fset.Position(n.Pos()).Offset:fset.Position(n.End()).Offset]
+	}
+	add := func(d sourceDecl) { decls = append(decls, d) }
+	inits := 0
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			switch {
+			case d.Recv != nil && len(d.Recv.List) > 0:
+				add(sourceDecl{key: "method " + text(d.Recv.List[0].Type) + "." + d.Name.Name, src: text(d)})
+			case d.Name.Name == "init":
+				inits++
+				add(sourceDecl{key: "init #" + strconv.Itoa(inits), src: text(d)})
+			default:
+				add(sourceDecl{key: "func " + d.Name.Name, src: text(d), fun: d.Name.Name})
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				for _, s := range d.Specs {
+					add(sourceDecl{key: "import " + text(s), src: "import " + text(s)})
+				}
+				continue
+			}
+			var names []string
+			for _, s := range d.Specs {
+				switch s := s.(type) {
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						names = append(names, n.Name)
+					}
+				case *ast.TypeSpec:
+					names = append(names, s.Name.Name)
+				}
+			}
+			add(sourceDecl{key: d.Tok.String() + " " + strings.Join(names, ", "), src: text(d)})
+		}
+	}
+	return decls
+}
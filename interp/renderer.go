@@ -0,0 +1,68 @@
+package interp
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"unicode/utf8"
+)
+
+// A Renderer formats values for display by the print and println builtins and
+// by the REPL. It is set using Options.Renderer.
+type Renderer interface {
+	Render(v reflect.Value) string
+}
+
+// A ValueRenderer is a configurable Renderer. Its zero value renders values as
+// the "%v" verb of the fmt package, which is the default interpreter behavior.
+type ValueRenderer struct {
+	// MaxWidth, if greater than 0, is the maximum number of characters of a
+	// rendered value. Longer values are truncated and terminated by "...".
+	MaxWidth int
+
+	// FloatFormat, if not 0, is the format of floating point values, as in
+	// strconv.FormatFloat, with precision FloatPrecision.
+	FloatFormat    byte
+	FloatPrecision int
+
+	// ExpandErrors renders error values with the "%+v" verb of the fmt
+	// package, which expands the details of errors which support it.
+	ExpandErrors bool
+}
+
+// Render returns the textual representation of v.
+func (r *ValueRenderer) Render(v reflect.Value) string {
+	if v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	var s string
+	switch {
+	case !v.IsValid():
+		s = fmt.Sprintf("%v", v)
+	case r.FloatFormat != 0 && (v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64):
+		s = strconv.FormatFloat(v.Float(), r.FloatFormat, r.FloatPrecision, v.Type().Bits())
+	case r.ExpandErrors && v.CanInterface():
+		if err, ok := v.Interface().(error); ok {
+			s = fmt.Sprintf("%+v", err)
+			break
+		}
+		s = fmt.Sprintf("%v", v)
+	default:
+		s = fmt.Sprintf("%v", v)
+	}
+
+	if r.MaxWidth > 0 && utf8.RuneCountInString(s) > r.MaxWidth {
+		s = string([]rune(s)[:r.MaxWidth]) + "..."
+	}
+	return s
+}
+
+// render returns the textual representation of v, using the interpreter
+// renderer if set.
+func (interp *Interpreter) render(v reflect.Value) string {
+	if interp.renderer == nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return interp.renderer.Render(v)
+}
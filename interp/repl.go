@@ -0,0 +1,262 @@
+package interp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"go/scanner"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrREPLInterrupt is returned by the ReadLine method of a REPLTransport to
+// interrupt the current evaluation, as Ctrl-C in a terminal.
+var ErrREPLInterrupt = errors.New("repl: interrupt")
+
+// REPLFrameKind is the kind of a REPLFrame.
+type REPLFrameKind int
+
+// REPL frame kinds.
+const (
+	REPLPrompt   REPLFrameKind = iota // ready for a new input
+	REPLContinue                      // ready for the continuation of an incomplete input
+	REPLResult                        // rendered result of an evaluation
+	REPLError                         // error of an evaluation or a command
+	REPLOutput                        // output of a command, e.g. :doc
+)
+
+var replFrameKinds = [...]string{
+	REPLPrompt:   "prompt",
+	REPLContinue: "continue",
+	REPLResult:   "result",
+	REPLError:    "error",
+	REPLOutput:   "output",
+}
+
+func (k REPLFrameKind) String() string {
+	if k >= 0 && int(k) < len(replFrameKinds) {
+		return replFrameKinds[k]
+	}
+	return "REPLFrameKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// A REPLFrame is a message sent by the REPL to its client.
+type REPLFrame struct {
+	Kind REPLFrameKind
+	Text string // without trailing newline, empty for prompts
+}
+
+// A REPLTransport connects a REPL to its client, e.g. a terminal or a
+// WebSocket connection, with a framing of its input lines and of its
+// prompts, results and errors. The output of interpreted code is written
+// to the standard streams of the interpreter, not to the transport.
+type REPLTransport interface {
+	// ReadLine returns the next input line, without its line terminator.
+	// It returns ErrREPLInterrupt to interrupt the current evaluation, and
+	// io.EOF or another error to end the REPL. ReadLine may be called
+	// during an evaluation.
+	ReadLine() (string, error)
+
+	// WriteFrame sends f to the client. An error ends the REPL.
+	WriteFrame(f REPLFrame) error
+}
+
+// ServeREPL performs a Read-Eval-Print-Loop on the transport t, as REPL
+// does on the standard streams of the interpreter. It returns the last
+// interpreter result value and error, or the error of the transport.
+func (interp *Interpreter) ServeREPL(t REPLTransport) (reflect.Value, error) {
+	var v reflect.Value // result value from eval
+	var err error       // error from eval
+	src := ""           // source string to evaluate
+
+	var mutex sync.Mutex          // protects cancel
+	var cancel context.CancelFunc // cancels the current evaluation
+	newContext := func() context.Context {
+		ctx, f := context.WithCancel(context.Background())
+		mutex.Lock()
+		cancel = f
+		mutex.Unlock()
+		return ctx
+	}
+	interrupt := func() {
+		mutex.Lock()
+		cancel()
+		mutex.Unlock()
+	}
+	ctx := newContext()
+	defer interrupt()
+
+	type input struct {
+		line string
+		err  error
+	}
+	inputs := make(chan input) // input lines, read ahead of the evaluations
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			line, err := t.ReadLine()
+			if err == ErrREPLInterrupt {
+				interrupt()
+				line, err = "", nil
+			}
+			select {
+			case inputs <- input{line, err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	if e := t.WriteFrame(REPLFrame{Kind: REPLPrompt}); e != nil {
+		return v, e
+	}
+	for {
+		in := <-inputs
+		if in.err == io.EOF {
+			return v, err
+		}
+		if in.err != nil {
+			return v, in.err
+		}
+		line := in.line
+		if src == "" && strings.HasPrefix(line, ":doc ") {
+			// Print the documentation of a symbol.
+			f := REPLFrame{Kind: REPLOutput}
+			if f.Text = strings.TrimSuffix(interp.Doc(strings.TrimSpace(line[5:])), "\n"); f.Text == "" {
+				f = REPLFrame{Kind: REPLError, Text: "no documentation found for " + strings.TrimSpace(line[5:])}
+			}
+			if e := writeFrames(t, f, REPLFrame{Kind: REPLPrompt}); e != nil {
+				return v, e
+			}
+			continue
+		}
+		src += line + "\n"
+
+		v, err = interp.EvalWithContext(ctx, src)
+		var frames []REPLFrame
+		if err != nil {
+			text := err.Error()
+			switch e := err.(type) {
+			case scanner.ErrorList:
+				if len(e) > 0 && ignoreScannerError(e[0], line) {
+					if e := t.WriteFrame(REPLFrame{Kind: REPLContinue}); e != nil {
+						return v, e
+					}
+					continue
+				}
+				text = strings.TrimPrefix(e[0].Error(), DefaultSourceName+":")
+			case Panic:
+				text = fmt.Sprintln(e.Value) + string(e.Stack)
+			}
+			frames = append(frames, REPLFrame{Kind: REPLError, Text: text})
+		}
+		if errors.Is(err, context.Canceled) {
+			ctx = newContext()
+		}
+		src = ""
+		if v.IsValid() {
+			frames = append(frames, REPLFrame{Kind: REPLResult, Text: interp.render(v)})
+		}
+		if e := writeFrames(t, append(frames, REPLFrame{Kind: REPLPrompt})...); e != nil {
+			return v, e
+		}
+	}
+}
+
+func writeFrames(t REPLTransport, frames ...REPLFrame) error {
+	for _, f := range frames {
+		if err := t.WriteFrame(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamREPL is the transport of the REPL on the standard streams of the
+// interpreter. The interrupt signal interrupts the current evaluation.
+// Prompts and results are printed only if the input is a terminal.
+type streamREPL struct {
+	out, errs io.Writer
+	prompt    bool
+	lines     chan string    // input lines
+	end       chan struct{}  // closed at the end of input
+	sig       chan os.Signal // interrupt signal (Ctrl-C)
+}
+
+func newStreamREPL(in io.Reader, out, errs io.Writer) *streamREPL {
+	t := &streamREPL{
+		out:    out,
+		errs:   errs,
+		prompt: isTerminal(in),
+		lines:  make(chan string),
+		end:    make(chan struct{}),
+		sig:    make(chan os.Signal, 1),
+	}
+	signalNotify(t.sig, os.Interrupt)
+
+	go func() {
+		defer close(t.end)
+		s := bufio.NewScanner(in) // read input stream line by line
+		for s.Scan() {
+			t.lines <- s.Text()
+		}
+		if e := s.Err(); e != nil {
+			fmt.Fprintln(errs, e)
+		}
+	}()
+	return t
+}
+
+func (t *streamREPL) close() { signalStop(t.sig) }
+
+func (t *streamREPL) ReadLine() (string, error) {
+	select {
+	case line := <-t.lines:
+		return line, nil
+	case <-t.sig:
+		return "", ErrREPLInterrupt
+	case <-t.end:
+		return "", io.EOF
+	}
+}
+
+func (t *streamREPL) WriteFrame(f REPLFrame) error {
+	switch f.Kind {
+	case REPLPrompt:
+		if t.prompt {
+			fmt.Fprint(t.out, "> ")
+		}
+	case REPLResult:
+		if t.prompt {
+			fmt.Fprintln(t.out, ":", f.Text)
+		}
+	case REPLError:
+		fmt.Fprintln(t.errs, f.Text)
+	case REPLOutput:
+		fmt.Fprintln(t.out, f.Text)
+	}
+	return nil
+}
+
+// isTerminal returns true if prompts are activated for the input stream in:
+// if it is a terminal, or if forced by the YAEGI_PROMPT environment variable.
+func isTerminal(in io.Reader) bool {
+	if force, _ := strconv.ParseBool(os.Getenv("YAEGI_PROMPT")); force {
+		return true
+	}
+	s, ok := in.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return false
+	}
+	stat, err := s.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
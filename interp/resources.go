@@ -0,0 +1,120 @@
+package interp
+
+import (
+	"errors"
+	"net"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultResourceTypes are the types of the resources tracked by
+// TrackResources if no type is given: files, network listeners and
+// connections, tickers and timers.
+var DefaultResourceTypes = []reflect.Type{
+	reflect.TypeOf((*os.File)(nil)),
+	reflect.TypeOf((*net.Listener)(nil)).Elem(),
+	reflect.TypeOf((*net.Conn)(nil)).Elem(),
+	reflect.TypeOf((*time.Ticker)(nil)),
+	reflect.TypeOf((*time.Timer)(nil)),
+}
+
+// resources holds the resources returned by the binary calls of a program.
+type resources struct {
+	types  []reflect.Type
+	mutex  sync.Mutex
+	values []reflect.Value
+}
+
+// TrackResources enables the tracking of the resources returned to the
+// program by binary functions and methods, e.g. os.Open or net.Listen, so
+// they can be released by CloseLeaked. A value is tracked if its type is one
+// of types, or implements one of the interface types. If no type is given,
+// DefaultResourceTypes is used. It must not be called during the execution
+// of the program.
+func (p *Program) TrackResources(types ...reflect.Type) {
+	if len(types) == 0 {
+		types = DefaultResourceTypes
+	}
+	p.resources = &resources{types: types}
+	p.root.Walk(func(n *node) bool {
+		if n.kind == callExpr {
+			n.resources = p.resources
+		}
+		return true
+	}, nil)
+}
+
+// CloseLeaked releases the tracked resources of the program which are still
+// open, in the reverse order of their creation, and stops tracking them. It
+// is meant to be called once the execution of the program has ended or
+// panicked, to prevent descriptor leaks in long running hosts. Resources
+// are released by their Close method, or by their Stop method for tickers
+// and timers. Resources already closed are ignored, other errors are
+// returned joined.
+func (p *Program) CloseLeaked() error {
+	r := p.resources
+	if r == nil {
+		return nil
+	}
+	r.mutex.Lock()
+	values := r.values
+	r.values = nil
+	r.mutex.Unlock()
+
+	var errs []error
+	for i := len(values) - 1; i >= 0; i-- {
+		if err := closeResource(values[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// track records the resources in the results out of binary call n, if
+// tracked, and returns out.
+func (n *node) track(out []reflect.Value) []reflect.Value {
+	r := n.resources
+	if r == nil {
+		return out
+	}
+	for _, v := range out {
+		if v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) || !r.match(v.Type()) {
+			continue
+		}
+		r.mutex.Lock()
+		r.values = append(r.values, v)
+		r.mutex.Unlock()
+	}
+	return out
+}
+
+// match returns true if values of type t are tracked.
+func (r *resources) match(t reflect.Type) bool {
+	for _, rt := range r.types {
+		if t == rt || rt.Kind() == reflect.Interface && t.Implements(rt) {
+			return true
+		}
+	}
+	return false
+}
+
+// closeResource closes the resource v, ignoring errors for already closed
+// resources.
+func closeResource(v reflect.Value) error {
+	switch r := v.Interface().(type) {
+	case interface{ Close() error }:
+		if err := r.Close(); err != nil && !errors.Is(err, os.ErrClosed) && !errors.Is(err, net.ErrClosed) {
+			return err
+		}
+	case interface{ Stop() bool }:
+		r.Stop()
+	case interface{ Stop() }:
+		r.Stop()
+	}
+	return nil
+}
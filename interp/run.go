@@ -6,9 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"go/constant"
+	"go/token"
+	"io"
+	"math"
 	"reflect"
 	"regexp"
 	"strings"
+	"sync/atomic"
 )
 
 // bltn type defines functions which run at CFG execution.
@@ -95,24 +99,21 @@ func init() {
 	complexType = reflect.ValueOf(complex(0, 0)).Type()
 }
 
+// run executes node n in a new frame whose ancestor is cf, or in the global
+// frame if cf is nil.
 func (interp *Interpreter) run(n *node, cf *frame) {
-	if n == nil {
-		return
-	}
-	var f *frame
 	if cf == nil {
-		f = interp.frame
-	} else {
-		f = newFrame(cf, len(n.types), interp.runid())
+		interp.runFrame(n, interp.frame.Load().forRun(interp.pkgRun))
+		return
 	}
-	interp.mutex.RLock()
-	c := reflect.ValueOf(interp.done)
-	interp.mutex.RUnlock()
-
-	f.mutex.Lock()
-	f.done = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: c}
-	f.mutex.Unlock()
+	interp.runFrame(n, newFrame(cf, len(n.types), interp.runid()))
+}
 
+// runFrame executes node n in frame f.
+func (interp *Interpreter) runFrame(n *node, f *frame) {
+	if n == nil {
+		return
+	}
 	for i, t := range n.types {
 		f.data[i] = reflect.New(t).Elem()
 	}
@@ -175,6 +176,7 @@ func originalExecNode(n *node, exec bltn) *node {
 //go:noinline
 func runCfgPanic(callHandle uintptr, o *node, err interface{}) {
 	o.interp.Panic(err)
+	o.interp.unwind(err, o)
 }
 
 // Functions set to run during execution of CFG.
@@ -185,6 +187,15 @@ func runCfgPanic(callHandle uintptr, o *node, err interface{}) {
 // same circumstances.
 var errAbortHandler = errors.New("net/http: abort Handler")
 
+// runtimeError is a run-time error detected by the interpreter, which
+// implements runtime.Error as the errors of the Go runtime.
+type runtimeError string
+
+func (e runtimeError) Error() string { return "runtime error: " + string(e) }
+func (e runtimeError) RuntimeError() {}
+
+var errNilDeref = runtimeError("invalid memory address or nil pointer dereference")
+
 // Functions set to run during execution of CFG.
 
 func panicFunc(s *scope) string {
@@ -212,29 +223,56 @@ func panicFunc(s *scope) string {
 // callHandle is just to show up in debug.Stack, see interp.FilterStack(), must be first arg
 func runCfg(callHandle uintptr, n *node, f *frame, funcNode, callNode *node) {
 	var exec bltn
+	if t := n.interp.tracer.Load(); t != nil && funcNode != nil && (funcNode.kind == funcDecl || funcNode.kind == funcLit) {
+		defer n.interp.traceCall(*t, f, funcNode)()
+	}
 	defer func() {
+		r := n.interp.restorePanic(recover())
+		if r != nil && n.interp.tracer.Load() != nil {
+			n.interp.tracePanic(n, exec, f, r)
+		}
+		// The frame is not locked during the deferred calls, which may
+		// reenter it, e.g. through closures.
 		f.mutex.Lock()
-		f.recovered = recover()
-		for _, val := range f.deferred {
-			val[0].Call(val[1:])
+		f.recovered = r
+		deferred := f.deferred
+		f.mutex.Unlock()
+		for _, val := range deferred {
+			runDeferred(f, val)
 		}
-		if f.recovered != nil {
+		f.mutex.Lock()
+		r = f.recovered
+		f.mutex.Unlock()
+		if r != nil {
 			oNode := originalExecNode(n, exec)
 			if oNode == nil {
 				oNode = n
 			}
 			// capture node that caused panic
 			handle := oNode.interp.addCall(oNode)
-			runCfgPanic(handle, oNode, f.recovered)
-			f.mutex.Unlock()
-			panic(f.recovered)
+			runCfgPanic(handle, oNode, r)
+			panic(r)
 		}
-		f.mutex.Unlock()
 	}()
 
+	if p := n.interp.profiler.Load(); p != nil && funcNode != nil {
+		defer p.exit(f, p.enter(f, funcNode))
+	}
+
 	dbg := n.interp.debugger
 	if dbg == nil {
-		for exec = n.exec; exec != nil && f.runid() == n.interp.runid(); {
+		if y := n.interp.yielder.Load(); y != nil {
+			yn := n
+			if funcNode != nil {
+				yn = funcNode
+			}
+			for exec = n.exec; exec != nil && f.runid() == n.interp.runid() && !f.run.cancelled(); {
+				y.step(f, yn)
+				exec = exec(f)
+			}
+			return
+		}
+		for exec = n.exec; exec != nil && f.runid() == n.interp.runid() && !f.run.cancelled(); {
 			exec = exec(f)
 		}
 		return
@@ -247,7 +285,7 @@ func runCfg(callHandle uintptr, n *node, f *frame, funcNode, callNode *node) {
 	dbg.enterCall(funcNode, callNode, f)
 	defer dbg.exitCall(funcNode, callNode, f)
 
-	for m, exec := n, n.exec; f.runid() == n.interp.runid(); {
+	for m, exec := n, n.exec; f.runid() == n.interp.runid() && !f.run.cancelled(); {
 		if dbg.exec(m, f) {
 			break
 		}
@@ -273,6 +311,20 @@ func runCfg(callHandle uintptr, n *node, f *frame, funcNode, callNode *node) {
 	}
 }
 
+// runDeferred runs the deferred call val of frame f. As in Go, a panic of
+// the call replaces the current one of the frame, if any, and does not
+// prevent the next deferred calls.
+func runDeferred(f *frame, val []reflect.Value) {
+	defer func() {
+		if r := recover(); r != nil {
+			f.mutex.Lock()
+			f.recovered = r
+			f.mutex.Unlock()
+		}
+	}()
+	val[0].Call(val[1:])
+}
+
 func stripReceiverFromArgs(signature string) (string, error) {
 	fields := receiverStripperRxp.FindStringSubmatch(signature)
 	if len(fields) < 5 {
@@ -334,7 +386,8 @@ func typeAssert(n *node, withResult, withOk bool) {
 				}
 				return next
 			}
-			if c0.typ.cat == valueT {
+			if c0.typ.cat == valueT || valf.Type() != valueInterfaceType {
+				// The input may be stored as an empty interface, e.g. interface{}.
 				valf = reflect.ValueOf(v)
 			}
 			if v.node.typ.id() == typID {
@@ -422,7 +475,13 @@ func typeAssert(n *node, withResult, withOk bool) {
 				}
 
 				if withResult {
-					value0(f).Set(genInterfaceWrapper(val.node, rtype)(f))
+					// Wrap the asserted value itself: its node may belong
+					// to another frame than f.
+					v := val.value
+					if w := interfaceWrapper(val.node, rtype); w != nil {
+						v = w(v, f)
+					}
+					value0(f).Set(v)
 				}
 				ok = true
 				return next
@@ -852,7 +911,7 @@ func deref(n *node) {
 	if n.fnext != nil {
 		fnext := getExec(n.fnext)
 		n.exec = func(f *frame) bltn {
-			r := value(f).Elem()
+			r := derefValue(value(f))
 			if r.Bool() {
 				getFrame(f, l).data[i] = r
 				return tnext
@@ -861,26 +920,56 @@ func deref(n *node) {
 		}
 	} else {
 		n.exec = func(f *frame) bltn {
-			getFrame(f, l).data[i] = value(f).Elem()
+			getFrame(f, l).data[i] = derefValue(value(f))
 			return tnext
 		}
 	}
 }
 
+// derefValue returns the value pointed to by v, or panics as the Go runtime
+// if v is a nil pointer.
+func derefValue(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		panic(errNilDeref)
+	}
+	return v.Elem()
+}
+
+// fieldValue returns the nested field of struct v at index, or panics as
+// the Go runtime if it is reached through a nil embedded pointer.
+func fieldValue(v reflect.Value, index []int) reflect.Value {
+	r, err := v.FieldByIndexErr(index)
+	if err != nil {
+		panic(errNilDeref)
+	}
+	return r
+}
+
 func _print(n *node) {
 	child := n.child[1:]
 	values := make([]func(*frame) reflect.Value, len(child))
 	for i, c := range child {
 		values[i] = genValue(c)
 	}
-	out := n.interp.stdout
+	render := n.interp.render
+	if sink := n.interp.logSink; sink != nil {
+		pos := n.interp.fset.Position(n.pos)
+		genBuiltinDeferWrapper(n, values, nil, func(args []reflect.Value) []reflect.Value {
+			sink(LogPrint, renderArgs(render, args), pos)
+			return nil
+		})
+		return
+	}
 
+	// The output is the first argument, to honor the output scope of the run.
+	values = append([]func(*frame) reflect.Value{n.interp.stdoutValue()}, values...)
 	genBuiltinDeferWrapper(n, values, nil, func(args []reflect.Value) []reflect.Value {
-		for i, value := range args {
+		out := args[0].Interface().(io.Writer)
+		for i, value := range args[1:] {
 			if i > 0 {
 				fmt.Fprintf(out, " ")
 			}
-			fmt.Fprintf(out, "%v", value)
+			fmt.Fprint(out, render(value))
 		}
 		return nil
 	})
@@ -892,14 +981,25 @@ func _println(n *node) {
 	for i, c := range child {
 		values[i] = genValue(c)
 	}
-	out := n.interp.stdout
+	render := n.interp.render
+	if sink := n.interp.logSink; sink != nil {
+		pos := n.interp.fset.Position(n.pos)
+		genBuiltinDeferWrapper(n, values, nil, func(args []reflect.Value) []reflect.Value {
+			sink(LogPrint, renderArgs(render, args), pos)
+			return nil
+		})
+		return
+	}
 
+	// The output is the first argument, to honor the output scope of the run.
+	values = append([]func(*frame) reflect.Value{n.interp.stdoutValue()}, values...)
 	genBuiltinDeferWrapper(n, values, nil, func(args []reflect.Value) []reflect.Value {
-		for i, value := range args {
+		out := args[0].Interface().(io.Writer)
+		for i, value := range args[1:] {
 			if i > 0 {
 				fmt.Fprintf(out, " ")
 			}
-			fmt.Fprintf(out, "%v", value)
+			fmt.Fprint(out, render(value))
 		}
 		fmt.Fprintln(out, "")
 		return nil
@@ -911,7 +1011,23 @@ func _recover(n *node) {
 	dest := genValue(n)
 
 	n.exec = func(f *frame) bltn {
-		if f.anc.recovered == nil {
+		af := f.anc
+		if af.recovered == nil && af.src != nil {
+			// The deferred function is a closure, whose ancestor is a
+			// clone of the frame where it was defined.
+			af = af.src
+		}
+		af.mutex.Lock()
+		r := af.recovered
+		if _, ok := r.(*YieldError); ok {
+			// An execution aborted by a yield hook can not be recovered.
+			r = nil
+		} else {
+			af.recovered = nil
+		}
+		af.mutex.Unlock()
+
+		if r == nil {
 			// TODO(mpl): maybe we don't need that special case, and we're just forgetting to unwrap the valueInterface somewhere else.
 			if isEmptyInterface(n.typ) {
 				return tnext
@@ -920,18 +1036,33 @@ func _recover(n *node) {
 			return tnext
 		}
 
+		v := recoveredValue(r)
 		if isEmptyInterface(n.typ) {
-			dest(f).Set(reflect.ValueOf(f.anc.recovered))
+			dest(f).Set(v)
 		} else {
-			dest(f).Set(reflect.ValueOf(valueInterface{n, reflect.ValueOf(f.anc.recovered)}))
+			dest(f).Set(reflect.ValueOf(valueInterface{n, v}))
 		}
-		f.anc.recovered = nil
 		return tnext
 	}
 }
 
+// recoveredValue returns the value of a recovered panic: the reflect.Value
+// given to panic by interpreted code, or the value of a runtime or binary
+// panic.
+func recoveredValue(r interface{}) reflect.Value {
+	if v, ok := r.(reflect.Value); ok {
+		return v
+	}
+	return reflect.ValueOf(r)
+}
+
 func _panic(n *node) {
 	value := genValue(n.child[1])
+	if c := n.child[1]; c.typ != nil && len(c.typ.method) > 0 {
+		// Preserve the interpreted type of the value, so it can be
+		// asserted to an interface after recover.
+		value = genValueInterface(c)
+	}
 
 	n.exec = func(f *frame) bltn {
 		panic(value(f))
@@ -942,7 +1073,9 @@ func genBuiltinDeferWrapper(n *node, in, out []func(*frame) reflect.Value, fn fu
 	next := getExec(n.tnext)
 
 	if n.anc.kind == deferStmt {
+		def := funcDef(n)
 		n.exec = func(f *frame) bltn {
+			checkDefer(def, f)
 			val := make([]reflect.Value, len(in)+1)
 			inTypes := make([]reflect.Type, len(in))
 			for i, v := range in {
@@ -984,7 +1117,6 @@ func genFunctionWrapper(n *node) func(*frame) reflect.Value {
 	if def, ok = n.val.(*node); !ok {
 		return genValueAsFunctionWrapper(n)
 	}
-	start := def.child[3].start
 	numRet := len(def.typ.ret)
 	var rcvr func(*frame) reflect.Value
 
@@ -992,6 +1124,7 @@ func genFunctionWrapper(n *node) func(*frame) reflect.Value {
 		rcvr = genValueRecv(n)
 	}
 	funcType := n.typ.TypeOf()
+	recvArg := isMethodExpr(n) // receiver is passed as first argument
 
 	value := genValue(n)
 	isDefer := false
@@ -1007,6 +1140,12 @@ func genFunctionWrapper(n *node) func(*frame) reflect.Value {
 			return v
 		}
 
+		// The method receiver is evaluated when the function value is built.
+		var rv reflect.Value
+		if rcvr != nil && !recvArg {
+			rv = copyRecv(def, rcvr(f))
+		}
+
 		return reflect.MakeFunc(funcType, func(in []reflect.Value) []reflect.Value {
 			// Allocate and init local frame. All values to be settable and addressable.
 			fr := newFrame(f, len(def.types), f.runid())
@@ -1015,28 +1154,15 @@ func genFunctionWrapper(n *node) func(*frame) reflect.Value {
 				d[i] = reflect.New(t).Elem()
 			}
 
-			if rcvr == nil {
+			switch {
+			case recvArg:
+				setRecv(d[numRet], in[0])
+				d, in = d[numRet+1:], in[1:]
+			case rcvr == nil:
 				d = d[numRet:]
-			} else {
+			default:
 				// Copy method receiver as first argument.
-				src, dest := rcvr(f), d[numRet]
-				sk, dk := src.Kind(), dest.Kind()
-				for {
-					vs, ok := src.Interface().(valueInterface)
-					if !ok {
-						break
-					}
-					src = vs.value
-					sk = src.Kind()
-				}
-				switch {
-				case sk == reflect.Ptr && dk != reflect.Ptr:
-					dest.Set(src.Elem())
-				case sk != reflect.Ptr && dk == reflect.Ptr:
-					dest.Set(src.Addr())
-				default:
-					dest.Set(src)
-				}
+				setRecv(d[numRet], rv)
 				d = d[numRet+1:]
 			}
 
@@ -1058,26 +1184,59 @@ func genFunctionWrapper(n *node) func(*frame) reflect.Value {
 			}
 
 			// Interpreter code execution.
+			defer n.interp.tunnelPanic()
 			callHandle := n.interp.addCall(n)
-			runCfg(callHandle, start, fr, def, n)
+			runCfg(callHandle, def.child[3].start, fr, def, n)
 
 			return fr.data[:numRet]
 		})
 	}
 }
 
+// setRecv sets the method receiver dest from src, adjusting for pointer or
+// value receivers.
+func setRecv(dest, src reflect.Value) {
+	for {
+		vs, ok := src.Interface().(valueInterface)
+		if !ok {
+			break
+		}
+		src = vs.value
+	}
+	sk, dk := src.Kind(), dest.Kind()
+	switch {
+	case sk == reflect.Ptr && dk != reflect.Ptr:
+		dest.Set(src.Elem())
+	case sk != reflect.Ptr && dk == reflect.Ptr:
+		dest.Set(src.Addr())
+	default:
+		dest.Set(src)
+	}
+}
+
 func genInterfaceWrapper(n *node, typ reflect.Type) func(*frame) reflect.Value {
 	value := genValue(n)
-	if typ == nil || typ.Kind() != reflect.Interface || typ.NumMethod() == 0 || n.typ.cat == valueT {
+	wrapper := interfaceWrapper(n, typ)
+	if wrapper == nil {
 		return value
 	}
+	return func(f *frame) reflect.Value { return wrapper(value(f), f) }
+}
+
+// interfaceWrapper returns a function which wraps a value of the type of n
+// into a binary value implementing the interface typ, or nil if no wrapping
+// is necessary.
+func interfaceWrapper(n *node, typ reflect.Type) func(reflect.Value, *frame) reflect.Value {
+	if typ == nil || typ.Kind() != reflect.Interface || typ.NumMethod() == 0 || n.typ.cat == valueT {
+		return nil
+	}
 	tc := n.typ.cat
 	if tc != structT {
 		// Always force wrapper generation for struct types, as they may contain
 		// embedded interface fields which require wrapping, even if reported as
 		// implementing typ by reflect.
 		if nt := n.typ.frameType(); nt != nil && nt.Implements(typ) {
-			return value
+			return nil
 		}
 	}
 
@@ -1086,7 +1245,12 @@ func genInterfaceWrapper(n *node, typ reflect.Type) func(*frame) reflect.Value {
 	// As the field name was generated with a prefixed first character (in order to avoid
 	// collisions with method names), this first character is ignored in comparisons.
 	wrap := getWrapper(n, typ)
-	mn := wrap.NumField() - 1
+	if wrap == nil {
+		// No wrapper is available, e.g. for an instance of a generic
+		// interface which was not extracted.
+		return nil
+	}
+	mn := wrapperMethods(wrap)
 	names := make([]string, mn)
 	methods := make([]*node, mn)
 	indexes := make([][]int, mn)
@@ -1099,8 +1263,17 @@ func genInterfaceWrapper(n *node, typ reflect.Type) func(*frame) reflect.Value {
 		}
 	}
 
-	return func(f *frame) reflect.Value {
-		v := value(f)
+	// The unexported methods of typ are provided by a binary value embedded in
+	// the interpreted one, set as the fallback of the wrapper.
+	fallback := wrapperFallback(wrap)
+	var fallbackIndex []int
+	if fallback > 0 {
+		if fallbackIndex = n.typ.embeddedImplementer(typ); fallbackIndex == nil {
+			return nil
+		}
+	}
+
+	return func(v reflect.Value, f *frame) reflect.Value {
 		if tc != structT && v.Type().Implements(typ) {
 			return v
 		}
@@ -1114,9 +1287,19 @@ func genInterfaceWrapper(n *node, typ reflect.Type) func(*frame) reflect.Value {
 		if vi, ok := v.Interface().(valueInterface); ok {
 			n2 = vi.node
 		}
+		rv := v // receiver of interpreted methods
 		v = getConcreteValue(v)
 		w := reflect.New(wrap).Elem()
 		w.Field(0).Set(v)
+		if fallback > 0 {
+			fv := v
+			if fv.Kind() == reflect.Ptr {
+				fv = fv.Elem()
+			}
+			if fv, ok := fieldByIndex(fv, fallbackIndex); ok {
+				w.Field(fallback).Set(fv)
+			}
+		}
 		for i, m := range methods {
 			if m == nil {
 				// First direct method lookup on field.
@@ -1131,14 +1314,14 @@ func genInterfaceWrapper(n *node, typ reflect.Type) func(*frame) reflect.Value {
 				m2, i2 := n2.typ.lookupMethod(names[i])
 				if m2 != nil {
 					nod := *m2
-					nod.recv = &receiver{n, v, i2}
+					nod.recv = &receiver{nil, rv, i2}
 					w.Field(i + 1).Set(genFunctionWrapper(&nod)(f))
 					continue
 				}
 				panic(n.cfgErrorf("method not found: %s", names[i]))
 			}
 			nod := *m
-			nod.recv = &receiver{n, v, indexes[i]}
+			nod.recv = &receiver{nil, rv, indexes[i]}
 			w.Field(i + 1).Set(genFunctionWrapper(&nod)(f))
 		}
 		return w
@@ -1296,7 +1479,9 @@ func call(n *node) {
 	if n.anc.kind == deferStmt {
 		// Store function call in frame for deferred execution.
 		value = genFunctionWrapper(c0)
+		def := funcDef(n)
 		n.exec = func(f *frame) bltn {
+			checkDefer(def, f)
 			val := make([]reflect.Value, len(values)+1)
 			val[0] = value(f)
 			for i, v := range values {
@@ -1308,12 +1493,27 @@ func call(n *node) {
 		return
 	}
 
+	if def := funcDef(n); isTailCall(n, def) {
+		tailCall(n, def, values)
+		return
+	}
+	if fwd := forwardValue(n, value); fwd != nil {
+		value = fwd
+	}
+
 	n.exec = func(f *frame) bltn {
 		f.mutex.Lock()
 		bf := value(f)
-		def, ok := bf.Interface().(*node)
-		if ok {
+		var def *node
+		var bm *boundMethod
+		switch v := bf.Interface().(type) {
+		case *node:
+			def = v
 			bf = def.rval
+		case *boundMethod:
+			bm = v
+			def = bm.method
+			bf = reflect.Value{}
 		}
 		f.mutex.Unlock()
 
@@ -1339,6 +1539,8 @@ func call(n *node) {
 					in[i].Set(value)
 				}
 
+				n.interp.traceGo(n, f)
+				f.run.usage().startGo()
 				go callf(in)
 				return tnext
 			}
@@ -1359,7 +1561,26 @@ func call(n *node) {
 			return tnext
 		}
 
-		nf := newFrame(f, len(def.types), f.runid())
+		// Reuse a frame if possible.
+		frames := def.frames
+		if goroutine || n.interp.debugger != nil {
+			frames = nil
+		}
+		var pf *pooledFrame
+		var nf *frame
+		if frames != nil {
+			pf = frames.get(f, f.runid())
+			nf = pf.frame
+		} else {
+			nf = newFrame(f, len(def.types), f.runid())
+		}
+		nf.depth = f.depth + 1
+		nf.call = n
+		if !goroutine {
+			nf.prof = f.prof
+		}
+		nf.run.usage().enter(nf.depth)
+		checkCallDepth(def, nf)
 		var vararg reflect.Value
 
 		// Init return values
@@ -1371,18 +1592,27 @@ func call(n *node) {
 			}
 		}
 
-		// Init local frame values
+		// Init local frame values, except the reused ones
 		for i, t := range def.types[numRet:] {
-			nf.data[numRet+i] = reflect.New(t).Elem()
+			if !nf.data[numRet+i].IsValid() {
+				nf.data[numRet+i] = reflect.New(t).Elem()
+			}
+		}
+
+		// Copy method receiver, followed by input parameters
+		in := numRet
+		if bm != nil {
+			setRecv(nf.data[in], bm.rcvr)
+			in++
 		}
 
 		// Init variadic argument vector
 		if variadic >= 0 {
-			vararg = nf.data[numRet+variadic]
+			vararg = nf.data[in+variadic]
 		}
 
 		// Copy input parameters from caller
-		if dest := nf.data[numRet:]; len(dest) > 0 {
+		if dest := nf.data[in:]; len(dest) > 0 {
 			for i, v := range values {
 				switch {
 				case variadic >= 0 && i >= variadic:
@@ -1417,6 +1647,9 @@ func call(n *node) {
 
 		// Execute function body
 		if goroutine {
+			n.interp.traceGo(n, f)
+			f.run.usage().startGo()
+			nf.run = nf.run.fork()
 			go runCfg(callHandle, def.child[3].start, nf, def, n)
 			return tnext
 		}
@@ -1430,10 +1663,53 @@ func call(n *node) {
 		}
 
 		// Handle branching according to boolean result
+		next := tnext
 		if fnext != nil && !nf.data[0].Bool() {
-			return fnext
+			next = fnext
 		}
-		return tnext
+		if pf != nil {
+			frames.put(pf)
+		}
+		return next
+	}
+}
+
+// tailCall generates the exec of the tail call n of function def to itself,
+// where values are the input argument value functions. The frame of the
+// current call is reset and reused to execute the body of def again.
+func tailCall(n, def *node, values []func(*frame) reflect.Value) {
+	// The body and types are the ones at compilation, in case the function
+	// is modified by ReloadPath while running.
+	start, types := def.child[3].start, def.types
+	numRet := len(def.typ.ret)
+
+	n.exec = func(f *frame) bltn {
+		in := make([]reflect.Value, len(values))
+		for i, v := range values {
+			in[i] = v(f)
+		}
+
+		// Reset return and local values.
+		for i := 0; i < numRet; i++ {
+			f.data[i].SetZero()
+		}
+		for i, t := range types[numRet:] {
+			f.data[numRet+i] = reflect.New(t).Elem()
+		}
+
+		// Copy input parameters.
+		dest := f.data[numRet:]
+		for i, v := range in {
+			if v.IsZero() && dest[i].Kind() != reflect.Interface {
+				continue
+			}
+			if nod, ok := v.Interface().(*node); ok && nod.recv != nil {
+				dest[i] = genFunctionWrapper(nod)(f)
+				continue
+			}
+			dest[i].Set(v)
+		}
+		return start.exec
 	}
 }
 
@@ -1462,6 +1738,11 @@ func callBin(n *node) {
 	child := n.child[1:]
 	c0 := n.child[0]
 	value := genValue(c0)
+	if gen, ok := n.interp.logFuncs[c0.rval]; ok {
+		// Bind the position of the call to the messages sent to the log sink.
+		v := gen(n.interp.fset.Position(n.pos))
+		value = func(*frame) reflect.Value { return v }
+	}
 	var values []func(*frame) reflect.Value
 	funcType := c0.typ.rtype
 	wt := wrappedType(c0)
@@ -1494,13 +1775,14 @@ func callBin(n *node) {
 	// Determine if we should use `Call` or `CallSlice` on the function Value.
 	// callHandle is to identify this call in debug stacktrace, see interp.FilterStack(). Must be first arg.
 	callFn := func(callHandle uintptr, v reflect.Value, in []reflect.Value) []reflect.Value {
-		return v.Call(in)
+		return n.track(v.Call(in))
 	}
 	if n.action == aCallSlice {
 		callFn = func(callHandle uintptr, v reflect.Value, in []reflect.Value) []reflect.Value {
-			return v.CallSlice(in)
+			return n.track(v.CallSlice(in))
 		}
 	}
+	callFn = interceptCall(n, callFn)
 
 	for i, c := range child {
 		switch {
@@ -1541,6 +1823,10 @@ func callBin(n *node) {
 			var defType reflect.Type
 			if variadic >= 0 && i+rcvrOffset >= variadic {
 				defType = funcType.In(variadic)
+				if n.action != aCallSlice {
+					// Variadic arguments are passed as elements.
+					defType = defType.Elem()
+				}
 			} else {
 				defType = funcType.In(rcvrOffset + i)
 			}
@@ -1581,9 +1867,11 @@ func callBin(n *node) {
 	switch {
 	case n.anc.kind == deferStmt:
 		// Store function call in frame for deferred execution.
+		def := funcDef(n)
 		n.exec = func(f *frame) bltn {
+			checkDefer(def, f)
 			val := make([]reflect.Value, l+1)
-			val[0] = value(f)
+			val[0] = interceptDeferred(n, value(f))
 			for i, v := range values {
 				val[i+1] = getBinValue(getMapType, v, f)
 			}
@@ -1597,6 +1885,8 @@ func callBin(n *node) {
 			for i, v := range values {
 				in[i] = getBinValue(getMapType, v, f)
 			}
+			n.interp.traceGo(n, f)
+			f.run.usage().startGo()
 			go callFn(handle, value(f), in)
 			return tnext
 		}
@@ -1799,6 +2089,9 @@ func getIndexArray(n *node) {
 
 // getIndexMap retrieves map value from index.
 func getIndexMap(n *node) {
+	if getIndexMapFast(n) {
+		return
+	}
 	dest := genValue(n)
 	value0 := genValue(n.child[0]) // map
 	tnext := getExec(n.tnext)
@@ -1951,6 +2244,7 @@ func getFunc(n *node) {
 			}
 
 			// Interpreter code execution.
+			defer n.interp.tunnelPanic()
 			callHandle := n.interp.addCall(n)
 			runCfg(callHandle, n.child[3].start, fr2, n, n)
 
@@ -1974,6 +2268,16 @@ func getMethod(n *node) {
 	l := n.level
 	next := getExec(n.tnext)
 
+	if isDirectCallee(n) {
+		m := n.val.(*node)
+		rcvr := genValueRecv(n)
+		n.exec = func(f *frame) bltn {
+			getFrame(f, l).data[i] = reflect.ValueOf(&boundMethod{method: m, rcvr: rcvr(f)})
+			return next
+		}
+		return
+	}
+
 	n.exec = func(f *frame) bltn {
 		nod := *(n.val.(*node))
 		nod.val = &nod
@@ -1990,6 +2294,15 @@ func getMethodByName(n *node) {
 	i := n.findex
 	l := n.level
 
+	// Inline cache of the method lookup, for the last dynamic type of the receiver.
+	var cache atomic.Pointer[methodCache]
+
+	// A method called directly is bound without a function wrapper.
+	bind := bindMethod
+	if isDirectCallee(n) {
+		bind = bindMethodCall
+	}
+
 	n.exec = func(f *frame) bltn {
 		// The interface object must be directly accessible, or embedded in a struct (exported anonymous field).
 		val0 := value0(f)
@@ -2023,8 +2336,24 @@ func getMethodByName(n *node) {
 			val = v
 		}
 
-		if met := val.value.MethodByName(name); met.IsValid() {
-			getFrame(f, l).data[i] = met
+		rtype := val.value.Type()
+		var vtyp *itype
+		if val.node != nil {
+			vtyp = val.node.typ
+		}
+		gen := n.interp.methods.Load()
+		if c := cache.Load(); c != nil && c.rtype == rtype && c.typ == vtyp && c.gen == gen {
+			if c.index >= 0 {
+				getFrame(f, l).data[i] = val.value.Method(c.index)
+			} else {
+				getFrame(f, l).data[i] = bind(f, c.method, val.value, c.path)
+			}
+			return next
+		}
+
+		if m, ok := rtype.MethodByName(name); ok {
+			cache.Store(&methodCache{rtype: rtype, typ: vtyp, gen: gen, index: m.Index})
+			getFrame(f, l).data[i] = val.value.Method(m.Index)
 			return next
 		}
 
@@ -2038,6 +2367,12 @@ func getMethodByName(n *node) {
 			return next
 		}
 
+		if m, li := typ.lookupMethod(name); m != nil {
+			cache.Store(&methodCache{rtype: rtype, typ: vtyp, gen: gen, index: -1, method: m, path: li})
+			getFrame(f, l).data[i] = bind(f, m, val.value, li)
+			return next
+		}
+
 		// Finally search method recursively in embedded valueInterfaces.
 		r, m, li := lookupMethodValue(val, name)
 		if r.IsValid() {
@@ -2047,15 +2382,185 @@ func getMethodByName(n *node) {
 		if m == nil {
 			panic(n.cfgErrorf("method not found: %s", name))
 		}
+		getFrame(f, l).data[i] = bind(f, m, val.value, li)
+		return next
+	}
+}
 
-		nod := *m
-		nod.val = &nod
-		nod.recv = &receiver{nil, val.value, li}
-		getFrame(f, l).data[i] = genFuncValue(&nod)(f)
+// methodCache is an inline cache entry of a method lookup on an interface
+// value, for a given dynamic type of the receiver. The entry is valid until
+// methods are (re)declared in the interpreter.
+type methodCache struct {
+	rtype  reflect.Type // runtime type of the receiver
+	typ    *itype       // interpreter type of the receiver
+	gen    uint64       // generation of interpreter methods at lookup
+	index  int          // index of the runtime method, or -1 for an interpreter method
+	method *node        // interpreter method definition, if index < 0
+	path   []int        // embedded field indexes leading to the method receiver
+}
+
+// bindMethod returns the method value of the interpreter method m for the
+// receiver rcvr, where path is the list of embedded field indexes leading to
+// the method receiver.
+func bindMethod(f *frame, m *node, rcvr reflect.Value, path []int) reflect.Value {
+	nod := *m
+	nod.val = &nod
+	nod.recv = &receiver{nil, rcvr, path}
+	return genFuncValue(&nod)(f)
+}
+
+// copyRecv returns a copy of the receiver r of the interpreter method m if m
+// has a value receiver, so the bound method is not affected by later changes
+// of r. A pointer to a value receiver is dereferenced.
+func copyRecv(m *node, r reflect.Value) reflect.Value {
+	if rtn := m.child[0].child[0].lastChild(); !r.IsValid() || rtn != nil && rtn.kind == starExpr {
+		return r
+	}
+	if vi, ok := r.Interface().(valueInterface); ok {
+		r = vi.value
+	}
+	if r.Kind() == reflect.Ptr {
+		if r.IsNil() {
+			panic(errNilDeref)
+		}
+		r = r.Elem()
+	}
+	c := reflect.New(r.Type()).Elem()
+	c.Set(r)
+	return c
+}
+
+// boundMethod is an interpreter method bound to its receiver. It is produced
+// by method selectors which are directly called, and run by call without
+// building a function wrapper.
+type boundMethod struct {
+	method *node         // interpreter method definition
+	rcvr   reflect.Value // method receiver
+}
+
+// bindMethodCall returns the interpreter method m bound to the receiver rcvr,
+// where path is the list of embedded field indexes leading to the method
+// receiver, to be run by call.
+func bindMethodCall(f *frame, m *node, rcvr reflect.Value, path []int) reflect.Value {
+	return reflect.ValueOf(&boundMethod{method: m, rcvr: fieldRecv(rcvr, path)})
+}
+
+// isDirectCallee returns true if the method selector n is the function of a
+// call node, executed in place (not deferred nor in a goroutine).
+func isDirectCallee(n *node) bool {
+	c := n.anc
+	if c == nil || c.kind != callExpr || c.child[0] != n || n.typ.cat != funcT {
+		return false
+	}
+	if c.action != aCall && c.action != aCallSlice {
+		return false
+	}
+	k := c.anc.kind
+	return k != deferStmt && k != goStmt
+}
+
+// methodExpr sets the function value of the method expression n, of an
+// interpreter method promoted from an embedded field or of an interpreter
+// interface, which binds the method to its receiver at each call.
+func methodExpr(n *node) {
+	next := getExec(n.tnext)
+	i, l := n.findex, n.level
+	name := n.child[1].ident
+	ft := n.typ.TypeOf()
+	m, _ := n.val.(*node)
+	var path []int
+	if n.recv != nil {
+		path = n.recv.index
+	}
+
+	n.exec = func(f *frame) bltn {
+		getFrame(f, l).data[i] = reflect.MakeFunc(ft, func(in []reflect.Value) []reflect.Value {
+			var mv reflect.Value
+			if m != nil {
+				mv = bindMethod(f, m, in[0], path)
+			} else {
+				mv = interfaceMethodValue(f, n, in[0], name)
+			}
+			return callMethodValue(mv, in[1:], ft.IsVariadic())
+		})
 		return next
 	}
 }
 
+// interfaceMethodValue returns the method of the given name of the dynamic
+// value of the interpreter interface value v, bound to it.
+func interfaceMethodValue(f *frame, n *node, v reflect.Value, name string) reflect.Value {
+	val, ok := v.Interface().(valueInterface)
+	if !ok {
+		if r := v.MethodByName(name); r.IsValid() {
+			return r
+		}
+		panic(n.cfgErrorf("method not found: %s", name))
+	}
+	for {
+		v, ok := val.value.Interface().(valueInterface)
+		if !ok {
+			break
+		}
+		val = v
+	}
+	if !val.value.IsValid() {
+		panic(errNilDeref)
+	}
+	if r := val.value.MethodByName(name); r.IsValid() {
+		return r
+	}
+	if val.node != nil {
+		if m, li := val.node.typ.lookupMethod(name); m != nil {
+			return bindMethod(f, m, val.value, li)
+		}
+	}
+	r, m, li := lookupMethodValue(val, name)
+	if r.IsValid() {
+		return r
+	}
+	if m == nil {
+		panic(n.cfgErrorf("method not found: %s", name))
+	}
+	return bindMethod(f, m, val.value, li)
+}
+
+// interfaceMethodExpr returns the function value of type ft of the method
+// expression of the method m of a binary interface.
+func interfaceMethodExpr(ft reflect.Type, m reflect.Method) reflect.Value {
+	return reflect.MakeFunc(ft, func(in []reflect.Value) []reflect.Value {
+		return callMethodValue(in[0].Method(m.Index), in[1:], ft.IsVariadic())
+	})
+}
+
+// promotedMethodExpr returns the function value of type ft of the method
+// expression of the binary method name, promoted from the embedded field at
+// path of the receiver. If isPtr is true, the method has a pointer receiver.
+func promotedMethodExpr(ft reflect.Type, name string, path []int, isPtr bool) reflect.Value {
+	return reflect.MakeFunc(ft, func(in []reflect.Value) []reflect.Value {
+		r := fieldRecv(in[0], path)
+		if isPtr && r.Kind() != reflect.Ptr {
+			if r.CanAddr() {
+				r = r.Addr()
+			} else {
+				p := reflect.New(r.Type())
+				p.Elem().Set(r)
+				r = p
+			}
+		}
+		return callMethodValue(r.MethodByName(name), in[1:], ft.IsVariadic())
+	})
+}
+
+// callMethodValue calls the method value mv with the arguments in, where the
+// last one holds the variadic arguments if variadic is true.
+func callMethodValue(mv reflect.Value, in []reflect.Value, variadic bool) []reflect.Value {
+	if variadic {
+		return mv.CallSlice(in)
+	}
+	return mv.Call(in)
+}
+
 // lookupMethodValue recursively looks within val for the method with the given
 // name. If a runtime value is found, it is returned in r, otherwise it is returned
 // in m, with li as the list of recursive field indexes.
@@ -2108,7 +2613,7 @@ func getIndexSeq(n *node) {
 		fnext := getExec(n.fnext)
 		n.exec = func(f *frame) bltn {
 			v := value(f)
-			r := v.FieldByIndex(index)
+			r := fieldValue(v, index)
 			getFrame(f, l).data[i] = r
 			if r.Bool() {
 				return tnext
@@ -2118,7 +2623,7 @@ func getIndexSeq(n *node) {
 	} else {
 		n.exec = func(f *frame) bltn {
 			v := value(f)
-			getFrame(f, l).data[i] = v.FieldByIndex(index)
+			getFrame(f, l).data[i] = fieldValue(v, index)
 			return tnext
 		}
 	}
@@ -2134,7 +2639,7 @@ func getPtrIndexSeq(n *node) {
 	if n.fnext != nil {
 		fnext := getExec(n.fnext)
 		n.exec = func(f *frame) bltn {
-			r := value(f).Elem().FieldByIndex(index)
+			r := fieldValue(derefValue(value(f)), index)
 			getFrame(f, l).data[i] = r
 			if r.Bool() {
 				return tnext
@@ -2143,7 +2648,7 @@ func getPtrIndexSeq(n *node) {
 		}
 	} else {
 		n.exec = func(f *frame) bltn {
-			getFrame(f, l).data[i] = value(f).Elem().FieldByIndex(index)
+			getFrame(f, l).data[i] = fieldValue(derefValue(value(f)), index)
 			return tnext
 		}
 	}
@@ -2160,7 +2665,7 @@ func getIndexSeqField(n *node) {
 		fnext := getExec(n.fnext)
 		if n.child[0].typ.TypeOf().Kind() == reflect.Ptr {
 			n.exec = func(f *frame) bltn {
-				r := value(f).Elem().FieldByIndex(index)
+				r := fieldValue(derefValue(value(f)), index)
 				getFrame(f, l).data[i] = r
 				if r.Bool() {
 					return tnext
@@ -2169,7 +2674,7 @@ func getIndexSeqField(n *node) {
 			}
 		} else {
 			n.exec = func(f *frame) bltn {
-				r := value(f).FieldByIndex(index)
+				r := fieldValue(value(f), index)
 				getFrame(f, l).data[i] = r
 				if r.Bool() {
 					return tnext
@@ -2180,12 +2685,12 @@ func getIndexSeqField(n *node) {
 	} else {
 		if n.child[0].typ.TypeOf().Kind() == reflect.Ptr {
 			n.exec = func(f *frame) bltn {
-				getFrame(f, l).data[i] = value(f).Elem().FieldByIndex(index)
+				getFrame(f, l).data[i] = fieldValue(derefValue(value(f)), index)
 				return tnext
 			}
 		} else {
 			n.exec = func(f *frame) bltn {
-				getFrame(f, l).data[i] = value(f).FieldByIndex(index)
+				getFrame(f, l).data[i] = fieldValue(value(f), index)
 				return tnext
 			}
 		}
@@ -2209,7 +2714,7 @@ func getIndexSeqPtrMethod(n *node) {
 			}
 		} else {
 			n.exec = func(f *frame) bltn {
-				getFrame(f, l).data[i] = value(f).Elem().FieldByIndex(fi).Addr().Method(mi)
+				getFrame(f, l).data[i] = fieldValue(derefValue(value(f)), fi).Addr().Method(mi)
 				return next
 			}
 		}
@@ -2221,7 +2726,7 @@ func getIndexSeqPtrMethod(n *node) {
 			}
 		} else {
 			n.exec = func(f *frame) bltn {
-				getFrame(f, l).data[i] = value(f).FieldByIndex(fi).Addr().Method(mi)
+				getFrame(f, l).data[i] = fieldValue(value(f), fi).Addr().Method(mi)
 				return next
 			}
 		}
@@ -2240,12 +2745,12 @@ func getIndexSeqMethod(n *node) {
 	if n.child[0].typ.TypeOf().Kind() == reflect.Ptr {
 		if len(fi) == 0 {
 			n.exec = func(f *frame) bltn {
-				getFrame(f, l).data[i] = value(f).Elem().Method(mi)
+				getFrame(f, l).data[i] = derefValue(value(f)).Method(mi)
 				return next
 			}
 		} else {
 			n.exec = func(f *frame) bltn {
-				getFrame(f, l).data[i] = value(f).Elem().FieldByIndex(fi).Method(mi)
+				getFrame(f, l).data[i] = fieldValue(derefValue(value(f)), fi).Method(mi)
 				return next
 			}
 		}
@@ -2257,7 +2762,7 @@ func getIndexSeqMethod(n *node) {
 			}
 		} else {
 			n.exec = func(f *frame) bltn {
-				getFrame(f, l).data[i] = value(f).FieldByIndex(fi).Method(mi)
+				getFrame(f, l).data[i] = fieldValue(value(f), fi).Method(mi)
 				return next
 			}
 		}
@@ -2909,26 +3414,164 @@ func rangeInt(n *node) {
 	index2 := index0 - 1 // max
 	fnext := getExec(n.fnext)
 	tnext := getExec(n.tnext)
+	typ := ixn.typ.TypeOf()
 
-	var value func(*frame) reflect.Value
 	mxn := n.child[1]
-	value = genValue(mxn)
+	convertLiteralValue(mxn, typ)
+	value := genValue(mxn)
+
+	if isUint(typ) {
+		n.exec = func(f *frame) bltn {
+			rv := f.data[index0]
+			rv.SetUint(rv.Uint() + 1)
+			if rv.Uint() >= f.data[index2].Uint() {
+				return fnext
+			}
+			return tnext
+		}
+
+		// Init sequence
+		ixn.exec = func(f *frame) bltn {
+			f.data[index2].Set(value(f)) // set max
+			f.data[index0].SetUint(0)    // assign index value
+			if f.data[index2].Uint() == 0 {
+				return fnext
+			}
+			return tnext
+		}
+		return
+	}
+
 	n.exec = func(f *frame) bltn {
 		rv := f.data[index0]
 		rv.SetInt(rv.Int() + 1)
-		if int(rv.Int()) >= int(f.data[index2].Int()) {
+		if rv.Int() >= f.data[index2].Int() {
 			return fnext
 		}
 		return tnext
 	}
 
 	// Init sequence
-	next := n.exec
-	index := index0
 	ixn.exec = func(f *frame) bltn {
-		f.data[index2] = value(f) // set max
-		f.data[index].SetInt(-1)  // assing index value
-		return next
+		f.data[index2].Set(value(f)) // set max
+		f.data[index0].SetInt(0)     // assign index value
+		if f.data[index2].Int() <= 0 {
+			return fnext
+		}
+		return tnext
+	}
+}
+
+// rangeFuncState holds the state of a range over function loop during the
+// call of its iterator function.
+type rangeFuncState struct {
+	cont  bool  // the loop body completed or continued
+	done  bool  // the loop body exited, yield must not be called again
+	exit  bltn  // target of a branch statement out of the loop body
+	outer []int // frame indexes of the states of enclosing loops to exit
+}
+
+var errRangeFuncDone = errors.New("runtime error: range function continued iteration after function for loop body returned false")
+
+func rangeFunc(n *node) {
+	ixn := n.child[0]
+	index0 := ixn.findex // key location in frame
+	index2 := index0 - 1 // iterator state, always just behind index0
+	index1 := -1         // value location in frame
+	ktyp, vtyp := ixn.typ, (*itype)(nil)
+	if len(n.child) == 4 && n.child[1].ident != "_" {
+		index1 = n.child[1].findex
+		vtyp = n.child[1].typ
+	}
+	fnext := getExec(n.fnext)
+	tnext := getExec(n.tnext)
+	value := genFunctionWrapper(n.child[len(n.child)-2])
+
+	// End of loop body or continue: return from yield to the iterator.
+	n.exec = func(f *frame) bltn {
+		f.data[index2].Interface().(*rangeFuncState).cont = true
+		return nil
+	}
+
+	// Init sequence: call the iterator with a yield function running the loop body.
+	ixn.exec = func(f *frame) bltn {
+		st := &rangeFuncState{}
+		f.data[index2].Set(reflect.ValueOf(st))
+		fv := value(f)
+		ytyp := fv.Type().In(0)
+		yield := reflect.MakeFunc(ytyp, func(in []reflect.Value) []reflect.Value {
+			if st.done {
+				panic(errRangeFuncDone)
+			}
+			if len(in) > 0 {
+				setRangeVar(f.data[index0], ktyp, in[0])
+			}
+			if index1 >= 0 {
+				setRangeVar(f.data[index1], vtyp, in[1])
+			}
+			st.cont = false
+			for exec := tnext; exec != nil && f.runid() == n.interp.runid() && !f.run.cancelled(); {
+				exec = exec(f)
+			}
+			st.done = !st.cont
+			return []reflect.Value{reflect.ValueOf(st.cont).Convert(ytyp.Out(0))}
+		})
+		fv.Call([]reflect.Value{yield})
+
+		exited := st.done
+		st.done = true
+		switch {
+		case !exited:
+			return fnext
+		case st.exit == nil:
+			// Return statement in loop body, or execution cancelled.
+			return nil
+		case len(st.outer) > 0:
+			o := f.data[st.outer[0]].Interface().(*rangeFuncState)
+			o.exit, o.outer = st.exit, st.outer[1:]
+			return nil
+		}
+		return st.exit
+	}
+}
+
+// setRangeVar sets the range variable dest of type t from the iterator value v.
+func setRangeVar(dest reflect.Value, t *itype, v reflect.Value) {
+	switch {
+	case isEmptyInterface(t) || t.TypeOf() == valueInterfaceType:
+		dest.Set(v)
+	case isInterfaceSrc(t):
+		dest.Set(reflect.ValueOf(valueInterface{value: v.Elem()}))
+	default:
+		dest.Set(v)
+	}
+}
+
+// rangeFuncBranch generates a break, continue or goto statement within the
+// body of a range over function loop. If the statement exits the body of one
+// or more such loops, their iterators must return before reaching the target.
+func rangeFuncBranch(n *node) {
+	var loops []int // frame indexes of the states of exited loops, innermost first
+	for a := n.anc; a != nil && a.kind != funcDecl && a.kind != funcLit; a = a.anc {
+		if !isRangeFunc(a) {
+			continue
+		}
+		if n.tnext == a || n.tnext.hasAnc(a.lastChild()) {
+			// Continue of the loop, or target within its body.
+			break
+		}
+		loops = append(loops, a.child[0].findex-1)
+	}
+	next := getExec(n.tnext)
+
+	if len(loops) == 0 {
+		n.exec = func(f *frame) bltn { return next }
+		return
+	}
+	n.exec = func(f *frame) bltn {
+		st := f.data[loops[0]].Interface().(*rangeFuncState)
+		st.exit, st.outer = next, loops[1:]
+		return nil
 	}
 }
 
@@ -2968,19 +3611,46 @@ func loopVarFor(n *node) {
 	}
 }
 
-func rangeChan(n *node) {
+// loopVarForNext copies the per-iteration variable of a for statement back
+// to the loop variable, at the end of the loop body or on continue.
+func loopVarForNext(n *node) {
+	lv := n.child[0]
+	ixn := n.anc.child[0].child[0]
+	next := getExec(n.tnext)
+	n.exec = func(f *frame) bltn {
+		f.data[ixn.findex].Set(f.data[lv.findex])
+		return next
+	}
+}
+
+// rangeChanFast iterates over a channel, blocking without being cancellable.
+// See chanGen.
+func rangeChanFast(n *node) {
 	i := n.child[0].findex        // element index location in frame
 	value := genValue(n.child[1]) // chan
 	fnext := getExec(n.fnext)
 	tnext := getExec(n.tnext)
 
 	n.exec = func(f *frame) bltn {
-		f.mutex.RLock()
-		done := f.done
-		f.mutex.RUnlock()
+		v, ok := value(f).Recv()
+		if !ok {
+			return fnext
+		}
+		f.data[i].Set(v)
+		return tnext
+	}
+}
 
-		chosen, v, ok := reflect.Select([]reflect.SelectCase{done, {Dir: reflect.SelectRecv, Chan: value(f)}})
-		if chosen == 0 {
+// rangeChan iterates over a channel, cancellable by the run of the frame.
+func rangeChan(n *node) {
+	i := n.child[0].findex        // element index location in frame
+	value := genValue(n.child[1]) // chan
+	fnext := getExec(n.fnext)
+	tnext := getExec(n.tnext)
+
+	n.exec = func(f *frame) bltn {
+		v, ok, notCancelled := recvCancellable(f, value(f))
+		if !notCancelled {
 			return nil
 		}
 		if !ok {
@@ -3321,6 +3991,9 @@ func _append(n *node) {
 			value0 = genValue(n.child[2])
 		}
 
+		if appendFast(n, dest, value, value0) {
+			return
+		}
 		n.exec = func(f *frame) bltn {
 			dest(f).Set(reflect.Append(value(f), value0(f)))
 			return next
@@ -3347,6 +4020,9 @@ func _cap(n *node) {
 }
 
 func _copy(n *node) {
+	if copyFast(n) {
+		return
+	}
 	in := []func(*frame) reflect.Value{genValueArray(n.child[1]), genValue(n.child[2])}
 	out := []func(*frame) reflect.Value{genValueOutput(n, reflect.TypeOf(0))}
 
@@ -3356,6 +4032,15 @@ func _copy(n *node) {
 	})
 }
 
+func _clear(n *node) {
+	in := []func(*frame) reflect.Value{genValue(n.child[1])}
+
+	genBuiltinDeferWrapper(n, in, nil, func(args []reflect.Value) []reflect.Value {
+		args[0].Clear()
+		return nil
+	})
+}
+
 func _close(n *node) {
 	in := []func(*frame) reflect.Value{genValue(n.child[1])}
 
@@ -3434,7 +4119,71 @@ func _real(n *node) {
 	}
 }
 
+func _max(n *node) { minMax(n, false) }
+func _min(n *node) { minMax(n, true) }
+
+func minMax(n *node, isMin bool) {
+	typ := n.typ.concrete().TypeOf()
+	dest := genValueOutput(n, typ)
+	next := getExec(n.tnext)
+	values := make([]func(*frame) reflect.Value, len(n.child)-1)
+	for i, c := range n.child[1:] {
+		convertLiteralValue(c, typ)
+		values[i] = genValue(c)
+	}
+
+	// better returns true if x replaces r as the current result.
+	var better func(x, r reflect.Value) bool
+	switch {
+	case isString(typ):
+		better = func(x, r reflect.Value) bool {
+			if isMin {
+				return x.String() < r.String()
+			}
+			return x.String() > r.String()
+		}
+	case isFloat(typ):
+		// Rely on Go semantics for NaN and signed zero values.
+		m := func(a, b float64) float64 { return max(a, b) }
+		if isMin {
+			m = func(a, b float64) float64 { return min(a, b) }
+		}
+		better = func(x, r reflect.Value) bool {
+			a, b := x.Float(), r.Float()
+			return !math.IsNaN(b) && math.Float64bits(m(a, b)) != math.Float64bits(b)
+		}
+	case isUint(typ):
+		better = func(x, r reflect.Value) bool {
+			if isMin {
+				return x.Uint() < r.Uint()
+			}
+			return x.Uint() > r.Uint()
+		}
+	default:
+		better = func(x, r reflect.Value) bool {
+			if isMin {
+				return x.Int() < r.Int()
+			}
+			return x.Int() > r.Int()
+		}
+	}
+
+	n.exec = func(f *frame) bltn {
+		r := values[0](f)
+		for _, value := range values[1:] {
+			if x := value(f); better(x, r) {
+				r = x
+			}
+		}
+		dest(f).Set(r)
+		return next
+	}
+}
+
 func _delete(n *node) {
+	if deleteFast(n) {
+		return
+	}
 	value0 := genValue(n.child[1]) // map
 	value1 := genValue(n.child[2]) // key
 	in := []func(*frame) reflect.Value{value0, value1}
@@ -3606,123 +4355,143 @@ func reset(n *node) {
 	}
 }
 
-// recv reads from a channel.
-func recv(n *node) {
+// recvFast reads from a channel, blocking without being cancellable. See
+// chanGen.
+func recvFast(n *node) {
 	value := genValue(n.child[0])
 	tnext := getExec(n.tnext)
 	i := n.findex
 	l := n.level
 
-	if n.interp.cancelChan {
-		// Cancellable channel read
-		if n.fnext != nil {
-			fnext := getExec(n.fnext)
-			n.exec = func(f *frame) bltn {
-				// Fast: channel read doesn't block
-				ch := value(f)
-				if r, ok := ch.TryRecv(); ok {
-					getFrame(f, l).data[i] = r
-					if r.Bool() {
-						return tnext
-					}
-					return fnext
-				}
-				// Slow: channel read blocks, allow cancel
-				f.mutex.RLock()
-				done := f.done
-				f.mutex.RUnlock()
-
-				chosen, v, _ := reflect.Select([]reflect.SelectCase{done, {Dir: reflect.SelectRecv, Chan: ch}})
-				if chosen == 0 {
-					return nil
-				}
-				if v.Bool() {
-					return tnext
-				}
-				return fnext
-			}
-		} else {
-			n.exec = func(f *frame) bltn {
-				// Fast: channel read doesn't block
-				ch := value(f)
-				if r, ok := ch.TryRecv(); ok {
-					getFrame(f, l).data[i] = r
-					return tnext
-				}
-				// Slow: channel is blocked, allow cancel
-				f.mutex.RLock()
-				done := f.done
-				f.mutex.RUnlock()
-
-				var chosen int
-				chosen, getFrame(f, l).data[i], _ = reflect.Select([]reflect.SelectCase{done, {Dir: reflect.SelectRecv, Chan: ch}})
-				if chosen == 0 {
-					return nil
-				}
+	if n.fnext != nil {
+		fnext := getExec(n.fnext)
+		n.exec = func(f *frame) bltn {
+			if r, _ := value(f).Recv(); r.Bool() {
+				getFrame(f, l).data[i] = r
 				return tnext
 			}
+			return fnext
 		}
 	} else {
-		// Blocking channel read (less overhead)
-		if n.fnext != nil {
-			fnext := getExec(n.fnext)
-			n.exec = func(f *frame) bltn {
-				if r, _ := value(f).Recv(); r.Bool() {
-					getFrame(f, l).data[i] = r
-					return tnext
-				}
-				return fnext
-			}
-		} else {
-			i := n.findex
-			n.exec = func(f *frame) bltn {
-				getFrame(f, l).data[i], _ = value(f).Recv()
-				return tnext
-			}
+		n.exec = func(f *frame) bltn {
+			getFrame(f, l).data[i], _ = value(f).Recv()
+			return tnext
 		}
 	}
 }
 
-func recv2(n *node) {
-	vchan := genValue(n.child[0])    // chan
-	vres := genValue(n.anc.child[0]) // result
-	vok := genValue(n.anc.child[1])  // status
+// recv reads from a channel, cancellable by the run of the frame.
+func recv(n *node) {
+	value := genValue(n.child[0])
 	tnext := getExec(n.tnext)
+	i := n.findex
+	l := n.level
 
-	if n.interp.cancelChan {
-		// Cancellable channel read
+	if n.fnext != nil {
+		fnext := getExec(n.fnext)
 		n.exec = func(f *frame) bltn {
-			ch, result, status := vchan(f), vres(f), vok(f)
-			//  Fast: channel read doesn't block
-			if v, ok := ch.TryRecv(); ok {
-				result.Set(v)
-				status.SetBool(true)
-				return tnext
-			}
-			// Slow: channel is blocked, allow cancel
-			f.mutex.RLock()
-			done := f.done
-			f.mutex.RUnlock()
-
-			chosen, v, ok := reflect.Select([]reflect.SelectCase{done, {Dir: reflect.SelectRecv, Chan: ch}})
-			if chosen == 0 {
+			r, _, ok := recvCancellable(f, value(f))
+			if !ok {
 				return nil
 			}
-			result.Set(v)
-			status.SetBool(ok)
-			return tnext
+			getFrame(f, l).data[i] = r
+			if r.Bool() {
+				return tnext
+			}
+			return fnext
 		}
 	} else {
-		// Blocking channel read (less overhead)
 		n.exec = func(f *frame) bltn {
-			v, ok := vchan(f).Recv()
-			vres(f).Set(v)
-			vok(f).SetBool(ok)
+			r, _, ok := recvCancellable(f, value(f))
+			if !ok {
+				return nil
+			}
+			getFrame(f, l).data[i] = r
 			return tnext
 		}
 	}
 }
 
+// recv2Fast reads from a channel with a status, blocking without being
+// cancellable. See chanGen.
+func recv2Fast(n *node) {
+	vchan := genValue(n.child[0])    // chan
+	vres := genValue(n.anc.child[0]) // result
+	vok := genValue(n.anc.child[1])  // status
+	tnext := getExec(n.tnext)
+
+	n.exec = func(f *frame) bltn {
+		v, ok := vchan(f).Recv()
+		vres(f).Set(v)
+		vok(f).SetBool(ok)
+		return tnext
+	}
+}
+
+// recv2 reads from a channel with a status, cancellable by the run of the
+// frame.
+func recv2(n *node) {
+	vchan := genValue(n.child[0])    // chan
+	vres := genValue(n.anc.child[0]) // result
+	vok := genValue(n.anc.child[1])  // status
+	tnext := getExec(n.tnext)
+
+	n.exec = func(f *frame) bltn {
+		v, recvOK, ok := recvCancellable(f, vchan(f))
+		if !ok {
+			return nil
+		}
+		vres(f).Set(v)
+		vok(f).SetBool(recvOK)
+		return tnext
+	}
+}
+
+// doneCase returns the select case which is ready when the run of frame f is
+// cancelled, and false if the run is not cancellable, i.e. not started with a
+// context. In that case, channel operations can block without overhead.
+func doneCase(f *frame) (reflect.SelectCase, bool) {
+	if r := f.run; r != nil && r.cancel != nil {
+		return r.cancel.recv, true
+	}
+	return noDone, false
+}
+
+// recvCancellable receives a value from channel ch. It returns the received value, and
+// whether it was delivered by a send, as reflect.Value.Recv. The last result
+// is false if the run of frame f has been cancelled while blocked.
+func recvCancellable(f *frame, ch reflect.Value) (reflect.Value, bool, bool) {
+	// Fast: channel read doesn't block.
+	if v, ok := ch.TryRecv(); ok || v.IsValid() {
+		return v, ok, true
+	}
+	done, cancellable := doneCase(f)
+	if !cancellable {
+		v, ok := ch.Recv()
+		return v, ok, true
+	}
+	// Slow: channel read blocks, allow cancel.
+	chosen, v, ok := reflect.Select([]reflect.SelectCase{done, {Dir: reflect.SelectRecv, Chan: ch}})
+	return v, ok, chosen != 0
+}
+
+// sendCancellable sends data on channel ch. It returns false if the run of frame f
+// has been cancelled while blocked.
+func sendCancellable(f *frame, ch, data reflect.Value) bool {
+	// Fast: send on channel doesn't block.
+	if ch.TrySend(data) {
+		return true
+	}
+	done, cancellable := doneCase(f)
+	if !cancellable {
+		ch.Send(data)
+		return true
+	}
+	// Slow: send on channel blocks, allow cancel.
+	chosen, _, _ := reflect.Select([]reflect.SelectCase{done, {Dir: reflect.SelectSend, Chan: ch, Send: data}})
+	return chosen != 0
+}
+
 func convertLiteralValue(n *node, t reflect.Type) {
 	switch {
 	case n.typ.cat == nilT:
@@ -3774,36 +4543,29 @@ func convertConstantValue(n *node) {
 	n.rval = v.Convert(n.typ.TypeOf())
 }
 
-// Write to a channel.
-func send(n *node) {
+// sendFast writes to a channel, blocking without being cancellable. See
+// chanGen.
+func sendFast(n *node) {
 	next := getExec(n.tnext)
 	c0, c1 := n.child[0], n.child[1]
 	value0 := genValue(c0) // Send channel.
 	value1 := genDestValue(c0.typ.val, c1)
 
-	if !n.interp.cancelChan {
-		// Send is non-cancellable, has the least overhead.
-		n.exec = func(f *frame) bltn {
-			value0(f).Send(value1(f))
-			return next
-		}
-		return
+	n.exec = func(f *frame) bltn {
+		value0(f).Send(value1(f))
+		return next
 	}
+}
 
-	// Send is cancellable, may have some overhead.
-	n.exec = func(f *frame) bltn {
-		ch, data := value0(f), value1(f)
-		// Fast: send on channel doesn't block.
-		if ok := ch.TrySend(data); ok {
-			return next
-		}
-		// Slow: send on channel blocks, allow cancel.
-		f.mutex.RLock()
-		done := f.done
-		f.mutex.RUnlock()
+// Write to a channel, cancellable by the run of the frame.
+func send(n *node) {
+	next := getExec(n.tnext)
+	c0, c1 := n.child[0], n.child[1]
+	value0 := genValue(c0) // Send channel.
+	value1 := genDestValue(c0.typ.val, c1)
 
-		chosen, _, _ := reflect.Select([]reflect.SelectCase{done, {Dir: reflect.SelectSend, Chan: ch, Send: data}})
-		if chosen == 0 {
+	n.exec = func(f *frame) bltn {
+		if !sendCancellable(f, value0(f), value1(f)) {
 			return nil
 		}
 		return next
@@ -3890,9 +4652,7 @@ func _select(n *node) {
 	}
 
 	n.exec = func(f *frame) bltn {
-		f.mutex.RLock()
-		cases[nbClause] = f.done
-		f.mutex.RUnlock()
+		cases[nbClause], _ = doneCase(f)
 
 		for i := range cases[:nbClause] {
 			switch cases[i].Dir {
@@ -4172,6 +4932,29 @@ func imagConst(n *node) {
 	}
 }
 
+func maxConst(n *node) { minMaxConst(n, token.GTR) }
+func minConst(n *node) { minMaxConst(n, token.LSS) }
+
+// minMaxConst computes the result of min or max if all arguments are constants.
+func minMaxConst(n *node, op token.Token) {
+	var r reflect.Value
+	var cr constant.Value
+	for _, c := range n.child[1:] {
+		cv := constValue(c.rval)
+		if cv == nil {
+			return
+		}
+		if cr == nil || constant.Compare(cv, op, cr) {
+			r, cr = c.rval, cv
+		}
+	}
+	if isConstantValue(r.Type()) && isFloat(n.typ.TypeOf()) {
+		r = reflect.ValueOf(constant.ToFloat(cr))
+	}
+	n.rval = r
+	n.gen = nop
+}
+
 func realConst(n *node) {
 	if v := n.child[1].rval; v.IsValid() {
 		n.rval = reflect.ValueOf(real(v.Complex()))
@@ -0,0 +1,128 @@
+package interp
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// runState is the state of an execution of interpreted code, e.g. by
+// ExecuteWithContext, shared by the frames of its calls and goroutines.
+type runState struct {
+	cancel *runCancel   // cancellation of the run, or nil if not cancellable
+	sched  *schedRun    // scheduling of the run, or nil if not scheduled
+	stats  *runStats    // resource usage of the run, or nil if not collected
+	output *outputScope // output of the run, or nil to use the interpreter streams
+	steps  atomic.Int64 // CFG steps executed, counted for the yield hooks
+}
+
+// runCancel is the cancellation state of a run started with a context.
+type runCancel struct {
+	done    chan struct{}      // closed when the run is cancelled
+	recv    reflect.SelectCase // receive case of done, for channel operations
+	ended   chan struct{}      // closed when the execution of the run has returned
+	stopped atomic.Bool
+}
+
+// noDone is the select case of the runs which are not cancellable, never
+// ready.
+var noDone = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf((chan struct{})(nil))}
+
+func newRunCancel() *runCancel {
+	done := make(chan struct{})
+	return &runCancel{done: done, recv: reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(done)}, ended: make(chan struct{})}
+}
+
+// stop cancels the run, if not already done.
+func (c *runCancel) stop() {
+	if c.stopped.CompareAndSwap(false, true) {
+		close(c.done)
+	}
+}
+
+// cancelled returns true if the run r has been cancelled. It is false on a
+// nil r, i.e. for the code not executed by a run.
+func (r *runState) cancelled() bool {
+	return r != nil && r.cancel != nil && r.cancel.stopped.Load()
+}
+
+// fork returns the state of a goroutine started by run r. It shares the
+// cancellation of r but, as the goroutine executes concurrently, not its
+// scheduling.
+func (r *runState) fork() *runState {
+	if r == nil || r.sched == nil {
+		return r
+	}
+	return &runState{cancel: r.cancel, stats: r.stats, output: r.output}
+}
+
+// newRun returns the state of a run started with ctx, cancellable by stop,
+// registered to be stopped by Shutdown until endRun is called.
+func (interp *Interpreter) newRun(ctx context.Context) *runState {
+	r := &runState{cancel: newRunCancel()}
+	r.sched, _ = ctx.Value(schedKey{}).(*schedRun)
+	r.output, _ = ctx.Value(outputKey{}).(*outputScope)
+	if interp.stats {
+		r.stats = newRunStats()
+	}
+	interp.mutex.Lock()
+	interp.runs[r.cancel] = struct{}{}
+	interp.mutex.Unlock()
+	return r
+}
+
+// endRun unregisters run r.
+func (interp *Interpreter) endRun(r *runState) {
+	interp.mutex.Lock()
+	delete(interp.runs, r.cancel)
+	interp.mutex.Unlock()
+}
+
+// runWithContext calls f, executing run r obtained from newRun with ctx, in
+// a new goroutine. If ctx is done before f returns, r is cancelled and the
+// error of ctx is returned, the other runs of the interpreter being
+// unaffected. The execution may then still be in progress, until r.cancel.ended
+// is closed.
+func (interp *Interpreter) runWithContext(ctx context.Context, r *runState, f func() (reflect.Value, error)) (reflect.Value, error) {
+	var res reflect.Value
+	var err error
+
+	defer interp.endRun(r)
+
+	done := r.cancel.ended
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				var pc [64]uintptr
+				n := runtime.Callers(1, pc[:])
+				err = Panic{Value: interp.exportPanic(r), raw: r, Callers: pc[:n], Stack: debug.Stack()}
+			}
+			close(done)
+		}()
+		res, err = f()
+	}()
+
+	select {
+	case <-ctx.Done():
+		r.cancel.stop()
+		return reflect.Value{}, ctx.Err()
+	case <-done:
+	}
+	return res, err
+}
+
+// forRun returns a view of the global frame f for the execution of run r,
+// sharing its values, or f itself if r is nil. The global frame is shared by
+// the concurrent executions, each one has its own view.
+func (f *frame) forRun(r *runState) *frame {
+	if r == nil {
+		return f
+	}
+	f.mutex.RLock()
+	nf := &frame{id: f.runid(), debug: f.debug, trace: f.trace, data: f.data, run: r}
+	f.mutex.RUnlock()
+	nf.root = nf
+	return nf
+}
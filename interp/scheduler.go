@@ -0,0 +1,265 @@
+package interp
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DefaultQuantum is the number of CFG steps executed by a scheduled run
+// between two yields, if not set in SchedulerOptions.
+const DefaultQuantum = 10000
+
+// SchedulerOptions are the options of a Scheduler.
+type SchedulerOptions struct {
+	// Slots is the maximum number of runs executing at once. It defaults to
+	// runtime.GOMAXPROCS(0).
+	Slots int
+
+	// Quantum is the number of CFG steps executed by a run before it yields
+	// its slot to a waiting run of higher or equal precedence. It defaults
+	// to DefaultQuantum.
+	Quantum int
+}
+
+// ScheduleOptions are the scheduling parameters of a run.
+type ScheduleOptions struct {
+	// Priority is the priority of the run: a run of higher priority is
+	// always executed before the runs of lower priority.
+	Priority int
+
+	// Deadline, if not zero, is the time at which the run is aborted with
+	// context.DeadlineExceeded, whether it is waiting or executing. Among
+	// runs of equal priority, the run of earliest deadline is executed
+	// first, and the runs without a deadline last.
+	Deadline time.Time
+}
+
+// A Scheduler time-slices the execution of concurrent evaluations, in one or
+// several interpreters, so that a heavy script can not starve the others in
+// a shared host. At most Slots runs execute at once, the others wait for a
+// slot in the order of their priorities and deadlines, then of their
+// arrival. An executing run cooperatively yields its slot every Quantum
+// steps if a waiting run has a higher or equal precedence, so runs of equal
+// precedence are executed in a round robin.
+//
+// An interpreter can only be used by one scheduler. Its evaluations not
+// started by the scheduler are not time-sliced, nor are the goroutines
+// started by the scheduled runs. A run blocked, e.g. on a channel operation
+// or a call of binary code, keeps its slot.
+type Scheduler struct {
+	slots   int
+	quantum int
+
+	mutex sync.Mutex
+	free  int        // number of free slots
+	seq   uint64     // arrival counter
+	queue schedQueue // waiting runs
+}
+
+// NewScheduler returns a new scheduler.
+func NewScheduler(opts SchedulerOptions) *Scheduler {
+	if opts.Slots <= 0 {
+		opts.Slots = runtime.GOMAXPROCS(0)
+	}
+	if opts.Quantum <= 0 {
+		opts.Quantum = DefaultQuantum
+	}
+	return &Scheduler{slots: opts.Slots, quantum: opts.Quantum, free: opts.Slots}
+}
+
+// Eval evaluates src in interp as EvalWithContext does, once the run is
+// given a slot according to opts.
+func (s *Scheduler) Eval(ctx context.Context, interp *Interpreter, src string, opts ScheduleOptions) (reflect.Value, error) {
+	return s.run(ctx, interp, opts, func(ctx context.Context) (reflect.Value, error) {
+		return interp.EvalWithContext(ctx, src)
+	})
+}
+
+// Execute executes p in interp as ExecuteWithContext does, once the run is
+// given a slot according to opts.
+func (s *Scheduler) Execute(ctx context.Context, interp *Interpreter, p *Program, opts ScheduleOptions) (reflect.Value, error) {
+	return s.run(ctx, interp, opts, func(ctx context.Context) (reflect.Value, error) {
+		return interp.ExecuteWithContext(ctx, p)
+	})
+}
+
+// run runs f in interp, with a context carrying the scheduled run, once the
+// run is given a slot.
+func (s *Scheduler) run(ctx context.Context, interp *Interpreter, opts ScheduleOptions, f func(context.Context) (reflect.Value, error)) (reflect.Value, error) {
+	if err := interp.setScheduler(s); err != nil {
+		return reflect.Value{}, err
+	}
+	if !opts.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, opts.Deadline)
+		defer cancel()
+	}
+
+	r := &schedRun{sched: s, ctx: ctx, priority: opts.Priority, deadline: opts.Deadline, index: -1}
+	if err := s.acquire(r); err != nil {
+		return reflect.Value{}, err
+	}
+	defer s.release()
+	return f(context.WithValue(ctx, schedKey{}, r))
+}
+
+// acquire waits until a slot is given to r.
+func (s *Scheduler) acquire(r *schedRun) error {
+	s.mutex.Lock()
+	s.push(r)
+	s.grant()
+	s.mutex.Unlock()
+	return r.wait()
+}
+
+// release frees a slot, given to the first waiting run, if any.
+func (s *Scheduler) release() {
+	s.mutex.Lock()
+	s.free++
+	s.grant()
+	s.mutex.Unlock()
+}
+
+// push queues r. It must be called with s.mutex locked.
+func (s *Scheduler) push(r *schedRun) {
+	s.seq++
+	r.seq = s.seq
+	r.ready = make(chan struct{})
+	heap.Push(&s.queue, r)
+}
+
+// grant gives the free slots to the first waiting runs. It must be called
+// with s.mutex locked.
+func (s *Scheduler) grant() {
+	for s.free > 0 && len(s.queue) > 0 {
+		r := heap.Pop(&s.queue).(*schedRun)
+		s.free--
+		close(r.ready)
+	}
+}
+
+// yield gives the slot of the scheduled run executing frame f, if any, to
+// the first waiting run if it has a higher or equal precedence, and waits for
+// a slot again. It returns the error of the context of the run if it is done
+// meanwhile.
+func (s *Scheduler) yield(f *frame, _ *node) error {
+	if f.run == nil || f.run.sched == nil {
+		return nil
+	}
+	r := f.run.sched
+	s.mutex.Lock()
+	if r.index >= 0 || len(s.queue) == 0 || r.precedes(s.queue[0]) {
+		// The run is already yielding, e.g. from a binary callback
+		// executed by another goroutine, or it keeps its slot.
+		s.mutex.Unlock()
+		return nil
+	}
+	s.push(r)
+	s.free++
+	s.grant()
+	s.mutex.Unlock()
+	return r.wait()
+}
+
+// schedKey is the context key of the scheduled run of an evaluation.
+type schedKey struct{}
+
+// setScheduler sets s as the scheduler of interp.
+func (interp *Interpreter) setScheduler(s *Scheduler) error {
+	interp.mutex.Lock()
+	defer interp.mutex.Unlock()
+	switch interp.scheduler {
+	case s:
+		return nil
+	case nil:
+		interp.scheduler = s
+		interp.setYielder()
+		return nil
+	}
+	return errors.New("interpreter already used by another scheduler")
+}
+
+// schedRun is a run of a scheduler.
+type schedRun struct {
+	sched    *Scheduler
+	ctx      context.Context
+	priority int
+	deadline time.Time
+	seq      uint64        // arrival order, among runs of equal precedence
+	index    int           // index in queue, or -1
+	ready    chan struct{} // closed when a slot is given to the run
+}
+
+// precedes returns true if r must be executed before o, regardless of their
+// arrival order.
+func (r *schedRun) precedes(o *schedRun) bool {
+	if r.priority != o.priority {
+		return r.priority > o.priority
+	}
+	if r.deadline.IsZero() || o.deadline.IsZero() {
+		return !r.deadline.IsZero() && o.deadline.IsZero()
+	}
+	return r.deadline.Before(o.deadline)
+}
+
+// wait waits until a slot is given to r, or until its context is done.
+func (r *schedRun) wait() error {
+	s := r.sched
+	select {
+	case <-r.ready:
+		if err := r.ctx.Err(); err != nil {
+			s.release()
+			return err
+		}
+		return nil
+	case <-r.ctx.Done():
+	}
+	s.mutex.Lock()
+	if r.index >= 0 {
+		heap.Remove(&s.queue, r.index)
+		s.mutex.Unlock()
+	} else {
+		// The slot was given meanwhile.
+		s.mutex.Unlock()
+		s.release()
+	}
+	return r.ctx.Err()
+}
+
+// schedQueue is a priority queue of runs, implementing heap.Interface.
+type schedQueue []*schedRun
+
+func (q schedQueue) Len() int { return len(q) }
+
+func (q schedQueue) Less(i, j int) bool {
+	if q[i].precedes(q[j]) {
+		return true
+	}
+	return !q[j].precedes(q[i]) && q[i].seq < q[j].seq
+}
+
+func (q schedQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *schedQueue) Push(x interface{}) {
+	r := x.(*schedRun)
+	r.index = len(*q)
+	*q = append(*q, r)
+}
+
+func (q *schedQueue) Pop() interface{} {
+	old := *q
+	r := old[len(old)-1]
+	old[len(old)-1] = nil
+	r.index = -1
+	*q = old[:len(old)-1]
+	return r
+}
@@ -120,9 +120,9 @@ func (s *scope) pushBloc() *scope { return s.push(false) }
 func (s *scope) pushFunc() *scope { return s.push(true) }
 
 func (s *scope) pop() *scope {
-	if s.level == s.anc.level {
-		// Propagate size and types, as scopes at same level share the same frame.
-		s.anc.types = s.types
+	// Propagate size and types, as scopes at same level share the same frame.
+	for anc := s.anc; anc != nil && anc.level == s.level; anc = anc.anc {
+		anc.types = s.types
 	}
 	return s.anc
 }
@@ -191,6 +191,73 @@ func (s *scope) rangeChanType(n *node) *itype {
 	return nil
 }
 
+// rangeFuncTypes returns the types of the iteration variables of the range
+// statement n over a function iterator of the form func(yield func(K, V) bool).
+// A nil type is returned for a missing iteration variable.
+func (s *scope) rangeFuncTypes(n *node) (ktyp, vtyp *itype, err error) {
+	o := n.child[len(n.child)-2]
+	var params []*itype
+	t := o.typ
+	for t.cat == linkedT {
+		t = t.val
+	}
+	switch {
+	case t.cat == funcT:
+		if len(t.arg) != 1 || len(t.ret) != 0 {
+			break
+		}
+		y := t.arg[0]
+		for y.cat == linkedT {
+			y = y.val
+		}
+		if y.cat != funcT || len(y.arg) > 2 || len(y.ret) != 1 || !isBool(y.ret[0]) {
+			break
+		}
+		params = y.arg
+		if params == nil {
+			params = []*itype{}
+		}
+	case t.cat == valueT && t.rtype.Kind() == reflect.Func:
+		rt := t.rtype
+		if rt.NumIn() != 1 || rt.NumOut() != 0 {
+			break
+		}
+		y := rt.In(0)
+		if y.Kind() != reflect.Func || y.NumIn() > 2 || y.NumOut() != 1 || y.Out(0).Kind() != reflect.Bool {
+			break
+		}
+		params = make([]*itype, y.NumIn())
+		for i := range params {
+			params[i] = valueTOf(y.In(i))
+		}
+	}
+	if params == nil {
+		return nil, nil, o.cfgErrorf("cannot range over %s (variable of type %s): func must be func(yield func(...) bool)", o.ident, o.typ.id())
+	}
+
+	nvar := len(n.child) - 2
+	if nvar == 1 && n.child[0].ident == "_" {
+		nvar = 0 // for range f
+	}
+	if nvar > len(params) {
+		if len(params) == 0 {
+			return nil, nil, o.cfgErrorf("range over %s permits no iteration variables", o.ident)
+		}
+		return nil, nil, o.cfgErrorf("range over %s permits only one iteration variable", o.ident)
+	}
+
+	// Iterator state, always just behind the key in frame.
+	s.add(valueTOf(reflect.TypeOf((*rangeFuncState)(nil))))
+	ktyp = s.getType("bool") // Dummy type if the yield function has no argument.
+	if len(params) > 0 {
+		ktyp = params[0]
+	}
+	if len(params) > 1 {
+		vtyp = params[1]
+	}
+	return ktyp, vtyp, nil
+}
+
 // fixType returns the input type, or a valid default type for untyped constant.
 func (s *scope) fixType(t *itype) *itype {
 	if !t.untyped || t.cat != valueT {
@@ -280,7 +347,7 @@ func (interp *Interpreter) Globals() map[string]reflect.Value {
 		case constSym:
 			syms[n] = s.rval
 		case varSym:
-			syms[n] = interp.frame.data[s.index]
+			syms[n] = interp.frame.Load().data[s.index]
 		}
 	}
 
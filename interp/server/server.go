@@ -0,0 +1,637 @@
+// Package server provides an HTTP service running Go scripts with the
+// interpreter.
+//
+// The service exposes the following endpoints, whose requests and responses
+// are JSON encoded:
+//
+//	POST   /compile              compile a program without running it
+//	POST   /run                  run a program in a new interpreter
+//	POST   /sessions             create a session, an interpreter kept between requests
+//	POST   /sessions/{id}/eval   evaluate source code in a session, as in a REPL
+//	DELETE /sessions/{id}        close a session
+//	GET    /repl                 REPL over a WebSocket, with an interpreter per connection
+//
+// The run and eval endpoints stream the standard output and error of the
+// interpreted code as newline delimited JSON events, terminated by a final
+// event holding the result of the evaluation. The messages of the REPL
+// endpoint are JSON encoded REPLMessage values. The interpreters are created
+// with the options of the server configuration, which define their sandbox.
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/breadchris/yaegi/interp"
+)
+
+// Default limits of the configuration.
+const (
+	DefaultSessionTimeout = 10 * time.Minute
+	DefaultMaxSourceSize  = 1 << 20
+)
+
+// Config is the configuration of a Server.
+type Config struct {
+	// Options of the interpreters. The standard streams are set by the
+	// server, and Args by the run requests if they provide it. The sandbox
+	// of interpreted code is defined by the Unrestricted, Capabilities,
+	// ExecPolicy, Env and Dir options, and by Symbols.
+	Options interp.Options
+
+	// Symbols used by the interpreters, e.g. stdlib.Symbols.
+	Symbols []interp.Exports
+
+	// Timeout limits the duration of each run or eval request, if not zero.
+	Timeout time.Duration
+
+	// SessionTimeout is the idle duration after which a session, or a REPL
+	// connection without messages from its client, is closed. It defaults
+	// to DefaultSessionTimeout.
+	SessionTimeout time.Duration
+
+	// MaxSessions limits the number of open sessions and REPL connections,
+	// if not zero.
+	MaxSessions int
+
+	// MaxSourceSize limits the size of the request bodies and REPL messages.
+	// It defaults to DefaultMaxSourceSize.
+	MaxSourceSize int64
+}
+
+// Request is the body of the compile, run and eval requests.
+type Request struct {
+	Src   string   `json:"src"`             // source code
+	Args  []string `json:"args,omitempty"`  // command line arguments, run only
+	Stdin string   `json:"stdin,omitempty"` // standard input, run only
+}
+
+// Event is an element of the response stream of the run and eval requests.
+type Event struct {
+	Stream string `json:"stream,omitempty"` // "stdout" or "stderr"
+	Data   string `json:"data,omitempty"`   // data written on the stream
+
+	// The final event has Done set, and holds the formatted result of the
+	// evaluation, or its error.
+	Done   bool   `json:"done,omitempty"`
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CompileResult is the response of the compile requests.
+type CompileResult struct {
+	Error string `json:"error,omitempty"`
+}
+
+// REPLMessage is a message of the REPL endpoint. The client sends "input"
+// messages, holding one or more lines of source code, and "interrupt"
+// messages to interrupt the current evaluation. The server sends the frames
+// of the REPL, of the kinds of interp.REPLFrameKind, and the "stdout" and
+// "stderr" output of interpreted code.
+type REPLMessage struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// SessionInfo is the response of the session creation requests.
+type SessionInfo struct {
+	ID string `json:"id"`
+}
+
+// Server is an HTTP handler running Go scripts. It is safe for concurrent
+// use.
+type Server struct {
+	config Config
+	mux    *http.ServeMux
+
+	mutex    sync.Mutex
+	sessions map[string]*session
+	repls    map[*session]*wsConn // sessions of the REPL connections
+	closed   bool
+}
+
+// New returns a server of configuration config.
+func New(config Config) *Server {
+	if config.SessionTimeout <= 0 {
+		config.SessionTimeout = DefaultSessionTimeout
+	}
+	if config.MaxSourceSize <= 0 {
+		config.MaxSourceSize = DefaultMaxSourceSize
+	}
+	s := &Server{config: config, mux: http.NewServeMux(), sessions: map[string]*session{}, repls: map[*session]*wsConn{}}
+	s.mux.HandleFunc("POST /compile", s.handleCompile)
+	s.mux.HandleFunc("POST /run", s.handleRun)
+	s.mux.HandleFunc("POST /sessions", s.handleCreate)
+	s.mux.HandleFunc("POST /sessions/{id}/eval", s.handleEval)
+	s.mux.HandleFunc("DELETE /sessions/{id}", s.handleDelete)
+	s.mux.HandleFunc("GET /repl", s.handleREPL)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close closes all the sessions. The sessions can no longer be created.
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	sessions, repls := s.sessions, s.repls
+	s.sessions, s.repls, s.closed = map[string]*session{}, map[*session]*wsConn{}, true
+	s.mutex.Unlock()
+
+	var errs []error
+	for _, sess := range sessions {
+		sess.timer.Stop()
+		errs = append(errs, sess.close())
+	}
+	for sess, conn := range repls {
+		if conn != nil {
+			conn.Close()
+		}
+		errs = append(errs, sess.close())
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Server) handleCompile(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.readRequest(w, r)
+	if !ok {
+		return
+	}
+	opts := s.config.Options
+	opts.Stdin, opts.Stdout, opts.Stderr = strings.NewReader(""), io.Discard, io.Discard
+	i := interp.New(opts)
+	var res CompileResult
+	if err := s.use(i); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := i.Compile(req.Src); err != nil {
+		res.Error = err.Error()
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.readRequest(w, r)
+	if !ok {
+		return
+	}
+	sess, err := s.newSession(req.Args, []byte(req.Stdin))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer sess.close()
+	s.eval(w, r, sess, req.Src)
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	if !s.available() {
+		http.Error(w, "too many sessions", http.StatusServiceUnavailable)
+		return
+	}
+	sess, err := s.newSession(nil, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sess.timer = time.AfterFunc(s.config.SessionTimeout, func() { s.closeSession(sess.id) })
+
+	s.mutex.Lock()
+	ok := s.availableLocked()
+	if ok {
+		s.sessions[sess.id] = sess
+	}
+	s.mutex.Unlock()
+	if !ok {
+		sess.timer.Stop()
+		sess.close()
+		http.Error(w, "too many sessions", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusCreated, SessionInfo{ID: sess.id})
+}
+
+// handleREPL runs a REPL on a WebSocket connection, with an interpreter of
+// its own, until the connection is closed.
+func (s *Server) handleREPL(w http.ResponseWriter, r *http.Request) {
+	if !s.available() {
+		http.Error(w, "too many sessions", http.StatusServiceUnavailable)
+		return
+	}
+	sess, err := s.newSession(nil, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.mutex.Lock()
+	ok := s.availableLocked()
+	if ok {
+		s.repls[sess] = nil
+	}
+	s.mutex.Unlock()
+	if !ok {
+		sess.close()
+		http.Error(w, "too many sessions", http.StatusServiceUnavailable)
+		return
+	}
+	defer func() {
+		s.mutex.Lock()
+		delete(s.repls, sess)
+		s.mutex.Unlock()
+		sess.close()
+	}()
+
+	conn, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	s.mutex.Lock()
+	if _, ok := s.repls[sess]; ok {
+		s.repls[sess] = conn
+	}
+	s.mutex.Unlock()
+	conn.maxSize, conn.timeout = s.config.MaxSourceSize, s.config.SessionTimeout
+
+	t := &wsREPL{conn: conn, sess: sess}
+	out := &output{send: func(e Event) error { return t.send(REPLMessage{Type: e.Stream, Text: e.Data}) }}
+	sess.stdout.setOutput(out)
+	sess.stderr.setOutput(out)
+	sess.interp.ServeREPL(t)
+}
+
+// available returns true if a session can be created.
+func (s *Server) available() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.availableLocked()
+}
+
+func (s *Server) availableLocked() bool {
+	return !s.closed && (s.config.MaxSessions <= 0 || len(s.sessions)+len(s.repls) < s.config.MaxSessions)
+}
+
+func (s *Server) handleEval(w http.ResponseWriter, r *http.Request) {
+	sess := s.session(r.PathValue("id"))
+	if sess == nil {
+		http.NotFound(w, r)
+		return
+	}
+	req, ok := s.readRequest(w, r)
+	if !ok {
+		return
+	}
+	sess.timer.Stop()
+	defer sess.timer.Reset(s.config.SessionTimeout)
+	s.eval(w, r, sess, req.Src)
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if !s.closeSession(r.PathValue("id")) {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) session(id string) *session {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.sessions[id]
+}
+
+// closeSession closes the session id, and returns false if it does not
+// exist.
+func (s *Server) closeSession(id string) bool {
+	s.mutex.Lock()
+	sess := s.sessions[id]
+	delete(s.sessions, id)
+	s.mutex.Unlock()
+	if sess == nil {
+		return false
+	}
+	sess.timer.Stop()
+	sess.close()
+	return true
+}
+
+func (s *Server) readRequest(w http.ResponseWriter, r *http.Request) (*Request, bool) {
+	req := &Request{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.config.MaxSourceSize)).Decode(req); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return req, true
+}
+
+func (s *Server) use(i *interp.Interpreter) error {
+	for _, symbols := range s.config.Symbols {
+		if err := i.Use(symbols); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eval evaluates src in the session, and streams its output and result as
+// events in the response.
+func (s *Server) eval(w http.ResponseWriter, r *http.Request, sess *session, src string) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	out := &output{send: func(e Event) error {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}}
+
+	ctx := r.Context()
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	// Evaluations of a session are serialized.
+	sess.mutex.Lock()
+	defer sess.mutex.Unlock()
+	sess.stdout.setOutput(out)
+	sess.stderr.setOutput(out)
+	res, err := sess.interp.EvalWithContext(ctx, src)
+	sess.stdout.sync()
+	sess.stderr.sync()
+	sess.stdout.setOutput(nil)
+	sess.stderr.setOutput(nil)
+
+	final := Event{Done: true}
+	if err != nil {
+		final.Error = err.Error()
+	} else if res.IsValid() && res.CanInterface() {
+		final.Result = fmt.Sprintf("%v", res)
+	}
+	out.emit(final)
+}
+
+// output sends the events of an evaluation to the client.
+type output struct {
+	mutex sync.Mutex
+	send  func(Event) error
+}
+
+func (o *output) emit(e Event) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.send(e)
+}
+
+// session is an interpreter, with its standard streams.
+type session struct {
+	id     string
+	interp *interp.Interpreter
+	mutex  sync.Mutex // serializes the evaluations
+	stdin  *os.File   // read end of the standard input
+	stdout *stream
+	stderr *stream
+	timer  *time.Timer // closes the idle session
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// newSession returns a session whose interpreter has the command line
+// arguments args, if not nil, and reads stdin on its standard input. The
+// standard streams are pipes, to capture the output of interpreted code
+// written to os.Stdout and os.Stderr directly.
+func (s *Server) newSession(args []string, stdin []byte) (sess *session, err error) {
+	sess = &session{id: newID()}
+	defer func() {
+		if err != nil {
+			sess.close()
+		}
+	}()
+	var w *os.File
+	if sess.stdin, w, err = os.Pipe(); err != nil {
+		return nil, err
+	}
+	go func() {
+		w.Write(stdin)
+		w.Close()
+	}()
+	if sess.stdout, err = newStream("stdout"); err != nil {
+		return nil, err
+	}
+	if sess.stderr, err = newStream("stderr"); err != nil {
+		return nil, err
+	}
+
+	opts := s.config.Options
+	opts.Stdin, opts.Stdout, opts.Stderr = sess.stdin, sess.stdout.w, sess.stderr.w
+	if args != nil {
+		opts.Args = args
+	}
+	sess.interp = interp.New(opts)
+	if err := s.use(sess.interp); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// close shuts the interpreter down, and closes the standard streams. It can
+// be called several times.
+func (sess *session) close() error {
+	sess.closeOnce.Do(func() { sess.closeErr = sess.doClose() })
+	return sess.closeErr
+}
+
+func (sess *session) doClose() error {
+	var err error
+	if sess.interp != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = sess.interp.Shutdown(ctx)
+		cancel()
+	}
+	if sess.stdin != nil {
+		sess.stdin.Close()
+	}
+	if sess.stdout != nil {
+		sess.stdout.close()
+	}
+	if sess.stderr != nil {
+		sess.stderr.close()
+	}
+	return err
+}
+
+// newID returns a random session identifier.
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// stream relays the data written on a pipe as events to the output of the
+// current evaluation, or discards it between evaluations.
+type stream struct {
+	name   string
+	r, w   *os.File
+	mark   []byte        // written by sync, to wait for the pending data
+	synced chan struct{} // signals that mark has been read
+	done   chan struct{} // closed when the pipe is closed
+
+	mutex sync.Mutex
+	out   *output
+}
+
+func newStream(name string) (*stream, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	mark := make([]byte, 16)
+	rand.Read(mark)
+	s := &stream{name: name, r: r, w: w, mark: mark, synced: make(chan struct{}), done: make(chan struct{})}
+	go s.relay()
+	return s, nil
+}
+
+func (s *stream) setOutput(out *output) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.out = out
+}
+
+func (s *stream) emit(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	s.mutex.Lock()
+	out := s.out
+	s.mutex.Unlock()
+	if out != nil {
+		out.emit(Event{Stream: s.name, Data: string(b)})
+	}
+}
+
+// sync waits until the data written before its call has been relayed.
+func (s *stream) sync() {
+	if _, err := s.w.Write(s.mark); err != nil {
+		return
+	}
+	select {
+	case <-s.synced:
+	case <-s.done:
+	}
+}
+
+func (s *stream) close() {
+	s.w.Close()
+	<-s.done
+	s.r.Close()
+}
+
+func (s *stream) relay() {
+	defer close(s.done)
+	buf := make([]byte, 32<<10)
+	var pending []byte
+	for {
+		n, err := s.r.Read(buf)
+		pending = append(pending, buf[:n]...)
+		for {
+			i := bytes.Index(pending, s.mark)
+			if i < 0 {
+				break
+			}
+			s.emit(pending[:i])
+			pending = pending[i+len(s.mark):]
+			s.synced <- struct{}{}
+		}
+		if err != nil {
+			s.emit(pending)
+			return
+		}
+		// Hold back a possible beginning of the mark.
+		k := len(pending) - s.partialMark(pending)
+		s.emit(pending[:k])
+		pending = append([]byte{}, pending[k:]...)
+	}
+}
+
+// partialMark returns the length of the longest suffix of b which is a
+// prefix of the mark.
+func (s *stream) partialMark(b []byte) int {
+	for n := min(len(b), len(s.mark)-1); n > 0; n-- {
+		if bytes.HasSuffix(b, s.mark[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// wsREPL is the transport of a REPL on a WebSocket connection.
+type wsREPL struct {
+	conn    *wsConn
+	sess    *session
+	pending []string // lines of the last input message
+}
+
+func (t *wsREPL) ReadLine() (string, error) {
+	for len(t.pending) == 0 {
+		b, err := t.conn.ReadMessage()
+		if err != nil {
+			return "", err
+		}
+		var m REPLMessage
+		if err := json.Unmarshal(b, &m); err != nil {
+			return "", err
+		}
+		switch m.Type {
+		case "input":
+			t.pending = strings.Split(strings.TrimSuffix(m.Text, "\n"), "\n")
+		case "interrupt":
+			return "", interp.ErrREPLInterrupt
+		}
+	}
+	line := t.pending[0]
+	t.pending = t.pending[1:]
+	return line, nil
+}
+
+func (t *wsREPL) WriteFrame(f interp.REPLFrame) error {
+	// The output of an evaluation precedes its result.
+	t.sess.stdout.sync()
+	t.sess.stderr.sync()
+	return t.send(REPLMessage{Type: f.Kind.String(), Text: f.Text})
+}
+
+func (t *wsREPL) send(m REPLMessage) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return t.conn.WriteMessage(b)
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/breadchris/yaegi/interp"
+	"github.com/breadchris/yaegi/stdlib"
+)
+
+func newTestServer(t *testing.T, config Config) *httptest.Server {
+	t.Helper()
+	config.Symbols = []interp.Exports{stdlib.Symbols}
+	s := New(config)
+	ts := httptest.NewServer(s)
+	t.Cleanup(func() {
+		ts.Close()
+		s.Close()
+	})
+	return ts
+}
+
+func post(t *testing.T, url string, req Request) *http.Response {
+	t.Helper()
+	b, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// events returns the output streams and the final event of a response.
+func events(t *testing.T, resp *http.Response) (stdout, stderr string, final Event) {
+	t.Helper()
+	var out, errs strings.Builder
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		var e Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case e.Done:
+			return out.String(), errs.String(), e
+		case e.Stream == "stdout":
+			out.WriteString(e.Data)
+		case e.Stream == "stderr":
+			errs.WriteString(e.Data)
+		}
+	}
+	t.Fatal("missing final event")
+	return
+}
+
+func TestRun(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	src := `package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+)
+
+func main() {
+	s := bufio.NewScanner(os.Stdin)
+	s.Scan()
+	fmt.Println("hello", s.Text(), os.Args[1:])
+	os.Stdout.WriteString("direct\n")
+	fmt.Fprintln(os.Stderr, "warning")
+}
+`
+	stdout, stderr, final := events(t, post(t, ts.URL+"/run", Request{Src: src, Args: []string{"prog", "a"}, Stdin: "world\n"}))
+	if final.Error != "" {
+		t.Fatal(final.Error)
+	}
+	if want := "hello world [a]\ndirect\n"; stdout != want {
+		t.Errorf("got stdout %q, want %q", stdout, want)
+	}
+	if want := "warning\n"; stderr != want {
+		t.Errorf("got stderr %q, want %q", stderr, want)
+	}
+
+	_, _, final = events(t, post(t, ts.URL+"/run", Request{Src: `panic("boom")`}))
+	if !strings.Contains(final.Error, "boom") {
+		t.Errorf("got error %q, want a panic", final.Error)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	var res CompileResult
+	if err := json.NewDecoder(post(t, ts.URL+"/compile", Request{Src: `var a int = "x"`}).Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Error == "" {
+		t.Error("expected a compile error")
+	}
+}
+
+func TestSession(t *testing.T) {
+	ts := newTestServer(t, Config{MaxSessions: 1, Timeout: time.Second})
+	resp := post(t, ts.URL+"/sessions", Request{})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("got status %d", resp.StatusCode)
+	}
+	var info SessionInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if resp := post(t, ts.URL+"/sessions", Request{}); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+
+	eval := ts.URL + "/sessions/" + info.ID + "/eval"
+	events(t, post(t, eval, Request{Src: `import "fmt"`}))
+	events(t, post(t, eval, Request{Src: `x := 20`}))
+	stdout, _, final := events(t, post(t, eval, Request{Src: `fmt.Println("x is", x); x * 2 + 2`}))
+	if stdout != "x is 20\n" || final.Result != "42" {
+		t.Errorf("got %q and result %q", stdout, final.Result)
+	}
+	_, _, final = events(t, post(t, eval, Request{Src: `for {}`}))
+	if !strings.Contains(final.Error, "deadline") {
+		t.Errorf("got error %q, want a timeout", final.Error)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/sessions/"+info.ID, nil)
+	dresp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dresp.Body.Close()
+	if dresp.StatusCode != http.StatusNoContent {
+		t.Errorf("got status %d", dresp.StatusCode)
+	}
+	if resp := post(t, eval, Request{Src: `x`}); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The WebSocket protocol, RFC 6455, reduced to what the REPL endpoint needs:
+// text messages, with the control frames handled internally.
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes.
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+var errWSProtocol = errors.New("websocket: protocol error")
+
+// wsConn is a server side WebSocket connection.
+type wsConn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	maxSize int64         // maximum size of a received message
+	timeout time.Duration // idle timeout of reads, if not zero
+
+	mutex sync.Mutex // serializes the writes
+	w     *bufio.Writer
+}
+
+// headerContains returns true if the comma separated list of the header key
+// contains token, case insensitively.
+func headerContains(h http.Header, key, token string) bool {
+	for _, v := range h.Values(key) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgrade upgrades the HTTP connection of r to the WebSocket protocol.
+func upgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		http.Error(w, "websocket: bad handshake", http.StatusBadRequest)
+		return nil, errWSProtocol
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket: hijacking not supported", http.StatusInternalServerError)
+		return nil, errors.New("websocket: hijacking not supported")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	h := sha1.Sum([]byte(key + wsGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(h[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, r: rw.Reader, w: rw.Writer}, nil
+}
+
+// ReadMessage returns the next text or binary message. It returns io.EOF
+// when the connection is closed by the peer.
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	var msg []byte
+	started := false
+	for {
+		if c.timeout > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+		}
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			c.writeFrame(wsClose, payload)
+			return nil, io.EOF
+		case wsText, wsBinary:
+			if started {
+				return nil, errWSProtocol
+			}
+			started = true
+		case wsContinuation:
+			if !started {
+				return nil, errWSProtocol
+			}
+		default:
+			return nil, errWSProtocol
+		}
+		if c.maxSize > 0 && int64(len(msg)+len(payload)) > c.maxSize {
+			c.writeFrame(wsClose, []byte{0x03, 0xf1}) // 1009: message too big
+			return nil, errors.New("websocket: message too big")
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var h [2]byte
+	if _, err = io.ReadFull(c.r, h[:]); err != nil {
+		return
+	}
+	fin, op = h[0]&0x80 != 0, h[0]&0x0f
+	masked, n := h[1]&0x80 != 0, uint64(h[1]&0x7f)
+	if !masked {
+		// The frames sent by clients must be masked.
+		return false, 0, nil, errWSProtocol
+	}
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(c.r, b[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(c.r, b[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if op >= wsClose && (n > 125 || !fin) || c.maxSize > 0 && n > uint64(c.maxSize) {
+		return false, 0, nil, errWSProtocol
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.r, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.r, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// WriteMessage sends the text message b.
+func (c *wsConn) WriteMessage(b []byte) error {
+	return c.writeFrame(wsText, b)
+}
+
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	h := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n < 126:
+		h = append(h, byte(n))
+	case n <= 0xffff:
+		h = append(h, 126)
+		h = binary.BigEndian.AppendUint16(h, uint16(n))
+	default:
+		h = append(h, 127)
+		h = binary.BigEndian.AppendUint64(h, uint64(n))
+	}
+	c.w.Write(h)
+	c.w.Write(payload)
+	return c.w.Flush()
+}
+
+// Close closes the connection, after sending a close frame.
+func (c *wsConn) Close() error {
+	c.writeFrame(wsClose, []byte{0x03, 0xe8}) // 1000: normal closure
+	return c.conn.Close()
+}
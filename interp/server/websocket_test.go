@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsClient is a minimal WebSocket client for tests.
+type wsClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialWS(t *testing.T, url string) *wsClient {
+	t.Helper()
+	addr := strings.TrimPrefix(url, "http://")
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	req := "GET /repl HTTP/1.1\r\nHost: " + addr + "\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := io.WriteString(conn, req); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("got status %d", resp.StatusCode)
+	}
+	if got, want := resp.Header.Get("Sec-WebSocket-Accept"), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Fatalf("got accept key %q, want %q", got, want)
+	}
+	return &wsClient{conn: conn, r: r}
+}
+
+func (c *wsClient) send(t *testing.T, m REPLMessage) {
+	t.Helper()
+	payload, _ := json.Marshal(m)
+	mask := [4]byte{1, 2, 3, 4}
+	h := []byte{0x80 | wsText}
+	if n := len(payload); n < 126 {
+		h = append(h, 0x80|byte(n))
+	} else {
+		h = binary.BigEndian.AppendUint16(append(h, 0x80|126), uint16(n))
+	}
+	h = append(h, mask[:]...)
+	for i, b := range payload {
+		h = append(h, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(h); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *wsClient) receive(t *testing.T) REPLMessage {
+	t.Helper()
+	var h [2]byte
+	if _, err := io.ReadFull(c.r, h[:]); err != nil {
+		t.Fatal(err)
+	}
+	n := int(h[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		io.ReadFull(c.r, b[:])
+		n = int(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		io.ReadFull(c.r, b[:])
+		n = int(binary.BigEndian.Uint64(b[:]))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		t.Fatal(err)
+	}
+	var m REPLMessage
+	if err := json.Unmarshal(payload, &m); err != nil {
+		t.Fatalf("%v: %q", err, payload)
+	}
+	return m
+}
+
+// until returns the messages received until the next message of type typ,
+// excluded.
+func (c *wsClient) until(t *testing.T, typ string) []REPLMessage {
+	t.Helper()
+	var res []REPLMessage
+	for {
+		m := c.receive(t)
+		if m.Type == typ {
+			return res
+		}
+		res = append(res, m)
+	}
+}
+
+func TestREPL(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	c := dialWS(t, ts.URL)
+	c.until(t, "prompt")
+
+	c.send(t, REPLMessage{Type: "input", Text: `import "fmt"`})
+	c.until(t, "prompt")
+	c.send(t, REPLMessage{Type: "input", Text: "func f(n int) int {\nreturn n * 2\n}"})
+	if m := c.receive(t); m.Type != "continue" {
+		t.Fatalf("got %+v, want a continuation prompt", m)
+	}
+	c.until(t, "prompt")
+
+	c.send(t, REPLMessage{Type: "input", Text: `fmt.Println("hello"); f(21)`})
+	got := c.until(t, "prompt")
+	want := []REPLMessage{{Type: "stdout", Text: "hello\n"}, {Type: "result", Text: "42"}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	c.send(t, REPLMessage{Type: "input", Text: `for {}`})
+	c.send(t, REPLMessage{Type: "interrupt"})
+	got = c.until(t, "prompt")
+	if len(got) == 0 || got[0].Type != "error" || !strings.Contains(got[0].Text, "canceled") {
+		t.Errorf("got %+v, want a cancellation error", got)
+	}
+	// As Ctrl-C in a terminal, an interrupt also gives an empty input line.
+	c.until(t, "prompt")
+
+	c.send(t, REPLMessage{Type: "input", Text: `f(1)`})
+	if got := c.until(t, "prompt"); len(got) != 1 || got[0].Text != "2" {
+		t.Errorf("got %+v after interrupt", got)
+	}
+}
@@ -0,0 +1,148 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+)
+
+// shutdown holds what Shutdown releases: the network listeners opened by
+// interpreted code, and the registered cleanup functions.
+type shutdown struct {
+	mutex     sync.Mutex
+	listeners []io.Closer
+	cleanups  []func()
+}
+
+func (s *shutdown) addListener(l io.Closer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.listeners = append(s.listeners, l)
+}
+
+// OnShutdown registers f to be called by Shutdown. Interpreted code can
+// register its own cleanup functions through the Self symbol of the
+// interpreter package, when its symbols are used, e.g.
+// interp.Self.OnShutdown(srv.Close).
+func (interp *Interpreter) OnShutdown(f func()) {
+	s := interp.shutdown
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cleanups = append(s.cleanups, f)
+}
+
+// Shutdown stops gracefully the program run by the interpreter, e.g. a
+// plugin server. It delivers a synthetic os.Interrupt signal to the signal
+// handlers of interpreted code, closes the network listeners opened by
+// interpreted code, and calls the functions registered with OnShutdown, in
+// the reverse order of their registration, until they complete or ctx is
+// done. The execution of interpreted code, including its goroutines, is
+// then cancelled, as by the expiration of the context of EvalWithContext.
+// Shutdown returns ctx.Err() if ctx is done before the completion of the
+// cleanup functions, and the errors of listeners and the panics of cleanup
+// functions, joined.
+func (interp *Interpreter) Shutdown(ctx context.Context) error {
+	interp.Notify(os.Interrupt)
+
+	s := interp.shutdown
+	s.mutex.Lock()
+	listeners, cleanups := s.listeners, s.cleanups
+	s.listeners, s.cleanups = nil, nil
+	s.mutex.Unlock()
+
+	var errs []error
+	for _, l := range listeners {
+		if err := l.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			errs = append(errs, err)
+		}
+	}
+
+	var panics []error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						panics = append(panics, fmt.Errorf("shutdown cleanup panic: %v", r))
+					}
+				}()
+				cleanups[i]()
+			}()
+		}
+	}()
+
+	select {
+	case <-done:
+		errs = append(errs, panics...)
+	case <-ctx.Done():
+		errs = append(errs, ctx.Err())
+	}
+	interp.stop()
+	return errors.Join(errs...)
+}
+
+// listen announces on the network address, as net.Listen, and registers the
+// listener to be closed by Shutdown.
+func (interp *Interpreter) listen(network, address string) (net.Listener, error) {
+	l, err := net.Listen(network, address)
+	if err == nil {
+		interp.shutdown.addListener(l)
+	}
+	return l, err
+}
+
+// fixNet redefines the listen functions of the net and net/http packages to
+// register the listeners of interpreted code to be closed by Shutdown.
+func fixNet(interp *Interpreter) {
+	s := interp.shutdown
+	if p := interp.binPkg["net"]; p != nil {
+		p["Listen"] = reflect.ValueOf(interp.listen)
+		p["ListenTCP"] = reflect.ValueOf(func(network string, laddr *net.TCPAddr) (*net.TCPListener, error) {
+			l, err := net.ListenTCP(network, laddr)
+			if err == nil {
+				s.addListener(l)
+			}
+			return l, err
+		})
+		p["ListenUnix"] = reflect.ValueOf(func(network string, laddr *net.UnixAddr) (*net.UnixListener, error) {
+			l, err := net.ListenUnix(network, laddr)
+			if err == nil {
+				s.addListener(l)
+			}
+			return l, err
+		})
+	}
+	if p := interp.binPkg["net/http"]; p != nil {
+		p["ListenAndServe"] = reflect.ValueOf(func(addr string, handler http.Handler) error {
+			if addr == "" {
+				addr = ":http"
+			}
+			l, err := interp.listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			return http.Serve(l, handler)
+		})
+		p["ListenAndServeTLS"] = reflect.ValueOf(func(addr, certFile, keyFile string, handler http.Handler) error {
+			if addr == "" {
+				addr = ":https"
+			}
+			l, err := interp.listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			if w := interp.wd; w != nil {
+				certFile, keyFile = w.resolve(certFile), w.resolve(keyFile)
+			}
+			return http.ServeTLS(l, handler, certFile, keyFile)
+		})
+	}
+}
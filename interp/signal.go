@@ -0,0 +1,208 @@
+package interp
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sync"
+)
+
+// signals are the signal handlers registered by interpreted code with the
+// os/signal package, to which Notify delivers synthetic signals. In
+// unrestricted mode, the handlers are also registered for the signals of the
+// process. It is safe for concurrent use.
+type signals struct {
+	mutex     sync.Mutex
+	process   bool                             // also register to process signals
+	handlers  map[chan<- os.Signal]*sigHandler // registered channels
+	ignored   map[os.Signal]bool               // ignored signals
+	ignoreAll bool                             // all signals are ignored, except the notified ones
+}
+
+// sigHandler is the set of signals relayed to a channel.
+type sigHandler struct {
+	all  bool // all signals are relayed
+	sigs map[os.Signal]bool
+}
+
+func newSignals(process bool) *signals {
+	return &signals{process: process, handlers: map[chan<- os.Signal]*sigHandler{}, ignored: map[os.Signal]bool{}}
+}
+
+// notify relays the signals sigs, or all signals if none, to c, as
+// signal.Notify.
+func (s *signals) notify(c chan<- os.Signal, sigs ...os.Signal) {
+	if c == nil {
+		panic("os/signal: Notify using nil channel")
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	h := s.handlers[c]
+	if h == nil {
+		h = &sigHandler{sigs: map[os.Signal]bool{}}
+		s.handlers[c] = h
+	}
+	if len(sigs) == 0 {
+		h.all = true
+		s.ignored, s.ignoreAll = map[os.Signal]bool{}, false
+	}
+	for _, sig := range sigs {
+		h.sigs[sig] = true
+		delete(s.ignored, sig)
+	}
+	if s.process {
+		signalNotify(c, sigs...)
+	}
+}
+
+// stop stops relaying signals to c, as signal.Stop.
+func (s *signals) stop(c chan<- os.Signal) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.handlers, c)
+	if s.process {
+		signalStop(c)
+	}
+}
+
+// ignore ignores the signals sigs, or all signals if none, as signal.Ignore.
+func (s *signals) ignore(sigs ...os.Signal) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.remove(sigs)
+	if len(sigs) == 0 {
+		s.ignoreAll = true
+	}
+	for _, sig := range sigs {
+		s.ignored[sig] = true
+	}
+	if s.process {
+		signalIgnore(sigs...)
+	}
+}
+
+// isIgnored returns true if sig is ignored, as signal.Ignored.
+func (s *signals) isIgnored(sig os.Signal) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.ignoredLocked(sig)
+}
+
+func (s *signals) ignoredLocked(sig os.Signal) bool {
+	if s.ignored[sig] {
+		return true
+	}
+	if !s.ignoreAll {
+		return false
+	}
+	for _, h := range s.handlers {
+		if h.all || h.sigs[sig] {
+			return false
+		}
+	}
+	return true
+}
+
+// reset undoes the effect of notify and ignore for the signals sigs, or all
+// signals if none, as signal.Reset.
+func (s *signals) reset(sigs ...os.Signal) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.remove(sigs)
+	if len(sigs) == 0 {
+		s.ignored, s.ignoreAll = map[os.Signal]bool{}, false
+	}
+	for _, sig := range sigs {
+		delete(s.ignored, sig)
+	}
+	if s.process {
+		signalReset(sigs...)
+	}
+}
+
+// remove stops relaying the signals sigs, or all signals if none, to all
+// channels. It must be called with s.mutex locked.
+func (s *signals) remove(sigs []os.Signal) {
+	if len(sigs) == 0 {
+		s.handlers = map[chan<- os.Signal]*sigHandler{}
+		return
+	}
+	for c, h := range s.handlers {
+		for _, sig := range sigs {
+			delete(h.sigs, sig)
+		}
+		if !h.all && len(h.sigs) == 0 {
+			delete(s.handlers, c)
+		}
+	}
+}
+
+// notifyContext returns a copy of parent which is done when one of the
+// signals sigs, or any signal if none, is received, as signal.NotifyContext.
+func (s *signals) notifyContext(parent context.Context, sigs ...os.Signal) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	c := make(chan os.Signal, 1)
+	s.notify(c, sigs...)
+	if ctx.Err() == nil {
+		go func() {
+			select {
+			case <-c:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, func() {
+		cancel()
+		s.stop(c)
+	}
+}
+
+// deliver sends sig to the channels relaying it, without blocking, and
+// returns true if at least one channel relays it.
+func (s *signals) deliver(sig os.Signal) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ignoredLocked(sig) {
+		return false
+	}
+	handled := false
+	for c, h := range s.handlers {
+		if !h.all && !h.sigs[sig] {
+			continue
+		}
+		handled = true
+		select {
+		case c <- sig:
+		default:
+		}
+	}
+	return handled
+}
+
+// Notify delivers the synthetic signal sig to interpreted code, on the
+// channels registered for it with signal.Notify, as the os/signal package
+// does for the signals of the process: without blocking, so a signal is
+// dropped for a channel which is not ready. The signal handling of the
+// process is not affected. Notify returns false if no channel is registered
+// for sig, or if it is ignored.
+func (interp *Interpreter) Notify(sig os.Signal) bool {
+	return interp.signals.deliver(sig)
+}
+
+// fixSignal redefines the symbols of the os/signal package to register the
+// signal handlers of interpreted code in the interpreter.
+func fixSignal(interp *Interpreter) {
+	p := interp.binPkg["os/signal"]
+	if p == nil {
+		return
+	}
+	s := interp.signals
+	p["Ignore"] = reflect.ValueOf(s.ignore)
+	p["Ignored"] = reflect.ValueOf(s.isIgnored)
+	p["Notify"] = reflect.ValueOf(s.notify)
+	p["NotifyContext"] = reflect.ValueOf(s.notifyContext)
+	p["Reset"] = reflect.ValueOf(s.reset)
+	p["Stop"] = reflect.ValueOf(s.stop)
+}
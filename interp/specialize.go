@@ -0,0 +1,241 @@
+package interp
+
+import "reflect"
+
+// Specialized builtins apply to append, copy, delete and map index
+// operations on slices and maps of some predeclared scalar types. Instead of
+// going through reflect.Append, reflect.Copy or reflect.Value.MapIndex, which
+// allocate, the closures access the Go values directly through pointers to
+// their locations in the frame. If a value is not addressable at run time,
+// the generic reflect operation is used instead.
+
+// scalar is the set of element types for which builtins are specialized.
+type scalar interface {
+	bool | int | int64 | uint8 | float64 | string
+}
+
+// scalarKey is the set of map key types for which builtins are specialized.
+type scalarKey interface {
+	int | string
+}
+
+// sliceBuiltins holds the specialized generators of builtins on a slice type.
+type sliceBuiltins struct {
+	append func(n *node, dest, value, value0 func(*frame) reflect.Value) bltn
+	copy   func(n *node) bltn
+}
+
+// mapBuiltins holds the specialized generators of builtins on a map type.
+type mapBuiltins struct {
+	delete func(n *node) bltn
+	index  func(n *node) bltn
+}
+
+// sliceSpecialized and mapSpecialized are indexed by element type, and by
+// key and element types.
+var (
+	sliceSpecialized = map[reflect.Type]sliceBuiltins{}
+	mapSpecialized   = map[[2]reflect.Type]mapBuiltins{}
+)
+
+func init() {
+	registerScalar[bool]()
+	registerScalar[int]()
+	registerScalar[int64]()
+	registerScalar[uint8]()
+	registerScalar[float64]()
+	registerScalar[string]()
+}
+
+func registerScalar[T scalar]() {
+	sliceSpecialized[reflect.TypeFor[T]()] = sliceBuiltins{appendScalar[T], copyScalar[T]}
+	registerMap[int, T]()
+	registerMap[string, T]()
+}
+
+func registerMap[K scalarKey, V scalar]() {
+	mapSpecialized[[2]reflect.Type{reflect.TypeFor[K](), reflect.TypeFor[V]()}] = mapBuiltins{deleteMap[K, V], indexMap[K, V]}
+}
+
+// valuePtr returns a pointer to the Go value held in v, or nil if v is not
+// addressable. The type of v must have the same memory layout as T.
+func valuePtr[T any](v reflect.Value) *T {
+	if !v.CanAddr() {
+		return nil
+	}
+	return (*T)(v.Addr().UnsafePointer())
+}
+
+// isSpecialized returns true if the result of builtin n can be set directly
+// in the frame.
+func isSpecialized(n *node) bool {
+	return !isOutputWrapped(n) && n.anc.kind != deferStmt && n.anc.kind != goStmt && n.findex >= 0
+}
+
+// appendFast generates a specialized exec for append of a single element to
+// a slice of scalar elements. It returns false if n does not qualify.
+func appendFast(n *node, dest, value, value0 func(*frame) reflect.Value) bool {
+	st := n.typ.TypeOf()
+	if !isSpecialized(n) || st.Kind() != reflect.Slice || n.typ.frameType() != st || n.child[1].typ.TypeOf() != st {
+		return false
+	}
+	b, ok := sliceSpecialized[st.Elem()]
+	if !ok {
+		return false
+	}
+	n.exec = b.append(n, dest, value, value0)
+	return true
+}
+
+func appendScalar[T scalar](n *node, dest, value, value0 func(*frame) reflect.Value) bltn {
+	next := getExec(n.tnext)
+
+	if c2 := n.child[2]; c2.rval.IsValid() {
+		x := c2.rval.Convert(n.typ.TypeOf().Elem()).Interface().(T)
+		return func(f *frame) bltn {
+			d, v := dest(f), value(f)
+			pd, ps := valuePtr[[]T](d), valuePtr[[]T](v)
+			if pd == nil || ps == nil {
+				d.Set(reflect.Append(v, value0(f)))
+				return next
+			}
+			*pd = append(*ps, x)
+			return next
+		}
+	}
+
+	return func(f *frame) bltn {
+		d, v, e := dest(f), value(f), value0(f)
+		pd, ps, pe := valuePtr[[]T](d), valuePtr[[]T](v), valuePtr[T](e)
+		if pd == nil || ps == nil || pe == nil {
+			d.Set(reflect.Append(v, e))
+			return next
+		}
+		*pd = append(*ps, *pe)
+		return next
+	}
+}
+
+// copyFast generates a specialized exec for copy between slices of scalar
+// elements. It returns false if n does not qualify.
+func copyFast(n *node) bool {
+	t1, t2 := n.child[1].typ.TypeOf(), n.child[2].typ.TypeOf()
+	if !isSpecialized(n) || t1.Kind() != reflect.Slice || t2.Kind() != reflect.Slice || t1.Elem() != t2.Elem() {
+		return false
+	}
+	b, ok := sliceSpecialized[t1.Elem()]
+	if !ok {
+		return false
+	}
+	n.exec = b.copy(n)
+	return true
+}
+
+func copyScalar[T scalar](n *node) bltn {
+	dest := genValue(n)
+	value0, value1 := genValue(n.child[1]), genValue(n.child[2])
+	next := getExec(n.tnext)
+
+	return func(f *frame) bltn {
+		v0, v1 := value0(f), value1(f)
+		p0, p1 := valuePtr[[]T](v0), valuePtr[[]T](v1)
+		if p0 == nil || p1 == nil {
+			dest(f).SetInt(int64(reflect.Copy(v0, v1)))
+			return next
+		}
+		dest(f).SetInt(int64(copy(*p0, *p1)))
+		return next
+	}
+}
+
+// mapFast returns the specialized generators of builtins on map node m,
+// indexed by key node k.
+func mapFast(m, k *node) (mapBuiltins, bool) {
+	t := m.typ.TypeOf()
+	if t.Kind() != reflect.Map || !k.rval.IsValid() && k.typ.TypeOf() != t.Key() {
+		return mapBuiltins{}, false
+	}
+	b, ok := mapSpecialized[[2]reflect.Type{t.Key(), t.Elem()}]
+	return b, ok
+}
+
+// deleteFast generates a specialized exec for delete in a map of scalar keys
+// and values. It returns false if n does not qualify.
+func deleteFast(n *node) bool {
+	if n.anc.kind == deferStmt || n.anc.kind == goStmt {
+		return false
+	}
+	b, ok := mapFast(n.child[1], n.child[2])
+	if !ok {
+		return false
+	}
+	n.exec = b.delete(n)
+	return true
+}
+
+// getIndexMapFast generates a specialized exec for the index expression n in
+// a map of scalar keys and values. It returns false if n does not qualify.
+func getIndexMapFast(n *node) bool {
+	if n.fnext != nil || n.typ.TypeOf() != n.typ.frameType() {
+		return false
+	}
+	b, ok := mapFast(n.child[0], n.child[1])
+	if !ok {
+		return false
+	}
+	n.exec = b.index(n)
+	return true
+}
+
+// genKey returns a function returning a pointer to the map key of node k, or
+// nil if the key is not addressable.
+func genKey[K scalarKey](k *node, kt reflect.Type) func(*frame) *K {
+	if k.rval.IsValid() {
+		key := k.rval.Convert(kt).Interface().(K)
+		return func(*frame) *K { return &key }
+	}
+	value := genValue(k)
+	return func(f *frame) *K { return valuePtr[K](value(f)) }
+}
+
+func deleteMap[K scalarKey, V scalar](n *node) bltn {
+	value0 := genValue(n.child[1])
+	value1 := genValue(n.child[2])
+	key := genKey[K](n.child[2], n.child[1].typ.TypeOf().Key())
+	next := getExec(n.tnext)
+
+	return func(f *frame) bltn {
+		m := value0(f)
+		pm, pk := valuePtr[map[K]V](m), key(f)
+		if pm == nil || pk == nil {
+			m.SetMapIndex(value1(f), reflect.Value{})
+			return next
+		}
+		delete(*pm, *pk)
+		return next
+	}
+}
+
+func indexMap[K scalarKey, V scalar](n *node) bltn {
+	dest := genValue(n)
+	value0 := genValue(n.child[0])
+	value1 := genValue(n.child[1])
+	key := genKey[K](n.child[1], n.child[0].typ.TypeOf().Key())
+	z := reflect.New(n.typ.TypeOf()).Elem()
+	next := getExec(n.tnext)
+
+	return func(f *frame) bltn {
+		d, m := dest(f), value0(f)
+		pd, pm, pk := valuePtr[V](d), valuePtr[map[K]V](m), key(f)
+		if pd == nil || pm == nil || pk == nil {
+			if v := m.MapIndex(value1(f)); v.IsValid() {
+				d.Set(v)
+			} else {
+				d.Set(z)
+			}
+			return next
+		}
+		*pd = (*pm)[*pk]
+		return next
+	}
+}
@@ -13,9 +13,12 @@ import (
 // importPath. rPath is the relative path to the directory containing the source
 // code for the package. It can also be "main" as a special value.
 func (interp *Interpreter) importSrc(rPath, importPath string, skipTest bool) (string, error) {
-	var dir string
-	var err error
+	return interp.loadSrc(rPath, importPath, skipTest, skipTest)
+}
 
+// loadSrc is the implementation of importSrc. If runMain is true and the
+// package is a main package, its main function is executed after init.
+func (interp *Interpreter) loadSrc(rPath, importPath string, skipTest, runMain bool) (string, error) {
 	if interp.srcPkg[importPath] != nil {
 		name, ok := interp.pkgNames[importPath]
 		if !ok {
@@ -24,30 +27,22 @@ func (interp *Interpreter) importSrc(rPath, importPath string, skipTest bool) (s
 		return name, nil
 	}
 
-	// For relative import paths in the form "./xxx" or "../xxx", the initial
-	// base path is the directory of the interpreter input file, or "." if no file
-	// was provided.
-	// In all other cases, absolute import paths are resolved from the GOPATH
-	// and the nested "vendor" directories.
-	if isPathRelative(importPath) {
-		if rPath == mainID {
-			rPath = "."
-		}
-		dir = path.Join(path.Dir(interp.name), rPath, importPath)
-	} else if dir, rPath, err = interp.pkgDir(filepath.ToSlash(interp.context.GOPATH), rPath, importPath); err != nil {
-		// Try again, assuming a root dir at the source location.
-		if rPath, err = interp.rootFromSourceLocation(); err != nil {
-			return "", err
-		}
-		if dir, rPath, err = interp.pkgDir(filepath.ToSlash(interp.context.GOPATH), rPath, importPath); err != nil {
-			return "", err
-		}
+	dir, rPath, err := interp.srcDir(rPath, importPath)
+	if err != nil {
+		return "", err
+	}
+
+	if err := interp.importCycle(importPath); err != nil {
+		return "", err
 	}
 
-	if interp.rdir[importPath] {
-		return "", fmt.Errorf("import cycle not allowed\n\timports %s", importPath)
+	lazy := interp.lazyImport(importPath)
+	if lazy {
+		// The uses of packages by the code of a lazy package do not initialize
+		// them: the packages it imports are initialized before it.
+		used := interp.lazyUsed
+		defer func() { interp.lazyUsed = used }()
 	}
-	interp.rdir[importPath] = true
 
 	files, err := fs.ReadDir(interp.opt.filesystem, dir)
 	if err != nil {
@@ -141,36 +136,77 @@ func (interp *Interpreter) importSrc(rPath, importPath string, skipTest bool) (s
 	interp.srcPkg[importPath] = gs.sym
 	interp.pkgNames[importPath] = pkgName
 
-	interp.frame.mutex.Lock()
 	interp.resizeFrame()
-	interp.frame.mutex.Unlock()
 	interp.mutex.Unlock()
+	interp.freezeCompiled(importPath)
 
-	// Once all package sources have been parsed, execute entry points then init functions.
-	for _, n := range rootNodes {
-		if err = genRun(n); err != nil {
-			return "", err
+	initPkg := func() error {
+		// Once all package sources have been parsed, execute entry points then init functions.
+		for _, n := range rootNodes {
+			if err := genRun(n); err != nil {
+				return err
+			}
+			interp.run(n, nil)
+		}
+
+		// Wire and execute global vars in global scope gs.
+		n, err := genGlobalVars(rootNodes, gs)
+		if err != nil {
+			return err
 		}
 		interp.run(n, nil)
+
+		// Add main to list of functions to run, after all inits.
+		if m := gs.sym[mainID]; pkgName == mainID && m != nil && runMain {
+			initNodes = append(initNodes, m.node)
+		}
+
+		for _, n := range initNodes {
+			interp.run(n, interp.frame.Load().forRun(interp.pkgRun))
+		}
+		return nil
 	}
 
-	// Wire and execute global vars in global scope gs.
-	n, err := genGlobalVars(rootNodes, gs)
-	if err != nil {
+	if lazy {
+		if interp.lazy == nil {
+			interp.lazy = map[string]*lazyPkg{}
+		}
+		interp.lazy[importPath] = &lazyPkg{init: initPkg}
+		return pkgName, nil
+	}
+	if err = interp.initUsed(); err != nil {
 		return "", err
 	}
-	interp.run(n, nil)
-
-	// Add main to list of functions to run, after all inits.
-	if m := gs.sym[mainID]; pkgName == mainID && m != nil && skipTest {
-		initNodes = append(initNodes, m.node)
+	if err = initPkg(); err != nil {
+		return "", err
 	}
+	return pkgName, nil
+}
 
-	for _, n := range initNodes {
-		interp.run(n, interp.frame)
+// srcDir returns the directory in filesystem of the source package identified
+// by importPath, and the root of the subtree dependencies.
+func (interp *Interpreter) srcDir(rPath, importPath string) (dir, root string, err error) {
+	// For relative import paths in the form "./xxx" or "../xxx", the initial
+	// base path is the directory of the interpreter input file, or "." if no file
+	// was provided.
+	// In all other cases, absolute import paths are resolved from the GOPATH
+	// and the nested "vendor" directories.
+	if isPathRelative(importPath) {
+		if rPath == mainID {
+			rPath = "."
+		}
+		return path.Join(path.Dir(interp.name), rPath, importPath), rPath, nil
 	}
-
-	return pkgName, nil
+	if dir, rPath, err = interp.pkgDir(filepath.ToSlash(interp.context.GOPATH), rPath, importPath); err != nil {
+		// Try again, assuming a root dir at the source location.
+		if rPath, err = interp.rootFromSourceLocation(); err != nil {
+			return "", "", err
+		}
+		if dir, rPath, err = interp.pkgDir(filepath.ToSlash(interp.context.GOPATH), rPath, importPath); err != nil {
+			return "", "", err
+		}
+	}
+	return dir, rPath, nil
 }
 
 // rootFromSourceLocation returns the path to the directory containing the input
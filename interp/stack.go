@@ -0,0 +1,220 @@
+//go:build !yaegi_small
+
+package interp
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+func (interp *Interpreter) FilteredStack() []byte {
+	return interp.FilterStack(debug.Stack())
+}
+
+func (interp *Interpreter) FilteredCallers() []uintptr {
+	pc := make([]uintptr, 64)
+	runtime.Callers(0, pc)
+	_, fPc := interp.FilterStackAndCallers(debug.Stack(), pc, 2)
+	return fPc
+}
+
+func (interp *Interpreter) FilterStack(stack []byte) []byte {
+	newStack, _ := interp.FilterStackAndCallers(stack, []uintptr{}, 2)
+	return newStack
+}
+
+// Given a runtime stacktrace and callers list, filter out the interpreter runtime
+// and replace it with the interpreted calls. Parses runtime stacktrace to figure
+// out which interp node by placing a magic value in parameters to runCfg and callBin
+func (interp *Interpreter) FilterStackAndCallers(stack []byte, callers []uintptr, skip int) ([]byte, []uintptr) {
+	return interp.filterStack(stack, callers, skip, nil)
+}
+
+// filterStack is FilterStackAndCallers, where the interpreted frames of
+// the runCfg calls of the stack are given by nodes, innermost first, as
+// recorded by the unwinding of a panic. The magic values in parameters are
+// unreliable with the register based calling convention, so they are only
+// used in the absence of recorded nodes.
+func (interp *Interpreter) filterStack(stack []byte, callers []uintptr, skip int, nodes []*node) ([]byte, []uintptr) {
+	recorded := len(nodes) > 0
+	newFrames := [][]string{}
+	newCallers := []uintptr{}
+
+	stackLines := strings.Split(string(stack), "\n")
+	lastFrame := len(stackLines)
+	skipFrame := 0
+
+	const (
+		notSyncedYet = -1
+		dontSync     = -2
+	)
+
+	// index to copy over from callers into newCallers
+	callersIndex := notSyncedYet // to indicate we haven't synced up with stack yet
+	if len(callers) == 0 {
+		callersIndex = dontSync // don't attempt to copy over from callers
+	}
+
+	// Parse stack in reverse order, because sometimes we want to skip frames
+	var lastInterpFrame int
+	for i := len(stackLines) - 1; i >= 0; i-- {
+		// Split stack trace into paragraphs (frames)
+		if len(stackLines[i]) == 0 || stackLines[i][0] == '\t' {
+			continue
+		}
+
+		if callersIndex > 0 {
+			callersIndex--
+		}
+
+		if skipFrame > 0 {
+			lastFrame = i
+			skipFrame--
+			continue
+		}
+
+		p := stackLines[i:lastFrame] // all lines in single frame
+		lastFrame = i
+
+		lastSlash := strings.LastIndex(p[0], "/")
+		funcPath := strings.Split(p[0][lastSlash+1:], ".")
+		pkgName := p[0][0:lastSlash+1] + funcPath[0]
+
+		if callersIndex >= 0 {
+			callName := runtime.FuncForPC(callers[callersIndex]).Name()
+			if callName != strings.Split(p[0], "(")[0] {
+				// for some reason runtime.gopanic shows up as panic in stacktrace
+				if callName != "runtime.gopanic" || strings.Split(p[0], "(")[0] != "panic" {
+					// since we're walking stack and callers at the same time they
+					// should be in sync. If not, we stop messing with callers
+					for ; callersIndex >= 0; callersIndex-- {
+						newCallers = append(newCallers, callers[callersIndex])
+					}
+					callersIndex = dontSync
+				}
+			}
+		}
+
+		// Don't touch any stack frames that aren't in the yaegi runtime
+		// Functions called on (*Interpreter) may provide information
+		// on how we entered yaegi, so we pass these through as well
+		if pkgName != selfPrefix+"/interp" || funcPath[1] == "(*Interpreter)" {
+			newFrames = append(newFrames, p)
+			if callersIndex >= 0 {
+				newCallers = append(newCallers, callers[callersIndex])
+			}
+			continue
+		}
+
+		// This is the first call into the interpreter, so try to sync callers
+		if callersIndex == notSyncedYet {
+			for j, call := range callers {
+				if call == 0 {
+					break
+				}
+				callName := runtime.FuncForPC(call).Name()
+				if callName == strings.Split(p[0], "(")[0] {
+					callersIndex = j
+				}
+			}
+			for j := len(callers) - 1; j > callersIndex; j-- {
+				if callers[j] != 0 {
+					newCallers = append(newCallers, callers[j])
+				}
+			}
+		}
+
+		var handle uintptr
+		var frameNode *node
+		originalExecNode := false
+
+		// A runCfg call refers to an interpreter level call
+		// grab callHandle from the first parameter to it
+		if strings.HasPrefix(funcPath[1], "runCfg(") {
+			if k := len(nodes); k > 0 {
+				// The stack is parsed from the outermost frame.
+				frameNode, nodes = nodes[k-1], nodes[:k-1]
+				handle = interp.addCall(frameNode)
+			} else if !recorded {
+				fmt.Sscanf(funcPath[1], "runCfg(%v,", &handle)
+			}
+		}
+
+		// capture node that panicked
+		if strings.HasPrefix(funcPath[1], "runCfgPanic(") && !recorded {
+			fmt.Sscanf(funcPath[1], "runCfgPanic(%v,", &handle)
+			originalExecNode = true
+		}
+
+		// callBin is a call to a binPkg
+		// the callHandle will be on the first or second function literal
+		if funcPath[1] == "callBin" &&
+			(strings.HasPrefix(funcPath[2], "func1(") ||
+				strings.HasPrefix(funcPath[2], "func2(")) {
+			if !recorded {
+				fmt.Sscanf(strings.Split(funcPath[2], "(")[1], "%v,", &handle)
+			}
+			// after a binary call, the next two frames will be reflect.Value.Call
+			skipFrame = 2
+		}
+
+		if handle != 0 {
+			if callersIndex >= 0 {
+				newCallers = append(newCallers, handle)
+			}
+			n, ok := interp.callNode(handle)
+
+			// Don't print scopes that weren't function calls
+			// (unless they're the node that caused the panic)
+			if !ok || (n.kind != callExpr && !originalExecNode && frameNode == nil) {
+				continue
+			}
+			if frameNode != nil && funcName(n) == "" {
+				// Statements evaluated outside of a function.
+				continue
+			}
+
+			pos := n.interp.fset.Position(n.pos)
+			newFrame := []string{
+				funcName(n) + "()",
+				fmt.Sprintf("\t%s", pos),
+			}
+
+			// we only find originalExecNode a few frames later
+			// so place it right after the last interpreted frame
+			if originalExecNode && len(newFrames) != lastInterpFrame {
+				newFrames = append(
+					newFrames[:lastInterpFrame+1],
+					newFrames[lastInterpFrame:]...)
+				newFrames[lastInterpFrame] = newFrame
+			} else {
+				newFrames = append(newFrames, newFrame)
+			}
+			lastInterpFrame = len(newFrames)
+		}
+	}
+
+	// reverse order because we parsed from bottom up, fix that now.
+	newStack := []string{}
+	newStack = append(newStack, newFrames[len(newFrames)-1]...) // skip after goroutine id
+	for i := len(newFrames) - 2 - skip; i >= 0; i-- {
+		newStack = append(newStack, newFrames[i]...)
+	}
+	unreversedNewCallers := []uintptr{}
+	if len(newCallers) == 0 {
+		if len(callers) >= skip {
+			unreversedNewCallers = callers[skip:] // just pass the original through
+		}
+	} else {
+		for i := len(newCallers) - 1 - skip; i >= 0; i-- {
+			unreversedNewCallers = append(unreversedNewCallers, newCallers[i])
+		}
+	}
+
+	newStackJoined := strings.Join(newStack, "\n")
+	newStackBytes := make([]byte, len(newStackJoined)-1)
+	copy(newStackBytes, newStackJoined)
+	return newStackBytes, unreversedNewCallers
+}
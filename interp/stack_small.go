@@ -0,0 +1,37 @@
+//go:build yaegi_small
+
+package interp
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// The filtering of the runtime stack traces, which replaces the frames of
+// the interpreter by the interpreted calls, is not available in the small
+// build profile: the stack traces are the ones of the runtime.
+
+func (interp *Interpreter) FilteredStack() []byte {
+	return debug.Stack()
+}
+
+func (interp *Interpreter) FilteredCallers() []uintptr {
+	pc := make([]uintptr, 64)
+	n := runtime.Callers(2, pc)
+	return pc[:n]
+}
+
+func (interp *Interpreter) FilterStack(stack []byte) []byte {
+	return stack
+}
+
+func (interp *Interpreter) FilterStackAndCallers(stack []byte, callers []uintptr, skip int) ([]byte, []uintptr) {
+	return interp.filterStack(stack, callers, skip, nil)
+}
+
+func (interp *Interpreter) filterStack(stack []byte, callers []uintptr, skip int, nodes []*node) ([]byte, []uintptr) {
+	if len(callers) >= skip {
+		callers = callers[skip:]
+	}
+	return stack, callers
+}
@@ -0,0 +1,146 @@
+package interp
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// RunStats is the resource usage of an execution of interpreted code, e.g.
+// to bill or throttle the scripts of a multi-tenant host. It is collected
+// if the interpreter is created with the CollectStats option, and returned
+// by EvalWithStats and Run.Stats.
+//
+// The counters cover the code executed by the run, including the goroutines
+// it started while they run. The concurrent executions in the same
+// interpreter are accounted separately.
+type RunStats struct {
+	WallTime   time.Duration // duration of the execution
+	Steps      int64         // CFG steps executed, i.e. node builtins run
+	Allocs     int64         // calls of the make, new and append builtins
+	Goroutines int64         // goroutines started by go statements
+	MaxDepth   int           // maximum depth of nested interpreted calls
+}
+
+// runStats holds the counters of the resource usage of a run.
+type runStats struct {
+	start      time.Time
+	end        atomic.Int64 // duration of the execution, once complete
+	steps      atomic.Int64
+	allocs     atomic.Int64
+	goroutines atomic.Int64
+	maxDepth   atomic.Int64
+}
+
+func newRunStats() *runStats { return &runStats{start: time.Now()} }
+
+// usage returns the resource usage counters of run r, or nil if r is nil or
+// its stats are not collected.
+func (r *runState) usage() *runStats {
+	if r == nil {
+		return nil
+	}
+	return r.stats
+}
+
+// wrapStats returns exec, the builtin of node n, instrumented to count its
+// executions in the stats of the run of its frame, and the allocations if n
+// is a call of an allocating builtin.
+func wrapStats(n *node, exec bltn) bltn {
+	if exec == nil {
+		return exec
+	}
+	if isAllocCall(n) {
+		return func(f *frame) bltn {
+			if s := f.run.usage(); s != nil {
+				s.steps.Add(1)
+				s.allocs.Add(1)
+			}
+			return exec(f)
+		}
+	}
+	return func(f *frame) bltn {
+		if s := f.run.usage(); s != nil {
+			s.steps.Add(1)
+		}
+		return exec(f)
+	}
+}
+
+// isAllocCall returns true if n is a call of the make, new or append builtin.
+func isAllocCall(n *node) bool {
+	if n.kind != callExpr || len(n.child) == 0 {
+		return false
+	}
+	c0 := n.child[0]
+	if c0.kind != identExpr || c0.sym == nil || c0.sym.kind != bltnSym {
+		return false
+	}
+	switch c0.ident {
+	case bltnMake, bltnNew, bltnAppend:
+		return true
+	}
+	return false
+}
+
+// startGo records the start of a goroutine by a go statement. It is a no-op
+// on a nil s.
+func (s *runStats) startGo() {
+	if s != nil {
+		s.goroutines.Add(1)
+	}
+}
+
+// enter records a call at the given depth. It is a no-op on a nil s.
+func (s *runStats) enter(depth int) {
+	for s != nil {
+		max := s.maxDepth.Load()
+		if int64(depth) <= max || s.maxDepth.CompareAndSwap(max, int64(depth)) {
+			return
+		}
+	}
+}
+
+// stop records the end of the execution. It is a no-op on a nil s.
+func (s *runStats) stop() {
+	if s != nil {
+		s.end.CompareAndSwap(0, int64(time.Since(s.start)))
+	}
+}
+
+// result returns the resource usage recorded by s, or a zero RunStats if s
+// is nil. The wall time of an incomplete execution is the time elapsed
+// since its start.
+func (s *runStats) result() RunStats {
+	if s == nil {
+		return RunStats{}
+	}
+	wall := time.Duration(s.end.Load())
+	if wall == 0 {
+		wall = time.Since(s.start)
+	}
+	return RunStats{
+		WallTime:   wall,
+		Steps:      s.steps.Load(),
+		Allocs:     s.allocs.Load(),
+		Goroutines: s.goroutines.Load(),
+		MaxDepth:   int(s.maxDepth.Load()),
+	}
+}
+
+// EvalWithStats evaluates src as Eval does, and also returns the resource
+// usage of its execution. It returns an error if the interpreter was not
+// created with the CollectStats option.
+func (interp *Interpreter) EvalWithStats(src string) (reflect.Value, RunStats, error) {
+	if !interp.stats {
+		return reflect.Value{}, RunStats{}, errors.New("stats not collected, see the CollectStats option")
+	}
+	prog, err := interp.compileEval(src, "", true, false)
+	if err != nil || interp.noRun {
+		return reflect.Value{}, RunStats{}, err
+	}
+	r := &runState{stats: newRunStats()}
+	res, err := interp.execute(prog, r)
+	return res, r.stats.result(), err
+}
@@ -0,0 +1,60 @@
+package interp
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// minStrCatLen is the minimum length of a concatenation result for which a
+// strCat buffer is used.
+const minStrCatLen = 64
+
+// A strCat performs the string concatenations of a "s += x" or "s = s + x"
+// statement.
+//
+// When s is the result of the previous concatenation, as when accumulating in
+// a loop, the new result is obtained by appending x in place to a growing
+// buffer shared by successive results, instead of copying s each time, which
+// has a quadratic cost. This is safe as strings are immutable: previous
+// results are prefixes of the buffer, which is never modified below its
+// length.
+type strCat struct {
+	mutex sync.Mutex
+	buf   []byte
+}
+
+// add returns the concatenation of s and x.
+func (c *strCat) add(s, x string) string {
+	if len(x) == 0 {
+		return s
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(s) > 0 && len(s) == len(c.buf) && unsafe.StringData(s) == &c.buf[0] {
+		c.buf = append(c.buf, x...)
+		return unsafe.String(&c.buf[0], len(c.buf))
+	}
+
+	n := len(s) + len(x)
+	if n < minStrCatLen {
+		c.buf = nil
+		return s + x
+	}
+	c.buf = make([]byte, 0, 2*n)
+	c.buf = append(c.buf, s...)
+	c.buf = append(c.buf, x...)
+	return unsafe.String(&c.buf[0], len(c.buf))
+}
+
+// isStrAccumulation returns true if the string concatenation n is the source
+// of an assignment to its first operand, as in "s = s + x".
+func isStrAccumulation(n *node) bool {
+	a := n.anc
+	if a.kind != assignStmt || a.action != aAssign || len(a.child) != 2 || a.child[1] != n {
+		return false
+	}
+	c0, dest := n.child[0], a.child[0]
+	return c0.sym != nil && c0.sym == dest.sym
+}
@@ -0,0 +1,79 @@
+package interp
+
+import (
+	"reflect"
+	"strings"
+)
+
+// A SyscallTable intercepts the symbols of the syscall and golang.org/x/sys
+// packages used by interpreted code, so a host can stub or deny the raw
+// system calls of scripts. The symbols are designated by their import path
+// and name, e.g. "syscall.Kill" or "golang.org/x/sys/unix.Mmap". The higher
+// level wrappers of the standard library, e.g. os.Open, remain available.
+type SyscallTable struct {
+	// Stubs are the replacements of symbols, indexed by symbol. A
+	// replacement must be of the type of the symbol it replaces.
+	Stubs map[string]interface{}
+
+	// Deny lists the symbols which can not be used. A symbol of the form
+	// "syscall.*" denies all the functions of a package, except the stubbed
+	// ones. Denied symbols are rejected at compilation.
+	Deny []string
+}
+
+// isSyscallPath returns true if the symbols of the package of import path p
+// are subject to the syscall table.
+func isSyscallPath(p string) bool {
+	return p == "syscall" || strings.HasPrefix(p, "golang.org/x/sys/")
+}
+
+// denied returns true if the symbol of importPath and name, of value v, is
+// denied by the table.
+func (t SyscallTable) denied(importPath, name string, v reflect.Value) bool {
+	if _, ok := t.Stubs[importPath+"."+name]; ok {
+		return false
+	}
+	for _, d := range t.Deny {
+		if d == importPath+"."+name || d == importPath+".*" && v.Kind() == reflect.Func {
+			return true
+		}
+	}
+	return false
+}
+
+// syscallSymbol returns the value of the symbol of importPath and name, of
+// value v, after application of the syscall table of the interpreter. It
+// returns false if the symbol is denied or its stub has not the right type.
+func (interp *Interpreter) syscallSymbol(importPath, name string, v reflect.Value) (reflect.Value, bool) {
+	t := interp.opt.syscalls
+	if t.denied(importPath, name, v) {
+		return reflect.Value{}, false
+	}
+	stub, ok := t.Stubs[importPath+"."+name]
+	if !ok {
+		return v, true
+	}
+	if reflect.TypeOf(stub) != v.Type() {
+		return reflect.Value{}, false
+	}
+	return reflect.ValueOf(stub), true
+}
+
+// checkSyscall returns an error at n if the syscall table of the interpreter
+// denies the symbol of importPath and name, or does not replace it correctly.
+func (interp *Interpreter) checkSyscall(n *node, importPath, name string) error {
+	v, ok := interp.binPkg[importPath][name]
+	if !ok || isBinType(v) {
+		return nil
+	}
+	t := interp.opt.syscalls
+	if t.denied(importPath, name, v) {
+		return n.cfgErrorf("use of %s.%s is denied by the syscall table", importPath, name)
+	}
+	if stub, ok := t.Stubs[importPath+"."+name]; ok {
+		if st := reflect.TypeOf(stub); st != v.Type() {
+			return n.cfgErrorf("stub of %s.%s has type %v, want %v", importPath, name, st, v.Type())
+		}
+	}
+	return nil
+}
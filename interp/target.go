@@ -0,0 +1,214 @@
+package interp
+
+import (
+	"errors"
+	"go/constant"
+	"path"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// targetPath implements the lexical operations of path/filepath for the
+// separators of a target operating system other than the host one.
+// Only slash and backslash separated paths are supported, the volume names
+// of Windows being drive letters and UNC prefixes.
+type targetPath struct {
+	sep     byte // path separator
+	listSep byte // path list separator
+	windows bool // paths have volume names, and slash is also a separator
+}
+
+// newTargetPath returns the path style of the operating system goos.
+func newTargetPath(goos string) *targetPath {
+	if goos == "windows" {
+		return &targetPath{sep: '\\', listSep: ';', windows: true}
+	}
+	return &targetPath{sep: '/', listSep: ':'}
+}
+
+func (t *targetPath) isSep(c uint8) bool { return c == t.sep || t.windows && c == '/' }
+
+func (t *targetPath) ToSlash(p string) string {
+	if t.sep == '/' {
+		return p
+	}
+	return strings.ReplaceAll(p, string(t.sep), "/")
+}
+
+func (t *targetPath) FromSlash(p string) string {
+	if t.sep == '/' {
+		return p
+	}
+	return strings.ReplaceAll(p, "/", string(t.sep))
+}
+
+func (t *targetPath) VolumeName(p string) string {
+	if !t.windows {
+		return ""
+	}
+	if len(p) >= 2 && p[1] == ':' && ('a' <= p[0] && p[0] <= 'z' || 'A' <= p[0] && p[0] <= 'Z') {
+		return p[:2]
+	}
+	// UNC path: \\host\share.
+	if len(p) < 5 || !t.isSep(p[0]) || !t.isSep(p[1]) || t.isSep(p[2]) {
+		return ""
+	}
+	n := 0
+	for i := 2; i < len(p); i++ {
+		if !t.isSep(p[i]) {
+			continue
+		}
+		if n++; n == 2 {
+			return p[:i]
+		}
+	}
+	return p
+}
+
+func (t *targetPath) IsAbs(p string) bool {
+	if !t.windows {
+		return strings.HasPrefix(p, "/")
+	}
+	vol := t.VolumeName(p)
+	if len(vol) > 2 {
+		return true // UNC path
+	}
+	p = p[len(vol):]
+	return vol != "" && p != "" && t.isSep(p[0])
+}
+
+func (t *targetPath) Clean(p string) string {
+	vol := t.VolumeName(p)
+	p = t.ToSlash(p[len(vol):])
+	if p == "" && vol != "" {
+		return vol + "."
+	}
+	return vol + t.FromSlash(path.Clean(p))
+}
+
+func (t *targetPath) Join(elem ...string) string {
+	for i, e := range elem {
+		if e != "" {
+			return t.Clean(strings.Join(elem[i:], string(t.sep)))
+		}
+	}
+	return ""
+}
+
+func (t *targetPath) Split(p string) (dir, file string) {
+	vol := t.VolumeName(p)
+	i := len(p) - 1
+	for i >= len(vol) && !t.isSep(p[i]) {
+		i--
+	}
+	return p[:i+1], p[i+1:]
+}
+
+func (t *targetPath) Base(p string) string {
+	if p == "" {
+		return "."
+	}
+	for len(p) > 0 && t.isSep(p[len(p)-1]) {
+		p = p[:len(p)-1]
+	}
+	p = p[len(t.VolumeName(p)):]
+	i := len(p) - 1
+	for i >= 0 && !t.isSep(p[i]) {
+		i--
+	}
+	if p = p[i+1:]; p == "" {
+		return string(t.sep)
+	}
+	return p
+}
+
+func (t *targetPath) Dir(p string) string {
+	vol := t.VolumeName(p)
+	i := len(p) - 1
+	for i >= len(vol) && !t.isSep(p[i]) {
+		i--
+	}
+	dir := t.Clean(p[len(vol) : i+1])
+	if dir == "." && len(vol) > 2 {
+		return vol // UNC volume name
+	}
+	return vol + dir
+}
+
+func (t *targetPath) Ext(p string) string {
+	for i := len(p) - 1; i >= 0 && !t.isSep(p[i]); i-- {
+		if p[i] == '.' {
+			return p[i:]
+		}
+	}
+	return ""
+}
+
+func (t *targetPath) SplitList(p string) []string {
+	if p == "" {
+		return []string{}
+	}
+	return strings.Split(p, string(t.listSep))
+}
+
+func (t *targetPath) Rel(basepath, targpath string) (string, error) {
+	bv, tv := t.VolumeName(basepath), t.VolumeName(targpath)
+	if !strings.EqualFold(bv, tv) {
+		return "", errors.New("Rel: can't make " + targpath + " relative to " + basepath)
+	}
+	rel, err := filepath.Rel(t.ToSlash(t.Clean(basepath)[len(bv):]), t.ToSlash(t.Clean(targpath)[len(tv):]))
+	if err != nil {
+		return "", errors.New("Rel: can't make " + targpath + " relative to " + basepath)
+	}
+	return t.FromSlash(rel), nil
+}
+
+func (t *targetPath) Match(pattern, name string) (bool, error) {
+	if t.windows {
+		// Backslash is a separator, not an escape character.
+		pattern, name = t.ToSlash(pattern), t.ToSlash(name)
+	}
+	return path.Match(pattern, name)
+}
+
+// fixTarget redefines the runtime symbols of the interpreter stdlib to match
+// the target operating system and architecture set by the GOOS and GOARCH
+// options, and the path symbols if the target path separators differ from
+// the host ones.
+func fixTarget(interp *Interpreter) {
+	goos, goarch := interp.context.GOOS, interp.context.GOARCH
+	if p := interp.binPkg["runtime"]; p != nil {
+		p["GOOS"] = reflect.ValueOf(goos)
+		p["GOARCH"] = reflect.ValueOf(goarch)
+	}
+	if (goos == "windows") == (runtime.GOOS == "windows") {
+		return // same path separators
+	}
+
+	t := newTargetPath(goos)
+	sep, listSep := constant.MakeInt64(int64(t.sep)), constant.MakeInt64(int64(t.listSep))
+	if p := interp.binPkg["os"]; p != nil {
+		p["PathSeparator"] = reflect.ValueOf(sep)
+		p["PathListSeparator"] = reflect.ValueOf(listSep)
+		p["IsPathSeparator"] = reflect.ValueOf(t.isSep)
+	}
+	if p := interp.binPkg["path/filepath"]; p != nil {
+		p["Separator"] = reflect.ValueOf(sep)
+		p["ListSeparator"] = reflect.ValueOf(listSep)
+		p["Base"] = reflect.ValueOf(t.Base)
+		p["Clean"] = reflect.ValueOf(t.Clean)
+		p["Dir"] = reflect.ValueOf(t.Dir)
+		p["Ext"] = reflect.ValueOf(t.Ext)
+		p["FromSlash"] = reflect.ValueOf(t.FromSlash)
+		p["IsAbs"] = reflect.ValueOf(t.IsAbs)
+		p["Join"] = reflect.ValueOf(t.Join)
+		p["Match"] = reflect.ValueOf(t.Match)
+		p["Rel"] = reflect.ValueOf(t.Rel)
+		p["Split"] = reflect.ValueOf(t.Split)
+		p["SplitList"] = reflect.ValueOf(t.SplitList)
+		p["ToSlash"] = reflect.ValueOf(t.ToSlash)
+		p["VolumeName"] = reflect.ValueOf(t.VolumeName)
+	}
+}
@@ -0,0 +1,309 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TestResult is the result of an interpreted test or subtest run by RunTests.
+type TestResult struct {
+	Name     string        // name of the test, as "TestFoo" or "TestFoo/sub" for a subtest
+	Package  string        // import path of the package, as given to EvalTest
+	Failed   bool          // true if the test or one of its subtests failed
+	Skipped  bool          // true if the test was skipped
+	Duration time.Duration // duration of the test, including its subtests
+	Logs     []string      // messages logged by the test, including errors and the panic, if any
+}
+
+// testTB is the subset of testing.TB implemented by the test shim. It
+// replaces testing.TB in interpreted code when the TestShim option is set.
+type testTB interface {
+	Cleanup(func())
+	Context() context.Context
+	Error(args ...any)
+	Errorf(format string, args ...any)
+	Fail()
+	FailNow()
+	Failed() bool
+	Fatal(args ...any)
+	Fatalf(format string, args ...any)
+	Helper()
+	Log(args ...any)
+	Logf(format string, args ...any)
+	Name() string
+	Setenv(key, value string)
+	Skip(args ...any)
+	SkipNow()
+	Skipf(format string, args ...any)
+	Skipped() bool
+	TempDir() string
+}
+
+// testT is a shim of testing.T, which replaces it in interpreted code when
+// the TestShim option is set. Subtests are run sequentially.
+type testT struct {
+	runner *testRunner
+	result *TestResult
+	parent *testT
+
+	mutex    sync.Mutex
+	cleanups []func()
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// testRunner runs the tests of a RunTests call.
+type testRunner struct {
+	interp  *Interpreter
+	pattern []*regexp.Regexp // per level of subtests
+	mutex   sync.Mutex
+	results []*TestResult
+}
+
+// RunTests runs the test functions of the packages evaluated by EvalTest
+// whose names match pattern, as the -run flag of "go test": pattern is split
+// by slashes into regular expressions matching the names of tests and of
+// their subtests at each level. It returns the results of the tests and
+// subtests which were run, in order of start, sorted by package. Failures
+// of tests are reported in results, not as an error.
+//
+// Test functions receive a shim of *testing.T, which requires the test
+// packages to be compiled with the TestShim option. The shim logs messages
+// in the results instead of printing them, runs subtests sequentially, and
+// ignores Parallel.
+func (interp *Interpreter) RunTests(pattern string) ([]TestResult, error) {
+	if !interp.opt.testShim {
+		return nil, errors.New("running tests requires the TestShim option")
+	}
+	r := &testRunner{interp: interp}
+	if pattern != "" {
+		for _, s := range strings.Split(pattern, "/") {
+			re, err := regexp.Compile(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid test pattern: %w", err)
+			}
+			r.pattern = append(r.pattern, re)
+		}
+	}
+
+	interp.mutex.RLock()
+	paths := append([]string(nil), interp.tested...)
+	interp.mutex.RUnlock()
+	if len(paths) == 0 {
+		return nil, errors.New("no test package evaluated")
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		syms := interp.Symbols(path)[path]
+		names := make([]string, 0, len(syms))
+		for name, sym := range syms {
+			if _, ok := sym.Interface().(func(*testT)); ok && strings.HasPrefix(name, "Test") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			r.run(path, name, nil, syms[name].Interface().(func(*testT)))
+		}
+	}
+
+	results := make([]TestResult, len(r.results))
+	for i, res := range r.results {
+		results[i] = *res
+	}
+	return results, nil
+}
+
+// match returns true if the test name at subtest level matches the pattern.
+func (r *testRunner) match(level int, name string) bool {
+	return level >= len(r.pattern) || r.pattern[level].MatchString(name)
+}
+
+// run runs the test fn named name, as a subtest of parent if not nil, and
+// returns false if it failed.
+func (r *testRunner) run(pkg, name string, parent *testT, fn func(*testT)) bool {
+	level := 0
+	if parent != nil {
+		level = strings.Count(parent.result.Name, "/") + 1
+		name = parent.result.Name + "/" + strings.ReplaceAll(name, " ", "_")
+	}
+	if !r.match(level, name[strings.LastIndex(name, "/")+1:]) {
+		return true
+	}
+
+	t := newTestT(r, &TestResult{Name: name, Package: pkg}, parent)
+	r.mutex.Lock()
+	r.results = append(r.results, t.result)
+	r.mutex.Unlock()
+
+	start := time.Now()
+	t.exec(func() { fn(t) })
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.result.Duration = time.Since(start)
+	if t.result.Failed && parent != nil {
+		parent.Fail()
+	}
+	return !t.result.Failed
+}
+
+func newTestT(r *testRunner, result *TestResult, parent *testT) *testT {
+	t := &testT{runner: r, result: result, parent: parent}
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	return t
+}
+
+// exec calls f, then the cleanup functions of test t. f is called in its
+// own goroutine, which FailNow and SkipNow exit, and a panic fails t.
+func (t *testT) exec(f func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			if p := recover(); p != nil {
+				t.fail(fmt.Sprintf("panic: %v", panicValue(p)))
+			}
+			t.cancel()
+			t.runCleanups()
+		}()
+		f()
+	}()
+	<-done
+}
+
+func (t *testT) runCleanups() {
+	for {
+		t.mutex.Lock()
+		n := len(t.cleanups)
+		if n == 0 {
+			t.mutex.Unlock()
+			return
+		}
+		f := t.cleanups[n-1]
+		t.cleanups = t.cleanups[:n-1]
+		t.mutex.Unlock()
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					t.fail(fmt.Sprintf("panic in cleanup: %v", panicValue(p)))
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func (t *testT) log(s string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.result.Logs = append(t.result.Logs, strings.TrimSuffix(s, "\n"))
+}
+
+func (t *testT) fail(s string) {
+	t.log(s)
+	t.Fail()
+}
+
+// Run runs f as a subtest of t called name, and reports whether it
+// succeeded.
+func (t *testT) Run(name string, f func(*testT)) bool {
+	return t.runner.run(t.result.Package, name, t, f)
+}
+
+// Parallel is ignored, as subtests are run sequentially.
+func (t *testT) Parallel() {}
+
+func (t *testT) Cleanup(f func()) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.cleanups = append(t.cleanups, f)
+}
+
+func (t *testT) Context() context.Context { return t.ctx }
+
+func (t *testT) Error(args ...any)                 { t.fail(fmt.Sprintln(args...)) }
+func (t *testT) Errorf(format string, args ...any) { t.fail(fmt.Sprintf(format, args...)) }
+
+func (t *testT) Fail() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.result.Failed = true
+}
+
+func (t *testT) FailNow() {
+	t.Fail()
+	runtime.Goexit()
+}
+
+func (t *testT) Failed() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.result.Failed
+}
+
+func (t *testT) Fatal(args ...any)                 { t.log(fmt.Sprintln(args...)); t.FailNow() }
+func (t *testT) Fatalf(format string, args ...any) { t.log(fmt.Sprintf(format, args...)); t.FailNow() }
+
+func (t *testT) Helper() {}
+
+func (t *testT) Log(args ...any)                 { t.log(fmt.Sprintln(args...)) }
+func (t *testT) Logf(format string, args ...any) { t.log(fmt.Sprintf(format, args...)) }
+
+func (t *testT) Name() string { return t.result.Name }
+
+// Setenv sets an environment variable of the interpreter for the duration
+// of the test.
+func (t *testT) Setenv(key, value string) {
+	lookup, set, unset := os.LookupEnv, os.Setenv, os.Unsetenv
+	if env := t.runner.interp.env; env != nil {
+		lookup, set = env.lookup, env.set
+		unset = func(key string) error { env.unset(key); return nil }
+	}
+	old, ok := lookup(key)
+	if err := set(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			_ = set(key, old)
+		} else {
+			_ = unset(key)
+		}
+	})
+}
+
+func (t *testT) Skip(args ...any)                 { t.log(fmt.Sprintln(args...)); t.SkipNow() }
+func (t *testT) Skipf(format string, args ...any) { t.log(fmt.Sprintf(format, args...)); t.SkipNow() }
+
+func (t *testT) SkipNow() {
+	t.mutex.Lock()
+	t.result.Skipped = true
+	t.mutex.Unlock()
+	runtime.Goexit()
+}
+
+func (t *testT) Skipped() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.result.Skipped
+}
+
+func (t *testT) TempDir() string {
+	dir, err := os.MkdirTemp("", "yaegi-test-")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
@@ -0,0 +1,160 @@
+package interp
+
+import (
+	"go/token"
+	"strconv"
+)
+
+// TraceKind is the kind of a trace event.
+type TraceKind int
+
+// Kinds of trace events.
+const (
+	TraceCall   TraceKind = iota // an interpreted function is entered
+	TraceReturn                  // an interpreted function returns, or is unwound by a panic
+	TraceLine                    // the execution reaches a new source line
+	TraceGo                      // a go statement starts a goroutine
+	TracePanic                   // a panic unwinds an interpreted function
+)
+
+var traceKindNames = [...]string{
+	TraceCall:   "call",
+	TraceReturn: "return",
+	TraceLine:   "line",
+	TraceGo:     "go",
+	TracePanic:  "panic",
+}
+
+func (k TraceKind) String() string {
+	if k >= 0 && int(k) < len(traceKindNames) {
+		return traceKindNames[k]
+	}
+	return "TraceKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// A TraceEvent is an execution step of interpreted code, reported to the
+// tracer set by SetTracer.
+type TraceEvent struct {
+	Kind  TraceKind
+	Pos   token.Position // source position of the function, line, go statement or panic
+	Func  string         // name of the function, as in stack traces
+	Frame uint64         // identifier of the function frame, unique per call
+	Value interface{}    // panic value, for TracePanic events
+}
+
+// tracer is the trace event handler of an interpreter.
+type tracer func(TraceEvent)
+
+// frameTrace is the trace state of a frame.
+type frameTrace struct {
+	id   uint64 // frame identifier
+	name string // function name
+	line int    // last traced line
+	node *node  // last traced node
+}
+
+// SetTracer sets fn to receive trace events from the execution of
+// interpreted code, or disables tracing if fn is nil. Events are delivered
+// synchronously by the goroutine executing the code, so fn must be safe for
+// concurrent use if the code starts goroutines.
+//
+// A panic event is delivered for each function frame unwound by the panic,
+// starting with the frame where it occurred, before its deferred calls.
+// Line events are only delivered for code run for the first time after
+// SetTracer is called with a non nil fn, which is then instrumented.
+func (interp *Interpreter) SetTracer(fn func(TraceEvent)) {
+	if fn == nil {
+		interp.tracer.Store(nil)
+		return
+	}
+	t := tracer(fn)
+	interp.tracer.Store(&t)
+}
+
+// frameTrace returns the trace state of frame f, created if needed.
+func (interp *Interpreter) frameTrace(f *frame) *frameTrace {
+	if f.trace == nil {
+		f.trace = &frameTrace{id: interp.traceFrames.Add(1)}
+	}
+	return f.trace
+}
+
+// traceCall reports the call of function def in frame f, and returns the
+// function reporting its return.
+func (interp *Interpreter) traceCall(t tracer, f *frame, def *node) func() {
+	ft := interp.frameTrace(f)
+	ft.name = funcName(def)
+	ev := TraceEvent{
+		Kind:  TraceCall,
+		Pos:   interp.fset.Position(def.pos),
+		Func:  ft.name,
+		Frame: ft.id,
+	}
+	t(ev)
+	return func() {
+		ev.Kind = TraceReturn
+		if t := interp.tracer.Load(); t != nil {
+			(*t)(ev)
+		}
+	}
+}
+
+// tracePanic reports a panic of value v in frame f, raised or propagated by
+// the last traced node of f, or else by the node of builtin exec in the CFG
+// starting at n.
+func (interp *Interpreter) tracePanic(n *node, exec bltn, f *frame, v interface{}) {
+	t := interp.tracer.Load()
+	if t == nil {
+		return
+	}
+	ft := interp.frameTrace(f)
+	o := ft.node
+	if o == nil {
+		if o = originalExecNode(n, exec); o == nil {
+			o = n
+		}
+	}
+	(*t)(TraceEvent{
+		Kind:  TracePanic,
+		Pos:   interp.fset.Position(o.pos),
+		Func:  ft.name,
+		Frame: ft.id,
+		Value: panicValue(v),
+	})
+}
+
+// traceGo reports the go statement of node n in frame f.
+func (interp *Interpreter) traceGo(n *node, f *frame) {
+	t := interp.tracer.Load()
+	if t == nil {
+		return
+	}
+	ft := interp.frameTrace(f)
+	(*t)(TraceEvent{
+		Kind:  TraceGo,
+		Pos:   interp.fset.Position(n.pos),
+		Func:  ft.name,
+		Frame: ft.id,
+	})
+}
+
+// traceLine returns exec, the builtin of node n, instrumented to report
+// the execution of a new source line.
+func (interp *Interpreter) traceLine(n *node, exec bltn) bltn {
+	if exec == nil || n.pos == token.NoPos {
+		return exec
+	}
+	pos := interp.fset.Position(n.pos)
+
+	return func(f *frame) bltn {
+		if t := interp.tracer.Load(); t != nil {
+			ft := interp.frameTrace(f)
+			ft.node = n
+			if ft.line != pos.Line {
+				ft.line = pos.Line
+				(*t)(TraceEvent{Kind: TraceLine, Pos: pos, Func: ft.name, Frame: ft.id})
+			}
+		}
+		return exec(f)
+	}
+}
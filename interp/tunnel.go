@@ -0,0 +1,56 @@
+package interp
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A CallbackPanic is the value of a panic raised by interpreted code called
+// back by binary code, e.g. by a host function or a package function taking
+// a func parameter, as recovered by the binary frames. It lets host code
+// inspect the panic value as a Go value, while the original interpreted
+// value is restored when the panic reenters interpreted frames, to be
+// recovered there with its interpreted type.
+//
+// A CallbackPanic is an error, which unwraps to its value if it is an error.
+type CallbackPanic struct {
+	// Value is the panic value, as exposed to the host in Panic.Value.
+	Value interface{}
+
+	interp *Interpreter // interpreter of the value
+	raw    interface{}  // value as raised by interpreted code
+}
+
+func (p *CallbackPanic) Error() string { return fmt.Sprint(p.Value) }
+
+// Unwrap returns the value of the panic if it is an error, so that errors.Is
+// and errors.As inspect the error chain of the panic value.
+func (p *CallbackPanic) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// tunnelPanic is deferred by the interpreted functions called from binary
+// frames. It raises the panic of the function, if any, to the caller as a
+// CallbackPanic.
+func (interp *Interpreter) tunnelPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if _, ok := r.(reflect.Value); !ok {
+		// Binary panic value, already usable by host code, or a panic
+		// already tunnelled by a nested callback.
+		panic(r)
+	}
+	panic(&CallbackPanic{Value: interp.exportPanic(r), interp: interp, raw: r})
+}
+
+// restorePanic returns the original value of the panic r if it was raised
+// by tunnelPanic of interp, or r otherwise.
+func (interp *Interpreter) restorePanic(r interface{}) interface{} {
+	if p, ok := r.(*CallbackPanic); ok && p.interp == interp {
+		return p.raw
+	}
+	return r
+}
@@ -3,6 +3,7 @@ package interp
 import (
 	"fmt"
 	"go/constant"
+	"go/token"
 	"path"
 	"reflect"
 	"strconv"
@@ -134,6 +135,7 @@ type itype struct {
 	node         *node         // root AST node of type definition
 	scope        *scope        // type declaration scope (in case of re-parse incomplete type)
 	str          string        // String representation of the type
+	tversion     *typeVersion  // declaration version of a global defined type, or nil
 	incomplete   bool          // true if type must be parsed again (out of order declarations)
 	untyped      bool          // true for a literal value (string or number)
 	isBinMethod  bool          // true if the type refers to a bin method function
@@ -665,6 +667,18 @@ func nodeType2(interp *Interpreter, sc *scope, n *node, seen []*node) (t *itype,
 				t = sc.getType("int")
 			case bltnAppend, bltnMake:
 				t, err = nodeType2(interp, sc, n.child[1], seen)
+			case bltnMax, bltnMin:
+				// The result has the type of the first typed argument, or else the
+				// widest kind of untyped constant.
+				for _, c := range n.child[1:] {
+					var ct *itype
+					if ct, err = nodeType2(interp, sc, c, seen); err != nil {
+						return nil, err
+					}
+					if t == nil || t.untyped && (!ct.untyped || untypedRank(ct) > untypedRank(t)) {
+						t = ct
+					}
+				}
 			case bltnNew:
 				t, err = nodeType2(interp, sc, n.child[1], seen)
 				incomplete := t.incomplete
@@ -982,6 +996,11 @@ func nodeType2(interp *Interpreter, sc *scope, n *node, seen []*node) (t *itype,
 		name := n.child[1].ident
 		switch lt.cat {
 		case binPkgT:
+			if lt.path == "unsafe" {
+				if err = interp.checkUnsafeSymbol(n, name); err != nil {
+					return nil, err
+				}
+			}
 			pkg := interp.binPkg[lt.path]
 			if v, ok := pkg[name]; ok {
 				rtype := v.Type()
@@ -997,12 +1016,17 @@ func nodeType2(interp *Interpreter, sc *scope, n *node, seen []*node) (t *itype,
 		case srcPkgT:
 			if pkg, ok := interp.srcPkg[lt.path]; ok {
 				if s, ok := pkg[name]; ok {
+					interp.useSrcPkg(lt.path)
 					t = s.typ
 					break
 				}
 			}
 			err = n.cfgErrorf("undefined selector %s.%s", lt.path, name)
 		default:
+			if n.child[0].isType(sc) {
+				t, err = methodExprType(n, lt, name)
+				break
+			}
 			if m, _ := lt.lookupMethod(name); m != nil {
 				t, err = nodeType2(interp, sc, m.child[2], seen)
 			} else if bm, _, _, ok := lt.lookupBinMethod(name); ok {
@@ -1130,6 +1154,13 @@ func nodeType2(interp *Interpreter, sc *scope, n *node, seen []*node) (t *itype,
 }
 
 func genType(interp *Interpreter, sc *scope, name string, lt *itype, types []*itype, seen []*node) (t *itype, err error) {
+	// Reuse the instance, with its compiled methods, if already generated
+	// from another scope.
+	if sym := lt.scope.sym[name]; sym != nil && sym.kind == typeSym && sym.typ != nil && !sym.typ.incomplete {
+		sc.sym[name] = sym
+		return sym.typ, nil
+	}
+
 	// A generic type is being instantiated. Generate it.
 	g, _, err := genAST(sc, lt.node.anc, types)
 	if err != nil {
@@ -1140,10 +1171,18 @@ func genType(interp *Interpreter, sc *scope, name string, lt *itype, types []*it
 		return nil, err
 	}
 	lt.instance = append(lt.instance, t)
-	// Add generated symbol in the scope of generic source and user.
+	// Add generated symbol in the scope of generic source and user. In the
+	// scope of the generic source, e.g. for the receivers of its methods, the
+	// name can also be used unqualified by the package.
 	sc.sym[name] = &symbol{index: -1, kind: typeSym, typ: t, node: g}
-	if lt.scope.sym[name] == nil {
-		lt.scope.sym[name] = sc.sym[name]
+	lname := name
+	if i := strings.Index(name, "["); i > 0 {
+		lname = name[strings.LastIndex(name[:i], ".")+1:]
+	}
+	for _, n := range []string{name, lname} {
+		if lt.scope.sym[n] == nil {
+			lt.scope.sym[n] = sc.sym[name]
+		}
 	}
 
 	for _, nod := range lt.method {
@@ -1296,11 +1335,17 @@ func (t *itype) finalize() (*itype, error) {
 	return t, err
 }
 
+// addMethod adds the method n to t, replacing a method of the same name
+// declared by a previous evaluation.
 func (t *itype) addMethod(n *node) {
-	for _, m := range t.method {
+	for i, m := range t.method {
 		if m == n {
 			return
 		}
+		if n.ident != "" && m.ident == n.ident {
+			t.method[i] = n
+			return
+		}
 	}
 	t.method = append(t.method, n)
 }
@@ -1351,7 +1396,7 @@ func (t *itype) numOut() int {
 		}
 	case builtinT:
 		switch t.name {
-		case "append", "cap", "complex", "copy", "imag", "len", "make", "new", "real", "recover", "unsafe.Alignof", "unsafe.Offsetof", "unsafe.Sizeof":
+		case "append", "cap", "complex", "copy", "imag", "len", "make", "max", "min", "new", "real", "recover", "unsafe.Alignof", "unsafe.Offsetof", "unsafe.Sizeof":
 			return 1
 		}
 	}
@@ -1547,7 +1592,7 @@ func (t *itype) equals(o *itype) bool {
 	case oi && !ti:
 		return t.methods().contains(o.methods())
 	default:
-		return t.id() == o.id()
+		return t.id() == o.id() && sameVersion(t, o)
 	}
 }
 
@@ -1974,6 +2019,7 @@ func exportName(s string) string {
 var (
 	// TODO(mpl): generators.
 	emptyInterfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+	errorType          = reflect.TypeOf((*error)(nil)).Elem()
 	valueInterfaceType = reflect.TypeOf((*valueInterface)(nil)).Elem()
 	constVal           = reflect.TypeOf((*constant.Value)(nil)).Elem()
 )
@@ -2153,9 +2199,10 @@ func (t *itype) refType(ctx *refTypeContext) reflect.Type {
 				Type: f.typ.refType(ctx),
 				Tag:  reflect.StructTag(f.tag),
 			}
-			if len(t.field) == 1 && f.embed {
+			if f.embed && (len(t.field) == 1 || isStruct(f.typ) && !isBin(f.typ) && noMethods(field.Type)) {
 				// Mark the field as embedded (anonymous) only if it is the
-				// only one, to avoid a panic due to golang/go#15924 issue.
+				// only one, or if it is an interpreter struct, which has no
+				// methods, to avoid a panic due to golang/go#15924 issue.
 				field.Anonymous = true
 			}
 			fields = append(fields, field)
@@ -2252,18 +2299,104 @@ func (t *itype) frameType() (r reflect.Type) {
 }
 
 func (t *itype) implements(it *itype) bool {
+	// Note: in case of a valueInterfaceType, we
+	// miss required data which will be available
+	// later, so we optimistically return true to progress,
+	// and additional checks will be hopefully performed at
+	// runtime.
+	if rt := it.TypeOf(); rt == valueInterfaceType {
+		return true
+	}
 	if isBin(t) {
-		// Note: in case of a valueInterfaceType, we
-		// miss required data which will be available
-		// later, so we optimistically return true to progress,
-		// and additional checks will be hopefully performed at
-		// runtime.
-		if rt := it.TypeOf(); rt == valueInterfaceType {
+		return t.TypeOf().Implements(it.TypeOf())
+	}
+	ms := it.methods()
+	if isBin(it) && hasUnexportedMethods(it.TypeOf()) {
+		// The unexported methods of a binary interface can only be
+		// provided by an embedded binary type implementing it.
+		if t.embeddedImplementer(it.TypeOf()) == nil {
+			return false
+		}
+		for name := range ms {
+			if !token.IsExported(name) {
+				delete(ms, name)
+			}
+		}
+	}
+	return t.methods().contains(ms)
+}
+
+// hasUnexportedMethods returns true if the interface type rt has unexported
+// methods, which can not be implemented by interpreted types.
+func hasUnexportedMethods(rt reflect.Type) bool {
+	if rt.Kind() != reflect.Interface {
+		return false
+	}
+	for i := 0; i < rt.NumMethod(); i++ {
+		if !rt.Method(i).IsExported() {
 			return true
 		}
-		return t.TypeOf().Implements(it.TypeOf())
 	}
-	return t.methods().contains(it.methods())
+	return false
+}
+
+// unexportedMismatch returns an error explaining why the interpreted type t,
+// having the exported methods of the binary interface o, does not implement
+// its unexported methods, or nil.
+func unexportedMismatch(n *node, t, o *itype) error {
+	if isBin(t) || !isBin(o) || !hasUnexportedMethods(o.TypeOf()) {
+		return nil
+	}
+	rt := o.TypeOf()
+	ms := t.methods()
+	for i := 0; i < rt.NumMethod(); i++ {
+		if _, ok := ms[rt.Method(i).Name]; !ok && rt.Method(i).IsExported() {
+			return nil // not a matter of unexported methods
+		}
+	}
+	for i := 0; i < rt.NumMethod(); i++ {
+		if m := rt.Method(i); !m.IsExported() {
+			return n.cfgErrorf("cannot use type %s as type %s: missing unexported method %s, embed a type of package %s implementing it",
+				t.id(), o.id(), m.Name, rt.PkgPath())
+		}
+	}
+	return nil
+}
+
+// embeddedImplementer returns the index of the embedded field of the struct
+// type t, possibly nested, whose binary type implements the interface rt,
+// or nil if not found.
+func (t *itype) embeddedImplementer(rt reflect.Type) []int {
+	return t.embeddedImplementer2(rt, map[*itype]bool{})
+}
+
+func (t *itype) embeddedImplementer2(rt reflect.Type, seen map[*itype]bool) []int {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	switch t.cat {
+	case linkedT, ptrT:
+		return t.val.embeddedImplementer2(rt, seen)
+	case structT:
+	default:
+		return nil
+	}
+	for i, f := range t.field {
+		if !f.embed {
+			continue
+		}
+		if isBin(f.typ) {
+			if f.typ.TypeOf().Implements(rt) {
+				return []int{i}
+			}
+			continue
+		}
+		if index := f.typ.embeddedImplementer2(rt, seen); index != nil {
+			return append([]int{i}, index...)
+		}
+	}
+	return nil
 }
 
 // defaultType returns the default type of an untyped type.
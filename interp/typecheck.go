@@ -55,6 +55,12 @@ func (check typecheck) assignment(n *node, typ *itype, context string) error {
 	}
 
 	if !n.typ.assignableTo(typ) && typ.str != "*unsafe2.dummy" {
+		if err := versionMismatch(n, n.typ, typ); err != nil {
+			return err
+		}
+		if err := unexportedMismatch(n, n.typ, typ); err != nil {
+			return err
+		}
 		if context == "" {
 			return n.cfgErrorf("cannot use type %s as type %s", n.typ.id(), typ.id())
 		}
@@ -727,6 +733,7 @@ var builtinFuncs = map[string]struct {
 	bltnAlignof:  {args: 1, variadic: false},
 	bltnAppend:   {args: 1, variadic: true},
 	bltnCap:      {args: 1, variadic: false},
+	bltnClear:    {args: 1, variadic: false},
 	bltnClose:    {args: 1, variadic: false},
 	bltnComplex:  {args: 2, variadic: false},
 	bltnImag:     {args: 1, variadic: false},
@@ -734,6 +741,8 @@ var builtinFuncs = map[string]struct {
 	bltnDelete:   {args: 2, variadic: false},
 	bltnLen:      {args: 1, variadic: false},
 	bltnMake:     {args: 1, variadic: true},
+	bltnMax:      {args: 1, variadic: true},
+	bltnMin:      {args: 1, variadic: true},
 	bltnNew:      {args: 1, variadic: false},
 	bltnOffsetof: {args: 1, variadic: false},
 	bltnPanic:    {args: 1, variadic: false},
@@ -810,6 +819,11 @@ func (check typecheck) builtin(name string, n *node, child []*node, ellipsis boo
 		if !ok {
 			return params[0].nod.cfgErrorf("invalid argument for %s", name)
 		}
+	case bltnClear:
+		typ := params[0].Type()
+		if k := typ.TypeOf().Kind(); k != reflect.Map && k != reflect.Slice {
+			return params[0].nod.cfgErrorf("invalid argument: %s must be a map or slice", typ.id())
+		}
 	case bltnClose:
 		p := params[0]
 		typ := p.Type()
@@ -919,6 +933,27 @@ func (check typecheck) builtin(name string, n *node, child []*node, ellipsis boo
 			return n.cfgErrorf("len larger than cap in make")
 		}
 
+	case bltnMax, bltnMin:
+		// Untyped constants are converted to the type of the first typed
+		// argument, or else to the widest kind of untyped constant.
+		var typ *itype
+		for _, p := range params {
+			t := p.Type()
+			if typ == nil || typ.untyped && (!t.untyped || untypedRank(t) > untypedRank(typ)) {
+				typ = t
+			}
+		}
+		for _, p := range params {
+			if err := check.convertUntyped(p.nod, typ); err != nil {
+				return err
+			}
+			if t := p.Type(); !t.untyped && !t.equals(typ) || t.untyped && !typ.untyped {
+				return p.nod.cfgErrorf("invalid argument: mismatched types %s (previous argument) and %s", typ.id(), t.id())
+			}
+		}
+		if !typ.ordered() {
+			return params[0].nod.cfgErrorf("invalid argument: %s cannot be ordered", typ.id())
+		}
 	case bltnPanic:
 		return check.assignment(params[0].nod, check.scope.getType("interface{}"), "argument to panic")
 	case bltnPrint, bltnPrintln:
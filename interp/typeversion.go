@@ -0,0 +1,69 @@
+package interp
+
+// typeVersion identifies a declaration of a global defined type. In REPL mode,
+// successive evaluations can redeclare a type with the same name but a
+// different definition, whose values are not interchangeable. Versions allow
+// to tell the declarations apart and to report their mix clearly.
+type typeVersion struct {
+	version int // declaration number of the type name, from 1
+	eval    int // number of the compilation which declared the type
+}
+
+// addTypeVersion records the declaration of the global defined type t.
+func (interp *Interpreter) addTypeVersion(t *itype) {
+	key := t.path + "." + t.name
+	last := interp.types[key]
+	if last != nil && last.eval == interp.evals {
+		// Type declared again in the same compilation, e.g. on revisit.
+		t.tversion = last
+		return
+	}
+	t.tversion = &typeVersion{version: 1, eval: interp.evals}
+	if last != nil {
+		t.tversion.version = last.version + 1
+	}
+	interp.types[key] = t.tversion
+}
+
+// sameVersion returns false if t and o, or their element types, are different
+// declarations of a type.
+func sameVersion(t, o *itype) bool {
+	for t != nil && o != nil {
+		if t.tversion != nil && o.tversion != nil && t.tversion != o.tversion {
+			return false
+		}
+		switch t.cat {
+		case arrayT, chanT, chanRecvT, chanSendT, ptrT, sliceT, variadicT:
+			t, o = t.val, o.val
+		default:
+			return true
+		}
+	}
+	return true
+}
+
+// typeVersionOf returns the declaration of t or of its element type, or nil.
+func typeVersionOf(t *itype) *typeVersion {
+	for ; t != nil; t = t.val {
+		if t.tversion != nil {
+			return t.tversion
+		}
+		switch t.cat {
+		case arrayT, chanT, chanRecvT, chanSendT, ptrT, sliceT, variadicT:
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// versionMismatch returns an error if the value n of type t can not be used as
+// type o because they are different declarations of a same type.
+func versionMismatch(n *node, t, o *itype) error {
+	if t.id() != o.id() || sameVersion(t, o) {
+		return nil
+	}
+	td, od := typeVersionOf(t), typeVersionOf(o)
+	return n.cfgErrorf("value of %s (v%d, declared eval#%d) used where %s (v%d, declared eval#%d) expected",
+		t.id(), td.version, td.eval, o.id(), od.version, od.eval)
+}
@@ -0,0 +1,83 @@
+package interp
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// An UnsafePolicy controls what interpreted code may do with the unsafe
+// package. The symbols of the unsafe package must still be provided to the
+// interpreter with Use, e.g. from github.com/breadchris/yaegi/stdlib/unsafe.
+type UnsafePolicy int
+
+// Unsafe policies, from the most to the least restrictive.
+const (
+	// UnsafeForbidden rejects the import of the unsafe package. It is the
+	// default policy.
+	UnsafeForbidden UnsafePolicy = iota
+
+	// UnsafeSizes allows the unsafe.Sizeof, unsafe.Alignof and
+	// unsafe.Offsetof functions only, which do not give access to memory.
+	UnsafeSizes
+
+	// UnsafeUnrestricted allows all the symbols of the unsafe package,
+	// including unsafe.Pointer and the conversions between pointers,
+	// unsafe.Pointer and uintptr.
+	UnsafeUnrestricted
+)
+
+func (p UnsafePolicy) String() string {
+	switch p {
+	case UnsafeForbidden:
+		return "UnsafeForbidden"
+	case UnsafeSizes:
+		return "UnsafeSizes"
+	case UnsafeUnrestricted:
+		return "UnsafeUnrestricted"
+	}
+	return "UnsafePolicy(" + strconv.Itoa(int(p)) + ")"
+}
+
+// checkUnsafeImport returns an error at n if the unsafe policy of the
+// interpreter forbids the import of the unsafe package.
+func (interp *Interpreter) checkUnsafeImport(n *node) error {
+	if interp.opt.unsafe > UnsafeForbidden {
+		return nil
+	}
+	return n.cfgErrorf("import of package unsafe is not allowed (unsafe policy is %s)", interp.opt.unsafe)
+}
+
+// unsafeAllowed returns true if the unsafe policy of the interpreter allows
+// the use of the symbol name of the unsafe package.
+func (interp *Interpreter) unsafeAllowed(name string) bool {
+	switch interp.opt.unsafe {
+	case UnsafeUnrestricted:
+		return true
+	case UnsafeSizes:
+		return name == "Sizeof" || name == "Alignof" || name == "Offsetof"
+	}
+	return false
+}
+
+// checkUnsafeSymbol returns an error at n if the unsafe policy of the
+// interpreter forbids the use of the symbol name of the unsafe package.
+func (interp *Interpreter) checkUnsafeSymbol(n *node, name string) error {
+	if interp.unsafeAllowed(name) {
+		return nil
+	}
+	return n.cfgErrorf("use of unsafe.%s is not allowed (unsafe policy is %s)", name, interp.opt.unsafe)
+}
+
+// checkUnsafeConversion returns an error at n if the conversion of a value of
+// type from to type to involves unsafe.Pointer and is forbidden by the unsafe
+// policy of the interpreter.
+func (interp *Interpreter) checkUnsafeConversion(n *node, from, to *itype) error {
+	if interp.opt.unsafe >= UnsafeUnrestricted {
+		return nil
+	}
+	ft, tt := from.TypeOf(), to.TypeOf()
+	if ft == nil || tt == nil || (ft.Kind() == reflect.UnsafePointer) == (tt.Kind() == reflect.UnsafePointer) {
+		return nil
+	}
+	return n.cfgErrorf("conversion of %s to %s is not allowed (unsafe policy is %s)", from.id(), to.id(), interp.opt.unsafe)
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"go/constant"
+	"io"
 	"log"
 	"math/bits"
 	"os"
@@ -17,6 +18,12 @@ import (
 // import path. If the argument is the empty string, all known symbols are
 // returned.
 func (interp *Interpreter) Symbols(importPath string) Exports {
+	interp.initPending(importPath)
+	if importPath != "" {
+		interp.compile.Lock()
+		_ = interp.loadProvided(importPath)
+		interp.compile.Unlock()
+	}
 	m := map[string]map[string]reflect.Value{}
 	interp.mutex.RLock()
 	defer interp.mutex.RUnlock()
@@ -35,9 +42,9 @@ func (interp *Interpreter) Symbols(importPath string) Exports {
 			case constSym:
 				syms[n] = s.rval
 			case funcSym:
-				syms[n] = genFunctionWrapper(s.node)(interp.frame)
+				syms[n] = genFunctionWrapper(s.node)(interp.frame.Load())
 			case varSym:
-				syms[n] = interp.frame.data[s.index]
+				syms[n] = interp.frame.Load().data[s.index]
 			case typeSym:
 				syms[n] = reflect.New(s.typ.TypeOf())
 			}
@@ -70,13 +77,17 @@ func (interp *Interpreter) Symbols(importPath string) Exports {
 }
 
 // getWrapper returns the wrapper type of the corresponding interface, trying
-// first the composed ones, or nil if not found.
+// first the composed ones, or nil if not found. The wrapper of an instance of
+// a generic interface is named after the instance, e.g. "_Getter[int]".
 func getWrapper(n *node, t reflect.Type) reflect.Type {
 	p, ok := n.interp.binPkg[t.PkgPath()]
 	if !ok {
 		return nil
 	}
-	w := p["_"+t.Name()]
+	w, ok := p["_"+t.Name()]
+	if !ok {
+		return nil
+	}
 	lm := n.typ.methods()
 
 	// mapTypes may contain composed interfaces wrappers to test against, from
@@ -84,9 +95,16 @@ func getWrapper(n *node, t reflect.Type) reflect.Type {
 	// first for which the interpreter type has all the methods.
 	for _, rt := range n.interp.mapTypes[w] {
 		match := true
-		for i := 1; i < rt.NumField(); i++ {
+		for i := 1; i <= wrapperMethods(rt); i++ {
 			// The interpreter type must have all required wrapper methods.
-			if _, ok := lm[rt.Field(i).Name[1:]]; !ok {
+			f := rt.Field(i)
+			if _, ok := lm[f.Name[1:]]; !ok {
+				match = false
+				break
+			}
+			// The interpreted methods must also fit the wrapper fields,
+			// e.g. for methods of the same name but different signatures.
+			if m, _ := n.typ.lookupMethod(f.Name[1:]); m != nil && m.typ.cat == funcT && !m.typ.TypeOf().AssignableTo(f.Type) {
 				match = false
 				break
 			}
@@ -100,16 +118,37 @@ func getWrapper(n *node, t reflect.Type) reflect.Type {
 	return w.Type().Elem()
 }
 
+// wrapperMethods returns the number of method fields of the interface
+// wrapper type w, which follow its IValue field.
+func wrapperMethods(w reflect.Type) int {
+	if wrapperFallback(w) > 0 {
+		return w.NumField() - 2
+	}
+	return w.NumField() - 1
+}
+
+// wrapperFallback returns the index of the fallback field of the interface
+// wrapper type w, or -1 if w has none. The wrapper of an interface with
+// unexported methods, which can not be defined by the wrapper, embeds the
+// interface as its last field, to be set to a binary value implementing them.
+func wrapperFallback(w reflect.Type) int {
+	if i := w.NumField() - 1; i > 0 && w.Field(i).Anonymous {
+		return i
+	}
+	return -1
+}
+
 // Use loads binary runtime symbols in the interpreter context so
-// they can be used in interpreted code.
+// they can be used in interpreted code. The symbols of a package can be
+// provided lazily, on first import, see Lazy.
 // Use "." for the package path to directly import variables into
 // the interpreter package scope, so they can be referred to as if
 // they were declared using `var` statements.
 func (interp *Interpreter) Use(values Exports) error {
+	defer interp.hooks.clearSymbols() // binary symbols are changed
+
 	for k, v := range values {
 		importPath := path.Dir(k)
-		packageName := path.Base(k)
-		// fmt.Println(importPath, packageName)
 
 		if k == "." && v["MapTypes"].IsValid() {
 			// Use mapping for special interface wrappers.
@@ -136,37 +175,34 @@ func (interp *Interpreter) Use(values Exports) error {
 			continue
 		}
 
-		if interp.binPkg[importPath] == nil {
-			interp.binPkg[importPath] = make(map[string]reflect.Value)
-			interp.pkgNames[importPath] = packageName
-		}
-
-		for s, sym := range v {
-			interp.binPkg[importPath][s] = sym
-		}
-		if k == selfPath {
-			interp.binPkg[importPath]["Self"] = reflect.ValueOf(interp)
+		if interp.addProvider(k, v) {
+			continue
 		}
+		interp.addBinPkg(k, v)
 	}
 
 	for k, v := range values {
-		packageName := path.Base(k)
-		for _, sym := range v {
-			if gf, ok := sym.Interface().(GenericFunc); ok {
-				str := fmt.Sprintf("package %s\nimport . %q\n%s", packageName, path.Dir(k), string(gf))
-				if _, err := interp.Compile(str); err != nil {
-					return err
-				}
-			}
+		if err := interp.compileGenerics(k, v, func(src string) error {
+			_, err := interp.Compile(src)
+			return err
+		}); err != nil {
+			return err
 		}
 	}
 
+	if interp.execPolicy != nil {
+		fixExec(interp)
+	}
+
 	// Checks if input values correspond to stdlib packages by looking for one
 	// well known stdlib package path.
 	if _, ok := values["fmt/fmt"]; ok {
 		fixStdlib(interp)
 
-		// Load stdlib generic source.
+		// Load stdlib generic source, which is not subject to compile limits.
+		limits := interp.limits
+		interp.limits = CompileLimits{}
+		defer func() { interp.limits = limits }()
 		for _, s := range gen.Sources {
 			if _, err := interp.Compile(s); err != nil {
 				return err
@@ -176,6 +212,9 @@ func (interp *Interpreter) Use(values Exports) error {
 	return nil
 }
 
+// writerVar returns an io.Writer variable set to w.
+func writerVar(w io.Writer) reflect.Value { return reflect.ValueOf(&w).Elem() }
+
 // fixStdlib redefines interpreter stdlib symbols to use the standard input,
 // output and errror assigned to the interpreter. The changes are limited to
 // the interpreter only.
@@ -189,9 +228,20 @@ func fixStdlib(interp *Interpreter) {
 
 	stdin, stdout, stderr := interp.stdin, interp.stdout, interp.stderr
 
-	p["Print"] = reflect.ValueOf(func(a ...interface{}) (n int, err error) { return fmt.Fprint(stdout, a...) })
-	p["Printf"] = reflect.ValueOf(func(f string, a ...interface{}) (n int, err error) { return fmt.Fprintf(stdout, f, a...) })
-	p["Println"] = reflect.ValueOf(func(a ...interface{}) (n int, err error) { return fmt.Fprintln(stdout, a...) })
+	// The print functions honor the output scopes set by WithOutput.
+	fmtPrint := func(w io.Writer) reflect.Value {
+		return reflect.ValueOf(func(a ...interface{}) (n int, err error) { return fmt.Fprint(w, a...) })
+	}
+	fmtPrintf := func(w io.Writer) reflect.Value {
+		return reflect.ValueOf(func(f string, a ...interface{}) (n int, err error) { return fmt.Fprintf(w, f, a...) })
+	}
+	fmtPrintln := func(w io.Writer) reflect.Value {
+		return reflect.ValueOf(func(a ...interface{}) (n int, err error) { return fmt.Fprintln(w, a...) })
+	}
+	p["Print"], p["Printf"], p["Println"] = fmtPrint(stdout), fmtPrintf(stdout), fmtPrintln(stdout)
+	interp.scopeSymbol(p["Print"], false, fmtPrint)
+	interp.scopeSymbol(p["Printf"], false, fmtPrintf)
+	interp.scopeSymbol(p["Println"], false, fmtPrintln)
 
 	p["Scan"] = reflect.ValueOf(func(a ...interface{}) (n int, err error) { return fmt.Fscan(stdin, a...) })
 	p["Scanf"] = reflect.ValueOf(func(f string, a ...interface{}) (n int, err error) { return fmt.Fscanf(stdin, f, a...) })
@@ -211,6 +261,12 @@ func fixStdlib(interp *Interpreter) {
 		p["CommandLine"] = reflect.ValueOf(&c).Elem()
 	}
 
+	if p = interp.binPkg["testing"]; p != nil && interp.testShim {
+		p["T"] = reflect.ValueOf((*testT)(nil))
+		p["F"] = reflect.ValueOf((*testF)(nil))
+		p["TB"] = reflect.ValueOf((*testTB)(nil))
+	}
+
 	if p = interp.binPkg["log"]; p != nil {
 		l := log.New(stderr, "", log.LstdFlags)
 		// Restrict Fatal symbols to panic instead of exit.
@@ -231,6 +287,20 @@ func fixStdlib(interp *Interpreter) {
 		p["SetOutput"] = reflect.ValueOf(l.SetOutput)
 		p["SetPrefix"] = reflect.ValueOf(l.SetPrefix)
 		p["Writer"] = reflect.ValueOf(l.Writer)
+		// The output functions honor the output scopes set by WithOutput,
+		// through a copy of the logger writing to the scope.
+		for name, method := range map[string]string{
+			"Fatal": "Panic", "Fatalf": "Panicf", "Fatalln": "Panicln",
+			"Panic": "Panic", "Panicf": "Panicf", "Panicln": "Panicln",
+			"Print": "Print", "Printf": "Printf", "Println": "Println",
+		} {
+			interp.scopeSymbol(p[name], true, func(w io.Writer) reflect.Value {
+				return reflect.ValueOf(log.New(w, l.Prefix(), l.Flags())).MethodByName(method)
+			})
+		}
+		if interp.logSink != nil {
+			interp.sinkLog(p)
+		}
 
 		// Update mapTypes to virtualized symbols as well.
 		interp.mapTypes[p["Print"]] = interp.mapTypes[reflect.ValueOf(log.Print)]
@@ -243,41 +313,46 @@ func fixStdlib(interp *Interpreter) {
 
 	if p = interp.binPkg["os"]; p != nil {
 		p["Args"] = reflect.ValueOf(&interp.args).Elem()
-		if interp.specialStdio {
-			// Inherit streams from interpreter even if they do not have a file descriptor.
+		// Inherits streams from interpreter if they have a file descriptor and preserve
+		// original type, or even if they do not have a file descriptor with specialStdio.
+		if s, ok := stdin.(*os.File); ok {
+			p["Stdin"] = reflect.ValueOf(&s).Elem()
+		} else if interp.specialStdio || interp.recStdin {
 			p["Stdin"] = reflect.ValueOf(&stdin).Elem()
+		}
+		if s, ok := stdout.(*os.File); ok {
+			p["Stdout"] = reflect.ValueOf(&s).Elem()
+		} else if interp.specialStdio {
 			p["Stdout"] = reflect.ValueOf(&stdout).Elem()
+			interp.scopeSymbol(p["Stdout"], false, writerVar)
+		}
+		if s, ok := stderr.(*os.File); ok {
+			p["Stderr"] = reflect.ValueOf(&s).Elem()
+		} else if interp.specialStdio {
 			p["Stderr"] = reflect.ValueOf(&stderr).Elem()
-		} else {
-			// Inherits streams from interpreter only if they have a file descriptor and preserve original type.
-			if s, ok := stdin.(*os.File); ok {
-				p["Stdin"] = reflect.ValueOf(&s).Elem()
-			}
-			if s, ok := stdout.(*os.File); ok {
-				p["Stdout"] = reflect.ValueOf(&s).Elem()
-			}
-			if s, ok := stderr.(*os.File); ok {
-				p["Stderr"] = reflect.ValueOf(&s).Elem()
-			}
+			interp.scopeSymbol(p["Stderr"], true, writerVar)
 		}
-		if !interp.unrestricted {
-			// In restricted mode, scripts can only access to a passed virtualized env, and can not write the real one.
-			getenv := func(key string) string { return interp.env[key] }
-			p["Clearenv"] = reflect.ValueOf(func() { interp.env = map[string]string{} })
-			p["ExpandEnv"] = reflect.ValueOf(func(s string) string { return os.Expand(s, getenv) })
-			p["Getenv"] = reflect.ValueOf(getenv)
-			p["LookupEnv"] = reflect.ValueOf(func(key string) (s string, ok bool) { s, ok = interp.env[key]; return })
-			p["Setenv"] = reflect.ValueOf(func(key, value string) error { interp.env[key] = value; return nil })
-			p["Unsetenv"] = reflect.ValueOf(func(key string) error { delete(interp.env, key); return nil })
-			p["Environ"] = reflect.ValueOf(func() (a []string) {
-				for k, v := range interp.env {
-					a = append(a, k+"="+v)
-				}
-				return
-			})
+		if env := interp.env; env != nil {
+			// Scripts can only access to a virtualized env, and can not write the real one.
+			p["Clearenv"] = reflect.ValueOf(env.clear)
+			p["Environ"] = reflect.ValueOf(env.list)
+			p["ExpandEnv"] = reflect.ValueOf(func(s string) string { return os.Expand(s, env.get) })
+			p["Getenv"] = reflect.ValueOf(env.get)
+			p["LookupEnv"] = reflect.ValueOf(env.lookup)
+			p["Setenv"] = reflect.ValueOf(env.set)
+			p["Unsetenv"] = reflect.ValueOf(func(key string) error { env.unset(key); return nil })
 		}
 	}
 
+	fixTime(interp)
+	fixRand(interp)
+	fixErrors(interp)
+	fixReflect(interp)
+	fixWorkdir(interp)
+	fixSignal(interp)
+	fixNet(interp)
+	fixTarget(interp)
+
 	if p = interp.binPkg["math/bits"]; p != nil {
 		// Do not trust extracted value maybe from another arch.
 		p["UintSize"] = reflect.ValueOf(constant.MakeInt64(bits.UintSize))
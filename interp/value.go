@@ -53,22 +53,25 @@ func genValueRecv(n *node) func(*frame) reflect.Value {
 		return v
 	}
 
-	return func(f *frame) reflect.Value {
-		r := v(f)
-		for _, i := range fi {
-			if r.Kind() == reflect.Ptr {
-				r = r.Elem()
-			}
-			// Note that we can't use reflect FieldByIndex method, as we may
-			// traverse valueInterface wrappers to access the embedded receiver.
-			r = r.Field(i)
-			vi, ok := r.Interface().(valueInterface)
-			if ok {
-				r = vi.value
-			}
+	return func(f *frame) reflect.Value { return fieldRecv(v(f), fi) }
+}
+
+// fieldRecv returns the embedded field of r at index path, which is the
+// receiver of a promoted method.
+func fieldRecv(r reflect.Value, path []int) reflect.Value {
+	for _, i := range path {
+		if r.Kind() == reflect.Ptr {
+			r = r.Elem()
+		}
+		// Note that we can't use reflect FieldByIndex method, as we may
+		// traverse valueInterface wrappers to access the embedded receiver.
+		r = r.Field(i)
+		vi, ok := r.Interface().(valueInterface)
+		if ok {
+			r = vi.value
 		}
-		return r
 	}
+	return r
 }
 
 func genValueAsFunctionWrapper(n *node) func(*frame) reflect.Value {
@@ -128,6 +131,11 @@ func genValue(n *node) func(*frame) reflect.Value {
 		if n.rval.IsValid() {
 			convertConstantValue(n)
 			v := n.rval
+			if n.interp != nil {
+				if s, ok := n.interp.scopedSyms[v]; ok {
+					return s.value(v)
+				}
+			}
 			return func(f *frame) reflect.Value { return v }
 		}
 		if n.sym != nil {
@@ -173,6 +181,9 @@ func genDestValue(typ *itype, n *node) func(*frame) reflect.Value {
 }
 
 func genFuncValue(n *node) func(*frame) reflect.Value {
+	if isMethodExpr(n) {
+		return genFunctionWrapper(n)
+	}
 	value := genValue(n)
 	return func(f *frame) reflect.Value {
 		v := value(f)
@@ -351,6 +362,17 @@ func valueInterfaceValue(v reflect.Value) reflect.Value {
 	return v
 }
 
+// ifaceValue returns the value of the interface v for comparisons, which is
+// the interpreted value held by an interface wrapper, so the errors wrapping
+// a same value, e.g. returned by errors.Unwrap, compare equal as in Go.
+func ifaceValue(v reflect.Value) interface{} {
+	i := v.Interface()
+	if w := reflect.ValueOf(i); w.Kind() == reflect.Struct && isWrapper(w.Type()) {
+		return w.Field(0).Interface()
+	}
+	return i
+}
+
 func genValueInterfaceValue(n *node) func(*frame) reflect.Value {
 	value := genValue(n)
 
@@ -0,0 +1,184 @@
+package interp
+
+import (
+	"context"
+	"hash/fnv"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// watchInterval is the delay between two checks of source changes by Watch.
+var watchInterval = 500 * time.Millisecond
+
+// Watch evaluates the Go source file or package directory at path, as
+// EvalPath, then watches the source tree for changes until ctx is done, and
+// re-evaluates it at each change. The function onReload, if not nil, is
+// called after each evaluation, once it returns, with its resulting error.
+//
+// A source file is reloaded with ReloadPath, so package state is preserved
+// and existing references to functions use the new code. A package directory
+// is recompiled entirely, and its main function, if any, is executed again:
+// symbols previously obtained from the package must then be retrieved again,
+// e.g. from onReload.
+//
+// Changes are detected by polling the interpreter source filesystem, while
+// the evaluation is running. If it is still running at a change, e.g. the
+// main function of a server, the interpreted program is first stopped with
+// Shutdown, and the evaluation waited for, so that the reload never runs
+// concurrently with the previous code. The main function of a source file is
+// then executed again after the reload. A main function blocked in a call of
+// binary code not interrupted by Shutdown delays the reload until the call
+// returns.
+//
+// Watch returns the context error once ctx is done, the execution of the
+// running evaluation being cancelled, or an error if the package directory
+// can not be found.
+func (interp *Interpreter) Watch(ctx context.Context, path string, onReload func(error)) error {
+	path = filepath.ToSlash(path)
+	isDir := !isFile(interp.opt.filesystem, path)
+	dir := path
+	if isDir {
+		var err error
+		if dir, _, err = interp.srcDir(mainID, path); err != nil {
+			return err
+		}
+	}
+	report := func(err error) {
+		if onReload != nil {
+			onReload(err)
+		}
+	}
+	evalPath := func(r *runState) error {
+		_, err := interp.evalPath(path, r)
+		return err
+	}
+
+	sum := interp.sourceSum(dir)
+	w := interp.watchEval(ctx, evalPath)
+	loaded := false
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		var done chan struct{}
+		if w != nil {
+			done = w.done
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-done:
+			loaded = loaded || w.err == nil
+			report(w.err)
+			w = nil
+			continue
+		case <-ticker.C:
+		}
+
+		s := interp.sourceSum(dir)
+		if s == sum {
+			continue
+		}
+		sum = s
+
+		running := w != nil
+		if running {
+			// Stop the program, and wait for the end of its evaluation.
+			// The errors of the listeners and cleanups are not the ones
+			// of the evaluation, and are not reported.
+			_ = interp.Shutdown(ctx)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-w.done:
+			}
+			loaded = loaded || w.err == nil
+			w = nil
+		}
+
+		switch {
+		case isDir:
+			interp.forgetSrc(path)
+			w = interp.watchEval(ctx, evalPath)
+		case loaded:
+			if err := interp.ReloadPath(path); err != nil || !running || !interp.hasMain() {
+				report(err)
+				break
+			}
+			w = interp.watchEval(ctx, func(r *runState) error {
+				_, err := interp.eval(mainID+"()", "", true, r)
+				return err
+			})
+		default:
+			w = interp.watchEval(ctx, evalPath)
+		}
+	}
+}
+
+// watchRun is an evaluation started by Watch in its own goroutine.
+type watchRun struct {
+	done chan struct{} // closed once the evaluation has returned
+	err  error         // error of the evaluation, set before done is closed
+}
+
+// watchEval starts the evaluation eval in a run cancelled once ctx is done.
+func (interp *Interpreter) watchEval(ctx context.Context, eval func(r *runState) error) *watchRun {
+	w := &watchRun{done: make(chan struct{})}
+	r := interp.newRun(ctx)
+	go func() {
+		_, w.err = interp.runWithContext(ctx, r, func() (reflect.Value, error) {
+			return reflect.Value{}, eval(r)
+		})
+		<-r.cancel.ended
+		close(w.done)
+	}()
+	return w
+}
+
+// hasMain returns true if the main package declares a main function.
+func (interp *Interpreter) hasMain() bool {
+	interp.mutex.RLock()
+	defer interp.mutex.RUnlock()
+	sc := interp.scopes[mainID]
+	if sc == nil {
+		return false
+	}
+	m := sc.sym[mainID]
+	return m != nil && m.kind == funcSym
+}
+
+// sourceSum returns a checksum of the Go source files located at path,
+// recursively if path is a directory.
+func (interp *Interpreter) sourceSum(root string) uint64 {
+	h := fnv.New64a()
+	_ = fs.WalkDir(interp.opt.filesystem, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, ".go") {
+			return nil
+		}
+		b, err := fs.ReadFile(interp.opt.filesystem, p)
+		if err != nil {
+			return nil
+		}
+		h.Write([]byte(p))
+		h.Write(b)
+		return nil
+	})
+	return h.Sum64()
+}
+
+// forgetSrc removes from the interpreter the source package of the given
+// import path, so it can be imported again.
+func (interp *Interpreter) forgetSrc(importPath string) {
+	interp.mutex.Lock()
+	defer interp.mutex.Unlock()
+
+	delete(interp.srcPkg, importPath)
+	delete(interp.pkgNames, importPath)
+	delete(interp.scopes, importPath)
+	delete(interp.srcImports, importPath)
+	delete(interp.lazy, importPath)
+}
@@ -0,0 +1,178 @@
+package interp
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// workdir is the virtual current working directory of an interpreter, against
+// which the relative paths of the file functions of interpreted code are
+// resolved. It is safe for concurrent use.
+type workdir struct {
+	mutex sync.RWMutex
+	dir   string // absolute path
+}
+
+func (w *workdir) get() string {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.dir
+}
+
+// resolve returns the path name relative to the working directory.
+func (w *workdir) resolve(name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(w.get(), name)
+}
+
+// chdir changes the working directory, as os.Chdir.
+func (w *workdir) chdir(dir string) error {
+	d := w.resolve(dir)
+	fi, err := os.Stat(d)
+	if err != nil {
+		if pe, ok := err.(*fs.PathError); ok {
+			err = pe.Err
+		}
+		return &fs.PathError{Op: "chdir", Path: dir, Err: err}
+	}
+	if !fi.IsDir() {
+		return &fs.PathError{Op: "chdir", Path: dir, Err: syscall.ENOTDIR}
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.dir = d
+	return nil
+}
+
+// abs returns the absolute path of name, as filepath.Abs.
+func (w *workdir) abs(name string) (string, error) {
+	return w.resolve(filepath.Clean(name)), nil
+}
+
+// rel returns the path p, under the resolution of name, as if name had not
+// been resolved.
+func (w *workdir) rel(name, resolved, p string) string {
+	if name == resolved || p == resolved {
+		return name
+	}
+	return filepath.Join(name, strings.TrimPrefix(p, resolved))
+}
+
+// walk walks the file tree rooted at root, as filepath.Walk.
+func (w *workdir) walk(root string, fn filepath.WalkFunc) error {
+	r := w.resolve(root)
+	return filepath.Walk(r, func(p string, info fs.FileInfo, err error) error {
+		return fn(w.rel(root, r, p), info, err)
+	})
+}
+
+// walkDir walks the file tree rooted at root, as filepath.WalkDir.
+func (w *workdir) walkDir(root string, fn fs.WalkDirFunc) error {
+	r := w.resolve(root)
+	return filepath.WalkDir(r, func(p string, d fs.DirEntry, err error) error {
+		return fn(w.rel(root, r, p), d, err)
+	})
+}
+
+// glob returns the names of the files matching pattern, as filepath.Glob.
+func (w *workdir) glob(pattern string) ([]string, error) {
+	r := w.resolve(pattern)
+	matches, err := filepath.Glob(r)
+	if r != pattern {
+		dir := w.get()
+		if !strings.HasSuffix(dir, string(filepath.Separator)) {
+			dir += string(filepath.Separator)
+		}
+		for i, m := range matches {
+			matches[i] = strings.TrimPrefix(m, dir)
+		}
+	}
+	return matches, err
+}
+
+// wrap returns the function fn, whose string arguments of indices args are
+// resolved against the working directory. The path of the fs.PathError
+// errors on resolved paths are restored to the ones given to fn, and so is
+// the name of a returned file, which is opened on the first path.
+func (w *workdir) wrap(fn reflect.Value, args ...int) reflect.Value {
+	return reflect.MakeFunc(fn.Type(), func(in []reflect.Value) []reflect.Value {
+		names := map[string]string{}
+		for _, i := range args {
+			name := in[i].String()
+			r := w.resolve(name)
+			names[r] = name
+			in[i] = reflect.ValueOf(r)
+		}
+		out := fn.Call(in)
+		if f, ok := out[0].Interface().(*os.File); ok && f != nil {
+			if r := in[args[0]].String(); names[r] != r {
+				out[0] = reflect.ValueOf(renameFile(f, w.rel(names[r], r, f.Name())))
+			}
+		}
+		if n := len(out); n > 0 && out[n-1].Kind() == reflect.Interface && !out[n-1].IsNil() {
+			if pe, ok := out[n-1].Interface().(*fs.PathError); ok {
+				if name, ok := names[pe.Path]; ok {
+					pe.Path = name
+				}
+			}
+		}
+		return out
+	})
+}
+
+// fixWorkdir redefines the file functions of the os, io/ioutil and
+// path/filepath packages to use the virtual working directory of the
+// interpreter.
+func fixWorkdir(interp *Interpreter) {
+	w := interp.wd
+	if w == nil {
+		return
+	}
+	// Functions and indices of their path arguments. The target of a
+	// symbolic link is relative to its directory, and left unchanged.
+	wrapped := map[string]map[string][]int{
+		"os": {
+			"Chmod": {0}, "Chown": {0}, "Chtimes": {0}, "CopyFS": {0}, "Create": {0},
+			"CreateTemp": {0}, "DirFS": {0}, "Lchown": {0}, "Link": {0, 1}, "Lstat": {0},
+			"Mkdir": {0}, "MkdirAll": {0}, "MkdirTemp": {0}, "Open": {0}, "OpenFile": {0},
+			"ReadDir": {0}, "ReadFile": {0}, "Readlink": {0}, "Remove": {0}, "RemoveAll": {0},
+			"Rename": {0, 1}, "Stat": {0}, "Symlink": {1},
+			"Truncate": {0}, "WriteFile": {0},
+		},
+		"io/ioutil": {
+			"ReadDir": {0}, "ReadFile": {0}, "TempDir": {0}, "TempFile": {0}, "WriteFile": {0},
+		},
+		"path/filepath": {
+			"EvalSymlinks": {0},
+		},
+	}
+	for pkg, fns := range wrapped {
+		p := interp.binPkg[pkg]
+		if p == nil {
+			continue
+		}
+		for name, args := range fns {
+			if v, ok := p[name]; ok && v.Kind() == reflect.Func {
+				p[name] = w.wrap(v, args...)
+			}
+		}
+	}
+
+	if p := interp.binPkg["os"]; p != nil {
+		p["Chdir"] = reflect.ValueOf(w.chdir)
+		p["Getwd"] = reflect.ValueOf(func() (string, error) { return w.get(), nil })
+	}
+	if p := interp.binPkg["path/filepath"]; p != nil {
+		p["Abs"] = reflect.ValueOf(w.abs)
+		p["Glob"] = reflect.ValueOf(w.glob)
+		p["Walk"] = reflect.ValueOf(w.walk)
+		p["WalkDir"] = reflect.ValueOf(w.walkDir)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix && !windows
+
+package interp
+
+import "os"
+
+// renameFile returns the file f, which keeps its resolved name on this
+// platform, where its descriptor can not be duplicated.
+func renameFile(f *os.File, name string) *os.File { return f }
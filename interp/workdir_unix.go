@@ -0,0 +1,32 @@
+//go:build unix
+
+package interp
+
+import (
+	"os"
+	"syscall"
+)
+
+// renameFile returns the file f under the given name, for os.File.Name, on a
+// duplicate of its descriptor. The file f is closed. If the descriptor can
+// not be duplicated, f is returned unchanged.
+func renameFile(f *os.File, name string) *os.File {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return f
+	}
+	var fd int
+	var derr error
+	err = rc.Control(func(s uintptr) {
+		syscall.ForkLock.RLock()
+		defer syscall.ForkLock.RUnlock()
+		if fd, derr = syscall.Dup(int(s)); derr == nil {
+			syscall.CloseOnExec(fd)
+		}
+	})
+	if err != nil || derr != nil {
+		return f
+	}
+	f.Close()
+	return os.NewFile(uintptr(fd), name)
+}
@@ -0,0 +1,27 @@
+package interp
+
+import (
+	"os"
+	"syscall"
+)
+
+// renameFile returns the file f under the given name, for os.File.Name, on a
+// duplicate of its handle. The file f is closed. If the handle can not be
+// duplicated, f is returned unchanged.
+func renameFile(f *os.File, name string) *os.File {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return f
+	}
+	var h syscall.Handle
+	var derr error
+	err = rc.Control(func(s uintptr) {
+		p, _ := syscall.GetCurrentProcess()
+		derr = syscall.DuplicateHandle(p, syscall.Handle(s), p, &h, 0, false, syscall.DUPLICATE_SAME_ACCESS)
+	})
+	if err != nil || derr != nil {
+		return f
+	}
+	f.Close()
+	return os.NewFile(uintptr(h), name)
+}
@@ -0,0 +1,186 @@
+package interp
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GenerateWrappers generates the source of a compiled Go package named
+// pkgName, which gives statically typed access to the exported functions of
+// the interpreted package importPath, the reverse of what "yaegi extract"
+// does for binary packages.
+//
+// The generated package declares a Package struct, with a field of function
+// type per exported function, and a Load function filling it from an
+// interpreter where the package has been imported. Load fails if a function
+// is missing or its signature has changed, so the host code keeps compile
+// time checks on the signatures it depends on. Functions whose signature
+// uses types declared by interpreted code, non empty interfaces or generics
+// cannot be expressed in compiled code and are skipped, as are variables,
+// constants and types.
+func (interp *Interpreter) GenerateWrappers(importPath, pkgName string) ([]byte, error) {
+	interp.mutex.RLock()
+	pkg, ok := interp.srcPkg[importPath]
+	var names []string
+	types := map[string]reflect.Type{}
+	for name, sym := range pkg {
+		if sym.kind != funcSym || !canExport(name) || sym.typ == nil || isGeneric(sym.typ) {
+			continue
+		}
+		names = append(names, name)
+		types[name] = sym.typ.TypeOf()
+	}
+	interp.mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("source package not found: %s", importPath)
+	}
+	sort.Strings(names)
+
+	imports := map[string]string{"github.com/breadchris/yaegi/interp": "interp", "fmt": "fmt", "reflect": "reflect"}
+	var fields, loads, skipped bytes.Buffer
+	for _, name := range names {
+		expr, ok := typeExpr(types[name], imports)
+		if !ok {
+			fmt.Fprintf(&skipped, "//\t%s %v\n", name, types[name])
+			continue
+		}
+		fmt.Fprintf(&fields, "\t%s %s\n", name, expr)
+		fmt.Fprintf(&loads, "\tif err := load(syms, %q, &p.%s); err != nil {\n\t\treturn nil, err\n\t}\n", name, name)
+	}
+
+	paths := make([]string, 0, len(imports))
+	for p := range imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by 'yaegi export %s'. DO NOT EDIT.\n\npackage %s\n\nimport (\n", importPath, pkgName)
+	for _, p := range paths {
+		if name := imports[p]; name != path.Base(p) {
+			fmt.Fprintf(&b, "\t%s %q\n", name, p)
+		} else {
+			fmt.Fprintf(&b, "\t%q\n", p)
+		}
+	}
+	fmt.Fprintf(&b, ")\n\n// ImportPath is the import path of the interpreted package.\nconst ImportPath = %q\n\n", importPath)
+	fmt.Fprintf(&b, "// Package holds the exported functions of the interpreted package %q.\n", importPath)
+	if skipped.Len() > 0 {
+		fmt.Fprintf(&b, "//\n// The following functions have types which cannot be expressed in compiled code:\n//\n%s", skipped.String())
+	}
+	fmt.Fprintf(&b, "type Package struct {\n%s}\n\n", fields.String())
+	fmt.Fprintf(&b, `// Load returns the functions of the interpreted package from i, where the
+// package must have been imported. An error is returned if a function is
+// missing or has an unexpected type.
+func Load(i *interp.Interpreter) (*Package, error) {
+	syms := i.Symbols(ImportPath)[ImportPath]
+	if syms == nil {
+		return nil, fmt.Errorf("package not found: %%s", ImportPath)
+	}
+	p := &Package{}
+%s	return p, nil
+}
+
+func load(syms map[string]reflect.Value, name string, dest interface{}) error {
+	v, ok := syms[name]
+	if !ok {
+		return fmt.Errorf("%%s.%%s: function not found", ImportPath, name)
+	}
+	d := reflect.ValueOf(dest).Elem()
+	if v.Type() != d.Type() {
+		return fmt.Errorf("%%s.%%s: invalid type %%v, want %%v", ImportPath, name, v.Type(), d.Type())
+	}
+	d.Set(v)
+	return nil
+}
+`, loads.String())
+
+	return format.Source(b.Bytes())
+}
+
+// typeExpr returns the Go expression of type t for generated code, and
+// records the import paths of the packages it refers to in imports, with
+// their names. It returns false if t cannot be expressed in compiled code.
+func typeExpr(t reflect.Type, imports map[string]string) (string, bool) {
+	if t.Name() != "" {
+		pkgPath := t.PkgPath()
+		if pkgPath == "" {
+			return t.Name(), true // predeclared type
+		}
+		if strings.Contains(t.Name(), "[") || pkgPath == reflect.TypeOf(valueInterface{}).PkgPath() {
+			return "", false
+		}
+		name, _, _ := strings.Cut(t.String(), ".")
+		for p, n := range imports {
+			if n == name && p != pkgPath {
+				return "", false // package name conflict
+			}
+		}
+		imports[pkgPath] = name
+		return t.String(), true
+	}
+
+	switch t.Kind() {
+	case reflect.Array:
+		elem, ok := typeExpr(t.Elem(), imports)
+		return "[" + strconv.Itoa(t.Len()) + "]" + elem, ok
+	case reflect.Chan:
+		elem, ok := typeExpr(t.Elem(), imports)
+		switch t.ChanDir() {
+		case reflect.RecvDir:
+			return "<-chan " + elem, ok
+		case reflect.SendDir:
+			return "chan<- " + elem, ok
+		}
+		return "chan " + elem, ok
+	case reflect.Func:
+		in := make([]string, t.NumIn())
+		for i := range in {
+			expr, ok := typeExpr(t.In(i), imports)
+			if !ok {
+				return "", false
+			}
+			if t.IsVariadic() && i == len(in)-1 {
+				expr = "..." + strings.TrimPrefix(expr, "[]")
+			}
+			in[i] = expr
+		}
+		out := make([]string, t.NumOut())
+		for i := range out {
+			expr, ok := typeExpr(t.Out(i), imports)
+			if !ok {
+				return "", false
+			}
+			out[i] = expr
+		}
+		s := "func(" + strings.Join(in, ", ") + ")"
+		switch len(out) {
+		case 0:
+		case 1:
+			s += " " + out[0]
+		default:
+			s += " (" + strings.Join(out, ", ") + ")"
+		}
+		return s, true
+	case reflect.Interface:
+		return "interface{}", t.NumMethod() == 0
+	case reflect.Map:
+		key, ok1 := typeExpr(t.Key(), imports)
+		elem, ok2 := typeExpr(t.Elem(), imports)
+		return "map[" + key + "]" + elem, ok1 && ok2
+	case reflect.Pointer:
+		elem, ok := typeExpr(t.Elem(), imports)
+		return "*" + elem, ok
+	case reflect.Slice:
+		elem, ok := typeExpr(t.Elem(), imports)
+		return "[]" + elem, ok
+	}
+	// Structs produced by the interpreter stand for interpreted types.
+	return "", false
+}
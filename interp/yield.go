@@ -0,0 +1,91 @@
+package interp
+
+import (
+	"fmt"
+	"go/token"
+	"sync/atomic"
+)
+
+// A YieldError is the panic value raised by interpreted code whose execution
+// is aborted by a hook set with SetYieldHook, or by a Scheduler. Unlike other
+// panics, it can not be recovered by interpreted code.
+type YieldError struct {
+	Pos token.Position // position of the function being executed
+	Err error          // error returned by the hook
+}
+
+func (e *YieldError) Error() string {
+	return fmt.Sprintf("%s: execution aborted: %v", e.Pos, e.Err)
+}
+
+func (e *YieldError) Unwrap() error { return e.Err }
+
+// SetYieldHook sets fn to be called every given number of CFG steps by the
+// execution of interpreted code, with the position of the function being
+// executed, or removes the hook if fn is nil or every is not positive.
+//
+// It is the primitive of cooperative preemption: fn can pause the execution
+// by blocking, e.g. to enforce a rate or to let a user interface respond,
+// and abort it by returning an error, which is then raised as a YieldError
+// panic. The hook is called by the goroutine executing the code, so it must
+// be safe for concurrent use if the code starts goroutines. The steps are
+// counted per execution, for all its goroutines together. Only the
+// functions entered after the call of SetYieldHook are affected.
+func (interp *Interpreter) SetYieldHook(every int, fn func(pos token.Position) error) {
+	interp.mutex.Lock()
+	defer interp.mutex.Unlock()
+	interp.yieldHook = nil
+	if fn != nil && every > 0 {
+		interp.yieldHook = &yieldHook{every: int64(every), fn: func(_ *frame, n *node) error {
+			return fn(interp.fset.Position(n.pos))
+		}}
+	}
+	interp.setYielder()
+}
+
+// yieldHook is a function run every given number of CFG steps.
+type yieldHook struct {
+	every int64
+	fn    func(f *frame, n *node) error // n is the function, or the code, being executed in f
+}
+
+// yielder runs the yield hooks of an interpreter.
+type yielder struct {
+	count atomic.Int64 // steps of the code not executed by a run
+	hooks []yieldHook
+}
+
+// setYielder sets the yielder of the hooks of the scheduler and of
+// SetYieldHook. It must be called with interp.mutex locked.
+func (interp *Interpreter) setYielder() {
+	var hooks []yieldHook
+	if s := interp.scheduler; s != nil {
+		hooks = append(hooks, yieldHook{every: int64(s.quantum), fn: s.yield})
+	}
+	if h := interp.yieldHook; h != nil {
+		hooks = append(hooks, *h)
+	}
+	if len(hooks) == 0 {
+		interp.yielder.Store(nil)
+		return
+	}
+	interp.yielder.Store(&yielder{hooks: hooks})
+}
+
+// step counts a CFG step in the execution of n in frame f, and runs the hooks
+// which are due. It panics with a YieldError if a hook returns an error.
+func (y *yielder) step(f *frame, n *node) {
+	count := &y.count
+	if r := f.run; r != nil {
+		count = &r.steps
+	}
+	c := count.Add(1)
+	for _, h := range y.hooks {
+		if c%h.every != 0 {
+			continue
+		}
+		if err := h.fn(f, n); err != nil {
+			panic(&YieldError{Pos: n.interp.fset.Position(n.pos), Err: err})
+		}
+	}
+}
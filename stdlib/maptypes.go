@@ -15,7 +15,6 @@ func init() {
 		reflect.TypeOf((*fmt.Stringer)(nil)).Elem(),
 	}
 
-	MapTypes[reflect.ValueOf(fmt.Errorf)] = mt
 	MapTypes[reflect.ValueOf(fmt.Fprint)] = mt
 	MapTypes[reflect.ValueOf(fmt.Fprintf)] = mt
 	MapTypes[reflect.ValueOf(fmt.Fprintln)] = mt
@@ -33,6 +32,13 @@ func init() {
 	MapTypes[reflect.ValueOf(log.Panicf)] = mt
 	MapTypes[reflect.ValueOf(log.Panicln)] = mt
 
+	// The operands of %w must implement error.
+	MapTypes[reflect.ValueOf(fmt.Errorf)] = []reflect.Type{
+		reflect.TypeOf((*fmt.Formatter)(nil)).Elem(),
+		reflect.TypeOf((*error)(nil)).Elem(),
+		reflect.TypeOf((*fmt.Stringer)(nil)).Elem(),
+	}
+
 	mt = []reflect.Type{reflect.TypeOf((*fmt.Scanner)(nil)).Elem()}
 
 	MapTypes[reflect.ValueOf(fmt.Scan)] = mt